@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/branchname"
 	wtxconfig "github.com/darkLord19/foglet/internal/config"
 	fogenv "github.com/darkLord19/foglet/internal/env"
 	"github.com/darkLord19/foglet/internal/state"
@@ -211,7 +212,7 @@ func validateBranchPrefix(prefix string) error {
 	if len(prefix) > 120 {
 		return fmt.Errorf("branch prefix is too long")
 	}
-	return nil
+	return branchname.ValidatePrefix(prefix)
 }
 
 func valueOrUnset(value string) string {
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/editor"
+	fogenv "github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var doctorPortFlag int
+
+// doctorVersionTimeout bounds each `--version` probe, so a wedged CLI does
+// not hang the report.
+const doctorVersionTimeout = 5 * time.Second
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that Fog's dependencies are installed and working",
+	Long: `Check the environment Fog runs in: FOG_HOME, the state database, git,
+the GitHub CLI, the AI tools, an editor and the daemon port.
+
+Exits non-zero when a check Fog cannot run without fails.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !printDoctorReport(os.Stdout, runDoctorChecks(doctorPortFlag)) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	doctorCmd.Flags().IntVar(&doctorPortFlag, "port", 8080, "Port fogd will listen on")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorCheck is one line of the doctor report. A failed critical check
+// makes fog doctor exit non-zero; any other failure is a warning.
+type doctorCheck struct {
+	Name     string
+	OK       bool
+	Critical bool
+	Detail   string
+	Hint     string
+}
+
+func runDoctorChecks(port int) []doctorCheck {
+	checks := make([]doctorCheck, 0, 12)
+
+	fogHome, homeCheck := checkFogHome()
+	checks = append(checks, homeCheck)
+	var db state.DBInspection
+	if homeCheck.OK {
+		var dbCheck doctorCheck
+		db, dbCheck = checkStateDB(fogHome)
+		checks = append(checks, dbCheck)
+	}
+	checks = append(checks, checkGit(), checkGh(db.HasGitHubToken))
+	checks = append(checks, checkAITools()...)
+	checks = append(checks, checkEditor(), checkDaemonPort(port))
+	return checks
+}
+
+func checkFogHome() (string, doctorCheck) {
+	check := doctorCheck{Name: "FOG_HOME writable", Critical: true}
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Set FOG_HOME to a writable directory"
+		return "", check
+	}
+	check.Detail = fogHome
+	if err := os.MkdirAll(fogHome, 0o700); err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Create " + fogHome + " or set FOG_HOME to a writable directory"
+		return fogHome, check
+	}
+	f, err := os.CreateTemp(fogHome, ".doctor-*")
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Fix the permissions on " + fogHome + " or set FOG_HOME to a writable directory"
+		return fogHome, check
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	check.OK = true
+	return fogHome, check
+}
+
+// checkStateDB opens the database read-only: doctor must not create,
+// migrate or recover it, and fogd may have it open.
+func checkStateDB(fogHome string) (state.DBInspection, doctorCheck) {
+	check := doctorCheck{Name: "State database readable", Critical: true}
+	db, err := state.InspectDB(fogHome)
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Start fogd to move a corrupt fog.db aside, or stop other Fog processes locking it"
+		return db, check
+	}
+	check.OK = true
+	check.Detail = db.Path
+	if !db.Exists {
+		check.Detail = "not created yet; fog or fogd creates it on first use"
+	}
+	return db, check
+}
+
+func checkGit() doctorCheck {
+	check := doctorCheck{Name: "git installed", Critical: true}
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Install git (https://git-scm.com/downloads)"
+		return check
+	}
+	check.OK = true
+	check.Detail = strings.TrimSpace(string(out))
+	return check
+}
+
+// checkGh is a warning rather than critical when a GitHub token is stored:
+// repos are then cloned over HTTPS with the token, and gh, when installed,
+// authenticates with it too.
+func checkGh(hasToken bool) doctorCheck {
+	check := doctorCheck{Name: "GitHub CLI authenticated", Critical: !hasToken}
+	if !ghcli.IsGhAvailable() {
+		check.Detail = "gh not found in PATH"
+		check.Hint = "Install GitHub CLI (https://cli.github.com/)"
+		if hasToken {
+			check.Hint += "; the stored GitHub token still clones repos, but PRs need gh"
+		}
+		return check
+	}
+	if hasToken {
+		check.OK = true
+		check.Detail = "using the stored GitHub token"
+		return check
+	}
+	if !ghcli.IsGhAuthenticated() {
+		check.Detail = "gh is installed but not logged in"
+		check.Hint = "Run 'gh auth login', or store a GitHub token in Fog's settings"
+		return check
+	}
+	check.OK = true
+	return check
+}
+
+// checkAITools reports each supported tool as a warning and adds a critical
+// failure only when none of them is installed.
+func checkAITools() []doctorCheck {
+	names := ai.AvailableToolNames()
+	checks := make([]doctorCheck, 0, len(names)+1)
+	found := 0
+	for _, name := range names {
+		check := doctorCheck{Name: "AI tool " + name}
+		tool, err := ai.GetTool(name)
+		if err != nil || !tool.IsAvailable() {
+			check.Detail = "not found in PATH"
+			checks = append(checks, check)
+			continue
+		}
+		found++
+		check.OK = true
+		ctx, cancel := context.WithTimeout(context.Background(), doctorVersionTimeout)
+		if v, err := ai.ToolVersion(ctx, name); err == nil && v != "" {
+			check.Detail = v
+		} else {
+			check.Detail = "installed, version unknown"
+		}
+		cancel()
+		checks = append(checks, check)
+	}
+	if found == 0 {
+		checks = append(checks, doctorCheck{
+			Name:     "AI tool available",
+			Critical: true,
+			Detail:   "no supported AI tool found",
+			Hint:     "Install one of: " + strings.Join(names, ", "),
+		})
+	}
+	return checks
+}
+
+func checkEditor() doctorCheck {
+	check := doctorCheck{Name: "Editor detected"}
+	ed, err := editor.Detect("")
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Install an editor such as VS Code, Cursor or Neovim, or set $EDITOR"
+		return check
+	}
+	check.OK = true
+	check.Detail = ed.Name()
+	return check
+}
+
+// checkDaemonPort is a warning: the port is taken when fogd is already
+// running on it.
+func checkDaemonPort(port int) doctorCheck {
+	check := doctorCheck{Name: fmt.Sprintf("Port %d free for fogd", port)}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		check.Detail = err.Error()
+		check.Hint = "Stop whatever holds the port, ignore this if it is fogd, or start fogd with --port"
+		return check
+	}
+	_ = ln.Close()
+	check.OK = true
+	return check
+}
+
+// printDoctorReport writes the checklist and reports whether every critical
+// check passed.
+func printDoctorReport(w io.Writer, checks []doctorCheck) bool {
+	healthy := true
+	for _, c := range checks {
+		mark := "ok"
+		switch {
+		case !c.OK && c.Critical:
+			mark = "FAIL"
+			healthy = false
+		case !c.OK:
+			mark = "warn"
+		}
+		line := fmt.Sprintf("[%-4s] %s", mark, c.Name)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		fmt.Fprintln(w, line)
+		if !c.OK && c.Hint != "" {
+			fmt.Fprintf(w, "       %s\n", c.Hint)
+		}
+	}
+	if healthy {
+		fmt.Fprintln(w, "\nFog is ready.")
+	} else {
+		fmt.Fprintln(w, "\nFix the failed checks above.")
+	}
+	return healthy
+}
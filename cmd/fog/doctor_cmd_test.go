@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintDoctorReport(t *testing.T) {
+	var buf bytes.Buffer
+	healthy := printDoctorReport(&buf, []doctorCheck{
+		{Name: "git installed", OK: true, Critical: true, Detail: "git version 2.45.0"},
+		{Name: "Editor detected", Detail: "no editor found", Hint: "Set $EDITOR"},
+	})
+	if !healthy {
+		t.Fatal("a failed non-critical check made the report unhealthy")
+	}
+	out := buf.String()
+	for _, want := range []string{"[ok  ] git installed: git version 2.45.0", "[warn] Editor detected: no editor found", "Set $EDITOR"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("report missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	healthy = printDoctorReport(&buf, []doctorCheck{
+		{Name: "GitHub CLI authenticated", Critical: true, Hint: "Run 'gh auth login'"},
+	})
+	if healthy {
+		t.Fatal("a failed critical check left the report healthy")
+	}
+	if !strings.Contains(buf.String(), "[FAIL] GitHub CLI authenticated") {
+		t.Fatalf("report missing the failure:\n%s", buf.String())
+	}
+}
+
+func TestCheckFogHome(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("FOG_HOME", dir)
+	home, check := checkFogHome()
+	if !check.OK || home != dir {
+		t.Fatalf("checkFogHome = %q, %+v; want %q ok", home, check, dir)
+	}
+	if _, db := checkStateDB(home); !db.OK {
+		t.Fatalf("checkStateDB = %+v", db)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("doctor created files in FOG_HOME: %v", entries)
+	}
+}
+
+func TestCheckStateDBReportsCorruptDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "fog.db")
+	garbage := []byte(strings.Repeat("not a sqlite database\n", 512))
+	if err := os.WriteFile(dbPath, garbage, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, check := checkStateDB(dir); check.OK || !check.Critical {
+		t.Fatalf("checkStateDB on a corrupt db = %+v, want a critical failure", check)
+	}
+	if got, _ := os.ReadFile(dbPath); !bytes.Equal(got, garbage) {
+		t.Fatal("doctor changed a corrupt database")
+	}
+	if aside, _ := filepath.Glob(dbPath + ".corrupt-*"); len(aside) != 0 {
+		t.Fatalf("doctor moved the database aside: %v", aside)
+	}
+}
+
+func TestCheckGhIsAWarningWithStoredToken(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	if check := checkGh(false); check.OK || !check.Critical {
+		t.Fatalf("checkGh without gh or a token = %+v, want a critical failure", check)
+	}
+	if check := checkGh(true); check.OK || check.Critical {
+		t.Fatalf("checkGh without gh but with a token = %+v, want a warning", check)
+	}
+}
+
+func TestCheckDaemonPortInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	check := checkDaemonPort(ln.Addr().(*net.TCPAddr).Port)
+	if check.OK || check.Critical {
+		t.Fatalf("checkDaemonPort on a taken port = %+v, want a warning", check)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/branchname"
+	"github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	forkPromptFlag string
+	forkBranchFlag string
+	forkToolFlag   string
+	forkModelFlag  string
+)
+
+var sessionForkCmd = &cobra.Command{
+	Use:   "fork <source-session-id>",
+	Short: "Start a new session from an existing one",
+	Long: `Start a new session on a new branch, seeded with a summary of the source
+session's work, and run --prompt in it.
+
+The branch name is generated from the prompt when --branch is omitted. The
+tool defaults to the source session's.
+
+Example:
+  fog session fork 3f2a9c1e-... --prompt "Try the same fix with a cache instead"`,
+	Args: cobra.ExactArgs(1),
+	Run:  runForkCmd,
+}
+
+// forkCmd keeps the top-level `fog fork` working for scripts written before
+// fork moved under `fog session`.
+var forkCmd = &cobra.Command{
+	Use:    "fork <source-session-id>",
+	Short:  "Alias for fog session fork",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	Run:    runForkCmd,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{sessionForkCmd, forkCmd} {
+		cmd.Flags().StringVar(&forkPromptFlag, "prompt", "", "Task prompt for the fork (required)")
+		cmd.Flags().StringVar(&forkBranchFlag, "branch", "", "Branch name (default: generated from the prompt)")
+		cmd.Flags().StringVar(&forkToolFlag, "tool", "", "AI tool to use (default: the source session's)")
+		cmd.Flags().StringVar(&forkModelFlag, "model", "", "Model to use with the tool")
+		cmd.MarkFlagRequired("prompt")
+	}
+	sessionCmd.AddCommand(sessionForkCmd)
+	rootCmd.AddCommand(forkCmd)
+}
+
+func runForkCmd(cmd *cobra.Command, args []string) {
+	if err := runFork(args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// sessionForker is the part of *runner.Runner that fog session fork uses.
+type sessionForker interface {
+	GetSession(id string) (state.Session, bool, error)
+	ResolveBranch(repoPath, requested, prompt string, vars branchname.PrefixVars) (string, error)
+	ForkSession(sourceSessionID string, opts runner.ForkSessionOptions) (state.Session, state.Run, error)
+}
+
+func runFork(sourceSessionID string) error {
+	fogHome, err := env.FogHome()
+	if err != nil {
+		return err
+	}
+
+	stateStore, err := state.NewStore(fogHome)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stateStore.Close() }()
+
+	ghcli.SetConfigSource(ghcli.StoreConfigSource(stateStore))
+	return forkSession(os.Stdout, runner.New(stateStore), sourceSessionID, forkPromptFlag, forkBranchFlag, forkToolFlag, forkModelFlag)
+}
+
+// forkSession forks sourceSessionID as POST /api/sessions/{id}/fork does and
+// reports the new session, running the fork to completion first.
+func forkSession(w io.Writer, forker sessionForker, sourceSessionID, prompt, branch, tool, model string) error {
+	sourceSessionID = strings.TrimSpace(sourceSessionID)
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return errors.New("prompt is required")
+	}
+
+	source, found, err := forker.GetSession(sourceSessionID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("session not found: %s", sourceSessionID)
+	}
+
+	tool = strings.TrimSpace(tool)
+	if tool == "" {
+		tool = source.Tool
+	} else if _, err := ai.GetTool(tool); err != nil {
+		return err
+	}
+	branch, err = forker.ResolveBranch(source.WorktreePath, branch, prompt, branchname.PrefixVars{Tool: tool})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Forking session %s\n", source.ID)
+	fmt.Fprintf(w, "Branch: %s\n", branch)
+	fmt.Fprintf(w, "AI Tool: %s\n", tool)
+	fmt.Fprintf(w, "Prompt: %s\n", prompt)
+	fmt.Fprintln(w)
+
+	session, run, err := forker.ForkSession(source.ID, runner.ForkSessionOptions{
+		Branch: branch,
+		Prompt: prompt,
+		Tool:   tool,
+		Model:  strings.TrimSpace(model),
+		Origin: "cli",
+	})
+	if err != nil {
+		if session.ID != "" {
+			fmt.Fprintf(w, "Session: %s\n", session.ID)
+			fmt.Fprintf(w, "Run: %s\n", run.ID)
+		}
+		return fmt.Errorf("fork failed: %w", err)
+	}
+
+	fmt.Fprintf(w, "✅ Fork completed\n")
+	fmt.Fprintf(w, "Session: %s\n", session.ID)
+	fmt.Fprintf(w, "Run: %s (%s)\n", run.ID, run.State)
+	fmt.Fprintf(w, "Branch: %s\n", session.Branch)
+	fmt.Fprintf(w, "Worktree: %s\n", session.WorktreePath)
+	if session.PRURL != "" {
+		fmt.Fprintf(w, "PR: %s\n", session.PRURL)
+	}
+	return nil
+}
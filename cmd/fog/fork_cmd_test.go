@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/branchname"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+type fakeForker struct {
+	source state.Session
+	opts   runner.ForkSessionOptions
+	vars   branchname.PrefixVars
+}
+
+func (f *fakeForker) GetSession(id string) (state.Session, bool, error) {
+	if id != f.source.ID {
+		return state.Session{}, false, nil
+	}
+	return f.source, true, nil
+}
+
+func (f *fakeForker) ResolveBranch(_, requested, _ string, vars branchname.PrefixVars) (string, error) {
+	f.vars = vars
+	if requested == "" {
+		return "fog/try-a-cache", nil
+	}
+	return requested, nil
+}
+
+func (f *fakeForker) ForkSession(_ string, opts runner.ForkSessionOptions) (state.Session, state.Run, error) {
+	f.opts = opts
+	return state.Session{ID: "session-2", Branch: opts.Branch, WorktreePath: "/tmp/wt/session-2"},
+		state.Run{ID: "run-2", State: "COMPLETED"}, nil
+}
+
+func TestForkSessionDefaultsToSourceToolAndGeneratedBranch(t *testing.T) {
+	forker := &fakeForker{source: state.Session{ID: "session-1", Tool: "claude", WorktreePath: "/tmp/wt/session-1"}}
+	var out bytes.Buffer
+	if err := forkSession(&out, forker, "session-1", "Try a cache", "", "", "opus"); err != nil {
+		t.Fatalf("forkSession: %v", err)
+	}
+	if forker.opts.Branch != "fog/try-a-cache" || forker.opts.Tool != "claude" || forker.opts.Model != "opus" || forker.opts.Origin != "cli" {
+		t.Fatalf("fork options = %+v", forker.opts)
+	}
+	if forker.vars.Tool != "claude" {
+		t.Fatalf("branch prefix {tool} = %q, want the source session's tool", forker.vars.Tool)
+	}
+	for _, want := range []string{"Session: session-2", "Run: run-2 (COMPLETED)", "Worktree: /tmp/wt/session-2"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestForkSessionRejectsUnknownSourceAndTool(t *testing.T) {
+	forker := &fakeForker{source: state.Session{ID: "session-1", Tool: "claude"}}
+	if err := forkSession(&bytes.Buffer{}, forker, "session-9", "Try a cache", "", "", ""); err == nil || !strings.Contains(err.Error(), "session not found") {
+		t.Fatalf("unknown source: err = %v", err)
+	}
+	if err := forkSession(&bytes.Buffer{}, forker, "session-1", "Try a cache", "fog/x", "no-such-tool", ""); err == nil {
+		t.Fatal("expected an unknown tool to be rejected")
+	}
+	if forker.opts.Prompt != "" {
+		t.Fatal("ForkSession ran despite a rejected request")
+	}
+}
+
+func TestForkIsASessionSubcommandWithTopLevelAlias(t *testing.T) {
+	for _, path := range [][]string{{"session", "fork"}, {"fork"}} {
+		cmd, _, err := rootCmd.Find(path)
+		if err != nil || cmd.Name() != "fork" {
+			t.Fatalf("fog %s resolves to %v (%v), want the fork command", strings.Join(path, " "), cmd, err)
+		}
+		if cmd.Flags().Lookup("prompt") == nil {
+			t.Fatalf("fog %s has no --prompt flag", strings.Join(path, " "))
+		}
+	}
+	if cmd, _, _ := rootCmd.Find([]string{"session", "fork"}); cmd.Parent() != sessionCmd {
+		t.Fatalf("fork parent = %s, want session", cmd.Parent().Name())
+	}
+}
@@ -5,6 +5,7 @@ import "github.com/darkLord19/foglet/internal/ghcli"
 var (
 	isGhAvailableFn     = ghcli.IsGhAvailable
 	isGhAuthenticatedFn = ghcli.IsGhAuthenticated
+	isGhHostAuthedFn    = ghcli.IsGhAuthenticatedOnHost
 	discoverGhReposFn   = ghcli.DiscoverRepos
 	cloneGhRepoFn       = ghcli.CloneRepo
 )
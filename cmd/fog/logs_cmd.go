@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollowFlag bool
+	logsRunFlag    string
+	logsTypeFlag   []string
+
+	// logsPollInterval is how often --follow re-reads the run's events.
+	logsPollInterval = time.Second
+)
+
+// logsEventLimit matches what GET /api/sessions/{id}/runs/{runID}/stream reads
+// per poll, the most ListRunEvents returns.
+const logsEventLimit = 2000
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <session-id>",
+	Short: "Print a run's events",
+	Long: `Print the events of a session's latest run, or of --run.
+
+With --follow, keep printing new events every second until the run
+completes, fails or is cancelled.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showRunLogs(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollowFlag, "follow", "f", false, "Keep printing events until the run ends")
+	logsCmd.Flags().StringVar(&logsRunFlag, "run", "", "Run ID to show (default: the session's latest run)")
+	logsCmd.Flags().StringSliceVar(&logsTypeFlag, "type", nil, "Only print events of this type, e.g. ai_output (repeatable)")
+	rootCmd.AddCommand(logsCmd)
+}
+
+// runLogReader is the part of *runner.Runner that fog logs reads.
+type runLogReader interface {
+	ListSessionRuns(sessionID string) ([]state.Run, error)
+	ListRunEvents(runID string, limit int) ([]state.RunEvent, error)
+}
+
+func showRunLogs(sessionID string) error {
+	fogHome, err := env.FogHome()
+	if err != nil {
+		return err
+	}
+
+	stateStore, err := state.NewStore(fogHome)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stateStore.Close() }()
+
+	return printRunLogs(os.Stdout, runner.New(stateStore), sessionID, logsRunFlag, logsTypeFlag, logsFollowFlag)
+}
+
+// printRunLogs writes the events of runID, or of the session's latest run when
+// runID is empty, keeping only types when given. With follow it polls until
+// the run reaches a terminal state.
+func printRunLogs(w io.Writer, reader runLogReader, sessionID, runID string, types []string, follow bool) error {
+	sessionID = strings.TrimSpace(sessionID)
+	runID = strings.TrimSpace(runID)
+
+	run, err := findLogRun(reader, sessionID, runID)
+	if err != nil {
+		return err
+	}
+
+	printer := &runEventPrinter{w: w}
+	cursor := int64(0)
+	for {
+		events, err := reader.ListRunEvents(run.ID, logsEventLimit)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if event.ID <= cursor {
+				continue
+			}
+			cursor = event.ID
+			if len(types) > 0 && !slices.Contains(types, event.Type) {
+				continue
+			}
+			printer.print(event)
+		}
+
+		if !follow || isTerminalRunState(run.State) {
+			printer.endLine()
+			return nil
+		}
+		time.Sleep(logsPollInterval)
+		// Re-read the run after sleeping, so events written before it turned
+		// terminal are printed by the next pass.
+		if run, err = findLogRun(reader, sessionID, run.ID); err != nil {
+			return err
+		}
+	}
+}
+
+func findLogRun(reader runLogReader, sessionID, runID string) (state.Run, error) {
+	runs, err := reader.ListSessionRuns(sessionID)
+	if err != nil {
+		return state.Run{}, err
+	}
+	if len(runs) == 0 {
+		return state.Run{}, fmt.Errorf("session %s has no runs", sessionID)
+	}
+	if runID == "" {
+		// ListSessionRuns returns the newest run first.
+		return runs[0], nil
+	}
+	for _, run := range runs {
+		if run.ID == runID {
+			return run, nil
+		}
+	}
+	return state.Run{}, fmt.Errorf("run %s not found in session %s", runID, sessionID)
+}
+
+// runEventPrinter prints ai_stream chunks as they were written, so followed
+// output reads like the tool's own, and every other event on its own line.
+type runEventPrinter struct {
+	w io.Writer
+	// midLine is set when the last chunk did not end in a newline.
+	midLine bool
+}
+
+func (p *runEventPrinter) print(event state.RunEvent) {
+	if event.Type == "ai_stream" {
+		if event.Message != "" {
+			fmt.Fprint(p.w, event.Message)
+			p.midLine = !strings.HasSuffix(event.Message, "\n")
+		}
+		return
+	}
+	p.endLine()
+	message := strings.TrimRight(event.Message, "\n")
+	if message == "" {
+		message = event.Data
+	}
+	fmt.Fprintf(p.w, "[%s] %s: %s\n", event.TS.Local().Format("15:04:05"), event.Type, message)
+}
+
+func (p *runEventPrinter) endLine() {
+	if p.midLine {
+		fmt.Fprintln(p.w)
+		p.midLine = false
+	}
+}
+
+func isTerminalRunState(stateName string) bool {
+	switch strings.TrimSpace(stateName) {
+	case "COMPLETED", "FAILED", "CANCELLED":
+		return true
+	default:
+		return false
+	}
+}
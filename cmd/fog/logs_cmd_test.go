@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// scriptedLogReader serves one snapshot of the run and its events per poll,
+// repeating the last one once the script runs out.
+type scriptedLogReader struct {
+	runs   [][]state.Run
+	events [][]state.RunEvent
+	polls  int
+}
+
+func (s *scriptedLogReader) ListSessionRuns(string) ([]state.Run, error) {
+	return s.runs[min(s.polls, len(s.runs)-1)], nil
+}
+
+func (s *scriptedLogReader) ListRunEvents(runID string, _ int) ([]state.RunEvent, error) {
+	events := s.events[min(s.polls, len(s.events)-1)]
+	s.polls++
+	var out []state.RunEvent
+	for _, event := range events {
+		if event.RunID == runID {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func logEvent(id int64, runID, typ, message string) state.RunEvent {
+	return state.RunEvent{ID: id, RunID: runID, TS: time.Now(), Type: typ, Message: message}
+}
+
+func TestPrintRunLogsFollowsLatestRunUntilTerminal(t *testing.T) {
+	orig := logsPollInterval
+	t.Cleanup(func() { logsPollInterval = orig })
+	logsPollInterval = time.Millisecond
+
+	first := []state.RunEvent{
+		logEvent(1, "run-2", "ai_start", "Running claude"),
+		logEvent(2, "run-2", "ai_stream", "Reading "),
+	}
+	second := append(append([]state.RunEvent{}, first...),
+		logEvent(3, "run-2", "ai_stream", "files\n"),
+		logEvent(4, "run-2", "commit", "Committed abc123"),
+	)
+	reader := &scriptedLogReader{
+		runs: [][]state.Run{
+			{{ID: "run-2", State: "AI_RUNNING"}, {ID: "run-1", State: "COMPLETED"}},
+			{{ID: "run-2", State: "COMPLETED"}, {ID: "run-1", State: "COMPLETED"}},
+		},
+		events: [][]state.RunEvent{first, second},
+	}
+
+	var out bytes.Buffer
+	if err := printRunLogs(&out, reader, "session-1", "", nil, true); err != nil {
+		t.Fatalf("printRunLogs: %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "Reading files\n") || !strings.Contains(got, "commit: Committed abc123") {
+		t.Fatalf("output = %q", got)
+	}
+	if strings.Count(got, "ai_start") != 1 {
+		t.Fatalf("an event was printed twice: %q", got)
+	}
+}
+
+func TestPrintRunLogsFiltersTypesForNamedRun(t *testing.T) {
+	reader := &scriptedLogReader{
+		runs: [][]state.Run{{{ID: "run-2", State: "COMPLETED"}, {ID: "run-1", State: "FAILED"}}},
+		events: [][]state.RunEvent{{
+			logEvent(1, "run-1", "ai_stream", "chunk"),
+			logEvent(2, "run-1", "ai_output", "the whole answer"),
+			logEvent(3, "run-2", "ai_output", "another run"),
+		}},
+	}
+
+	var out bytes.Buffer
+	if err := printRunLogs(&out, reader, "session-1", "run-1", []string{"ai_output"}, false); err != nil {
+		t.Fatalf("printRunLogs: %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, "ai_output: the whole answer") || strings.Contains(got, "chunk") || strings.Contains(got, "another run") {
+		t.Fatalf("output = %q", got)
+	}
+
+	if err := printRunLogs(&out, reader, "session-1", "run-9", nil, false); err == nil {
+		t.Fatal("expected an error for a run outside the session")
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/ghcli"
 	"github.com/darkLord19/foglet/internal/runner"
 	"github.com/darkLord19/foglet/internal/state"
 	"github.com/darkLord19/foglet/internal/toolcfg"
@@ -16,19 +18,24 @@ import (
 var version = "dev"
 
 var (
-	flagBranch      string
-	flagRepo        string
-	flagTool        string
-	flagPrompt      string
-	flagCommit      bool
-	flagPR          bool
-	flagValidate    bool
-	flagBaseBranch  string
-	flagSetupCmd    string
-	flagValidateCmd string
-	flagAsync       bool
-	flagJSON        bool
-	flagPRTitle     string
+	flagBranch         string
+	flagRepo           string
+	flagTool           string
+	flagPrompt         string
+	flagCommit         bool
+	flagPR             bool
+	flagValidate       bool
+	flagBaseBranch     string
+	flagSetupCmd       string
+	flagValidateCmd    string
+	flagValidateOK     []int
+	flagAsync          bool
+	flagJSON           bool
+	flagDryRun         bool
+	flagPRTitle        string
+	flagCommitStrategy string
+	flagFocusPaths     []string
+	flagTimeout        time.Duration
 )
 
 func main() {
@@ -106,10 +113,16 @@ func init() {
 	runCmd.Flags().BoolVar(&flagPR, "pr", false, "Create pull request")
 	runCmd.Flags().StringVar(&flagPRTitle, "pr-title", "", "Pull request title (requires --pr)")
 	runCmd.Flags().BoolVar(&flagValidate, "validate", false, "Run validation after AI")
-	runCmd.Flags().StringVar(&flagBaseBranch, "base", "main", "Base branch for PR")
+	runCmd.Flags().StringVar(&flagBaseBranch, "base", "", "Base branch for PR (default: repo default branch, then default_base_branch, then main)")
 	runCmd.Flags().StringVar(&flagSetupCmd, "setup-cmd", "", "Setup command to run")
 	runCmd.Flags().StringVar(&flagValidateCmd, "validate-cmd", "", "Validation command to run")
+	runCmd.Flags().IntSliceVar(&flagValidateOK, "validate-success-codes", nil, "Non-zero validation exit codes that still count as success")
 	runCmd.Flags().BoolVar(&flagAsync, "async", false, "Run asynchronously")
+	runCmd.Flags().StringVar(&flagCommitStrategy, "commit-strategy", "", "Commit shape before push: per_run (default), squash, or squash_force")
+	runCmd.Flags().StringSliceVar(&flagFocusPaths, "focus", nil, "Repo-relative path the AI should focus its changes on (repeatable)")
+	runCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "Print the plan and exit without creating a worktree or calling the AI tool")
+	runCmd.Flags().BoolVar(&flagJSON, "json", false, "Print the --dry-run plan as JSON")
+	runCmd.Flags().DurationVar(&flagTimeout, "timeout", 0, "Fail the run if it takes longer than this, e.g. 45m (default: run_timeout_seconds setting)")
 
 	runCmd.MarkFlagRequired("branch")
 	runCmd.MarkFlagRequired("prompt")
@@ -135,17 +148,17 @@ func runTask() error {
 	}
 	defer func() { _ = stateStore.Close() }()
 
-	resolvedTool, err := toolcfg.ResolveTool(flagTool, stateStore, "cli")
-	if err != nil {
-		return err
-	}
-
 	repoName, err := resolveRepoNameForRun(flagRepo, stateStore)
 	if err != nil {
 		return err
 	}
 
-	repo, err := ensureRepoRegisteredForRun(repoName, stateStore, fogHome)
+	var repo state.Repo
+	if flagDryRun {
+		repo, err = lookupRepoForPlan(repoName, stateStore)
+	} else {
+		repo, err = ensureRepoRegisteredForRun(repoName, stateStore, fogHome)
+	}
 	if err != nil {
 		return err
 	}
@@ -154,30 +167,20 @@ func runTask() error {
 	}
 
 	// Create runner
+	ghcli.SetConfigSource(ghcli.StoreConfigSource(stateStore))
 	r := runner.New(stateStore)
 
-	baseBranch := strings.TrimSpace(flagBaseBranch)
-	if baseBranch == "" {
-		baseBranch = strings.TrimSpace(repo.DefaultBranch)
-	}
-	if baseBranch == "" {
-		baseBranch = "main"
+	opts, err := resolveRunOptions(r, repo, stateStore)
+	if err != nil {
+		return err
 	}
 
-	opts := runner.StartSessionOptions{
-		RepoName:    repo.Name,
-		RepoPath:    repo.BaseWorktreePath,
-		Branch:      flagBranch,
-		Tool:        resolvedTool,
-		Model:       "",
-		Prompt:      flagPrompt,
-		AutoPR:      flagPR,
-		SetupCmd:    flagSetupCmd,
-		Validate:    flagValidate,
-		ValidateCmd: flagValidateCmd,
-		BaseBranch:  baseBranch,
-		CommitMsg:   "",
-		PRTitle:     flagPRTitle,
+	if flagDryRun {
+		plan, err := planRun(r, opts, r.WorktreeDir(repo))
+		if err != nil {
+			return err
+		}
+		return printRunPlan(os.Stdout, plan, flagJSON)
 	}
 
 	fmt.Printf("Starting session\n")
@@ -204,6 +207,58 @@ func runTask() error {
 	return nil
 }
 
+// resolveRunOptions builds the session options from the run flags. The
+// repo's .fog.yaml fills what the flags leave out, with the same precedence
+// a daemon launch uses, so --dry-run plans what a real run does.
+func resolveRunOptions(r *runner.Runner, repo state.Repo, tools toolcfg.DefaultToolReader) (runner.StartSessionOptions, error) {
+	repoCfg, err := runner.LoadRepoConfig(repo.BaseWorktreePath)
+	if err != nil {
+		return runner.StartSessionOptions{}, err
+	}
+	repoTool := repo.DefaultTool
+	if strings.TrimSpace(repoTool) == "" {
+		repoTool = repoCfg.Tool
+	}
+	resolvedTool, err := toolcfg.ResolveRepoTool(flagTool, repoTool, tools, "cli")
+	if err != nil {
+		return runner.StartSessionOptions{}, err
+	}
+
+	model := toolcfg.ResolveRepoModel("", resolvedTool, repo.DefaultTool, repo.DefaultModel)
+	if repoCfg.BaseBranch != "" {
+		repo.DefaultBranch = repoCfg.BaseBranch
+	}
+	setupCmd, validateCmd := flagSetupCmd, flagValidateCmd
+	if strings.TrimSpace(setupCmd) == "" {
+		setupCmd = repoCfg.SetupCmd
+	}
+	if strings.TrimSpace(validateCmd) == "" {
+		validateCmd = repoCfg.ValidateCmd
+	}
+
+	return runner.StartSessionOptions{
+		RepoName:    repo.Name,
+		RepoPath:    repo.BaseWorktreePath,
+		Branch:      flagBranch,
+		Tool:        resolvedTool,
+		Model:       model,
+		Prompt:      flagPrompt,
+		AutoPR:      flagPR,
+		SetupCmd:    setupCmd,
+		Validate:    flagValidate,
+		ValidateCmd: validateCmd,
+		BaseBranch:  r.ResolveBaseBranch(flagBaseBranch, repo),
+		CommitMsg:   "",
+		PRTitle:     flagPRTitle,
+
+		ValidateSuccessCodes: flagValidateOK,
+		Origin:               "cli",
+		CommitStrategy:       flagCommitStrategy,
+		FocusPaths:           flagFocusPaths,
+		Timeout:              flagTimeout,
+	}, nil
+}
+
 func listSessions() error {
 	fogHome, err := env.FogHome()
 	if err != nil {
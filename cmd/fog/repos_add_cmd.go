@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	fogenv "github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reposAddNameFlag          string
+	reposAddDefaultBranchFlag string
+	reposRmPurgeFlag          bool
+	reposRmForceFlag          bool
+
+	// detectDefaultBranchFn reads the default branch of a fresh bare clone.
+	detectDefaultBranchFn = func(barePath string) (string, error) {
+		return git.New(barePath).GetDefaultBranch()
+	}
+
+	// scpLikeURL matches git's scp-style SSH syntax, [user@]host:path.
+	scpLikeURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+)
+
+var reposAddCmd = &cobra.Command{
+	Use:   "add <clone-url>",
+	Short: "Register a repository by clone URL, without gh",
+	Long: `Clone a repository by its SSH or HTTPS URL and register it with Fog, for
+hosts without gh or the desktop app. The repo is named owner/repo from the
+URL unless --name is given, and its default branch is read from the clone
+unless --default-branch is given.
+
+Example:
+  fog repo add git@github.com:acme/api.git
+  fog repo add https://git.example.com/team/service --default-branch develop`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReposAdd(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var reposRmCmd = &cobra.Command{
+	Use:     "rm <owner/repo>",
+	Aliases: []string{"remove"},
+	Short:   "Stop managing a repository",
+	Long: `Stop managing a repository. A repo that still has sessions is refused;
+delete them first from the app or with DELETE /api/sessions/{id}. The clone and base worktree stay
+on disk unless --purge is given, which is refused while the clone has
+branches not merged into the default branch unless --force is also given.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReposRm(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	reposAddCmd.Flags().StringVar(&reposAddNameFlag, "name", "", "Repository name as owner/repo (default: inferred from the URL)")
+	reposAddCmd.Flags().StringVar(&reposAddDefaultBranchFlag, "default-branch", "", "Default branch (default: read from the clone)")
+	reposRmCmd.Flags().BoolVar(&reposRmPurgeFlag, "purge", false, "Also delete the managed clone and base worktree")
+	reposRmCmd.Flags().BoolVar(&reposRmForceFlag, "force", false, "With --purge, delete branches not merged into the default branch too")
+
+	reposCmd.AddCommand(reposAddCmd)
+	reposCmd.AddCommand(reposRmCmd)
+}
+
+// parseCloneURL returns the host of an SSH or HTTPS clone URL and the
+// owner/repo its path ends in. name is empty when the path has fewer than two
+// segments; err is set only for URLs Fog cannot clone from.
+func parseCloneURL(raw string) (host, name string, err error) {
+	raw = strings.TrimSpace(raw)
+	var path string
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid clone URL %q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "https", "http", "ssh", "git+ssh":
+		default:
+			return "", "", fmt.Errorf("unsupported clone URL scheme %q; use an SSH or HTTPS URL", u.Scheme)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if m := scpLikeURL.FindStringSubmatch(raw); m != nil {
+		host, path = m[1], m[2]
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("invalid clone URL %q; use an SSH or HTTPS URL", raw)
+	}
+
+	segments := strings.Split(strings.TrimSuffix(strings.Trim(path, "/"), ".git"), "/")
+	if len(segments) >= 2 {
+		name = segments[len(segments)-2] + "/" + segments[len(segments)-1]
+	}
+	return host, name, nil
+}
+
+func runReposAdd(cloneURL string) error {
+	cloneURL = strings.TrimSpace(cloneURL)
+	host, inferred, err := parseCloneURL(cloneURL)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSpace(reposAddNameFlag)
+	if name == "" {
+		if inferred == "" {
+			return fmt.Errorf("cannot infer owner/repo from %q; pass --name", cloneURL)
+		}
+		name = inferred
+	}
+	owner, repoName, err := splitRepoFullName(name)
+	if err != nil {
+		return fmt.Errorf("invalid repo name %q: %w", name, err)
+	}
+	name = owner + "/" + repoName
+
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return err
+	}
+	store, err := state.NewStore(fogHome)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	if existing, found, err := store.GetRepoByName(name); err != nil {
+		return err
+	} else if found && existing.URL != cloneURL {
+		return fmt.Errorf("repo %s is already registered from %s", name, existing.URL)
+	}
+
+	repoDir := filepath.Join(fogenv.ManagedReposDir(fogHome), owner, repoName)
+	if err := os.MkdirAll(repoDir, 0o755); err != nil {
+		return fmt.Errorf("create repo dir %s: %w", repoDir, err)
+	}
+	barePath := filepath.Join(repoDir, "repo.git")
+	basePath := filepath.Join(repoDir, "base")
+
+	// Fail rather than hang on a credential prompt nobody can see.
+	clone := func() error {
+		return gitRunner([]string{"GIT_TERMINAL_PROMPT=0"}, "clone", "--bare", cloneURL, barePath)
+	}
+	if err := ensureBareRepo(name, barePath, basePath, clone); err != nil {
+		return err
+	}
+
+	defaultBranch := strings.TrimSpace(reposAddDefaultBranchFlag)
+	if defaultBranch == "" {
+		if defaultBranch, err = detectDefaultBranchFn(barePath); err != nil {
+			return fmt.Errorf("detect default branch of %s (pass --default-branch): %w", name, err)
+		}
+	}
+
+	if _, err := store.UpsertRepo(state.Repo{
+		Name:             name,
+		URL:              cloneURL,
+		Host:             host,
+		Owner:            owner,
+		Repo:             repoName,
+		BarePath:         barePath,
+		BaseWorktreePath: basePath,
+		DefaultBranch:    defaultBranch,
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Added %s (default branch %s)\n", name, defaultBranch)
+	return nil
+}
+
+func runReposRm(name string) error {
+	name = strings.TrimSpace(name)
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		return err
+	}
+	store, err := state.NewStore(fogHome)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+
+	repo, found, err := store.GetRepoByName(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("repo %q is not registered", name)
+	}
+
+	if reposRmPurgeFlag && !reposRmForceFlag {
+		if err := store.CheckRepoUnused(repo.Name); err != nil {
+			return err
+		}
+		branches, err := fogenv.UnmergedManagedBranches(fogenv.ManagedReposDir(fogHome), repo.BarePath, repo.BaseWorktreePath, repo.DefaultBranch)
+		if err != nil {
+			return err
+		}
+		if len(branches) > 0 {
+			return fmt.Errorf("purging %s would delete %d branch(es) not merged into %s: %s; pass --force to purge anyway", repo.Name, len(branches), repo.DefaultBranch, strings.Join(branches, ", "))
+		}
+	}
+
+	if err := store.DeleteRepo(repo.Name); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", repo.Name)
+
+	if !reposRmPurgeFlag {
+		return nil
+	}
+	dir, ok, err := fogenv.PurgeManagedRepo(fogenv.ManagedReposDir(fogHome), repo.BarePath, repo.BaseWorktreePath)
+	if err != nil {
+		return fmt.Errorf("repo removed but purge failed: %w", err)
+	}
+	if !ok {
+		fmt.Printf("Left %s in place: it is not a Fog-managed clone\n", filepath.Dir(repo.BarePath))
+		return nil
+	}
+	fmt.Printf("Deleted %s\n", dir)
+	return nil
+}
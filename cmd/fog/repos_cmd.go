@@ -19,12 +19,14 @@ import (
 var (
 	reposJSONFlag   bool
 	reposSelectFlag string
+	reposHostFlag   string
 	gitRunner       = runGitCommand
 )
 
 var reposCmd = &cobra.Command{
-	Use:   "repos",
-	Short: "Manage Fog repositories",
+	Use:     "repos",
+	Aliases: []string{"repo"},
+	Short:   "Manage Fog repositories",
 }
 
 var reposDiscoverCmd = &cobra.Command{
@@ -63,6 +65,9 @@ var reposListCmd = &cobra.Command{
 func init() {
 	reposDiscoverCmd.Flags().BoolVar(&reposJSONFlag, "json", false, "Output JSON")
 	reposImportCmd.Flags().StringVar(&reposSelectFlag, "select", "", "Comma-separated GitHub full names to import (e.g. org/repo,org/repo2)")
+	for _, cmd := range []*cobra.Command{reposDiscoverCmd, reposImportCmd} {
+		cmd.Flags().StringVar(&reposHostFlag, "host", "", "GitHub host to use, e.g. a GitHub Enterprise Server hostname (default: gh's default host)")
+	}
 
 	reposCmd.AddCommand(reposDiscoverCmd)
 	reposCmd.AddCommand(reposImportCmd)
@@ -71,7 +76,7 @@ func init() {
 }
 
 func runReposDiscover() error {
-	repos, err := discoverGitHubRepos()
+	repos, err := discoverGitHubRepos(reposHostFlag)
 	if err != nil {
 		return err
 	}
@@ -100,7 +105,7 @@ func runReposDiscover() error {
 }
 
 func runReposImport() error {
-	repos, err := discoverGitHubRepos()
+	repos, err := discoverGitHubRepos(reposHostFlag)
 	if err != nil {
 		return err
 	}
@@ -201,20 +206,36 @@ func runReposList() error {
 	return nil
 }
 
-func discoverGitHubRepos() ([]ghcli.Repo, error) {
+func discoverGitHubRepos(rawHost string) ([]ghcli.Repo, error) {
+	host, err := ghcli.NormalizeHost(rawHost)
+	if err != nil {
+		return nil, err
+	}
 	if !isGhAvailableFn() {
 		return nil, fmt.Errorf("gh CLI invalid or not found")
 	}
-	if !isGhAuthenticatedFn() {
+	if host != "" {
+		if !isGhHostAuthedFn(host) {
+			return nil, fmt.Errorf("gh CLI not authenticated to %s; run `gh auth login --hostname %s`", host, host)
+		}
+	} else if !isGhAuthenticatedFn() {
 		return nil, fmt.Errorf("gh CLI not authenticated; run `gh auth login`")
 	}
-	return discoverGhReposFn()
+	return discoverGhReposFn(host)
 }
 
 func ensureBareRepoInitialized(repo ghcli.Repo, barePath, basePath string) error {
+	return ensureBareRepo(repo.NameWithOwner, barePath, basePath, func() error {
+		return cloneGhRepoFn(ghcli.CloneName(repo), barePath)
+	})
+}
+
+// ensureBareRepo clones name into barePath with clone unless it is already
+// there, then adds the base worktree at basePath unless that exists.
+func ensureBareRepo(name, barePath, basePath string, clone func() error) error {
 	if _, err := os.Stat(barePath); errorsIsNotExist(err) {
-		if err := cloneGhRepoFn(repo.NameWithOwner, barePath); err != nil {
-			return fmt.Errorf("clone bare repository %s: %w", repo.NameWithOwner, err)
+		if err := clone(); err != nil {
+			return fmt.Errorf("clone bare repository %s: %w", name, err)
 		}
 	} else if err != nil {
 		return fmt.Errorf("check bare repo path %s: %w", barePath, err)
@@ -225,7 +246,7 @@ func ensureBareRepoInitialized(repo ghcli.Repo, barePath, basePath string) error
 			return fmt.Errorf("create base worktree parent: %w", err)
 		}
 		if err := gitRunner(nil, "--git-dir", barePath, "worktree", "add", basePath); err != nil {
-			return fmt.Errorf("create base worktree for %s: %w", repo.NameWithOwner, err)
+			return fmt.Errorf("create base worktree for %s: %w", name, err)
 		}
 	} else if err != nil {
 		return fmt.Errorf("check base worktree path %s: %w", basePath, err)
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
 )
 
 func TestParseIndex(t *testing.T) {
@@ -104,3 +105,90 @@ func TestEnsureBareRepoInitialized(t *testing.T) {
 		t.Fatalf("expected worktree add call, got %q", calls[1])
 	}
 }
+
+func TestParseCloneURL(t *testing.T) {
+	tests := []struct {
+		raw, host, name string
+		wantErr         bool
+	}{
+		{raw: "git@github.com:acme/api.git", host: "github.com", name: "acme/api"},
+		{raw: "github.com:acme/api", host: "github.com", name: "acme/api"},
+		{raw: "https://github.com/acme/api.git", host: "github.com", name: "acme/api"},
+		{raw: "https://git.example.com:8443/group/sub/service/", host: "git.example.com", name: "sub/service"},
+		{raw: "ssh://git@git.example.com:2222/team/tool.git", host: "git.example.com", name: "team/tool"},
+		{raw: "https://git.example.com/solo", host: "git.example.com", name: ""},
+		{raw: "file:///tmp/repo.git", wantErr: true},
+		{raw: "/tmp/repo.git", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		host, name, err := parseCloneURL(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseCloneURL(%q) err = %v, wantErr %v", tt.raw, err, tt.wantErr)
+		}
+		if host != tt.host || name != tt.name {
+			t.Fatalf("parseCloneURL(%q) = %q, %q; want %q, %q", tt.raw, host, name, tt.host, tt.name)
+		}
+	}
+}
+
+func TestRunReposAddAndRm(t *testing.T) {
+	fogHome := t.TempDir()
+	t.Setenv("FOG_HOME", fogHome)
+
+	var cloneArgs []string
+	origRunner := gitRunner
+	t.Cleanup(func() {
+		gitRunner = origRunner
+		reposAddNameFlag, reposAddDefaultBranchFlag, reposRmPurgeFlag = "", "", false
+	})
+	gitRunner = func(extraEnv []string, args ...string) error {
+		if args[0] == "clone" {
+			cloneArgs = args
+		}
+		// Both the bare clone and worktree add end with the path they create.
+		return os.MkdirAll(args[len(args)-1], 0o755)
+	}
+	reposAddDefaultBranchFlag = "develop"
+
+	url := "git@git.example.com:acme/api.git"
+	if err := runReposAdd(url); err != nil {
+		t.Fatalf("runReposAdd: %v", err)
+	}
+	repoDir := filepath.Join(fogHome, "repos", "acme", "api")
+	if want := []string{"clone", "--bare", url, filepath.Join(repoDir, "repo.git")}; !reflect.DeepEqual(cloneArgs, want) {
+		t.Fatalf("clone args = %v, want %v", cloneArgs, want)
+	}
+
+	store, err := state.NewStore(fogHome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, found, err := store.GetRepoByName("acme/api")
+	_ = store.Close()
+	if err != nil || !found {
+		t.Fatalf("repo not registered: found=%v err=%v", found, err)
+	}
+	if repo.URL != url || repo.Host != "git.example.com" || repo.DefaultBranch != "develop" || repo.BaseWorktreePath != filepath.Join(repoDir, "base") {
+		t.Fatalf("unexpected repo: %+v", repo)
+	}
+
+	// Re-adding the same URL is a no-op; a different URL under the same name is refused.
+	if err := runReposAdd(url); err != nil {
+		t.Fatalf("re-add: %v", err)
+	}
+	if err := runReposAdd("https://git.example.com/acme/api"); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Fatalf("err = %v, want already registered", err)
+	}
+
+	reposRmPurgeFlag = true
+	if err := runReposRm("acme/api"); err != nil {
+		t.Fatalf("runReposRm: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(fogHome, "repos", "acme")); !os.IsNotExist(err) {
+		t.Fatalf("managed dir not purged: %v", err)
+	}
+	if err := runReposRm("acme/api"); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Fatalf("err = %v, want not registered", err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// runPlan is what `fog run --dry-run` reports: the session the flags resolve
+// to, without creating a worktree or calling the AI tool.
+type runPlan struct {
+	Repo           string   `json:"repo"`
+	RepoPath       string   `json:"repo_path"`
+	Tool           string   `json:"tool"`
+	Model          string   `json:"model,omitempty"`
+	Branch         string   `json:"branch"`
+	BaseBranch     string   `json:"base_branch"`
+	Worktree       string   `json:"worktree"`
+	Prompt         string   `json:"prompt"`
+	AutoPR         bool     `json:"autopr"`
+	PRTitle        string   `json:"pr_title,omitempty"`
+	SetupCmd       string   `json:"setup_cmd,omitempty"`
+	Validate       bool     `json:"validate"`
+	ValidateCmd    string   `json:"validate_cmd,omitempty"`
+	CommitStrategy string   `json:"commit_strategy,omitempty"`
+	FocusPaths     []string `json:"focus_paths,omitempty"`
+	Timeout        string   `json:"timeout,omitempty"`
+}
+
+var getToolFn = ai.GetTool
+
+// lookupRepoForPlan finds an already-registered repo. A dry run must not
+// import or clone, so an unknown repo is an error rather than a prompt.
+func lookupRepoForPlan(repoName string, store *state.Store) (state.Repo, error) {
+	repo, found, err := store.GetRepoByName(repoName)
+	if err != nil {
+		return state.Repo{}, err
+	}
+	if !found {
+		return state.Repo{}, fmt.Errorf("repo %q is not registered; run `fog repos import` first (--dry-run never imports)", repoName)
+	}
+	return repo, nil
+}
+
+// planRun checks what a real run would refuse on, a dirty base worktree, a
+// missing tool or a command off the command allowlist, and resolves where the
+// worktree would go. worktreeDir is the repo's runner.WorktreeDir.
+func planRun(r *runner.Runner, opts runner.StartSessionOptions, worktreeDir string) (runPlan, error) {
+	dirty, err := git.New(opts.RepoPath).IsDirty()
+	if err != nil {
+		return runPlan{}, fmt.Errorf("check repo %s: %w", opts.RepoPath, err)
+	}
+	if dirty {
+		return runPlan{}, fmt.Errorf("repo %s has uncommitted changes", opts.RepoPath)
+	}
+
+	tool, err := getToolFn(opts.Tool)
+	if err != nil {
+		return runPlan{}, err
+	}
+	if !tool.IsAvailable() {
+		return runPlan{}, fmt.Errorf("AI tool %q is not installed", opts.Tool)
+	}
+	if err := r.CheckSessionCommands(opts); err != nil {
+		return runPlan{}, err
+	}
+
+	worktree, err := runner.PlanWorktreePath(opts.RepoPath, worktreeDir, opts.Branch)
+	if err != nil {
+		return runPlan{}, err
+	}
+
+	plan := runPlan{
+		Repo:           opts.RepoName,
+		RepoPath:       opts.RepoPath,
+		Tool:           opts.Tool,
+		Model:          opts.Model,
+		Branch:         strings.TrimSpace(opts.Branch),
+		BaseBranch:     opts.BaseBranch,
+		Worktree:       worktree,
+		Prompt:         opts.Prompt,
+		AutoPR:         opts.AutoPR,
+		PRTitle:        opts.PRTitle,
+		SetupCmd:       opts.SetupCmd,
+		Validate:       opts.Validate,
+		ValidateCmd:    opts.ValidateCmd,
+		CommitStrategy: opts.CommitStrategy,
+		FocusPaths:     opts.FocusPaths,
+	}
+	if opts.Timeout > 0 {
+		plan.Timeout = opts.Timeout.String()
+	}
+	return plan, nil
+}
+
+func printRunPlan(w io.Writer, plan runPlan, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	fmt.Fprintf(w, "Dry run: nothing will be created\n")
+	fmt.Fprintf(w, "Repo: %s (%s)\n", plan.Repo, plan.RepoPath)
+	fmt.Fprintf(w, "Branch: %s (from %s)\n", plan.Branch, plan.BaseBranch)
+	fmt.Fprintf(w, "Worktree: %s\n", plan.Worktree)
+	if plan.Model != "" {
+		fmt.Fprintf(w, "AI Tool: %s (%s)\n", plan.Tool, plan.Model)
+	} else {
+		fmt.Fprintf(w, "AI Tool: %s\n", plan.Tool)
+	}
+	fmt.Fprintf(w, "Prompt: %s\n", plan.Prompt)
+	if plan.SetupCmd != "" {
+		fmt.Fprintf(w, "Setup: %s\n", plan.SetupCmd)
+	}
+	if plan.Validate && plan.ValidateCmd != "" {
+		fmt.Fprintf(w, "Validate: %s\n", plan.ValidateCmd)
+	} else if plan.Validate {
+		fmt.Fprintf(w, "Validate: yes\n")
+	}
+	if plan.CommitStrategy != "" {
+		fmt.Fprintf(w, "Commit strategy: %s\n", plan.CommitStrategy)
+	}
+	if len(plan.FocusPaths) > 0 {
+		fmt.Fprintf(w, "Focus: %s\n", strings.Join(plan.FocusPaths, ", "))
+	}
+	if plan.Timeout != "" {
+		fmt.Fprintf(w, "Timeout: %s\n", plan.Timeout)
+	}
+	if plan.AutoPR {
+		if plan.PRTitle != "" {
+			fmt.Fprintf(w, "PR: yes (%s)\n", plan.PRTitle)
+		} else {
+			fmt.Fprintf(w, "PR: yes\n")
+		}
+	}
+	return nil
+}
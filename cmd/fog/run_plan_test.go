@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+type planTool struct{ available bool }
+
+func (t planTool) Name() string                  { return "claude" }
+func (t planTool) IsAvailable() bool             { return t.available }
+func (t planTool) Capabilities() ai.Capabilities { return ai.Capabilities{} }
+func (t planTool) ExecuteStream(context.Context, ai.ExecuteRequest, func(string)) (*ai.Result, error) {
+	panic("a dry run must not execute the tool")
+}
+
+func stubPlanTool(t *testing.T, available bool) {
+	t.Helper()
+	orig := getToolFn
+	t.Cleanup(func() { getToolFn = orig })
+	getToolFn = func(string) (ai.Tool, error) { return planTool{available: available}, nil }
+}
+
+// newPlanRunner returns a runner over a fresh store, with command_allowlist
+// set to allowlist when it is not empty.
+func newPlanRunner(t *testing.T, allowlist string) *runner.Runner {
+	t.Helper()
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if allowlist != "" {
+		if err := store.SetSetting(runner.SettingCommandAllowlist, allowlist); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return runner.New(store)
+}
+
+func initPlanRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.email=fog@test", "-c", "user.name=fog", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestPlanRunReportsWorktreeWithoutCreatingIt(t *testing.T) {
+	repo := initPlanRepo(t)
+	stubPlanTool(t, true)
+
+	plan, err := planRun(newPlanRunner(t, ""), runner.StartSessionOptions{
+		RepoName:   "acme/api",
+		RepoPath:   repo,
+		Branch:     "fog/otp login",
+		Tool:       "claude",
+		Prompt:     "Add OTP login",
+		BaseBranch: "main",
+	}, "")
+	if err != nil {
+		t.Fatalf("planRun: %v", err)
+	}
+	if base := filepath.Base(plan.Worktree); base != "fog-otp-login-<run-id>" {
+		t.Fatalf("worktree = %q, want a fog-otp-login-<run-id> leaf", plan.Worktree)
+	}
+	if _, err := os.Stat(plan.Worktree); !os.IsNotExist(err) {
+		t.Fatalf("dry run created %s (stat err %v)", plan.Worktree, err)
+	}
+
+	var out bytes.Buffer
+	if err := printRunPlan(&out, plan, true); err != nil {
+		t.Fatalf("printRunPlan: %v", err)
+	}
+	var decoded runPlan
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("plan JSON: %v\n%s", err, out.String())
+	}
+	if decoded.Branch != "fog/otp login" || decoded.Tool != "claude" || decoded.Worktree != plan.Worktree {
+		t.Fatalf("decoded plan = %+v", decoded)
+	}
+}
+
+func TestPlanRunRefusesDirtyRepoAndMissingTool(t *testing.T) {
+	repo := initPlanRepo(t)
+	opts := runner.StartSessionOptions{RepoName: "acme/api", RepoPath: repo, Branch: "fog/x", Tool: "claude", BaseBranch: "main"}
+	r := newPlanRunner(t, "")
+
+	stubPlanTool(t, false)
+	if _, err := planRun(r, opts, ""); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Fatalf("planRun with missing tool = %v, want not installed", err)
+	}
+
+	stubPlanTool(t, true)
+	if err := os.WriteFile(filepath.Join(repo, "stray.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := planRun(r, opts, ""); err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Fatalf("planRun on dirty repo = %v, want uncommitted changes", err)
+	}
+}
+
+func TestPlanRunRejectsCommandsOffTheAllowlist(t *testing.T) {
+	repo := initPlanRepo(t)
+	stubPlanTool(t, true)
+	r := newPlanRunner(t, `["make"]`)
+	opts := runner.StartSessionOptions{RepoName: "acme/api", RepoPath: repo, Branch: "fog/x", Tool: "claude", BaseBranch: "main", SetupCmd: "npm ci"}
+
+	if _, err := planRun(r, opts, ""); !errors.Is(err, runner.ErrCommandNotAllowed) || !strings.Contains(err.Error(), "setup_cmd") {
+		t.Fatalf("planRun with setup_cmd npm ci = %v, want a setup_cmd allowlist rejection", err)
+	}
+
+	opts.SetupCmd = "make deps"
+	opts.Validate = true
+	opts.ValidateCmd = "go test ./..."
+	if _, err := planRun(r, opts, ""); !errors.Is(err, runner.ErrCommandNotAllowed) || !strings.Contains(err.Error(), "validate_cmd") {
+		t.Fatalf("planRun with validate_cmd go test = %v, want a validate_cmd allowlist rejection", err)
+	}
+
+	opts.ValidateCmd = "make test"
+	if _, err := planRun(r, opts, ""); err != nil {
+		t.Fatalf("planRun with allowed commands: %v", err)
+	}
+}
+
+type planDefaultTool string
+
+func (d planDefaultTool) GetDefaultTool() (string, bool, error) { return string(d), d != "", nil }
+
+func TestResolveRunOptionsAppliesRepoConfig(t *testing.T) {
+	repo := initPlanRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, runner.RepoConfigFile), []byte("setup_cmd: make deps\nvalidate_cmd: make test\ntool: codex\nbase_branch: trunk\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := newPlanRunner(t, "")
+	managed := state.Repo{Name: "acme/api", BaseWorktreePath: repo, DefaultBranch: "develop"}
+
+	opts, err := resolveRunOptions(r, managed, planDefaultTool("claude"))
+	if err != nil {
+		t.Fatalf("resolveRunOptions: %v", err)
+	}
+	if opts.Tool != "codex" || opts.BaseBranch != "trunk" || opts.SetupCmd != "make deps" || opts.ValidateCmd != "make test" {
+		t.Fatalf("opts = tool %q base %q setup %q validate %q, want the .fog.yaml values", opts.Tool, opts.BaseBranch, opts.SetupCmd, opts.ValidateCmd)
+	}
+
+	origTool, origBase, origSetup := flagTool, flagBaseBranch, flagSetupCmd
+	t.Cleanup(func() { flagTool, flagBaseBranch, flagSetupCmd = origTool, origBase, origSetup })
+	flagTool, flagBaseBranch, flagSetupCmd = "claude", "main", "npm ci"
+	opts, err = resolveRunOptions(r, managed, planDefaultTool(""))
+	if err != nil {
+		t.Fatalf("resolveRunOptions: %v", err)
+	}
+	if opts.Tool != "claude" || opts.BaseBranch != "main" || opts.SetupCmd != "npm ci" || opts.ValidateCmd != "make test" {
+		t.Fatalf("opts = tool %q base %q setup %q validate %q, want the flags to win", opts.Tool, opts.BaseBranch, opts.SetupCmd, opts.ValidateCmd)
+	}
+
+	flagTool = ""
+	managed.DefaultTool = "cursor"
+	if opts, err = resolveRunOptions(r, managed, planDefaultTool("")); err != nil || opts.Tool != "cursor" {
+		t.Fatalf("tool = %q (%v), want the repo default tool set through Fog", opts.Tool, err)
+	}
+}
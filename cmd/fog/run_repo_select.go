@@ -161,7 +161,7 @@ func listGitHubRepos() ([]ghcli.Repo, error) {
 	if !isGhAuthenticatedFn() {
 		return nil, fmt.Errorf("gh CLI not authenticated; run `gh auth login`")
 	}
-	return discoverGhReposFn()
+	return discoverGhReposFn("")
 }
 
 func stdinIsTTY() bool {
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var sessionCmd = &cobra.Command{
+	Use:     "session",
+	Aliases: []string{"sessions"},
+	Short:   "Work with sessions",
+}
+
+var sessionExportCmd = &cobra.Command{
+	Use:   "export <session-id>",
+	Short: "Print a session as a shareable JSON bundle",
+	Long: `Print a session with all its runs and their events and its final diff as
+one JSON document, for attaching to an incident review or PR. Session env
+values and anything that looks like a credential are redacted.
+
+Example:
+  fog session export 3f2a9c1e-... > bundle.json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSessionExport(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	sessionCmd.AddCommand(sessionExportCmd)
+	rootCmd.AddCommand(sessionCmd)
+}
+
+// sessionExporter is the part of *runner.Runner that fog session export uses.
+type sessionExporter interface {
+	ExportSession(sessionID string) (runner.SessionExport, error)
+}
+
+func runSessionExport(sessionID string) error {
+	fogHome, err := env.FogHome()
+	if err != nil {
+		return err
+	}
+	stateStore, err := state.NewStore(fogHome)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stateStore.Close() }()
+
+	return writeSessionExport(os.Stdout, runner.New(stateStore), sessionID)
+}
+
+func writeSessionExport(w io.Writer, exporter sessionExporter, sessionID string) error {
+	export, err := exporter.ExportSession(sessionID)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
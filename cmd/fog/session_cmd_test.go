@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestWriteSessionExport(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if _, err := store.UpsertRepo(state.Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/missing/repo.git", BaseWorktreePath: "/missing/base", DefaultBranch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	if err := store.CreateSession(state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/x", WorktreePath: "/missing", Tool: "claude", Status: "COMPLETED", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateRun(state.Run{ID: "run-1", SessionID: "session-1", Prompt: "fix it", WorktreePath: "/missing", State: "COMPLETED", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSessionExport(&buf, runner.New(store), "session-1"); err != nil {
+		t.Fatalf("writeSessionExport: %v", err)
+	}
+	var out runner.SessionExport
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v\n%s", err, buf.String())
+	}
+	if out.Session.ID != "session-1" || len(out.Runs) != 1 || out.Runs[0].Prompt != "fix it" {
+		t.Fatalf("unexpected export: %+v", out)
+	}
+
+	if err := writeSessionExport(&buf, runner.New(store), "missing"); err == nil {
+		t.Fatal("expected an error for an unknown session")
+	}
+}
@@ -4,15 +4,28 @@ import type {
     CancelResponse,
     CreateSessionPayload,
     CreateSessionResponse,
+    DeleteRepoResponse,
     DiffResult,
     DiscoveredRepo,
+    DiskUsage,
     FollowupResponse,
+    ImportDryRunResponse,
     ImportResponse,
     OpenResponse,
+    SetupResult,
+    SessionEditor,
+    SessionExport,
+    SessionChange,
     Repo,
+    RetryResponse,
+    RunDiff,
     RunEvent,
     SessionDetail,
+    SessionListQuery,
+    SessionPage,
+    SessionPreview,
     SessionSummary,
+    SessionUsage,
     Settings,
     UpdateSettingsPayload,
     Branch,
@@ -25,6 +38,7 @@ import type {
     TrackerConfig,
     UpdateTrackerPayload,
     SyncResult,
+    ToolInfo,
 } from "./types";
 
 let apiBaseURL = "http://127.0.0.1:8080";
@@ -91,6 +105,7 @@ async function fetchJSON<T>(path: string, options?: RequestInit): Promise<T> {
     if (apiToken) {
         headers.set("Authorization", "Bearer " + apiToken);
     }
+    headers.set("X-Fog-Client", "desktop");
     opts.headers = headers;
     const res = await fetch(url, opts);
     if (!res.ok) {
@@ -121,28 +136,110 @@ export async function updateSettings(
     });
 }
 
+export async function fetchTools(): Promise<ToolInfo[]> {
+    return fetchJSON<ToolInfo[]>("/api/tools");
+}
+
 export async function fetchRepos(): Promise<Repo[]> {
     return fetchJSON<Repo[]>("/api/repos");
 }
 
-export async function discoverRepos(): Promise<DiscoveredRepo[]> {
-    return fetchJSON<DiscoveredRepo[]>("/api/repos/discover", {
+/** List repos gh can see; host targets a GitHub Enterprise Server. */
+export async function discoverRepos(host?: string): Promise<DiscoveredRepo[]> {
+    const query = host ? `?host=${encodeURIComponent(host)}` : "";
+    return fetchJSON<DiscoveredRepo[]>(`/api/repos/discover${query}`, {
         method: "POST",
     });
 }
 
 export async function importRepos(
     repos: string[],
+    host?: string,
 ): Promise<ImportResponse> {
     return fetchJSON<ImportResponse>("/api/repos/import", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ repos, host }),
+    });
+}
+
+/** Check repos for import without cloning: access, default branch and size. */
+export async function checkImportRepos(
+    repos: string[],
+): Promise<ImportDryRunResponse> {
+    return fetchJSON<ImportDryRunResponse>("/api/repos/import?dry_run=1", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
         body: JSON.stringify({ repos }),
     });
 }
 
+/**
+ * Stop managing a repo; purge also deletes its clone and worktrees. A repo
+ * with sessions is refused, as is a purge that would delete unmerged
+ * branches unless force confirms it.
+ */
+export async function deleteRepo(
+    repoName: string,
+    purge = false,
+    force = false,
+): Promise<DeleteRepoResponse> {
+    const params = new URLSearchParams({ name: repoName });
+    if (purge) {
+        params.set("purge", "true");
+    }
+    if (force) {
+        params.set("force", "true");
+    }
+    return fetchJSON<DeleteRepoResponse>("/api/repos?" + params.toString(), {
+        method: "DELETE",
+    });
+}
+
+export async function setRepoDefaults(
+    repoName: string,
+    defaultTool: string,
+    defaultModel: string,
+): Promise<Repo> {
+    return fetchJSON<Repo>(
+        "/api/repos/" + repoName + "/defaults",
+        {
+            method: "PUT",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({
+                default_tool: defaultTool,
+                default_model: defaultModel,
+            }),
+        },
+    );
+}
+
 export async function fetchSessions(): Promise<SessionSummary[]> {
-    return fetchJSON<SessionSummary[]>("/api/sessions");
+    const page = await fetchSessionPage();
+    return page.sessions;
+}
+
+export async function fetchSessionPage(
+    query: SessionListQuery = {},
+): Promise<SessionPage> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(query)) {
+        if (value !== undefined && value !== "") {
+            params.set(key, String(value));
+        }
+    }
+    const qs = params.toString();
+    return fetchJSON<SessionPage>("/api/sessions" + (qs ? "?" + qs : ""));
+}
+
+/** Sessions forked from sessionID, oldest first. */
+export async function fetchSessionChildren(
+    sessionID: string,
+): Promise<SessionSummary[]> {
+    const page = await fetchJSON<{ sessions: SessionSummary[] }>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + "/children",
+    );
+    return page.sessions;
 }
 
 export async function fetchBranches(repoName: string): Promise<Branch[]> {
@@ -169,6 +266,16 @@ export async function createSession(
     });
 }
 
+export async function previewSession(
+    payload: CreateSessionPayload,
+): Promise<SessionPreview> {
+    return fetchJSON<SessionPreview>("/api/sessions/preview", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(payload),
+    });
+}
+
 export async function followUp(
     sessionID: string,
     prompt: string,
@@ -183,6 +290,20 @@ export async function followUp(
     );
 }
 
+export async function retryRun(
+    sessionID: string,
+    runID: string,
+): Promise<RetryResponse> {
+    return fetchJSON<RetryResponse>(
+        "/api/sessions/" +
+        encodeURIComponent(sessionID) +
+        "/runs/" +
+        encodeURIComponent(runID) +
+        "/retry",
+        { method: "POST" },
+    );
+}
+
 export async function forkSession(
     sessionID: string,
     prompt: string,
@@ -206,12 +327,70 @@ export async function cancelSession(
     );
 }
 
+/**
+ * Delete a finished session with its runs and worktree. The branch is kept.
+ * Without `force` the request fails with 409 when the worktree has
+ * uncommitted changes.
+ */
+export async function deleteSession(
+    sessionID: string,
+    force = false,
+): Promise<void> {
+    await fetchJSON<null>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            (force ? "?force=true" : ""),
+        { method: "DELETE" },
+    );
+}
+
 export async function fetchDiff(sessionID: string): Promise<DiffResult> {
     return fetchJSON<DiffResult>(
         "/api/sessions/" + encodeURIComponent(sessionID) + "/diff",
     );
 }
 
+export async function fetchRunDiff(
+    sessionID: string,
+    runID: string,
+): Promise<RunDiff> {
+    return fetchJSON<RunDiff>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            "/runs/" +
+            encodeURIComponent(runID) +
+            "/diff",
+    );
+}
+
+export async function fetchDiskUsage(
+    sessionID: string,
+    excludeGit = false,
+): Promise<DiskUsage> {
+    return fetchJSON<DiskUsage>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            "/disk-usage" +
+            (excludeGit ? "?exclude_git=true" : ""),
+    );
+}
+
+export async function fetchSessionUsage(
+    sessionID: string,
+): Promise<SessionUsage> {
+    return fetchJSON<SessionUsage>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + "/usage",
+    );
+}
+
+export async function fetchSessionExport(
+    sessionID: string,
+): Promise<SessionExport> {
+    return fetchJSON<SessionExport>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + "/export",
+    );
+}
+
 export async function openInEditor(
     sessionID: string,
 ): Promise<OpenResponse> {
@@ -221,6 +400,81 @@ export async function openInEditor(
     );
 }
 
+export async function fetchSessionEditor(
+    sessionID: string,
+): Promise<SessionEditor> {
+    return fetchJSON<SessionEditor>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + "/editor",
+    );
+}
+
+export async function acceptSessionRun(
+    sessionID: string,
+    runID: string,
+): Promise<SessionDetail> {
+    return fetchJSON<SessionDetail>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            "/accept?run=" +
+            encodeURIComponent(runID),
+        { method: "POST" },
+    );
+}
+
+export async function setSessionPaused(
+    sessionID: string,
+    paused: boolean,
+): Promise<SessionDetail> {
+    return fetchJSON<SessionDetail>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            (paused ? "/pause" : "/resume"),
+        { method: "POST" },
+    );
+}
+
+/** Hide a session from the default list, or bring it back; nothing is deleted. */
+export async function setSessionArchived(
+    sessionID: string,
+    archived: boolean,
+): Promise<SessionDetail> {
+    return fetchJSON<SessionDetail>(
+        "/api/sessions/" +
+            encodeURIComponent(sessionID) +
+            (archived ? "/archive" : "/unarchive"),
+        { method: "POST" },
+    );
+}
+
+export async function renameSessionBranch(
+    sessionID: string,
+    branch: string,
+): Promise<SessionDetail> {
+    return fetchJSON<SessionDetail>(
+        "/api/sessions/" + encodeURIComponent(sessionID),
+        {
+            method: "PATCH",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ branch }),
+        },
+    );
+}
+
+/** Re-run the setup command in the session worktree; omit setupCmd to use .fog.yaml's. */
+export async function rerunSetup(
+    sessionID: string,
+    setupCmd?: string,
+): Promise<SetupResult> {
+    return fetchJSON<SetupResult>(
+        "/api/sessions/" + encodeURIComponent(sessionID) + "/setup",
+        {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify(setupCmd ? { setup_cmd: setupCmd } : {}),
+        },
+    );
+}
+
 export async function fetchRunEvents(
     sessionID: string,
     runID: string,
@@ -238,6 +492,28 @@ export async function fetchRunEvents(
 
 // ── SSE streaming ──
 
+export function openSessionEvents(
+    onChange: (change: SessionChange) => void,
+    onError: () => void,
+): EventSource {
+    const source = new EventSource(apiBaseURL + "/api/events");
+
+    source.addEventListener("session", (ev) => {
+        try {
+            onChange(JSON.parse(ev.data) as SessionChange);
+        } catch {
+            // ignore malformed events
+        }
+    });
+
+    source.onerror = () => {
+        source.close();
+        onError();
+    };
+
+    return source;
+}
+
 export function openRunStream(
     sessionID: string,
     runID: string,
@@ -7,6 +7,7 @@ import type {
     Repo,
     RunEvent,
     RunSummary,
+    SessionChange,
     SessionSummary,
     Settings,
     Task,
@@ -26,6 +27,7 @@ import {
     purgeTask,
     restoreTask,
     openRunStream,
+    openSessionEvents,
     resolveAPIBaseURL,
     resolveAPIToken,
     resolveVersion,
@@ -90,6 +92,9 @@ class AppState {
     private streamSessionID = "";
     private streamRunID = "";
 
+    // Session list changes; polling covers the gaps while it is closed.
+    private sessionEvents: EventSource | null = null;
+
     // Polling
     private pollInterval: ReturnType<typeof setInterval> | null = null;
 
@@ -500,10 +505,70 @@ class AppState {
         );
     }
 
+    // ── Session events ──
+
+    private openSessionEvents(): void {
+        if (this.sessionEvents) return;
+        this.sessionEvents = openSessionEvents(
+            (change) => this.applySessionChange(change),
+            () => {
+                this.sessionEvents = null;
+            },
+        );
+    }
+
+    private applySessionChange(change: SessionChange): void {
+        const session = this.sessions.find((s) => s.id === change.session_id);
+        if (change.kind === "deleted") {
+            this.sessions = this.sessions.filter(
+                (s) => s.id !== change.session_id,
+            );
+            return;
+        }
+        if (
+            !session ||
+            change.kind === "run" ||
+            change.kind === "archived" ||
+            change.kind === "worktree_pruned"
+        ) {
+            // A new session or run, or one whose place in the list or
+            // timestamps changed: the list needs fields a change lacks.
+            this.refreshSessions().catch(() => { });
+        } else {
+            this.sessions = this.sessions.map((s) => {
+                if (s.id !== change.session_id) return s;
+                switch (change.kind) {
+                    case "status":
+                        return { ...s, status: change.status ?? s.status };
+                    case "busy":
+                        return { ...s, busy: change.busy ?? false };
+                    case "pr":
+                        return { ...s, pr_url: change.pr_url };
+                    case "branch":
+                        return { ...s, branch: change.branch ?? s.branch };
+                    case "paused":
+                        return { ...s, paused: change.paused ?? false };
+                }
+                return s;
+            });
+        }
+        if (
+            change.session_id === this.selectedSessionID &&
+            this.currentView === "detail"
+        ) {
+            this.loadDetail().catch(() => { });
+        }
+    }
+
     // ── Polling ──
 
     private startPolling(): void {
+        this.openSessionEvents();
         this.pollInterval = setInterval(() => {
+            // Reconnect a dropped event stream, resyncing what it missed.
+            // While it is open, changes arrive without polling.
+            if (this.sessionEvents) return;
+            this.openSessionEvents();
             this.refreshSessions()
                 .then(() => {
                     if (
@@ -524,6 +589,8 @@ class AppState {
         if (this.pollInterval) {
             clearInterval(this.pollInterval);
         }
+        this.sessionEvents?.close();
+        this.sessionEvents = null;
         this.closeStream();
     }
 }
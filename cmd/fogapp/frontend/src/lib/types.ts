@@ -11,9 +11,36 @@ export interface SessionSummary {
     pr_url?: string;
     status: string;
     busy: boolean;
+    ephemeral?: boolean;
+    origin?: string;
+    commit_strategy?: string;
+    issue_ref?: string;
+    close_issue?: boolean;
+    env?: Record<string, string>;
+    paused?: boolean;
+    accepted_run_id?: string;
+    slack_channel_id?: string;
+    slack_thread_ts?: string;
+    archived_at?: string;
+    worktree_pruned_at?: string;
+    parent_session_id?: string;
     created_at: string;
     updated_at: string;
     latest_run?: RunSummary;
+    worktree_present?: boolean;
+}
+
+export interface SessionPage {
+    sessions: SessionSummary[];
+    total: number;
+}
+
+export interface SessionListQuery {
+    limit?: number;
+    offset?: number;
+    repo?: string;
+    status?: string;
+    include_archived?: boolean;
 }
 
 export interface RunSummary {
@@ -28,6 +55,11 @@ export interface RunSummary {
     created_at: string;
     updated_at: string;
     completed_at?: string;
+    input_tokens?: number;
+    output_tokens?: number;
+    cost_usd?: number;
+    attachments?: string[];
+    context_text?: string;
 }
 
 export interface RunEvent {
@@ -42,6 +74,9 @@ export interface RunEvent {
 export interface SessionDetail {
     session: SessionSummary;
     runs: RunSummary[];
+    ahead?: number;
+    behind?: number;
+    worktree_present: boolean;
 }
 
 export interface DiffResult {
@@ -52,6 +87,59 @@ export interface DiffResult {
     patch: string;
 }
 
+export interface RunDiff {
+    run_id: string;
+    commit_sha?: string;
+    base_sha?: string;
+    stat: string;
+    patch: string;
+}
+
+export interface DiskUsage {
+    session_id: string;
+    worktree_path: string;
+    exists: boolean;
+    bytes: number;
+    files: number;
+    exclude_git: boolean;
+    computed_at: string;
+}
+
+export interface SessionUsage {
+    session_id: string;
+    input_tokens?: number;
+    output_tokens?: number;
+    cost_usd?: number;
+    runs_with_usage: number;
+}
+
+export interface SessionExport {
+    version: number;
+    exported_at: string;
+    session: Omit<SessionSummary, "latest_run">;
+    runs: (RunSummary & { events: RunEvent[] })[];
+    diff?: {
+        stat: string;
+        patch: string;
+        truncated?: boolean;
+    };
+    diff_error?: string;
+}
+
+/** A session list change pushed on GET /api/events. */
+export interface SessionChange {
+    session_id: string;
+    kind: "status" | "busy" | "run" | "pr" | "branch" | "paused" | "archived" | "worktree_pruned" | "deleted";
+    status?: string;
+    busy?: boolean;
+    run_id?: string;
+    pr_url?: string;
+    branch?: string;
+    paused?: boolean;
+    archived?: boolean;
+    at: string;
+}
+
 export interface Settings {
     default_tool?: string;
     default_model?: string;
@@ -59,8 +147,26 @@ export interface Settings {
     default_autopr: boolean;
     default_notify: boolean;
     keep_awake: boolean;
+    run_log_files: boolean;
     branch_prefix?: string;
+    branch_name_regex?: string;
+    default_user?: string;
+    followup_dirty_policy?: string;
+    validate_fail_policy: string;
+    scratch_dir?: string;
+    default_worktree_root?: string;
+    default_base_branch?: string;
+    tool_exec_wrapper?: string;
+    tool_args?: Record<string, string[]>;
+    model_fallbacks?: Record<string, string[]>;
+    command_allowlist?: string[];
+    commit_template?: string;
+    commit_sign: string;
+    commit_signing_key?: string;
     trash_retention_days: number;
+    max_sessions_per_repo: number;
+    session_retention_action: string;
+    worktree_ttl_days: number;
     gh_installed: boolean;
     gh_authenticated: boolean;
     onboarding_required: boolean;
@@ -74,8 +180,34 @@ export interface UpdateSettingsPayload {
     default_autopr?: boolean;
     default_notify?: boolean;
     keep_awake?: boolean;
+    run_log_files?: boolean;
     branch_prefix?: string;
+    branch_name_regex?: string;
+    default_user?: string;
+    followup_dirty_policy?: string;
+    validate_fail_policy?: string;
+    scratch_dir?: string;
+    default_worktree_root?: string;
+    default_base_branch?: string;
+    tool_exec_wrapper?: string;
+    tool_args?: Record<string, string[]>;
+    model_fallbacks?: Record<string, string[]>;
+    command_allowlist?: string[];
+    commit_template?: string;
+    commit_sign?: string;
+    commit_signing_key?: string;
     trash_retention_days?: number;
+    max_sessions_per_repo?: number;
+    session_retention_action?: string;
+    worktree_ttl_days?: number;
+}
+
+export interface ToolInfo {
+    name: string;
+    available: boolean;
+    supports_streaming: boolean;
+    supports_models: boolean;
+    supports_resume: boolean;
 }
 
 export interface Repo {
@@ -88,6 +220,9 @@ export interface Repo {
     bare_path?: string;
     base_worktree_path: string;
     default_branch?: string;
+    default_tool?: string;
+    default_model?: string;
+    worktree_root?: string;
     created_at?: string;
 }
 
@@ -97,6 +232,7 @@ export interface DiscoveredRepo {
     nameWithOwner: string;
     url: string;
     isPrivate: boolean;
+    diskUsage?: number;
     defaultBranchRef: { name: string };
     owner: { login: string };
 }
@@ -124,8 +260,42 @@ export interface CreateSessionPayload {
     validate_cmd?: string;
     base_branch?: string;
     commit_msg?: string;
+    commit_msg_mode?: string;
     async?: boolean;
     pr_title?: string;
+    focus_paths?: string[];
+    self_review?: boolean;
+    issue_ref?: string;
+    close_issue?: boolean;
+    env?: Record<string, string>;
+    user?: string;
+    attachments?: string[];
+    context_text?: string;
+}
+
+export interface SessionPreview {
+    repo: string;
+    tool: string;
+    model?: string;
+    branch?: string;
+    base_branch: string;
+    autopr: boolean;
+    setup_cmd?: string;
+    validate: boolean;
+    validate_cmd?: string;
+    validate_success_codes?: number[];
+    commit_msg?: string;
+    pr_title?: string;
+    ephemeral: boolean;
+    commit_strategy: string;
+    commit_msg_mode?: string;
+    focus_paths?: string[];
+    attachments?: string[];
+    self_review: boolean;
+    issue_ref?: string;
+    close_issue: boolean;
+    env?: Record<string, string>;
+    origin: string;
 }
 
 export interface CreateSessionResponse {
@@ -140,6 +310,10 @@ export interface FollowupResponse {
     session: string;
 }
 
+export interface RetryResponse extends FollowupResponse {
+    retry_of: string;
+}
+
 export interface CancelResponse {
     status: string;
     run_id: string;
@@ -151,15 +325,50 @@ export interface OpenResponse {
     worktree_path: string;
 }
 
+export interface SetupResult {
+    session_id: string;
+    setup_cmd: string;
+    exit_code: number;
+    output: string;
+}
+
+export interface SessionEditor {
+    editor?: string;
+    available: boolean;
+    worktree_path?: string;
+}
+
 export interface ImportResponse {
     imported: string[];
 }
 
+export interface ImportCheck {
+    repo: string;
+    accessible: boolean;
+    error?: string;
+    default_branch?: string;
+    private?: boolean;
+    disk_usage_kb?: number;
+    already_managed?: boolean;
+}
+
+export interface ImportDryRunResponse {
+    dry_run: boolean;
+    repos: ImportCheck[];
+}
+
+export interface DeleteRepoResponse {
+    name: string;
+    purged: string[];
+}
+
 export const ACTIVE_STATES: Record<string, boolean> = {
     CREATED: true,
     SETUP: true,
+    QUEUED: true,
     AI_RUNNING: true,
     VALIDATING: true,
+    REVIEWING: true,
     COMMITTED: true,
     PR_CREATED: true,
 };
@@ -339,7 +339,11 @@ func (m *mockFogAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	case r.Method == http.MethodGet && r.URL.Path == "/api/sessions":
-		writeJSON(http.StatusOK, m.sessionSummariesLocked())
+		sessions := m.sessionSummariesLocked()
+		writeJSON(http.StatusOK, map[string]any{
+			"sessions": sessions,
+			"total":    len(sessions),
+		})
 		return
 	case r.Method == http.MethodPost && r.URL.Path == "/api/sessions":
 		m.counters.createSessionCount++
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -25,6 +26,11 @@ var (
 	flagSlackSigning      string
 	flagSlackScopes       string
 	flagPairCodeTTL       time.Duration
+	flagAdminToken        string
+	flagJobClaimTimeout   time.Duration
+	flagUserRateLimit     int
+	flagDataDirMode       string
+	flagDBMode            string
 )
 
 func main() {
@@ -53,15 +59,30 @@ var versionCmd = &cobra.Command{
 func init() {
 	rootCmd.Flags().IntVar(&flagPort, "port", 9090, "HTTP server port")
 	rootCmd.Flags().StringVar(&flagDataDir, "data-dir", "", "Data directory for cloud sqlite/key (default: $FOG_HOME/cloud)")
+	rootCmd.Flags().StringVar(&flagDataDirMode, "data-dir-mode", "", "Octal permissions for the data directory (default 0700)")
+	rootCmd.Flags().StringVar(&flagDBMode, "db-mode", "", "Octal permissions for the sqlite database and its WAL files (default 0600)")
 	rootCmd.Flags().StringVar(&flagPublicURL, "public-url", "", "Public base URL for Slack OAuth callbacks (required)")
 	rootCmd.Flags().StringVar(&flagSlackClientID, "slack-client-id", "", "Slack app client ID (required)")
 	rootCmd.Flags().StringVar(&flagSlackClientSecret, "slack-client-secret", "", "Slack app client secret (required)")
 	rootCmd.Flags().StringVar(&flagSlackSigning, "slack-signing-secret", "", "Slack signing secret (required)")
 	rootCmd.Flags().StringVar(&flagSlackScopes, "slack-scopes", "app_mentions:read,chat:write", "Comma-separated Slack OAuth bot scopes")
 	rootCmd.Flags().DurationVar(&flagPairCodeTTL, "pair-code-ttl", 10*time.Minute, "Pairing code TTL")
+	rootCmd.Flags().StringVar(&flagAdminToken, "admin-token", "", "Bearer token for /v1/admin APIs (default: $FOG_CLOUD_ADMIN_TOKEN; admin APIs are disabled when empty)")
+	rootCmd.Flags().DurationVar(&flagJobClaimTimeout, "job-claim-timeout", time.Hour, "How long a device may go silent on a claimed job before it is requeued")
+	rootCmd.Flags().IntVar(&flagUserRateLimit, "user-rate-limit", 10, "Max @fog requests per Slack user per minute (0 disables)")
 	rootCmd.AddCommand(versionCmd)
 }
 
+// openStore opens the cloud store in dataDir with the permissions the flags
+// ask for.
+func openStore(dataDir string) (*cloud.Store, error) {
+	dirMode, dbMode, err := env.ParseStoreModes("--data-dir-mode", flagDataDirMode, "--db-mode", flagDBMode)
+	if err != nil {
+		return nil, err
+	}
+	return cloud.NewStoreWithOptions(dataDir, cloud.StoreOptions{DirMode: dirMode, DBMode: dbMode})
+}
+
 func runServer() error {
 	dataDir := strings.TrimSpace(flagDataDir)
 	if dataDir == "" {
@@ -76,24 +97,31 @@ func runServer() error {
 		return fmt.Errorf("at least one slack scope is required")
 	}
 
-	store, err := cloud.NewStore(dataDir)
+	store, err := openStore(dataDir)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = store.Close() }()
 
 	server, err := cloud.NewServer(store, cloud.Config{
-		ClientID:       strings.TrimSpace(flagSlackClientID),
-		ClientSecret:   strings.TrimSpace(flagSlackClientSecret),
-		SigningSecret:  strings.TrimSpace(flagSlackSigning),
-		PublicURL:      strings.TrimSpace(flagPublicURL),
-		Scopes:         scopes,
-		PairingCodeTTL: flagPairCodeTTL,
+		ClientID:        strings.TrimSpace(flagSlackClientID),
+		ClientSecret:    strings.TrimSpace(flagSlackClientSecret),
+		SigningSecret:   strings.TrimSpace(flagSlackSigning),
+		PublicURL:       strings.TrimSpace(flagPublicURL),
+		Scopes:          scopes,
+		PairingCodeTTL:  flagPairCodeTTL,
+		AdminToken:      adminToken(),
+		JobClaimTimeout: flagJobClaimTimeout,
+		UserRateLimit:   flagUserRateLimit,
 	})
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.StartJobReclaimer(ctx)
+
 	mux := http.NewServeMux()
 	server.RegisterRoutes(mux)
 
@@ -104,6 +132,15 @@ func runServer() error {
 	return http.ListenAndServe(addr, mux)
 }
 
+// adminToken prefers the flag and falls back to the environment, which keeps
+// the token out of process listings.
+func adminToken() string {
+	if token := strings.TrimSpace(flagAdminToken); token != "" {
+		return token
+	}
+	return strings.TrimSpace(os.Getenv("FOG_CLOUD_ADMIN_TOKEN"))
+}
+
 func normalizeScopes(raw string) []string {
 	parts := strings.Split(raw, ",")
 	out := make([]string, 0, len(parts))
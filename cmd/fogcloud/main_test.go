@@ -1,6 +1,10 @@
 package main
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestNormalizeScopes(t *testing.T) {
 	out := normalizeScopes("app_mentions:read, chat:write, ,commands")
@@ -11,3 +15,35 @@ func TestNormalizeScopes(t *testing.T) {
 		t.Fatalf("unexpected scopes: %#v", out)
 	}
 }
+
+func TestOpenStoreAppliesModes(t *testing.T) {
+	origDir, origDB := flagDataDirMode, flagDBMode
+	t.Cleanup(func() { flagDataDirMode, flagDBMode = origDir, origDB })
+
+	flagDataDirMode, flagDBMode = "0750", "0640"
+	dataDir := filepath.Join(t.TempDir(), "cloud")
+	store, err := openStore(dataDir)
+	if err != nil {
+		t.Fatalf("openStore: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	for path, want := range map[string]os.FileMode{
+		dataDir:                               0o750,
+		filepath.Join(dataDir, "fogcloud.db"): 0o640,
+		filepath.Join(dataDir, "cloud.key"):   0o600,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat %s: %v", path, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: got %o want %o", filepath.Base(path), got, want)
+		}
+	}
+
+	flagDataDirMode = "01777"
+	if _, err := openStore(t.TempDir()); err == nil {
+		t.Fatal("openStore with --data-dir-mode 01777 succeeded, want an error")
+	}
+}
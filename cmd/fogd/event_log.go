@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// eventLogLine is the shape of one --log-events line. Field names follow the
+// run events API so log queries and API clients agree on vocabulary.
+type eventLogLine struct {
+	TS      string `json:"ts"`
+	Msg     string `json:"msg"`
+	EventID int64  `json:"event_id"`
+	RunID   string `json:"run_id"`
+	Type    string `json:"type"`
+	Message string `json:"message,omitempty"`
+	Data    string `json:"data,omitempty"`
+}
+
+// newEventLogger returns a run event observer that writes one JSON line per
+// event to w. Writes are serialised so concurrent runs never interleave lines.
+func newEventLogger(w io.Writer) func(state.RunEvent) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e state.RunEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(eventLogLine{
+			TS:      e.TS.UTC().Format(time.RFC3339Nano),
+			Msg:     "run_event",
+			EventID: e.ID,
+			RunID:   e.RunID,
+			Type:    e.Type,
+			Message: e.Message,
+			Data:    e.Data,
+		})
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/darkLord19/foglet/internal/cloudcfg"
 	"github.com/darkLord19/foglet/internal/cloudrelay"
 	"github.com/darkLord19/foglet/internal/env"
+	"github.com/darkLord19/foglet/internal/logging"
 	"github.com/darkLord19/foglet/internal/slack"
 	"github.com/spf13/cobra"
 )
@@ -30,6 +33,11 @@ var (
 	flagSlackApp    string
 	flagCloudURL    string
 	flagCloudPoll   time.Duration
+	flagLogEvents   bool
+	flagDBRecover   bool
+	flagLogLevel    string
+	flagHomeMode    string
+	flagDBMode      string
 )
 
 func main() {
@@ -67,11 +75,38 @@ func init() {
 	rootCmd.Flags().StringVar(&flagSlackApp, "slack-app-token", "", "Slack app token (xapp-..., required for socket mode)")
 	rootCmd.Flags().StringVar(&flagCloudURL, "cloud-url", "", "Fog cloud base URL for distributed Slack relay (optional)")
 	rootCmd.Flags().DurationVar(&flagCloudPoll, "cloud-poll-interval", 2*time.Second, "Fog cloud relay polling interval")
+	rootCmd.Flags().BoolVar(&flagLogEvents, "log-events", false, "Mirror run events to stdout as JSON lines")
+	rootCmd.Flags().BoolVar(&flagDBRecover, "db-recover", false, "Salvage readable rows when fog.db fails its integrity check, instead of starting empty")
+	rootCmd.Flags().StringVar(&flagHomeMode, "home-mode", "", "Octal permissions for FOG_HOME (default 0700)")
+	rootCmd.Flags().StringVar(&flagDBMode, "db-mode", "", "Octal permissions for fog.db and its WAL files (default 0600)")
+	rootCmd.Flags().StringVar(&flagLogLevel, "log-level", "", "Log level: debug, info, warn or error (default info, or $"+logging.EnvLevel+")")
 
 	rootCmd.AddCommand(versionCmd)
 }
 
+// daemonBuildOpts turns the daemon flags into app.BuildOpts.
+func daemonBuildOpts(fogHome, cwd string) (app.BuildOpts, error) {
+	dirMode, dbMode, err := env.ParseStoreModes("--home-mode", flagHomeMode, "--db-mode", flagDBMode)
+	if err != nil {
+		return app.BuildOpts{}, err
+	}
+	return app.BuildOpts{
+		FogHome:   fogHome,
+		Cwd:       cwd,
+		Port:      flagPort,
+		DBRecover: flagDBRecover,
+		DirMode:   dirMode,
+		DBMode:    dbMode,
+	}, nil
+}
+
 func runDaemon() error {
+	level, err := resolveLogLevel(flagLogLevel, os.Getenv(logging.EnvLevel))
+	if err != nil {
+		return err
+	}
+	logging.Setup(os.Stderr, level)
+
 	daemonCtx, daemonCancel := context.WithCancel(context.Background())
 	defer daemonCancel()
 
@@ -85,18 +120,22 @@ func runDaemon() error {
 		return err
 	}
 
+	opts, err := daemonBuildOpts(fogHome, cwd)
+	if err != nil {
+		return err
+	}
 	// Build the application graph via composition root
-	application, err := app.Build(daemonCtx, app.BuildOpts{
-		FogHome: fogHome,
-		Cwd:     cwd,
-		Port:    flagPort,
-	})
+	application, err := app.Build(daemonCtx, opts)
 	if err != nil {
 		return err
 	}
 	defer application.Close()
 
-	log.Printf("API token written to %s/api.token\n", fogHome)
+	slog.Info("API token written", "path", filepath.Join(fogHome, "api.token"))
+
+	if flagLogEvents {
+		application.Store.SetRunEventObserver(newEventLogger(os.Stdout))
+	}
 
 	// Register Slack integration if enabled
 	if flagEnableSlack {
@@ -108,32 +147,34 @@ func runDaemon() error {
 		switch mode {
 		case "http":
 			if strings.TrimSpace(flagSlackSecret) == "" {
-				log.Println("Warning: Slack HTTP mode enabled without --slack-secret")
+				slog.Warn("Slack HTTP mode enabled without --slack-secret")
 			}
 
 			slackHandler := slack.New(application.Runner, application.Store, flagSlackSecret)
 			application.Mount("/slack/command", http.HandlerFunc(slackHandler.HandleCommand))
 
-			log.Println("Slack integration enabled (http mode)")
-			log.Printf("Slack webhook: http://localhost:%d/slack/command\n", flagPort)
-			log.Println("Note: Use a tunnel service (ngrok, cloudflared) to expose this to Slack")
+			slog.Info("Slack integration enabled", "mode", "http", "webhook", fmt.Sprintf("http://localhost:%d/slack/command", flagPort))
+			slog.Info("Use a tunnel service (ngrok, cloudflared) to expose the Slack webhook")
 
 		case "socket":
 			socketServer := slack.NewSocketMode(application.Runner, application.Store, flagSlackApp, flagSlackBot)
 			go func() {
 				if err := socketServer.Run(daemonCtx); err != nil {
-					log.Printf("Slack socket mode stopped: %v", err)
+					slog.Error("Slack socket mode stopped", "err", err)
 				}
 			}()
 
-			log.Println("Slack integration enabled (socket mode)")
-			log.Println("Socket mode active: listening for app mentions and slash commands over WebSocket")
+			slog.Info("Slack integration enabled", "mode", "socket")
 
 		default:
 			return fmt.Errorf("invalid --slack-mode %q: expected http or socket", flagSlackMode)
 		}
 	}
 
+	// Closed once the cloud relay has stopped, which includes reporting any job
+	// it was running. Stays nil when the relay is not started.
+	var relayDone chan struct{}
+
 	// Cloud relay setup
 	cloudURL := strings.TrimSpace(flagCloudURL)
 	if cloudURL != "" {
@@ -153,7 +194,7 @@ func runDaemon() error {
 			return err
 		}
 		if !foundID || strings.TrimSpace(deviceID) == "" || !foundToken || strings.TrimSpace(deviceToken) == "" {
-			log.Println("Cloud URL configured but device is not paired yet. Pair via http://localhost:<port>/api/cloud/pair")
+			slog.Warn("Cloud URL configured but device is not paired yet", "pair", fmt.Sprintf("http://localhost:%d/api/cloud/pair", flagPort))
 		} else {
 			client, err := cloudrelay.NewClient(cloudrelay.ClientConfig{
 				BaseURL:     cloudURL,
@@ -169,12 +210,14 @@ func runDaemon() error {
 			if err != nil {
 				return err
 			}
+			relayDone = make(chan struct{})
 			go func() {
+				defer close(relayDone)
 				if err := relay.Run(daemonCtx); err != nil {
-					log.Printf("Cloud relay stopped: %v", err)
+					slog.Error("Cloud relay stopped", "err", err)
 				}
 			}()
-			log.Printf("Cloud relay enabled: %s (device: %s)", cloudURL, strings.TrimSpace(deviceID))
+			slog.Info("Cloud relay enabled", "cloud_url", cloudURL, "device_id", strings.TrimSpace(deviceID))
 		}
 	}
 
@@ -184,20 +227,56 @@ func runDaemon() error {
 
 	go func() {
 		<-sigChan
-		log.Println("\nShutting down gracefully...")
+		slog.Info("Shutting down gracefully")
+		application.SetReady(false)
 		daemonCancel()
+		waitForRelay(relayDone, relayShutdownGrace)
 		os.Exit(0)
 	}()
 
 	// Start server
 	addr := fmt.Sprintf(":%d", flagPort)
-	log.Printf("Starting fogd on %s\n", addr)
-	log.Printf("API: http://localhost:%d/api/\n", flagPort)
-	log.Printf("Health: http://localhost:%d/health\n", flagPort)
+	slog.Info("Starting fogd",
+		"addr", addr,
+		"api", fmt.Sprintf("http://localhost:%d/api/", flagPort),
+		"health", fmt.Sprintf("http://localhost:%d/health", flagPort),
+		"log_level", level.String(),
+	)
+
+	application.SetReady(true)
 
 	return http.ListenAndServe(addr, application.Handler)
 }
 
+// relayShutdownGrace is how long shutdown waits for the cloud relay to report
+// an in-flight job before exiting anyway.
+const relayShutdownGrace = 10 * time.Second
+
+// waitForRelay blocks until done is closed or grace elapses. A nil done means
+// no relay is running.
+func waitForRelay(done <-chan struct{}, grace time.Duration) {
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(grace):
+		slog.Warn("Cloud relay did not stop in time; exiting")
+	}
+}
+
+// resolveLogLevel picks the --log-level flag, then FOG_LOG_LEVEL, then info.
+func resolveLogLevel(flagValue, envValue string) (slog.Level, error) {
+	if strings.TrimSpace(flagValue) != "" {
+		return logging.ParseLevel(flagValue)
+	}
+	level, err := logging.ParseLevel(envValue)
+	if err != nil {
+		return level, fmt.Errorf("%s: %w", logging.EnvLevel, err)
+	}
+	return level, nil
+}
+
 func validateSlackConfig(mode, botToken, appToken string) error {
 	switch mode {
 	case "http":
@@ -1,6 +1,19 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/app"
+	"github.com/darkLord19/foglet/internal/state"
+)
 
 func TestValidateSlackConfig(t *testing.T) {
 	tests := []struct {
@@ -29,3 +42,116 @@ func TestValidateSlackConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveLogLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    string
+		env     string
+		want    slog.Level
+		wantErr bool
+	}{
+		{name: "default", want: slog.LevelInfo},
+		{name: "env", env: "debug", want: slog.LevelDebug},
+		{name: "flag wins over env", flag: "warn", env: "debug", want: slog.LevelWarn},
+		{name: "invalid env", env: "loud", wantErr: true},
+		{name: "invalid flag", flag: "loud", env: "debug", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveLogLevel(tc.flag, tc.env)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got level %v", got)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("resolveLogLevel(%q, %q) = %v, %v; want %v", tc.flag, tc.env, got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestEventLoggerWritesOneJSONLinePerEvent(t *testing.T) {
+	var buf bytes.Buffer
+	logEvent := newEventLogger(&buf)
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	logEvent(state.RunEvent{ID: 7, RunID: "run-1", TS: ts, Type: "setup", Message: "Running setup"})
+	logEvent(state.RunEvent{ID: 8, RunID: "run-1", TS: ts, Type: "complete"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected line count: got %d want 2: %q", len(lines), buf.String())
+	}
+
+	var got eventLogLine
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("decode line failed: %v", err)
+	}
+	want := eventLogLine{
+		TS:      "2026-01-02T03:04:05Z",
+		Msg:     "run_event",
+		EventID: 7,
+		RunID:   "run-1",
+		Type:    "setup",
+		Message: "Running setup",
+	}
+	if got != want {
+		t.Fatalf("unexpected log line: got %+v want %+v", got, want)
+	}
+}
+
+func TestWaitForRelay(t *testing.T) {
+	// No relay: returns immediately.
+	waitForRelay(nil, time.Hour)
+
+	done := make(chan struct{})
+	close(done)
+	waitForRelay(done, time.Hour)
+
+	start := time.Now()
+	waitForRelay(make(chan struct{}), 20*time.Millisecond)
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("expected waitForRelay to wait out the grace period")
+	}
+}
+
+func TestDaemonBuildOptsAppliesStoreModes(t *testing.T) {
+	origHome, origDB := flagHomeMode, flagDBMode
+	t.Cleanup(func() { flagHomeMode, flagDBMode = origHome, origDB })
+
+	flagHomeMode, flagDBMode = "0750", "640"
+	home := filepath.Join(t.TempDir(), "fog")
+	opts, err := daemonBuildOpts(home, t.TempDir())
+	if err != nil {
+		t.Fatalf("daemonBuildOpts: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	application, err := app.Build(ctx, opts)
+	if err != nil {
+		t.Fatalf("app.Build: %v", err)
+	}
+	defer application.Close()
+
+	for path, want := range map[string]os.FileMode{
+		home:                              0o750,
+		filepath.Join(home, "fog.db"):     0o640,
+		filepath.Join(home, "master.key"): 0o600,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat %s: %v", path, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: got %o want %o", filepath.Base(path), got, want)
+		}
+	}
+
+	flagDBMode = "rw-r-----"
+	if _, err := daemonBuildOpts(home, ""); err == nil || !strings.Contains(err.Error(), "--db-mode") {
+		t.Fatalf("daemonBuildOpts with a bad --db-mode = %v, want an error", err)
+	}
+}
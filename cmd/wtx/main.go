@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/darkLord19/foglet/internal/config"
@@ -18,9 +20,14 @@ import (
 var version = "dev"
 
 var (
-	flagJSON    bool
-	flagAddJSON bool
-	flagEditor  string
+	flagJSON       bool
+	flagAddJSON    bool
+	flagStatusJSON bool
+	flagNoOpen     bool
+	flagEditor     string
+
+	flagPruneMerged bool
+	flagPruneDryRun bool
 )
 
 func main() {
@@ -72,14 +79,19 @@ var versionCmd = &cobra.Command{
 func init() {
 	listCmd.Flags().BoolVar(&flagJSON, "json", false, "Output as JSON")
 	addCmd.Flags().BoolVar(&flagAddJSON, "json", false, "Output result as JSON")
+	addCmd.Flags().BoolVar(&flagNoOpen, "no-open", false, "Do not open the new worktree in an editor")
+	statusCmd.Flags().BoolVar(&flagStatusJSON, "json", false, "Output as JSON")
+	pruneCmd.Flags().BoolVar(&flagPruneMerged, "merged", false, "Also remove worktrees whose branches are merged into the default branch")
+	pruneCmd.Flags().BoolVar(&flagPruneDryRun, "dry-run", false, "Show what would be removed without removing anything")
 
-	rootCmd.PersistentFlags().StringVar(&flagEditor, "editor", "", "Editor to use (vscode, cursor, neovim, etc)")
+	rootCmd.PersistentFlags().StringVar(&flagEditor, "editor", "", "Editor to use (vscode, cursor, neovim, tmux, zellij, etc)")
 
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(openCmd)
 	rootCmd.AddCommand(rmCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(pruneCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(versionCmd)
 }
@@ -138,6 +150,22 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove stale worktrees and their metadata",
+	Long: `Prune git's records of worktrees whose directories are gone and drop
+their wtx metadata. With --merged, also remove clean worktrees whose branches
+were committed on and are fully merged into the default branch; a branch
+nobody has committed on yet is kept.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runPrune(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Show or edit configuration",
@@ -297,9 +325,13 @@ func runAdd(name, branch string) error {
 		return nil
 	}
 
-	// Auto-open if configured
+	// Auto-open unless turned off by flag or config
+	if flagNoOpen || !cfg.ShouldAutoOpen() {
+		return nil
+	}
 	if err := runOpen(name); err != nil {
 		fmt.Printf("Note: Could not auto-open: %v\n", err)
+		fmt.Printf("Open it manually at: %s\n", filepath.Clean(wtPath))
 	}
 
 	return nil
@@ -485,6 +517,15 @@ func runStatus(name string) error {
 		}
 	}
 
+	if flagStatusJSON {
+		data, err := json.MarshalIndent(newStatusOutput(wt, status, wtMeta), "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal status: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
 	// Display status
 	fmt.Printf("Worktree: %s\n", name)
 	fmt.Printf("Path: %s\n", wt.Path)
@@ -536,6 +577,177 @@ func runStatus(name string) error {
 	return nil
 }
 
+func runPrune() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current directory: %w", err)
+	}
+
+	g := git.New(cwd)
+	if !g.IsRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	root, err := g.GetRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	verb := "Removed"
+	if flagPruneDryRun {
+		verb = "Would remove"
+	}
+	removed := 0
+
+	// Drop git's records of worktrees whose directories are gone
+	pruned, err := g.PruneWorktrees(flagPruneDryRun)
+	if err != nil {
+		return fmt.Errorf("prune worktrees: %w", err)
+	}
+	for _, line := range pruned {
+		// git reports "Removing worktrees/<name>: <reason>"
+		fmt.Printf("%s stale worktree record %s\n", verb, strings.TrimPrefix(line, "Removing "))
+	}
+	removed += len(pruned)
+
+	store, err := metadata.New(root)
+	if err != nil {
+		return err
+	}
+
+	if flagPruneMerged {
+		n, err := pruneMerged(g, store, root, verb)
+		if err != nil {
+			return err
+		}
+		removed += n
+	}
+
+	// Drop metadata for worktrees that no longer exist on disk
+	missing, err := store.PruneMissing(flagPruneDryRun)
+	if err != nil {
+		return fmt.Errorf("prune metadata: %w", err)
+	}
+	for _, name := range missing {
+		fmt.Printf("%s metadata for missing worktree '%s'\n", verb, name)
+	}
+	removed += len(missing)
+
+	if removed == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+	if flagPruneDryRun {
+		fmt.Printf("\n%d item(s) would be removed (dry run)\n", removed)
+	} else {
+		fmt.Printf("\n✓ Removed %d item(s)\n", removed)
+	}
+
+	return nil
+}
+
+// pruneMerged removes worktrees whose branches are fully merged into the
+// default branch. The main worktree, the current one, locked ones and ones
+// with uncommitted changes are kept, as are branches never committed on,
+// which sit on the default branch without having been merged into it.
+func pruneMerged(g *git.Git, store *metadata.Store, current, verb string) (int, error) {
+	worktrees, err := g.ListWorktrees()
+	if err != nil {
+		return 0, fmt.Errorf("list worktrees: %w", err)
+	}
+	if len(worktrees) == 0 {
+		return 0, nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return 0, err
+	}
+	defaultBranch := cfg.DefaultBranch
+	if defaultBranch == "" {
+		// The first worktree listed is the main one
+		defaultBranch, err = git.New(worktrees[0].Path).GetDefaultBranch()
+		if err != nil {
+			return 0, fmt.Errorf("determine default branch: %w", err)
+		}
+	}
+
+	removed := 0
+	for _, wt := range worktrees[1:] {
+		if wt.Branch == "" || wt.Branch == defaultBranch || wt.Prunable {
+			continue
+		}
+		if !g.IsAncestor(wt.Branch, defaultBranch) || !g.BranchHasOwnCommits(wt.Branch) {
+			continue
+		}
+		switch {
+		case filepath.Clean(wt.Path) == filepath.Clean(current):
+			fmt.Printf("Skipped '%s': it is the current worktree\n", wt.Name)
+			continue
+		case wt.Locked:
+			fmt.Printf("Skipped '%s': worktree is locked\n", wt.Name)
+			continue
+		}
+		dirty, err := g.HasUncommittedChanges(wt.Path)
+		if err != nil {
+			fmt.Printf("Skipped '%s': could not check status: %v\n", wt.Name, err)
+			continue
+		}
+		if dirty {
+			fmt.Printf("Skipped '%s': uncommitted changes\n", wt.Name)
+			continue
+		}
+
+		if !flagPruneDryRun {
+			if err := g.RemoveWorktree(wt.Path, false); err != nil {
+				fmt.Printf("⚠ Could not remove '%s': %v\n", wt.Name, err)
+				continue
+			}
+			if err := store.DeleteWorktree(wt.Name); err != nil {
+				return removed, fmt.Errorf("remove metadata for '%s': %w", wt.Name, err)
+			}
+		}
+		fmt.Printf("%s merged worktree '%s' (%s)\n", verb, wt.Name, wt.Branch)
+		removed++
+	}
+
+	return removed, nil
+}
+
+// statusOutput is what `wtx status --json` prints.
+type statusOutput struct {
+	Name     string                     `json:"name"`
+	Path     string                     `json:"path"`
+	Branch   string                     `json:"branch"`
+	Head     string                     `json:"head"`
+	Locked   bool                       `json:"locked"`
+	Prunable bool                       `json:"prunable"`
+	Dirty    bool                       `json:"dirty"`
+	Ahead    int                        `json:"ahead"`
+	Behind   int                        `json:"behind"`
+	Stash    bool                       `json:"stash"`
+	Metadata *metadata.WorktreeMetadata `json:"metadata,omitempty"`
+}
+
+func newStatusOutput(wt *git.Worktree, status *git.Status, wtMeta *metadata.WorktreeMetadata) statusOutput {
+	out := statusOutput{
+		Name:     wt.Name,
+		Path:     wt.Path,
+		Branch:   wt.Branch,
+		Head:     wt.Head,
+		Locked:   wt.Locked,
+		Prunable: wt.Prunable,
+		Metadata: wtMeta,
+	}
+	if status != nil {
+		out.Dirty = status.Dirty
+		out.Ahead = status.Ahead
+		out.Behind = status.Behind
+		out.Stash = status.Stash
+	}
+	return out
+}
+
 func runConfig() error {
 	cfg, err := config.Load()
 	if err != nil {
@@ -552,6 +764,7 @@ func runConfig() error {
 	fmt.Printf("default_branch: %s\n", cfg.DefaultBranch)
 	fmt.Printf("setup_cmd: %s\n", cfg.SetupCmd)
 	fmt.Printf("validate_cmd: %s\n", cfg.ValidateCmd)
+	fmt.Printf("auto_open: %v\n", cfg.ShouldAutoOpen())
 
 	fmt.Println()
 
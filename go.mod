@@ -12,6 +12,7 @@ require (
 	github.com/wailsapp/wails/v2 v2.11.0
 	golang.org/x/sync v0.17.0
 	golang.org/x/term v0.29.0
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.45.0
 )
 
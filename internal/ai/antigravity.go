@@ -17,6 +17,10 @@ func (a *Antigravity) IsAvailable() bool {
 	return antigravityCommand() != ""
 }
 
+func (a *Antigravity) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, Models: true, Resume: true}
+}
+
 func (a *Antigravity) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk func(string)) (*Result, error) {
 	cmdName := antigravityCommand()
 	if cmdName == "" {
@@ -24,19 +28,20 @@ func (a *Antigravity) ExecuteStream(ctx context.Context, req ExecuteRequest, onC
 	}
 
 	streamArgs := buildAntigravityHeadlessArgs(req, true, true)
-	streamOutput, conversationID, streamErr := runJSONStreamingCommand(ctx, a.Name(), req.Workdir, cmdName, streamArgs, onChunk)
+	streamOutput, conversationID, usage, streamErr := runJSONStreamingCommand(ctx, a.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, streamArgs, onChunk)
 	if streamErr == nil {
 		return &Result{
 			Success:        true,
 			Output:         strings.TrimSpace(streamOutput),
 			ConversationID: conversationID,
+			Usage:          usage,
 		}, nil
 	}
 
 	if looksLikeUnsupportedFlag(streamOutput) || streamOutput == "" {
 		// Retry without auto-approve, which is not supported by all versions.
 		retryArgs := buildAntigravityHeadlessArgs(req, true, false)
-		retryOutput, retryConversationID, retryErr := runJSONStreamingCommand(ctx, a.Name(), req.Workdir, cmdName, retryArgs, onChunk)
+		retryOutput, retryConversationID, retryUsage, retryErr := runJSONStreamingCommand(ctx, a.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, retryArgs, onChunk)
 		if retryErr == nil {
 			if retryConversationID == "" {
 				retryConversationID = conversationID
@@ -45,6 +50,7 @@ func (a *Antigravity) ExecuteStream(ctx context.Context, req ExecuteRequest, onC
 				Success:        true,
 				Output:         strings.TrimSpace(retryOutput),
 				ConversationID: retryConversationID,
+				Usage:          retryUsage,
 			}, nil
 		}
 		if conversationID == "" {
@@ -52,10 +58,10 @@ func (a *Antigravity) ExecuteStream(ctx context.Context, req ExecuteRequest, onC
 		}
 
 		fallbackArgs := buildAntigravityHeadlessArgs(req, false, true)
-		plainOutput, plainErr := runPlainStreamingCommand(ctx, a.Name(), req.Workdir, cmdName, fallbackArgs, onChunk)
+		plainOutput, plainErr := runPlainStreamingCommand(ctx, a.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, fallbackArgs, onChunk)
 		if plainErr != nil && (looksLikeUnsupportedFlag(plainOutput) || plainOutput == "") {
 			noApproveArgs := buildAntigravityHeadlessArgs(req, false, false)
-			plainOutput, plainErr = runPlainStreamingCommand(ctx, a.Name(), req.Workdir, cmdName, noApproveArgs, onChunk)
+			plainOutput, plainErr = runPlainStreamingCommand(ctx, a.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, noApproveArgs, onChunk)
 		}
 		return &Result{
 			Success:        plainErr == nil,
@@ -70,6 +76,7 @@ func (a *Antigravity) ExecuteStream(ctx context.Context, req ExecuteRequest, onC
 		Output:         strings.TrimSpace(streamOutput),
 		Error:          streamErr,
 		ConversationID: conversationID,
+		Usage:          usage,
 	}, streamErr
 }
 
@@ -96,6 +103,7 @@ func buildAntigravityHeadlessArgs(req ExecuteRequest, withStreamJSON, withAutoAp
 	if withAutoApprove {
 		args = append(args, "--dangerously-skip-permissions")
 	}
+	args = append(args, req.ExtraArgs...)
 	args = append(args, "-p", strings.TrimSpace(req.Prompt))
 	return args
 }
@@ -17,6 +17,10 @@ func (c *ClaudeCode) IsAvailable() bool {
 	return commandExists("claude") || commandExists("claude-code")
 }
 
+func (c *ClaudeCode) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, Models: true, Resume: true}
+}
+
 func (c *ClaudeCode) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk func(string)) (*Result, error) {
 	if !c.IsAvailable() {
 		return nil, fmt.Errorf("claude not available")
@@ -30,12 +34,13 @@ func (c *ClaudeCode) ExecuteStream(ctx context.Context, req ExecuteRequest, onCh
 	if conversationID := strings.TrimSpace(req.ConversationID); conversationID != "" {
 		args = append(args, "--resume", conversationID)
 	}
+	args = append(args, req.ExtraArgs...)
 
 	streamArgs := append(append([]string{}, args...), "--output-format", "stream-json")
-	output, conversationID, err := runJSONStreamingCommand(ctx, c.Name(), req.Workdir, cmdName, streamArgs, onChunk)
+	output, conversationID, usage, err := runJSONStreamingCommand(ctx, c.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, streamArgs, onChunk)
 
 	if err != nil && (looksLikeUnsupportedFlag(output) || strings.TrimSpace(output) == "") {
-		plainOutput, plainErr := runPlainStreamingCommand(ctx, c.Name(), req.Workdir, cmdName, args, onChunk)
+		plainOutput, plainErr := runPlainStreamingCommand(ctx, c.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, args, onChunk)
 		result := &Result{
 			Success:        plainErr == nil,
 			Output:         strings.TrimSpace(plainOutput),
@@ -53,6 +58,7 @@ func (c *ClaudeCode) ExecuteStream(ctx context.Context, req ExecuteRequest, onCh
 		Output:         strings.TrimSpace(output),
 		Error:          err,
 		ConversationID: conversationID,
+		Usage:          usage,
 	}
 	if err != nil {
 		return result, err
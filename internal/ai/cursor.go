@@ -17,6 +17,10 @@ func (c *Cursor) IsAvailable() bool {
 	return cursorAgentCommand() != ""
 }
 
+func (c *Cursor) Capabilities() Capabilities {
+	return Capabilities{Streaming: true, Models: true, Resume: true}
+}
+
 func (c *Cursor) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk func(string)) (*Result, error) {
 	cmdName := cursorAgentCommand()
 	if cmdName == "" {
@@ -24,18 +28,19 @@ func (c *Cursor) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 	}
 
 	streamArgs := buildCursorHeadlessArgs(req, true)
-	streamOutput, conversationID, streamErr := runJSONStreamingCommand(ctx, c.Name(), req.Workdir, cmdName, streamArgs, onChunk)
+	streamOutput, conversationID, usage, streamErr := runJSONStreamingCommand(ctx, c.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, streamArgs, onChunk)
 	if streamErr == nil {
 		return &Result{
 			Success:        true,
 			Output:         strings.TrimSpace(streamOutput),
 			ConversationID: conversationID,
+			Usage:          usage,
 		}, nil
 	}
 
 	if looksLikeUnsupportedFlag(streamOutput) || streamOutput == "" {
 		fallbackArgs := buildCursorHeadlessArgs(req, false)
-		plainOutput, plainErr := runPlainStreamingCommand(ctx, c.Name(), req.Workdir, cmdName, fallbackArgs, onChunk)
+		plainOutput, plainErr := runPlainStreamingCommand(ctx, c.Name(), req.Workdir, req.Env, req.Wrapper, cmdName, fallbackArgs, onChunk)
 		return &Result{
 			Success:        plainErr == nil,
 			Output:         strings.TrimSpace(plainOutput),
@@ -49,6 +54,7 @@ func (c *Cursor) ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk
 		Output:         strings.TrimSpace(streamOutput),
 		Error:          streamErr,
 		ConversationID: conversationID,
+		Usage:          usage,
 	}, streamErr
 }
 
@@ -72,6 +78,7 @@ func buildCursorHeadlessArgs(req ExecuteRequest, withStreamJSON bool) []string {
 	if withStreamJSON {
 		args = append(args, "--output-format", "stream-json")
 	}
+	args = append(args, req.ExtraArgs...)
 	args = append(args, strings.TrimSpace(req.Prompt))
 	return args
 }
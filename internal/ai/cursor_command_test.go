@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -52,3 +53,31 @@ func TestCursorAgentCommandFallsBackToAgent(t *testing.T) {
 		t.Fatalf("unexpected command: got %q want %q", got, agentPath)
 	}
 }
+
+func TestToolVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses unix-like executable fixtures")
+	}
+
+	tempDir := t.TempDir()
+	script := "#!/bin/sh\necho 'cursor-agent 2026.10.01'\necho 'build abc123'\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "cursor-agent"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write cursor-agent fixture: %v", err)
+	}
+
+	t.Setenv("PATH", tempDir)
+	commandPathCache = sync.Map{}
+	commandPathCache.Store("claude", "")
+	commandPathCache.Store("claude-code", "")
+
+	got, err := ToolVersion(context.Background(), "cursor")
+	if err != nil {
+		t.Fatalf("ToolVersion(cursor): %v", err)
+	}
+	if got != "cursor-agent 2026.10.01" {
+		t.Fatalf("unexpected version: got %q", got)
+	}
+	if _, err := ToolVersion(context.Background(), "claude"); err == nil {
+		t.Fatal("expected an error for a tool not in PATH")
+	}
+}
@@ -24,3 +24,11 @@ func TestBuildCursorHeadlessArgsWithoutOutputFormat(t *testing.T) {
 		t.Fatalf("args mismatch: got %v want %v", got, want)
 	}
 }
+
+func TestBuildCursorHeadlessArgsPutsExtraArgsBeforePrompt(t *testing.T) {
+	got := buildCursorHeadlessArgs(ExecuteRequest{Prompt: "fix auth", ExtraArgs: []string{"--max-turns", "5"}}, false)
+	want := []string{"-p", "--force", "--max-turns", "5", "fix auth"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("args mismatch: got %v want %v", got, want)
+	}
+}
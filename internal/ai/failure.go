@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+)
+
+// TransientError marks a tool failure that is likely to go away if the same
+// request is made again: the provider was overloaded or rate limiting, or the
+// network dropped. Anything not recognised as transient is treated as
+// deterministic, so a refusal or a bad prompt is never retried.
+type TransientError struct {
+	Err error
+	// Reason is the marker that classified the failure, for run events.
+	Reason string
+}
+
+func (e *TransientError) Error() string { return e.Err.Error() }
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// IsTransient reports whether err was classified as a TransientError.
+func IsTransient(err error) bool {
+	var t *TransientError
+	return errors.As(err, &t)
+}
+
+// ModelUnavailableError marks a tool failure caused by the requested model
+// itself: the provider does not know it, has retired it, or will not serve it
+// on this account's quota. Another model may succeed where retrying the same
+// one will not.
+type ModelUnavailableError struct {
+	Err error
+	// Reason is the marker that classified the failure, for run events.
+	Reason string
+}
+
+func (e *ModelUnavailableError) Error() string { return e.Err.Error() }
+func (e *ModelUnavailableError) Unwrap() error { return e.Err }
+
+// IsModelUnavailable reports whether err was classified as a
+// ModelUnavailableError.
+func IsModelUnavailable(err error) bool {
+	var m *ModelUnavailableError
+	return errors.As(err, &m)
+}
+
+// transientTailBytes bounds how much of the tool output is searched. CLIs
+// print their fatal error last; searching the whole transcript would let the
+// agent's own prose ("this returns 503 when...") look like an outage.
+const transientTailBytes = 2048
+
+var transientMarkers = []string{
+	"overloaded",
+	"rate limit",
+	"rate_limit",
+	"too many requests",
+	"internal server error",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+	"temporarily unavailable",
+	"connection reset",
+	"connection refused",
+	"econnreset",
+	"etimedout",
+	"network error",
+	"socket hang up",
+}
+
+// modelUnavailableMarkers are checked before transientMarkers: a quota
+// message can also mention a rate limit, and retrying the same model cannot
+// restore an exhausted quota.
+var modelUnavailableMarkers = []string{
+	"model_not_found",
+	"model not found",
+	"unknown model",
+	"invalid model",
+	"unsupported model",
+	"model is not available",
+	"model not available",
+	"does not have access to model",
+	"model has been deprecated",
+	"model is deprecated",
+	"insufficient_quota",
+	"exceeded your current quota",
+	"quota exceeded",
+}
+
+// modelUnavailablePhrase matches errors that name the model before saying
+// what is wrong with it, such as "model: claude-x does not exist".
+var modelUnavailablePhrase = regexp.MustCompile(`\bmodel\b[^\n]{0,80}?\b(?:does not exist|not found|is not supported|has been retired|has been deprecated)`)
+
+// transientStatus matches an HTTP status the provider uses for transient
+// trouble when the CLI reports it as a code rather than a phrase.
+var transientStatus = regexp.MustCompile(`\b(?:status|error|code)\W{0,3}(?:429|500|502|503|504|529)\b`)
+
+// classifyFailure wraps err in a ModelUnavailableError or a TransientError
+// when it or the tail of the tool output carries one of their markers.
+// Cancellation is never either.
+func classifyFailure(output string, err error) error {
+	if err == nil || errors.Is(err, proc.ErrCanceled) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if len(output) > transientTailBytes {
+		output = output[len(output)-transientTailBytes:]
+	}
+	text := strings.ToLower(output + "\n" + err.Error())
+	for _, marker := range modelUnavailableMarkers {
+		if strings.Contains(text, marker) {
+			return &ModelUnavailableError{Err: err, Reason: marker}
+		}
+	}
+	if m := modelUnavailablePhrase.FindString(text); m != "" {
+		return &ModelUnavailableError{Err: err, Reason: m}
+	}
+	for _, marker := range transientMarkers {
+		if strings.Contains(text, marker) {
+			return &TransientError{Err: err, Reason: marker}
+		}
+	}
+	if m := transientStatus.FindString(text); m != "" {
+		return &TransientError{Err: err, Reason: m}
+	}
+	return err
+}
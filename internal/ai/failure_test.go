@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/proc"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	exit := errors.New("exit status 1")
+	cases := []struct {
+		name      string
+		output    string
+		err       error
+		transient bool
+	}{
+		{"overloaded", `API Error: 529 {"type":"error","error":{"type":"overloaded_error"}}`, exit, true},
+		{"rate limited", "Error: rate limit exceeded, retry later", exit, true},
+		{"status code", "request failed: status 503", exit, true},
+		{"network", "fetch failed: ECONNRESET", exit, true},
+		{"refusal", "I can't help with that request.", exit, false},
+		{"auth", "Invalid API key. Please run /login", exit, false},
+		{"no marker", "", exit, false},
+		{"canceled", "service unavailable", fmt.Errorf("%w: context canceled", proc.ErrCanceled), false},
+		{"marker only early in transcript", "the endpoint returns service unavailable\n" + strings.Repeat("x", transientTailBytes), exit, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := classifyFailure(tc.output, tc.err)
+			if got := IsTransient(err); got != tc.transient {
+				t.Fatalf("IsTransient = %v, want %v (err=%v)", got, tc.transient, err)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("classified error must wrap the original")
+			}
+		})
+	}
+	if classifyFailure("overloaded", nil) != nil {
+		t.Fatal("nil error must stay nil")
+	}
+}
+
+func TestClassifyFailureModelUnavailable(t *testing.T) {
+	exit := errors.New("exit status 1")
+	cases := []struct {
+		name        string
+		output      string
+		unavailable bool
+	}{
+		{"not found", `API Error: 404 {"type":"error","error":{"type":"not_found_error","message":"model: claude-old does not exist"}}`, true},
+		{"deprecated", "Error: The model gpt-x has been deprecated", true},
+		{"quota", "You exceeded your current quota, please check your plan", true},
+		{"unknown model", "Cannot use this model: unknown model 'fast-9'", true},
+		{"task failure", "Tests failed: expected 2, got 3", false},
+		{"overloaded", "API Error: 529 overloaded", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := classifyFailure(tc.output, exit)
+			if got := IsModelUnavailable(err); got != tc.unavailable {
+				t.Fatalf("IsModelUnavailable = %v, want %v (err=%v)", got, tc.unavailable, err)
+			}
+			if tc.unavailable && IsTransient(err) {
+				t.Fatal("a model error must not also be retried as transient")
+			}
+		})
+	}
+}
@@ -40,19 +40,32 @@ func hostGuard() sandbox.Guard {
 
 // runGuardedStreaming executes an AI CLI under host-level restrictions: a
 // filesystem deny-list plus an environment reduced to what the named tool needs.
+// env entries (KEY=value) are added after filtering: the user set them for
+// this session, so they reach the tool even when the filter would drop them.
+// A wrapper, when set, runs inside the guard with the tool inside it.
 //
 // Guard setup errors are deliberately non-fatal — the command still runs, just
 // unrestricted. Refusing to run would let a transient temp-file failure break a
 // user's session, which is a worse outcome than the exposure it avoids.
 func runGuardedStreaming(
 	ctx context.Context,
-	toolName, workdir, cmdName string,
+	toolName, workdir string,
+	env, wrapper []string,
+	cmdName string,
 	onChunk func([]byte),
 	args []string,
 ) ([]byte, error) {
+	cmdName, args = wrapCommand(wrapper, cmdName, args)
 	wrapped, _ := hostGuard().Wrap(cmdName, args)
 	defer wrapped.Cleanup()
 
 	childEnv := sandbox.FilterEnv(os.Environ(), toolEnvPrefixes[toolName])
+	if len(env) > 0 {
+		if childEnv == nil {
+			childEnv = os.Environ()
+		}
+		// exec uses the last value of a duplicated key, so these win.
+		childEnv = append(childEnv, env...)
+	}
 	return proc.RunStreamingEnv(ctx, workdir, childEnv, wrapped.Name, onChunk, wrapped.Args...)
 }
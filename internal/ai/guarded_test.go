@@ -49,6 +49,8 @@ func TestRunGuardedStreamingFiltersChildEnvironment(t *testing.T) {
 		context.Background(),
 		"claude",
 		t.TempDir(),
+		nil,
+		nil,
 		"/usr/bin/env",
 		func(chunk []byte) { out.Write(chunk) },
 		nil,
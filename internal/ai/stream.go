@@ -11,6 +11,7 @@ type streamJSONParser struct {
 	pending        bytes.Buffer
 	output         bytes.Buffer
 	conversationID string
+	usage          Usage
 	onChunk        func(string)
 }
 
@@ -65,6 +66,9 @@ func (p *streamJSONParser) processLine(line string) {
 	if p.conversationID == "" {
 		p.conversationID = extractConversationID(payload)
 	}
+	if usage, ok := extractResultUsage(payload); ok {
+		p.usage = usage
+	}
 
 	text := extractStreamText(payload)
 	if strings.TrimSpace(text) == "" {
@@ -84,21 +88,30 @@ func (p *streamJSONParser) ConversationID() string {
 	return strings.TrimSpace(p.conversationID)
 }
 
-func runJSONStreamingCommand(ctx context.Context, toolName, workdir, cmdName string, args []string, onChunk func(string)) (output, conversationID string, err error) {
+// Usage returns the usage from the stream's result event, or zero.
+func (p *streamJSONParser) Usage() Usage {
+	return p.usage
+}
+
+func runJSONStreamingCommand(ctx context.Context, toolName, workdir string, env, wrapper []string, cmdName string, args []string, onChunk func(string)) (output, conversationID string, usage Usage, err error) {
 	parser := newStreamJSONParser(onChunk)
-	raw, err := runGuardedStreaming(ctx, toolName, workdir, cmdName, parser.Feed, args)
+	raw, err := runGuardedStreaming(ctx, toolName, workdir, env, wrapper, cmdName, parser.Feed, args)
 	parser.Close()
 
 	output = parser.Output()
 	if output == "" {
 		output = strings.TrimSpace(string(raw))
 	}
-	return output, parser.ConversationID(), err
+	if err != nil {
+		// The parser keeps only assistant text; the CLI's own error is in raw.
+		err = classifyFailure(string(raw), err)
+	}
+	return output, parser.ConversationID(), parser.Usage(), err
 }
 
-func runPlainStreamingCommand(ctx context.Context, toolName, workdir, cmdName string, args []string, onChunk func(string)) (string, error) {
+func runPlainStreamingCommand(ctx context.Context, toolName, workdir string, env, wrapper []string, cmdName string, args []string, onChunk func(string)) (string, error) {
 	var out bytes.Buffer
-	_, err := runGuardedStreaming(ctx, toolName, workdir, cmdName, func(chunk []byte) {
+	_, err := runGuardedStreaming(ctx, toolName, workdir, env, wrapper, cmdName, func(chunk []byte) {
 		if len(chunk) == 0 {
 			return
 		}
@@ -107,7 +120,37 @@ func runPlainStreamingCommand(ctx context.Context, toolName, workdir, cmdName st
 			onChunk(string(chunk))
 		}
 	}, args)
-	return strings.TrimSpace(out.String()), err
+	return strings.TrimSpace(out.String()), classifyFailure(out.String(), err)
+}
+
+// extractResultUsage reads token counts and cost from a stream's final result
+// event. Only that event is used: per-message usage on assistant events would
+// count the same turn twice.
+func extractResultUsage(payload map[string]any) (Usage, bool) {
+	if !strings.EqualFold(firstString(payload, "type"), "result") {
+		return Usage{}, false
+	}
+	var usage Usage
+	if counts, ok := payload["usage"].(map[string]any); ok {
+		usage.InputTokens = jsonInt(counts, "input_tokens") +
+			jsonInt(counts, "cache_creation_input_tokens") +
+			jsonInt(counts, "cache_read_input_tokens")
+		usage.OutputTokens = jsonInt(counts, "output_tokens")
+	}
+	for _, key := range []string{"total_cost_usd", "cost_usd"} {
+		if cost, ok := payload[key].(float64); ok && cost > 0 {
+			usage.CostUSD = cost
+			break
+		}
+	}
+	return usage, !usage.IsZero()
+}
+
+func jsonInt(payload map[string]any, key string) int64 {
+	if n, ok := payload[key].(float64); ok && n > 0 {
+		return int64(n)
+	}
+	return 0
 }
 
 func extractConversationID(payload map[string]any) string {
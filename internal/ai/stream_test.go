@@ -51,3 +51,26 @@ func TestStreamJSONParserPreservesLeadingSpacesAcrossChunks(t *testing.T) {
 		t.Fatalf("unexpected streamed chunks: %+v", chunks)
 	}
 }
+
+func TestStreamJSONParserReadsResultUsage(t *testing.T) {
+	parser := newStreamJSONParser(nil)
+	// Per-message usage is ignored; only the result event counts.
+	parser.Feed([]byte(`{"type":"assistant","message":{"content":"hi","usage":{"input_tokens":5,"output_tokens":2}}}` + "\n"))
+	parser.Feed([]byte(`{"type":"result","total_cost_usd":0.0125,"usage":{"input_tokens":10,"cache_read_input_tokens":200,"cache_creation_input_tokens":30,"output_tokens":40}}` + "\n"))
+	parser.Close()
+
+	want := Usage{InputTokens: 240, OutputTokens: 40, CostUSD: 0.0125}
+	if got := parser.Usage(); got != want {
+		t.Fatalf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestStreamJSONParserWithoutUsage(t *testing.T) {
+	parser := newStreamJSONParser(nil)
+	parser.Feed([]byte(`{"type":"result","session_id":"sess-123"}` + "\n"))
+	parser.Close()
+
+	if !parser.Usage().IsZero() {
+		t.Fatalf("usage = %+v, want zero", parser.Usage())
+	}
+}
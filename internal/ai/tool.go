@@ -26,15 +26,38 @@ import (
 type Tool interface {
 	Name() string
 	IsAvailable() bool
+	Capabilities() Capabilities
 	ExecuteStream(ctx context.Context, req ExecuteRequest, onChunk func(string)) (*Result, error)
 }
 
+// Capabilities is what a tool's CLI supports, so clients offer only the
+// options that have an effect. It describes the adapter, not the installed
+// binary: an older CLI may still reject a flag the adapter would pass.
+type Capabilities struct {
+	// Streaming means output arrives incrementally through onChunk.
+	Streaming bool
+	// Models means ExecuteRequest.Model selects the model.
+	Models bool
+	// Resume means ExecuteRequest.ConversationID continues a prior
+	// conversation.
+	Resume bool
+}
+
 // ExecuteRequest configures one tool execution call.
 type ExecuteRequest struct {
 	Workdir        string
 	Prompt         string
 	Model          string
 	ConversationID string
+	// Env holds KEY=value entries added to the tool's environment.
+	Env []string
+	// Wrapper is a wrapper argv from ParseExecWrapper that the tool's
+	// command runs inside, such as nice or ssh. nil runs the tool directly.
+	Wrapper []string
+	// ExtraArgs are passed to the tool's CLI after the flags Fog sets and
+	// before the prompt, for flags Fog does not know, such as --max-turns.
+	// See ValidateExtraArgs.
+	ExtraArgs []string
 }
 
 // Result contains the AI execution result
@@ -43,6 +66,21 @@ type Result struct {
 	Output         string
 	Error          error
 	ConversationID string
+	// Usage is zero when the tool reported none, as in plain-text mode.
+	Usage Usage
+}
+
+// Usage is what one execution consumed, as reported by the tool's CLI.
+// InputTokens includes prompt cache reads and writes.
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
+	CostUSD      float64
+}
+
+// IsZero reports whether the tool reported no usage.
+func (u Usage) IsZero() bool {
+	return u == Usage{}
 }
 
 // GetTool returns an AI tool by name
@@ -90,6 +128,31 @@ func AvailableToolNames() []string {
 	return []string{"cursor", "claude", "antigravity"}
 }
 
+// ToolVersion runs the named tool's CLI with --version and returns the first
+// line it prints. It is for diagnostics; nothing gates on the version.
+func ToolVersion(ctx context.Context, name string) (string, error) {
+	var path string
+	switch normalizeToolName(name) {
+	case "cursor":
+		path = cursorAgentCommand()
+	case "claude":
+		path = claudeCommand()
+	case "antigravity", "agy":
+		path = antigravityCommand()
+	default:
+		return "", fmt.Errorf("unknown AI tool: %s", name)
+	}
+	if path == "" {
+		return "", fmt.Errorf("%s not found in PATH", name)
+	}
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", name, err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line), nil
+}
+
 func normalizeToolName(name string) string {
 	value := strings.ToLower(strings.TrimSpace(name))
 	switch value {
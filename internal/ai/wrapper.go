@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ExecWrapperPlaceholder marks where a wrapper template puts the tool's argv.
+const ExecWrapperPlaceholder = "{cmd}"
+
+// execWrapperShellChars are refused in a wrapper template. The template is
+// split on whitespace and run as an argv, never through a shell, so quoting,
+// pipes and substitutions would reach the wrapper as literal arguments rather
+// than doing what they appear to. Refusing them keeps that surprise out.
+const execWrapperShellChars = "'\"`\\$;|&<>(){}*?~"
+
+// ParseExecWrapper splits a wrapper template such as "nice -n 10 {cmd}" into
+// argv. {cmd} must appear once, as a whole word, and not first; words after
+// it follow the tool's own arguments. A template without {cmd} has the tool
+// appended. An empty template returns nil, meaning no wrapper.
+func ParseExecWrapper(template string) ([]string, error) {
+	words := strings.Fields(template)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	placeholders := 0
+	for i, word := range words {
+		if word == ExecWrapperPlaceholder {
+			if i == 0 {
+				return nil, fmt.Errorf("wrapper must start with a command, not %s", ExecWrapperPlaceholder)
+			}
+			placeholders++
+			continue
+		}
+		if strings.IndexFunc(word, unicode.IsControl) >= 0 {
+			return nil, fmt.Errorf("wrapper word %q contains a control character", word)
+		}
+		if strings.ContainsAny(word, execWrapperShellChars) {
+			return nil, fmt.Errorf("wrapper word %q contains shell syntax; the wrapper is not run through a shell", word)
+		}
+	}
+	if placeholders > 1 {
+		return nil, fmt.Errorf("wrapper must contain %s at most once", ExecWrapperPlaceholder)
+	}
+	if placeholders == 0 {
+		words = append(words, ExecWrapperPlaceholder)
+	}
+	return words, nil
+}
+
+// ValidateExtraArgs checks arguments passed through to a tool's CLI. Like a
+// wrapper they are run as an argv and never through a shell, so words with
+// shell syntax or control characters are refused rather than reaching the
+// tool as literal text, and so are empty words.
+func ValidateExtraArgs(args []string) error {
+	for _, arg := range args {
+		if strings.TrimSpace(arg) == "" {
+			return fmt.Errorf("argument must not be empty")
+		}
+		if strings.IndexFunc(arg, unicode.IsControl) >= 0 {
+			return fmt.Errorf("argument %q contains a control character", arg)
+		}
+		if strings.ContainsAny(arg, execWrapperShellChars) {
+			return fmt.Errorf("argument %q contains shell syntax; arguments are not run through a shell", arg)
+		}
+	}
+	return nil
+}
+
+// wrapCommand puts name and args in place of the placeholder in a parsed
+// wrapper. A nil wrapper returns the command unchanged.
+func wrapCommand(wrapper []string, name string, args []string) (string, []string) {
+	if len(wrapper) == 0 {
+		return name, args
+	}
+	out := make([]string, 0, len(wrapper)+len(args))
+	for _, word := range wrapper[1:] {
+		if word == ExecWrapperPlaceholder {
+			out = append(out, name)
+			out = append(out, args...)
+			continue
+		}
+		out = append(out, word)
+	}
+	return wrapper[0], out
+}
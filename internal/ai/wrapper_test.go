@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseExecWrapper(t *testing.T) {
+	cases := []struct {
+		template string
+		want     []string
+		wantErr  bool
+	}{
+		{template: "", want: nil},
+		{template: "  nice -n 10  {cmd} ", want: []string{"nice", "-n", "10", "{cmd}"}},
+		{template: "timeout 600", want: []string{"timeout", "600", "{cmd}"}},
+		{template: "ssh buildbox {cmd} --extra", want: []string{"ssh", "buildbox", "{cmd}", "--extra"}},
+		{template: "{cmd} --verbose", wantErr: true},
+		{template: "wrap {cmd} {cmd}", wantErr: true},
+		{template: "wrap x{cmd}", wantErr: true},
+		{template: "sh -c '{cmd}'", wantErr: true},
+		{template: "nice {cmd}; rm -rf /", wantErr: true},
+		{template: "env FOO=$HOME {cmd}", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseExecWrapper(tc.template)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseExecWrapper(%q) = %q, want an error", tc.template, got)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("ParseExecWrapper(%q) = %q, %v; want %q", tc.template, got, err, tc.want)
+		}
+	}
+}
+
+func TestWrapCommand(t *testing.T) {
+	name, args := wrapCommand(nil, "claude", []string{"-p", "fix it"})
+	if name != "claude" || !slices.Equal(args, []string{"-p", "fix it"}) {
+		t.Fatalf("nil wrapper changed the command: %s %q", name, args)
+	}
+
+	wrapper := []string{"ssh", "buildbox", "{cmd}", "--tail"}
+	name, args = wrapCommand(wrapper, "claude", []string{"-p", "fix it; rm -rf /"})
+	want := []string{"buildbox", "claude", "-p", "fix it; rm -rf /", "--tail"}
+	if name != "ssh" || !slices.Equal(args, want) {
+		t.Fatalf("wrapped command = %s %q, want ssh %q", name, args, want)
+	}
+}
+
+func TestValidateExtraArgs(t *testing.T) {
+	if err := ValidateExtraArgs([]string{"--max-turns", "20", "--temperature=0.2"}); err != nil {
+		t.Fatalf("plain flags refused: %v", err)
+	}
+	for _, args := range [][]string{
+		{"--max-turns", "$(reboot)"},
+		{"--append", "x; rm -rf /"},
+		{"--note", "a\nb"},
+		{""},
+	} {
+		if err := ValidateExtraArgs(args); err == nil {
+			t.Errorf("ValidateExtraArgs(%q) accepted", args)
+		}
+	}
+}
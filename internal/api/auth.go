@@ -49,7 +49,7 @@ func ReadTokenFile(fogHome string) (string, error) {
 }
 
 // WithAuth returns middleware that enforces Bearer token authentication.
-// The /health endpoint and OPTIONS preflight requests are exempt.
+// The /health and /ready endpoints and OPTIONS preflight requests are exempt.
 // Only /api/* and /mcp/* routes require auth so Slack webhooks and other
 // non-API handlers can function without needing to attach the bearer token.
 func WithAuth(token string, next http.Handler) http.Handler {
@@ -58,8 +58,8 @@ func WithAuth(token string, next http.Handler) http.Handler {
 		return next
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Exempt health check and CORS preflight.
-		if r.URL.Path == "/health" || r.Method == http.MethodOptions {
+		// Exempt health checks and CORS preflight.
+		if r.URL.Path == "/health" || r.URL.Path == "/ready" || r.Method == http.MethodOptions {
 			next.ServeHTTP(w, r)
 			return
 		}
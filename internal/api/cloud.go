@@ -37,7 +37,11 @@ func (s *Server) handleCloudPair(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Code string `json:"code"`
+		Code      string `json:"code"`
+		Force     bool   `json:"force,omitempty"`
+		AddDevice bool   `json:"add_device,omitempty"`
+		Default   bool   `json:"default,omitempty"`
+		Label     string `json:"label,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
@@ -77,7 +81,12 @@ func (s *Server) handleCloudPair(w http.ResponseWriter, r *http.Request) {
 
 	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
 	defer cancel()
-	resp, err := client.ClaimPairing(ctx, req.Code)
+	resp, err := client.ClaimPairing(ctx, req.Code, cloudrelay.PairClaimOptions{
+		Force:     req.Force,
+		AddDevice: req.AddDevice,
+		Default:   req.Default,
+		Label:     req.Label,
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskUsageTTL is how long a worktree size is reused. Walking a large
+// worktree with node_modules or build output takes seconds, and a session
+// list sorted by size asks for every session at once.
+const diskUsageTTL = 30 * time.Second
+
+// diskUsageCacheMax bounds the cache. Expired sizes are dropped as new ones
+// are stored, and past this many the oldest go first.
+const diskUsageCacheMax = 1024
+
+type diskUsageResponse struct {
+	SessionID    string    `json:"session_id"`
+	WorktreePath string    `json:"worktree_path"`
+	Exists       bool      `json:"exists"`
+	Bytes        int64     `json:"bytes"`
+	Files        int64     `json:"files"`
+	ExcludeGit   bool      `json:"exclude_git"`
+	ComputedAt   time.Time `json:"computed_at"`
+}
+
+// diskUsageCache holds recent worktree sizes, keyed by session and whether
+// .git was excluded.
+type diskUsageCache struct {
+	mu      sync.Mutex
+	entries map[string]diskUsageResponse
+}
+
+func diskUsageKey(sessionID string, excludeGit bool) string {
+	return sessionID + "\x00" + strconv.FormatBool(excludeGit)
+}
+
+func (c *diskUsageCache) get(key string) (diskUsageResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	usage, ok := c.entries[key]
+	if !ok || time.Since(usage.ComputedAt) >= diskUsageTTL {
+		return diskUsageResponse{}, false
+	}
+	return usage, true
+}
+
+func (c *diskUsageCache) put(key string, usage diskUsageResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]diskUsageResponse)
+	}
+	for k, cached := range c.entries {
+		if time.Since(cached.ComputedAt) >= diskUsageTTL {
+			delete(c.entries, k)
+		}
+	}
+	for len(c.entries) >= diskUsageCacheMax {
+		oldest := ""
+		for k, cached := range c.entries {
+			if oldest == "" || cached.ComputedAt.Before(c.entries[oldest].ComputedAt) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = usage
+}
+
+// forget drops a session's cached sizes, for when its worktree goes away.
+func (c *diskUsageCache) forget(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, diskUsageKey(sessionID, false))
+	delete(c.entries, diskUsageKey(sessionID, true))
+}
+
+// getSessionDiskUsage serves GET /api/sessions/{id}/disk-usage.
+func (s *Server) getSessionDiskUsage(w http.ResponseWriter, r *http.Request, sessionID string) {
+	excludeGit := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("exclude_git")); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "exclude_git must be a boolean", http.StatusBadRequest)
+			return
+		}
+		excludeGit = parsed
+	}
+
+	// The session is looked up even on a cache hit, so a deleted session is
+	// a 404 rather than its last size.
+	session, found, err := s.stateStore.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		s.diskCache.forget(sessionID)
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	key := diskUsageKey(session.ID, excludeGit)
+	if usage, ok := s.diskCache.get(key); ok {
+		s.writeJSON(w, http.StatusOK, usage)
+		return
+	}
+
+	usage := diskUsageResponse{
+		SessionID:    session.ID,
+		WorktreePath: session.WorktreePath,
+		ExcludeGit:   excludeGit,
+	}
+	if wt := strings.TrimSpace(session.WorktreePath); wt != "" {
+		usage.Bytes, usage.Files, err = dirSize(wt, excludeGit)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			usage.Bytes, usage.Files = 0, 0
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		default:
+			usage.Exists = true
+		}
+	}
+	usage.ComputedAt = time.Now().UTC()
+	s.diskCache.put(key, usage)
+	s.writeJSON(w, http.StatusOK, usage)
+}
+
+// dirSize totals the sizes of the regular files under root. Symlinks are not
+// followed, so a link to a shared cache is not counted against the worktree.
+// A missing root is reported as fs.ErrNotExist; files that vanish during the
+// walk are skipped.
+func dirSize(root string, excludeGit bool) (bytes, files int64, err error) {
+	if _, err := os.Stat(root); err != nil {
+		return 0, 0, err
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+		if excludeGit && d.Name() == ".git" && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		bytes += info.Size()
+		files++
+		return nil
+	})
+	return bytes, files, err
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestGetSessionDiskUsage(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	wt := t.TempDir()
+	for name, size := range map[string]int{"a.txt": 100, "sub/b.txt": 50, ".git/objects/pack": 1000} {
+		path := filepath.Join(wt, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateSession(state.Session{
+		ID: "session-2", RepoName: "acme/api", Branch: "fog/size",
+		WorktreePath: wt, Tool: "claude", Status: "COMPLETED",
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	get := func(path string) diskUsageResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: got %d body=%s", path, w.Code, w.Body.String())
+		}
+		var usage diskUsageResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return usage
+	}
+
+	if usage := get("/api/sessions/session-2/disk-usage"); !usage.Exists || usage.Bytes != 1150 || usage.Files != 3 {
+		t.Fatalf("usage = %+v, want 1150 bytes in 3 files", usage)
+	}
+	if usage := get("/api/sessions/session-2/disk-usage?exclude_git=true"); usage.Bytes != 150 || usage.Files != 2 {
+		t.Fatalf("usage without .git = %+v, want 150 bytes in 2 files", usage)
+	}
+
+	if err := os.WriteFile(filepath.Join(wt, "c.txt"), make([]byte, 10), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if usage := get("/api/sessions/session-2/disk-usage"); usage.Bytes != 1150 {
+		t.Fatalf("cached usage = %+v, want the earlier 1150 bytes", usage)
+	}
+
+	// The fixture's worktree path does not exist on disk.
+	if usage := get("/api/sessions/session-1/disk-usage"); usage.Exists || usage.Bytes != 0 {
+		t.Fatalf("missing worktree usage = %+v", usage)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/missing/disk-usage", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", w.Code)
+	}
+
+	// A cached size is not served once its session is gone.
+	if err := srv.stateStore.DeleteSession("session-2"); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions/session-2/disk-usage", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("deleted session: got %d, want 404", w.Code)
+	}
+	if _, ok := srv.diskCache.get(diskUsageKey("session-2", false)); ok {
+		t.Error("deleted session's size is still cached")
+	}
+}
+
+func TestDiskUsageCacheDropsExpiredAndOldest(t *testing.T) {
+	var c diskUsageCache
+	old := time.Now().Add(-2 * diskUsageTTL)
+	c.put("expired", diskUsageResponse{ComputedAt: old})
+	c.put("fresh", diskUsageResponse{ComputedAt: time.Now()})
+	if _, ok := c.entries["expired"]; ok {
+		t.Fatal("expired entry survived a put")
+	}
+
+	for i := range diskUsageCacheMax + 10 {
+		c.put(diskUsageKey("s"+strconv.Itoa(i), false), diskUsageResponse{ComputedAt: time.Now()})
+	}
+	if len(c.entries) != diskUsageCacheMax {
+		t.Fatalf("cache holds %d entries, want at most %d", len(c.entries), diskUsageCacheMax)
+	}
+	if _, ok := c.entries["fresh"]; ok {
+		t.Error("the oldest entry was not the one dropped")
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// eventsStreamBuffer is how many events a slow /api/events/stream client may
+// fall behind by before it starts missing them.
+const eventsStreamBuffer = 256
+
+// sessionEventsBuffer is how many session changes a slow /api/events client
+// may fall behind by before it starts missing them.
+const sessionEventsBuffer = 64
+
+// eventsKeepAlive is how often an idle stream sends an SSE comment, so
+// proxies do not close a console that is waiting for the next run.
+const eventsKeepAlive = 15 * time.Second
+
+// handleEventsStream pushes every run event, across all runs, as it is
+// appended. Unlike the per-run stream it never finishes on its own and does
+// not replay history: it carries only events appended after it connected.
+func (s *Server) handleEventsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := s.stateStore.SubscribeRunEvents(eventsStreamBuffer)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			payload, _ := json.Marshal(event)
+			fmt.Fprintf(w, "id: %d\n", event.ID)
+			fmt.Fprintf(w, "event: run_event\n")
+			fmt.Fprintf(w, "data: %s\n\n", payload)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleSessionEvents pushes session list changes — status, busy flag, new
+// run, PR, branch, pause, archive, pruning and deletion — as they are made, so a client can refresh one row
+// instead of polling GET /api/sessions. Like handleEventsStream it carries
+// only changes made after it connected.
+func (s *Server) handleSessionEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	changes, unsubscribe := s.runner.SubscribeSessionChanges(sessionEventsBuffer)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			payload, _ := json.Marshal(change)
+			fmt.Fprintf(w, "event: session\n")
+			fmt.Fprintf(w, "data: %s\n\n", payload)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleEventsStream(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	ts := httptest.NewServer(http.HandlerFunc(srv.handleEventsStream))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	// The handler subscribes before sending headers, so this event is seen.
+	if err := srv.stateStore.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: "ai_start"}); err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event state.RunEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		if event.RunID != "run-1" || event.Type != "ai_start" {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		return
+	}
+	t.Fatalf("stream ended without an event: %v", scanner.Err())
+}
+
+func TestHandleSessionEvents(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	// The fixture's worktree does not exist, so with a dirty-worktree policy
+	// a follow-up marks the session busy and releases it again when the
+	// preflight fails, without running anything.
+	if err := srv.stateStore.SetSetting(runner.SettingFollowupDirtyPolicy, runner.DirtyPolicyFail); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(srv.handleSessionEvents))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	if _, err := srv.runner.ContinueSession("session-1", "carry on"); err == nil {
+		t.Fatal("follow-up without a worktree succeeded")
+	}
+
+	var busy []bool
+	scanner := bufio.NewScanner(resp.Body)
+	for len(busy) < 2 && scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var change runner.SessionChange
+		if err := json.Unmarshal([]byte(data), &change); err != nil {
+			t.Fatalf("decode change: %v", err)
+		}
+		if change.SessionID != "session-1" || change.Kind != runner.SessionChangeBusy || change.Busy == nil {
+			t.Fatalf("unexpected change: %s", data)
+		}
+		busy = append(busy, *change.Busy)
+	}
+	if len(busy) != 2 || !busy[0] || busy[1] {
+		t.Fatalf("busy changes = %v, want [true false] (%v)", busy, scanner.Err())
+	}
+}
+
+func TestListRunEventsFiltersByType(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	for _, typ := range []string{"ai_start", "ai_stream", "ai_stream", "ai_output", "commit"} {
+		if err := srv.stateStore.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/runs/run-1/events?types=ai_output,+commit,,commit", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	var events []state.RunEvent
+	if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+		t.Fatalf("decode events: %v", err)
+	}
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	if got := strings.Join(types, ","); got != "ai_output,commit" {
+		t.Fatalf("event types = %s", got)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// streamEventType is the per-chunk output event. The complete output is also
+// recorded as ai_output, so exports leave the chunks out.
+const streamEventType = "ai_stream"
+
+type repoExport struct {
+	Repo       string          `json:"repo"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Sessions   []sessionExport `json:"sessions"`
+}
+
+type sessionExport struct {
+	state.Session
+	Runs []runExport `json:"runs"`
+}
+
+type runExport struct {
+	state.Run
+	Events []state.RunEvent `json:"events"`
+}
+
+// exportRepo writes every session of a repo with its runs and their events.
+// format=ndjson writes one session per line as it is read, so large repos are
+// never held in memory whole.
+func (s *Server) exportRepo(w http.ResponseWriter, r *http.Request, name string) {
+	format := strings.TrimSpace(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != "ndjson" {
+		http.Error(w, "format must be json or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	repo, found, err := s.stateStore.GetRepoByName(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "repo not found", http.StatusNotFound)
+		return
+	}
+	sessions, err := s.stateStore.ListSessionsByRepo(repo.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "ndjson" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		enc := json.NewEncoder(w)
+		for _, session := range sessions {
+			export, err := s.exportSession(session)
+			if err != nil {
+				// Headers are sent; all that is left is to stop short.
+				return
+			}
+			if err := enc.Encode(export); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return
+	}
+
+	out := repoExport{
+		Repo:       repo.Name,
+		ExportedAt: time.Now().UTC(),
+		Sessions:   make([]sessionExport, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		export, err := s.exportSession(session)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out.Sessions = append(out.Sessions, export)
+	}
+	s.writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) exportSession(session state.Session) (sessionExport, error) {
+	runs, err := s.stateStore.ListRuns(session.ID)
+	if err != nil {
+		return sessionExport{}, err
+	}
+	out := sessionExport{Session: redactSession(session), Runs: make([]runExport, 0, len(runs))}
+	// Oldest first, so the export reads as the session's history.
+	for i := len(runs) - 1; i >= 0; i-- {
+		events, err := s.stateStore.ListRunEventsExcept(runs[i].ID, streamEventType)
+		if err != nil {
+			return sessionExport{}, err
+		}
+		out.Runs = append(out.Runs, runExport{Run: runs[i], Events: events})
+	}
+	return out, nil
+}
+
+// getSessionExport serves GET /api/sessions/{id}/export: one session as a
+// self-contained bundle with its final diff, secrets redacted, for attaching
+// to an incident review or PR.
+func (s *Server) getSessionExport(w http.ResponseWriter, sessionID string) {
+	export, err := s.runner.ExportSession(sessionID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, state.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="fog-session-%s.json"`, export.Session.ID))
+	s.writeJSON(w, http.StatusOK, export)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestExportRepo(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	for _, typ := range []string{"ai_start", "ai_stream", "ai_output"} {
+		if err := srv.stateStore.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/repos/acme/api/export", nil)
+	w := httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var out repoExport
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if out.Repo != "acme/api" || len(out.Sessions) != 1 {
+		t.Fatalf("unexpected export: %+v", out)
+	}
+	sess := out.Sessions[0]
+	if sess.ID != "session-1" || len(sess.Runs) != 1 || sess.Runs[0].ID != "run-1" {
+		t.Fatalf("unexpected session export: %+v", sess)
+	}
+	if got := len(sess.Runs[0].Events); got != 2 {
+		t.Fatalf("exported %d events, want 2 (stream chunks skipped)", got)
+	}
+}
+
+func TestExportRepoNDJSON(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/repos/acme/api/export?format=ndjson", nil)
+	w := httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	var lines int
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var sess sessionExport
+		if err := json.Unmarshal(scanner.Bytes(), &sess); err != nil {
+			t.Fatalf("decode line %d: %v", lines, err)
+		}
+		if sess.ID != "session-1" {
+			t.Fatalf("unexpected session: %+v", sess)
+		}
+		lines++
+	}
+	if lines != 1 {
+		t.Fatalf("got %d lines, want 1", lines)
+	}
+}
+
+func TestExportRepoErrors(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/api/repos/acme/missing/export", http.StatusNotFound},
+		{"/api/repos/acme/api/export?format=xml", http.StatusBadRequest},
+		{"/api/repos/acme/api/unknown", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		w := httptest.NewRecorder()
+		srv.handleRepoDetail(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.path, w.Code, tc.want)
+		}
+	}
+}
+
+func TestGetSessionExport(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	for _, typ := range []string{"ai_start", "ai_stream", "ai_output"} {
+		if err := srv.stateStore.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/export", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="fog-session-session-1.json"` {
+		t.Fatalf("content disposition = %q", cd)
+	}
+
+	var out runner.SessionExport
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if out.Version != runner.SessionExportVersion || out.Session.ID != "session-1" || out.Session.Model != "sonnet" {
+		t.Fatalf("unexpected export: %+v", out)
+	}
+	if len(out.Runs) != 1 || len(out.Runs[0].Events) != 2 {
+		t.Fatalf("unexpected runs: %+v", out.Runs)
+	}
+	// The fixture's worktree does not exist.
+	if out.Diff != nil || out.DiffError == "" {
+		t.Fatalf("diff = %+v, diff_error = %q", out.Diff, out.DiffError)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions/missing/export", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("missing session: status %d, want 404", w.Code)
+	}
+}
+
+func TestSessionResponsesRedactEnvValues(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateSession(state.Session{
+		ID:           "session-env",
+		RepoName:     "acme/api",
+		Branch:       "fog/env",
+		WorktreePath: "/tmp/acme-api/env",
+		Tool:         "claude",
+		Status:       "COMPLETED",
+		Env:          map[string]string{"API_KEY": "s3cr3t-value-123"},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	for _, tc := range []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/api/sessions", srv.handleSessions},
+		{"/api/sessions/session-env", srv.handleSessionDetail},
+		{"/api/repos/acme/api/export", srv.handleRepoDetail},
+	} {
+		w := httptest.NewRecorder()
+		tc.handler(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d body=%s", tc.path, w.Code, w.Body.String())
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "s3cr3t-value-123") {
+			t.Errorf("GET %s leaks the env value: %s", tc.path, body)
+		}
+		if !strings.Contains(body, `"API_KEY"`) {
+			t.Errorf("GET %s dropped the env key: %s", tc.path, body)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+const (
+	settingGhStatusTTLSeconds = "gh_status_ttl_seconds"
+	defaultGhStatusTTLSeconds = 30
+)
+
+// ghStatus is whether gh is installed and, if so, logged in.
+type ghStatus struct {
+	Installed     bool
+	Authenticated bool
+}
+
+// ghStatusCache holds the last gh status check. `gh auth status` forks a
+// process, so settings and status requests read the cached value and a stale
+// one is refreshed in the background.
+type ghStatusCache struct {
+	mu         sync.Mutex
+	status     ghStatus
+	key        string
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// ghStatusTTL returns how long a gh status check is reused. Zero disables
+// caching; an unset or malformed value falls back to the default.
+func ghStatusTTL(store *state.Store) time.Duration {
+	val, found, err := store.GetSetting(settingGhStatusTTLSeconds)
+	if err != nil || !found {
+		return defaultGhStatusTTLSeconds * time.Second
+	}
+	secs, err := strconv.Atoi(val)
+	if err != nil || secs < 0 {
+		return defaultGhStatusTTLSeconds * time.Second
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// ghConfigKey fingerprints the gh configuration a status was checked under,
+// so a changed gh_path or GitHub token invalidates the cached status even when
+// it was changed by another process such as `fog config`.
+func ghConfigKey(store *state.Store) string {
+	cfg := ghcli.StoreConfigSource(store)()
+	sum := sha256.Sum256([]byte(cfg.Path + "\x00" + cfg.Token))
+	return hex.EncodeToString(sum[:])
+}
+
+func checkGhStatus() ghStatus {
+	var st ghStatus
+	st.Installed = isGhAvailableFn()
+	if st.Installed {
+		st.Authenticated = isGhAuthenticatedFn()
+	}
+	return st
+}
+
+// ghStatus returns the cached gh status. The first call, and the first after
+// the gh configuration changes, checks synchronously; later calls return the
+// cached value and refresh it in the background once it is older than the TTL.
+func (s *Server) ghStatus() ghStatus {
+	ttl := ghStatusTTL(s.stateStore)
+	key := ghConfigKey(s.stateStore)
+	c := &s.ghCache
+
+	c.mu.Lock()
+	if ttl == 0 || c.fetchedAt.IsZero() || c.key != key {
+		c.mu.Unlock()
+		st := checkGhStatus()
+		c.mu.Lock()
+		c.status, c.key, c.fetchedAt = st, key, time.Now()
+		c.mu.Unlock()
+		return st
+	}
+	st := c.status
+	if time.Since(c.fetchedAt) >= ttl && !c.refreshing {
+		c.refreshing = true
+		go s.refreshGhStatus(key)
+	}
+	c.mu.Unlock()
+	return st
+}
+
+func (s *Server) refreshGhStatus(key string) {
+	st := checkGhStatus()
+	c := &s.ghCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	// The configuration changed while checking; the synchronous path has
+	// already stored a status for the new one.
+	if c.key != key {
+		return
+	}
+	c.status, c.fetchedAt = st, time.Now()
+}
+
+// invalidateGhStatus drops the cached status so the next read checks gh again.
+func (s *Server) invalidateGhStatus() {
+	c := &s.ghCache
+	c.mu.Lock()
+	c.fetchedAt = time.Time{}
+	c.mu.Unlock()
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stubGhChecks(t *testing.T, authenticated *atomic.Bool) *atomic.Int32 {
+	t.Helper()
+	origAvail, origAuth := isGhAvailableFn, isGhAuthenticatedFn
+	t.Cleanup(func() {
+		isGhAvailableFn = origAvail
+		isGhAuthenticatedFn = origAuth
+	})
+	var calls atomic.Int32
+	isGhAvailableFn = func() bool { return true }
+	isGhAuthenticatedFn = func() bool {
+		calls.Add(1)
+		return authenticated.Load()
+	}
+	return &calls
+}
+
+func TestGhStatusIsCachedWithinTTL(t *testing.T) {
+	srv := newTestServer(t)
+	var authed atomic.Bool
+	authed.Store(true)
+	calls := stubGhChecks(t, &authed)
+
+	for i := 0; i < 3; i++ {
+		if st := srv.ghStatus(); !st.Installed || !st.Authenticated {
+			t.Fatalf("status = %+v, want installed and authenticated", st)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("gh auth checks = %d, want 1", got)
+	}
+}
+
+func TestGhStatusRefreshesInBackgroundWhenStale(t *testing.T) {
+	srv := newTestServer(t)
+	var authed atomic.Bool
+	authed.Store(true)
+	calls := stubGhChecks(t, &authed)
+
+	srv.ghStatus()
+	authed.Store(false)
+	srv.ghCache.mu.Lock()
+	srv.ghCache.fetchedAt = time.Now().Add(-time.Hour)
+	srv.ghCache.mu.Unlock()
+
+	// The stale value is served while the refresh runs.
+	if st := srv.ghStatus(); !st.Authenticated {
+		t.Fatalf("stale read = %+v, want cached authenticated status", st)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.ghStatus().Authenticated {
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not update the cached status")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("gh auth checks = %d, want 2", got)
+	}
+}
+
+func TestGhStatusRecheckedWhenTokenChanges(t *testing.T) {
+	srv := newTestServer(t)
+	var authed atomic.Bool
+	calls := stubGhChecks(t, &authed)
+
+	if st := srv.ghStatus(); st.Authenticated {
+		t.Fatalf("status = %+v, want unauthenticated", st)
+	}
+	authed.Store(true)
+	if err := srv.stateStore.SaveGitHubToken("ghp_new"); err != nil {
+		t.Fatalf("save token: %v", err)
+	}
+	if st := srv.ghStatus(); !st.Authenticated {
+		t.Fatalf("status after token update = %+v, want authenticated", st)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("gh auth checks = %d, want 2", got)
+	}
+}
+
+func TestGhStatusTTLZeroDisablesCache(t *testing.T) {
+	srv := newTestServer(t)
+	var authed atomic.Bool
+	calls := stubGhChecks(t, &authed)
+	if err := srv.stateStore.SetSetting(settingGhStatusTTLSeconds, "0"); err != nil {
+		t.Fatalf("set ttl: %v", err)
+	}
+
+	srv.ghStatus()
+	srv.ghStatus()
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("gh auth checks = %d, want 2", got)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+var (
+	// lookPathFn and toolAvailableFn are swapped out by tests.
+	lookPathFn      = exec.LookPath
+	toolAvailableFn = isToolAvailable
+)
+
+// readinessCheck is one dependency in GET /health/ready. A failing critical
+// check makes the daemon unready; a failing non-critical one only degrades
+// it, as gh does: without it runs still work but PRs cannot be opened.
+type readinessCheck struct {
+	OK       bool   `json:"ok"`
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// readinessReport is the GET /health/ready response.
+type readinessReport struct {
+	Status string                    `json:"status"`
+	Checks map[string]readinessCheck `json:"checks"`
+	// Tools maps every tool Fog can drive to whether it is installed.
+	Tools map[string]bool `json:"tools"`
+	Time  string          `json:"time"`
+}
+
+// handleHealthReady is a deep readiness probe. On top of what /ready checks
+// it verifies that git is installed, that the database accepts writes, that
+// at least one AI tool is available, and whether gh is installed and logged
+// in. It answers 503 when a critical check fails.
+func (s *Server) handleHealthReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report := readinessReport{
+		Status: "ready",
+		Checks: map[string]readinessCheck{},
+		Tools:  map[string]bool{},
+		Time:   time.Now().Format(time.RFC3339),
+	}
+
+	server := readinessCheck{OK: s.ready.Load() && s.runner != nil, Critical: true}
+	if !server.OK {
+		server.Detail = "not accepting traffic"
+	}
+	report.Checks["server"] = server
+
+	database := readinessCheck{Critical: true}
+	if s.stateStore == nil {
+		database.Detail = "state store not configured"
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := s.stateStore.CheckWritable(ctx); err != nil {
+			database.Detail = "not writable: " + err.Error()
+		} else {
+			database.OK = true
+		}
+	}
+	report.Checks["database"] = database
+
+	gitCheck := readinessCheck{Critical: true}
+	if path, err := lookPathFn("git"); err != nil {
+		gitCheck.Detail = "git not found in PATH"
+	} else {
+		gitCheck.OK, gitCheck.Detail = true, path
+	}
+	report.Checks["git"] = gitCheck
+
+	gh := readinessCheck{}
+	if s.stateStore == nil {
+		gh.Detail = "state store not configured"
+	} else {
+		switch st := s.ghStatus(); {
+		case !st.Installed:
+			gh.Detail = "gh not installed"
+		case !st.Authenticated:
+			gh.Detail = "gh not authenticated"
+		default:
+			gh.OK = true
+		}
+	}
+	report.Checks["gh"] = gh
+
+	var available []string
+	for _, name := range ai.AvailableToolNames() {
+		ok := toolAvailableFn(name)
+		report.Tools[name] = ok
+		if ok {
+			available = append(available, name)
+		}
+	}
+	tools := readinessCheck{OK: len(available) > 0, Critical: true, Detail: strings.Join(available, ", ")}
+	if !tools.OK {
+		tools.Detail = "no AI tool installed"
+	}
+	report.Checks["tools"] = tools
+
+	status := http.StatusOK
+	for _, check := range report.Checks {
+		if check.Critical && !check.OK {
+			report.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	s.writeJSON(w, status, report)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func stubReadinessDeps(t *testing.T, gitFound bool, tools map[string]bool) {
+	t.Helper()
+	origLook, origTool := lookPathFn, toolAvailableFn
+	t.Cleanup(func() {
+		lookPathFn = origLook
+		toolAvailableFn = origTool
+	})
+	lookPathFn = func(name string) (string, error) {
+		if !gitFound {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + name, nil
+	}
+	toolAvailableFn = func(name string) bool { return tools[name] }
+}
+
+func getHealthReady(t *testing.T, srv *Server) (int, readinessReport) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	srv.handleHealthReady(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	var report readinessReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	return w.Code, report
+}
+
+func TestHealthReadyReportsEachCheck(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetReady(true)
+	var authed atomic.Bool
+	stubGhChecks(t, &authed)
+	stubReadinessDeps(t, true, map[string]bool{"claude": true})
+
+	code, report := getHealthReady(t, srv)
+	if code != http.StatusOK || report.Status != "ready" {
+		t.Fatalf("status = %d %q, want 200 ready: %+v", code, report.Status, report)
+	}
+	for _, name := range []string{"server", "database", "git", "tools"} {
+		if c := report.Checks[name]; !c.OK || !c.Critical {
+			t.Fatalf("check %s = %+v, want ok and critical", name, c)
+		}
+	}
+	// gh missing auth degrades the report without failing it.
+	if c := report.Checks["gh"]; c.OK || c.Critical || c.Detail != "gh not authenticated" {
+		t.Fatalf("gh check = %+v", c)
+	}
+	if !report.Tools["claude"] || report.Tools["cursor"] {
+		t.Fatalf("tools = %v", report.Tools)
+	}
+}
+
+func TestHealthReadyFailsOnMissingCriticalDependency(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetReady(true)
+	var authed atomic.Bool
+	authed.Store(true)
+	stubGhChecks(t, &authed)
+
+	stubReadinessDeps(t, true, nil)
+	code, report := getHealthReady(t, srv)
+	if code != http.StatusServiceUnavailable || report.Checks["tools"].OK {
+		t.Fatalf("no tools: status %d, tools check %+v", code, report.Checks["tools"])
+	}
+
+	stubReadinessDeps(t, false, map[string]bool{"claude": true})
+	code, report = getHealthReady(t, srv)
+	if code != http.StatusServiceUnavailable || report.Checks["git"].OK {
+		t.Fatalf("no git: status %d, git check %+v", code, report.Checks["git"])
+	}
+
+	stubReadinessDeps(t, true, map[string]bool{"claude": true})
+	_ = srv.stateStore.Close()
+	code, report = getHealthReady(t, srv)
+	if code != http.StatusServiceUnavailable || report.Checks["database"].OK {
+		t.Fatalf("closed database: status %d, database check %+v", code, report.Checks["database"])
+	}
+}
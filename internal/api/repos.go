@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -10,11 +11,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
 	fogenv "github.com/darkLord19/foglet/internal/env"
 	"github.com/darkLord19/foglet/internal/ghcli"
+	"github.com/darkLord19/foglet/internal/runner"
 	"github.com/darkLord19/foglet/internal/state"
 	"golang.org/x/sync/errgroup"
 )
@@ -25,19 +28,75 @@ var (
 	runGitCommandFn     = runGitCommand
 	isGhAvailableFn     = ghcli.IsGhAvailable
 	isGhAuthenticatedFn = ghcli.IsGhAuthenticated
+	isGhHostAuthedFn    = ghcli.IsGhAuthenticatedOnHost
 	ghcliCloneRepoFn    = ghcli.CloneRepo
 	repoSegmentPattern  = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
 )
 
+const (
+	// settingMaxConcurrentImports is the store key for how many repos an
+	// import clones at once.
+	settingMaxConcurrentImports = "max_concurrent_imports"
+
+	// defaultMaxConcurrentImports keeps a bulk import from saturating the
+	// network or tripping GitHub's secondary rate limits.
+	defaultMaxConcurrentImports = 5
+)
+
 type importReposRequest struct {
 	Repos []string `json:"repos"`
+	// Host is the GitHub host to import from, for GitHub Enterprise. Empty
+	// means gh's default, normally github.com.
+	Host string `json:"host,omitempty"`
 }
 
 type importReposResponse struct {
 	Imported []string `json:"imported"`
 }
 
+// importDryRunRepo is one requested repo as a dry-run import reports it.
+// DiskUsageKB is GitHub's estimate of the repository size, which is close to
+// what a bare clone fetches.
+type importDryRunRepo struct {
+	Repo           string `json:"repo"`
+	Accessible     bool   `json:"accessible"`
+	Error          string `json:"error,omitempty"`
+	DefaultBranch  string `json:"default_branch,omitempty"`
+	Private        bool   `json:"private,omitempty"`
+	DiskUsageKB    int64  `json:"disk_usage_kb,omitempty"`
+	AlreadyManaged bool   `json:"already_managed,omitempty"`
+}
+
+type importDryRunResponse struct {
+	DryRun bool               `json:"dry_run"`
+	Repos  []importDryRunRepo `json:"repos"`
+}
+
+// deleteRepoResponse reports a removed repo. Purged lists the directories
+// deleted with ?purge=true.
+type deleteRepoResponse struct {
+	Name   string   `json:"name"`
+	Purged []string `json:"purged"`
+}
+
+// repoInUseResponse is the 409 body of DELETE /api/repos.
+type repoInUseResponse struct {
+	Error      string   `json:"error"`
+	SessionIDs []string `json:"session_ids"`
+}
+
+// repoUnmergedResponse is the 409 body of DELETE /api/repos?purge=true when
+// the clone holds branches the purge would lose.
+type repoUnmergedResponse struct {
+	Error    string   `json:"error"`
+	Branches []string `json:"branches"`
+}
+
 func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		s.deleteRepo(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -52,23 +111,189 @@ func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, repos)
 }
 
+// deleteRepo stops managing ?name=owner/repo. With ?purge=true it also
+// deletes the repo's directory under the managed repos dir: the bare clone,
+// the base worktree and any session worktrees beside them. A repo whose files
+// live anywhere else is unmanaged but its files are left alone. A purge that
+// would delete branches not merged into the default branch, such as those of
+// deleted sessions, is refused unless ?force=true confirms it.
+func (s *Server) deleteRepo(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	purge, _ := strconv.ParseBool(r.URL.Query().Get("purge"))
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	repo, found, err := s.stateStore.GetRepoByName(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "repo not found", http.StatusNotFound)
+		return
+	}
+
+	var inUse *state.RepoInUseError
+	fogHome, err := fogenv.FogHome()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Sessions are reported first: their branches are among those a purge
+	// would otherwise complain about.
+	if purge && !force {
+		if err := s.stateStore.CheckRepoUnused(repo.Name); errors.As(err, &inUse) {
+			s.writeJSON(w, http.StatusConflict, repoInUseResponse{Error: inUse.Error(), SessionIDs: inUse.SessionIDs})
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		branches, err := fogenv.UnmergedManagedBranches(fogenv.ManagedReposDir(fogHome), repo.BarePath, repo.BaseWorktreePath, repo.DefaultBranch)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(branches) > 0 {
+			s.writeJSON(w, http.StatusConflict, repoUnmergedResponse{
+				Error:    fmt.Sprintf("purging %s would delete %d branch(es) not merged into %s; pass force=true to purge anyway", repo.Name, len(branches), repo.DefaultBranch),
+				Branches: branches,
+			})
+			return
+		}
+	}
+
+	err = s.stateStore.DeleteRepo(repo.Name)
+	switch {
+	case errors.As(err, &inUse):
+		s.writeJSON(w, http.StatusConflict, repoInUseResponse{Error: inUse.Error(), SessionIDs: inUse.SessionIDs})
+		return
+	case errors.Is(err, state.ErrNotFound):
+		http.Error(w, "repo not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := deleteRepoResponse{Name: repo.Name, Purged: []string{}}
+	if purge {
+		dir, ok, err := fogenv.PurgeManagedRepo(fogenv.ManagedReposDir(fogHome), repo.BarePath, repo.BaseWorktreePath)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("repo unmanaged but purge failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if ok {
+			resp.Purged = append(resp.Purged, dir)
+		}
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRepoDetail serves /api/repos/{owner}/{repo}/... subroutes. Repo names
+// contain a slash, so the action is the last path segment.
+func (s *Server) handleRepoDetail(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/repos/"), "/")
+	idx := strings.LastIndex(path, "/")
+	if idx <= 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	name, action := path[:idx], path[idx+1:]
+
+	switch action {
+	case "export":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.exportRepo(w, r, name)
+	case "defaults":
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.setRepoDefaults(w, r, name)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// RepoDefaultsRequest is the payload for PUT /api/repos/{name}/defaults.
+// Empty fields clear the repo's value, deferring to the global settings.
+type RepoDefaultsRequest struct {
+	DefaultTool  string `json:"default_tool"`
+	DefaultModel string `json:"default_model"`
+	// WorktreeRoot is left alone when omitted, so clients that only manage
+	// the tool and model do not clear it.
+	WorktreeRoot *string `json:"worktree_root"`
+}
+
+func (s *Server) setRepoDefaults(w http.ResponseWriter, r *http.Request, name string) {
+	var req RepoDefaultsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tool := strings.TrimSpace(req.DefaultTool)
+	if tool != "" && !s.skipToolCheck && !isToolAvailable(tool) {
+		http.Error(w, fmt.Sprintf("default_tool %q is not available", tool), http.StatusBadRequest)
+		return
+	}
+	if req.WorktreeRoot != nil {
+		if err := runner.ValidateWorktreeRoot(*req.WorktreeRoot); err != nil {
+			http.Error(w, "worktree_root: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.stateStore.SetRepoDefaults(name, tool, req.DefaultModel); err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			http.Error(w, "repo not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.WorktreeRoot != nil {
+		if err := s.stateStore.SetRepoWorktreeRoot(name, *req.WorktreeRoot); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	repo, _, err := s.stateStore.GetRepoByName(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, repo)
+}
+
 func (s *Server) handleDiscoverRepos(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	host, err := ghcli.NormalizeHost(r.URL.Query().Get("host"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// No token required, relies on gh CLI authentication
 	if !isGhAvailableFn() {
 		http.Error(w, "gh CLI is not installed", http.StatusServiceUnavailable)
 		return
 	}
-	if !isGhAuthenticatedFn() {
-		http.Error(w, "gh CLI is not authenticated", http.StatusUnauthorized)
+	if !ghAuthenticatedOn(host) {
+		http.Error(w, ghNotAuthenticatedMessage(host), http.StatusUnauthorized)
 		return
 	}
 
-	repos, err := discoverReposFn()
+	repos, err := discoverReposFn(host)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -83,6 +308,16 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	dryRun := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("dry_run")); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "dry_run must be a boolean", http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+
 	var req importReposRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -92,18 +327,23 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "repos is required", http.StatusBadRequest)
 		return
 	}
+	host, err := ghcli.NormalizeHost(req.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// No token check needed, relies on gh CLI auth which is checked in discover/import steps implicitly or explicitly
 	if !isGhAvailableFn() {
 		http.Error(w, "gh CLI is not installed", http.StatusServiceUnavailable)
 		return
 	}
-	if !isGhAuthenticatedFn() {
-		http.Error(w, "gh CLI is not authenticated", http.StatusUnauthorized)
+	if !ghAuthenticatedOn(host) {
+		http.Error(w, ghNotAuthenticatedMessage(host), http.StatusUnauthorized)
 		return
 	}
 
-	discovered, err := discoverReposFn()
+	discovered, err := discoverReposFn(host)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -117,6 +357,14 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 		available[name] = repo
 	}
 
+	if dryRun {
+		s.writeJSON(w, http.StatusOK, importDryRunResponse{
+			DryRun: true,
+			Repos:  s.checkImport(req.Repos, available),
+		})
+		return
+	}
+
 	selected := make([]ghcli.Repo, 0, len(req.Repos))
 	for _, raw := range req.Repos {
 		fullName := strings.TrimSpace(raw)
@@ -149,8 +397,50 @@ func (s *Server) handleImportRepos(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func discoverGitHubRepos() ([]ghcli.Repo, error) {
-	return ghcli.DiscoverRepos()
+// checkImport reports, for each requested name, what importing it would do.
+// Unlike the import itself it does not stop at the first bad name, so a user
+// sees every typo at once.
+func (s *Server) checkImport(names []string, available map[string]ghcli.Repo) []importDryRunRepo {
+	out := make([]importDryRunRepo, 0, len(names))
+	for _, raw := range names {
+		fullName := strings.TrimSpace(raw)
+		check := importDryRunRepo{Repo: fullName}
+		if _, _, err := splitRepoFullName(fullName); err != nil {
+			check.Error = fmt.Sprintf("invalid repo name %q", raw)
+		} else if repo, ok := available[fullName]; !ok {
+			check.Error = fmt.Sprintf("repo %q is not accessible via gh CLI", fullName)
+		} else {
+			check.Accessible = true
+			check.DefaultBranch = strings.TrimSpace(repo.DefaultBranchRef.Name)
+			check.Private = repo.IsPrivate
+			check.DiskUsageKB = repo.DiskUsage
+			if _, found, err := s.stateStore.GetRepoByName(fullName); err == nil && found {
+				check.AlreadyManaged = true
+			}
+		}
+		out = append(out, check)
+	}
+	return out
+}
+
+func discoverGitHubRepos(host string) ([]ghcli.Repo, error) {
+	return ghcli.DiscoverRepos(host)
+}
+
+// ghAuthenticatedOn reports whether gh is logged in to host, or to any host
+// when host is empty.
+func ghAuthenticatedOn(host string) bool {
+	if host == "" {
+		return isGhAuthenticatedFn()
+	}
+	return isGhHostAuthedFn(host)
+}
+
+func ghNotAuthenticatedMessage(host string) string {
+	if host == "" {
+		return "gh CLI is not authenticated"
+	}
+	return fmt.Sprintf("gh CLI is not authenticated to %s; run `gh auth login --hostname %s`", host, host)
 }
 
 func importSelectedRepos(fogHome string, store *state.Store, repos []ghcli.Repo) ([]string, error) {
@@ -164,7 +454,7 @@ func importSelectedRepos(fogHome string, store *state.Store, repos []ghcli.Repo)
 
 	// Use errgroup to limit concurrency
 	g, ctx := errgroup.WithContext(context.Background())
-	g.SetLimit(5) // Limit to 5 concurrent clones
+	g.SetLimit(maxConcurrentImports(store))
 
 	for i, repo := range repos {
 		g.Go(func() error {
@@ -235,6 +525,23 @@ func importSelectedRepos(fogHome string, store *state.Store, repos []ghcli.Repo)
 	return finalImported, nil
 }
 
+// maxConcurrentImports reads the configured clone concurrency, falling back to
+// the default for an unset, malformed, or non-positive value.
+func maxConcurrentImports(store *state.Store) int {
+	if store == nil {
+		return defaultMaxConcurrentImports
+	}
+	val, found, err := store.GetSetting(settingMaxConcurrentImports)
+	if err != nil || !found {
+		return defaultMaxConcurrentImports
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || n < 1 {
+		return defaultMaxConcurrentImports
+	}
+	return n
+}
+
 func canonicalRepoName(repo ghcli.Repo) (string, error) {
 	fullName := strings.TrimSpace(repo.NameWithOwner)
 	if fullName == "" {
@@ -284,8 +591,8 @@ func splitRepoFullName(fullName string) (owner string, name string, err error) {
 func ensureBareRepoInitialized(repo ghcli.Repo, barePath, basePath string) error {
 	clone := func() error {
 		// Use gh repo clone via ghcli package
-		// We pass FullName (owner/repo)
-		if err := ghcliCloneRepoFn(repo.NameWithOwner, barePath); err != nil {
+		// We pass FullName (owner/repo), host-qualified off github.com
+		if err := ghcliCloneRepoFn(ghcli.CloneName(repo), barePath); err != nil {
 			return fmt.Errorf("clone bare repository %s: %w", repo.NameWithOwner, err)
 		}
 		return nil
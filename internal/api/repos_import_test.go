@@ -217,3 +217,76 @@ func TestImportSelectedRepos_Interrupted_ValidRepo(t *testing.T) {
 		t.Error("expected clone NOT to be called for valid repo, but it was")
 	}
 }
+
+func TestImportSelectedReposHonorsMaxConcurrentImports(t *testing.T) {
+	tmpHome := t.TempDir()
+	store, err := state.NewStore(tmpHome)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+	if err := store.SetSetting(settingMaxConcurrentImports, "2"); err != nil {
+		t.Fatalf("set setting: %v", err)
+	}
+
+	origGit := runGitCommandFn
+	defer func() { runGitCommandFn = origGit }()
+	runGitCommandFn = func(args ...string) error { return nil }
+
+	origClone := ghcliCloneRepoFn
+	defer func() { ghcliCloneRepoFn = origClone }()
+	var inFlight, peak int32
+	ghcliCloneRepoFn = func(fullName, destPath string) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil
+	}
+
+	repos := make([]ghcli.Repo, 6)
+	for i := range repos {
+		name := fmt.Sprintf("acme/repo-%d", i)
+		repos[i] = ghcli.Repo{
+			Name:          fmt.Sprintf("repo-%d", i),
+			NameWithOwner: name,
+			URL:           fmt.Sprintf("https://github.com/%s", name),
+		}
+	}
+
+	imported, err := importReposFn(tmpHome, store, repos)
+	if err != nil {
+		t.Fatalf("importReposFn failed: %v", err)
+	}
+	if len(imported) != len(repos) {
+		t.Fatalf("expected %d imported, got %d", len(repos), len(imported))
+	}
+	if got := atomic.LoadInt32(&peak); got > 2 {
+		t.Fatalf("expected at most 2 concurrent clones, saw %d", got)
+	}
+}
+
+func TestMaxConcurrentImportsFallsBackToDefault(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	if got := maxConcurrentImports(store); got != defaultMaxConcurrentImports {
+		t.Fatalf("unset: expected %d, got %d", defaultMaxConcurrentImports, got)
+	}
+	for _, raw := range []string{"0", "-3", "many"} {
+		if err := store.SetSetting(settingMaxConcurrentImports, raw); err != nil {
+			t.Fatalf("set setting: %v", err)
+		}
+		if got := maxConcurrentImports(store); got != defaultMaxConcurrentImports {
+			t.Fatalf("%q: expected %d, got %d", raw, defaultMaxConcurrentImports, got)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
@@ -83,7 +84,7 @@ func TestHandleDiscoverRepos(t *testing.T) {
 
 	origDiscover := discoverReposFn
 	t.Cleanup(func() { discoverReposFn = origDiscover })
-	discoverReposFn = func() ([]ghcli.Repo, error) {
+	discoverReposFn = func(string) ([]ghcli.Repo, error) {
 		return []ghcli.Repo{
 			{Name: "api", NameWithOwner: "acme/api", URL: "https://github.com/acme/api", IsPrivate: true},
 		}, nil
@@ -105,6 +106,48 @@ func TestHandleDiscoverRepos(t *testing.T) {
 	}
 }
 
+func TestHandleDiscoverReposTargetsHost(t *testing.T) {
+	srv := newTestServer(t)
+
+	origAvail, origHostAuth, origDiscover := isGhAvailableFn, isGhHostAuthedFn, discoverReposFn
+	t.Cleanup(func() {
+		isGhAvailableFn, isGhHostAuthedFn, discoverReposFn = origAvail, origHostAuth, origDiscover
+	})
+	isGhAvailableFn = func() bool { return true }
+	var authHost, discoverHost string
+	isGhHostAuthedFn = func(host string) bool {
+		authHost = host
+		return true
+	}
+	discoverReposFn = func(host string) ([]ghcli.Repo, error) {
+		discoverHost = host
+		return []ghcli.Repo{{Name: "billing", NameWithOwner: "corp/billing", URL: "https://ghe.example.com/corp/billing"}}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/repos/discover?host=GHE.example.com", nil)
+	w := httptest.NewRecorder()
+	srv.handleDiscoverRepos(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	if authHost != "ghe.example.com" || discoverHost != "ghe.example.com" {
+		t.Fatalf("auth/discover host = %q/%q, want ghe.example.com", authHost, discoverHost)
+	}
+
+	isGhHostAuthedFn = func(string) bool { return false }
+	w = httptest.NewRecorder()
+	srv.handleDiscoverRepos(w, httptest.NewRequest(http.MethodPost, "/api/repos/discover?host=ghe.example.com", nil))
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "--hostname ghe.example.com") {
+		t.Fatalf("unauthenticated host: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleDiscoverRepos(w, httptest.NewRequest(http.MethodPost, "/api/repos/discover?host=https://ghe.example.com", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("implausible host: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
 func TestHandleImportReposRequiresSelection(t *testing.T) {
 	srv := newTestServer(t)
 	req := httptest.NewRequest(http.MethodPost, "/api/repos/import", bytes.NewBufferString(`{}`))
@@ -155,7 +198,7 @@ func TestHandleImportRepos(t *testing.T) {
 		importReposFn = origImport
 	})
 
-	discoverReposFn = func() ([]ghcli.Repo, error) {
+	discoverReposFn = func(string) ([]ghcli.Repo, error) {
 		return []ghcli.Repo{
 			{Name: "api", NameWithOwner: "acme/api", URL: "https://github.com/acme/api"},
 		}, nil
@@ -186,6 +229,60 @@ func TestHandleImportRepos(t *testing.T) {
 	}
 }
 
+func TestHandleImportReposDryRun(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	origAuth, origAvail := isGhAuthenticatedFn, isGhAvailableFn
+	origDiscover, origImport := discoverReposFn, importReposFn
+	t.Cleanup(func() {
+		isGhAuthenticatedFn, isGhAvailableFn = origAuth, origAvail
+		discoverReposFn, importReposFn = origDiscover, origImport
+	})
+	isGhAuthenticatedFn = func() bool { return true }
+	isGhAvailableFn = func() bool { return true }
+	discoverReposFn = func(string) ([]ghcli.Repo, error) {
+		big := ghcli.Repo{Name: "monorepo", NameWithOwner: "acme/monorepo", URL: "https://github.com/acme/monorepo", IsPrivate: true, DiskUsage: 4_200_000}
+		big.DefaultBranchRef.Name = "trunk"
+		return []ghcli.Repo{
+			{Name: "api", NameWithOwner: "acme/api", URL: "https://github.com/acme/api"},
+			big,
+		}, nil
+	}
+	importReposFn = func(string, *state.Store, []ghcli.Repo) ([]string, error) {
+		t.Fatal("dry run must not clone")
+		return nil, nil
+	}
+
+	body := bytes.NewBufferString(`{"repos":["acme/monorepo","acme/api","acme/apj","not-a-repo"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/repos/import?dry_run=1", body)
+	w := httptest.NewRecorder()
+	srv.handleImportRepos(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var resp importDryRunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode dry run response: %v", err)
+	}
+	if !resp.DryRun || len(resp.Repos) != 4 {
+		t.Fatalf("unexpected dry run response: %+v", resp)
+	}
+	if got := resp.Repos[0]; !got.Accessible || got.DefaultBranch != "trunk" || !got.Private || got.DiskUsageKB != 4_200_000 || got.AlreadyManaged {
+		t.Errorf("monorepo = %+v", got)
+	}
+	if got := resp.Repos[1]; !got.Accessible || !got.AlreadyManaged {
+		t.Errorf("api = %+v, want accessible and already managed", got)
+	}
+	if got := resp.Repos[2]; got.Accessible || !strings.Contains(got.Error, "not accessible") {
+		t.Errorf("typo = %+v", got)
+	}
+	if got := resp.Repos[3]; got.Accessible || !strings.Contains(got.Error, "invalid repo name") {
+		t.Errorf("bad name = %+v", got)
+	}
+}
+
 func TestSplitRepoFullNameValidation(t *testing.T) {
 	owner, name, err := splitRepoFullName("acme/api")
 	if err != nil {
@@ -333,3 +430,157 @@ func runGit(t *testing.T, dir string, args ...string) string {
 	}
 	return strings.TrimSpace(string(out))
 }
+
+func TestSetRepoDefaults(t *testing.T) {
+	srv := newTestServer(t)
+	srv.skipToolCheck = true
+	seedSessionFixture(t, srv)
+
+	body := bytes.NewBufferString(`{"default_tool":"codex","default_model":"o3"}`)
+	req := httptest.NewRequest(http.MethodPut, "/api/repos/acme/api/defaults", body)
+	w := httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	var repo state.Repo
+	if err := json.Unmarshal(w.Body.Bytes(), &repo); err != nil {
+		t.Fatalf("decode repo: %v", err)
+	}
+	if repo.DefaultTool != "codex" || repo.DefaultModel != "o3" {
+		t.Fatalf("unexpected repo defaults: %+v", repo)
+	}
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.handleRepoDetail(w, httptest.NewRequest(http.MethodPut, "/api/repos/acme/api/defaults", bytes.NewBufferString(body)))
+		return w
+	}
+	if w := put(`{"default_tool":"codex","worktree_root":"/mnt/scratch"}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"worktree_root":"/mnt/scratch"`) {
+		t.Fatalf("set worktree_root: %d %s", w.Code, w.Body.String())
+	}
+	// Omitting worktree_root keeps it
+	if w := put(`{"default_tool":"codex"}`); !strings.Contains(w.Body.String(), `"worktree_root":"/mnt/scratch"`) {
+		t.Fatalf("worktree_root cleared by a request without it: %s", w.Body.String())
+	}
+	if w := put(`{"worktree_root":"relative/dir"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("relative worktree_root status = %d, want 400", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/repos/acme/missing/defaults", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	srv.handleRepoDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown repo, got %d", w.Code)
+	}
+}
+
+func TestDeleteRepo(t *testing.T) {
+	fogHome := t.TempDir()
+	t.Setenv("FOG_HOME", fogHome)
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	repoDir := filepath.Join(fogHome, "repos", "acme", "api")
+	for _, dir := range []string{"base", "worktrees"} {
+		if err := os.MkdirAll(filepath.Join(repoDir, dir), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	// The clone keeps a deleted session's branch, never merged or pushed.
+	src, defaultBranch := initTestGitRepoWithFeatureBranch(t)
+	runGit(t, src, "checkout", "-b", "fog/unpushed")
+	if err := os.WriteFile(filepath.Join(src, "work.txt"), []byte("work\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGit(t, src, "add", "work.txt")
+	runGit(t, src, "commit", "-m", "unpushed work")
+	runGit(t, src, "checkout", defaultBranch)
+	runGit(t, fogHome, "clone", "--bare", src, filepath.Join(repoDir, "repo.git"))
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		Owner:            "acme",
+		Repo:             "api",
+		BarePath:         filepath.Join(repoDir, "repo.git"),
+		BaseWorktreePath: filepath.Join(repoDir, "base"),
+		DefaultBranch:    defaultBranch,
+	}); err != nil {
+		t.Fatalf("upsert repo: %v", err)
+	}
+
+	del := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/repos?"+query, nil)
+		w := httptest.NewRecorder()
+		srv.handleRepos(w, req)
+		return w
+	}
+
+	if w := del(""); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing name: got %d, want 400", w.Code)
+	}
+	if w := del("name=acme/missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown repo: got %d, want 404", w.Code)
+	}
+
+	w := del("name=acme/api&purge=true")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("repo with a running session: got %d, want 409 (%s)", w.Code, w.Body.String())
+	}
+	var conflict repoInUseResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &conflict); err != nil {
+		t.Fatalf("decode 409 body: %v", err)
+	}
+	if len(conflict.SessionIDs) != 1 || conflict.SessionIDs[0] != "session-1" {
+		t.Fatalf("blocking sessions = %v, want [session-1]", conflict.SessionIDs)
+	}
+	if _, err := os.Stat(repoDir); err != nil {
+		t.Fatalf("a refused delete touched the repo dir: %v", err)
+	}
+
+	// A finished session still blocks: its history goes only when it is
+	// deleted itself.
+	if err := srv.stateStore.UpdateSessionStatus("session-1", "COMPLETED"); err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	if w := del("name=acme/api"); w.Code != http.StatusConflict {
+		t.Fatalf("repo with a finished session: got %d, want 409 (%s)", w.Code, w.Body.String())
+	}
+	if err := srv.stateStore.DeleteSession("session-1"); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+
+	w = del("name=acme/api&purge=true")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("purge with unmerged branches: got %d, want 409 (%s)", w.Code, w.Body.String())
+	}
+	var unmerged repoUnmergedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &unmerged); err != nil {
+		t.Fatalf("decode 409 body: %v", err)
+	}
+	if !slices.Contains(unmerged.Branches, "fog/unpushed") {
+		t.Fatalf("unmerged branches = %v, want fog/unpushed", unmerged.Branches)
+	}
+	if _, found, _ := srv.stateStore.GetRepoByName("acme/api"); !found {
+		t.Fatal("a refused purge unmanaged the repo")
+	}
+
+	w = del("name=acme/api&purge=true&force=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: got %d, want 200 (%s)", w.Code, w.Body.String())
+	}
+	var resp deleteRepoResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Purged) != 1 || resp.Purged[0] != repoDir {
+		t.Fatalf("purged = %v, want [%s]", resp.Purged, repoDir)
+	}
+	if _, err := os.Stat(filepath.Join(fogHome, "repos", "acme")); !os.IsNotExist(err) {
+		t.Fatalf("owner dir still present: %v", err)
+	}
+	if _, found, _ := srv.stateStore.GetRepoByName("acme/api"); found {
+		t.Fatal("repo still managed after delete")
+	}
+}
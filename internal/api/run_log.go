@@ -0,0 +1,45 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+)
+
+// getRunLog serves GET /api/sessions/{id}/runs/{runID}/log: the run's full AI
+// streaming output, written while run_log_files was on. Range requests are
+// honoured, so a client can tail a long log or resume a download.
+func (s *Server) getRunLog(w http.ResponseWriter, r *http.Request, sessionID, runID string) {
+	run, found, err := s.stateStore.GetRun(runID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found || run.SessionID != sessionID {
+		http.Error(w, "run not found in session", http.StatusNotFound)
+		return
+	}
+
+	path := s.runner.RunLogPath(run.ID)
+	if path == "" {
+		http.Error(w, "run log not found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "run log not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(w, r, run.ID+".log", info.ModTime(), f)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestGetRunLogServesRangesAndIsRemovedWithTheRun(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	dir := t.TempDir()
+	srv.runner.SetRunLogDir(dir)
+	logPath := filepath.Join(dir, "run-1.log")
+	if err := os.WriteFile(logPath, []byte("0123456789"), 0o600); err != nil {
+		t.Fatalf("write run log: %v", err)
+	}
+
+	get := func(path, rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w
+	}
+	if w := get("/api/sessions/session-1/runs/run-1/log", ""); w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("full log: %d %q", w.Code, w.Body.String())
+	}
+	if w := get("/api/sessions/session-1/runs/run-1/log", "bytes=2-4"); w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Fatalf("ranged log: %d %q", w.Code, w.Body.String())
+	}
+	if w := get("/api/sessions/session-2/runs/run-1/log", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("log through another session: got %d, want 404", w.Code)
+	}
+
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateRun(state.Run{
+		ID: "run-2", SessionID: "session-1", Prompt: "again",
+		WorktreePath: "/tmp/acme-api/worktree-run-2", State: "COMPLETED",
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if w := get("/api/sessions/session-1/runs/run-2/log", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("run without a log: got %d, want 404", w.Code)
+	}
+	if err := srv.stateStore.CompleteRun("run-1", "FAILED", "", "", "boom"); err != nil {
+		t.Fatalf("complete run: %v", err)
+	}
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1/runs/run-1", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete run: got %d, want 204", w.Code)
+	}
+	if _, err := os.Stat(logPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("run log left after deleting the run: %v", err)
+	}
+}
@@ -1,15 +1,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/branchname"
 	"github.com/darkLord19/foglet/internal/ghcli"
 	"github.com/darkLord19/foglet/internal/git"
 	"github.com/darkLord19/foglet/internal/runner"
@@ -22,6 +28,13 @@ type Server struct {
 	stateStore    *state.Store
 	port          int
 	skipToolCheck bool // for testing: bypass isToolAvailable
+	// ready gates /ready. It starts false and is flipped by the daemon once
+	// startup finishes, then back to false while shutting down.
+	ready atomic.Bool
+	// ghCache holds the last gh install/auth check.
+	ghCache ghStatusCache
+	// diskCache holds recently computed worktree sizes.
+	diskCache diskUsageCache
 }
 
 // New creates a new API server
@@ -37,21 +50,31 @@ func New(runner *runner.Runner, stateStore *state.Store, port int) *Server {
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/sessions", s.handleSessions)
 	mux.HandleFunc("/api/sessions/", s.handleSessionDetail)
+	mux.HandleFunc("/api/sessions/preview", s.handleSessionPreview)
 	mux.HandleFunc("/api/tasks", s.handleTasks)
 	mux.HandleFunc("/api/tasks/trash", s.handleTasksTrash)
 	mux.HandleFunc("/api/tasks/", s.handleTaskDetail)
 	mux.HandleFunc("/api/tracker", s.handleTracker)
 	mux.HandleFunc("/api/tracker/sync", s.handleTrackerSync)
 	mux.HandleFunc("/api/repos", s.handleRepos)
+	mux.HandleFunc("/api/repos/", s.handleRepoDetail)
 	mux.HandleFunc("/api/repos/branches", s.handleListBranches)
 	mux.HandleFunc("/api/repos/discover", s.handleDiscoverRepos)
 	mux.HandleFunc("/api/repos/import", s.handleImportRepos)
 	mux.HandleFunc("/api/settings", s.handleSettings)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/runner/stats", s.handleRunnerStats)
+	mux.HandleFunc("/api/queue/drain", s.handleQueueDrain)
+	mux.HandleFunc("/api/tools", s.handleTools)
+	mux.HandleFunc("/api/events", s.handleSessionEvents)
+	mux.HandleFunc("/api/events/stream", s.handleEventsStream)
 	mux.HandleFunc("/api/gh/status", s.handleGhStatus)
 	mux.HandleFunc("/api/cloud", s.handleCloud)
 	mux.HandleFunc("/api/cloud/pair", s.handleCloudPair)
 	mux.HandleFunc("/api/cloud/unpair", s.handleCloudUnpair)
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/ready", s.handleReady)
+	mux.HandleFunc("/health/ready", s.handleHealthReady)
 }
 
 // Start starts the HTTP server
@@ -73,19 +96,88 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// SetReady marks the server as accepting traffic (or not) for /ready.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// handleReady reports whether the daemon can serve traffic. Unlike /health,
+// which only says the process is alive, it fails while starting up, while
+// draining for shutdown, and when the database is unreachable.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	reason := ""
+	switch {
+	case !s.ready.Load():
+		reason = "not accepting traffic"
+	case s.runner == nil:
+		reason = "runner not initialized"
+	case s.stateStore == nil:
+		reason = "state store not configured"
+	default:
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := s.stateStore.Ping(ctx); err != nil {
+			reason = "database unreachable: " + err.Error()
+		}
+	}
+	if reason != "" {
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "unavailable",
+			"reason": reason,
+		})
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{
+		"status": "ready",
+		"time":   time.Now().Format(time.RFC3339),
+	})
+}
+
 type SettingsResponse struct {
-	DefaultTool        string            `json:"default_tool,omitempty"`
-	DefaultModel       string            `json:"default_model,omitempty"`
-	DefaultModels      map[string]string `json:"default_models"`
-	DefaultAutoPR      bool              `json:"default_autopr"`
-	DefaultNotify      bool              `json:"default_notify"`
-	KeepAwake          bool              `json:"keep_awake"`
-	BranchPrefix       string            `json:"branch_prefix,omitempty"`
-	TrashRetentionDays int               `json:"trash_retention_days"`
-	GhInstalled        bool              `json:"gh_installed"`
-	GhAuthenticated    bool              `json:"gh_authenticated"`
-	OnboardingRequired bool              `json:"onboarding_required"`
-	AvailableTools     []string          `json:"available_tools"`
+	DefaultTool          string            `json:"default_tool,omitempty"`
+	DefaultModel         string            `json:"default_model,omitempty"`
+	DefaultModels        map[string]string `json:"default_models"`
+	DefaultAutoPR        bool              `json:"default_autopr"`
+	DefaultNotify        bool              `json:"default_notify"`
+	KeepAwake            bool              `json:"keep_awake"`
+	DedupePrompts        bool              `json:"dedupe_prompts"`
+	RunLogFiles          bool              `json:"run_log_files"`
+	FollowupDirtyPolicy  string            `json:"followup_dirty_policy,omitempty"`
+	ValidateFailPolicy   string            `json:"validate_fail_policy"`
+	BranchPrefix         string            `json:"branch_prefix,omitempty"`
+	BranchNameRegex      string            `json:"branch_name_regex,omitempty"`
+	DefaultUser          string            `json:"default_user,omitempty"`
+	TrashRetentionDays   int               `json:"trash_retention_days"`
+	MaxSessionsPerRepo   int               `json:"max_sessions_per_repo"`
+	SessionRetention     string            `json:"session_retention_action"`
+	WorktreeTTLDays      int               `json:"worktree_ttl_days"`
+	GhPath               string            `json:"gh_path,omitempty"`
+	ScratchDir           string            `json:"scratch_dir,omitempty"`
+	DefaultWorktreeRoot  string            `json:"default_worktree_root,omitempty"`
+	DefaultBaseBranch    string            `json:"default_base_branch,omitempty"`
+	ToolExecWrapper      string            `json:"tool_exec_wrapper,omitempty"`
+	CommitTemplate       string            `json:"commit_template,omitempty"`
+	CommitSign           string            `json:"commit_sign"`
+	CommitSigningKey     string            `json:"commit_signing_key,omitempty"`
+	MaxQueuedRuns        int               `json:"max_queued_runs"`
+	MaxConcurrentRuns    int               `json:"max_concurrent_runs"`
+	RunRetryCount        int               `json:"run_retry_count"`
+	CommitDiffBudget     int               `json:"commit_diff_budget"`
+	RunTimeoutSeconds    int               `json:"run_timeout_seconds"`
+	MaxConcurrentImports int               `json:"max_concurrent_imports"`
+	GhStatusTTLSeconds   int               `json:"gh_status_ttl_seconds"`
+	GhInstalled          bool              `json:"gh_installed"`
+	GhAuthenticated      bool              `json:"gh_authenticated"`
+	OnboardingRequired   bool              `json:"onboarding_required"`
+	AvailableTools       []string          `json:"available_tools"`
+	// ToolArgs maps a tool to the extra CLI arguments it is run with.
+	ToolArgs map[string][]string `json:"tool_args,omitempty"`
+	// ModelFallbacks maps a tool to the models tried, in order, when its
+	// model is unavailable.
+	ModelFallbacks map[string][]string `json:"model_fallbacks,omitempty"`
+	// CommandAllowlist lists the programs setup and validation commands may
+	// start with. Empty allows any command.
+	CommandAllowlist []string `json:"command_allowlist,omitempty"`
 }
 
 type UpdateSettingsRequest struct {
@@ -96,9 +188,92 @@ type UpdateSettingsRequest struct {
 	DefaultNotify *bool             `json:"default_notify"`
 	KeepAwake     *bool             `json:"keep_awake,omitempty"`
 	BranchPrefix  *string           `json:"branch_prefix"`
+	// BranchNameRegex is a pattern every session branch name must also
+	// match. Empty clears it.
+	BranchNameRegex *string `json:"branch_name_regex"`
+	// DefaultUser fills {user} in branch_prefix when a request names no
+	// user. Empty clears it.
+	DefaultUser *string `json:"default_user"`
+	// DedupePrompts rejects a follow-up that repeats the prompt of a run the
+	// session is still running.
+	DedupePrompts *bool `json:"dedupe_prompts,omitempty"`
+	// RunLogFiles tees each run's full AI streaming output to
+	// $FOG_HOME/logs/<run_id>.log.
+	RunLogFiles *bool `json:"run_log_files,omitempty"`
+	// FollowupDirtyPolicy is fail, commit, reset, stash or include: what a
+	// follow-up does with uncommitted changes in the session worktree. Empty
+	// turns the check off.
+	FollowupDirtyPolicy *string `json:"followup_dirty_policy"`
+	// ValidateFailPolicy is keep or discard: what happens to the AI's
+	// uncommitted changes when validation fails. Empty means keep.
+	ValidateFailPolicy *string `json:"validate_fail_policy"`
 	// TrashRetentionDays is how long trashed tasks stay recoverable. Must be
 	// at least 1; there is no "keep forever" — trash is temporary by design.
 	TrashRetentionDays *int `json:"trash_retention_days,omitempty"`
+	// MaxSessionsPerRepo caps a repo's unarchived sessions; the oldest
+	// finished ones beyond it are retired hourly. Zero disables the cap.
+	MaxSessionsPerRepo *int `json:"max_sessions_per_repo,omitempty"`
+	// SessionRetention is archive or delete: what retiring a session means.
+	SessionRetention *string `json:"session_retention_action,omitempty"`
+	// WorktreeTTLDays is how long a finished session keeps its worktree
+	// before the hourly janitor removes it, keeping the branch. Zero keeps
+	// worktrees.
+	WorktreeTTLDays *int `json:"worktree_ttl_days,omitempty"`
+	// GhPath pins the gh binary Fog invokes. Empty clears it, falling back to
+	// PATH lookup.
+	GhPath *string `json:"gh_path,omitempty"`
+	// ScratchDir is where scratch AI calls (commit messages, fork summaries)
+	// run. Empty clears it, falling back to the system temp dir.
+	ScratchDir *string `json:"scratch_dir"`
+	// DefaultWorktreeRoot is where session worktrees are created, as
+	// <root>/<repo>/<worktree>, unless a repo sets its own. Empty clears it,
+	// placing them next to the repo.
+	DefaultWorktreeRoot *string `json:"default_worktree_root"`
+	// DefaultBaseBranch is the base branch for sessions on repos with no
+	// default branch of their own. Empty clears it, falling back to "main".
+	DefaultBaseBranch *string `json:"default_base_branch"`
+	// ToolExecWrapper is a template the AI tool runs inside, such as
+	// "nice -n 10 {cmd}". Empty clears it.
+	ToolExecWrapper *string `json:"tool_exec_wrapper"`
+	// ToolArgs sets extra CLI arguments per AI tool, merged into the stored
+	// ones tool by tool. An empty list clears a tool's arguments.
+	ToolArgs map[string][]string `json:"tool_args"`
+	// ModelFallbacks sets the ordered fallback models per AI tool, merged into
+	// the stored ones tool by tool. An empty list clears a tool's fallbacks.
+	ModelFallbacks map[string][]string `json:"model_fallbacks"`
+	// CommandAllowlist replaces the programs setup and validation commands
+	// may start with. An empty list clears it, allowing any command.
+	CommandAllowlist []string `json:"command_allowlist"`
+	// CommitTemplate is the commit message used when a run was given none,
+	// with {branch}, {prompt}, {session_id} and {date} filled in. Empty
+	// clears it.
+	CommitTemplate *string `json:"commit_template"`
+	// CommitSign is none, gpg or ssh: how Fog signs the commits it makes.
+	// Empty means none.
+	CommitSign *string `json:"commit_sign"`
+	// CommitSigningKey is the GPG key ID or ssh key file commits are signed
+	// with. Empty clears it.
+	CommitSigningKey *string `json:"commit_signing_key"`
+	// MaxQueuedRuns caps background runs; async requests beyond it get 503.
+	// Zero disables the cap.
+	MaxQueuedRuns *int `json:"max_queued_runs,omitempty"`
+	// MaxConcurrentRuns caps runs in their AI phase; later ones wait in
+	// QUEUED. Zero disables the cap.
+	MaxConcurrentRuns *int `json:"max_concurrent_runs,omitempty"`
+	// RunRetryCount is how many times a transient AI failure is retried
+	// within a run, from 0 to runner.MaxRunRetryCount.
+	RunRetryCount *int `json:"run_retry_count,omitempty"`
+	// CommitDiffBudget is how many characters of the staged diff commit
+	// message generation sees, from 0 to runner.MaxCommitDiffBudget.
+	CommitDiffBudget *int `json:"commit_diff_budget,omitempty"`
+	// RunTimeoutSeconds bounds how long a run may take; 0 means no limit.
+	RunTimeoutSeconds *int `json:"run_timeout_seconds,omitempty"`
+	// MaxConcurrentImports caps parallel clones during a repo import. Must be
+	// at least 1.
+	MaxConcurrentImports *int `json:"max_concurrent_imports,omitempty"`
+	// GhStatusTTLSeconds is how long the gh install/auth check is cached.
+	// Zero checks on every request.
+	GhStatusTTLSeconds *int `json:"gh_status_ttl_seconds,omitempty"`
 }
 
 func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
@@ -140,17 +315,72 @@ func (s *Server) getSettings(w http.ResponseWriter) {
 	if prefix, found, err := s.stateStore.GetSetting("branch_prefix"); err == nil && found {
 		resp.BranchPrefix = prefix
 	}
+	if pattern, found, err := s.stateStore.GetSetting(runner.SettingBranchNameRegex); err == nil && found {
+		resp.BranchNameRegex = pattern
+	}
+	if user, found, err := s.stateStore.GetSetting(runner.SettingDefaultUser); err == nil && found {
+		resp.DefaultUser = user
+	}
 
 	resp.TrashRetentionDays = s.trashRetentionDays()
+	resp.MaxSessionsPerRepo = s.maxSessionsPerRepo()
+	resp.SessionRetention = s.sessionRetentionAction()
+	resp.WorktreeTTLDays = int(s.runner.WorktreeTTL() / (24 * time.Hour))
 
-	resp.GhInstalled = ghcli.IsGhAvailable()
-	if resp.GhInstalled {
-		resp.GhAuthenticated = ghcli.IsGhAuthenticated()
+	if ghPath, found, err := s.stateStore.GetSetting(ghcli.SettingGhPath); err == nil && found {
+		resp.GhPath = ghPath
 	}
+	if scratchDir, found, err := s.stateStore.GetSetting(runner.SettingScratchDir); err == nil && found {
+		resp.ScratchDir = scratchDir
+	}
+	if root, found, err := s.stateStore.GetSetting(runner.SettingDefaultWorktreeRoot); err == nil && found {
+		resp.DefaultWorktreeRoot = root
+	}
+	if branch, found, err := s.stateStore.GetSetting(runner.SettingDefaultBaseBranch); err == nil && found {
+		resp.DefaultBaseBranch = branch
+	}
+	if wrapper, found, err := s.stateStore.GetSetting(runner.SettingToolExecWrapper); err == nil && found {
+		resp.ToolExecWrapper = wrapper
+	}
+	if toolArgs, err := s.runner.ToolArgs(); err == nil {
+		resp.ToolArgs = toolArgs
+	}
+	if fallbacks, err := s.runner.ModelFallbacks(); err == nil {
+		resp.ModelFallbacks = fallbacks
+	}
+	if allowlist, err := s.runner.CommandAllowlist(); err == nil {
+		resp.CommandAllowlist = allowlist
+	}
+	resp.CommitTemplate = s.runner.CommitTemplate()
+	resp.CommitSign, resp.CommitSigningKey = s.runner.CommitSign()
+	resp.MaxQueuedRuns = s.runner.MaxQueuedRuns()
+	resp.MaxConcurrentRuns = s.runner.MaxConcurrentRuns()
+	resp.RunRetryCount = s.runner.RunRetryCount()
+	resp.CommitDiffBudget = s.runner.CommitDiffBudget()
+	resp.RunTimeoutSeconds = int(s.runner.RunTimeout() / time.Second)
+	resp.MaxConcurrentImports = maxConcurrentImports(s.stateStore)
+
+	resp.GhStatusTTLSeconds = int(ghStatusTTL(s.stateStore) / time.Second)
+	gh := s.ghStatus()
+	resp.GhInstalled = gh.Installed
+	resp.GhAuthenticated = gh.Authenticated
 
 	if keepAwake, found, err := s.stateStore.GetSetting("keep_awake"); err == nil && found {
 		resp.KeepAwake = keepAwake == "true"
 	}
+	if dedupe, found, err := s.stateStore.GetSetting(runner.SettingDedupePrompts); err == nil && found {
+		resp.DedupePrompts = dedupe == "true"
+	}
+	if runLogs, found, err := s.stateStore.GetSetting(runner.SettingRunLogFiles); err == nil && found {
+		resp.RunLogFiles = runLogs == "true"
+	}
+	if policy, found, err := s.stateStore.GetSetting(runner.SettingFollowupDirtyPolicy); err == nil && found {
+		resp.FollowupDirtyPolicy = policy
+	}
+	resp.ValidateFailPolicy = runner.ValidateFailKeep
+	if policy, found, err := s.stateStore.GetSetting(runner.SettingValidateFailPolicy); err == nil && found && strings.TrimSpace(policy) == runner.ValidateFailDiscard {
+		resp.ValidateFailPolicy = runner.ValidateFailDiscard
+	}
 
 	resp.OnboardingRequired = !resp.GhAuthenticated || strings.TrimSpace(resp.DefaultTool) == ""
 
@@ -227,18 +457,87 @@ func (s *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if req.DedupePrompts != nil {
+		val := "false"
+		if *req.DedupePrompts {
+			val = "true"
+		}
+		if err := s.stateStore.SetSetting(runner.SettingDedupePrompts, val); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.RunLogFiles != nil {
+		val := "false"
+		if *req.RunLogFiles {
+			val = "true"
+		}
+		if err := s.stateStore.SetSetting(runner.SettingRunLogFiles, val); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.FollowupDirtyPolicy != nil {
+		policy := strings.TrimSpace(*req.FollowupDirtyPolicy)
+		if !runner.ValidDirtyPolicy(policy) {
+			http.Error(w, "followup_dirty_policy must be fail, commit, reset, stash or include", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingFollowupDirtyPolicy, policy); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.ValidateFailPolicy != nil {
+		policy := strings.TrimSpace(*req.ValidateFailPolicy)
+		if !runner.ValidValidateFailPolicy(policy) {
+			http.Error(w, "validate_fail_policy must be keep or discard", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingValidateFailPolicy, policy); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	if req.BranchPrefix != nil {
 		prefix := strings.TrimSpace(*req.BranchPrefix)
 		if prefix == "" {
 			http.Error(w, "branch_prefix cannot be empty", http.StatusBadRequest)
 			return
 		}
+		if err := branchname.ValidatePrefix(prefix); err != nil {
+			http.Error(w, "branch_prefix: "+err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err := s.stateStore.SetSetting("branch_prefix", prefix); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	if req.BranchNameRegex != nil {
+		pattern := strings.TrimSpace(*req.BranchNameRegex)
+		if _, err := regexp.Compile(pattern); err != nil {
+			http.Error(w, fmt.Sprintf("branch_name_regex is not a valid pattern: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingBranchNameRegex, pattern); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.DefaultUser != nil {
+		if err := s.stateStore.SetSetting(runner.SettingDefaultUser, strings.TrimSpace(*req.DefaultUser)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	if req.TrashRetentionDays != nil {
 		if *req.TrashRetentionDays < 1 {
 			http.Error(w, "trash_retention_days must be at least 1", http.StatusBadRequest)
@@ -250,9 +549,298 @@ func (s *Server) updateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if req.MaxSessionsPerRepo != nil {
+		if *req.MaxSessionsPerRepo < 0 {
+			http.Error(w, "max_sessions_per_repo cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(settingMaxSessionsPerRepo, strconv.Itoa(*req.MaxSessionsPerRepo)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.WorktreeTTLDays != nil {
+		if *req.WorktreeTTLDays < 0 {
+			http.Error(w, "worktree_ttl_days cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingWorktreeTTLDays, strconv.Itoa(*req.WorktreeTTLDays)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.SessionRetention != nil {
+		action := strings.TrimSpace(*req.SessionRetention)
+		if action != sessionRetentionArchive && action != sessionRetentionDelete {
+			http.Error(w, "session_retention_action must be archive or delete", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(settingSessionRetentionAction, action); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.GhPath != nil {
+		ghPath := strings.TrimSpace(*req.GhPath)
+		if ghPath != "" && !filepath.IsAbs(ghPath) {
+			http.Error(w, "gh_path must be an absolute path", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(ghcli.SettingGhPath, ghPath); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.invalidateGhStatus()
+	}
+
+	if req.ScratchDir != nil {
+		scratchDir := strings.TrimSpace(*req.ScratchDir)
+		if scratchDir != "" && !filepath.IsAbs(scratchDir) {
+			http.Error(w, "scratch_dir must be an absolute path", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingScratchDir, scratchDir); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.DefaultWorktreeRoot != nil {
+		root := strings.TrimSpace(*req.DefaultWorktreeRoot)
+		if err := runner.ValidateWorktreeRoot(root); err != nil {
+			http.Error(w, "default_worktree_root: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingDefaultWorktreeRoot, root); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.DefaultBaseBranch != nil {
+		branch := strings.TrimSpace(*req.DefaultBaseBranch)
+		if err := runner.ValidateDefaultBaseBranch(branch); err != nil {
+			http.Error(w, "default_base_branch: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingDefaultBaseBranch, branch); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.ToolExecWrapper != nil {
+		wrapper := strings.Join(strings.Fields(*req.ToolExecWrapper), " ")
+		if _, err := ai.ParseExecWrapper(wrapper); err != nil {
+			http.Error(w, "tool_exec_wrapper: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingToolExecWrapper, wrapper); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.ToolArgs != nil {
+		updates, err := runner.NormalizeToolArgs(req.ToolArgs)
+		if err != nil {
+			http.Error(w, "tool_args: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// A stored value that no longer parses is replaced rather than
+		// blocking the update that fixes it.
+		stored, _ := s.runner.ToolArgs()
+		raw, err := mergePerTool(stored, req.ToolArgs, updates)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingToolArgs, raw); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.ModelFallbacks != nil {
+		updates, err := runner.NormalizeModelFallbacks(req.ModelFallbacks)
+		if err != nil {
+			http.Error(w, "model_fallbacks: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		stored, _ := s.runner.ModelFallbacks()
+		raw, err := mergePerTool(stored, req.ModelFallbacks, updates)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingModelFallbacks, raw); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.CommandAllowlist != nil {
+		allowlist, err := runner.NormalizeCommandAllowlist(req.CommandAllowlist)
+		if err != nil {
+			http.Error(w, "command_allowlist: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		raw := ""
+		if len(allowlist) > 0 {
+			encoded, err := json.Marshal(allowlist)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			raw = string(encoded)
+		}
+		if err := s.stateStore.SetSetting(runner.SettingCommandAllowlist, raw); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.CommitTemplate != nil {
+		template := strings.TrimSpace(*req.CommitTemplate)
+		if err := runner.ValidateCommitTemplate(template); err != nil {
+			http.Error(w, "commit_template: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingCommitTemplate, template); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.CommitSign != nil {
+		mode := strings.TrimSpace(*req.CommitSign)
+		if err := runner.ValidateCommitSign(mode); err != nil {
+			http.Error(w, "commit_sign: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingCommitSign, mode); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.CommitSigningKey != nil {
+		if err := s.stateStore.SetSetting(runner.SettingCommitSigningKey, strings.TrimSpace(*req.CommitSigningKey)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.MaxQueuedRuns != nil {
+		if *req.MaxQueuedRuns < 0 {
+			http.Error(w, "max_queued_runs cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingMaxQueuedRuns, strconv.Itoa(*req.MaxQueuedRuns)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.MaxConcurrentRuns != nil {
+		if *req.MaxConcurrentRuns < 0 {
+			http.Error(w, "max_concurrent_runs cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingMaxConcurrentRuns, strconv.Itoa(*req.MaxConcurrentRuns)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.RunRetryCount != nil {
+		if *req.RunRetryCount < 0 || *req.RunRetryCount > runner.MaxRunRetryCount {
+			http.Error(w, fmt.Sprintf("run_retry_count must be between 0 and %d", runner.MaxRunRetryCount), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingRunRetryCount, strconv.Itoa(*req.RunRetryCount)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.CommitDiffBudget != nil {
+		if *req.CommitDiffBudget < 0 || *req.CommitDiffBudget > runner.MaxCommitDiffBudget {
+			http.Error(w, fmt.Sprintf("commit_diff_budget must be between 0 and %d", runner.MaxCommitDiffBudget), http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingCommitDiffBudget, strconv.Itoa(*req.CommitDiffBudget)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.RunTimeoutSeconds != nil {
+		if *req.RunTimeoutSeconds < 0 {
+			http.Error(w, "run_timeout_seconds cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(runner.SettingRunTimeoutSeconds, strconv.Itoa(*req.RunTimeoutSeconds)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.MaxConcurrentImports != nil {
+		if *req.MaxConcurrentImports < 1 {
+			http.Error(w, "max_concurrent_imports must be at least 1", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(settingMaxConcurrentImports, strconv.Itoa(*req.MaxConcurrentImports)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.GhStatusTTLSeconds != nil {
+		if *req.GhStatusTTLSeconds < 0 {
+			http.Error(w, "gh_status_ttl_seconds cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateStore.SetSetting(settingGhStatusTTLSeconds, strconv.Itoa(*req.GhStatusTTLSeconds)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	s.getSettings(w)
 }
 
+// mergePerTool replaces the stored per-tool lists of every tool named in
+// requested with its normalized updates, leaving other tools alone, and
+// returns the result encoded for storage. Tools whose update is empty are
+// cleared; nothing left encodes as "".
+func mergePerTool(stored, requested, updates map[string][]string) (string, error) {
+	merged := make(map[string][]string, len(stored))
+	for name, list := range stored {
+		merged[name] = list
+	}
+	for name := range requested {
+		// Normalization already rejected unknown tools.
+		tool, _ := ai.GetTool(name)
+		delete(merged, tool.Name())
+	}
+	for name, list := range updates {
+		merged[name] = list
+	}
+	if len(merged) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 func detectAvailableTools() []string {
 	names := ai.AvailableToolNames()
 	out := make([]string, 0, len(names))
@@ -315,8 +903,8 @@ func WithCORS(next http.Handler) http.Handler {
 		allowed := allowedCORSOrigin(origin)
 		if allowed != "" {
 			w.Header().Set("Access-Control-Allow-Origin", allowed)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+clientHeader)
 			w.Header().Set("Vary", "Origin")
 		}
 
@@ -341,6 +929,57 @@ func WithBodyLimit(next http.Handler) http.Handler {
 	})
 }
 
+// WithRequestLog logs each request's method, path, status and duration at
+// debug level.
+func WithRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slog.Default().Enabled(r.Context(), slog.LevelDebug) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		slog.Debug("http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(started).Round(time.Millisecond),
+		)
+	})
+}
+
+// statusRecorder remembers the status a handler wrote. It passes Flush
+// through so streaming endpoints keep working behind WithRequestLog.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // handleListBranches lists branches for a repo
 func (s *Server) handleListBranches(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -393,16 +1032,13 @@ func (s *Server) handleGhStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	gh := s.ghStatus()
 	status := map[string]any{
-		"installed":     ghcli.IsGhAvailable(),
-		"authenticated": false,
+		"installed":     gh.Installed,
+		"authenticated": gh.Authenticated,
 		"os":            runtimeOS(),
 	}
 
-	if status["installed"].(bool) {
-		status["authenticated"] = ghcli.IsGhAuthenticated()
-	}
-
 	s.writeJSON(w, http.StatusOK, status)
 }
 
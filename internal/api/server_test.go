@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/darkLord19/foglet/internal/runner"
@@ -61,6 +63,522 @@ func TestHandleSettingsPut(t *testing.T) {
 	}
 }
 
+func TestHandleSettingsPutGhPath(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"gh_path":"bin/gh"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for relative gh_path: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"gh_path":"/opt/gh/bin/gh"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.GhPath != "/opt/gh/bin/gh" {
+		t.Fatalf("unexpected gh_path: got %q", resp.GhPath)
+	}
+
+	// A request that leaves gh alone does not carry the field at all.
+	if body, _ := json.Marshal(UpdateSettingsRequest{}); strings.Contains(string(body), "gh_path") {
+		t.Fatalf("gh_path encoded in a request without it: %s", body)
+	}
+}
+
+func TestHandleSettingsPutScratchDir(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"scratch_dir":"tmp/fog"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for relative scratch_dir: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"scratch_dir":"/var/tmp/fog"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.ScratchDir != "/var/tmp/fog" {
+		t.Fatalf("unexpected scratch_dir: got %q", resp.ScratchDir)
+	}
+}
+
+func TestHandleSettingsPutDefaultBaseBranch(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"default_base_branch":"bad..branch"}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "default_base_branch:") {
+		t.Fatalf("invalid branch: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w := put(`{"default_base_branch":" develop "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.DefaultBaseBranch != "develop" {
+		t.Fatalf("unexpected default_base_branch: got %q", resp.DefaultBaseBranch)
+	}
+	if got := srv.runner.ResolveBaseBranch("", state.Repo{Name: "acme/api"}); got != "develop" {
+		t.Fatalf("ResolveBaseBranch = %q, want the stored setting", got)
+	}
+}
+
+func TestHandleSettingsPutCommitSign(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"commit_sign":"pgp"}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "commit_sign:") {
+		t.Fatalf("unknown mode: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w := put(`{"commit_sign":"ssh","commit_signing_key":" ~/.ssh/id_ed25519.pub "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.CommitSign != "ssh" || resp.CommitSigningKey != "~/.ssh/id_ed25519.pub" {
+		t.Fatalf("commit signing = %q/%q", resp.CommitSign, resp.CommitSigningKey)
+	}
+
+	w = put(`{"commit_sign":""}`)
+	resp = SettingsResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.CommitSign != "none" {
+		t.Fatalf("cleared commit_sign = %q, want none", resp.CommitSign)
+	}
+}
+
+func TestHandleSettingsPutValidateFailPolicy(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"validate_fail_policy":"stash"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for unknown policy: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"validate_fail_policy":"discard"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.ValidateFailPolicy != "discard" {
+		t.Fatalf("unexpected validate_fail_policy: got %q", resp.ValidateFailPolicy)
+	}
+}
+
+func TestHandleSettingsPutRunRetryCount(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"run_retry_count":-1}`, `{"run_retry_count":6}`} {
+		if w := put(body); w.Code != http.StatusBadRequest {
+			t.Fatalf("unexpected status for %s: got %d want %d", body, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w := put(`{"run_retry_count":3}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.RunRetryCount != 3 {
+		t.Fatalf("unexpected run_retry_count: got %d", resp.RunRetryCount)
+	}
+}
+
+func TestHandleSettingsPutCommitDiffBudget(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"commit_diff_budget":-1}`, `{"commit_diff_budget":200001}`} {
+		if w := put(body); w.Code != http.StatusBadRequest {
+			t.Fatalf("unexpected status for %s: got %d want %d", body, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w := put(`{"commit_diff_budget":4000}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.CommitDiffBudget != 4000 {
+		t.Fatalf("unexpected commit_diff_budget: got %d", resp.CommitDiffBudget)
+	}
+}
+
+func TestHandleSettingsPutRunTimeoutSeconds(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"run_timeout_seconds":-1}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for negative timeout: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"run_timeout_seconds":1800}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.RunTimeoutSeconds != 1800 {
+		t.Fatalf("unexpected run_timeout_seconds: got %d", resp.RunTimeoutSeconds)
+	}
+}
+
+func TestHandleSettingsPutWorktreeTTLDays(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"worktree_ttl_days":-1}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for negative ttl: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"worktree_ttl_days":14}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.WorktreeTTLDays != 14 {
+		t.Fatalf("unexpected worktree_ttl_days: got %d", resp.WorktreeTTLDays)
+	}
+}
+
+func TestHandleSettingsPutToolExecWrapper(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"tool_exec_wrapper":"sh -c '{cmd}'"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for shell syntax: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"tool_exec_wrapper":"  nice -n 10  {cmd} "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.ToolExecWrapper != "nice -n 10 {cmd}" {
+		t.Fatalf("unexpected tool_exec_wrapper: got %q", resp.ToolExecWrapper)
+	}
+}
+
+func TestHandleSettingsPutToolArgsMergesPerTool(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+	toolArgs := func(w *httptest.ResponseRecorder) map[string][]string {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp SettingsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response failed: %v", err)
+		}
+		return resp.ToolArgs
+	}
+
+	if w := put(`{"tool_args":{"claude":["--max-turns","$(reboot)"]}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for shell syntax: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := put(`{"tool_args":{"vim":["--max-turns","20"]}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for an unknown tool: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	got := toolArgs(put(`{"tool_args":{"claude-code":["--max-turns","20"],"cursor":["--verbose"]}}`))
+	if !reflect.DeepEqual(got, map[string][]string{"claude": {"--max-turns", "20"}, "cursor": {"--verbose"}}) {
+		t.Fatalf("unexpected tool_args: %v", got)
+	}
+	got = toolArgs(put(`{"tool_args":{"cursor":[]}}`))
+	if !reflect.DeepEqual(got, map[string][]string{"claude": {"--max-turns", "20"}}) {
+		t.Fatalf("clearing cursor's args left: %v", got)
+	}
+}
+
+func TestHandleSettingsPutModelFallbacks(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"model_fallbacks":{"claude":["--model"]}}`); w.Code != http.StatusBadRequest || !strings.HasPrefix(w.Body.String(), "model_fallbacks: ") {
+		t.Fatalf("flag as model: got %d %q, want a 400", w.Code, w.Body.String())
+	}
+	if w := put(`{"model_fallbacks":{"claude":["opus","haiku"],"cursor":["gpt-5"]}}`); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	w := put(`{"model_fallbacks":{"cursor":[]}}`)
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if !reflect.DeepEqual(resp.ModelFallbacks, map[string][]string{"claude": {"opus", "haiku"}}) {
+		t.Fatalf("model_fallbacks = %v", resp.ModelFallbacks)
+	}
+}
+
+func TestHandleSettingsPutCommitTemplate(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"commit_template":"feat({ticket}): {prompt}"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for unknown placeholder: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"commit_template":" chore({branch}): {prompt} "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.CommitTemplate != "chore({branch}): {prompt}" {
+		t.Fatalf("unexpected commit_template: got %q", resp.CommitTemplate)
+	}
+}
+
+func TestHandleSettingsPutBranchNameRegex(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"branch_name_regex":"(feat"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for malformed pattern: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"branch_name_regex":"^(feat|fix|chore)/"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.BranchNameRegex != "^(feat|fix|chore)/" {
+		t.Fatalf("unexpected branch_name_regex: got %q", resp.BranchNameRegex)
+	}
+}
+
+func TestHandleSettingsPutBranchPrefixTokens(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+
+	if w := put(`{"branch_prefix":"{team}/fog"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for unknown token: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := put(`{"branch_prefix":"{user}/{date}","default_user":" Ada "}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp SettingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.BranchPrefix != "{user}/{date}" || resp.DefaultUser != "Ada" {
+		t.Fatalf("unexpected settings: branch_prefix=%q default_user=%q", resp.BranchPrefix, resp.DefaultUser)
+	}
+}
+
+func TestHandleSettingsPutCommandAllowlist(t *testing.T) {
+	srv := newTestServer(t)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleSettings(w, req)
+		return w
+	}
+	allowlist := func(w *httptest.ResponseRecorder) []string {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp SettingsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response failed: %v", err)
+		}
+		return resp.CommandAllowlist
+	}
+
+	if w := put(`{"command_allowlist":["npm run"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for a multi-word entry: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+	if got := allowlist(put(`{"command_allowlist":["make"," npm","make"]}`)); !reflect.DeepEqual(got, []string{"make", "npm"}) {
+		t.Fatalf("command_allowlist = %v", got)
+	}
+	// Omitting the field leaves it alone; an empty list clears it.
+	if got := allowlist(put(`{"default_tool":"claude"}`)); len(got) != 2 {
+		t.Fatalf("command_allowlist = %v after an unrelated update", got)
+	}
+	if got := allowlist(put(`{"command_allowlist":[]}`)); got != nil {
+		t.Fatalf("command_allowlist = %v, want it cleared", got)
+	}
+}
+
+func TestHandleReady(t *testing.T) {
+	srv := newTestServer(t)
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		srv.handleReady(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return w.Code
+	}
+
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status before startup: got %d want %d", code, http.StatusServiceUnavailable)
+	}
+	srv.SetReady(true)
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("unexpected status when ready: got %d want %d", code, http.StatusOK)
+	}
+	srv.SetReady(false)
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status while draining: got %d want %d", code, http.StatusServiceUnavailable)
+	}
+
+	srv.SetReady(true)
+	_ = srv.stateStore.Close()
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status with closed database: got %d want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
 func newTestServer(t *testing.T) *Server {
 	t.Helper()
 
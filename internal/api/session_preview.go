@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/darkLord19/foglet/internal/runner"
+)
+
+// sessionPreviewResponse is what POST /api/sessions would resolve a request
+// to: every default applied, nothing created.
+type sessionPreviewResponse struct {
+	Repo                 string            `json:"repo"`
+	Tool                 string            `json:"tool"`
+	Model                string            `json:"model,omitempty"`
+	Branch               string            `json:"branch,omitempty"`
+	BaseBranch           string            `json:"base_branch"`
+	AutoPR               bool              `json:"autopr"`
+	SetupCmd             string            `json:"setup_cmd,omitempty"`
+	Validate             bool              `json:"validate"`
+	ValidateCmd          string            `json:"validate_cmd,omitempty"`
+	ValidateSuccessCodes []int             `json:"validate_success_codes,omitempty"`
+	CommitMsg            string            `json:"commit_msg,omitempty"`
+	PRTitle              string            `json:"pr_title,omitempty"`
+	Ephemeral            bool              `json:"ephemeral"`
+	CommitStrategy       string            `json:"commit_strategy"`
+	CommitMsgMode        string            `json:"commit_msg_mode,omitempty"`
+	FocusPaths           []string          `json:"focus_paths,omitempty"`
+	Attachments          []string          `json:"attachments,omitempty"`
+	SelfReview           bool              `json:"self_review"`
+	IssueRef             string            `json:"issue_ref,omitempty"`
+	CloseIssue           bool              `json:"close_issue"`
+	Env                  map[string]string `json:"env,omitempty"`
+	Origin               string            `json:"origin"`
+}
+
+// handleSessionPreview serves POST /api/sessions/preview. It takes the body of
+// POST /api/sessions and answers with the resolved settings, so a client can
+// show which tool, model and branch a session would get before starting it.
+func (s *Server) handleSessionPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	launch, ok := decodeCreateSession(w, r)
+	if !ok {
+		return
+	}
+
+	opts, err := s.runner.PreviewLaunch(launch)
+	if err != nil {
+		http.Error(w, err.Error(), launchErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, newSessionPreview(opts))
+}
+
+func newSessionPreview(opts runner.StartSessionOptions) sessionPreviewResponse {
+	return sessionPreviewResponse{
+		Repo:                 opts.RepoName,
+		Tool:                 opts.Tool,
+		Model:                opts.Model,
+		Branch:               opts.Branch,
+		BaseBranch:           opts.BaseBranch,
+		AutoPR:               opts.AutoPR,
+		SetupCmd:             opts.SetupCmd,
+		Validate:             opts.Validate,
+		ValidateCmd:          opts.ValidateCmd,
+		ValidateSuccessCodes: opts.ValidateSuccessCodes,
+		CommitMsg:            opts.CommitMsg,
+		PRTitle:              opts.PRTitle,
+		Ephemeral:            opts.Ephemeral,
+		CommitStrategy:       opts.CommitStrategy,
+		CommitMsgMode:        opts.CommitMsgMode,
+		FocusPaths:           opts.FocusPaths,
+		Attachments:          opts.Attachments,
+		SelfReview:           opts.SelfReview,
+		IssueRef:             opts.IssueRef,
+		CloseIssue:           opts.CloseIssue,
+		Env:                  runner.RedactEnv(opts.Env),
+		Origin:               opts.Origin,
+	}
+}
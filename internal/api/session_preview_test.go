@@ -0,0 +1,70 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleSessionPreviewResolvesWithoutCreating(t *testing.T) {
+	srv := newTestServer(t)
+
+	repoPath := t.TempDir()
+	runGit(t, repoPath, "init")
+	runGit(t, repoPath, "config", "user.email", "test@example.com")
+	runGit(t, repoPath, "config", "user.name", "Test User")
+	runGit(t, repoPath, "commit", "--allow-empty", "-m", "init")
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		Owner:            "acme",
+		Repo:             "api",
+		BarePath:         repoPath,
+		BaseWorktreePath: repoPath,
+		DefaultBranch:    "develop",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := srv.stateStore.SetRepoDefaults("acme/api", "cursor", "gpt-5"); err != nil {
+		t.Fatalf("set repo defaults failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	srv.RegisterRoutes(mux)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions/preview", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(`{"repo":"acme/missing","prompt":"x"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status for unknown repo: got %d want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := post(`{"repo":"acme/api","prompt":"Add OTP login","commit_strategy":"squash"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp sessionPreviewResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response failed: %v", err)
+	}
+	if resp.Tool != "cursor" || resp.Model != "gpt-5" || resp.BaseBranch != "develop" {
+		t.Fatalf("unexpected resolution: %+v", resp)
+	}
+	if !strings.Contains(resp.Branch, "otp-login") || resp.CommitStrategy != "squash" || resp.AutoPR {
+		t.Fatalf("unexpected resolution: %+v", resp)
+	}
+
+	sessions, err := srv.stateStore.ListSessions()
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("preview must not create sessions: %d, %v", len(sessions), err)
+	}
+}
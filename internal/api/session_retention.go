@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+const (
+	// settingMaxSessionsPerRepo is the store key for how many unarchived
+	// sessions a repo keeps before the oldest finished ones are retired.
+	// Unset or 0 keeps every session.
+	settingMaxSessionsPerRepo = "max_sessions_per_repo"
+
+	// settingSessionRetentionAction is the store key for what retiring a
+	// session means: archive (the default) or delete.
+	settingSessionRetentionAction = "session_retention_action"
+
+	sessionRetentionArchive = "archive"
+	sessionRetentionDelete  = "delete"
+
+	// sessionJanitorInterval is how often the daemon enforces
+	// max_sessions_per_repo.
+	sessionJanitorInterval = 1 * time.Hour
+)
+
+// maxSessionsPerRepo reads the configured cap; 0 means no cap, which is also
+// what an unset, malformed or negative value falls back to.
+func (s *Server) maxSessionsPerRepo() int {
+	val, found, err := s.stateStore.GetSetting(settingMaxSessionsPerRepo)
+	if err != nil || !found {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// sessionRetentionAction reads the configured action, falling back to archive
+// for an unset or unknown value: the sweep never deletes unless asked to.
+func (s *Server) sessionRetentionAction() string {
+	val, found, err := s.stateStore.GetSetting(settingSessionRetentionAction)
+	if err == nil && found && strings.TrimSpace(val) == sessionRetentionDelete {
+		return sessionRetentionDelete
+	}
+	return sessionRetentionArchive
+}
+
+// StartSessionJanitor enforces max_sessions_per_repo now and then on an
+// interval until ctx is cancelled, like StartTrashJanitor.
+func (s *Server) StartSessionJanitor(ctx context.Context) {
+	if n, err := s.sweepSessionRetention(); err != nil {
+		slog.Error("session janitor: initial sweep failed", "err", err)
+	} else if n > 0 {
+		slog.Info("session janitor: retired sessions", "count", n)
+	}
+
+	go func() {
+		ticker := time.NewTicker(sessionJanitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n, err := s.sweepSessionRetention(); err != nil {
+					slog.Error("session janitor: sweep failed", "err", err)
+				} else if n > 0 {
+					slog.Info("session janitor: retired sessions", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+// sweepSessionRetention retires the least recently active sessions of every
+// repo holding more than max_sessions_per_repo unarchived ones. Only finished
+// sessions are eligible; see retireable. Ineligible sessions still count
+// toward the cap, so a repo full of open PRs can stay over it. Like the trash
+// purge it is best-effort per session.
+func (s *Server) sweepSessionRetention() (int, error) {
+	limit := s.maxSessionsPerRepo()
+	if limit == 0 {
+		return 0, nil
+	}
+	action := s.sessionRetentionAction()
+
+	repos, err := s.stateStore.ListRepos()
+	if err != nil {
+		return 0, err
+	}
+	retired := 0
+	for _, repo := range repos {
+		sessions, err := s.stateStore.ListSessionsByRepo(repo.Name)
+		if err != nil {
+			slog.Warn("session janitor: list sessions failed", "repo", repo.Name, "err", err)
+			continue
+		}
+		live := make([]state.Session, 0, len(sessions))
+		for _, sess := range sessions {
+			if sess.ArchivedAt == nil {
+				live = append(live, sess)
+			}
+		}
+		excess := len(live) - limit
+		// live is most recently updated first, so walk it from the end.
+		for i := len(live) - 1; i >= 0 && excess > 0; i-- {
+			if !retireable(live[i]) {
+				continue
+			}
+			if err := s.retireSession(live[i], action); err != nil {
+				slog.Warn("session janitor: retire session failed", "session_id", live[i].ID, "err", err)
+				continue
+			}
+			excess--
+			retired++
+		}
+	}
+	return retired, nil
+}
+
+// retireable reports whether retention may touch a session: it must not be
+// running and its latest run must have finished. A session with a PR is left
+// alone, since Fog does not track whether the PR is still open.
+func retireable(sess state.Session) bool {
+	if sess.Busy || strings.TrimSpace(sess.PRURL) != "" {
+		return false
+	}
+	switch sess.Status {
+	case "COMPLETED", "FAILED", "CANCELLED":
+		return true
+	}
+	return false
+}
+
+// retireSession archives or deletes one session. Deleting removes the
+// worktree without force and keeps the branch, like DELETE on a session: a
+// worktree with uncommitted changes fails the removal, and the session is
+// kept for the next sweep, while committed work stays on its branch.
+func (s *Server) retireSession(sess state.Session, action string) error {
+	if action != sessionRetentionDelete {
+		return s.runner.ArchiveSession(sess.ID)
+	}
+	if err := s.runner.RemoveSessionWorktree(sess.ID, false); err != nil {
+		return err
+	}
+	s.diskCache.forget(sess.ID)
+	if err := s.runner.RemoveSessionRunLogs(sess.ID); err != nil {
+		slog.Warn("session janitor: remove run logs failed", "session_id", sess.ID, "err", err)
+	}
+	return s.runner.DeleteSession(sess.ID)
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// seedRetentionSessions creates the given sessions in acme/api, each one a
+// minute newer than the last.
+func seedRetentionSessions(t *testing.T, srv *Server, sessions ...state.Session) {
+	t.Helper()
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	start := time.Now().UTC().Add(-time.Hour)
+	for i, sess := range sessions {
+		sess.RepoName = "acme/api"
+		sess.Branch = "fog/" + sess.ID
+		sess.WorktreePath = "/tmp/acme-api/" + sess.ID
+		sess.Tool = "claude"
+		sess.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		sess.UpdatedAt = sess.CreatedAt
+		if err := srv.stateStore.CreateSession(sess); err != nil {
+			t.Fatalf("create session %s failed: %v", sess.ID, err)
+		}
+	}
+}
+
+func TestSweepSessionRetentionArchivesOldestFinished(t *testing.T) {
+	srv := newTestServer(t)
+	seedRetentionSessions(t, srv,
+		state.Session{ID: "oldest-busy", Status: "AI_RUNNING", Busy: true},
+		state.Session{ID: "old-pr", Status: "COMPLETED", PRURL: "https://github.com/acme/api/pull/1"},
+		state.Session{ID: "old-done", Status: "COMPLETED"},
+		state.Session{ID: "old-failed", Status: "FAILED"},
+		state.Session{ID: "new-done", Status: "COMPLETED"},
+	)
+	if err := srv.stateStore.SetSetting(settingMaxSessionsPerRepo, "3"); err != nil {
+		t.Fatalf("set setting failed: %v", err)
+	}
+
+	n, err := srv.sweepSessionRetention()
+	if err != nil {
+		t.Fatalf("sweepSessionRetention: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("retired %d sessions, want 2", n)
+	}
+	for id, wantArchived := range map[string]bool{
+		"oldest-busy": false,
+		"old-pr":      false,
+		"old-done":    true,
+		"old-failed":  true,
+		"new-done":    false,
+	} {
+		sess, found, err := srv.stateStore.GetSession(id)
+		if err != nil || !found {
+			t.Fatalf("get session %s: found=%v err=%v", id, found, err)
+		}
+		if got := sess.ArchivedAt != nil; got != wantArchived {
+			t.Errorf("session %s archived = %v, want %v", id, got, wantArchived)
+		}
+	}
+
+	// The cap is now met, so a second sweep does nothing.
+	if n, err := srv.sweepSessionRetention(); err != nil || n != 0 {
+		t.Fatalf("second sweep: retired %d, err %v", n, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessions(w, req)
+	var listed sessionListResponse
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode list failed: %v", err)
+	}
+	if len(listed.Sessions) != 3 {
+		t.Fatalf("listed %d sessions, want 3 unarchived", len(listed.Sessions))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions?include_archived=true", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessions(w, req)
+	listed = sessionListResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode list failed: %v", err)
+	}
+	if len(listed.Sessions) != 5 {
+		t.Fatalf("listed %d sessions with include_archived, want 5", len(listed.Sessions))
+	}
+}
+
+func TestSweepSessionRetentionDeletes(t *testing.T) {
+	srv := newTestServer(t)
+	seedRetentionSessions(t, srv,
+		state.Session{ID: "old-done", Status: "COMPLETED"},
+		state.Session{ID: "new-done", Status: "COMPLETED"},
+	)
+	for key, value := range map[string]string{
+		settingMaxSessionsPerRepo:     "1",
+		settingSessionRetentionAction: sessionRetentionDelete,
+	} {
+		if err := srv.stateStore.SetSetting(key, value); err != nil {
+			t.Fatalf("set %s failed: %v", key, err)
+		}
+	}
+
+	if n, err := srv.sweepSessionRetention(); err != nil || n != 1 {
+		t.Fatalf("sweep: retired %d, err %v", n, err)
+	}
+	if _, found, _ := srv.stateStore.GetSession("old-done"); found {
+		t.Fatal("expected old-done to be deleted")
+	}
+	if _, found, _ := srv.stateStore.GetSession("new-done"); !found {
+		t.Fatal("expected new-done to survive")
+	}
+}
+
+func TestSweepSessionRetentionOffByDefault(t *testing.T) {
+	srv := newTestServer(t)
+	seedRetentionSessions(t, srv,
+		state.Session{ID: "a", Status: "COMPLETED"},
+		state.Session{ID: "b", Status: "COMPLETED"},
+	)
+	if n, err := srv.sweepSessionRetention(); err != nil || n != 0 {
+		t.Fatalf("sweep with no cap: retired %d, err %v", n, err)
+	}
+}
+
+func TestSweepSessionRetentionDeleteKeepsUnsavedWork(t *testing.T) {
+	srv := newTestServer(t)
+	base, _ := initTestGitRepoWithFeatureBranch(t)
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         filepath.Join(base, ".git"),
+		BaseWorktreePath: base,
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	start := time.Now().UTC().Add(-time.Hour)
+	worktrees := map[string]string{}
+	for i, id := range []string{"old-dirty", "old-unpushed", "new-done"} {
+		wt := filepath.Join(t.TempDir(), id)
+		runGit(t, base, "worktree", "add", "-b", "fog/"+id, wt)
+		worktrees[id] = wt
+		sess := state.Session{
+			ID: id, RepoName: "acme/api", Branch: "fog/" + id, WorktreePath: wt, Tool: "claude", Status: "COMPLETED",
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+		sess.UpdatedAt = sess.CreatedAt
+		if err := srv.stateStore.CreateSession(sess); err != nil {
+			t.Fatalf("create session %s failed: %v", id, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(worktrees["old-dirty"], "wip.txt"), []byte("wip\n"), 0o644); err != nil {
+		t.Fatalf("write wip file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktrees["old-unpushed"], "done.txt"), []byte("done\n"), 0o644); err != nil {
+		t.Fatalf("write done file: %v", err)
+	}
+	runGit(t, worktrees["old-unpushed"], "add", "done.txt")
+	runGit(t, worktrees["old-unpushed"], "commit", "-m", "unpushed work")
+	unpushed := runGit(t, worktrees["old-unpushed"], "rev-parse", "HEAD")
+	for key, value := range map[string]string{
+		settingMaxSessionsPerRepo:     "2",
+		settingSessionRetentionAction: sessionRetentionDelete,
+	} {
+		if err := srv.stateStore.SetSetting(key, value); err != nil {
+			t.Fatalf("set %s failed: %v", key, err)
+		}
+	}
+
+	// old-dirty is refused, so the sweep moves on to old-unpushed.
+	if n, err := srv.sweepSessionRetention(); err != nil || n != 1 {
+		t.Fatalf("sweep: retired %d, err %v; want only old-unpushed", n, err)
+	}
+	if _, found, _ := srv.stateStore.GetSession("old-dirty"); !found {
+		t.Fatal("a session with uncommitted changes was deleted")
+	}
+	if _, err := os.Stat(filepath.Join(worktrees["old-dirty"], "wip.txt")); err != nil {
+		t.Fatalf("uncommitted work was removed: %v", err)
+	}
+	if _, found, _ := srv.stateStore.GetSession("old-unpushed"); found {
+		t.Fatal("expected old-unpushed to be deleted")
+	}
+	if got := runGit(t, base, "rev-parse", "fog/old-unpushed"); got != unpushed {
+		t.Fatalf("unpushed branch = %q, want it kept at %s", got, unpushed)
+	}
+}
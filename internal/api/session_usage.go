@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// sessionUsageResponse totals AI usage over a session's runs. The token and
+// cost fields are omitted when no run reported them.
+type sessionUsageResponse struct {
+	SessionID string `json:"session_id"`
+	state.RunUsage
+	// RunsWithUsage counts the runs whose tool reported usage; runs by tools
+	// that report none are not in the totals.
+	RunsWithUsage int `json:"runs_with_usage"`
+}
+
+// getSessionUsage serves GET /api/sessions/{id}/usage.
+func (s *Server) getSessionUsage(w http.ResponseWriter, sessionID string) {
+	_, found, err := s.stateStore.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	usage, runs, err := s.stateStore.SessionUsage(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, sessionUsageResponse{
+		SessionID:     sessionID,
+		RunUsage:      usage,
+		RunsWithUsage: runs,
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestGetSessionUsage(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w
+	}
+
+	if w := get("/api/sessions/missing/usage"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", w.Code)
+	}
+
+	// No run reported usage: the totals are left out rather than zero.
+	w := get("/api/sessions/session-1/usage")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); strings.Contains(body, "input_tokens") || strings.Contains(body, "cost_usd") {
+		t.Fatalf("expected usage fields to be omitted, got %s", body)
+	}
+
+	if err := srv.stateStore.AddRunUsage("run-1", state.RunUsage{InputTokens: 900, OutputTokens: 100, CostUSD: 0.02}); err != nil {
+		t.Fatalf("AddRunUsage: %v", err)
+	}
+	w = get("/api/sessions/session-1/usage")
+	var out sessionUsageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if out.SessionID != "session-1" || out.InputTokens != 900 || out.OutputTokens != 100 || out.CostUSD != 0.02 || out.RunsWithUsage != 1 {
+		t.Fatalf("unexpected usage: %+v", out)
+	}
+
+	// The per-run figures appear on the runs list too.
+	w = get("/api/sessions/session-1/runs")
+	var runs []state.Run
+	if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil {
+		t.Fatalf("decode runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].InputTokens != 900 {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+}
@@ -4,42 +4,42 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/branchname"
 	"github.com/darkLord19/foglet/internal/editor"
 	"github.com/darkLord19/foglet/internal/runner"
 	"github.com/darkLord19/foglet/internal/state"
 )
 
-// dangerousShellChars contains characters that enable shell injection when
-// passed through sh -c. We reject commands containing any of these.
-var dangerousShellChars = []string{";", "||", "&&", "|", "`", "$(", "${", ">", "<", "\n", "\r"}
+// detectEditorFn picks the editor a session opens in; swapped in tests.
+var detectEditorFn = editor.Detect
 
-// validateShellCommand rejects commands containing dangerous shell metacharacters.
+// validateShellCommand rejects a validate_cmd containing dangerous shell
+// metacharacters; see runner.ValidateShellCommand.
 func validateShellCommand(cmd string) error {
-	cmd = strings.TrimSpace(cmd)
-	if cmd == "" {
-		return nil
-	}
-	for _, ch := range dangerousShellChars {
-		if strings.Contains(cmd, ch) {
-			return fmt.Errorf("validate_cmd contains forbidden character sequence %q", ch)
-		}
+	if err := runner.ValidateShellCommand(cmd); err != nil {
+		return fmt.Errorf("validate_cmd %w", err)
 	}
 	return nil
 }
 
 // CreateSessionRequest is the payload for POST /api/sessions.
 type CreateSessionRequest struct {
-	Repo        string `json:"repo"`
-	Tool        string `json:"tool,omitempty"`
-	Model       string `json:"model,omitempty"`
-	Prompt      string `json:"prompt"`
-	BranchName  string `json:"branch_name,omitempty"`
+	Repo       string `json:"repo"`
+	Tool       string `json:"tool,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Prompt     string `json:"prompt"`
+	BranchName string `json:"branch_name,omitempty"`
+	// User fills the {user} token of branch_prefix when branch_name is
+	// empty; default_user is used when it is omitted.
+	User        string `json:"user,omitempty"`
 	AutoPR      *bool  `json:"autopr,omitempty"`
 	SetupCmd    string `json:"setup_cmd,omitempty"`
 	Validate    bool   `json:"validate,omitempty"`
@@ -48,6 +48,38 @@ type CreateSessionRequest struct {
 	CommitMsg   string `json:"commit_msg,omitempty"`
 	Async       *bool  `json:"async,omitempty"`
 	PRTitle     string `json:"pr_title,omitempty"`
+	// ValidateSuccessCodes lists non-zero validate_cmd exit codes that still
+	// count as a pass.
+	ValidateSuccessCodes []int `json:"validate_success_codes,omitempty"`
+	// Ephemeral starts a scratch session on a detached worktree that is never
+	// committed or pushed and is removed when the run ends.
+	Ephemeral bool `json:"ephemeral,omitempty"`
+	// CommitStrategy is per_run (default), squash or squash_force.
+	CommitStrategy string `json:"commit_strategy,omitempty"`
+	// CommitMsgMode is ai, template or fixed: how the first run picks a
+	// commit message when commit_msg is empty. Empty uses commit_template
+	// when it is set, else ai.
+	CommitMsgMode string `json:"commit_msg_mode,omitempty"`
+	// FocusPaths are worktree-relative paths the AI is asked to confine its
+	// changes to.
+	FocusPaths []string `json:"focus_paths,omitempty"`
+	// Attachments are worktree-relative files whose contents are given to
+	// the AI ahead of the prompt.
+	Attachments []string `json:"attachments,omitempty"`
+	// ContextText is pasted context, such as a spec, given to the AI ahead
+	// of the prompt.
+	ContextText string `json:"context_text,omitempty"`
+	// SelfReview has the AI review and fix its committed changes once before
+	// anything is pushed.
+	SelfReview bool `json:"self_review,omitempty"`
+	// IssueRef is a tracking issue (#123, owner/repo#123, PROJ-123 or a URL)
+	// added as a Refs trailer to the session's commits and cited in its PR.
+	IssueRef string `json:"issue_ref,omitempty"`
+	// CloseIssue writes "Closes <issue_ref>" in the PR body instead.
+	CloseIssue bool `json:"close_issue,omitempty"`
+	// Env is extra environment for the setup command, validation and AI tool
+	// of this and every follow-up run.
+	Env map[string]string `json:"env,omitempty"`
 }
 
 // FollowUpRunRequest is the payload for POST /api/sessions/{id}/runs.
@@ -58,8 +90,10 @@ type FollowUpRunRequest struct {
 
 // ForkSessionRequest is the payload for POST /api/sessions/{id}/fork.
 type ForkSessionRequest struct {
-	Prompt      string `json:"prompt"`
-	BranchName  string `json:"branch_name,omitempty"`
+	Prompt     string `json:"prompt"`
+	BranchName string `json:"branch_name,omitempty"`
+	// User is as for CreateSessionRequest.
+	User        string `json:"user,omitempty"`
 	Tool        string `json:"tool,omitempty"`
 	Model       string `json:"model,omitempty"`
 	AutoPR      *bool  `json:"autopr,omitempty"`
@@ -70,6 +104,23 @@ type ForkSessionRequest struct {
 	CommitMsg   string `json:"commit_msg,omitempty"`
 	Async       *bool  `json:"async,omitempty"`
 	PRTitle     string `json:"pr_title,omitempty"`
+	// ValidateSuccessCodes lists non-zero validate_cmd exit codes that still
+	// count as a pass.
+	ValidateSuccessCodes []int `json:"validate_success_codes,omitempty"`
+	// FullTranscriptContext passes the source session's full transcript to the
+	// fork instead of an AI-generated summary.
+	FullTranscriptContext bool `json:"full_transcript_context,omitempty"`
+	// CommitStrategy defaults to the source session's.
+	CommitStrategy string `json:"commit_strategy,omitempty"`
+	// FocusPaths is as for CreateSessionRequest.
+	FocusPaths []string `json:"focus_paths,omitempty"`
+	// SelfReview is as for CreateSessionRequest.
+	SelfReview bool `json:"self_review,omitempty"`
+	// IssueRef and CloseIssue default to the source session's.
+	IssueRef   string `json:"issue_ref,omitempty"`
+	CloseIssue bool   `json:"close_issue,omitempty"`
+	// Env replaces the source session's environment when present.
+	Env map[string]string `json:"env,omitempty"`
 }
 
 type createSessionResponse struct {
@@ -78,19 +129,61 @@ type createSessionResponse struct {
 }
 
 type asyncCreateSessionResponse struct {
-	SessionID string `json:"session_id"`
-	RunID     string `json:"run_id"`
-	Status    string `json:"status"`
+	SessionID  string `json:"session_id"`
+	RunID      string `json:"run_id"`
+	Status     string `json:"status"`
+	QueueDepth int    `json:"queue_depth"`
+}
+
+// queueRetryAfterSeconds is the Retry-After hint sent when the run queue is
+// saturated. Runs take minutes, so there is no point in clients retrying sooner.
+const queueRetryAfterSeconds = 30
+
+// writeQueueFull reports a saturated run queue as 503 with a Retry-After hint
+// and the current depth, so clients back off instead of piling on.
+func (s *Server) writeQueueFull(w http.ResponseWriter, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(queueRetryAfterSeconds))
+	s.writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+		"error":       err.Error(),
+		"queue_depth": s.runner.QueueDepth(),
+	})
 }
 
 type sessionDetailResponse struct {
 	Session state.Session `json:"session"`
 	Runs    []state.Run   `json:"runs"`
+	// Ahead and Behind count the commits the session branch has over its
+	// repo's default branch and lacks from it. Omitted when they cannot be
+	// computed, as for scratch sessions.
+	Ahead  *int `json:"ahead,omitempty"`
+	Behind *int `json:"behind,omitempty"`
+	// WorktreePresent reports whether the session worktree is on disk, so
+	// it can still be opened.
+	WorktreePresent bool `json:"worktree_present"`
 }
 
 type sessionSummary struct {
 	state.Session
-	LatestRun *state.Run `json:"latest_run,omitempty"`
+	LatestRun       *state.Run `json:"latest_run,omitempty"`
+	WorktreePresent bool       `json:"worktree_present"`
+}
+
+// redactSession masks the session's env values. Every response that carries
+// a session goes through it, since env often holds credentials.
+func redactSession(sess state.Session) state.Session {
+	sess.Env = runner.RedactEnv(sess.Env)
+	return sess
+}
+
+// worktreePresent reports whether a session's worktree is still on disk: it
+// was not pruned and its directory exists.
+func worktreePresent(sess state.Session) bool {
+	wt := strings.TrimSpace(sess.WorktreePath)
+	if sess.WorktreePrunedAt != nil || wt == "" {
+		return false
+	}
+	info, err := os.Stat(wt)
+	return err == nil && info.IsDir()
 }
 
 type sessionDiffResponse struct {
@@ -99,12 +192,37 @@ type sessionDiffResponse struct {
 	WorktreePath string `json:"worktree_path"`
 	Stat         string `json:"stat"`
 	Patch        string `json:"patch"`
+	// UntrackedFiles and UntrackedPatch are only filled with
+	// ?include_untracked=1.
+	UntrackedFiles []string `json:"untracked_files,omitempty"`
+	UntrackedPatch string   `json:"untracked_patch,omitempty"`
+	// AcceptedRunID is set when the diff stops at an accepted run rather
+	// than the branch head.
+	AcceptedRunID string `json:"accepted_run_id,omitempty"`
+}
+
+// runDiffResponse is what a single run changed. BaseSHA and the diff are
+// empty when the run committed nothing.
+type runDiffResponse struct {
+	RunID     string `json:"run_id"`
+	CommitSHA string `json:"commit_sha,omitempty"`
+	BaseSHA   string `json:"base_sha,omitempty"`
+	Stat      string `json:"stat"`
+	Patch     string `json:"patch"`
+}
+
+type sessionCommit struct {
+	SHA         string    `json:"sha"`
+	Message     string    `json:"message"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email"`
+	Timestamp   time.Time `json:"timestamp"`
 }
 
 func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		s.listSessions(w)
+		s.listSessions(w, r)
 	case http.MethodPost:
 		s.createSession(w, r)
 	default:
@@ -127,11 +245,16 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(parts) == 1 {
-		if r.Method != http.MethodGet {
+		switch r.Method {
+		case http.MethodGet:
+			s.getSession(w, sessionID)
+		case http.MethodPatch:
+			s.updateSession(w, r, sessionID)
+		case http.MethodDelete:
+			s.deleteSession(w, r, sessionID)
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
 		}
-		s.getSession(w, sessionID)
 		return
 	}
 
@@ -143,12 +266,24 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		case len(parts) == 2 && r.Method == http.MethodPost:
 			s.createFollowUpRun(w, r, sessionID)
 			return
+		case len(parts) == 3 && r.Method == http.MethodDelete:
+			s.deleteSessionRun(w, sessionID, parts[2])
+			return
+		case len(parts) == 4 && parts[3] == "retry" && r.Method == http.MethodPost:
+			s.retrySessionRun(w, sessionID, parts[2])
+			return
 		case len(parts) == 4 && parts[3] == "events" && r.Method == http.MethodGet:
 			s.listRunEvents(w, r, sessionID, parts[2])
 			return
 		case len(parts) == 4 && parts[3] == "stream" && r.Method == http.MethodGet:
 			s.streamRunEvents(w, r, sessionID, parts[2])
 			return
+		case len(parts) == 4 && parts[3] == "log" && r.Method == http.MethodGet:
+			s.getRunLog(w, r, sessionID, parts[2])
+			return
+		case len(parts) == 4 && parts[3] == "diff" && r.Method == http.MethodGet:
+			s.getRunDiff(w, sessionID, parts[2])
+			return
 		}
 	}
 	if len(parts) == 2 {
@@ -160,10 +295,55 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 			s.createForkSession(w, r, sessionID)
 			return
 		case parts[1] == "diff" && r.Method == http.MethodGet:
-			s.getSessionDiff(w, sessionID)
+			s.getSessionDiff(w, r, sessionID)
+			return
+		case parts[1] == "disk-usage" && r.Method == http.MethodGet:
+			s.getSessionDiskUsage(w, r, sessionID)
+			return
+		case parts[1] == "usage" && r.Method == http.MethodGet:
+			s.getSessionUsage(w, sessionID)
+			return
+		case parts[1] == "export" && r.Method == http.MethodGet:
+			s.getSessionExport(w, sessionID)
+			return
+		case parts[1] == "commits" && r.Method == http.MethodGet:
+			s.listSessionCommits(w, sessionID)
+			return
+		case parts[1] == "children" && r.Method == http.MethodGet:
+			s.listSessionChildren(w, sessionID)
+			return
+		case parts[1] == "create-pr" && r.Method == http.MethodPost:
+			s.retrySessionPR(w, r, sessionID)
+			return
+		case parts[1] == "squash" && r.Method == http.MethodPost:
+			s.squashSession(w, r, sessionID)
+			return
+		case parts[1] == "setup" && r.Method == http.MethodPost:
+			s.rerunSessionSetup(w, r, sessionID)
 			return
 		case parts[1] == "open" && r.Method == http.MethodPost:
-			s.openSessionWorktree(w, sessionID)
+			s.openSessionWorktree(w, r, sessionID)
+			return
+		case parts[1] == "editor" && r.Method == http.MethodGet:
+			s.getSessionEditor(w, sessionID, r.URL.Query().Get("editor"))
+			return
+		case parts[1] == "accept" && r.Method == http.MethodPost:
+			s.acceptSessionRun(w, r, sessionID)
+			return
+		case parts[1] == "notify-slack" && r.Method == http.MethodPost:
+			s.setSessionSlackThread(w, r, sessionID)
+			return
+		case parts[1] == "pause" && r.Method == http.MethodPost:
+			s.setSessionPaused(w, sessionID, true)
+			return
+		case parts[1] == "resume" && r.Method == http.MethodPost:
+			s.setSessionPaused(w, sessionID, false)
+			return
+		case parts[1] == "archive" && r.Method == http.MethodPost:
+			s.setSessionArchived(w, sessionID, true)
+			return
+		case parts[1] == "unarchive" && r.Method == http.MethodPost:
+			s.setSessionArchived(w, sessionID, false)
 			return
 		}
 	}
@@ -171,13 +351,64 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
-func (s *Server) listSessions(w http.ResponseWriter) {
-	sessions, err := s.runner.ListSessions()
+// clientHeader is set by first-party clients to identify themselves. The
+// desktop app sends "desktop"; anything else counts as a plain API caller.
+const clientHeader = "X-Fog-Client"
+
+// requestOrigin is the session origin for a request: "desktop" from the
+// desktop app, otherwise "api".
+func requestOrigin(r *http.Request) string {
+	if strings.EqualFold(strings.TrimSpace(r.Header.Get(clientHeader)), "desktop") {
+		return "desktop"
+	}
+	return "api"
+}
+
+// sessionListResponse is one page of GET /api/sessions. Total counts every
+// session the filters match, not just this page.
+type sessionListResponse struct {
+	Sessions []sessionSummary `json:"sessions"`
+	Total    int              `json:"total"`
+}
+
+// listSessions serves GET /api/sessions, filtered by repo and status and
+// paged by limit and offset. Archived sessions are left out unless the
+// request asks for them with include_archived=true.
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := state.SessionFilter{
+		RepoName:        strings.TrimSpace(query.Get("repo")),
+		Status:          strings.ToUpper(strings.TrimSpace(query.Get("status"))),
+		IncludeArchived: query.Get("include_archived") == "true",
+	}
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		raw := strings.TrimSpace(query.Get(param.name))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			http.Error(w, param.name+" must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		*param.dst = n
+	}
+
+	sessions, total, err := s.stateStore.ListSessionsFiltered(filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	s.writeJSON(w, http.StatusOK, sessionListResponse{Sessions: s.sessionSummaries(sessions), Total: total})
+}
+
+// sessionSummaries pairs each session with its latest run, as session lists
+// return them.
+func (s *Server) sessionSummaries(sessions []state.Session) []sessionSummary {
 	out := make([]sessionSummary, 0, len(sessions))
 	for _, sess := range sessions {
 		var latest *state.Run
@@ -186,30 +417,51 @@ func (s *Server) listSessions(w http.ResponseWriter) {
 			latest = &runCopy
 		}
 		out = append(out, sessionSummary{
-			Session:   sess,
-			LatestRun: latest,
+			Session:         redactSession(sess),
+			LatestRun:       latest,
+			WorktreePresent: worktreePresent(sess),
 		})
 	}
+	return out
+}
 
-	s.writeJSON(w, http.StatusOK, out)
+// listSessionChildren serves GET /api/sessions/{id}/children: the sessions
+// forked from this one, oldest first. Archived forks are included.
+func (s *Server) listSessionChildren(w http.ResponseWriter, sessionID string) {
+	if _, found, err := s.stateStore.GetSession(sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	children, err := s.stateStore.ListChildSessions(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"sessions": s.sessionSummaries(children)})
 }
 
-func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+// decodeCreateSession reads a CreateSessionRequest body and turns it into the
+// LaunchRequest POST /api/sessions would make. Preview shares it, so the two
+// cannot drift. It writes the 400 itself and returns false on bad input.
+func decodeCreateSession(w http.ResponseWriter, r *http.Request) (runner.LaunchRequest, bool) {
 	var req CreateSessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return runner.LaunchRequest{}, false
 	}
 
 	req.Repo = strings.TrimSpace(req.Repo)
 	req.Prompt = strings.TrimSpace(req.Prompt)
 	if req.Repo == "" || req.Prompt == "" {
 		http.Error(w, "repo and prompt are required", http.StatusBadRequest)
-		return
+		return runner.LaunchRequest{}, false
 	}
 	if err := validateShellCommand(req.ValidateCmd); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return runner.LaunchRequest{}, false
 	}
 
 	autoPR := false
@@ -221,13 +473,15 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 		async = *req.Async
 	}
 
-	session, run, err := s.runner.Launch(runner.LaunchRequest{
+	return runner.LaunchRequest{
 		Entrypoint:  "api",
+		Origin:      requestOrigin(r),
 		RepoName:    req.Repo,
 		Prompt:      req.Prompt,
 		Tool:        req.Tool,
 		Model:       req.Model,
 		BranchName:  req.BranchName,
+		User:        req.User,
 		BaseBranch:  req.BaseBranch,
 		AutoPR:      autoPR,
 		SetupCmd:    req.SetupCmd,
@@ -236,7 +490,33 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 		CommitMsg:   req.CommitMsg,
 		PRTitle:     req.PRTitle,
 		Async:       async,
-	})
+
+		ValidateSuccessCodes: req.ValidateSuccessCodes,
+		Ephemeral:            req.Ephemeral,
+		CommitStrategy:       req.CommitStrategy,
+		CommitMsgMode:        req.CommitMsgMode,
+		FocusPaths:           req.FocusPaths,
+		Attachments:          req.Attachments,
+		ContextText:          req.ContextText,
+		SelfReview:           req.SelfReview,
+		IssueRef:             req.IssueRef,
+		CloseIssue:           req.CloseIssue,
+		Env:                  req.Env,
+	}, true
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	launch, ok := decodeCreateSession(w, r)
+	if !ok {
+		return
+	}
+	async := launch.Async
+
+	session, run, err := s.runner.Launch(launch)
+	if errors.Is(err, runner.ErrQueueFull) {
+		s.writeQueueFull(w, err)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), launchErrorStatus(err))
 		return
@@ -244,14 +524,15 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 
 	if async {
 		s.writeJSON(w, http.StatusAccepted, asyncCreateSessionResponse{
-			SessionID: session.ID,
-			RunID:     run.ID,
-			Status:    "accepted",
+			SessionID:  session.ID,
+			RunID:      run.ID,
+			Status:     "accepted",
+			QueueDepth: s.runner.QueueDepth(),
 		})
 		return
 	}
 	s.writeJSON(w, http.StatusOK, createSessionResponse{
-		Session: session,
+		Session: redactSession(session),
 		Run:     run,
 	})
 }
@@ -259,7 +540,7 @@ func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
 // launchErrorStatus maps a launch failure to an HTTP status. Rejected intent is
 // the caller's fault; anything else is ours.
 func launchErrorStatus(err error) int {
-	if errors.Is(err, runner.ErrInvalidLaunch) || errors.Is(err, runner.ErrUnknownRepo) {
+	if errors.Is(err, runner.ErrInvalidLaunch) || errors.Is(err, runner.ErrUnknownRepo) || errors.Is(err, runner.ErrCommandNotAllowed) {
 		return http.StatusBadRequest
 	}
 	return http.StatusInternalServerError
@@ -281,10 +562,16 @@ func (s *Server) getSession(w http.ResponseWriter, sessionID string) {
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, sessionDetailResponse{
-		Session: session,
-		Runs:    runs,
-	})
+	resp := sessionDetailResponse{
+		Session:         redactSession(session),
+		Runs:            runs,
+		WorktreePresent: worktreePresent(session),
+	}
+	if status, err := s.runner.SessionBranchStatus(sessionID); err == nil {
+		resp.Ahead = &status.Ahead
+		resp.Behind = &status.Behind
+	}
+	s.writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) listSessionRuns(w http.ResponseWriter, sessionID string) {
@@ -325,19 +612,34 @@ func (s *Server) createFollowUpRun(w http.ResponseWriter, r *http.Request, sessi
 	}
 	if async {
 		run, err := s.runner.ContinueSessionAsync(sessionID, req.Prompt)
+		if errors.Is(err, runner.ErrQueueFull) {
+			s.writeQueueFull(w, err)
+			return
+		}
+		if errors.Is(err, runner.ErrDuplicatePrompt) || errors.Is(err, runner.ErrDirtyWorktree) ||
+			errors.Is(err, runner.ErrSessionPaused) || errors.Is(err, runner.ErrWorktreePruned) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		s.writeJSON(w, http.StatusAccepted, map[string]string{
-			"run_id":  run.ID,
-			"status":  "accepted",
-			"session": run.SessionID,
+		s.writeJSON(w, http.StatusAccepted, map[string]any{
+			"run_id":      run.ID,
+			"status":      "accepted",
+			"session":     run.SessionID,
+			"queue_depth": s.runner.QueueDepth(),
 		})
 		return
 	}
 
 	run, err := s.runner.ContinueSession(sessionID, req.Prompt)
+	if errors.Is(err, runner.ErrDuplicatePrompt) || errors.Is(err, runner.ErrDirtyWorktree) ||
+		errors.Is(err, runner.ErrSessionPaused) || errors.Is(err, runner.ErrWorktreePruned) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -345,6 +647,35 @@ func (s *Server) createFollowUpRun(w http.ResponseWriter, r *http.Request, sessi
 	s.writeJSON(w, http.StatusOK, run)
 }
 
+// retrySessionRun serves POST /api/sessions/{id}/runs/{runID}/retry. The
+// retry always runs in the background.
+func (s *Server) retrySessionRun(w http.ResponseWriter, sessionID, runID string) {
+	run, err := s.runner.RetryRunAsync(sessionID, runID)
+	switch {
+	case errors.Is(err, runner.ErrQueueFull):
+		s.writeQueueFull(w, err)
+		return
+	case errors.Is(err, state.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, runner.ErrRunNotRetryable) || errors.Is(err, runner.ErrSessionBusy) ||
+		errors.Is(err, runner.ErrSessionPaused) || errors.Is(err, runner.ErrDirtyWorktree) ||
+		errors.Is(err, runner.ErrWorktreePruned):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeJSON(w, http.StatusAccepted, map[string]any{
+		"run_id":      run.ID,
+		"status":      "accepted",
+		"session":     run.SessionID,
+		"retry_of":    runID,
+		"queue_depth": s.runner.QueueDepth(),
+	})
+}
+
 func (s *Server) createForkSession(w http.ResponseWriter, r *http.Request, sourceSessionID string) {
 	var req ForkSessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -370,10 +701,10 @@ func (s *Server) createForkSession(w http.ResponseWriter, r *http.Request, sourc
 		http.Error(w, "session not found", http.StatusNotFound)
 		return
 	}
-
-	branch, err := s.runner.ResolveBranch(sourceSession.WorktreePath, req.BranchName, req.Prompt)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// Checked here as well as in the runner: branch resolution below runs git
+	// in the source worktree.
+	if sourceSession.WorktreePrunedAt != nil {
+		http.Error(w, fmt.Sprintf("%v; check out branch %s to continue", runner.ErrWorktreePruned, sourceSession.Branch), http.StatusConflict)
 		return
 	}
 
@@ -385,6 +716,16 @@ func (s *Server) createForkSession(w http.ResponseWriter, r *http.Request, sourc
 		}
 	}
 
+	prefixTool := tool
+	if prefixTool == "" {
+		prefixTool = sourceSession.Tool
+	}
+	branch, err := s.runner.ResolveBranch(sourceSession.WorktreePath, req.BranchName, req.Prompt, branchname.PrefixVars{User: req.User, Tool: prefixTool})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	async := true
 	if req.Async != nil {
 		async = *req.Async
@@ -401,6 +742,16 @@ func (s *Server) createForkSession(w http.ResponseWriter, r *http.Request, sourc
 		BaseBranch:  strings.TrimSpace(req.BaseBranch),
 		CommitMsg:   strings.TrimSpace(req.CommitMsg),
 		PRTitle:     strings.TrimSpace(req.PRTitle),
+
+		ValidateSuccessCodes:  req.ValidateSuccessCodes,
+		FullTranscriptContext: req.FullTranscriptContext,
+		Origin:                requestOrigin(r),
+		CommitStrategy:        strings.TrimSpace(req.CommitStrategy),
+		FocusPaths:            req.FocusPaths,
+		SelfReview:            req.SelfReview,
+		IssueRef:              req.IssueRef,
+		CloseIssue:            req.CloseIssue,
+		Env:                   req.Env,
 	}
 	if req.AutoPR != nil {
 		opts.HasAutoPR = true
@@ -412,25 +763,38 @@ func (s *Server) createForkSession(w http.ResponseWriter, r *http.Request, sourc
 
 	if async {
 		session, run, err := s.runner.ForkSessionAsync(sourceSessionID, opts)
+		if errors.Is(err, runner.ErrQueueFull) {
+			s.writeQueueFull(w, err)
+			return
+		}
+		if errors.Is(err, runner.ErrSessionPaused) || errors.Is(err, runner.ErrWorktreePruned) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		s.writeJSON(w, http.StatusAccepted, asyncCreateSessionResponse{
-			SessionID: session.ID,
-			RunID:     run.ID,
-			Status:    "accepted",
+			SessionID:  session.ID,
+			RunID:      run.ID,
+			Status:     "accepted",
+			QueueDepth: s.runner.QueueDepth(),
 		})
 		return
 	}
 
 	session, run, err := s.runner.ForkSession(sourceSessionID, opts)
+	if errors.Is(err, runner.ErrSessionPaused) || errors.Is(err, runner.ErrWorktreePruned) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	s.writeJSON(w, http.StatusOK, createSessionResponse{
-		Session: session,
+		Session: redactSession(session),
 		Run:     run,
 	})
 }
@@ -469,7 +833,12 @@ func (s *Server) listRunEvents(w http.ResponseWriter, r *http.Request, sessionID
 			limit = parsed
 		}
 	}
-	events, err := s.runner.ListRunEvents(runID, limit)
+	var events []state.RunEvent
+	if types := parseEventTypes(r.URL.Query().Get("types")); len(types) > 0 {
+		events, err = s.stateStore.ListRunEventsByType(runID, limit, types...)
+	} else {
+		events, err = s.runner.ListRunEvents(runID, limit)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -478,6 +847,22 @@ func (s *Server) listRunEvents(w http.ResponseWriter, r *http.Request, sessionID
 	s.writeJSON(w, http.StatusOK, events)
 }
 
+// parseEventTypes splits a comma-separated types query value, dropping blanks
+// and repeats.
+func parseEventTypes(raw string) []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		types = append(types, t)
+	}
+	return types
+}
+
 func (s *Server) streamRunEvents(w http.ResponseWriter, r *http.Request, sessionID, runID string) {
 	session, found, err := s.runner.GetSession(sessionID)
 	if err != nil {
@@ -588,13 +973,112 @@ func (s *Server) cancelSessionRun(w http.ResponseWriter, sessionID string) {
 	})
 }
 
-func (s *Server) getSessionDiff(w http.ResponseWriter, sessionID string) {
+// deleteSession removes a finished session's worktree, then the session with
+// its runs and events. The branch is kept. ?force=true removes a worktree with
+// uncommitted changes.
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request, sessionID string) {
+	force := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("force")); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "force must be a boolean", http.StatusBadRequest)
+			return
+		}
+		force = parsed
+	}
+
+	if err := s.runner.RemoveSessionWorktree(sessionID, force); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, state.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, runner.ErrSessionBusy), errors.Is(err, runner.ErrDirtyWorktree):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.diskCache.forget(sessionID)
+	if err := s.runner.RemoveSessionRunLogs(sessionID); err != nil {
+		slog.Warn("remove run logs failed", "session_id", sessionID, "err", err)
+	}
+	if err := s.runner.DeleteSession(sessionID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, state.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteSessionRun cancels the run when it is executing, and otherwise
+// deletes its record. Deletion is refused for an unfinished run and for the
+// session's only run.
+func (s *Server) deleteSessionRun(w http.ResponseWriter, sessionID, runID string) {
+	run, found, err := s.stateStore.GetRun(runID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found || run.SessionID != sessionID {
+		http.Error(w, "run not found in session", http.StatusNotFound)
+		return
+	}
+
+	if s.runner.CancelRunIfActive(sessionID, runID) {
+		s.writeJSON(w, http.StatusAccepted, map[string]string{
+			"status": "cancel_requested",
+			"run_id": runID,
+		})
+		return
+	}
+
+	if err := s.stateStore.DeleteRun(runID); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, state.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, state.ErrRunNotDeletable):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if err := s.runner.RemoveRunLog(runID); err != nil {
+		slog.Warn("remove run log failed", "run_id", runID, "err", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getSessionDiff(w http.ResponseWriter, r *http.Request, sessionID string) {
+	includeUntracked := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("include_untracked")); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "include_untracked must be a boolean", http.StatusBadRequest)
+			return
+		}
+		includeUntracked = parsed
+	}
+
 	stat, patch, err := s.runner.SessionDiff(sessionID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), branchOpErrorStatus(err))
 		return
 	}
 
+	var untrackedFiles []string
+	var untrackedPatch string
+	if includeUntracked {
+		untrackedFiles, untrackedPatch, err = s.runner.SessionUntracked(sessionID)
+		if err != nil {
+			http.Error(w, err.Error(), branchOpErrorStatus(err))
+			return
+		}
+	}
+
 	session, found, err := s.runner.GetSession(sessionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -621,16 +1105,318 @@ func (s *Server) getSessionDiff(w http.ResponseWriter, sessionID string) {
 	}
 
 	s.writeJSON(w, http.StatusOK, sessionDiffResponse{
-		BaseBranch:   repo.DefaultBranch,
+		BaseBranch:   s.runner.ResolveBaseBranch("", repo),
 		Branch:       session.Branch,
 		WorktreePath: worktreePath,
 		Stat:         stat,
 		Patch:        patch,
+
+		UntrackedFiles: untrackedFiles,
+		UntrackedPatch: untrackedPatch,
+		AcceptedRunID:  session.AcceptedRunID,
 	})
 }
 
-func (s *Server) openSessionWorktree(w http.ResponseWriter, sessionID string) {
-	session, found, err := s.runner.GetSession(sessionID)
+// getRunDiff serves GET /api/sessions/{id}/runs/{runID}/diff: the changes a
+// single finished run committed, for reviewing a session step by step.
+func (s *Server) getRunDiff(w http.ResponseWriter, sessionID, runID string) {
+	run, found, err := s.stateStore.GetRun(runID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found || run.SessionID != sessionID {
+		http.Error(w, "run not found in session", http.StatusNotFound)
+		return
+	}
+	if !isTerminalRunState(run.State) {
+		http.Error(w, "run has not finished", http.StatusNotFound)
+		return
+	}
+
+	base, stat, patch, err := s.runner.RunDiff(run)
+	if err != nil {
+		http.Error(w, err.Error(), branchOpErrorStatus(err))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, runDiffResponse{
+		RunID:     run.ID,
+		CommitSHA: run.CommitSHA,
+		BaseSHA:   base,
+		Stat:      stat,
+		Patch:     patch,
+	})
+}
+
+// branchOpErrorStatus maps an error from a read of a session's branch, such
+// as its diff or commits: 404 for an unknown session or run, 409 once the
+// worktree was pruned, else 400.
+func branchOpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, state.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, runner.ErrWorktreePruned):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) listSessionCommits(w http.ResponseWriter, sessionID string) {
+	commits, err := s.runner.SessionCommits(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), branchOpErrorStatus(err))
+		return
+	}
+
+	out := make([]sessionCommit, 0, len(commits))
+	for _, c := range commits {
+		out = append(out, sessionCommit{
+			SHA:         c.SHA,
+			Message:     c.Message,
+			Author:      c.Author,
+			AuthorEmail: c.AuthorEmail,
+			Timestamp:   c.Timestamp,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, out)
+}
+
+// RetryPRRequest is the optional payload for POST /api/sessions/{id}/create-pr.
+// Empty fields reuse what the failed attempt recorded.
+type RetryPRRequest struct {
+	BaseBranch string `json:"base_branch"`
+	PRTitle    string `json:"pr_title"`
+}
+
+func (s *Server) retrySessionPR(w http.ResponseWriter, r *http.Request, sessionID string) {
+	var req RetryPRRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	prURL, err := s.runner.RetrySessionPR(sessionID, runner.RetryPROptions{
+		BaseBranch: req.BaseBranch,
+		PRTitle:    req.PRTitle,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, state.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, runner.ErrPRNotPending), errors.Is(err, runner.ErrSessionBusy), errors.Is(err, runner.ErrWorktreePruned):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{
+		"session_id": sessionID,
+		"pr_url":     prURL,
+	})
+}
+
+// SquashSessionRequest is the optional payload for
+// POST /api/sessions/{id}/squash.
+type SquashSessionRequest struct {
+	// Message is the squashed commit's message. Empty generates one from the
+	// session's prompt.
+	Message string `json:"message"`
+}
+
+func (s *Server) squashSession(w http.ResponseWriter, r *http.Request, sessionID string) {
+	var req SquashSessionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	squash, err := s.runner.SquashSession(sessionID, req.Message)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, state.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, runner.ErrNothingToSquash), errors.Is(err, runner.ErrBranchPublished),
+			errors.Is(err, runner.ErrSessionBusy), errors.Is(err, runner.ErrDirtyWorktree),
+			errors.Is(err, runner.ErrWorktreePruned):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"session_id": sessionID,
+		"commit_sha": squash.SHA,
+		"message":    squash.Message,
+		"squashed":   squash.Squashed,
+	})
+}
+
+// RerunSetupRequest is the optional payload for POST /api/sessions/{id}/setup.
+type RerunSetupRequest struct {
+	// SetupCmd is the command to run. Empty uses the setup_cmd in the
+	// worktree's .fog.yaml.
+	SetupCmd string `json:"setup_cmd"`
+}
+
+func (s *Server) rerunSessionSetup(w http.ResponseWriter, r *http.Request, sessionID string) {
+	var req RerunSetupRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	res, err := s.runner.RerunSetup(sessionID, req.SetupCmd)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, state.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, runner.ErrNoSetupCmd), errors.Is(err, runner.ErrInvalidLaunch),
+			errors.Is(err, runner.ErrCommandNotAllowed):
+			status = http.StatusBadRequest
+		case errors.Is(err, runner.ErrSessionBusy), errors.Is(err, runner.ErrWorktreeMissing),
+			errors.Is(err, runner.ErrWorktreePruned):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"session_id": sessionID,
+		"setup_cmd":  res.Command,
+		"exit_code":  res.ExitCode,
+		"output":     res.Output,
+	})
+}
+
+// acceptSessionRun marks ?run=<id> as the session's accepted result. Only a
+// completed run of the session can be accepted; an empty run clears the mark.
+func (s *Server) acceptSessionRun(w http.ResponseWriter, r *http.Request, sessionID string) {
+	runID := strings.TrimSpace(r.URL.Query().Get("run"))
+	if _, found, err := s.stateStore.GetSession(sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	if runID != "" {
+		run, found, err := s.stateStore.GetRun(runID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found || run.SessionID != sessionID {
+			http.Error(w, "run not found in session", http.StatusNotFound)
+			return
+		}
+		if run.State != "COMPLETED" {
+			http.Error(w, fmt.Sprintf("run %s is %s; only completed runs can be accepted", run.ID, run.State), http.StatusConflict)
+			return
+		}
+	}
+
+	if err := s.stateStore.SetSessionAcceptedRun(sessionID, runID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.getSession(w, sessionID)
+}
+
+// UpdateSessionRequest is the payload for PATCH /api/sessions/{id}.
+type UpdateSessionRequest struct {
+	// Branch renames the session's branch. Refused once a PR is open.
+	Branch *string `json:"branch"`
+}
+
+func (s *Server) updateSession(w http.ResponseWriter, r *http.Request, sessionID string) {
+	var req UpdateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Branch == nil {
+		http.Error(w, "nothing to update: branch is required", http.StatusBadRequest)
+		return
+	}
+
+	_, err := s.runner.RenameSessionBranch(sessionID, *req.Branch)
+	switch {
+	case errors.Is(err, state.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, runner.ErrInvalidBranch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, runner.ErrBranchExists) || errors.Is(err, runner.ErrBranchPublished) || errors.Is(err, runner.ErrSessionBusy) ||
+		errors.Is(err, runner.ErrWorktreePruned):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.getSession(w, sessionID)
+}
+
+// NotifySlackRequest is the payload for POST /api/sessions/{id}/notify-slack.
+type NotifySlackRequest struct {
+	ChannelID string `json:"channel_id"`
+	// ThreadTS is the timestamp of the thread's root message. Empty posts to
+	// the channel itself.
+	ThreadTS string `json:"thread_ts"`
+}
+
+// setSessionSlackThread records where the session's run completion messages
+// go. fogd delivers them while Slack socket mode is running.
+func (s *Server) setSessionSlackThread(w http.ResponseWriter, r *http.Request, sessionID string) {
+	var req NotifySlackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.ChannelID) == "" {
+		http.Error(w, "channel_id is required", http.StatusBadRequest)
+		return
+	}
+	if err := s.stateStore.SetSessionSlackThread(sessionID, req.ChannelID, req.ThreadTS); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, state.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.getSession(w, sessionID)
+}
+
+// setSessionPaused serves POST /api/sessions/{id}/pause and /resume. Both are
+// idempotent, and neither touches a run already in flight.
+func (s *Server) setSessionPaused(w http.ResponseWriter, sessionID string, paused bool) {
+	if err := s.runner.SetSessionPaused(sessionID, paused); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, state.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.getSession(w, sessionID)
+}
+
+// setSessionArchived archives or unarchives a session by hand. Archiving hides
+// it from the default session list but keeps every record, and a direct fetch
+// still finds it. A session with a run in flight cannot be archived.
+func (s *Server) setSessionArchived(w http.ResponseWriter, sessionID string, archived bool) {
+	session, found, err := s.stateStore.GetSession(sessionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -640,17 +1426,73 @@ func (s *Server) openSessionWorktree(w http.ResponseWriter, sessionID string) {
 		return
 	}
 
-	worktreePath := strings.TrimSpace(session.WorktreePath)
-	if latest, found, err := s.stateStore.GetLatestRun(session.ID); err == nil && found && strings.TrimSpace(latest.WorktreePath) != "" {
-		worktreePath = strings.TrimSpace(latest.WorktreePath)
+	switch {
+	case archived && session.ArchivedAt != nil:
+		// Already archived; keep the original time.
+	case archived && session.Busy:
+		http.Error(w, "session has a run in progress", http.StatusConflict)
+		return
+	case archived:
+		err = s.runner.ArchiveSession(sessionID)
+	default:
+		err = s.runner.UnarchiveSession(sessionID)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, state.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.getSession(w, sessionID)
+}
+
+// OpenSessionRequest is the optional payload for POST /api/sessions/{id}/open.
+type OpenSessionRequest struct {
+	// Editor names the editor to open in, e.g. "tmux". Empty picks one
+	// for the session's tool.
+	Editor string `json:"editor"`
+}
+
+func (s *Server) openSessionWorktree(w http.ResponseWriter, r *http.Request, sessionID string) {
+	var req OpenSessionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
+
+	session, found, err := s.runner.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	worktreePath := s.sessionWorktreePath(session)
 	if worktreePath == "" {
 		http.Error(w, "session has no worktree path", http.StatusBadRequest)
 		return
 	}
+	if session.WorktreePrunedAt != nil {
+		http.Error(w, fmt.Sprintf("%v; check out branch %s to continue", runner.ErrWorktreePruned, session.Branch), http.StatusConflict)
+		return
+	}
 
-	pref := preferredEditorForTool(session.Tool)
-	ed, err := editor.Detect(pref)
+	pref, err := sessionEditorPreference(req.Editor, session.Tool)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ed, err := detectEditorFn(pref)
+	if err == nil && strings.TrimSpace(req.Editor) != "" && ed.Name() != pref {
+		err = fmt.Errorf("editor %s is not installed", pref)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -667,6 +1509,64 @@ func (s *Server) openSessionWorktree(w http.ResponseWriter, sessionID string) {
 	})
 }
 
+// sessionEditorResponse is the editor POST /open would use for a session.
+type sessionEditorResponse struct {
+	Editor       string `json:"editor,omitempty"`
+	Available    bool   `json:"available"`
+	WorktreePath string `json:"worktree_path,omitempty"`
+}
+
+// getSessionEditor resolves the editor openSessionWorktree would launch,
+// without launching it, so the UI can label its Open action or hide it.
+func (s *Server) getSessionEditor(w http.ResponseWriter, sessionID, preferred string) {
+	session, found, err := s.runner.GetSession(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	resp := sessionEditorResponse{WorktreePath: s.sessionWorktreePath(session)}
+	pref, err := sessionEditorPreference(preferred, session.Tool)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// A named editor that is not installed is unavailable, not replaced
+	if ed, err := detectEditorFn(pref); err == nil && (strings.TrimSpace(preferred) == "" || ed.Name() == pref) {
+		resp.Editor = ed.Name()
+		resp.Available = true
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// sessionWorktreePath is where a session's work lives: the latest run's
+// worktree when it recorded one, else the session's.
+func (s *Server) sessionWorktreePath(session state.Session) string {
+	worktreePath := strings.TrimSpace(session.WorktreePath)
+	if latest, found, err := s.stateStore.GetLatestRun(session.ID); err == nil && found && strings.TrimSpace(latest.WorktreePath) != "" {
+		worktreePath = strings.TrimSpace(latest.WorktreePath)
+	}
+	return worktreePath
+}
+
+// sessionEditorPreference is the editor to try first for a session: the one
+// the caller asked for, by any of its names, else the one that matches the
+// session's tool.
+func sessionEditorPreference(requested, toolName string) (string, error) {
+	if requested = strings.TrimSpace(requested); requested != "" {
+		ed := editor.GetEditor(requested)
+		if ed == nil {
+			return "", fmt.Errorf("unknown editor %q", requested)
+		}
+		return ed.Name(), nil
+	}
+	return preferredEditorForTool(toolName), nil
+}
+
 func preferredEditorForTool(toolName string) string {
 	switch strings.TrimSpace(toolName) {
 	case "cursor":
@@ -3,12 +3,18 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/darkLord19/foglet/internal/branchname"
+	"github.com/darkLord19/foglet/internal/editor"
+	"github.com/darkLord19/foglet/internal/runner"
 	"github.com/darkLord19/foglet/internal/state"
 )
 
@@ -25,15 +31,69 @@ func TestHandleSessionsGet(t *testing.T) {
 		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
 	}
 
-	var sessions []state.Session
-	if err := json.NewDecoder(w.Body).Decode(&sessions); err != nil {
+	var resp sessionListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
 		t.Fatalf("decode sessions failed: %v", err)
 	}
-	if len(sessions) != 1 {
-		t.Fatalf("unexpected session count: got %d want 1", len(sessions))
+	if len(resp.Sessions) != 1 || resp.Total != 1 {
+		t.Fatalf("unexpected session count: got %d (total %d) want 1", len(resp.Sessions), resp.Total)
 	}
-	if sessions[0].RepoName != "acme/api" {
-		t.Fatalf("unexpected repo name in response: %q", sessions[0].RepoName)
+	if resp.Sessions[0].RepoName != "acme/api" {
+		t.Fatalf("unexpected repo name in response: %q", resp.Sessions[0].RepoName)
+	}
+	if resp.Sessions[0].LatestRun == nil || resp.Sessions[0].LatestRun.ID != "run-1" {
+		t.Fatalf("unexpected latest run: %+v", resp.Sessions[0].LatestRun)
+	}
+}
+
+func TestHandleSessionsGetFiltersAndPages(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	now := time.Now().UTC()
+	for i, id := range []string{"session-2", "session-3"} {
+		ts := now.Add(time.Duration(i+1) * time.Minute)
+		if err := srv.stateStore.CreateSession(state.Session{
+			ID:           id,
+			RepoName:     "acme/api",
+			Branch:       "team/" + id,
+			WorktreePath: "/tmp/acme-api/" + id,
+			Tool:         "claude",
+			Status:       "FAILED",
+			CreatedAt:    ts,
+			UpdatedAt:    ts,
+		}); err != nil {
+			t.Fatalf("create session failed: %v", err)
+		}
+	}
+
+	list := func(query string) sessionListResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/sessions"+query, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessions(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: got %d body=%s", query, w.Code, w.Body.String())
+		}
+		var resp sessionListResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode sessions failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := list("?status=failed&limit=1&offset=1")
+	if resp.Total != 2 || len(resp.Sessions) != 1 || resp.Sessions[0].ID != "session-2" {
+		t.Fatalf("failed page 2 = %+v", resp)
+	}
+	if resp := list("?repo=acme/other"); resp.Total != 0 || len(resp.Sessions) != 0 {
+		t.Fatalf("other repo = %+v", resp)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions?limit=-1", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessions(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("negative limit: got %d want 400", w.Code)
 	}
 }
 
@@ -86,6 +146,76 @@ func TestHandleSessionDiffRoute(t *testing.T) {
 	}
 }
 
+func TestHandleSessionDiffRejectsBadIncludeUntracked(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/diff?include_untracked=maybe", nil)
+	w := httptest.NewRecorder()
+
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "include_untracked") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleSessionCreatePRRoute(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	if err := srv.stateStore.SetSessionPRURL("session-1", "https://example.invalid/pr/1"); err != nil {
+		t.Fatalf("set pr url: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/create-pr", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusConflict, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/sessions/missing/create-pr", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+}
+
+func TestRequestOrigin(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions", nil)
+	if got := requestOrigin(req); got != "api" {
+		t.Fatalf("no header: got %q want api", got)
+	}
+	req.Header.Set(clientHeader, "Desktop")
+	if got := requestOrigin(req); got != "desktop" {
+		t.Fatalf("desktop header: got %q want desktop", got)
+	}
+	req.Header.Set(clientHeader, "something-else")
+	if got := requestOrigin(req); got != "api" {
+		t.Fatalf("unknown client: got %q want api", got)
+	}
+}
+
+func TestHandleSessionCommitsRoute(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	// The fixture's worktree does not exist on disk, so git log fails.
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/commits", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/sessions/missing/commits", nil)
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+}
+
 func TestHandleSessionOpenRoute(t *testing.T) {
 	srv := newTestServer(t)
 	seedSessionFixture(t, srv)
@@ -163,7 +293,7 @@ func TestResolveBranchNameUsesPrefixAndSlugifiesPrompt(t *testing.T) {
 	runGit(t, repoPath, "config", "user.name", "Test User")
 	runGit(t, repoPath, "commit", "--allow-empty", "-m", "init")
 
-	branch, err := srv.runner.ResolveBranch(repoPath, "", "Add OTP Login!!")
+	branch, err := srv.runner.ResolveBranch(repoPath, "", "Add OTP Login!!", branchname.PrefixVars{})
 	if err != nil {
 		t.Fatalf("ResolveBranch failed: %v", err)
 	}
@@ -172,6 +302,39 @@ func TestResolveBranchNameUsesPrefixAndSlugifiesPrompt(t *testing.T) {
 	}
 }
 
+func TestResolveBranchNameExpandsPrefixTokens(t *testing.T) {
+	srv := newTestServer(t)
+	if err := srv.stateStore.SetSetting("branch_prefix", "{user}/{tool}"); err != nil {
+		t.Fatalf("set branch prefix failed: %v", err)
+	}
+
+	repoPath := t.TempDir()
+	runGit(t, repoPath, "init")
+	runGit(t, repoPath, "config", "user.email", "test@example.com")
+	runGit(t, repoPath, "config", "user.name", "Test User")
+	runGit(t, repoPath, "commit", "--allow-empty", "-m", "init")
+
+	if _, err := srv.runner.ResolveBranch(repoPath, "", "Add login", branchname.PrefixVars{Tool: "claude"}); err == nil || !strings.Contains(err.Error(), "{user}") {
+		t.Fatalf("err = %v, want a missing {user} error", err)
+	}
+	// An explicit branch name never needs the prefix.
+	if branch, err := srv.runner.ResolveBranch(repoPath, "fix/login", "Add login", branchname.PrefixVars{}); err != nil || branch != "fix/login" {
+		t.Fatalf("explicit branch = %q, %v", branch, err)
+	}
+
+	if err := srv.stateStore.SetSetting(runner.SettingDefaultUser, "Alice"); err != nil {
+		t.Fatalf("set default user failed: %v", err)
+	}
+	branch, err := srv.runner.ResolveBranch(repoPath, "", "Add login", branchname.PrefixVars{Tool: "claude"})
+	if err != nil || branch != "alice/claude/add-login" {
+		t.Fatalf("branch = %q, %v; want default_user filled in", branch, err)
+	}
+	branch, err = srv.runner.ResolveBranch(repoPath, "", "Add login", branchname.PrefixVars{User: "bob", Tool: "cursor"})
+	if err != nil || branch != "bob/cursor/add-login" {
+		t.Fatalf("branch = %q, %v; want the request's user", branch, err)
+	}
+}
+
 func TestResolveBranchNameRejectsInvalidSequences(t *testing.T) {
 	srv := newTestServer(t)
 
@@ -181,7 +344,7 @@ func TestResolveBranchNameRejectsInvalidSequences(t *testing.T) {
 	runGit(t, repoPath, "config", "user.name", "Test User")
 	runGit(t, repoPath, "commit", "--allow-empty", "-m", "init")
 
-	_, err := srv.runner.ResolveBranch(repoPath, "feature//bad", "")
+	_, err := srv.runner.ResolveBranch(repoPath, "feature//bad", "", branchname.PrefixVars{})
 	if err == nil {
 		t.Fatal("expected validation error")
 	}
@@ -253,7 +416,7 @@ func TestResolveBranchName_Unique(t *testing.T) {
 	// 3. Call ResolveBranch with a prompt that produces "task-collision" slug
 	prompt := "Task Collision"
 
-	uniqueName, err := srv.runner.ResolveBranch(repoPath, "", prompt)
+	uniqueName, err := srv.runner.ResolveBranch(repoPath, "", prompt, branchname.PrefixVars{})
 	if err != nil {
 		t.Fatalf("ResolveBranch failed: %v", err)
 	}
@@ -296,3 +459,640 @@ func TestCancelSessionWithoutActiveRunReturns400(t *testing.T) {
 		t.Fatalf("status = %d, want %d (body=%s)", w.Code, http.StatusBadRequest, w.Body.String())
 	}
 }
+
+type stubEditor struct{ name string }
+
+func (e stubEditor) Name() string            { return e.name }
+func (e stubEditor) IsAvailable() bool       { return true }
+func (e stubEditor) Open(string, bool) error { return nil }
+
+func TestGetSessionEditor(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	orig := detectEditorFn
+	t.Cleanup(func() { detectEditorFn = orig })
+
+	var gotPreferred string
+	detectEditorFn = func(preferred string) (editor.Editor, error) {
+		gotPreferred = preferred
+		return stubEditor{name: "claudecode"}, nil
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/editor", nil)
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp sessionEditorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Available || resp.Editor != "claudecode" || gotPreferred != "claudecode" {
+		t.Fatalf("unexpected response %+v (preferred %q)", resp, gotPreferred)
+	}
+	if resp.WorktreePath != "/tmp/acme-api/worktree-run-1" {
+		t.Errorf("worktree_path = %q, want the latest run's", resp.WorktreePath)
+	}
+
+	detectEditorFn = func(string) (editor.Editor, error) { return nil, errors.New("no editor found") }
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/editor", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"available":false`) {
+		t.Fatalf("no-editor response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, "/api/sessions/missing/editor", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("missing session status = %d, want 404", w.Code)
+	}
+}
+
+func TestOpenSessionWorktreeHonorsRequestedEditor(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	orig := detectEditorFn
+	t.Cleanup(func() { detectEditorFn = orig })
+
+	installed := "tmux"
+	var gotPreferred string
+	detectEditorFn = func(preferred string) (editor.Editor, error) {
+		gotPreferred = preferred
+		return stubEditor{name: installed}, nil
+	}
+	open := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/open", strings.NewReader(body)))
+		return w
+	}
+
+	if w := open(`{"editor":"tmux"}`); w.Code != http.StatusOK || gotPreferred != "tmux" || !strings.Contains(w.Body.String(), `"editor":"tmux"`) {
+		t.Fatalf("open in tmux: %d %s (preferred %q)", w.Code, w.Body.String(), gotPreferred)
+	}
+	if w := open(`{"editor":"emacs"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown editor status = %d, want 400", w.Code)
+	}
+
+	// Detect falls back to another editor when the named one is missing
+	installed = "vim"
+	if w := open(`{"editor":"zellij"}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "not installed") {
+		t.Fatalf("missing zellij: %d %s", w.Code, w.Body.String())
+	}
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, "/api/sessions/session-1/editor?editor=zellij", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"available":false`) {
+		t.Fatalf("editor?editor=zellij: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetRunDiffRequiresAFinishedRunInTheSession(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/api/sessions/session-1/runs/run-1/diff"); w.Code != http.StatusNotFound {
+		t.Fatalf("unfinished run status = %d, want 404", w.Code)
+	}
+	if w := get("/api/sessions/session-1/runs/missing/diff"); w.Code != http.StatusNotFound {
+		t.Fatalf("missing run status = %d, want 404", w.Code)
+	}
+
+	if err := srv.stateStore.CompleteRun("run-1", "COMPLETED", "", "", ""); err != nil {
+		t.Fatalf("complete run: %v", err)
+	}
+	w := get("/api/sessions/session-1/runs/run-1/diff")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp runDiffResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.RunID != "run-1" || resp.CommitSHA != "" || resp.Stat != "" || resp.Patch != "" {
+		t.Fatalf("run without a commit: %+v, want an empty diff", resp)
+	}
+}
+
+func TestAcceptSessionRun(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	accept := func(runID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions/session-1/accept?run="+runID, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w
+	}
+
+	if w := accept("run-1"); w.Code != http.StatusConflict {
+		t.Fatalf("accepting an unfinished run: got %d, want 409", w.Code)
+	}
+	if w := accept("run-missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("accepting an unknown run: got %d, want 404", w.Code)
+	}
+
+	if err := srv.stateStore.CompleteRun("run-1", "COMPLETED", "abc123", "feat: done", ""); err != nil {
+		t.Fatalf("complete run: %v", err)
+	}
+	w := accept("run-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	var out sessionDetailResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if out.Session.AcceptedRunID != "run-1" {
+		t.Fatalf("accepted_run_id = %q, want run-1", out.Session.AcceptedRunID)
+	}
+
+	if w := accept(""); w.Code != http.StatusOK {
+		t.Fatalf("clearing the accepted run: got %d", w.Code)
+	}
+	session, _, _ := srv.stateStore.GetSession("session-1")
+	if session.AcceptedRunID != "" {
+		t.Fatalf("accepted_run_id not cleared: %q", session.AcceptedRunID)
+	}
+}
+
+func TestDeleteSessionRun(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	del := func(runID string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1/runs/"+runID, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w.Code
+	}
+
+	if code := del("run-missing"); code != http.StatusNotFound {
+		t.Fatalf("deleting an unknown run: got %d, want 404", code)
+	}
+	if code := del("run-1"); code != http.StatusConflict {
+		t.Fatalf("deleting an unfinished run: got %d, want 409", code)
+	}
+
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateRun(state.Run{
+		ID: "run-2", SessionID: "session-1", Prompt: "again",
+		WorktreePath: "/tmp/acme-api/worktree-run-2", State: "COMPLETED",
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if err := srv.stateStore.CompleteRun("run-1", "FAILED", "", "", "boom"); err != nil {
+		t.Fatalf("complete run: %v", err)
+	}
+	if code := del("run-1"); code != http.StatusNoContent {
+		t.Fatalf("deleting a finished run: got %d, want 204", code)
+	}
+	if code := del("run-2"); code != http.StatusConflict {
+		t.Fatalf("deleting the only remaining run: got %d, want 409", code)
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	srv := newTestServer(t)
+	base := filepath.Join(t.TempDir(), "base")
+	runGit(t, t.TempDir(), "init", base)
+	runGit(t, base, "config", "user.email", "test@example.com")
+	runGit(t, base, "config", "user.name", "Test User")
+	runGit(t, base, "commit", "--allow-empty", "-m", "init")
+	wt := filepath.Join(t.TempDir(), "wt")
+	runGit(t, base, "worktree", "add", "-b", "fog/delete-me", wt)
+
+	if _, err := srv.stateStore.UpsertRepo(state.Repo{
+		Name: "acme/api", URL: "https://github.com/acme/api.git",
+		Host: "github.com", Owner: "acme", Repo: "api",
+		BarePath: filepath.Join(base, ".git"), BaseWorktreePath: base,
+	}); err != nil {
+		t.Fatalf("upsert repo: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateSession(state.Session{
+		ID: "session-1", RepoName: "acme/api", Branch: "fog/delete-me",
+		WorktreePath: wt, Tool: "claude", Status: "RUNNING", Busy: true,
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	del := func(query string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/sessions/session-1"+query, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w.Code
+	}
+
+	if code := del(""); code != http.StatusConflict {
+		t.Fatalf("deleting a busy session: got %d, want 409", code)
+	}
+	if err := srv.stateStore.SetSessionBusy("session-1", false); err != nil {
+		t.Fatalf("set busy: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wt, "wip.txt"), []byte("unsaved"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if code := del(""); code != http.StatusConflict {
+		t.Fatalf("deleting a dirty worktree: got %d, want 409", code)
+	}
+	if code := del("?force=true"); code != http.StatusNoContent {
+		t.Fatalf("force delete: got %d, want 204", code)
+	}
+
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Fatalf("worktree still on disk: %v", err)
+	}
+	if _, found, _ := srv.stateStore.GetSession("session-1"); found {
+		t.Fatal("session row was not deleted")
+	}
+	if got := runGit(t, base, "branch", "--list", "fog/delete-me"); got == "" {
+		t.Fatal("branch should be kept")
+	}
+	if code := del(""); code != http.StatusNotFound {
+		t.Fatalf("deleting a deleted session: got %d, want 404", code)
+	}
+}
+
+func TestSetSessionSlackThread(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	post := func(sessionID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions/"+sessionID+"/notify-slack", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w
+	}
+
+	if w := post("session-1", `{"thread_ts":"111.222"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing channel_id: got %d, want 400", w.Code)
+	}
+	if w := post("missing", `{"channel_id":"C123"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", w.Code)
+	}
+
+	w := post("session-1", `{"channel_id":"C123","thread_ts":"111.222"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+	var out sessionDetailResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if out.Session.SlackChannelID != "C123" || out.Session.SlackThreadTS != "111.222" {
+		t.Fatalf("unexpected slack routing: %+v", out.Session)
+	}
+}
+
+func TestPauseAndResumeSession(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) state.Session {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+		}
+		var out sessionDetailResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return out.Session
+	}
+
+	if w := post("/api/sessions/missing/pause", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", w.Code)
+	}
+	if session := decode(post("/api/sessions/session-1/pause", "")); !session.Paused {
+		t.Fatal("expected session to be paused")
+	}
+
+	w := post("/api/sessions/session-1/runs", `{"prompt":"carry on"}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("follow-up on paused session: got %d, want 409 body=%s", w.Code, w.Body.String())
+	}
+	w = post("/api/sessions/session-1/fork", `{"prompt":"try another way","branch_name":"fog/fork"}`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("fork of paused session: got %d, want 409 body=%s", w.Code, w.Body.String())
+	}
+
+	if session := decode(post("/api/sessions/session-1/resume", "")); session.Paused {
+		t.Fatal("expected session to be resumed")
+	}
+}
+
+func TestArchiveAndUnarchiveSession(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w
+	}
+	listed := func() int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.handleSessions(w, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+		var out sessionListResponse
+		if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+			t.Fatalf("decode list failed: %v", err)
+		}
+		return len(out.Sessions)
+	}
+
+	if w := post("/api/sessions/missing/archive"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", w.Code)
+	}
+	for range 2 {
+		if w := post("/api/sessions/session-1/archive"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"archived_at"`) {
+			t.Fatalf("archive: got %d body=%s", w.Code, w.Body.String())
+		}
+	}
+	if n := listed(); n != 0 {
+		t.Fatalf("listed %d sessions after archiving, want 0", n)
+	}
+	// A direct fetch still finds it.
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, "/api/sessions/session-1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get archived session: got %d", w.Code)
+	}
+
+	if w := post("/api/sessions/session-1/unarchive"); w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"archived_at"`) {
+		t.Fatalf("unarchive: got %d body=%s", w.Code, w.Body.String())
+	}
+	if n := listed(); n != 1 {
+		t.Fatalf("listed %d sessions after unarchiving, want 1", n)
+	}
+
+	if err := srv.stateStore.SetSessionBusy("session-1", true); err != nil {
+		t.Fatalf("mark busy: %v", err)
+	}
+	if w := post("/api/sessions/session-1/archive"); w.Code != http.StatusConflict {
+		t.Fatalf("archive busy session: got %d, want 409", w.Code)
+	}
+}
+
+func TestSessionWorktreePresent(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	// The fixture's worktree path is not on disk; session-2's is.
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateSession(state.Session{
+		ID: "session-2", RepoName: "acme/api", Branch: "team/fix-login", WorktreePath: t.TempDir(),
+		Tool: "claude", Status: "COMPLETED", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	present := func() map[string]bool {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.handleSessions(w, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+		var list sessionListResponse
+		if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+			t.Fatalf("list sessions: %v", err)
+		}
+		out := map[string]bool{}
+		for _, sess := range list.Sessions {
+			out[sess.ID] = sess.WorktreePresent
+		}
+		w = httptest.NewRecorder()
+		srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, "/api/sessions/session-2", nil))
+		var detail sessionDetailResponse
+		if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
+			t.Fatalf("get session: %v", err)
+		}
+		out["detail"] = detail.WorktreePresent
+		return out
+	}
+
+	if got := present(); got["session-1"] || !got["session-2"] || !got["detail"] {
+		t.Fatalf("worktree_present = %v, want only session-2", got)
+	}
+	if err := srv.stateStore.MarkSessionWorktreePruned("session-2"); err != nil {
+		t.Fatalf("mark pruned: %v", err)
+	}
+	if got := present(); got["session-2"] || got["detail"] {
+		t.Fatalf("worktree_present after pruning = %v, want false", got)
+	}
+
+	w := httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodPost, "/api/sessions/session-2/open", nil))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "team/fix-login") {
+		t.Fatalf("open pruned session: got %d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodPost, "/api/sessions/session-2/runs", strings.NewReader(`{"prompt":"more","async":false}`)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("follow-up on pruned session: got %d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodPost, "/api/sessions/session-2/fork", strings.NewReader(`{"prompt":"again","branch_name":"team/fix-login-2","async":false}`)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("fork pruned session: got %d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, "/api/sessions/session-2/diff", nil))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), runner.ErrWorktreePruned.Error()) {
+		t.Fatalf("diff of pruned session: got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestRetrySessionRunRejectsUnretryableRuns(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	post := func(path string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w.Code
+	}
+
+	if code := post("/api/sessions/session-1/runs/missing/retry"); code != http.StatusNotFound {
+		t.Fatalf("unknown run: got %d, want 404", code)
+	}
+	// run-1 has not failed.
+	if code := post("/api/sessions/session-1/runs/run-1/retry"); code != http.StatusConflict {
+		t.Fatalf("unfailed run: got %d, want 409", code)
+	}
+	if err := srv.stateStore.SetRunState("run-1", "FAILED"); err != nil {
+		t.Fatalf("set run state: %v", err)
+	}
+	if err := srv.stateStore.SetSessionBusy("session-1", true); err != nil {
+		t.Fatalf("set busy: %v", err)
+	}
+	if code := post("/api/sessions/session-1/runs/run-1/retry"); code != http.StatusConflict {
+		t.Fatalf("busy session: got %d, want 409", code)
+	}
+}
+
+func TestUpdateSessionBranchRefusals(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	patch := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w.Code
+	}
+
+	if code := patch("/api/sessions/session-1", `{}`); code != http.StatusBadRequest {
+		t.Fatalf("empty patch: got %d, want 400", code)
+	}
+	if code := patch("/api/sessions/missing", `{"branch":"team/new"}`); code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", code)
+	}
+	if code := patch("/api/sessions/session-1", `{"branch":"team/bad name"}`); code != http.StatusBadRequest {
+		t.Fatalf("invalid branch: got %d, want 400", code)
+	}
+	if err := srv.stateStore.SetSessionPRURL("session-1", "https://github.com/acme/api/pull/1"); err != nil {
+		t.Fatalf("set pr url: %v", err)
+	}
+	if code := patch("/api/sessions/session-1", `{"branch":"team/new"}`); code != http.StatusConflict {
+		t.Fatalf("session with a PR: got %d, want 409", code)
+	}
+}
+
+func TestSquashSessionRefusals(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, req)
+		return w.Code
+	}
+
+	if code := post("/api/sessions/session-1/squash", `{"message":`); code != http.StatusBadRequest {
+		t.Fatalf("malformed body: got %d, want 400", code)
+	}
+	if code := post("/api/sessions/missing/squash", ``); code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", code)
+	}
+	if err := srv.stateStore.SetSessionPRURL("session-1", "https://github.com/acme/api/pull/1"); err != nil {
+		t.Fatalf("set pr url: %v", err)
+	}
+	if code := post("/api/sessions/session-1/squash", `{"message":"feat: all of it"}`); code != http.StatusConflict {
+		t.Fatalf("session with a PR: got %d, want 409", code)
+	}
+}
+
+func TestRerunSessionSetup(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateSession(state.Session{
+		ID: "session-2", RepoName: "acme/api", Branch: "team/fix-login", WorktreePath: t.TempDir(),
+		Tool: "claude", Status: "COMPLETED", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := post("/api/sessions/session-2/setup", `{"setup_cmd":"echo installed"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("setup: got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		SetupCmd string `json:"setup_cmd"`
+		ExitCode int    `json:"exit_code"`
+		Output   string `json:"output"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.SetupCmd != "echo installed" || resp.ExitCode != 0 || strings.TrimSpace(resp.Output) != "installed" {
+		t.Fatalf("setup response = %+v", resp)
+	}
+	if w := post("/api/sessions/session-2/setup", `{"setup_cmd":"false"}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"exit_code":1`) {
+		t.Fatalf("failing setup: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	for _, tc := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/api/sessions/session-2/setup", `{"setup_cmd":`, http.StatusBadRequest},
+		{"/api/sessions/session-2/setup", `{"setup_cmd":"npm ci && npm test"}`, http.StatusBadRequest},
+		// No command given and no .fog.yaml in the worktree.
+		{"/api/sessions/session-2/setup", ``, http.StatusBadRequest},
+		{"/api/sessions/missing/setup", `{"setup_cmd":"npm ci"}`, http.StatusNotFound},
+		// The fixture's worktree is not on disk.
+		{"/api/sessions/session-1/setup", `{"setup_cmd":"npm ci"}`, http.StatusConflict},
+	} {
+		if w := post(tc.path, tc.body); w.Code != tc.want {
+			t.Errorf("POST %s %s: got %d, want %d body=%s", tc.path, tc.body, w.Code, tc.want, w.Body.String())
+		}
+	}
+
+	if err := srv.stateStore.SetSessionBusy("session-2", true); err != nil {
+		t.Fatalf("mark busy: %v", err)
+	}
+	if w := post("/api/sessions/session-2/setup", `{"setup_cmd":"echo again"}`); w.Code != http.StatusConflict {
+		t.Fatalf("busy session: got %d, want 409", w.Code)
+	}
+}
+
+func TestListSessionChildren(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+	now := time.Now().UTC()
+	if err := srv.stateStore.CreateSession(state.Session{
+		ID: "session-2", RepoName: "acme/api", Branch: "team/otp-sms", WorktreePath: "/tmp/acme-api/fork",
+		Tool: "claude", Status: "CREATED", ParentSessionID: "session-1", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create fork failed: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.handleSessionDetail(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/sessions/session-1/children")
+	if w.Code != http.StatusOK {
+		t.Fatalf("children: got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Sessions []sessionSummary `json:"sessions"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode children: %v", err)
+	}
+	if len(resp.Sessions) != 1 || resp.Sessions[0].ID != "session-2" || resp.Sessions[0].ParentSessionID != "session-1" {
+		t.Fatalf("children = %+v, want session-2", resp.Sessions)
+	}
+	if w := get("/api/sessions/session-2/children"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"sessions":[]`) {
+		t.Fatalf("fork without children: got %d body=%s", w.Code, w.Body.String())
+	}
+	if w := get("/api/sessions/missing/children"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: got %d, want 404", w.Code)
+	}
+	if w := get("/api/sessions/session-2"); !strings.Contains(w.Body.String(), `"parent_session_id":"session-1"`) {
+		t.Fatalf("fork detail lacks parent_session_id: %s", w.Body.String())
+	}
+}
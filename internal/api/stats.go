@@ -0,0 +1,57 @@
+package api
+
+import "net/http"
+
+// handleStats returns aggregate counts for a dashboard header, so clients need
+// not fetch every session just to count them.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := s.stateStore.Stats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, stats)
+}
+
+// runnerStatsResponse is the runner's load: runs holding a slot, runs waiting
+// for one, and the background queue the async endpoints fill.
+type runnerStatsResponse struct {
+	ActiveRuns        int `json:"active_runs"`
+	QueuedRuns        int `json:"queued_runs"`
+	MaxConcurrentRuns int `json:"max_concurrent_runs"`
+	QueueDepth        int `json:"queue_depth"`
+	MaxQueuedRuns     int `json:"max_queued_runs"`
+}
+
+// handleRunnerStats serves GET /api/runner/stats. Unlike /api/stats it reads
+// the daemon's memory, not the database, so it covers only this process.
+func (s *Server) handleRunnerStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := s.runner.Stats()
+	s.writeJSON(w, http.StatusOK, runnerStatsResponse{
+		ActiveRuns:        stats.ActiveRuns,
+		QueuedRuns:        stats.QueuedRuns,
+		MaxConcurrentRuns: stats.MaxConcurrentRuns,
+		QueueDepth:        stats.QueueDepth,
+		MaxQueuedRuns:     stats.MaxQueuedRuns,
+	})
+}
+
+// handleQueueDrain serves POST /api/queue/drain: every run waiting for a run
+// slot is cancelled, across sessions, and runs already going are left alone.
+func (s *Server) handleQueueDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]int{"drained": s.runner.DrainQueue()})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestHandleStatsGet(t *testing.T) {
+	srv := newTestServer(t)
+	seedSessionFixture(t, srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	w := httptest.NewRecorder()
+
+	srv.handleStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var stats state.Stats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("decode stats failed: %v", err)
+	}
+	if stats.Sessions != 1 || stats.Runs != 1 || stats.Repos != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.SessionsByStatus["CREATED"] != 1 {
+		t.Fatalf("unexpected sessions_by_status: %v", stats.SessionsByStatus)
+	}
+}
+
+func TestHandleStatsRejectsPost(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/stats", nil)
+	w := httptest.NewRecorder()
+
+	srv.handleStats(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status: got %d want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandleRunnerStatsReportsLimits(t *testing.T) {
+	srv := newTestServer(t)
+	if err := srv.stateStore.SetSetting(runner.SettingMaxConcurrentRuns, "2"); err != nil {
+		t.Fatalf("set max_concurrent_runs: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/runner/stats", nil)
+	w := httptest.NewRecorder()
+	srv.handleRunnerStats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var stats runnerStatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("decode runner stats failed: %v", err)
+	}
+	if stats.MaxConcurrentRuns != 2 || stats.ActiveRuns != 0 || stats.QueuedRuns != 0 {
+		t.Fatalf("unexpected runner stats: %+v", stats)
+	}
+}
+
+func TestHandleQueueDrain(t *testing.T) {
+	srv := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	srv.handleQueueDrain(w, httptest.NewRequest(http.MethodGet, "/api/queue/drain", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleQueueDrain(w, httptest.NewRequest(http.MethodPost, "/api/queue/drain", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d want %d body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp map[string]int
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode drain response failed: %v", err)
+	}
+	if drained, ok := resp["drained"]; !ok || drained != 0 {
+		t.Fatalf("drain of an empty queue = %v, want drained 0", resp)
+	}
+}
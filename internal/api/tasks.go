@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -103,7 +103,7 @@ func (s *Server) handleTaskDetail(w http.ResponseWriter, r *http.Request) {
 			s.moveTask(w, r, id)
 			return
 		case "start":
-			s.startTask(w, id)
+			s.startTask(w, r, id)
 			return
 		case "restore":
 			s.restoreTask(w, id)
@@ -279,7 +279,7 @@ func (s *Server) purgeTask(w http.ResponseWriter, id string) {
 	s.stopSessionBestEffort(current.SessionID)
 	if current.SessionID != "" {
 		if err := s.runner.RemoveSessionArtifacts(current.SessionID); err != nil {
-			log.Printf("purge task %s: %v", id, err)
+			slog.Warn("purge task: remove session artifacts failed", "task_id", id, "session_id", current.SessionID, "err", err)
 		}
 	}
 
@@ -313,7 +313,7 @@ func (s *Server) stopSessionBestEffort(sessionID string) {
 	}
 	if _, err := s.runner.CancelSessionLatestRun(sessionID); err != nil {
 		// The common case: the session had no active run to cancel.
-		log.Printf("stop session %s on trash: %v", sessionID, err)
+		slog.Debug("stop session on trash", "session_id", sessionID, "err", err)
 	}
 }
 
@@ -361,7 +361,7 @@ func (s *Server) moveTask(w http.ResponseWriter, r *http.Request, id string) {
 
 	resp := TaskResponse{}
 	if kind, ok := task.AutoStarts(from, target, task.OriginLocal); ok {
-		sessionID, err := s.startTaskWork(id, kind)
+		sessionID, err := s.startTaskWork(id, kind, requestOrigin(r))
 		if err != nil {
 			// The move succeeded; only the launch failed. Report it without
 			// rolling the card back — the user can see it landed and retry.
@@ -388,7 +388,7 @@ func (s *Server) moveTask(w http.ResponseWriter, r *http.Request, id string) {
 //
 // The kind is inferred from the column the task currently sits in, so the
 // button does whatever the card's position implies.
-func (s *Server) startTask(w http.ResponseWriter, id string) {
+func (s *Server) startTask(w http.ResponseWriter, r *http.Request, id string) {
 	t, err := s.stateStore.GetTask(id)
 	if err != nil {
 		s.writeTaskErr(w, err)
@@ -400,7 +400,7 @@ func (s *Server) startTask(w http.ResponseWriter, id string) {
 		kind = task.WorkReview
 	}
 
-	sessionID, err := s.startTaskWork(id, kind)
+	sessionID, err := s.startTaskWork(id, kind, requestOrigin(r))
 	if err != nil {
 		s.writeTaskErr(w, err)
 		return
@@ -423,7 +423,7 @@ func (s *Server) startTask(w http.ResponseWriter, id string) {
 // which creates a branch and a worktree. Review must NOT do that — it has to
 // read the code the implementation just wrote, so it appends a follow-up run to
 // the existing session and inherits its worktree.
-func (s *Server) startTaskWork(taskID string, kind task.WorkKind) (string, error) {
+func (s *Server) startTaskWork(taskID string, kind task.WorkKind, origin string) (string, error) {
 	t, err := s.stateStore.GetTask(taskID)
 	if err != nil {
 		return "", err
@@ -451,6 +451,7 @@ func (s *Server) startTaskWork(taskID string, kind task.WorkKind) (string, error
 	// exposing them on a card needs a schema change.
 	session, _, err := s.runner.Launch(runner.LaunchRequest{
 		Entrypoint: "task",
+		Origin:     origin,
 		RepoName:   t.RepoName,
 		Prompt:     prompt,
 		Tool:       t.Tool,
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+// toolInfo is one entry of GET /api/tools.
+type toolInfo struct {
+	Name              string `json:"name"`
+	Available         bool   `json:"available"`
+	SupportsStreaming bool   `json:"supports_streaming"`
+	SupportsModels    bool   `json:"supports_models"`
+	SupportsResume    bool   `json:"supports_resume"`
+}
+
+// handleTools lists every tool Fog can drive, installed or not, with what its
+// adapter supports, so clients stop hardcoding the feature matrix.
+func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	names := ai.AvailableToolNames()
+	out := make([]toolInfo, 0, len(names))
+	for _, name := range names {
+		tool, err := ai.GetTool(name)
+		if err != nil {
+			continue
+		}
+		caps := tool.Capabilities()
+		out = append(out, toolInfo{
+			Name:              tool.Name(),
+			Available:         tool.IsAvailable(),
+			SupportsStreaming: caps.Streaming,
+			SupportsModels:    caps.Models,
+			SupportsResume:    caps.Resume,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+func TestHandleTools(t *testing.T) {
+	srv := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	srv.handleTools(w, httptest.NewRequest(http.MethodGet, "/api/tools", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var tools []toolInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &tools); err != nil {
+		t.Fatalf("decode tools: %v", err)
+	}
+	if len(tools) != len(ai.AvailableToolNames()) {
+		t.Fatalf("got %d tools, want every supported tool: %+v", len(tools), tools)
+	}
+	for _, tool := range tools {
+		if !tool.SupportsStreaming || !tool.SupportsModels || !tool.SupportsResume {
+			t.Errorf("unexpected capabilities for %s: %+v", tool.Name, tool)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	srv.handleTools(w, httptest.NewRequest(http.MethodPost, "/api/tools", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: got %d, want 405", w.Code)
+	}
+}
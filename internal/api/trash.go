@@ -2,7 +2,7 @@ package api
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"strconv"
 	"time"
 )
@@ -39,9 +39,9 @@ func (s *Server) trashRetentionDays() int {
 // passes to app.Build.
 func (s *Server) StartTrashJanitor(ctx context.Context) {
 	if n, err := s.purgeExpiredTrash(); err != nil {
-		log.Printf("trash janitor: initial purge failed: %v", err)
+		slog.Error("trash janitor: initial purge failed", "err", err)
 	} else if n > 0 {
-		log.Printf("trash janitor: purged %d expired task(s)", n)
+		slog.Info("trash janitor: purged expired tasks", "count", n)
 	}
 
 	go func() {
@@ -53,9 +53,9 @@ func (s *Server) StartTrashJanitor(ctx context.Context) {
 				return
 			case <-ticker.C:
 				if n, err := s.purgeExpiredTrash(); err != nil {
-					log.Printf("trash janitor: purge failed: %v", err)
+					slog.Error("trash janitor: purge failed", "err", err)
 				} else if n > 0 {
-					log.Printf("trash janitor: purged %d expired task(s)", n)
+					slog.Info("trash janitor: purged expired tasks", "count", n)
 				}
 			}
 		}
@@ -77,11 +77,11 @@ func (s *Server) purgeExpiredTrash() (int, error) {
 	for _, t := range expired {
 		if t.SessionID != "" {
 			if err := s.runner.RemoveSessionArtifacts(t.SessionID); err != nil {
-				log.Printf("trash janitor: task %s: %v", t.ID, err)
+				slog.Warn("trash janitor: remove session artifacts failed", "task_id", t.ID, "session_id", t.SessionID, "err", err)
 			}
 		}
 		if err := s.stateStore.DeleteTask(t.ID); err != nil {
-			log.Printf("trash janitor: delete task %s: %v", t.ID, err)
+			slog.Warn("trash janitor: delete task failed", "task_id", t.ID, "err", err)
 			continue
 		}
 		purged++
@@ -3,19 +3,24 @@ package app
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/darkLord19/foglet/internal/api"
+	"github.com/darkLord19/foglet/internal/ghcli"
 	"github.com/darkLord19/foglet/internal/runner"
 	"github.com/darkLord19/foglet/internal/state"
 )
 
 // App is the fully-wired application graph.
 type App struct {
-	Handler http.Handler // fully-wired middleware stack (auth + CORS + body limit + routes)
+	Handler http.Handler // fully-wired middleware stack (request log + CORS + body limit + auth + routes)
 	Runner  *runner.Runner
 	Store   *state.Store
 	mux     *http.ServeMux // for Mount()
+	api     *api.Server
 }
 
 // BuildOpts configures the application.
@@ -23,20 +28,43 @@ type BuildOpts struct {
 	FogHome string // the fog home directory (~/.fog)
 	Cwd     string // working directory / repo root
 	Port    int    // API server port
+	// DBRecover salvages readable rows from a corrupt database instead of
+	// only moving it aside and starting empty.
+	DBRecover bool
+	// DirMode and DBMode override the permissions of FogHome and fog.db.
+	// Zero keeps the store's 0700 and 0600 defaults.
+	DirMode os.FileMode
+	DBMode  os.FileMode
 }
 
 // Build constructs the full application graph and returns it.
 // Callers must call Close() when done.
 func Build(ctx context.Context, opts BuildOpts) (*App, error) {
 	// 1. Create state store
-	store, err := state.NewStore(opts.FogHome)
+	store, err := state.NewStoreWithOptions(opts.FogHome, state.StoreOptions{
+		DirMode:        opts.DirMode,
+		DBMode:         opts.DBMode,
+		RecoverCorrupt: true,
+		SalvageCorrupt: opts.DBRecover,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	// gh reads its binary path and token from settings and the secret store.
+	ghcli.SetConfigSource(ghcli.StoreConfigSource(store))
+
 	// 2. Create runner with state store
 	r := runner.New(store)
 	r.SetBaseContext(ctx)
+	r.SetRunLogDir(filepath.Join(opts.FogHome, "logs"))
+	// Nothing is running yet, so every busy session was left by the last
+	// daemon process.
+	if failed, err := r.RecoverOrphanedRuns(); err != nil {
+		slog.Error("recover interrupted runs", "err", err)
+	} else if len(failed) > 0 {
+		slog.Warn("marked runs interrupted by the last shutdown as FAILED", "count", len(failed))
+	}
 
 	// 3. Create API server
 	apiServer := api.New(r, store, opts.Port)
@@ -47,6 +75,10 @@ func Build(ctx context.Context, opts BuildOpts) (*App, error) {
 
 	// Sweep expired trash now and on an interval, tied to the app context.
 	apiServer.StartTrashJanitor(ctx)
+	// Enforce max_sessions_per_repo the same way.
+	apiServer.StartSessionJanitor(ctx)
+	// Remove worktrees of sessions finished longer than worktree_ttl_days.
+	r.StartWorktreeJanitor(ctx)
 
 	// 5. Generate API token and write to file (for desktop UI)
 	apiToken, err := api.GenerateAPIToken()
@@ -60,16 +92,23 @@ func Build(ctx context.Context, opts BuildOpts) (*App, error) {
 	}
 
 	// 6. Build middleware chain
-	handler := api.WithCORS(api.WithBodyLimit(api.WithAuth(apiToken, mux)))
+	handler := api.WithRequestLog(api.WithCORS(api.WithBodyLimit(api.WithAuth(apiToken, mux))))
 
 	return &App{
 		Handler: handler,
 		Runner:  r,
 		Store:   store,
 		mux:     mux,
+		api:     apiServer,
 	}, nil
 }
 
+// SetReady toggles the /ready endpoint. Build leaves it unready so callers can
+// finish wiring integrations first, and should clear it before shutting down.
+func (a *App) SetReady(ready bool) {
+	a.api.SetReady(ready)
+}
+
 // Mount registers an additional handler under the given prefix.
 // Routes added via Mount go through the same middleware chain as api routes.
 // This is the single attach point for MCP handlers, Slack webhooks, etc.
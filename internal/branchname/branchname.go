@@ -79,6 +79,67 @@ func Resolve(requested, prefix, prompt string, exists func(string) bool) (string
 	return Validate(withSuffix(base, "-"+strconv.FormatInt(time.Now().UnixNano(), 36)))
 }
 
+// PrefixVars are the values a prefix's tokens expand to.
+type PrefixVars struct {
+	// User fills {user}; it is required only by prefixes that use it.
+	User string
+	// Tool fills {tool}.
+	Tool string
+	// Date fills {date} as YYYY-MM-DD in UTC. Zero means now.
+	Date time.Time
+}
+
+var prefixToken = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// ExpandPrefix fills the {user}, {date} and {tool} tokens of prefix, so one
+// setting can express conventions such as "{user}/{date}". User and tool are
+// slugified like a prompt, so a display name still yields a legal ref. Any
+// other token is an error, as is {user} or {tool} with no value.
+func ExpandPrefix(prefix string, vars PrefixVars) (string, error) {
+	var err error
+	expanded := prefixToken.ReplaceAllStringFunc(prefix, func(token string) string {
+		var value string
+		switch name := token[1 : len(token)-1]; name {
+		case "user":
+			value = nonSlugChar.ReplaceAllString(strings.ToLower(strings.TrimSpace(vars.User)), "-")
+		case "tool":
+			value = nonSlugChar.ReplaceAllString(strings.ToLower(strings.TrimSpace(vars.Tool)), "-")
+		case "date":
+			date := vars.Date
+			if date.IsZero() {
+				date = time.Now()
+			}
+			return date.UTC().Format("2006-01-02")
+		default:
+			if err == nil {
+				err = fmt.Errorf("branch prefix has unknown token %s; use {user}, {date} or {tool}", token)
+			}
+			return token
+		}
+		if value = strings.Trim(value, "-"); value == "" && err == nil {
+			err = fmt.Errorf("branch prefix uses %s but no value is set for it", token)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// ValidatePrefix checks a prefix before it is stored: its tokens must be
+// known, and it must expand to a legal branch name.
+func ValidatePrefix(prefix string) error {
+	expanded, err := ExpandPrefix(strings.TrimSpace(prefix), PrefixVars{User: "user", Tool: "tool"})
+	if err != nil {
+		return err
+	}
+	if _, err := Validate(strings.Trim(expanded, "/") + "/x"); err != nil {
+		return fmt.Errorf("branch prefix: %w", err)
+	}
+	return nil
+}
+
 // Validate reports whether value is a legal git branch name, returning it trimmed.
 //
 // This is deliberately stricter than git itself: Fog interpolates branch names into
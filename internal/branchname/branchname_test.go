@@ -3,6 +3,7 @@ package branchname
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 // neverExists is the predicate for a repository with no branches.
@@ -246,6 +247,53 @@ func TestValidateTrimsSurroundingWhitespace(t *testing.T) {
 	}
 }
 
+func TestExpandPrefix(t *testing.T) {
+	vars := PrefixVars{User: "Alice Smith", Tool: "claude", Date: time.Date(2024, 6, 3, 23, 30, 0, 0, time.FixedZone("PDT", -7*3600))}
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr string
+	}{
+		{prefix: "fog", want: "fog"},
+		{prefix: "{user}/{date}", want: "alice-smith/2024-06-04"},
+		{prefix: "team/{tool}-{user}", want: "team/claude-alice-smith"},
+		{prefix: "{owner}/fog", wantErr: "unknown token {owner}"},
+		{prefix: "{}/fog", wantErr: "unknown token {}"},
+	}
+	for _, tc := range tests {
+		got, err := ExpandPrefix(tc.prefix, vars)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("ExpandPrefix(%q) err = %v, want %q", tc.prefix, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("ExpandPrefix(%q) = %q, %v; want %q", tc.prefix, got, err, tc.want)
+		}
+	}
+
+	if _, err := ExpandPrefix("{user}/fog", PrefixVars{User: " !! "}); err == nil || !strings.Contains(err.Error(), "no value is set") {
+		t.Errorf("err = %v, want a missing user error", err)
+	}
+	if got, err := ExpandPrefix("fog/{date}", PrefixVars{}); err != nil || got != "fog/"+time.Now().UTC().Format("2006-01-02") {
+		t.Errorf("zero date expanded to %q, %v; want today", got, err)
+	}
+}
+
+func TestValidatePrefix(t *testing.T) {
+	for _, ok := range []string{"fog", "{user}/{date}", "team/{tool}"} {
+		if err := ValidatePrefix(ok); err != nil {
+			t.Errorf("ValidatePrefix(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"{team}/x", "a..b", "fog:{user}", "{user}//x"} {
+		if err := ValidatePrefix(bad); err == nil {
+			t.Errorf("ValidatePrefix(%q) = nil, want an error", bad)
+		}
+	}
+}
+
 func truncateForMsg(s string) string {
 	if len(s) > 40 {
 		return s[:40] + "..."
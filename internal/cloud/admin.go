@@ -0,0 +1,133 @@
+package cloud
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type adminDevice struct {
+	DeviceID    string     `json:"device_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
+	Pairings    int        `json:"pairings"`
+	PendingJobs int        `json:"pending_jobs"`
+}
+
+// authorizeAdmin checks the admin bearer token. With no token configured the
+// admin API does not exist, so it answers 404 rather than advertising itself.
+func (s *Server) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.cfg.AdminToken == "" {
+		http.NotFound(w, r)
+		return false
+	}
+	auth := strings.TrimSpace(r.Header.Get("Authorization"))
+	if !strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return false
+	}
+	presented := strings.TrimSpace(auth[len("Bearer "):])
+	if subtle.ConstantTimeCompare([]byte(presented), []byte(s.cfg.AdminToken)) != 1 {
+		http.Error(w, "invalid admin token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleAdminDevices(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	devices, err := s.store.ListDevices()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminDevice, 0, len(devices))
+	for _, d := range devices {
+		out = append(out, adminDevice{
+			DeviceID:    d.DeviceID,
+			CreatedAt:   d.CreatedAt,
+			LastSeenAt:  d.LastSeenAt,
+			Pairings:    d.Pairings,
+			PendingJobs: d.PendingJobs,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"devices": out})
+}
+
+// handleAdminDeviceDetail serves DELETE /v1/admin/devices/{id}, which revokes
+// the device and tells the Slack threads of its outstanding jobs.
+func (s *Server) handleAdminDeviceDetail(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	deviceID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/admin/devices/"), "/")
+	if deviceID == "" || strings.Contains(deviceID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	revoked, err := s.store.RevokeDevice(deviceID)
+	if err != nil {
+		if errors.Is(err, errUnknownDevice) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, job := range revoked.FailedJobs {
+		s.forgetJobProgress(job.ID)
+		_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, "❌ Task failed: "+errDeviceRevoked)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"device_id":   revoked.DeviceID,
+		"pairings":    revoked.Pairings,
+		"failed_jobs": len(revoked.FailedJobs),
+	})
+}
+
+// handleAdminTeamDetail serves POST /v1/admin/teams/{team_id}/api-key, which
+// issues the team's API key for POST /v1/jobs. Issuing again rotates it.
+func (s *Server) handleAdminTeamDetail(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/admin/teams/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "api-key" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, err := s.store.IssueTeamAPIKey(parts[0])
+	if err != nil {
+		if errors.Is(err, errUnknownTeam) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"team_id": parts[0],
+		"api_key": key,
+	})
+}
@@ -0,0 +1,111 @@
+package cloud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminDevicesRequiresConfiguredToken(t *testing.T) {
+	store := newCloudStore(t)
+	defer func() { _ = store.Close() }()
+
+	mux := newAdminTestMux(t, store, "")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodGet, "/v1/admin/devices", "anything"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status with admin api disabled: got %d want %d", rec.Code, http.StatusNotFound)
+	}
+
+	mux = newAdminTestMux(t, store, "admin-secret")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodGet, "/v1/admin/devices", "wrong"))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status with wrong token: got %d want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAdminListAndRevokeDevice(t *testing.T) {
+	store := newCloudStore(t)
+	defer func() { _ = store.Close() }()
+	pairTestDevice(t, store, "T1", "U1", "device-a")
+
+	mux := newAdminTestMux(t, store, "admin-secret")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodGet, "/v1/admin/devices", "admin-secret"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected list status: got %d want %d body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var listed struct {
+		Devices []adminDevice `json:"devices"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatalf("decode list failed: %v", err)
+	}
+	if len(listed.Devices) != 1 || listed.Devices[0].DeviceID != "device-a" || listed.Devices[0].Pairings != 1 {
+		t.Fatalf("unexpected devices: %+v", listed.Devices)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodDelete, "/v1/admin/devices/device-a", "admin-secret"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected revoke status: got %d want %d body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodDelete, "/v1/admin/devices/device-a", "admin-secret"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status revoking twice: got %d want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminRevokeDeviceForgetsJobProgress(t *testing.T) {
+	store := newCloudStore(t)
+	defer func() { _ = store.Close() }()
+	pairTestDevice(t, store, "T111", "U1", "device-a")
+	job := enqueueThreadJob(t, store, "U1", "123.456")
+
+	server, mux := newAdminTestServer(t, store, "admin-secret")
+	server.allowJobProgress(job.ID, jobEventTypePhase, time.Now())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodDelete, "/v1/admin/devices/device-a", "admin-secret"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected revoke status: got %d want %d body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if server.hasJobProgress(job.ID) {
+		t.Fatal("progress for a job of the revoked device was kept")
+	}
+}
+
+func newAdminTestMux(t *testing.T, store *Store, adminToken string) *http.ServeMux {
+	t.Helper()
+	_, mux := newAdminTestServer(t, store, adminToken)
+	return mux
+}
+
+func newAdminTestServer(t *testing.T, store *Store, adminToken string) (*Server, *http.ServeMux) {
+	t.Helper()
+	server, err := NewServer(store, Config{
+		ClientID:      "cid",
+		ClientSecret:  "secret",
+		SigningSecret: "signing-secret",
+		PublicURL:     "https://fogcloud.example",
+		AdminToken:    adminToken,
+	})
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	mux := http.NewServeMux()
+	server.RegisterRoutes(mux)
+	return server, mux
+}
+
+func adminRequest(method, path, token string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
@@ -0,0 +1,73 @@
+package cloud
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// teamAPIKeyPrefix marks team API keys so a leaked one is recognisable as
+// Fog's, and so a device token presented by mistake fails fast.
+const teamAPIKeyPrefix = "fogt_"
+
+var (
+	errUnknownTeam    = errors.New("unknown team")
+	errInvalidAPIKey  = errors.New("invalid api key")
+	errMissingAPIKey  = errors.New("missing bearer api key")
+	errDeviceNotOwned = errors.New("no device paired for that user in this team")
+)
+
+// IssueTeamAPIKey creates a new API key for an installed team, replacing any
+// previous one. Only its hash is stored; the key is returned once.
+func (s *Store) IssueTeamAPIKey(teamID string) (string, error) {
+	teamID = strings.TrimSpace(teamID)
+	if teamID == "" {
+		return "", errors.New("team_id is required")
+	}
+	if _, found, err := s.GetInstallation(teamID); err != nil {
+		return "", err
+	} else if !found {
+		return "", errUnknownTeam
+	}
+
+	token, err := randomToken(24)
+	if err != nil {
+		return "", fmt.Errorf("generate api key: %w", err)
+	}
+	key := teamAPIKeyPrefix + token
+	now := nowRFC3339Nano()
+	if _, err := s.db.Exec(
+		`INSERT INTO team_api_keys(team_id, key_hash, created_at)
+		 VALUES(?, ?, ?)
+		 ON CONFLICT(team_id) DO UPDATE SET
+		   key_hash=excluded.key_hash,
+		   created_at=excluded.created_at`,
+		teamID, tokenHashHex(key), now,
+	); err != nil {
+		return "", fmt.Errorf("save api key: %w", err)
+	}
+	return key, nil
+}
+
+// AuthenticateTeamAPIKey returns the team an API key belongs to. The team is
+// only ever derived from the key, never from the request, so a caller cannot
+// reach another team's devices or jobs.
+func (s *Store) AuthenticateTeamAPIKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if !strings.HasPrefix(key, teamAPIKeyPrefix) {
+		return "", errInvalidAPIKey
+	}
+	var teamID string
+	err := s.db.QueryRow(
+		`SELECT team_id FROM team_api_keys WHERE key_hash = ?`,
+		tokenHashHex(key),
+	).Scan(&teamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errInvalidAPIKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("load api key: %w", err)
+	}
+	return teamID, nil
+}
@@ -0,0 +1,214 @@
+package cloud
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errJobCancelled is recorded on jobs their requester cancelled before a
+// device claimed them.
+const errJobCancelled = "cancelled by user"
+
+var (
+	// errJobNotFound is returned for a job that does not exist or that
+	// someone else requested; the two are not told apart.
+	errJobNotFound = errors.New("job not found")
+	// errJobNotQueued is returned for a job a device already claimed or
+	// finished, which cancelling can no longer stop.
+	errJobNotQueued = errors.New("job is no longer queued")
+	// errJobNotRunning is returned for a job that already finished, which
+	// there is nothing left to stop.
+	errJobNotRunning = errors.New("job is not running")
+)
+
+// CancelJob fails a job that is still queued, as long as slackUserID in
+// teamID requested it. The device never sees a cancelled job. A job already
+// claimed is left alone and reported with errJobNotQueued, along with the job.
+func (s *Store) CancelJob(jobID, teamID, slackUserID string) (Job, error) {
+	jobID = strings.TrimSpace(jobID)
+	teamID = strings.TrimSpace(teamID)
+	slackUserID = strings.TrimSpace(slackUserID)
+	if jobID == "" || teamID == "" || slackUserID == "" {
+		return Job{}, errors.New("job_id, team_id and slack_user_id are required")
+	}
+
+	now := nowRFC3339Nano()
+	res, err := s.db.Exec(
+		`UPDATE jobs
+		    SET state = ?, error = ?, completed_at = ?, updated_at = ?
+		  WHERE id = ? AND team_id = ? AND slack_user_id = ? AND state = ?`,
+		jobStateFailed,
+		errJobCancelled,
+		now,
+		now,
+		jobID, teamID, slackUserID, jobStateQueued,
+	)
+	if err != nil {
+		return Job{}, fmt.Errorf("cancel job: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return Job{}, fmt.Errorf("rows affected: %w", err)
+	}
+
+	job, found, err := s.GetJob(jobID)
+	if err != nil {
+		return Job{}, err
+	}
+	if !found || job.TeamID != teamID || job.SlackUserID != slackUserID {
+		return Job{}, errJobNotFound
+	}
+	if rows == 0 {
+		return job, fmt.Errorf("%w: job %s is %s", errJobNotQueued, job.ID, job.State)
+	}
+	return job, nil
+}
+
+// RequestJobStop asks the device running a job to stop it, as long as
+// slackUserID in teamID requested it. A job still queued is cancelled outright
+// as CancelJob would; a claimed one gets CancelRequested, which the device
+// polls for. A job that already finished is reported with errJobNotRunning.
+func (s *Store) RequestJobStop(jobID, teamID, slackUserID string) (Job, error) {
+	job, err := s.CancelJob(jobID, teamID, slackUserID)
+	if !errors.Is(err, errJobNotQueued) {
+		return job, err
+	}
+	if job.State != jobStateClaimed {
+		return job, fmt.Errorf("%w: job %s is %s", errJobNotRunning, job.ID, job.State)
+	}
+	res, err := s.db.Exec(
+		`UPDATE jobs SET cancel_requested = 1, updated_at = ?
+		  WHERE id = ? AND state = ?`,
+		nowRFC3339Nano(),
+		job.ID, jobStateClaimed,
+	)
+	if err != nil {
+		return Job{}, fmt.Errorf("request job stop: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return Job{}, fmt.Errorf("rows affected: %w", err)
+	}
+	job, _, err = s.GetJob(job.ID)
+	if err != nil {
+		return Job{}, err
+	}
+	if rows == 0 {
+		return job, fmt.Errorf("%w: job %s is %s", errJobNotRunning, job.ID, job.State)
+	}
+	return job, nil
+}
+
+// LatestThreadJob returns the job most recently queued from a Slack thread.
+func (s *Store) LatestThreadJob(teamID, channelID, rootTS string) (Job, bool, error) {
+	var jobID string
+	// Ordered by rowid, the insertion order: RFC3339Nano drops trailing
+	// zeros, so created_at does not sort as a time.
+	err := s.db.QueryRow(
+		`SELECT id FROM jobs
+		  WHERE team_id = ? AND channel_id = ? AND root_ts = ?
+		  ORDER BY rowid DESC
+		  LIMIT 1`,
+		strings.TrimSpace(teamID), strings.TrimSpace(channelID), strings.TrimSpace(rootTS),
+	).Scan(&jobID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, false, nil
+	}
+	if err != nil {
+		return Job{}, false, fmt.Errorf("latest thread job: %w", err)
+	}
+	return s.GetJob(jobID)
+}
+
+// cancelThreadJob handles "@fog cancel" in a thread: it cancels jobID, or the
+// thread's latest job when jobID is empty, and replies with the outcome.
+func (s *Server) cancelThreadJob(teamID string, event slackInnerEvent, rootTS, jobID string) error {
+	if jobID == "" {
+		latest, found, err := s.store.LatestThreadJob(teamID, event.Channel, rootTS)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return s.postMessage(teamID, event.Channel, rootTS, "❌ No Fog job found for this thread.")
+		}
+		jobID = latest.ID
+	}
+
+	job, err := s.store.CancelJob(jobID, teamID, event.User)
+	switch {
+	case errors.Is(err, errJobNotFound):
+		return s.postMessage(teamID, event.Channel, rootTS, fmt.Sprintf("❌ No job `%s` of yours to cancel.", jobID))
+	case errors.Is(err, errJobNotQueued):
+		text := fmt.Sprintf("❌ Job `%s` is already %s and can no longer be cancelled.", job.ID, job.State)
+		if job.State == jobStateClaimed {
+			text = fmt.Sprintf("❌ Job `%s` was already claimed by your device and can no longer be cancelled.", job.ID)
+		}
+		return s.postMessage(teamID, event.Channel, rootTS, text)
+	case err != nil:
+		return err
+	}
+	// A job requeued after a stale claim may have posted progress already.
+	s.forgetJobProgress(job.ID)
+	return s.postMessage(teamID, event.Channel, rootTS, fmt.Sprintf("🛑 Cancelled job `%s` before your device picked it up.", job.ID))
+}
+
+// stopThreadJob handles "@fog stop" in a thread: it stops jobID, or the
+// thread's latest job when jobID is empty, and replies with the outcome. The
+// device reports the stopped run when it completes the job.
+func (s *Server) stopThreadJob(teamID string, event slackInnerEvent, rootTS, jobID string) error {
+	if jobID == "" {
+		latest, found, err := s.store.LatestThreadJob(teamID, event.Channel, rootTS)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return s.postMessage(teamID, event.Channel, rootTS, "❌ No Fog job found for this thread.")
+		}
+		jobID = latest.ID
+	}
+
+	job, err := s.store.RequestJobStop(jobID, teamID, event.User)
+	switch {
+	case errors.Is(err, errJobNotFound):
+		return s.postMessage(teamID, event.Channel, rootTS, fmt.Sprintf("❌ No job `%s` of yours to stop.", jobID))
+	case errors.Is(err, errJobNotRunning):
+		return s.postMessage(teamID, event.Channel, rootTS, fmt.Sprintf("❌ Job `%s` is already %s.", job.ID, job.State))
+	case err != nil:
+		return err
+	}
+	if job.State == jobStateFailed {
+		s.forgetJobProgress(job.ID)
+		return s.postMessage(teamID, event.Channel, rootTS, fmt.Sprintf("🛑 Cancelled job `%s` before your device picked it up.", job.ID))
+	}
+	return s.postMessage(teamID, event.Channel, rootTS, fmt.Sprintf("🛑 Asked your device to stop job `%s`.", job.ID))
+}
+
+// handleDeviceJobStatus tells the device running a job whether its requester
+// asked to stop it. Devices poll it while the job's run is in flight.
+func (s *Server) handleDeviceJobStatus(w http.ResponseWriter, jobID, deviceID string) {
+	job, found, err := s.store.GetJob(jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found || job.DeviceID != deviceID {
+		http.Error(w, "job not found for device", http.StatusNotFound)
+		return
+	}
+	// The device polls status throughout a run, so each poll renews its
+	// claim and keeps a long but live run from being reclaimed.
+	if job.State == jobStateClaimed {
+		if _, err := s.store.TouchJobClaim(job.ID, deviceID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"job_id":           job.ID,
+		"state":            job.State,
+		"cancel_requested": job.CancelRequested,
+	})
+}
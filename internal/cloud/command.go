@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
 var (
@@ -18,7 +20,10 @@ type parsedCommand struct {
 	AutoPR     bool
 	BranchName string
 	CommitMsg  string
-	Prompt     string
+	// Device names one of the user's paired devices by label or id; empty
+	// means their default device.
+	Device string
+	Prompt string
 }
 
 func parseCommandText(raw string) (*parsedCommand, error) {
@@ -27,7 +32,7 @@ func parseCommandText(raw string) (*parsedCommand, error) {
 		text = strings.TrimSpace(text[len("@fog"):])
 	}
 	if text == "" {
-		return nil, fmt.Errorf("invalid command format. Use: @fog [repo='' tool='' model='' autopr=true/false branch-name='' commit-msg=''] prompt")
+		return nil, fmt.Errorf("invalid command format. Use: @fog [repo='' tool='' model='' autopr=true/false branch-name='' commit-msg='' device=''] prompt")
 	}
 
 	if !strings.HasPrefix(text, "[") {
@@ -74,6 +79,7 @@ func parseCommandText(raw string) (*parsedCommand, error) {
 		AutoPR:     autopr,
 		BranchName: strings.TrimSpace(opts["branch-name"]),
 		CommitMsg:  strings.TrimSpace(opts["commit-msg"]),
+		Device:     strings.TrimSpace(opts["device"]),
 		Prompt:     prompt,
 	}, nil
 }
@@ -86,6 +92,7 @@ func parseOptions(input string) (map[string]string, error) {
 		"autopr":      {},
 		"branch-name": {},
 		"commit-msg":  {},
+		"device":      {},
 	}
 
 	matches := optionPattern.FindAllStringSubmatchIndex(input, -1)
@@ -135,3 +142,31 @@ func normalizeFollowUpPrompt(input string) (string, error) {
 	}
 	return prompt, nil
 }
+
+// parseCancelCommand recognises "cancel" and "cancel <job-id>", returning the
+// job id, empty for the thread's latest job. Anything else after "cancel" is
+// a prompt, so a follow-up like "cancel the old endpoint" still runs.
+func parseCancelCommand(input string) (string, bool) {
+	return parseJobCommand(input, "cancel")
+}
+
+// parseStopCommand recognises "stop" and "stop <job-id>" the way
+// parseCancelCommand recognises "cancel".
+func parseStopCommand(input string) (string, bool) {
+	return parseJobCommand(input, "stop")
+}
+
+func parseJobCommand(input, verb string) (string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > 2 || !strings.EqualFold(fields[0], verb) {
+		return "", false
+	}
+	if len(fields) == 1 {
+		return "", true
+	}
+	jobID := strings.Trim(fields[1], "`")
+	if _, err := uuid.Parse(jobID); err != nil {
+		return "", false
+	}
+	return jobID, true
+}
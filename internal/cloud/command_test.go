@@ -6,14 +6,14 @@ import (
 )
 
 func TestParseCommandText_AllOptions(t *testing.T) {
-	cmd, err := parseCommandText("@fog [repo='acme/api' tool='cursor' model='gpt-5' autopr=true branch-name='feat/login' commit-msg='add login'] implement login")
+	cmd, err := parseCommandText("@fog [repo='acme/api' tool='cursor' model='gpt-5' autopr=true branch-name='feat/login' commit-msg='add login' device='laptop'] implement login")
 	if err != nil {
 		t.Fatalf("parseCommandText failed: %v", err)
 	}
 	if cmd.Repo != "acme/api" || cmd.Tool != "cursor" || cmd.Model != "gpt-5" {
 		t.Fatalf("unexpected parsed command: %+v", cmd)
 	}
-	if !cmd.AutoPR || cmd.BranchName != "feat/login" || cmd.CommitMsg != "add login" {
+	if !cmd.AutoPR || cmd.BranchName != "feat/login" || cmd.CommitMsg != "add login" || cmd.Device != "laptop" {
 		t.Fatalf("unexpected parsed options: %+v", cmd)
 	}
 	if cmd.Prompt != "implement login" {
@@ -43,3 +43,32 @@ func TestNormalizeFollowUpPrompt(t *testing.T) {
 		t.Fatalf("unexpected normalized prompt: %q", got)
 	}
 }
+
+func TestParseCancelCommand(t *testing.T) {
+	const jobID = "3b241101-e2bb-4255-8caf-4136c566a962"
+	cases := []struct {
+		input  string
+		wantID string
+		wantOK bool
+	}{
+		{"cancel", "", true},
+		{" Cancel ", "", true},
+		{"cancel " + jobID, jobID, true},
+		{"cancel `" + jobID + "`", jobID, true},
+		{"cancel the old endpoint", "", false},
+		{"cancel everything", "", false},
+		{"please cancel", "", false},
+	}
+	for _, tc := range cases {
+		gotID, gotOK := parseCancelCommand(tc.input)
+		if gotID != tc.wantID || gotOK != tc.wantOK {
+			t.Errorf("parseCancelCommand(%q) = %q, %v; want %q, %v", tc.input, gotID, gotOK, tc.wantID, tc.wantOK)
+		}
+	}
+	if gotID, ok := parseStopCommand("stop `" + jobID + "`"); !ok || gotID != jobID {
+		t.Errorf("parseStopCommand = %q, %v; want %q, true", gotID, ok, jobID)
+	}
+	if _, ok := parseStopCommand("stop using the old client"); ok {
+		t.Error("parseStopCommand took a prompt for a command")
+	}
+}
@@ -24,6 +24,9 @@ func loadOrCreateMasterKey(path string) ([]byte, error) {
 
 	key, err := os.ReadFile(path)
 	if err == nil {
+		if err := restrictKeyFile(path); err != nil {
+			return nil, err
+		}
 		if len(key) != masterKeySize {
 			return nil, fmt.Errorf("invalid key size: got %d bytes", len(key))
 		}
@@ -55,6 +58,22 @@ func loadOrCreateMasterKey(path string) ([]byte, error) {
 	return key, nil
 }
 
+// restrictKeyFile resets an existing key file to 0600, in case it was copied or
+// restored with a looser mode.
+func restrictKeyFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat key file: %w", err)
+	}
+	if info.Mode().Perm() == 0o600 {
+		return nil
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("set key file permissions: %w", err)
+	}
+	return nil
+}
+
 func encrypt(scope string, plaintext, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -0,0 +1,211 @@
+package cloud
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// errDeviceRevoked is recorded on jobs that were still outstanding when their
+// device was revoked.
+const errDeviceRevoked = "device revoked"
+
+// errUnknownDevice is returned for a device id with no devices row, or one
+// that was revoked.
+var errUnknownDevice = errors.New("unknown device")
+
+// Device is one registered device as seen by operators.
+type Device struct {
+	DeviceID    string
+	CreatedAt   time.Time
+	LastSeenAt  *time.Time
+	Pairings    int
+	PendingJobs int
+}
+
+// DeviceRevocation reports what RevokeDevice removed.
+type DeviceRevocation struct {
+	DeviceID string
+	Pairings int
+	// FailedJobs are the queued or claimed jobs the device will never
+	// complete, already marked failed, and kept, so callers can notify their
+	// threads.
+	FailedJobs []Job
+}
+
+// ListDevices returns every registered device, oldest first, with its pairing
+// and outstanding-job counts.
+func (s *Store) ListDevices() ([]Device, error) {
+	rows, err := s.db.Query(
+		`SELECT d.device_id, d.created_at, d.last_seen_at,
+		        (SELECT COUNT(*) FROM pairings p WHERE p.device_id = d.device_id),
+		        (SELECT COUNT(*) FROM jobs j WHERE j.device_id = d.device_id AND j.state IN (?, ?))
+		   FROM devices d
+		  WHERE d.revoked_at IS NULL
+		  ORDER BY d.created_at ASC`,
+		jobStateQueued,
+		jobStateClaimed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list devices: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Device
+	for rows.Next() {
+		var d Device
+		var createdAtRaw string
+		var lastSeenRaw sql.NullString
+		if err := rows.Scan(&d.DeviceID, &createdAtRaw, &lastSeenRaw, &d.Pairings, &d.PendingJobs); err != nil {
+			return nil, fmt.Errorf("scan device: %w", err)
+		}
+		if d.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw); err != nil {
+			return nil, fmt.Errorf("parse created_at: %w", err)
+		}
+		if lastSeenRaw.Valid && lastSeenRaw.String != "" {
+			lastSeen, err := time.Parse(time.RFC3339Nano, lastSeenRaw.String)
+			if err != nil {
+				return nil, fmt.Errorf("parse last_seen_at: %w", err)
+			}
+			d.LastSeenAt = &lastSeen
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate devices: %w", err)
+	}
+	return out, nil
+}
+
+// RevokeDevice revokes a device so its token stops authenticating, and deletes
+// its pairings. Its queued and claimed jobs are marked failed and kept, so
+// their threads still find them; the device row stays behind, tokenless and
+// stamped revoked_at, because jobs reference it. Pairing the same device id
+// again issues a fresh token.
+func (s *Store) RevokeDevice(deviceID string) (DeviceRevocation, error) {
+	deviceID = strings.TrimSpace(deviceID)
+	if deviceID == "" {
+		return DeviceRevocation{}, errors.New("device_id is required")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return DeviceRevocation{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exists int
+	err = tx.QueryRow(`SELECT 1 FROM devices WHERE device_id = ? AND revoked_at IS NULL`, deviceID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DeviceRevocation{}, errUnknownDevice
+	}
+	if err != nil {
+		return DeviceRevocation{}, fmt.Errorf("load device: %w", err)
+	}
+
+	jobIDs, err := outstandingJobIDs(tx, deviceID)
+	if err != nil {
+		return DeviceRevocation{}, err
+	}
+	now := nowRFC3339Nano()
+	if _, err := tx.Exec(
+		`UPDATE jobs
+		    SET state = ?, error = ?, completed_at = ?, updated_at = ?
+		  WHERE device_id = ? AND state IN (?, ?)`,
+		jobStateFailed,
+		errDeviceRevoked,
+		now,
+		now,
+		deviceID,
+		jobStateQueued,
+		jobStateClaimed,
+	); err != nil {
+		return DeviceRevocation{}, fmt.Errorf("fail outstanding jobs: %w", err)
+	}
+	result := DeviceRevocation{DeviceID: deviceID}
+	for _, id := range jobIDs {
+		job, found, err := getJobTx(tx, id)
+		if err != nil {
+			return DeviceRevocation{}, err
+		}
+		if found {
+			result.FailedJobs = append(result.FailedJobs, job)
+		}
+	}
+
+	res, err := tx.Exec(`DELETE FROM pairings WHERE device_id = ?`, deviceID)
+	if err != nil {
+		return DeviceRevocation{}, fmt.Errorf("delete pairings: %w", err)
+	}
+	pairings, err := res.RowsAffected()
+	if err != nil {
+		return DeviceRevocation{}, fmt.Errorf("rows affected: %w", err)
+	}
+	result.Pairings = int(pairings)
+	if err := ensureDefaultPairings(tx); err != nil {
+		return DeviceRevocation{}, err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE devices
+		    SET token_hash = '', revoked_at = ?, updated_at = ?
+		  WHERE device_id = ?`,
+		now,
+		now,
+		deviceID,
+	); err != nil {
+		return DeviceRevocation{}, fmt.Errorf("revoke device: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return DeviceRevocation{}, fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+	return result, nil
+}
+
+func outstandingJobIDs(tx *sql.Tx, deviceID string) ([]string, error) {
+	rows, err := tx.Query(
+		`SELECT id FROM jobs
+		  WHERE device_id = ? AND state IN (?, ?)
+		  ORDER BY created_at ASC`,
+		deviceID,
+		jobStateQueued,
+		jobStateClaimed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list outstanding jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan job id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// touchDevice records that the device just authenticated. It is best effort:
+// a failed write must not turn a valid request into an error.
+func (s *Store) touchDevice(deviceID string) {
+	_, _ = s.db.Exec(`UPDATE devices SET last_seen_at = ? WHERE device_id = ?`, nowRFC3339Nano(), deviceID)
+}
+
+// ensureDevicesSchema backfills last_seen_at and revoked_at on databases
+// created before device listing and revocation existed.
+func (s *Store) ensureDevicesSchema() error {
+	if err := s.ensureColumn("devices", "last_seen_at", "TEXT"); err != nil {
+		return err
+	}
+	return s.ensureColumn("devices", "revoked_at", "TEXT")
+}
@@ -0,0 +1,107 @@
+package cloud
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListDevicesReportsPairingsAndLastSeen(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	claim := pairTestDevice(t, store, "T1", "U1", "device-a")
+	if err := store.AuthenticateDevice("device-a", claim.DeviceToken); err != nil {
+		t.Fatalf("authenticate device failed: %v", err)
+	}
+
+	devices, err := store.ListDevices()
+	if err != nil {
+		t.Fatalf("list devices failed: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("unexpected device count: got %d want 1", len(devices))
+	}
+	d := devices[0]
+	if d.DeviceID != "device-a" || d.Pairings != 1 || d.PendingJobs != 0 {
+		t.Fatalf("unexpected device: %+v", d)
+	}
+	if d.LastSeenAt == nil || d.LastSeenAt.Before(d.CreatedAt) {
+		t.Fatalf("unexpected last seen: %v (created %v)", d.LastSeenAt, d.CreatedAt)
+	}
+}
+
+func TestRevokeDeviceCascadesToPairingsAndJobs(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	claim := pairTestDevice(t, store, "T1", "U1", "device-a")
+	pairTestDevice(t, store, "T1", "U2", "device-b")
+
+	job, err := store.EnqueueJob(Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        jobKindStartSession,
+		Repo:        "owner/repo",
+		Prompt:      "implement auth",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+
+	revoked, err := store.RevokeDevice("device-a")
+	if err != nil {
+		t.Fatalf("revoke device failed: %v", err)
+	}
+	if revoked.Pairings != 1 {
+		t.Fatalf("unexpected revoked pairings: got %d want 1", revoked.Pairings)
+	}
+	if len(revoked.FailedJobs) != 1 || revoked.FailedJobs[0].ID != job.ID {
+		t.Fatalf("unexpected failed jobs: %+v", revoked.FailedJobs)
+	}
+	if got := revoked.FailedJobs[0]; got.State != jobStateFailed || got.Error != errDeviceRevoked {
+		t.Fatalf("unexpected failed job: state=%q error=%q", got.State, got.Error)
+	}
+
+	if err := store.AuthenticateDevice("device-a", claim.DeviceToken); err == nil {
+		t.Fatal("expected revoked device token to stop authenticating")
+	}
+	if _, found, err := store.GetPairing("T1", "U1"); err != nil || found {
+		t.Fatalf("expected pairing removed: found=%v err=%v", found, err)
+	}
+	if _, found, err := store.GetPairing("T1", "U2"); err != nil || !found {
+		t.Fatalf("expected other device's pairing kept: found=%v err=%v", found, err)
+	}
+	if got, found, err := store.GetJob(job.ID); err != nil || !found || got.State != jobStateFailed || got.Error != errDeviceRevoked {
+		t.Fatalf("expected the failed job kept: %+v found=%v err=%v", got, found, err)
+	}
+	if devices, err := store.ListDevices(); err != nil || len(devices) != 1 || devices[0].DeviceID != "device-b" {
+		t.Fatalf("expected only device-b listed: %+v err=%v", devices, err)
+	}
+	if _, err := store.RevokeDevice("device-a"); err == nil {
+		t.Fatal("expected revoking a revoked device to fail")
+	}
+
+	repaired := pairTestDevice(t, store, "T1", "U1", "device-a")
+	if repaired.DeviceToken == "" || repaired.DeviceToken == claim.DeviceToken {
+		t.Fatalf("expected re-pairing to issue a fresh token, got %q", repaired.DeviceToken)
+	}
+	if err := store.AuthenticateDevice("device-a", repaired.DeviceToken); err != nil {
+		t.Fatalf("authenticate re-paired device failed: %v", err)
+	}
+}
+
+func pairTestDevice(t *testing.T, store *Store, teamID, userID, deviceID string) PairingClaimResult {
+	t.Helper()
+	req, err := store.CreatePairingRequest(teamID, userID, "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	claim, err := store.ClaimPairingRequest(req.Code, deviceID, "", false)
+	if err != nil {
+		t.Fatalf("claim pairing request failed: %v", err)
+	}
+	return claim
+}
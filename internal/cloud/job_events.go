@@ -0,0 +1,148 @@
+package cloud
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// jobEventTypePhase reports a run entering a new state; its message is
+	// the state, as in SETUP or VALIDATING.
+	jobEventTypePhase = "phase"
+	// jobEventTypeAIOutput carries the AI tool's final output.
+	jobEventTypeAIOutput = "ai_output"
+
+	// maxJobProgressPosts caps the progress replies posted to one job's
+	// thread, so a device stuck in a loop cannot flood the channel.
+	maxJobProgressPosts = 12
+
+	// jobOutputPostInterval is the least time between two ai_output replies
+	// for a job. Phase replies are not spaced: a run has only a handful, and
+	// "now validating" usually lands right after the output.
+	jobOutputPostInterval = 30 * time.Second
+
+	// jobOutputPreviewRunes bounds how much AI output a reply quotes.
+	jobOutputPreviewRunes = 600
+)
+
+// jobPhaseText is the thread reply for each run state worth announcing.
+// Terminal states are left to the completion reply.
+var jobPhaseText = map[string]string{
+	"SETUP":      "⚙️ Running setup…",
+	"QUEUED":     "⏳ Waiting for a free run slot…",
+	"AI_RUNNING": "🤖 AI is working…",
+	"VALIDATING": "🧪 Now validating…",
+	"COMMITTED":  "📝 Committing changes…",
+}
+
+// jobEventRequest is the body of POST /v1/device/jobs/{id}/events: one run
+// event, in the shape the device records it.
+type jobEventRequest struct {
+	Type    string `json:"type"`
+	Message string `json:"message,omitempty"`
+	Data    string `json:"data,omitempty"`
+}
+
+// jobProgress is what has been posted for one in-flight job.
+type jobProgress struct {
+	posts      int
+	lastOutput time.Time
+}
+
+// handleDeviceJobEvent relays one progress event from the device running a
+// job into the job's Slack thread. The event is accepted whether or not it is
+// posted; "posted" in the response says which.
+func (s *Server) handleDeviceJobEvent(w http.ResponseWriter, r *http.Request, jobID, deviceID string) {
+	var req jobEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	req.Type = strings.TrimSpace(req.Type)
+	if req.Type == "" {
+		http.Error(w, "type is required", http.StatusBadRequest)
+		return
+	}
+
+	job, found, err := s.store.GetJob(jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found || job.DeviceID != deviceID {
+		http.Error(w, "job not found for device", http.StatusNotFound)
+		return
+	}
+	if job.State != jobStateClaimed {
+		http.Error(w, "job is "+job.State+", not claimed", http.StatusConflict)
+		return
+	}
+	if _, err := s.store.TouchJobClaim(job.ID, deviceID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	posted := false
+	if text := jobEventText(req); text != "" && s.allowJobProgress(job.ID, req.Type, time.Now()) {
+		posted = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, text) == nil
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "ok",
+		"job_id": job.ID,
+		"posted": posted,
+	})
+}
+
+// jobEventText renders an event as a thread reply, or "" for one not worth
+// posting.
+func jobEventText(event jobEventRequest) string {
+	switch event.Type {
+	case jobEventTypePhase:
+		return jobPhaseText[strings.TrimSpace(event.Message)]
+	case jobEventTypeAIOutput:
+		output := strings.TrimSpace(event.Message)
+		if output == "" {
+			return ""
+		}
+		if runes := []rune(output); len(runes) > jobOutputPreviewRunes {
+			output = strings.TrimSpace(string(runes[:jobOutputPreviewRunes])) + "…"
+		}
+		return "💬 " + output
+	default:
+		return ""
+	}
+}
+
+// allowJobProgress reports whether another reply may be posted for jobID,
+// counting it when so.
+func (s *Server) allowJobProgress(jobID, eventType string, now time.Time) bool {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	p := s.jobProgress[jobID]
+	if p == nil {
+		p = &jobProgress{}
+		s.jobProgress[jobID] = p
+	}
+	if p.posts >= maxJobProgressPosts {
+		return false
+	}
+	if eventType == jobEventTypeAIOutput {
+		if !p.lastOutput.IsZero() && now.Sub(p.lastOutput) < jobOutputPostInterval {
+			return false
+		}
+		p.lastOutput = now
+	}
+	p.posts++
+	return true
+}
+
+// forgetJobProgress drops the progress kept for a job once it ends, however it
+// ends: completed by its device, cancelled, failed by the reclaimer or by
+// revoking its device.
+func (s *Server) forgetJobProgress(jobID string) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	delete(s.jobProgress, jobID)
+}
@@ -0,0 +1,185 @@
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// enqueueJobRequest is the body of POST /v1/jobs. The target device is the
+// one SlackUserID has paired in the key's team. ChannelID and ThreadTS are
+// optional: when set, progress is posted there as for a Slack mention.
+type enqueueJobRequest struct {
+	Kind        string `json:"kind"`
+	SlackUserID string `json:"slack_user_id"`
+	Prompt      string `json:"prompt"`
+	Repo        string `json:"repo,omitempty"`
+	Tool        string `json:"tool,omitempty"`
+	Model       string `json:"model,omitempty"`
+	AutoPR      bool   `json:"autopr,omitempty"`
+	BranchName  string `json:"branch_name,omitempty"`
+	CommitMsg   string `json:"commit_msg,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	ThreadTS    string `json:"thread_ts,omitempty"`
+	// Device picks one of the user's paired devices by label or id.
+	Device string `json:"device,omitempty"`
+}
+
+// jobView is a job as the team API reports it.
+type jobView struct {
+	ID          string     `json:"id"`
+	Kind        string     `json:"kind"`
+	State       string     `json:"state"`
+	DeviceID    string     `json:"device_id"`
+	SlackUserID string     `json:"slack_user_id"`
+	Repo        string     `json:"repo,omitempty"`
+	SessionID   string     `json:"session_id,omitempty"`
+	RunID       string     `json:"run_id,omitempty"`
+	Branch      string     `json:"branch,omitempty"`
+	PRURL       string     `json:"pr_url,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ClaimCount  int        `json:"claim_count"`
+}
+
+func newJobView(job Job) jobView {
+	return jobView{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		State:       job.State,
+		DeviceID:    job.DeviceID,
+		SlackUserID: job.SlackUserID,
+		Repo:        job.Repo,
+		SessionID:   job.SessionID,
+		RunID:       job.RunID,
+		Branch:      job.Branch,
+		PRURL:       job.PRURL,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		ClaimedAt:   job.ClaimedAt,
+		CompletedAt: job.CompletedAt,
+		ClaimCount:  job.ClaimCount,
+	}
+}
+
+// authorizeTeam resolves the team API key in the Authorization header. It
+// writes the 401 itself and returns false when the key is missing or unknown.
+func (s *Server) authorizeTeam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	auth := strings.TrimSpace(r.Header.Get("Authorization"))
+	if !strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+		http.Error(w, errMissingAPIKey.Error(), http.StatusUnauthorized)
+		return "", false
+	}
+	teamID, err := s.store.AuthenticateTeamAPIKey(auth[len("Bearer "):])
+	if errors.Is(err, errInvalidAPIKey) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return "", false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	return teamID, true
+}
+
+// handleJobs serves POST /v1/jobs, which lets external systems such as CI or
+// an issue tracker queue the same start_session and follow_up jobs a Slack
+// mention does.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	teamID, ok := s.authorizeTeam(w, r)
+	if !ok {
+		return
+	}
+
+	var req enqueueJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	req.SlackUserID = strings.TrimSpace(req.SlackUserID)
+	if req.SlackUserID == "" {
+		http.Error(w, "slack_user_id is required", http.StatusBadRequest)
+		return
+	}
+	req.ChannelID = strings.TrimSpace(req.ChannelID)
+	req.ThreadTS = strings.TrimSpace(req.ThreadTS)
+	if req.ThreadTS != "" && req.ChannelID == "" {
+		http.Error(w, "thread_ts requires channel_id", http.StatusBadRequest)
+		return
+	}
+
+	deviceID, err := s.routeJob(teamID, req.SlackUserID, req.Device, req.SessionID)
+	switch {
+	case errors.Is(err, errDeviceNotOwned):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errUnknownPairedDevice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	job, err := s.store.EnqueueJob(Job{
+		DeviceID:    deviceID,
+		TeamID:      teamID,
+		ChannelID:   req.ChannelID,
+		RootTS:      req.ThreadTS,
+		SlackUserID: req.SlackUserID,
+		Kind:        req.Kind,
+		Repo:        req.Repo,
+		Tool:        req.Tool,
+		Model:       req.Model,
+		AutoPR:      req.AutoPR,
+		BranchName:  req.BranchName,
+		CommitMsg:   req.CommitMsg,
+		Prompt:      req.Prompt,
+		SessionID:   req.SessionID,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, newJobView(job))
+}
+
+// handleJobDetail serves GET /v1/jobs/{id} so an API caller can poll the job
+// it queued. Jobs of other teams answer 404, the same as missing ones.
+func (s *Server) handleJobDetail(w http.ResponseWriter, r *http.Request) {
+	jobID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), "/")
+	if jobID == "" || strings.Contains(jobID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	teamID, ok := s.authorizeTeam(w, r)
+	if !ok {
+		return
+	}
+
+	job, found, err := s.store.GetJob(jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found || job.TeamID != teamID {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, newJobView(job))
+}
@@ -0,0 +1,124 @@
+package cloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminIssuesTeamAPIKey(t *testing.T) {
+	store := newCloudStore(t)
+	defer func() { _ = store.Close() }()
+	if err := store.SaveInstallation("T1", "B1", "xoxb-1"); err != nil {
+		t.Fatalf("save installation failed: %v", err)
+	}
+	mux := newAdminTestMux(t, store, "admin-secret")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/v1/admin/teams/T404/api-key", "admin-secret"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status for unknown team: got %d want %d", rec.Code, http.StatusNotFound)
+	}
+
+	first := issueTeamKey(t, mux, "T1")
+	second := issueTeamKey(t, mux, "T1")
+	if first == second {
+		t.Fatal("expected issuing again to rotate the key")
+	}
+	if _, err := store.AuthenticateTeamAPIKey(first); err == nil {
+		t.Fatal("expected the rotated-out key to stop working")
+	}
+	if team, err := store.AuthenticateTeamAPIKey(second); err != nil || team != "T1" {
+		t.Fatalf("authenticate current key: team=%q err=%v", team, err)
+	}
+}
+
+func TestTeamAPIEnqueuesJobForPairedDevice(t *testing.T) {
+	store := newCloudStore(t)
+	defer func() { _ = store.Close() }()
+	for _, team := range []string{"T1", "T2"} {
+		if err := store.SaveInstallation(team, "B-"+team, "xoxb-"+team); err != nil {
+			t.Fatalf("save installation failed: %v", err)
+		}
+	}
+	pairTestDevice(t, store, "T1", "U1", "device-a")
+
+	mux := newAdminTestMux(t, store, "admin-secret")
+	keyT1 := issueTeamKey(t, mux, "T1")
+	keyT2 := issueTeamKey(t, mux, "T2")
+
+	body := `{"kind":"start_session","slack_user_id":"U1","repo":"acme/api","prompt":"fix flaky test"}`
+	if rec := teamRequest(mux, http.MethodPost, "/v1/jobs", "", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status without key: got %d want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := teamRequest(mux, http.MethodPost, "/v1/jobs", "fogt_bogus", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status with bad key: got %d want %d", rec.Code, http.StatusUnauthorized)
+	}
+	// U1 is paired in T1 only, so T2's key cannot reach device-a.
+	if rec := teamRequest(mux, http.MethodPost, "/v1/jobs", keyT2, body); rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status across teams: got %d want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := teamRequest(mux, http.MethodPost, "/v1/jobs", keyT1, `{"kind":"start_session","slack_user_id":"U1","prompt":"no repo"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status without repo: got %d want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := teamRequest(mux, http.MethodPost, "/v1/jobs", keyT1, body)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected enqueue status: got %d want %d body=%s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	var queued jobView
+	if err := json.NewDecoder(rec.Body).Decode(&queued); err != nil {
+		t.Fatalf("decode job failed: %v", err)
+	}
+	if queued.DeviceID != "device-a" || queued.State != jobStateQueued {
+		t.Fatalf("unexpected job: %+v", queued)
+	}
+
+	claimed, found, err := store.ClaimNextJob("device-a")
+	if err != nil || !found || claimed.ID != queued.ID {
+		t.Fatalf("device did not get the job: found=%v id=%q err=%v", found, claimed.ID, err)
+	}
+
+	if rec := teamRequest(mux, http.MethodGet, "/v1/jobs/"+queued.ID, keyT2, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status reading another team's job: got %d want %d", rec.Code, http.StatusNotFound)
+	}
+	rec = teamRequest(mux, http.MethodGet, "/v1/jobs/"+queued.ID, keyT1, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected job status code: got %d want %d", rec.Code, http.StatusOK)
+	}
+	var polled jobView
+	if err := json.NewDecoder(rec.Body).Decode(&polled); err != nil {
+		t.Fatalf("decode job failed: %v", err)
+	}
+	if polled.State != jobStateClaimed {
+		t.Fatalf("unexpected polled state: %q", polled.State)
+	}
+}
+
+func issueTeamKey(t *testing.T, mux *http.ServeMux, teamID string) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/v1/admin/teams/"+teamID+"/api-key", "admin-secret"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("issue api key for %s: got %d body=%s", teamID, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		APIKey string `json:"api_key"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.APIKey == "" {
+		t.Fatalf("decode api key failed: %v", err)
+	}
+	return resp.APIKey
+}
+
+func teamRequest(mux *http.ServeMux, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
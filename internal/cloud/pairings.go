@@ -0,0 +1,272 @@
+package cloud
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxDeviceLabelLen bounds a pairing label, which users type in device='...'.
+const maxDeviceLabelLen = 64
+
+// errUnknownPairedDevice is returned when device='...' names none of the
+// user's paired devices.
+var errUnknownPairedDevice = errors.New("no paired device matches")
+
+// Pairing is one device a Slack user has paired. A user may pair several;
+// the default one gets jobs that do not pick a device.
+type Pairing struct {
+	TeamID      string
+	SlackUserID string
+	DeviceID    string
+	Label       string
+	Default     bool
+	PairedAt    time.Time
+}
+
+// PairingClaimOptions changes how ClaimPairingRequestWithOptions treats a
+// user who already has a device paired.
+type PairingClaimOptions struct {
+	// Force moves the user to the claiming device, unpairing every other.
+	Force bool
+	// AddDevice pairs the claiming device alongside the user's others.
+	AddDevice bool
+	// MakeDefault makes the claiming device the user's default. A user's
+	// first device is always the default.
+	MakeDefault bool
+	// Label names the device in device='...'. Empty keeps the label a
+	// re-claiming device already has.
+	Label string
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// ensurePairingsSchema rebuilds pairings created when a user could pair only
+// one device, keyed by (team_id, slack_user_id), into the per-device table.
+// Each existing pairing becomes its user's default.
+func (s *Store) ensurePairingsSchema() error {
+	hasLabel, err := s.hasColumn("pairings", "label")
+	if err != nil {
+		return err
+	}
+	if !hasLabel {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("begin tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+		stmts := []string{
+			`CREATE TABLE pairings_multi (
+				team_id TEXT NOT NULL,
+				slack_user_id TEXT NOT NULL,
+				device_id TEXT NOT NULL,
+				label TEXT NOT NULL DEFAULT '',
+				is_default INTEGER NOT NULL DEFAULT 0,
+				paired_at TEXT NOT NULL,
+				PRIMARY KEY(team_id, slack_user_id, device_id),
+				FOREIGN KEY(device_id) REFERENCES devices(device_id)
+			);`,
+			`INSERT INTO pairings_multi(team_id, slack_user_id, device_id, label, is_default, paired_at)
+			 SELECT team_id, slack_user_id, device_id, '', 1, paired_at FROM pairings;`,
+			`DROP TABLE pairings;`,
+			`ALTER TABLE pairings_multi RENAME TO pairings;`,
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("migrate pairings: %w", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migrate pairings: %w", err)
+		}
+	}
+	if _, err := s.db.Exec(
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_pairings_user_label
+		    ON pairings(team_id, slack_user_id, label) WHERE label != ''`,
+	); err != nil {
+		return fmt.Errorf("init schema: %w", err)
+	}
+	return nil
+}
+
+// hasColumn reports whether table has column.
+func (s *Store) hasColumn(table, column string) (bool, error) {
+	rows, err := s.db.Query(`PRAGMA table_info(` + table + `)`)
+	if err != nil {
+		return false, fmt.Errorf("table info for %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notnull, pk int
+		var name, ctype string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			return false, fmt.Errorf("scan table info %s: %w", table, err)
+		}
+		if strings.EqualFold(name, column) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// existingPairings lists a user's pairings inside tx, in ListPairings order.
+func existingPairings(tx *sql.Tx, teamID, slackUserID string) ([]Pairing, error) {
+	rows, err := tx.Query(
+		`SELECT device_id, label, is_default
+		   FROM pairings
+		  WHERE team_id = ? AND slack_user_id = ?
+		  ORDER BY is_default DESC, rowid ASC`,
+		teamID, slackUserID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("check existing pairing: %w", err)
+	}
+	defer rows.Close()
+	var out []Pairing
+	for rows.Next() {
+		p := Pairing{TeamID: teamID, SlackUserID: slackUserID}
+		var isDefault int
+		if err := rows.Scan(&p.DeviceID, &p.Label, &isDefault); err != nil {
+			return nil, fmt.Errorf("check existing pairing: %w", err)
+		}
+		p.Default = isDefault == 1
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// ensureDefaultPairings makes the longest-paired device the default for every
+// user left without one, as after their default device was unpaired.
+func ensureDefaultPairings(e execer) error {
+	_, err := e.Exec(
+		`UPDATE pairings SET is_default = 1
+		  WHERE rowid IN (
+		    SELECT MIN(rowid) FROM pairings
+		     GROUP BY team_id, slack_user_id
+		    HAVING MAX(is_default) = 0
+		  )`,
+	)
+	if err != nil {
+		return fmt.Errorf("promote default pairings: %w", err)
+	}
+	return nil
+}
+
+func normalizeDeviceLabel(label string) (string, error) {
+	label = strings.TrimSpace(label)
+	if len(label) > maxDeviceLabelLen {
+		return "", fmt.Errorf("device label is longer than %d characters", maxDeviceLabelLen)
+	}
+	return label, nil
+}
+
+// ListPairings returns the devices a user has paired, the default first and
+// then in the order they were paired.
+func (s *Store) ListPairings(teamID, slackUserID string) ([]Pairing, error) {
+	teamID = strings.TrimSpace(teamID)
+	slackUserID = strings.TrimSpace(slackUserID)
+	if teamID == "" || slackUserID == "" {
+		return nil, errors.New("team_id and slack_user_id are required")
+	}
+	rows, err := s.db.Query(
+		`SELECT device_id, label, is_default, paired_at
+		   FROM pairings
+		  WHERE team_id = ? AND slack_user_id = ?
+		  ORDER BY is_default DESC, rowid ASC`,
+		teamID, slackUserID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list pairings: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Pairing
+	for rows.Next() {
+		p := Pairing{TeamID: teamID, SlackUserID: slackUserID}
+		var isDefault int
+		var pairedAtRaw string
+		if err := rows.Scan(&p.DeviceID, &p.Label, &isDefault, &pairedAtRaw); err != nil {
+			return nil, fmt.Errorf("scan pairing: %w", err)
+		}
+		p.Default = isDefault == 1
+		if p.PairedAt, err = time.Parse(time.RFC3339Nano, pairedAtRaw); err != nil {
+			return nil, fmt.Errorf("parse paired_at: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// ResolvePairedDevice picks the device a user's job goes to. An empty
+// selector means the default device; otherwise it must match one device's
+// label, ignoring case, or its device id. It returns errDeviceNotOwned when the
+// user has no device paired and errUnknownPairedDevice when none matches.
+func (s *Store) ResolvePairedDevice(teamID, slackUserID, selector string) (Pairing, error) {
+	pairings, err := s.ListPairings(teamID, slackUserID)
+	if err != nil {
+		return Pairing{}, err
+	}
+	if len(pairings) == 0 {
+		return Pairing{}, errDeviceNotOwned
+	}
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return pairings[0], nil
+	}
+	names := make([]string, 0, len(pairings))
+	for _, p := range pairings {
+		if (p.Label != "" && strings.EqualFold(p.Label, selector)) || p.DeviceID == selector {
+			return p, nil
+		}
+		names = append(names, fallback(p.Label, p.DeviceID))
+	}
+	return Pairing{}, fmt.Errorf("%w %q; paired devices: %s", errUnknownPairedDevice, selector, strings.Join(names, ", "))
+}
+
+// SessionDeviceID returns the device that last ran a job for sessionID, which
+// holds the session's worktree.
+func (s *Store) SessionDeviceID(teamID, sessionID string) (string, bool, error) {
+	var deviceID string
+	err := s.db.QueryRow(
+		`SELECT device_id FROM jobs
+		  WHERE team_id = ? AND session_id = ?
+		  ORDER BY rowid DESC
+		  LIMIT 1`,
+		strings.TrimSpace(teamID), strings.TrimSpace(sessionID),
+	).Scan(&deviceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("session device: %w", err)
+	}
+	return deviceID, true, nil
+}
+
+// routeJob picks the device for a job from slackUserID. A named device wins.
+// A job continuing sessionID goes to the device that holds the session while
+// it is still paired to the user, since no other device has its worktree.
+// Everything else goes to the user's default device.
+func (s *Server) routeJob(teamID, slackUserID, selector, sessionID string) (string, error) {
+	if strings.TrimSpace(selector) == "" && strings.TrimSpace(sessionID) != "" {
+		deviceID, found, err := s.store.SessionDeviceID(teamID, sessionID)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			if p, err := s.store.ResolvePairedDevice(teamID, slackUserID, deviceID); err == nil {
+				return p.DeviceID, nil
+			}
+		}
+	}
+	p, err := s.store.ResolvePairedDevice(teamID, slackUserID, selector)
+	if err != nil {
+		return "", err
+	}
+	return p.DeviceID, nil
+}
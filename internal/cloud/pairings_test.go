@@ -0,0 +1,171 @@
+package cloud
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func claimTestDevice(t *testing.T, store *Store, deviceID, token string, opts PairingClaimOptions) (PairingClaimResult, error) {
+	t.Helper()
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	return store.ClaimPairingRequestWithOptions(req.Code, deviceID, token, opts)
+}
+
+func TestClaimPairingAddsLabelledDevicesAndResolvesSelection(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	laptop, err := claimTestDevice(t, store, "device-a", "", PairingClaimOptions{Label: "laptop"})
+	if err != nil {
+		t.Fatalf("claim first device: %v", err)
+	}
+	if !laptop.Default || laptop.Label != "laptop" {
+		t.Fatalf("first device should be the labelled default: %+v", laptop)
+	}
+	if _, err := claimTestDevice(t, store, "device-b", "", PairingClaimOptions{Label: "desktop"}); err == nil {
+		t.Fatal("a second device was paired without add_device")
+	}
+	if _, err := claimTestDevice(t, store, "device-b", "", PairingClaimOptions{AddDevice: true, Label: "Laptop"}); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Fatalf("duplicate label: err = %v", err)
+	}
+	desktop, err := claimTestDevice(t, store, "device-b", "", PairingClaimOptions{AddDevice: true, Label: "desktop"})
+	if err != nil {
+		t.Fatalf("add second device: %v", err)
+	}
+	if desktop.Default {
+		t.Fatal("an added device should not take over the default")
+	}
+
+	for selector, want := range map[string]string{"": "device-a", "DESKTOP": "device-b", "device-a": "device-a"} {
+		p, err := store.ResolvePairedDevice("T1", "U1", selector)
+		if err != nil || p.DeviceID != want {
+			t.Fatalf("resolve %q = %+v, err=%v; want %s", selector, p, err, want)
+		}
+	}
+	if _, err := store.ResolvePairedDevice("T1", "U1", "tablet"); !errors.Is(err, errUnknownPairedDevice) || !strings.Contains(err.Error(), "laptop, desktop") {
+		t.Fatalf("unknown selector: err = %v", err)
+	}
+	if _, err := store.ResolvePairedDevice("T1", "U2", ""); !errors.Is(err, errDeviceNotOwned) {
+		t.Fatalf("unpaired user: err = %v", err)
+	}
+
+	// Re-claiming from desktop with default moves the default without losing its label.
+	if _, err := claimTestDevice(t, store, "device-b", desktop.DeviceToken, PairingClaimOptions{MakeDefault: true}); err != nil {
+		t.Fatalf("make desktop the default: %v", err)
+	}
+	pairings, err := store.ListPairings("T1", "U1")
+	if err != nil {
+		t.Fatalf("list pairings: %v", err)
+	}
+	if len(pairings) != 2 || pairings[0].DeviceID != "device-b" || !pairings[0].Default || pairings[0].Label != "desktop" || pairings[1].Default {
+		t.Fatalf("unexpected pairings: %+v", pairings)
+	}
+
+	if err := store.UnpairStrict("T1", "U1", "device-b"); err != nil {
+		t.Fatalf("unpair desktop: %v", err)
+	}
+	if deviceID, _, _ := store.GetPairing("T1", "U1"); deviceID != "device-a" {
+		t.Fatalf("default after unpairing desktop = %q, want device-a", deviceID)
+	}
+}
+
+func TestRouteJobKeepsFollowUpsOnTheSessionDevice(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	if _, err := claimTestDevice(t, store, "device-a", "", PairingClaimOptions{Label: "laptop"}); err != nil {
+		t.Fatalf("claim first device: %v", err)
+	}
+	if _, err := claimTestDevice(t, store, "device-b", "", PairingClaimOptions{AddDevice: true, Label: "desktop"}); err != nil {
+		t.Fatalf("add second device: %v", err)
+	}
+	if _, err := store.EnqueueJob(Job{
+		DeviceID:    "device-b",
+		TeamID:      "T1",
+		SlackUserID: "U1",
+		Kind:        jobKindFollowUp,
+		SessionID:   "session-1",
+		Prompt:      "add tests",
+	}); err != nil {
+		t.Fatalf("enqueue job: %v", err)
+	}
+
+	server := &Server{store: store}
+	for _, tc := range []struct{ selector, sessionID, want string }{
+		{"", "", "device-a"},
+		{"desktop", "", "device-b"},
+		{"", "session-1", "device-b"},
+		{"laptop", "session-1", "device-a"},
+		{"", "session-unknown", "device-a"},
+	} {
+		got, err := server.routeJob("T1", "U1", tc.selector, tc.sessionID)
+		if err != nil || got != tc.want {
+			t.Fatalf("routeJob(%q, %q) = %q, err=%v; want %s", tc.selector, tc.sessionID, got, err, tc.want)
+		}
+	}
+
+	// Once the session's device is unpaired its follow-ups fall back to the default.
+	if err := store.UnpairStrict("T1", "U1", "device-b"); err != nil {
+		t.Fatalf("unpair desktop: %v", err)
+	}
+	if got, err := server.routeJob("T1", "U1", "", "session-1"); err != nil || got != "device-a" {
+		t.Fatalf("routeJob after unpair = %q, err=%v; want device-a", got, err)
+	}
+}
+
+func TestPairingsSchemaMigratesSingleDevicePairings(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewStore(dataDir)
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	pairTestDevice(t, store, "T1", "U1", "device-a")
+	if err := store.Close(); err != nil {
+		t.Fatalf("close store: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(dataDir, defaultDBName))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	for _, stmt := range []string{
+		`DROP TABLE pairings`,
+		`CREATE TABLE pairings (
+			team_id TEXT NOT NULL,
+			slack_user_id TEXT NOT NULL,
+			device_id TEXT NOT NULL,
+			paired_at TEXT NOT NULL,
+			PRIMARY KEY(team_id, slack_user_id),
+			FOREIGN KEY(device_id) REFERENCES devices(device_id)
+		)`,
+		`INSERT INTO pairings(team_id, slack_user_id, device_id, paired_at)
+		 VALUES('T1', 'U1', 'device-a', '2026-01-02T03:04:05Z')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("build old schema: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	store, err = NewStore(dataDir)
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	pairings, err := store.ListPairings("T1", "U1")
+	if err != nil {
+		t.Fatalf("list pairings: %v", err)
+	}
+	if len(pairings) != 1 || pairings[0].DeviceID != "device-a" || !pairings[0].Default || pairings[0].Label != "" {
+		t.Fatalf("unexpected migrated pairings: %+v", pairings)
+	}
+	if _, err := claimTestDevice(t, store, "device-b", "", PairingClaimOptions{AddDevice: true}); err != nil {
+		t.Fatalf("add a device after migrating: %v", err)
+	}
+}
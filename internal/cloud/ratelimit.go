@@ -0,0 +1,84 @@
+package cloud
+
+import (
+	"sync"
+	"time"
+)
+
+// limiterSweepInterval is how often idle buckets are dropped from a
+// userLimiter, so users who stopped mentioning Fog do not pile up.
+const limiterSweepInterval = 10 * time.Minute
+
+// userLimiter is a token bucket per Slack user. Each user may send perMinute
+// requests in a burst, and earns them back at perMinute a minute.
+type userLimiter struct {
+	perMinute int
+	now       func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[userKey]*userBucket
+	lastSweep time.Time
+}
+
+type userKey struct {
+	teamID string
+	userID string
+}
+
+type userBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newUserLimiter returns a limiter allowing perMinute requests a minute per
+// user, or nil, which allows everything, when perMinute is not positive.
+func newUserLimiter(perMinute int) *userLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &userLimiter{
+		perMinute: perMinute,
+		now:       time.Now,
+		buckets:   make(map[userKey]*userBucket),
+	}
+}
+
+// allow takes a token from the user's bucket, reporting false when it is
+// empty.
+func (l *userLimiter) allow(teamID, userID string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= limiterSweepInterval {
+		l.sweep(now)
+	}
+
+	key := userKey{teamID: teamID, userID: userID}
+	b := l.buckets[key]
+	if b == nil {
+		b = &userBucket{tokens: float64(l.perMinute), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(float64(l.perMinute), b.tokens+now.Sub(b.last).Minutes()*float64(l.perMinute))
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep drops buckets that have refilled completely; a fresh bucket is the
+// same as a full one.
+func (l *userLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,194 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// maxJobClaims is how many times a job may be claimed. A job whose last
+	// allowed claim goes stale is failed rather than queued again, so a job
+	// that takes its device down every time does not loop forever.
+	maxJobClaims = 3
+
+	// defaultJobClaimTimeout is how long a claimed job may go without word
+	// from its device before it is reclaimed, when Config.JobClaimTimeout is
+	// unset. Job events and status polls renew the claim, so it bounds a
+	// device's silence, not the length of a run.
+	defaultJobClaimTimeout = time.Hour
+
+	// jobReclaimInterval is how often the server looks for stale claims.
+	jobReclaimInterval = time.Minute
+)
+
+// ensureJobsSchema backfills claim_count and cancel_requested on databases
+// created before claims could be reclaimed or running jobs stopped.
+func (s *Store) ensureJobsSchema() error {
+	if err := s.ensureColumn("jobs", "claim_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return s.ensureColumn("jobs", "cancel_requested", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// ReclaimStaleJobs takes back claims older than maxAge, from devices that
+// went to sleep or crashed mid-job. A job with claims left goes back to
+// queued for its device to claim again; one that has used maxJobClaims is
+// failed and returned, so its thread can be told. requeued counts the rest.
+//
+// A stale job its requester asked to stop is failed as cancelled rather than
+// queued again. A device that reports back after its claim was taken can no
+// longer complete the job: CompleteJob only finishes a claimed job.
+func (s *Store) ReclaimStaleJobs(maxAge time.Duration) (requeued int, failed []Job, err error) {
+	if maxAge <= 0 {
+		return 0, nil, errors.New("max age must be positive")
+	}
+	rows, err := s.db.Query(
+		`SELECT id, claimed_at, claim_count, cancel_requested FROM jobs WHERE state = ? AND claimed_at IS NOT NULL`,
+		jobStateClaimed,
+	)
+	if err != nil {
+		return 0, nil, fmt.Errorf("list claimed jobs: %w", err)
+	}
+	type claim struct {
+		id, claimedAt string
+		count         int
+		cancelled     bool
+	}
+	// Compared in Go rather than SQL: RFC3339Nano drops trailing zeros, so
+	// the stored strings do not sort as times.
+	cutoff := time.Now().UTC().Add(-maxAge)
+	var stale []claim
+	for rows.Next() {
+		var c claim
+		if err := rows.Scan(&c.id, &c.claimedAt, &c.count, &c.cancelled); err != nil {
+			_ = rows.Close()
+			return 0, nil, fmt.Errorf("scan claimed job: %w", err)
+		}
+		claimedAt, err := time.Parse(time.RFC3339Nano, c.claimedAt)
+		if err != nil || claimedAt.After(cutoff) {
+			continue
+		}
+		stale = append(stale, c)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, nil, err
+	}
+
+	for _, c := range stale {
+		// Matching claimed_at as well as the state leaves a job alone that
+		// completed, or was claimed afresh, since it was read.
+		now := nowRFC3339Nano()
+		if c.count >= maxJobClaims || c.cancelled {
+			reason := fmt.Sprintf("device did not complete the job after %d claims", c.count)
+			if c.cancelled {
+				reason = errJobCancelled
+			}
+			res, err := s.db.Exec(
+				`UPDATE jobs
+				    SET state = ?, error = ?, completed_at = ?, updated_at = ?
+				  WHERE id = ? AND state = ? AND claimed_at = ?`,
+				jobStateFailed,
+				reason,
+				now, now,
+				c.id, jobStateClaimed, c.claimedAt,
+			)
+			if err != nil {
+				return requeued, failed, fmt.Errorf("fail stale job: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				continue
+			}
+			job, found, err := s.GetJob(c.id)
+			if err != nil {
+				return requeued, failed, err
+			}
+			if found {
+				failed = append(failed, job)
+			}
+			continue
+		}
+		res, err := s.db.Exec(
+			`UPDATE jobs
+			    SET state = ?, claimed_at = NULL, updated_at = ?
+			  WHERE id = ? AND state = ? AND claimed_at = ?`,
+			jobStateQueued,
+			now,
+			c.id, jobStateClaimed, c.claimedAt,
+		)
+		if err != nil {
+			return requeued, failed, fmt.Errorf("requeue stale job: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			requeued++
+		}
+	}
+	return requeued, failed, nil
+}
+
+// TouchJobClaim renews deviceID's claim on a job by resetting claimed_at to
+// now, so ReclaimStaleJobs measures how long the device has been silent rather
+// than how long the job has run. It reports false when the job is not claimed
+// by deviceID.
+func (s *Store) TouchJobClaim(jobID, deviceID string) (bool, error) {
+	now := nowRFC3339Nano()
+	res, err := s.db.Exec(
+		`UPDATE jobs SET claimed_at = ?, updated_at = ?
+		  WHERE id = ? AND device_id = ? AND state = ?`,
+		now, now,
+		strings.TrimSpace(jobID), strings.TrimSpace(deviceID), jobStateClaimed,
+	)
+	if err != nil {
+		return false, fmt.Errorf("renew job claim: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// StartJobReclaimer reclaims stale job claims now and then every
+// jobReclaimInterval until ctx is cancelled. Jobs failed for running out of
+// claims are reported to their Slack thread.
+func (s *Server) StartJobReclaimer(ctx context.Context) {
+	s.reclaimStaleJobs()
+
+	go func() {
+		ticker := time.NewTicker(jobReclaimInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.reclaimStaleJobs()
+			}
+		}
+	}()
+}
+
+func (s *Server) reclaimStaleJobs() {
+	requeued, failed, err := s.store.ReclaimStaleJobs(s.cfg.JobClaimTimeout)
+	if err != nil {
+		log.Printf("job reclaimer: %v", err)
+	}
+	if requeued > 0 {
+		log.Printf("job reclaimer: requeued %d stale job(s)", requeued)
+	}
+	for _, job := range failed {
+		log.Printf("job reclaimer: failed job %s after %d claims", job.ID, job.ClaimCount)
+		s.forgetJobProgress(job.ID)
+		if job.ChannelID == "" || job.RootTS == "" {
+			continue
+		}
+		text := "❌ Task failed: " + job.Error + ". The device may be offline."
+		if job.CancelRequested {
+			text = fmt.Sprintf("🛑 Stopped job `%s`. Your device never reported back and may be offline.", job.ID)
+		}
+		_ = s.postMessage(job.TeamID, job.ChannelID, job.RootTS, text)
+	}
+}
@@ -1,7 +1,6 @@
 package cloud
 
 import (
-	"bytes"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -16,6 +15,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/darkLord19/foglet/internal/slackapi"
 )
 
 const (
@@ -37,6 +38,18 @@ type Config struct {
 	APIBaseURL     string
 	StateTTL       time.Duration
 	PairingCodeTTL time.Duration
+
+	// AdminToken guards the /v1/admin APIs. They are disabled when empty.
+	AdminToken string
+
+	// JobClaimTimeout is how long a device may hold a claimed job before
+	// StartJobReclaimer takes it back. Zero means one hour.
+	JobClaimTimeout time.Duration
+
+	// UserRateLimit is how many @fog requests a Slack user may make a
+	// minute, in a burst or spread out. Zero means no limit. Cancel and stop
+	// commands are never limited.
+	UserRateLimit int
 }
 
 // Server provides multi-tenant Slack install/event handling and device routing APIs.
@@ -47,6 +60,11 @@ type Server struct {
 
 	stateMu     sync.Mutex
 	oauthStates map[string]time.Time
+
+	progressMu  sync.Mutex
+	jobProgress map[string]*jobProgress
+
+	userLimiter *userLimiter
 }
 
 // NewServer creates a new fog cloud server.
@@ -59,6 +77,7 @@ func NewServer(store *Store, cfg Config) (*Server, error) {
 	cfg.ClientSecret = strings.TrimSpace(cfg.ClientSecret)
 	cfg.SigningSecret = strings.TrimSpace(cfg.SigningSecret)
 	cfg.PublicURL = strings.TrimSpace(cfg.PublicURL)
+	cfg.AdminToken = strings.TrimSpace(cfg.AdminToken)
 	if cfg.PublicURL == "" {
 		return nil, errors.New("public_url is required")
 	}
@@ -83,12 +102,17 @@ func NewServer(store *Store, cfg Config) (*Server, error) {
 	if cfg.PairingCodeTTL <= 0 {
 		cfg.PairingCodeTTL = 10 * time.Minute
 	}
+	if cfg.JobClaimTimeout <= 0 {
+		cfg.JobClaimTimeout = defaultJobClaimTimeout
+	}
 
 	return &Server{
 		store:       store,
 		cfg:         cfg,
 		httpClient:  cfg.HTTPClient,
 		oauthStates: make(map[string]time.Time),
+		jobProgress: make(map[string]*jobProgress),
+		userLimiter: newUserLimiter(cfg.UserRateLimit),
 	}, nil
 }
 
@@ -101,6 +125,11 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/v1/pair/unpair", s.handlePairUnpair)
 	mux.HandleFunc("/v1/device/jobs/claim", s.handleDeviceClaimJob)
 	mux.HandleFunc("/v1/device/jobs/", s.handleDeviceJobDetail)
+	mux.HandleFunc("/v1/admin/devices", s.handleAdminDevices)
+	mux.HandleFunc("/v1/admin/devices/", s.handleAdminDeviceDetail)
+	mux.HandleFunc("/v1/admin/teams/", s.handleAdminTeamDetail)
+	mux.HandleFunc("/v1/jobs", s.handleJobs)
+	mux.HandleFunc("/v1/jobs/", s.handleJobDetail)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
@@ -233,8 +262,20 @@ func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 	if rootTS == "" {
 		return nil
 	}
+	// Cancelling needs no device: the job is still in the cloud's queue.
+	if jobID, ok := parseCancelCommand(stripMentions(event.Text)); ok {
+		return s.cancelThreadJob(teamID, event, rootTS, jobID)
+	}
+	// Stopping only flags the job; the device running it polls for the flag.
+	if jobID, ok := parseStopCommand(stripMentions(event.Text)); ok {
+		return s.stopThreadJob(teamID, event, rootTS, jobID)
+	}
+	if !s.userLimiter.allow(teamID, event.User) {
+		text := fmt.Sprintf("🐢 Slow down: Fog takes up to %d requests a minute from you. Try again in a moment.", s.cfg.UserRateLimit)
+		return s.postEphemeral(teamID, event.Channel, event.User, text)
+	}
 
-	deviceID, paired, err := s.store.GetPairing(teamID, event.User)
+	_, paired, err := s.store.GetPairing(teamID, event.User)
 	if err != nil {
 		return err
 	}
@@ -254,7 +295,6 @@ func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 	rawPrompt := stripMentions(event.Text)
 	isFollowUp := strings.TrimSpace(event.ThreadTS) != "" && strings.TrimSpace(event.ThreadTS) != strings.TrimSpace(event.TS)
 	job := Job{
-		DeviceID:    deviceID,
 		TeamID:      teamID,
 		ChannelID:   event.Channel,
 		RootTS:      rootTS,
@@ -278,6 +318,7 @@ func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 		job.Kind = jobKindFollowUp
 		job.SessionID = sessionID
 		job.Prompt = prompt
+		job.DeviceID, err = s.routeJob(teamID, event.User, "", sessionID)
 	} else {
 		parsed, parseErr := parseCommandText(rawPrompt)
 		if parseErr != nil {
@@ -292,6 +333,14 @@ func (s *Server) handleAppMention(teamID string, event slackInnerEvent) error {
 		job.BranchName = parsed.BranchName
 		job.CommitMsg = parsed.CommitMsg
 		job.Prompt = parsed.Prompt
+		job.DeviceID, err = s.routeJob(teamID, event.User, parsed.Device, "")
+	}
+	if errors.Is(err, errUnknownPairedDevice) {
+		_ = s.postMessage(teamID, event.Channel, rootTS, "❌ "+err.Error())
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
 	enqueued, err := s.store.EnqueueJob(job)
@@ -311,22 +360,42 @@ func (s *Server) handlePairClaim(w http.ResponseWriter, r *http.Request) {
 		Code        string `json:"code"`
 		DeviceID    string `json:"device_id"`
 		DeviceToken string `json:"device_token,omitempty"`
+		// Force moves an existing pairing to this device instead of
+		// rejecting the claim. The device must authenticate.
+		Force bool `json:"force,omitempty"`
+		// AddDevice pairs this device alongside the user's others.
+		AddDevice bool   `json:"add_device,omitempty"`
+		Default   bool   `json:"default,omitempty"`
+		Label     string `json:"label,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
-	result, err := s.store.ClaimPairingRequest(req.Code, req.DeviceID, req.DeviceToken)
+	result, err := s.store.ClaimPairingRequestWithOptions(req.Code, req.DeviceID, req.DeviceToken, PairingClaimOptions{
+		Force:       req.Force,
+		AddDevice:   req.AddDevice,
+		MakeDefault: req.Default,
+		Label:       req.Label,
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{
+	resp := map[string]any{
 		"team_id":       result.TeamID,
 		"slack_user_id": result.SlackUserID,
 		"device_id":     result.DeviceID,
 		"device_token":  result.DeviceToken,
-	})
+		"default":       result.Default,
+	}
+	if result.Label != "" {
+		resp["label"] = result.Label
+	}
+	if result.ReplacedDeviceID != "" {
+		resp["replaced_device_id"] = result.ReplacedDeviceID
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handlePairUnpair(w http.ResponseWriter, r *http.Request) {
@@ -395,7 +464,14 @@ func (s *Server) handleDeviceJobDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	parts := strings.Split(path, "/")
-	if len(parts) != 2 || parts[1] != "complete" || r.Method != http.MethodPost {
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case (parts[1] == "complete" || parts[1] == "events") && r.Method == http.MethodPost:
+	case parts[1] == "status" && r.Method == http.MethodGet:
+	default:
 		http.NotFound(w, r)
 		return
 	}
@@ -414,6 +490,14 @@ func (s *Server) handleDeviceJobDetail(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
+	switch parts[1] {
+	case "events":
+		s.handleDeviceJobEvent(w, r, jobID, deviceID)
+		return
+	case "status":
+		s.handleDeviceJobStatus(w, jobID, deviceID)
+		return
+	}
 
 	var req struct {
 		Success   bool   `json:"success"`
@@ -442,12 +526,17 @@ func (s *Server) handleDeviceJobDetail(w http.ResponseWriter, r *http.Request) {
 		CommitSHA: req.CommitSHA,
 		CommitMsg: req.CommitMsg,
 	})
+	if errors.Is(err, errJobNotClaimed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.forgetJobProgress(job.ID)
 
-	if req.Success && strings.TrimSpace(job.Kind) == jobKindStartSession && strings.TrimSpace(job.SessionID) != "" {
+	if req.Success && strings.TrimSpace(job.Kind) == jobKindStartSession && strings.TrimSpace(job.SessionID) != "" && job.RootTS != "" {
 		_ = s.store.UpsertThreadSession(job.TeamID, job.ChannelID, job.RootTS, job.SessionID)
 	}
 
@@ -515,33 +604,15 @@ func (s *Server) postEphemeral(teamID, channelID, userID, text string) error {
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(s.cfg.APIBaseURL, "/")+"/chat.postEphemeral", bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+inst.BotToken)
-	req.Header.Set("User-Agent", "fogcloud")
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	var out struct {
-		OK    bool   `json:"ok"`
-		Error string `json:"error"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return err
-	}
-	if !out.OK {
-		return fmt.Errorf("chat.postEphemeral failed: %s", out.Error)
-	}
-	return nil
+	return s.callSlack(inst.BotToken, "chat.postEphemeral", body)
 }
 
 func (s *Server) postMessage(teamID, channelID, threadTS, text string) error {
+	// A job queued through the team API without a channel has nowhere to
+	// report; its caller polls GET /v1/jobs/{id} instead.
+	if strings.TrimSpace(channelID) == "" {
+		return nil
+	}
 	inst, found, err := s.store.GetInstallation(teamID)
 	if err != nil {
 		return err
@@ -562,28 +633,25 @@ func (s *Server) postMessage(teamID, channelID, threadTS, text string) error {
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(s.cfg.APIBaseURL, "/")+"/chat.postMessage", bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+inst.BotToken)
-	req.Header.Set("User-Agent", "fogcloud")
+	return s.callSlack(inst.BotToken, "chat.postMessage", body)
+}
 
-	resp, err := s.httpClient.Do(req)
+// callSlack posts body to a Slack Web API method, retrying rate limits and
+// server errors.
+func (s *Server) callSlack(botToken, method string, body []byte) error {
+	data, err := slackapi.PostJSON(s.httpClient, strings.TrimRight(s.cfg.APIBaseURL, "/")+"/"+method, botToken, "fogcloud", body)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s failed: %w", method, err)
 	}
-	defer resp.Body.Close()
 	var out struct {
 		OK    bool   `json:"ok"`
 		Error string `json:"error"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+	if err := json.Unmarshal(data, &out); err != nil {
 		return err
 	}
 	if !out.OK {
-		return fmt.Errorf("chat.postMessage failed: %s", out.Error)
+		return fmt.Errorf("%s failed: %s", method, out.Error)
 	}
 	return nil
 }
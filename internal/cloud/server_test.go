@@ -249,7 +249,7 @@ func TestHandleEventsAppMentionPairedQueuesJobAndDeviceClaimsIt(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create pairing request failed: %v", err)
 	}
-	claim, err := store.ClaimPairingRequest(pairReq.Code, "device-a", "")
+	claim, err := store.ClaimPairingRequest(pairReq.Code, "device-a", "", false)
 	if err != nil {
 		t.Fatalf("claim pairing request failed: %v", err)
 	}
@@ -339,7 +339,7 @@ func TestDeviceJobCompleteMapsThreadSessionAndPostsCompletion(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create pairing request failed: %v", err)
 	}
-	claim, err := store.ClaimPairingRequest(pairReq.Code, "device-a", "")
+	claim, err := store.ClaimPairingRequest(pairReq.Code, "device-a", "", false)
 	if err != nil {
 		t.Fatalf("claim pairing request failed: %v", err)
 	}
@@ -356,6 +356,9 @@ func TestDeviceJobCompleteMapsThreadSessionAndPostsCompletion(t *testing.T) {
 	if err != nil {
 		t.Fatalf("enqueue job failed: %v", err)
 	}
+	if _, found, err := store.ClaimNextJob("device-a"); err != nil || !found {
+		t.Fatalf("claim job: found=%v err=%v", found, err)
+	}
 
 	msgCh := make(chan map[string]string, 1)
 	slackMux := http.NewServeMux()
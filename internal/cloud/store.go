@@ -53,6 +53,11 @@ type PairingClaimResult struct {
 	SlackUserID string
 	DeviceID    string
 	DeviceToken string
+	// ReplacedDeviceID is the device a forced claim took the pairing from.
+	ReplacedDeviceID string
+	Label            string
+	// Default is set when the device is now the user's default.
+	Default bool
 }
 
 const (
@@ -91,6 +96,12 @@ type Job struct {
 	UpdatedAt   time.Time
 	ClaimedAt   *time.Time
 	CompletedAt *time.Time
+	// ClaimCount is how many times a device has claimed the job, counting
+	// claims that ReclaimStaleJobs later took back.
+	ClaimCount int
+	// CancelRequested is set when the requester asked to stop the job after
+	// a device claimed it. The device polls for it and cancels the run.
+	CancelRequested bool
 }
 
 // JobCompletion stores completion payload submitted by device runtime.
@@ -107,11 +118,44 @@ type JobCompletion struct {
 	CommitMsg string
 }
 
-// NewStore opens or creates cloud sqlite state in dataDir.
+// Default permissions for cloud state. dataDir holds every workspace's bot
+// token and the key that decrypts them.
+const (
+	defaultDirMode  os.FileMode = 0o700
+	defaultFileMode os.FileMode = 0o600
+)
+
+// StoreOptions overrides the permissions NewStoreWithOptions applies. Zero
+// values take the defaults. The key file is always 0600 regardless.
+type StoreOptions struct {
+	// DirMode is applied to dataDir, including one that already exists.
+	DirMode os.FileMode
+	// DBMode is applied to the database file. SQLite gives its -wal and -shm
+	// files the same mode.
+	DBMode os.FileMode
+}
+
+// NewStore opens or creates cloud sqlite state in dataDir with the default
+// permissions.
 func NewStore(dataDir string) (*Store, error) {
-	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+	return NewStoreWithOptions(dataDir, StoreOptions{})
+}
+
+// NewStoreWithOptions is NewStore with explicit directory and database file
+// permissions.
+func NewStoreWithOptions(dataDir string, opts StoreOptions) (*Store, error) {
+	if opts.DirMode == 0 {
+		opts.DirMode = defaultDirMode
+	}
+	if opts.DBMode == 0 {
+		opts.DBMode = defaultFileMode
+	}
+	if err := os.MkdirAll(dataDir, opts.DirMode); err != nil {
 		return nil, fmt.Errorf("create data dir: %w", err)
 	}
+	if err := os.Chmod(dataDir, opts.DirMode); err != nil {
+		return nil, fmt.Errorf("set data dir permissions: %w", err)
+	}
 
 	keyPath := filepath.Join(dataDir, defaultKeyName)
 	key, err := loadOrCreateMasterKey(keyPath)
@@ -120,6 +164,9 @@ func NewStore(dataDir string) (*Store, error) {
 	}
 
 	dbPath := filepath.Join(dataDir, defaultDBName)
+	if err := prepareDBFile(dbPath, opts.DBMode); err != nil {
+		return nil, err
+	}
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
@@ -133,6 +180,24 @@ func NewStore(dataDir string) (*Store, error) {
 	return s, nil
 }
 
+// prepareDBFile creates the database file with mode before SQLite opens it, and
+// tightens a database and WAL files left by an older, more permissive default.
+func prepareDBFile(path string, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, mode)
+	if err != nil {
+		return fmt.Errorf("create database file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close database file: %w", err)
+	}
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Chmod(p, mode); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("set database permissions: %w", err)
+		}
+	}
+	return nil
+}
+
 func (s *Store) init() error {
 	stmts := []string{
 		`PRAGMA foreign_keys = ON;`,
@@ -145,18 +210,28 @@ func (s *Store) init() error {
 			installed_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		);`,
+		`CREATE TABLE IF NOT EXISTS team_api_keys (
+			team_id TEXT PRIMARY KEY,
+			key_hash TEXT NOT NULL UNIQUE,
+			created_at TEXT NOT NULL,
+			FOREIGN KEY(team_id) REFERENCES installations(team_id)
+		);`,
 		`CREATE TABLE IF NOT EXISTS devices (
 			device_id TEXT PRIMARY KEY,
 			token_hash TEXT NOT NULL,
 			created_at TEXT NOT NULL,
-			updated_at TEXT NOT NULL
+			updated_at TEXT NOT NULL,
+			last_seen_at TEXT,
+			revoked_at TEXT
 		);`,
 		`CREATE TABLE IF NOT EXISTS pairings (
 			team_id TEXT NOT NULL,
 			slack_user_id TEXT NOT NULL,
 			device_id TEXT NOT NULL,
+			label TEXT NOT NULL DEFAULT '',
+			is_default INTEGER NOT NULL DEFAULT 0,
 			paired_at TEXT NOT NULL,
-			PRIMARY KEY(team_id, slack_user_id),
+			PRIMARY KEY(team_id, slack_user_id, device_id),
 			FOREIGN KEY(device_id) REFERENCES devices(device_id)
 		);`,
 		`CREATE TABLE IF NOT EXISTS pairing_requests (
@@ -210,6 +285,8 @@ func (s *Store) init() error {
 			completed_at TEXT,
 			commit_sha TEXT,
 			commit_msg_result TEXT,
+			claim_count INTEGER NOT NULL DEFAULT 0,
+			cancel_requested INTEGER NOT NULL DEFAULT 0,
 			FOREIGN KEY(device_id) REFERENCES devices(device_id)
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_pairing_requests_user ON pairing_requests(team_id, slack_user_id, created_at DESC);`,
@@ -221,6 +298,26 @@ func (s *Store) init() error {
 			return fmt.Errorf("init schema: %w", err)
 		}
 	}
+	if err := s.ensureDevicesSchema(); err != nil {
+		return err
+	}
+	if err := s.ensurePairingsSchema(); err != nil {
+		return err
+	}
+	return s.ensureJobsSchema()
+}
+
+// ensureColumn adds column to table with the given type and constraints
+// unless the table already has it, for databases created before the column
+// existed.
+func (s *Store) ensureColumn(table, column, decl string) error {
+	found, err := s.hasColumn(table, column)
+	if err != nil || found {
+		return err
+	}
+	if _, err := s.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + decl); err != nil {
+		return fmt.Errorf("add %s.%s column: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -358,13 +455,29 @@ func (s *Store) CreatePairingRequest(teamID, slackUserID, channelID, rootTS stri
 }
 
 // ClaimPairingRequest atomically consumes a pairing code and binds user to device.
-func (s *Store) ClaimPairingRequest(code, deviceID, deviceToken string) (PairingClaimResult, error) {
+//
+// A user already paired to a different device is rejected unless force is set,
+// in which case the pairing moves to the claiming device in the same
+// transaction. Force requires the claiming device to authenticate as a known
+// device, and the code itself was issued to the user being re-paired, so it
+// cannot be used to take over someone else's pairing.
+func (s *Store) ClaimPairingRequest(code, deviceID, deviceToken string, force bool) (PairingClaimResult, error) {
+	return s.ClaimPairingRequestWithOptions(code, deviceID, deviceToken, PairingClaimOptions{Force: force})
+}
+
+// ClaimPairingRequestWithOptions is ClaimPairingRequest that can also pair
+// the device alongside the user's others, label it, or make it the default.
+func (s *Store) ClaimPairingRequestWithOptions(code, deviceID, deviceToken string, opts PairingClaimOptions) (PairingClaimResult, error) {
 	code = strings.ToUpper(strings.TrimSpace(code))
 	deviceID = strings.TrimSpace(deviceID)
 	deviceToken = strings.TrimSpace(deviceToken)
 	if code == "" || deviceID == "" {
 		return PairingClaimResult{}, errors.New("code and device_id are required")
 	}
+	label, err := normalizeDeviceLabel(opts.Label)
+	if err != nil {
+		return PairingClaimResult{}, err
+	}
 
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -424,37 +537,86 @@ func (s *Store) ClaimPairingRequest(code, deviceID, deviceToken string) (Pairing
 		return PairingClaimResult{}, errors.New("pairing code expired")
 	}
 
-	var existingDevice string
-	err = tx.QueryRow(
-		`SELECT device_id FROM pairings WHERE team_id = ? AND slack_user_id = ?`,
-		req.TeamID,
-		req.SlackUserID,
-	).Scan(&existingDevice)
-	if err == nil && existingDevice != deviceID {
-		return PairingClaimResult{}, errors.New("user is already paired to another device; unpair first")
+	existing, err := existingPairings(tx, req.TeamID, req.SlackUserID)
+	if err != nil {
+		return PairingClaimResult{}, err
+	}
+	var others []Pairing
+	alreadyPaired, wasDefault := false, false
+	for _, p := range existing {
+		if p.DeviceID == deviceID {
+			alreadyPaired, wasDefault = true, p.Default
+			continue
+		}
+		others = append(others, p)
 	}
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return PairingClaimResult{}, fmt.Errorf("check existing pairing: %w", err)
+	repairing := len(others) > 0 && opts.Force
+	if len(others) > 0 && !alreadyPaired && !opts.Force && !opts.AddDevice {
+		return PairingClaimResult{}, errors.New("user is already paired to another device; unpair first, claim with force, or add this device")
+	}
+	if repairing && deviceToken == "" {
+		// An anonymous claim would mint a fresh device; only an existing,
+		// authenticated device may take over a pairing.
+		return PairingClaimResult{}, errors.New("force re-pair requires an authenticated device")
 	}
 
 	issuedToken, err := s.ensureDeviceToken(tx, deviceID, deviceToken)
 	if err != nil {
 		return PairingClaimResult{}, err
 	}
+	if repairing && issuedToken != "" {
+		return PairingClaimResult{}, errors.New("force re-pair requires an authenticated device")
+	}
+
+	var replacedDevice string
+	if repairing {
+		replacedDevice = others[0].DeviceID
+		if _, err := tx.Exec(
+			`DELETE FROM pairings WHERE team_id = ? AND slack_user_id = ? AND device_id != ?`,
+			req.TeamID, req.SlackUserID, deviceID,
+		); err != nil {
+			return PairingClaimResult{}, fmt.Errorf("replace pairing: %w", err)
+		}
+		others = nil
+	}
+	for _, p := range others {
+		if label != "" && strings.EqualFold(p.Label, label) {
+			return PairingClaimResult{}, fmt.Errorf("device label %q is already used by another of your devices", label)
+		}
+	}
+	makeDefault := len(others) == 0 || opts.MakeDefault || wasDefault
+	if makeDefault {
+		if _, err := tx.Exec(
+			`UPDATE pairings SET is_default = 0 WHERE team_id = ? AND slack_user_id = ?`,
+			req.TeamID, req.SlackUserID,
+		); err != nil {
+			return PairingClaimResult{}, fmt.Errorf("save pairing: %w", err)
+		}
+	}
 
 	if _, err := tx.Exec(
-		`INSERT INTO pairings(team_id, slack_user_id, device_id, paired_at)
-		 VALUES(?, ?, ?, ?)
-		 ON CONFLICT(team_id, slack_user_id) DO UPDATE SET
-		   device_id=excluded.device_id,
+		`INSERT INTO pairings(team_id, slack_user_id, device_id, label, is_default, paired_at)
+		 VALUES(?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(team_id, slack_user_id, device_id) DO UPDATE SET
+		   label=CASE WHEN excluded.label = '' THEN pairings.label ELSE excluded.label END,
+		   is_default=excluded.is_default,
 		   paired_at=excluded.paired_at`,
 		req.TeamID,
 		req.SlackUserID,
 		deviceID,
+		label,
+		boolToInt(makeDefault),
 		nowRFC3339Nano(),
 	); err != nil {
 		return PairingClaimResult{}, fmt.Errorf("save pairing: %w", err)
 	}
+	if label == "" {
+		for _, p := range existing {
+			if p.DeviceID == deviceID {
+				label = p.Label
+			}
+		}
+	}
 
 	if _, err := tx.Exec(
 		`UPDATE pairing_requests
@@ -471,18 +633,24 @@ func (s *Store) ClaimPairingRequest(code, deviceID, deviceToken string) (Pairing
 	}
 	tx = nil
 
-	return PairingClaimResult{
+	result := PairingClaimResult{
 		TeamID:      req.TeamID,
 		SlackUserID: req.SlackUserID,
 		DeviceID:    deviceID,
 		DeviceToken: issuedToken,
-	}, nil
+		Label:       label,
+		Default:     makeDefault,
+	}
+	if repairing {
+		result.ReplacedDeviceID = replacedDevice
+	}
+	return result, nil
 }
 
 func (s *Store) ensureDeviceToken(tx *sql.Tx, deviceID, presentedToken string) (string, error) {
 	var tokenHash string
 	err := tx.QueryRow(
-		`SELECT token_hash FROM devices WHERE device_id = ?`,
+		`SELECT token_hash FROM devices WHERE device_id = ? AND revoked_at IS NULL`,
 		deviceID,
 	).Scan(&tokenHash)
 	switch {
@@ -505,12 +673,19 @@ func (s *Store) ensureDeviceToken(tx *sql.Tx, deviceID, presentedToken string) (
 	}
 	now := nowRFC3339Nano()
 	if _, err := tx.Exec(
-		`INSERT INTO devices(device_id, token_hash, created_at, updated_at)
-		 VALUES(?, ?, ?, ?)`,
+		`INSERT INTO devices(device_id, token_hash, created_at, updated_at, last_seen_at)
+		 VALUES(?, ?, ?, ?, ?)
+		 ON CONFLICT(device_id) DO UPDATE SET
+		   token_hash=excluded.token_hash,
+		   created_at=excluded.created_at,
+		   updated_at=excluded.updated_at,
+		   last_seen_at=excluded.last_seen_at,
+		   revoked_at=NULL`,
 		deviceID,
 		tokenHashHex(issuedToken),
 		now,
 		now,
+		now,
 	); err != nil {
 		return "", fmt.Errorf("insert device: %w", err)
 	}
@@ -526,11 +701,11 @@ func (s *Store) AuthenticateDevice(deviceID, token string) error {
 	}
 	var storedHash string
 	err := s.db.QueryRow(
-		`SELECT token_hash FROM devices WHERE device_id = ?`,
+		`SELECT token_hash FROM devices WHERE device_id = ? AND revoked_at IS NULL`,
 		deviceID,
 	).Scan(&storedHash)
 	if errors.Is(err, sql.ErrNoRows) {
-		return errors.New("unknown device")
+		return errUnknownDevice
 	}
 	if err != nil {
 		return fmt.Errorf("load device token: %w", err)
@@ -538,10 +713,12 @@ func (s *Store) AuthenticateDevice(deviceID, token string) error {
 	if !constantTimeHashEqual(storedHash, tokenHashHex(token)) {
 		return errors.New("invalid device token")
 	}
+	s.touchDevice(deviceID)
 	return nil
 }
 
-// PairDevice enforces one device per (team,user) until explicit unpair.
+// PairDevice enforces one device per (team,user) until explicit unpair. The
+// device becomes the user's default.
 func (s *Store) PairDevice(teamID, slackUserID, deviceID string) error {
 	teamID = strings.TrimSpace(teamID)
 	slackUserID = strings.TrimSpace(slackUserID)
@@ -570,7 +747,7 @@ func (s *Store) PairDevice(teamID, slackUserID, deviceID string) error {
 	if _, err := s.db.Exec(
 		`INSERT INTO devices(device_id, token_hash, created_at, updated_at)
 		 VALUES(?, ?, ?, ?)
-		 ON CONFLICT(device_id) DO UPDATE SET updated_at=excluded.updated_at`,
+		 ON CONFLICT(device_id) DO UPDATE SET updated_at=excluded.updated_at, revoked_at=NULL`,
 		deviceID,
 		"",
 		now,
@@ -580,8 +757,8 @@ func (s *Store) PairDevice(teamID, slackUserID, deviceID string) error {
 	}
 
 	_, err = s.db.Exec(
-		`INSERT INTO pairings(team_id, slack_user_id, device_id, paired_at)
-		 VALUES(?, ?, ?, ?)`,
+		`INSERT INTO pairings(team_id, slack_user_id, device_id, is_default, paired_at)
+		 VALUES(?, ?, ?, 1, ?)`,
 		teamID, slackUserID, deviceID, nowRFC3339Nano(),
 	)
 	if err != nil {
@@ -606,6 +783,7 @@ func (s *Store) UnpairDevice(teamID, slackUserID string) error {
 	return nil
 }
 
+// GetPairing returns the user's default device.
 func (s *Store) GetPairing(teamID, slackUserID string) (string, bool, error) {
 	teamID = strings.TrimSpace(teamID)
 	slackUserID = strings.TrimSpace(slackUserID)
@@ -615,7 +793,10 @@ func (s *Store) GetPairing(teamID, slackUserID string) (string, bool, error) {
 
 	var deviceID string
 	err := s.db.QueryRow(
-		`SELECT device_id FROM pairings WHERE team_id = ? AND slack_user_id = ?`,
+		`SELECT device_id FROM pairings
+		  WHERE team_id = ? AND slack_user_id = ?
+		  ORDER BY is_default DESC, rowid ASC
+		  LIMIT 1`,
 		teamID, slackUserID,
 	).Scan(&deviceID)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -627,7 +808,8 @@ func (s *Store) GetPairing(teamID, slackUserID string) (string, bool, error) {
 	return deviceID, true, nil
 }
 
-// UnpairStrict removes pairing only if it belongs to the given device.
+// UnpairStrict removes pairing only if it belongs to the given device. When
+// that was the user's default, their longest-paired other device takes over.
 func (s *Store) UnpairStrict(teamID, slackUserID, deviceID string) error {
 	teamID = strings.TrimSpace(teamID)
 	slackUserID = strings.TrimSpace(slackUserID)
@@ -652,7 +834,7 @@ func (s *Store) UnpairStrict(teamID, slackUserID, deviceID string) error {
 	if rows == 0 {
 		return errors.New("pairing not found for device")
 	}
-	return nil
+	return ensureDefaultPairings(s.db)
 }
 
 func (s *Store) UpsertThreadSession(teamID, channelID, rootTS, sessionID string) error {
@@ -700,7 +882,9 @@ func (s *Store) GetThreadSession(teamID, channelID, rootTS string) (string, bool
 	return sessionID, true, nil
 }
 
-// EnqueueJob inserts one new queued job for a paired device.
+// EnqueueJob inserts one new queued job for a paired device. ChannelID and
+// RootTS name the Slack thread progress is reported to; jobs queued through
+// the team API may leave them empty.
 func (s *Store) EnqueueJob(job Job) (Job, error) {
 	job.DeviceID = strings.TrimSpace(job.DeviceID)
 	job.TeamID = strings.TrimSpace(job.TeamID)
@@ -721,7 +905,7 @@ func (s *Store) EnqueueJob(job Job) (Job, error) {
 	if job.State == "" {
 		job.State = jobStateQueued
 	}
-	if job.DeviceID == "" || job.TeamID == "" || job.ChannelID == "" || job.RootTS == "" || job.SlackUserID == "" || job.Kind == "" || job.Prompt == "" {
+	if job.DeviceID == "" || job.TeamID == "" || job.SlackUserID == "" || job.Kind == "" || job.Prompt == "" {
 		return Job{}, errors.New("job missing required fields")
 	}
 	switch job.Kind {
@@ -810,7 +994,7 @@ func (s *Store) ClaimNextJob(deviceID string) (Job, bool, error) {
 
 		res, err := tx.Exec(
 			`UPDATE jobs
-			    SET state = ?, claimed_at = ?, updated_at = ?
+			    SET state = ?, claimed_at = ?, updated_at = ?, claim_count = claim_count + 1
 			  WHERE id = ? AND state = ?`,
 			jobStateClaimed,
 			nowRFC3339Nano(),
@@ -849,7 +1033,13 @@ func (s *Store) ClaimNextJob(deviceID string) (Job, bool, error) {
 	return Job{}, false, nil
 }
 
-// CompleteJob stores terminal status and execution outputs.
+// errJobNotClaimed is returned by CompleteJob for a job that is no longer
+// claimed: it finished, was cancelled, or was reclaimed from a silent device.
+var errJobNotClaimed = errors.New("job is not claimed")
+
+// CompleteJob stores terminal status and execution outputs. Only the device
+// holding the claim can complete a job, and only while it is claimed, so a
+// late report never overwrites a cancel or a reclaim.
 func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 	in.JobID = strings.TrimSpace(in.JobID)
 	in.DeviceID = strings.TrimSpace(in.DeviceID)
@@ -884,7 +1074,7 @@ func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 		        commit_msg_result = CASE WHEN ? = '' THEN commit_msg_result ELSE ? END,
 		        completed_at = ?,
 		        updated_at = ?
-		  WHERE id = ? AND device_id = ?`,
+		  WHERE id = ? AND device_id = ? AND state = ?`,
 		nextState,
 		in.Error,
 		in.SessionID, in.SessionID,
@@ -897,6 +1087,7 @@ func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 		nowRFC3339Nano(),
 		in.JobID,
 		in.DeviceID,
+		jobStateClaimed,
 	)
 	if err != nil {
 		return Job{}, fmt.Errorf("complete job: %w", err)
@@ -905,16 +1096,16 @@ func (s *Store) CompleteJob(in JobCompletion) (Job, error) {
 	if err != nil {
 		return Job{}, fmt.Errorf("rows affected: %w", err)
 	}
-	if rows == 0 {
-		return Job{}, errors.New("job not found for device")
-	}
 
 	job, found, err := s.GetJob(in.JobID)
 	if err != nil {
 		return Job{}, err
 	}
-	if !found {
-		return Job{}, errors.New("job not found after update")
+	if !found || job.DeviceID != in.DeviceID {
+		return Job{}, errors.New("job not found for device")
+	}
+	if rows == 0 {
+		return job, fmt.Errorf("%w: job %s is %s", errJobNotClaimed, job.ID, job.State)
 	}
 	return job, nil
 }
@@ -939,10 +1130,11 @@ func getJobTx(q queryRower, jobID string) (Job, bool, error) {
 	var updatedAtRaw string
 	var claimedAtRaw sql.NullString
 	var completedAtRaw sql.NullString
+	var cancelRequested int
 	err := q.QueryRow(
 		`SELECT id, device_id, team_id, channel_id, root_ts, slack_user_id, kind, repo, tool, model, autopr,
 		        branch_name, commit_msg, prompt, session_id, run_id, branch, pr_url, state, error,
-		        created_at, updated_at, claimed_at, completed_at
+		        created_at, updated_at, claimed_at, completed_at, claim_count, cancel_requested
 		   FROM jobs WHERE id = ?`,
 		jobID,
 	).Scan(
@@ -970,6 +1162,8 @@ func getJobTx(q queryRower, jobID string) (Job, bool, error) {
 		&updatedAtRaw,
 		&claimedAtRaw,
 		&completedAtRaw,
+		&job.ClaimCount,
+		&cancelRequested,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return Job{}, false, nil
@@ -978,6 +1172,7 @@ func getJobTx(q queryRower, jobID string) (Job, bool, error) {
 		return Job{}, false, fmt.Errorf("get job: %w", err)
 	}
 	job.AutoPR = autopr == 1
+	job.CancelRequested = cancelRequested == 1
 	var parseErr error
 	job.CreatedAt, parseErr = time.Parse(time.RFC3339Nano, createdAtRaw)
 	if parseErr != nil {
@@ -1,6 +1,7 @@
 package cloud
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -106,7 +107,7 @@ func TestClaimPairingRequestIssuesTokenAndSupportsSameDeviceMultiWorkspace(t *te
 		t.Fatalf("create pairing request failed: %v", err)
 	}
 
-	claim, err := store.ClaimPairingRequest(req.Code, "device-a", "")
+	claim, err := store.ClaimPairingRequest(req.Code, "device-a", "", false)
 	if err != nil {
 		t.Fatalf("claim pairing request failed: %v", err)
 	}
@@ -119,7 +120,7 @@ func TestClaimPairingRequestIssuesTokenAndSupportsSameDeviceMultiWorkspace(t *te
 	if err != nil {
 		t.Fatalf("create second pairing request failed: %v", err)
 	}
-	claim2, err := store.ClaimPairingRequest(req2.Code, "device-a", claim.DeviceToken)
+	claim2, err := store.ClaimPairingRequest(req2.Code, "device-a", claim.DeviceToken, false)
 	if err != nil {
 		t.Fatalf("claim second pairing request failed: %v", err)
 	}
@@ -136,7 +137,7 @@ func TestClaimPairingRequestRejectsDifferentDeviceUntilUnpair(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create pairing request failed: %v", err)
 	}
-	_, err = store.ClaimPairingRequest(req.Code, "device-a", "")
+	_, err = store.ClaimPairingRequest(req.Code, "device-a", "", false)
 	if err != nil {
 		t.Fatalf("initial claim failed: %v", err)
 	}
@@ -145,7 +146,7 @@ func TestClaimPairingRequestRejectsDifferentDeviceUntilUnpair(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create second request failed: %v", err)
 	}
-	if _, err := store.ClaimPairingRequest(req2.Code, "device-b", ""); err == nil {
+	if _, err := store.ClaimPairingRequest(req2.Code, "device-b", "", false); err == nil {
 		t.Fatal("expected claim rejection when user already paired with another device")
 	}
 
@@ -156,11 +157,90 @@ func TestClaimPairingRequestRejectsDifferentDeviceUntilUnpair(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create third request failed: %v", err)
 	}
-	if _, err := store.ClaimPairingRequest(req3.Code, "device-b", ""); err != nil {
+	if _, err := store.ClaimPairingRequest(req3.Code, "device-b", "", false); err != nil {
 		t.Fatalf("claim after strict unpair failed: %v", err)
 	}
 }
 
+func TestClaimPairingRequestForceMovesPairingToAuthenticatedDevice(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", "", false); err != nil {
+		t.Fatalf("initial claim failed: %v", err)
+	}
+
+	// device-b becomes a known device through its own pairing.
+	reqB, err := store.CreatePairingRequest("T1", "U2", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create device-b request failed: %v", err)
+	}
+	claimB, err := store.ClaimPairingRequest(reqB.Code, "device-b", "", false)
+	if err != nil {
+		t.Fatalf("device-b claim failed: %v", err)
+	}
+
+	req2, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create re-pair request failed: %v", err)
+	}
+	claim, err := store.ClaimPairingRequest(req2.Code, "device-b", claimB.DeviceToken, true)
+	if err != nil {
+		t.Fatalf("forced re-pair failed: %v", err)
+	}
+	if claim.ReplacedDeviceID != "device-a" {
+		t.Fatalf("unexpected replaced device: got %q want %q", claim.ReplacedDeviceID, "device-a")
+	}
+	if claim.DeviceToken != "" {
+		t.Fatal("expected no new token for existing authenticated device")
+	}
+
+	deviceID, found, err := store.GetPairing("T1", "U1")
+	if err != nil {
+		t.Fatalf("get pairing failed: %v", err)
+	}
+	if !found || deviceID != "device-b" {
+		t.Fatalf("unexpected pairing: found=%v device=%q", found, deviceID)
+	}
+}
+
+func TestClaimPairingRequestForceRejectsUnauthenticatedDevice(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", "", false); err != nil {
+		t.Fatalf("initial claim failed: %v", err)
+	}
+
+	req2, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create second request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req2.Code, "device-b", "", true); err == nil {
+		t.Fatal("expected forced claim without a token to be rejected")
+	}
+	// A made-up token for an unknown device would mint a new device.
+	if _, err := store.ClaimPairingRequest(req2.Code, "device-c", "bogus-token", true); err == nil {
+		t.Fatal("expected forced claim from an unknown device to be rejected")
+	}
+
+	deviceID, found, err := store.GetPairing("T1", "U1")
+	if err != nil {
+		t.Fatalf("get pairing failed: %v", err)
+	}
+	if !found || deviceID != "device-a" {
+		t.Fatalf("pairing changed after rejected claims: found=%v device=%q", found, deviceID)
+	}
+}
+
 func TestAuthenticateDevice(t *testing.T) {
 	store := newTestStore(t)
 	defer func() { _ = store.Close() }()
@@ -169,7 +249,7 @@ func TestAuthenticateDevice(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create pairing request failed: %v", err)
 	}
-	claim, err := store.ClaimPairingRequest(req.Code, "device-a", "")
+	claim, err := store.ClaimPairingRequest(req.Code, "device-a", "", false)
 	if err != nil {
 		t.Fatalf("claim pairing failed: %v", err)
 	}
@@ -189,7 +269,7 @@ func TestEnqueueClaimAndCompleteJob(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create pairing request failed: %v", err)
 	}
-	_, err = store.ClaimPairingRequest(req.Code, "device-a", "")
+	_, err = store.ClaimPairingRequest(req.Code, "device-a", "", false)
 	if err != nil {
 		t.Fatalf("claim pairing failed: %v", err)
 	}
@@ -257,3 +337,177 @@ func newTestStore(t *testing.T) *Store {
 	}
 	return store
 }
+
+func TestNewStoreRestrictsPermissions(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "cloud")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	store, err := NewStore(dataDir)
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	for path, want := range map[string]os.FileMode{
+		dataDir:                                0o700,
+		filepath.Join(dataDir, defaultDBName):  0o600,
+		filepath.Join(dataDir, defaultKeyName): 0o600,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat %s: %v", path, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: got %o want %o", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestReclaimStaleJobsRequeuesThenFails(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", "", false); err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	job, err := store.EnqueueJob(Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        jobKindStartSession,
+		Repo:        "owner/repo",
+		Prompt:      "implement auth",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+	backdate := func() {
+		t.Helper()
+		old := time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339Nano)
+		if _, err := store.db.Exec(`UPDATE jobs SET claimed_at = ? WHERE id = ?`, old, job.ID); err != nil {
+			t.Fatalf("backdate claim: %v", err)
+		}
+	}
+
+	for claim := 1; claim <= maxJobClaims; claim++ {
+		claimed, found, err := store.ClaimNextJob("device-a")
+		if err != nil || !found {
+			t.Fatalf("claim %d: found=%v err=%v", claim, found, err)
+		}
+		if claimed.ClaimCount != claim {
+			t.Fatalf("claim %d: claim_count = %d", claim, claimed.ClaimCount)
+		}
+
+		// A fresh claim is left alone.
+		if requeued, failed, err := store.ReclaimStaleJobs(time.Hour); err != nil || requeued != 0 || len(failed) != 0 {
+			t.Fatalf("fresh claim reclaimed: requeued=%d failed=%d err=%v", requeued, len(failed), err)
+		}
+
+		backdate()
+		requeued, failed, err := store.ReclaimStaleJobs(time.Hour)
+		if err != nil {
+			t.Fatalf("reclaim %d: %v", claim, err)
+		}
+		got, _, _ := store.GetJob(job.ID)
+		if claim < maxJobClaims {
+			if requeued != 1 || len(failed) != 0 || got.State != jobStateQueued || got.ClaimedAt != nil {
+				t.Fatalf("reclaim %d: requeued=%d failed=%d job=%+v", claim, requeued, len(failed), got)
+			}
+			continue
+		}
+		if requeued != 0 || len(failed) != 1 || failed[0].ID != job.ID {
+			t.Fatalf("last reclaim: requeued=%d failed=%+v", requeued, failed)
+		}
+		if got.State != jobStateFailed || got.Error == "" || got.CompletedAt == nil {
+			t.Fatalf("unexpected failed job: %+v", got)
+		}
+	}
+	if _, found, _ := store.ClaimNextJob("device-a"); found {
+		t.Fatal("a failed job was claimable")
+	}
+}
+
+func TestJobClaimIsRenewedAndOnlyClaimedJobsComplete(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	req, err := store.CreatePairingRequest("T1", "U1", "C1", "111.222", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("create pairing request failed: %v", err)
+	}
+	if _, err := store.ClaimPairingRequest(req.Code, "device-a", "", false); err != nil {
+		t.Fatalf("claim pairing failed: %v", err)
+	}
+	job, err := store.EnqueueJob(Job{
+		DeviceID:    "device-a",
+		TeamID:      "T1",
+		ChannelID:   "C1",
+		RootTS:      "111.222",
+		SlackUserID: "U1",
+		Kind:        jobKindStartSession,
+		Repo:        "owner/repo",
+		Prompt:      "implement auth",
+	})
+	if err != nil {
+		t.Fatalf("enqueue job failed: %v", err)
+	}
+	if _, found, err := store.ClaimNextJob("device-a"); err != nil || !found {
+		t.Fatalf("claim: found=%v err=%v", found, err)
+	}
+	backdate := func() {
+		t.Helper()
+		old := time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339Nano)
+		if _, err := store.db.Exec(`UPDATE jobs SET claimed_at = ? WHERE id = ?`, old, job.ID); err != nil {
+			t.Fatalf("backdate claim: %v", err)
+		}
+	}
+
+	// A device that is still reporting keeps its claim however long it runs.
+	backdate()
+	if ok, err := store.TouchJobClaim(job.ID, "device-b"); err != nil || ok {
+		t.Fatalf("another device renewed the claim: ok=%v err=%v", ok, err)
+	}
+	if ok, err := store.TouchJobClaim(job.ID, "device-a"); err != nil || !ok {
+		t.Fatalf("renew claim: ok=%v err=%v", ok, err)
+	}
+	if requeued, failed, err := store.ReclaimStaleJobs(time.Hour); err != nil || requeued != 0 || len(failed) != 0 {
+		t.Fatalf("renewed claim reclaimed: requeued=%d failed=%d err=%v", requeued, len(failed), err)
+	}
+
+	// Once it goes silent the job is requeued, and its late report is refused.
+	backdate()
+	if requeued, _, err := store.ReclaimStaleJobs(time.Hour); err != nil || requeued != 1 {
+		t.Fatalf("reclaim: requeued=%d err=%v", requeued, err)
+	}
+	if ok, err := store.TouchJobClaim(job.ID, "device-a"); err != nil || ok {
+		t.Fatalf("renewed a requeued job: ok=%v err=%v", ok, err)
+	}
+	_, err = store.CompleteJob(JobCompletion{JobID: job.ID, DeviceID: "device-a", Success: true, Branch: "fog/late"})
+	if !errors.Is(err, errJobNotClaimed) {
+		t.Fatalf("complete requeued job err = %v, want errJobNotClaimed", err)
+	}
+	if got, _, _ := store.GetJob(job.ID); got.State != jobStateQueued || got.Branch != "" {
+		t.Fatalf("late completion changed the job: %+v", got)
+	}
+
+	if _, found, err := store.ClaimNextJob("device-a"); err != nil || !found {
+		t.Fatalf("reclaim job: found=%v err=%v", found, err)
+	}
+	if _, err := store.CompleteJob(JobCompletion{JobID: job.ID, DeviceID: "device-b", Success: true}); err == nil || errors.Is(err, errJobNotClaimed) {
+		t.Fatalf("complete from another device err = %v, want not found", err)
+	}
+	done, err := store.CompleteJob(JobCompletion{JobID: job.ID, DeviceID: "device-a", Success: true, Branch: "fog/done"})
+	if err != nil || done.State != jobStateCompleted {
+		t.Fatalf("complete: job=%+v err=%v", done, err)
+	}
+	if _, err := store.CompleteJob(JobCompletion{JobID: job.ID, DeviceID: "device-a", Success: false, Error: "again"}); !errors.Is(err, errJobNotClaimed) {
+		t.Fatalf("second completion err = %v, want errJobNotClaimed", err)
+	}
+}
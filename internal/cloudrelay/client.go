@@ -29,10 +29,27 @@ type Client struct {
 }
 
 type PairClaimResponse struct {
-	TeamID      string `json:"team_id"`
-	SlackUserID string `json:"slack_user_id"`
-	DeviceID    string `json:"device_id"`
-	DeviceToken string `json:"device_token,omitempty"`
+	TeamID           string `json:"team_id"`
+	SlackUserID      string `json:"slack_user_id"`
+	DeviceID         string `json:"device_id"`
+	DeviceToken      string `json:"device_token,omitempty"`
+	ReplacedDeviceID string `json:"replaced_device_id,omitempty"`
+	Label            string `json:"label,omitempty"`
+	Default          bool   `json:"default"`
+}
+
+// PairClaimOptions says how a claim treats a user who already has a device
+// paired.
+type PairClaimOptions struct {
+	// Force moves the user to this device, unpairing their others; the cloud
+	// requires this device to already hold a valid token for that.
+	Force bool
+	// AddDevice pairs this device alongside the user's others.
+	AddDevice bool
+	// Default makes this device the one jobs go to when they name none.
+	Default bool
+	// Label is the name users pick this device by, as in device='laptop'.
+	Label string
 }
 
 type CompletePayload struct {
@@ -46,6 +63,23 @@ type CompletePayload struct {
 	CommitMsg string `json:"commit_msg,omitempty"`
 }
 
+// JobEvent is one progress update for a running job, in the shape of the run
+// event it was taken from.
+type JobEvent struct {
+	Type    string `json:"type"`
+	Message string `json:"message,omitempty"`
+	Data    string `json:"data,omitempty"`
+}
+
+// JobStatus is the cloud's view of a job this device claimed.
+type JobStatus struct {
+	JobID string `json:"job_id"`
+	State string `json:"state"`
+	// CancelRequested says the requester asked to stop the job, as with
+	// "@fog stop" in its Slack thread.
+	CancelRequested bool `json:"cancel_requested"`
+}
+
 func NewClient(cfg ClientConfig) (*Client, error) {
 	cfg.BaseURL = strings.TrimSpace(cfg.BaseURL)
 	if cfg.BaseURL == "" {
@@ -79,7 +113,9 @@ func (c *Client) DeviceToken() string {
 	return c.deviceToken
 }
 
-func (c *Client) ClaimPairing(ctx context.Context, code string) (PairClaimResponse, error) {
+// ClaimPairing claims a pairing code for this device. A user paired to another
+// device is rejected unless opts forces the move or adds this device.
+func (c *Client) ClaimPairing(ctx context.Context, code string, opts PairClaimOptions) (PairClaimResponse, error) {
 	code = strings.TrimSpace(code)
 	if code == "" {
 		return PairClaimResponse{}, errors.New("pair code is required")
@@ -88,13 +124,25 @@ func (c *Client) ClaimPairing(ctx context.Context, code string) (PairClaimRespon
 		return PairClaimResponse{}, errors.New("device id is required")
 	}
 
-	payload := map[string]string{
+	payload := map[string]any{
 		"code":      code,
 		"device_id": c.deviceID,
 	}
 	if strings.TrimSpace(c.deviceToken) != "" {
 		payload["device_token"] = c.deviceToken
 	}
+	if opts.Force {
+		payload["force"] = true
+	}
+	if opts.AddDevice {
+		payload["add_device"] = true
+	}
+	if opts.Default {
+		payload["default"] = true
+	}
+	if label := strings.TrimSpace(opts.Label); label != "" {
+		payload["label"] = label
+	}
 	body, _ := json.Marshal(payload)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/pair/claim", bytes.NewReader(body))
 	if err != nil {
@@ -195,6 +243,64 @@ func (c *Client) CompleteJob(ctx context.Context, jobID string, payload Complete
 	return nil
 }
 
+// SendJobEvent reports progress on a claimed job. The cloud decides whether
+// the event is worth a reply in the job's Slack thread.
+func (c *Client) SendJobEvent(ctx context.Context, jobID string, event JobEvent) error {
+	if err := c.requireDeviceAuth(); err != nil {
+		return err
+	}
+	jobID = strings.TrimSpace(jobID)
+	if jobID == "" {
+		return errors.New("job id is required")
+	}
+	body, _ := json.Marshal(event)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/device/jobs/"+jobID+"/events", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.addDeviceAuthHeaders(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return decodeAPIError(resp)
+	}
+	return nil
+}
+
+// JobStatus fetches the status of a job this device claimed, to learn
+// whether it should be stopped.
+func (c *Client) JobStatus(ctx context.Context, jobID string) (JobStatus, error) {
+	if err := c.requireDeviceAuth(); err != nil {
+		return JobStatus{}, err
+	}
+	jobID = strings.TrimSpace(jobID)
+	if jobID == "" {
+		return JobStatus{}, errors.New("job id is required")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/device/jobs/"+jobID+"/status", nil)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	c.addDeviceAuthHeaders(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return JobStatus{}, decodeAPIError(resp)
+	}
+	var status JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return JobStatus{}, err
+	}
+	return status, nil
+}
+
 func (c *Client) requireDeviceAuth() error {
 	if strings.TrimSpace(c.deviceID) == "" || strings.TrimSpace(c.deviceToken) == "" {
 		return errors.New("device auth is required")
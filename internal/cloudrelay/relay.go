@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -13,6 +13,34 @@ import (
 	"github.com/darkLord19/foglet/internal/state"
 )
 
+// errDeviceShuttingDown is reported for a job whose run was cut short because
+// the daemon began shutting down while it was in flight.
+const errDeviceShuttingDown = "device shutting down"
+
+// shutdownReportTimeout bounds the completion report sent after the relay
+// context is cancelled, so a hung cloud cannot stall daemon exit.
+const shutdownReportTimeout = 5 * time.Second
+
+// runWatchInterval is how often a job's run is polled for a new state while
+// the relay waits on it.
+const runWatchInterval = 700 * time.Millisecond
+
+// errJobStopped is reported for a job whose run was cancelled because its
+// requester asked to stop it.
+var errJobStopped = errors.New("stopped by user")
+
+// jobStatusInterval is how often the cloud is asked whether a running job
+// should be stopped. Tests shorten it.
+var jobStatusInterval = 3 * time.Second
+
+// runEventBuffer is how many run events, across all runs, the watch may fall
+// behind by before it misses some.
+const runEventBuffer = 256
+
+// jobEventTimeout bounds each progress report, so a slow cloud delays the
+// watch by at most this much per event.
+const jobEventTimeout = 5 * time.Second
+
 type RelayConfig struct {
 	PollInterval time.Duration
 }
@@ -22,6 +50,8 @@ type Relay struct {
 	runner       *runner.Runner
 	stateStore   *state.Store
 	pollInterval time.Duration
+	// cancelRun cancels a session's latest run; tests replace it.
+	cancelRun func(sessionID string) (state.Run, error)
 }
 
 func New(client *Client, run *runner.Runner, stateStore *state.Store, cfg RelayConfig) (*Relay, error) {
@@ -42,6 +72,7 @@ func New(client *Client, run *runner.Runner, stateStore *state.Store, cfg RelayC
 		runner:       run,
 		stateStore:   stateStore,
 		pollInterval: cfg.PollInterval,
+		cancelRun:    run.CancelSessionLatestRun,
 	}, nil
 }
 
@@ -56,7 +87,7 @@ func (r *Relay) Run(ctx context.Context) error {
 
 		processed, err := r.processOne(ctx)
 		if err != nil {
-			log.Printf("cloud relay error: %v", err)
+			slog.Warn("cloud relay error", "err", err)
 			select {
 			case <-ctx.Done():
 				return nil
@@ -83,20 +114,43 @@ func (r *Relay) processOne(ctx context.Context) (bool, error) {
 	if !found {
 		return false, nil
 	}
+	slog.Info("cloud job claimed", "job_id", job.ID, "kind", job.Kind, "session_id", job.SessionID, "repo", job.Repo)
 
-	payload := r.handleJob(job)
-	if err := r.client.CompleteJob(ctx, job.ID, payload); err != nil {
-		return true, err
+	payload := r.handleJob(ctx, job)
+	if err := r.completeJob(ctx, job, payload); err != nil {
+		return true, fmt.Errorf("complete job %s: %w", job.ID, err)
+	}
+	if payload.Success {
+		slog.Info("cloud job completed", "job_id", job.ID, "session_id", payload.SessionID, "run_id", payload.RunID)
+	} else {
+		slog.Warn("cloud job failed", "job_id", job.ID, "session_id", payload.SessionID, "run_id", payload.RunID, "err", payload.Error)
 	}
 	return true, nil
 }
 
-func (r *Relay) handleJob(job cloud.Job) CompletePayload {
+// completeJob reports a job's outcome. A job that failed after ctx was
+// cancelled failed because the daemon is stopping, so it is reported as such
+// rather than with the run's own cancellation error. The report itself must
+// not inherit ctx: it would be cancelled already and the job would be left
+// claimed, with the Slack thread waiting on it forever.
+func (r *Relay) completeJob(ctx context.Context, job cloud.Job, payload CompletePayload) error {
+	if ctx.Err() == nil {
+		return r.client.CompleteJob(ctx, job.ID, payload)
+	}
+	if !payload.Success {
+		payload.Error = errDeviceShuttingDown
+	}
+	reportCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownReportTimeout)
+	defer cancel()
+	return r.client.CompleteJob(reportCtx, job.ID, payload)
+}
+
+func (r *Relay) handleJob(ctx context.Context, job cloud.Job) CompletePayload {
 	switch strings.TrimSpace(job.Kind) {
 	case "start_session":
-		return r.handleStartSession(job)
+		return r.handleStartSession(ctx, job)
 	case "follow_up":
-		return r.handleFollowUp(job)
+		return r.handleFollowUp(ctx, job)
 	default:
 		return CompletePayload{
 			Success: false,
@@ -105,7 +159,7 @@ func (r *Relay) handleJob(job cloud.Job) CompletePayload {
 	}
 }
 
-func (r *Relay) handleStartSession(job cloud.Job) CompletePayload {
+func (r *Relay) handleStartSession(ctx context.Context, job cloud.Job) CompletePayload {
 	session, run, err := r.runner.Launch(runner.LaunchRequest{
 		Entrypoint: "cloud",
 		RepoName:   job.Repo,
@@ -117,47 +171,151 @@ func (r *Relay) handleStartSession(job cloud.Job) CompletePayload {
 		CommitMsg:  job.CommitMsg,
 		// A relayed job did not originate at this machine.
 		RejectProtectedBranch: true,
+		// Run in the background so progress can be relayed while it runs.
+		Async: true,
 	})
 	if err != nil {
 		return CompletePayload{Success: false, Error: err.Error()}
 	}
-	return CompletePayload{
-		Success:   true,
-		SessionID: session.ID,
-		RunID:     run.ID,
-		Branch:    session.Branch,
-		PRURL:     strings.TrimSpace(session.PRURL),
-		CommitSHA: strings.TrimSpace(run.CommitSHA),
-		CommitMsg: strings.TrimSpace(run.CommitMsg),
-	}
+	run, err = r.watchRun(ctx, job.ID, run)
+	return r.runPayload(session.ID, run, err)
 }
 
-func (r *Relay) handleFollowUp(job cloud.Job) CompletePayload {
+func (r *Relay) handleFollowUp(ctx context.Context, job cloud.Job) CompletePayload {
 	sessionID := strings.TrimSpace(job.SessionID)
 	if sessionID == "" {
 		return CompletePayload{Success: false, Error: "missing session_id"}
 	}
-	run, err := r.runner.ContinueSession(sessionID, strings.TrimSpace(job.Prompt))
+	run, err := r.runner.ContinueSessionAsync(sessionID, strings.TrimSpace(job.Prompt))
+	if err == nil {
+		run, err = r.watchRun(ctx, job.ID, run)
+	}
+	return r.runPayload(sessionID, run, err)
+}
+
+// runPayload reports a job from its session and run as they ended. A run that
+// did not complete fails the job with the run's error.
+func (r *Relay) runPayload(sessionID string, run state.Run, err error) CompletePayload {
 	session, _, _ := r.runner.GetSession(sessionID)
-	if err != nil {
-		return CompletePayload{
-			Success:   false,
-			Error:     err.Error(),
-			SessionID: sessionID,
-			RunID:     strings.TrimSpace(run.ID),
-			Branch:    strings.TrimSpace(session.Branch),
-			PRURL:     strings.TrimSpace(session.PRURL),
-			CommitSHA: strings.TrimSpace(run.CommitSHA),
-			CommitMsg: strings.TrimSpace(run.CommitMsg),
-		}
+	if err == nil && run.State != "COMPLETED" {
+		err = errors.New(fallback(run.Error, "run "+strings.ToLower(run.State)))
 	}
-	return CompletePayload{
-		Success:   true,
+	payload := CompletePayload{
+		Success:   err == nil,
 		SessionID: sessionID,
-		RunID:     run.ID,
+		RunID:     strings.TrimSpace(run.ID),
 		Branch:    strings.TrimSpace(session.Branch),
 		PRURL:     strings.TrimSpace(session.PRURL),
 		CommitSHA: strings.TrimSpace(run.CommitSHA),
 		CommitMsg: strings.TrimSpace(run.CommitMsg),
 	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	return payload
+}
+
+// watchRun waits for run to finish, relaying its progress to the cloud as it
+// goes: each new state as a phase event, and its AI output. Every
+// jobStatusInterval it asks the cloud whether the job should be stopped, and
+// cancels the run when it should. It returns the run as it ended, with
+// errJobStopped if it was stopped, or as last seen with ctx's error once ctx
+// is cancelled.
+//
+// Progress is best effort. A report that fails is dropped, so a cloud without
+// the events or status endpoints still gets the completion.
+func (r *Relay) watchRun(ctx context.Context, jobID string, run state.Run) (state.Run, error) {
+	events, unsubscribe := r.stateStore.SubscribeRunEvents(runEventBuffer)
+	defer unsubscribe()
+	ticker := time.NewTicker(runWatchInterval)
+	defer ticker.Stop()
+	statusTicker := time.NewTicker(jobStatusInterval)
+	defer statusTicker.Stop()
+
+	lastState := ""
+	stopped := false
+	for {
+		latest, found, err := r.stateStore.GetRun(run.ID)
+		if err != nil {
+			return run, err
+		}
+		if !found {
+			return run, fmt.Errorf("run %q disappeared", run.ID)
+		}
+		run = latest
+		if isTerminalRunState(run.State) {
+			if stopped && run.State == "CANCELLED" {
+				return run, errJobStopped
+			}
+			return run, nil
+		}
+		if run.State != lastState {
+			lastState = run.State
+			r.sendJobEvent(ctx, jobID, JobEvent{Type: "phase", Message: run.State})
+		}
+
+		select {
+		case <-ctx.Done():
+			return run, ctx.Err()
+		case <-ticker.C:
+		case <-statusTicker.C:
+			if !stopped && r.stopRequested(ctx, jobID) {
+				stopped = r.stopRun(jobID, run)
+			}
+		case event := <-events:
+			if event.RunID == run.ID && event.Type == "ai_output" {
+				r.sendJobEvent(ctx, jobID, JobEvent{Type: event.Type, Message: event.Message, Data: event.Data})
+			}
+		}
+	}
+}
+
+// stopRequested asks the cloud whether jobID should be stopped. A failed
+// check counts as no: the next one may get through.
+func (r *Relay) stopRequested(ctx context.Context, jobID string) bool {
+	checkCtx, cancel := context.WithTimeout(ctx, jobEventTimeout)
+	defer cancel()
+	status, err := r.client.JobStatus(checkCtx, jobID)
+	if err != nil {
+		slog.Debug("cloud job status not fetched", "job_id", jobID, "err", err)
+		return false
+	}
+	return status.CancelRequested
+}
+
+// stopRun cancels run for jobID, reporting whether it did. A run that cannot
+// be cancelled yet, as before its AI process starts, is tried again on the
+// next status check.
+func (r *Relay) stopRun(jobID string, run state.Run) bool {
+	if _, err := r.cancelRun(run.SessionID); err != nil {
+		slog.Warn("cloud job stop failed", "job_id", jobID, "session_id", run.SessionID, "run_id", run.ID, "err", err)
+		return false
+	}
+	slog.Info("cloud job stopped", "job_id", jobID, "session_id", run.SessionID, "run_id", run.ID)
+	return true
+}
+
+func (r *Relay) sendJobEvent(ctx context.Context, jobID string, event JobEvent) {
+	sendCtx, cancel := context.WithTimeout(ctx, jobEventTimeout)
+	defer cancel()
+	if err := r.client.SendJobEvent(sendCtx, jobID, event); err != nil {
+		slog.Debug("cloud job event not delivered", "job_id", jobID, "type", event.Type, "err", err)
+	}
+}
+
+func isTerminalRunState(stateName string) bool {
+	switch strings.TrimSpace(stateName) {
+	case "COMPLETED", "FAILED", "CANCELLED":
+		return true
+	default:
+		return false
+	}
+}
+
+func fallback(v, alt string) string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return strings.TrimSpace(alt)
+	}
+	return v
 }
@@ -1,10 +1,18 @@
 package cloudrelay
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/darkLord19/foglet/internal/cloud"
+	"github.com/darkLord19/foglet/internal/state"
 )
 
 // Branch naming and launch resolution now live behind runner.Launch, and are
@@ -14,7 +22,7 @@ import (
 
 func TestHandleUnknownJobKind(t *testing.T) {
 	r := &Relay{}
-	out := r.handleJob(cloud.Job{Kind: "unknown"})
+	out := r.handleJob(context.Background(), cloud.Job{Kind: "unknown"})
 	if out.Success {
 		t.Fatal("expected unknown kind to fail")
 	}
@@ -22,3 +30,247 @@ func TestHandleUnknownJobKind(t *testing.T) {
 		t.Fatalf("unexpected error: %q", out.Error)
 	}
 }
+
+// completeServer records the payloads posted to the job completion endpoint.
+func completeServer(t *testing.T) (*Client, <-chan CompletePayload) {
+	t.Helper()
+	got := make(chan CompletePayload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/device/jobs/job-1/complete" {
+			http.NotFound(w, r)
+			return
+		}
+		var payload CompletePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got <- payload
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, DeviceID: "dev-1", DeviceToken: "tok"})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return client, got
+}
+
+func TestCompleteJobReportsShutdownAfterCancel(t *testing.T) {
+	client, got := completeServer(t)
+	r := &Relay{client: client}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := r.completeJob(ctx, cloud.Job{ID: "job-1"}, CompletePayload{
+		Success:   false,
+		Error:     "ai: context canceled",
+		SessionID: "session-1",
+	})
+	if err != nil {
+		t.Fatalf("completeJob with cancelled context: %v", err)
+	}
+	payload := <-got
+	if payload.Success || payload.Error != errDeviceShuttingDown {
+		t.Fatalf("expected shutdown failure, got %+v", payload)
+	}
+	if payload.SessionID != "session-1" {
+		t.Fatalf("expected session id to be kept, got %q", payload.SessionID)
+	}
+}
+
+func TestCompleteJobKeepsSuccessAfterCancel(t *testing.T) {
+	client, got := completeServer(t)
+	r := &Relay{client: client}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.completeJob(ctx, cloud.Job{ID: "job-1"}, CompletePayload{Success: true, RunID: "run-1"}); err != nil {
+		t.Fatalf("completeJob: %v", err)
+	}
+	if payload := <-got; !payload.Success || payload.Error != "" {
+		t.Fatalf("a job that finished before shutdown should stay successful, got %+v", payload)
+	}
+}
+
+// watchTestStore returns a state store holding session-1 with run-1 running
+// its AI step.
+func watchTestStore(t *testing.T) (*state.Store, state.Run) {
+	t.Helper()
+	st, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if _, err := st.UpsertRepo(state.Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		Owner:            "acme",
+		Repo:             "api",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+		DefaultBranch:    "main",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := st.CreateSession(state.Session{
+		ID:           "session-1",
+		RepoName:     "acme/api",
+		Branch:       "fog/test",
+		WorktreePath: "/tmp/worktree",
+		Tool:         "claude",
+		Status:       "CREATED",
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	run := state.Run{ID: "run-1", SessionID: "session-1", Prompt: "add auth", WorktreePath: "/tmp/worktree", State: "AI_RUNNING"}
+	if err := st.CreateRun(run); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	return st, run
+}
+
+func TestWatchRunRelaysPhasesAndOutput(t *testing.T) {
+	st, run := watchTestStore(t)
+	other := run
+	other.ID, other.State = "run-2", "COMPLETED"
+	if err := st.CreateRun(other); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	got := make(chan JobEvent, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/device/jobs/job-1/events" {
+			http.NotFound(w, r)
+			return
+		}
+		var event JobEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got <- event
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, DeviceID: "dev-1", DeviceToken: "tok"})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	r := &Relay{client: client, stateStore: st}
+
+	done := make(chan state.Run, 1)
+	go func() {
+		ended, err := r.watchRun(context.Background(), "job-1", run)
+		if err != nil {
+			t.Errorf("watchRun: %v", err)
+		}
+		done <- ended
+	}()
+
+	next := func() JobEvent {
+		t.Helper()
+		select {
+		case event := <-got:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a job event")
+			return JobEvent{}
+		}
+	}
+	if event := next(); event.Type != "phase" || event.Message != "AI_RUNNING" {
+		t.Fatalf("first event = %+v, want the AI_RUNNING phase", event)
+	}
+	for _, event := range []state.RunEvent{
+		{RunID: "run-1", Type: "ai_stream", Message: "partial"},
+		{RunID: "run-2", Type: "ai_output", Message: "another run"},
+		{RunID: "run-1", Type: "ai_output", Message: "Added auth middleware"},
+	} {
+		if err := st.AppendRunEvent(event); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+	if event := next(); event.Type != "ai_output" || event.Message != "Added auth middleware" {
+		t.Fatalf("second event = %+v, want this run's ai_output", event)
+	}
+	if err := st.SetRunState("run-1", "VALIDATING"); err != nil {
+		t.Fatalf("set run state: %v", err)
+	}
+	if event := next(); event.Type != "phase" || event.Message != "VALIDATING" {
+		t.Fatalf("third event = %+v, want the VALIDATING phase", event)
+	}
+	if err := st.CompleteRun("run-1", "COMPLETED", "abc123", "Add auth", ""); err != nil {
+		t.Fatalf("complete run: %v", err)
+	}
+	select {
+	case ended := <-done:
+		if ended.State != "COMPLETED" || ended.CommitSHA != "abc123" {
+			t.Fatalf("watchRun returned %+v, want the completed run", ended)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchRun did not return after the run completed")
+	}
+	select {
+	case event := <-got:
+		t.Fatalf("unexpected event after completion: %+v", event)
+	default:
+	}
+}
+
+func TestWatchRunStopsRunWhenCloudAsks(t *testing.T) {
+	prev := jobStatusInterval
+	jobStatusInterval = 20 * time.Millisecond
+	t.Cleanup(func() { jobStatusInterval = prev })
+	st, run := watchTestStore(t)
+
+	var stopAsked atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/device/jobs/job-1/status":
+			_ = json.NewEncoder(w).Encode(JobStatus{JobID: "job-1", State: "claimed", CancelRequested: stopAsked.Load()})
+		case "/v1/device/jobs/job-1/events":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	client, err := NewClient(ClientConfig{BaseURL: srv.URL, DeviceID: "dev-1", DeviceToken: "tok"})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	cancelled := make(chan string, 1)
+	r := &Relay{client: client, stateStore: st, cancelRun: func(sessionID string) (state.Run, error) {
+		cancelled <- sessionID
+		return run, st.CompleteRun(run.ID, "CANCELLED", "", "", "canceled")
+	}}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.watchRun(context.Background(), "job-1", run)
+		done <- err
+	}()
+	select {
+	case sessionID := <-cancelled:
+		t.Fatalf("run cancelled for %s before a stop was asked", sessionID)
+	case <-time.After(100 * time.Millisecond):
+	}
+	stopAsked.Store(true)
+	select {
+	case sessionID := <-cancelled:
+		if sessionID != "session-1" {
+			t.Fatalf("cancelled session %q, want session-1", sessionID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not cancelled after the cloud asked")
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, errJobStopped) {
+			t.Fatalf("watchRun err = %v, want errJobStopped", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchRun did not return after the run was cancelled")
+	}
+}
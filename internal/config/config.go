@@ -14,8 +14,15 @@ type Config struct {
 	WorktreeDir   string `json:"worktree_dir"`
 	AutoStartDev  bool   `json:"auto_start_dev"`
 	DefaultBranch string `json:"default_branch"`
-	SetupCmd      string `json:"setup_cmd"`    // Command to run after creating worktree
-	ValidateCmd   string `json:"validate_cmd"` // Command to validate worktree
+	SetupCmd      string `json:"setup_cmd"`           // Command to run after creating worktree
+	ValidateCmd   string `json:"validate_cmd"`        // Command to validate worktree
+	AutoOpen      *bool  `json:"auto_open,omitempty"` // Open new worktrees in the editor; nil means true
+}
+
+// ShouldAutoOpen reports whether `wtx add` opens the new worktree. It is a
+// pointer so config files written before auto_open existed keep opening.
+func (c *Config) ShouldAutoOpen() bool {
+	return c.AutoOpen == nil || *c.AutoOpen
 }
 
 // DefaultConfig returns default configuration
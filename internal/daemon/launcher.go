@@ -101,6 +101,7 @@ func startFogd(fogHome string, port int) error {
 		stateStore: application.Store,
 	}
 
+	application.SetReady(true)
 	go func() {
 		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Printf("embedded fogd stopped with error: %v", err)
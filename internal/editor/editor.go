@@ -21,6 +21,8 @@ func Detect(preferred string) (Editor, error) {
 		&Neovim{},
 		&ClaudeCode{},
 		&Vim{},
+		&Tmux{},
+		&Zellij{},
 	}
 
 	// If preferred editor is specified, try it first
@@ -64,6 +66,10 @@ func GetEditor(name string) Editor {
 		return &ClaudeCode{}
 	case "vim":
 		return &Vim{}
+	case "tmux":
+		return &Tmux{}
+	case "zellij":
+		return &Zellij{}
 	default:
 		return nil
 	}
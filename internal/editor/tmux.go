@@ -0,0 +1,62 @@
+package editor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Tmux opens a worktree in a tmux session named after it
+type Tmux struct{}
+
+func (t *Tmux) Name() string {
+	return "tmux"
+}
+
+func (t *Tmux) IsAvailable() bool {
+	return commandExists("tmux")
+}
+
+func (t *Tmux) Open(path string, reuse bool) error {
+	name := sessionName(path)
+	target := "=" + name // exact match, not a prefix
+
+	// Reuse the worktree's session if one is already running
+	if exec.Command("tmux", "has-session", "-t", target).Run() != nil {
+		if err := exec.Command("tmux", "new-session", "-d", "-s", name, "-c", path).Run(); err != nil {
+			return err
+		}
+	}
+
+	// Inside tmux, switch this client over to the session
+	if os.Getenv("TMUX") != "" {
+		return exec.Command("tmux", "switch-client", "-t", target).Run()
+	}
+
+	// Without a terminal to attach to (e.g. from fogd), leave the session
+	// running detached for the user to attach to
+	if !isTerminal() {
+		return nil
+	}
+
+	cmd := exec.Command("tmux", "attach-session", "-t", target)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// sessionName turns a worktree path into a multiplexer session name. tmux
+// does not allow '.' or ':' in session names.
+func sessionName(path string) string {
+	name := filepath.Base(filepath.Clean(path))
+	return strings.NewReplacer(".", "_", ":", "_").Replace(name)
+}
+
+// isTerminal reports whether stdin is a terminal a multiplexer can attach to
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
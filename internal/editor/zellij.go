@@ -0,0 +1,42 @@
+package editor
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Zellij opens a worktree in a zellij session named after it
+type Zellij struct{}
+
+func (z *Zellij) Name() string {
+	return "zellij"
+}
+
+func (z *Zellij) IsAvailable() bool {
+	return commandExists("zellij")
+}
+
+func (z *Zellij) Open(path string, reuse bool) error {
+	name := sessionName(path)
+
+	// Inside zellij, open the worktree in a new tab of the current session
+	if os.Getenv("ZELLIJ") != "" {
+		return exec.Command("zellij", "action", "new-tab", "--name", name, "--cwd", path).Run()
+	}
+
+	// Without a terminal to attach to (e.g. from fogd), start the session in
+	// the background for the user to attach to
+	if !isTerminal() {
+		cmd := exec.Command("zellij", "attach", "--create-background", name)
+		cmd.Dir = path
+		return cmd.Run()
+	}
+
+	// Attach to the worktree's session, creating it if needed
+	cmd := exec.Command("zellij", "attach", "--create", name)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = path
+	return cmd.Run()
+}
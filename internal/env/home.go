@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
 )
 
 const (
@@ -27,3 +30,79 @@ func FogHome() (string, error) {
 func ManagedReposDir(fogHome string) string {
 	return filepath.Join(fogHome, "repos")
 }
+
+// ManagedRepoDir returns the directory import created for a repo: the shared
+// parent of its bare clone and base worktree, which must sit strictly inside
+// managedReposDir. ok is false for a repo laid out any other way.
+func ManagedRepoDir(managedReposDir, barePath, baseWorktreePath string) (dir string, ok bool) {
+	dir = filepath.Dir(filepath.Clean(barePath))
+	if filepath.Dir(filepath.Clean(baseWorktreePath)) != dir {
+		return "", false
+	}
+	rel, err := filepath.Rel(filepath.Clean(managedReposDir), dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", false
+	}
+	return dir, true
+}
+
+// PurgeManagedRepo deletes the directory import created for a repo, and its
+// owner directory once that is empty. It returns the deleted directory, or
+// ok false without touching the disk when ManagedRepoDir rejects the layout.
+func PurgeManagedRepo(managedReposDir, barePath, baseWorktreePath string) (dir string, ok bool, err error) {
+	dir, ok = ManagedRepoDir(managedReposDir, barePath, baseWorktreePath)
+	if !ok {
+		return "", false, nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return dir, true, err
+	}
+	_ = os.Remove(filepath.Dir(dir))
+	return dir, true, nil
+}
+
+// UnmergedManagedBranches returns the branches of a managed repo's bare clone
+// that are not merged into defaultBranch: work PurgeManagedRepo would delete
+// along with the clone. It returns none when ManagedRepoDir rejects the
+// layout, as nothing is purged then, or when barePath is not a repository.
+func UnmergedManagedBranches(managedReposDir, barePath, baseWorktreePath, defaultBranch string) ([]string, error) {
+	if _, ok := ManagedRepoDir(managedReposDir, barePath, baseWorktreePath); !ok {
+		return nil, nil
+	}
+	g := git.New(barePath)
+	if !g.IsRepo() {
+		return nil, nil
+	}
+	branches, err := g.UnmergedBranches(defaultBranch)
+	if err != nil {
+		return nil, fmt.Errorf("list branches of %s: %w", barePath, err)
+	}
+	return branches, nil
+}
+
+// ParseFileMode parses a permission flag such as "0750" or "750" as octal.
+// Empty parses to 0, which the store constructors read as their default.
+func ParseFileMode(s string) (os.FileMode, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode == 0 || mode > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q: want octal permissions such as 0700", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// ParseStoreModes parses the directory and database permission flags fogd and
+// fogcloud pass to their store's StoreOptions. An error names the flag whose
+// value is invalid.
+func ParseStoreModes(dirFlag, dirValue, dbFlag, dbValue string) (dirMode, dbMode os.FileMode, err error) {
+	if dirMode, err = ParseFileMode(dirValue); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", dirFlag, err)
+	}
+	if dbMode, err = ParseFileMode(dbValue); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", dbFlag, err)
+	}
+	return dirMode, dbMode, nil
+}
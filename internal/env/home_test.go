@@ -2,7 +2,9 @@ package env
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -41,3 +43,106 @@ func TestFogHomeDefault(t *testing.T) {
 		t.Fatalf("FogHome mismatch: got %q want %q", got, want)
 	}
 }
+
+func TestManagedRepoDirStaysInsideManagedDir(t *testing.T) {
+	managed := "/home/me/.fog/repos"
+	cases := []struct {
+		bare, base string
+		ok         bool
+	}{
+		{"/home/me/.fog/repos/acme/api/repo.git", "/home/me/.fog/repos/acme/api/base", true},
+		{"/srv/acme/api/repo.git", "/srv/acme/api/base", false},
+		{"/home/me/.fog/repos/repo.git", "/home/me/.fog/repos/base", false},
+		{"/home/me/.fog/repos/acme/api/repo.git", "/home/me/.fog/repos/acme/other/base", false},
+		{"/home/me/.fog/repos-old/acme/api/repo.git", "/home/me/.fog/repos-old/acme/api/base", false},
+	}
+	for _, tc := range cases {
+		_, ok := ManagedRepoDir(managed, tc.bare, tc.base)
+		if ok != tc.ok {
+			t.Errorf("ManagedRepoDir(%s, %s) ok = %v, want %v", tc.bare, tc.base, ok, tc.ok)
+		}
+	}
+}
+
+func TestPurgeManagedRepo(t *testing.T) {
+	managed := ManagedReposDir(t.TempDir())
+	api := filepath.Join(managed, "acme", "api")
+	web := filepath.Join(managed, "acme", "web")
+	for _, dir := range []string{api, web} {
+		if err := os.MkdirAll(filepath.Join(dir, "base"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir, ok, err := PurgeManagedRepo(managed, filepath.Join(api, "repo.git"), filepath.Join(api, "base"))
+	if err != nil || !ok || dir != api {
+		t.Fatalf("PurgeManagedRepo = %q, %v, %v; want %q", dir, ok, err, api)
+	}
+	if _, err := os.Stat(api); !os.IsNotExist(err) {
+		t.Fatalf("repo dir still present: %v", err)
+	}
+	if _, err := os.Stat(web); err != nil {
+		t.Fatalf("sibling repo removed: %v", err)
+	}
+
+	if _, _, err := PurgeManagedRepo(managed, filepath.Join(web, "repo.git"), filepath.Join(web, "base")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(managed, "acme")); !os.IsNotExist(err) {
+		t.Fatalf("owner dir still present after its last repo: %v", err)
+	}
+
+	outside := t.TempDir()
+	if _, ok, err := PurgeManagedRepo(managed, filepath.Join(outside, "repo.git"), filepath.Join(outside, "base")); ok || err != nil {
+		t.Fatalf("PurgeManagedRepo outside the managed dir = %v, %v; want not ok", ok, err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Fatalf("unmanaged dir touched: %v", err)
+	}
+}
+
+func TestUnmergedManagedBranches(t *testing.T) {
+	managed := ManagedReposDir(t.TempDir())
+	api := filepath.Join(managed, "acme", "api")
+	bare := filepath.Join(api, "repo.git")
+	src := t.TempDir()
+	for _, step := range []struct {
+		dir  string
+		args []string
+	}{
+		{src, []string{"init", "-b", "main"}},
+		{src, []string{"-c", "user.email=t@example.com", "-c", "user.name=T", "commit", "--allow-empty", "-m", "base"}},
+		{src, []string{"branch", "fog/merged"}},
+		{src, []string{"checkout", "-b", "fog/unpushed"}},
+		{src, []string{"-c", "user.email=t@example.com", "-c", "user.name=T", "commit", "--allow-empty", "-m", "work"}},
+		{src, []string{"clone", "--bare", src, bare}},
+	} {
+		cmd := exec.Command("git", step.args...)
+		cmd.Dir = step.dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", step.args, err, out)
+		}
+	}
+
+	branches, err := UnmergedManagedBranches(managed, bare, filepath.Join(api, "base"), "main")
+	if err != nil || len(branches) != 1 || branches[0] != "fog/unpushed" {
+		t.Fatalf("UnmergedManagedBranches = %v, %v; want [fog/unpushed]", branches, err)
+	}
+	// Nothing is purged outside the managed dir, so nothing is at risk.
+	if branches, err := UnmergedManagedBranches(ManagedReposDir(t.TempDir()), bare, filepath.Join(api, "base"), "main"); err != nil || len(branches) != 0 {
+		t.Fatalf("UnmergedManagedBranches outside the managed dir = %v, %v; want none", branches, err)
+	}
+}
+
+func TestParseStoreModes(t *testing.T) {
+	dirMode, dbMode, err := ParseStoreModes("--home-mode", "750", "--db-mode", "")
+	if err != nil || dirMode != 0o750 || dbMode != 0 {
+		t.Fatalf("ParseStoreModes = %o, %o, %v; want 750, 0 (default), nil", dirMode, dbMode, err)
+	}
+	if _, _, err := ParseStoreModes("--home-mode", "0700", "--db-mode", "999"); err == nil || !strings.Contains(err.Error(), "--db-mode") {
+		t.Fatalf("ParseStoreModes with a bad --db-mode = %v, want an error naming the flag", err)
+	}
+	if _, _, err := ParseStoreModes("--home-mode", "rwx", "--db-mode", "0600"); err == nil || !strings.Contains(err.Error(), "--home-mode") {
+		t.Fatalf("ParseStoreModes with a bad --home-mode = %v, want an error naming the flag", err)
+	}
+}
@@ -2,15 +2,19 @@ package ghcli
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/darkLord19/foglet/internal/binpath"
 	"github.com/darkLord19/foglet/internal/proc"
@@ -23,6 +27,7 @@ type Repo struct {
 	NameWithOwner    string `json:"nameWithOwner"`
 	URL              string `json:"url"`
 	IsPrivate        bool   `json:"isPrivate"`
+	DiskUsage        int64  `json:"diskUsage"` // kilobytes, as GitHub reports it
 	DefaultBranchRef struct {
 		Name string `json:"name"`
 	} `json:"defaultBranchRef"`
@@ -36,9 +41,158 @@ var ErrGhNotFound = errors.New("gh CLI not found")
 var (
 	execCommand = exec.Command
 	ghPathFn    = ghPath
-	procRun     = proc.Run
+	procRun     = proc.RunEnv
 )
 
+// SettingGhPath is the settings key holding an explicit gh binary path.
+const SettingGhPath = "gh_path"
+
+// DefaultHost is the host gh talks to when none is given.
+const DefaultHost = "github.com"
+
+var hostLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NormalizeHost checks that raw is a plausible GitHub hostname, such as
+// github.com or a GitHub Enterprise Server host with an optional port, and
+// returns it lowercased. An empty raw stays empty, meaning gh's default host.
+func NormalizeHost(raw string) (string, error) {
+	host := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(raw)), ".")
+	if host == "" {
+		return "", nil
+	}
+	name, port, hasPort := strings.Cut(host, ":")
+	if hasPort {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid host %q: bad port", raw)
+		}
+	}
+	if len(name) > 253 {
+		return "", fmt.Errorf("invalid host %q: too long", raw)
+	}
+	for label := range strings.SplitSeq(name, ".") {
+		if !hostLabelPattern.MatchString(label) {
+			return "", fmt.Errorf("invalid host %q: want a hostname like github.example.com", raw)
+		}
+	}
+	return host, nil
+}
+
+// CloneName is the name to hand `gh repo clone` for repo: owner/repo on
+// github.com, HOST/owner/repo on any other host, so an Enterprise repo is
+// cloned from where it was discovered.
+func CloneName(repo Repo) string {
+	u, err := url.Parse(repo.URL)
+	if err != nil || u.Host == "" || strings.EqualFold(u.Host, DefaultHost) {
+		return repo.NameWithOwner
+	}
+	return u.Host + "/" + repo.NameWithOwner
+}
+
+// Config controls how Fog invokes gh. The zero value looks gh up on PATH and
+// relies on whatever `gh auth login` left behind.
+type Config struct {
+	// Path is an explicit gh binary. Empty means look it up.
+	Path string
+	// Token, when set, is passed to gh as GH_TOKEN so it authenticates with a
+	// dedicated token rather than the user's interactive login.
+	Token string
+}
+
+// ConfigStore is the subset of the state store gh configuration is read from.
+type ConfigStore interface {
+	GetSetting(key string) (value string, found bool, err error)
+	GetGitHubToken() (token string, found bool, err error)
+}
+
+var (
+	configMu     sync.RWMutex
+	configSource func() Config
+)
+
+// SetConfigSource installs fn as the source of gh configuration. It is
+// consulted on every invocation, so a changed setting or token takes effect
+// without a restart. A nil fn restores the defaults.
+func SetConfigSource(fn func() Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	configSource = fn
+}
+
+// StoreConfigSource reads the gh_path setting and the stored GitHub token from
+// st. Lookup errors are treated as unset so a broken store degrades to ambient
+// gh rather than disabling it.
+func StoreConfigSource(st ConfigStore) func() Config {
+	return func() Config {
+		var cfg Config
+		if path, found, err := st.GetSetting(SettingGhPath); err == nil && found {
+			cfg.Path = strings.TrimSpace(path)
+		}
+		if token, found, err := st.GetGitHubToken(); err == nil && found {
+			cfg.Token = strings.TrimSpace(token)
+		}
+		return cfg
+	}
+}
+
+func currentConfig() Config {
+	configMu.RLock()
+	fn := configSource
+	configMu.RUnlock()
+	if fn == nil {
+		return Config{}
+	}
+	return fn()
+}
+
+// ghEnviron returns the environment for a gh child process, or nil to inherit
+// the parent's unchanged when no token is configured and no host is given. A
+// host is passed as GH_HOST, which gh uses wherever it cannot infer one from
+// a local repository.
+func ghEnviron(base []string, host string) []string {
+	overrides := make(map[string]string, 2)
+	if token := currentConfig().Token; token != "" {
+		overrides["GH_TOKEN"] = token
+	}
+	if host != "" {
+		overrides["GH_HOST"] = host
+	}
+	if len(overrides) == 0 {
+		return nil
+	}
+	if base == nil {
+		base = os.Environ()
+	}
+	out := make([]string, 0, len(base)+len(overrides))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := overrides[key]; ok {
+			continue
+		}
+		out = append(out, kv)
+	}
+	for _, key := range []string{"GH_TOKEN", "GH_HOST"} {
+		if value, ok := overrides[key]; ok {
+			out = append(out, key+"="+value)
+		}
+	}
+	return out
+}
+
+// ghCommand builds a gh command carrying the configured token, if any.
+func ghCommand(gh string, args ...string) *exec.Cmd {
+	return ghHostCommand(gh, "", args...)
+}
+
+// ghHostCommand is ghCommand aimed at host; an empty host is gh's default.
+func ghHostCommand(gh, host string, args ...string) *exec.Cmd {
+	cmd := execCommand(gh, args...)
+	if env := ghEnviron(cmd.Env, host); env != nil {
+		cmd.Env = env
+	}
+	return cmd
+}
+
 // IsGhAvailable checks if the gh CLI tool is installed and available in the PATH.
 func IsGhAvailable() bool {
 	return ghPathFn() != ""
@@ -51,37 +205,49 @@ func IsGhAuthenticated() bool {
 	if gh == "" {
 		return false
 	}
-	cmd := execCommand(gh, "auth", "status")
+	cmd := ghCommand(gh, "auth", "status")
 	return cmd.Run() == nil
 }
 
-// DiscoverRepos fetches the list of repositories available to the authenticated user.
-func DiscoverRepos() ([]Repo, error) {
+// IsGhAuthenticatedOnHost reports whether gh is logged in to host, as with
+// `gh auth login --hostname host`.
+func IsGhAuthenticatedOnHost(host string) bool {
+	gh := ghPathFn()
+	if gh == "" {
+		return false
+	}
+	cmd := ghCommand(gh, "auth", "status", "--hostname", host)
+	return cmd.Run() == nil
+}
+
+// DiscoverRepos fetches the list of repositories available to the authenticated
+// user on host. An empty host is gh's default, normally github.com.
+func DiscoverRepos(host string) ([]Repo, error) {
 	gh := ghPathFn()
 	if gh == "" {
 		return nil, ErrGhNotFound
 	}
 
-	// --json fields: id,name,nameWithOwner,url,isPrivate,defaultBranchRef,owner
+	// --json fields: id,name,nameWithOwner,url,isPrivate,diskUsage,defaultBranchRef,owner
 	// --limit 200 to get a reasonable number of repos (per owner)
 	const (
 		repoLimit = 200
 		orgLimit  = 100
-		fields    = "id,name,nameWithOwner,url,isPrivate,defaultBranchRef,owner"
+		fields    = "id,name,nameWithOwner,url,isPrivate,diskUsage,defaultBranchRef,owner"
 	)
 
-	repos, err := listRepos(gh, "", fields, repoLimit)
+	repos, err := listRepos(gh, host, "", fields, repoLimit)
 	if err != nil {
 		return nil, err
 	}
 
-	orgs, err := listOrgs(gh, orgLimit)
+	orgs, err := listOrgs(gh, host, orgLimit)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, org := range orgs {
-		orgRepos, err := listRepos(gh, org, fields, repoLimit)
+		orgRepos, err := listRepos(gh, host, org, fields, repoLimit)
 		if err != nil {
 			return nil, err
 		}
@@ -93,29 +259,27 @@ func DiscoverRepos() ([]Repo, error) {
 	return repos, nil
 }
 
-// CloneRepo clones a repository by its full name (owner/repo) to the destination path.
+// CloneRepo clones a repository by its full name (owner/repo, or
+// HOST/owner/repo off github.com; see CloneName) to the destination path.
 // It uses --bare clone as required by the application architecture.
+//
+// When gh is missing or not logged in to the repository's host and a GitHub
+// token is configured, it clones with git over HTTPS using the token instead,
+// so a headless host with only a stored token can still import private
+// repositories.
 func CloneRepo(fullName, destPath string) error {
 	gh := ghPathFn()
+	if token := currentConfig().Token; token != "" && !ghCanClone(gh, fullName) {
+		return cloneWithToken(fullName, destPath, token)
+	}
 	if gh == "" {
 		return ErrGhNotFound
 	}
 
-	formatOutput := func(output []byte) string {
-		msg := strings.TrimSpace(string(output))
-		if len(msg) > 4096 {
-			msg = msg[:4096] + "..."
-		}
-		if msg != "" {
-			msg = "\n" + msg
-		}
-		return msg
-	}
-
 	// gh repo clone <repo> <directory> -- <git-args>
 	// Prefer a blobless filter for faster imports on large repos.
 	args := []string{"repo", "clone", fullName, destPath, "--", "--bare", "--filter=blob:none"}
-	cmd := execCommand(gh, args...)
+	cmd := ghCommand(gh, args...)
 	output, err := cmd.CombinedOutput()
 	if err == nil {
 		return nil
@@ -123,29 +287,127 @@ func CloneRepo(fullName, destPath string) error {
 
 	// Some environments ship an older git that doesn't support --filter.
 	// Retry without the filter in that case.
-	msgLower := strings.ToLower(string(output))
-	filterUnsupported := strings.Contains(msgLower, "unknown option") &&
-		(strings.Contains(msgLower, "--filter") || strings.Contains(msgLower, "filter=blob:none"))
-	if filterUnsupported {
+	if filterUnsupported(output) {
 		if removeErr := os.RemoveAll(destPath); removeErr != nil {
 			return fmt.Errorf("cleanup failed after clone retry: %w", removeErr)
 		}
-		cmd = execCommand(gh, "repo", "clone", fullName, destPath, "--", "--bare")
+		cmd = ghCommand(gh, "repo", "clone", fullName, destPath, "--", "--bare")
 		output, err = cmd.CombinedOutput()
 		if err == nil {
 			return nil
 		}
 	}
 
-	return fmt.Errorf("gh repo clone failed: %w%s", err, formatOutput(output))
+	return fmt.Errorf("gh repo clone failed: %w%s", err, formatCloneOutput(output))
+}
+
+// ghCanClone reports whether gh is installed and logged in to the host a
+// CloneName-style fullName lives on.
+func ghCanClone(gh, fullName string) bool {
+	if gh == "" {
+		return false
+	}
+	host, _ := splitCloneName(fullName)
+	if host == DefaultHost {
+		return IsGhAuthenticated()
+	}
+	return IsGhAuthenticatedOnHost(host)
+}
+
+// splitCloneName undoes CloneName: it returns the host, DefaultHost when
+// none is given, and owner/repo.
+func splitCloneName(fullName string) (host, nameWithOwner string) {
+	parts := strings.Split(strings.TrimSpace(fullName), "/")
+	if len(parts) == 3 {
+		return strings.ToLower(parts[0]), parts[1] + "/" + parts[2]
+	}
+	return DefaultHost, strings.TrimSpace(fullName)
+}
+
+// cloneWithToken bare-clones https://HOST/owner/repo.git with git,
+// authenticating with token. The token reaches git only as an Authorization
+// header in the child's environment (see tokenGitEnviron): it is not in the
+// clone URL, on the command line or in the clone's config, and it is
+// scrubbed from any output in the returned error. The clone's origin is the
+// plain HTTPS URL.
+//
+// The clone is a full one. A blobless clone fetches blobs lazily from origin
+// later, when the token is no longer offered.
+func cloneWithToken(fullName, destPath, token string) error {
+	host, name := splitCloneName(fullName)
+	if _, err := NormalizeHost(host); err != nil {
+		return err
+	}
+	remote := (&url.URL{Scheme: "https", Host: host, Path: "/" + name + ".git"}).String()
+
+	cmd := execCommand("git", "clone", "--bare", remote, destPath)
+	cmd.Env = tokenGitEnviron(cmd.Env, host, token)
+	output, err := cmd.CombinedOutput()
+	// Let go of the environment holding the token as soon as git exits,
+	// rather than for as long as cmd lives.
+	cmd.Env = nil
+	if err == nil {
+		return nil
+	}
+	output = []byte(strings.ReplaceAll(string(output), token, "[REDACTED]"))
+	return fmt.Errorf("git clone %s with the stored GitHub token failed: %w%s", remote, err, formatCloneOutput(output))
+}
+
+// tokenGitEnviron returns base, or the parent's environment when base is nil,
+// with token set as a Basic Authorization header for https://host/ through
+// GIT_CONFIG_COUNT/KEY/VALUE. Config given that way is never written to
+// disk, and scoping it to the host keeps it off redirects elsewhere. Any
+// inherited GIT_CONFIG_* entries are replaced, git's tracing variables are
+// dropped so the header cannot be logged, and credential prompts are
+// disabled so a bad token fails instead of hanging.
+func tokenGitEnviron(base []string, host, token string) []string {
+	if base == nil {
+		base = os.Environ()
+	}
+	out := make([]string, 0, len(base)+4)
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, "GIT_CONFIG_") || strings.HasPrefix(key, "GIT_TRACE") ||
+			key == "GIT_CURL_VERBOSE" || key == "GIT_TERMINAL_PROMPT" {
+			continue
+		}
+		out = append(out, kv)
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return append(out,
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.https://"+host+"/.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+basic,
+	)
+}
+
+// filterUnsupported reports whether clone output is an older git rejecting
+// --filter.
+func filterUnsupported(output []byte) bool {
+	msgLower := strings.ToLower(string(output))
+	return strings.Contains(msgLower, "unknown option") &&
+		(strings.Contains(msgLower, "--filter") || strings.Contains(msgLower, "filter=blob:none"))
 }
 
-func listOrgs(gh string, limit int) ([]string, error) {
+// formatCloneOutput trims clone output for an error message.
+func formatCloneOutput(output []byte) string {
+	msg := strings.TrimSpace(string(output))
+	if len(msg) > 4096 {
+		msg = msg[:4096] + "..."
+	}
+	if msg != "" {
+		msg = "\n" + msg
+	}
+	return msg
+}
+
+func listOrgs(gh, host string, limit int) ([]string, error) {
 	if limit <= 0 {
 		limit = 30
 	}
 
-	cmd := execCommand(gh, "org", "list", "--limit", strconv.Itoa(limit))
+	cmd := ghHostCommand(gh, host, "org", "list", "--limit", strconv.Itoa(limit))
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		msg := strings.TrimSpace(string(output))
@@ -172,7 +434,7 @@ func listOrgs(gh string, limit int) ([]string, error) {
 	return orgs, nil
 }
 
-func listRepos(gh string, owner string, fields string, limit int) ([]Repo, error) {
+func listRepos(gh, host, owner, fields string, limit int) ([]Repo, error) {
 	if fields == "" {
 		fields = "id,name,nameWithOwner,url,isPrivate,defaultBranchRef,owner"
 	}
@@ -186,7 +448,7 @@ func listRepos(gh string, owner string, fields string, limit int) ([]Repo, error
 	}
 	args = append(args, "--json", fields, "--limit", strconv.Itoa(limit))
 
-	cmd := execCommand(gh, args...)
+	cmd := ghHostCommand(gh, host, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		msg := strings.TrimSpace(string(output))
@@ -237,6 +499,15 @@ func dedupeReposByFullName(repos []Repo) []Repo {
 }
 
 func ghPath() string {
+	if configured := currentConfig().Path; configured != "" {
+		// An explicit path is authoritative: if it is wrong, report gh as
+		// missing rather than quietly using a different binary.
+		if info, err := os.Stat(configured); err == nil && !info.IsDir() {
+			return configured
+		}
+		return ""
+	}
+
 	if path, err := exec.LookPath("gh"); err == nil && strings.TrimSpace(path) != "" {
 		return path
 	}
@@ -263,8 +534,6 @@ func ghPath() string {
 	return ""
 }
 
-
-
 // CreatePR creates a pull request for the repository at repoPath.
 func CreatePR(repoPath, title, body, base, head string, draft bool) (string, error) {
 	gh := ghPathFn()
@@ -282,7 +551,7 @@ func CreatePR(repoPath, title, body, base, head string, draft bool) (string, err
 		args = append(args, "--draft")
 	}
 
-	cmd := execCommand(gh, args...)
+	cmd := ghCommand(gh, args...)
 	cmd.Dir = repoPath
 
 	output, err := cmd.CombinedOutput()
@@ -301,8 +570,9 @@ func CreatePR(repoPath, title, body, base, head string, draft bool) (string, err
 }
 
 // CreatePRWithContext creates a pull request for the repository at repoPath and
-// allows the operation to be canceled via ctx.
-func CreatePRWithContext(ctx context.Context, repoPath, title, body, base, head string, draft bool) (string, error) {
+// allows the operation to be canceled via ctx. A non-empty host is the GitHub
+// host the repository lives on, for repos imported from GitHub Enterprise.
+func CreatePRWithContext(ctx context.Context, repoPath, host, title, body, base, head string, draft bool) (string, error) {
 	gh := ghPathFn()
 	if gh == "" {
 		return "", ErrGhNotFound
@@ -318,7 +588,7 @@ func CreatePRWithContext(ctx context.Context, repoPath, title, body, base, head
 		args = append(args, "--draft")
 	}
 
-	output, err := procRun(ctx, repoPath, gh, args...)
+	output, err := procRun(ctx, repoPath, ghEnviron(nil, host), gh, args...)
 	if err != nil {
 		msg := strings.TrimSpace(string(output))
 		if len(msg) > 4096 {
@@ -2,6 +2,7 @@ package ghcli
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"os"
 	"os/exec"
@@ -24,7 +25,7 @@ func TestDiscoverReposIncludesOrgRepos(t *testing.T) {
 	ghPathFn = func() string { return "/test/gh" }
 	execCommand = stubExecCommand()
 
-	got, err := DiscoverRepos()
+	got, err := DiscoverRepos("")
 	if err != nil {
 		t.Fatalf("DiscoverRepos returned error: %v", err)
 	}
@@ -53,7 +54,7 @@ func TestDiscoverReposDedupesByFullName(t *testing.T) {
 	ghPathFn = func() string { return "/test/gh" }
 	execCommand = stubExecCommand()
 
-	got, err := DiscoverRepos()
+	got, err := DiscoverRepos("")
 	if err != nil {
 		t.Fatalf("DiscoverRepos returned error: %v", err)
 	}
@@ -69,6 +70,52 @@ func TestDiscoverReposDedupesByFullName(t *testing.T) {
 	}
 }
 
+func TestDiscoverReposTargetsHost(t *testing.T) {
+	t.Setenv("FOG_GHCLI_TEST_CASE", "enterprise")
+	t.Setenv("GH_HOST", "github.com")
+
+	origExec := execCommand
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		execCommand = origExec
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+	execCommand = stubExecCommand()
+
+	got, err := DiscoverRepos("ghe.example.com")
+	if err != nil {
+		t.Fatalf("DiscoverRepos returned error: %v", err)
+	}
+	if len(got) != 1 || got[0].NameWithOwner != "corp/billing" {
+		t.Fatalf("unexpected repo list: %+v", got)
+	}
+	if name := CloneName(got[0]); name != "ghe.example.com/corp/billing" {
+		t.Fatalf("CloneName = %q, want the host-qualified name", name)
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	for raw, want := range map[string]string{
+		"":                       "",
+		" github.com ":           "github.com",
+		"GHE.Example.com.":       "ghe.example.com",
+		"git.internal:8443":      "git.internal:8443",
+		"ghe-01.corp.example.io": "ghe-01.corp.example.io",
+	} {
+		got, err := NormalizeHost(raw)
+		if err != nil || got != want {
+			t.Fatalf("NormalizeHost(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"https://ghe.example.com", "ghe.example.com/org", "-bad.example.com", "two words", "ghe.example.com:0", "a..b"} {
+		if _, err := NormalizeHost(raw); err == nil {
+			t.Fatalf("NormalizeHost(%q) accepted an implausible host", raw)
+		}
+	}
+}
+
 func TestCloneRepoUsesBloblessFilter(t *testing.T) {
 	t.Setenv("FOG_GHCLI_TEST_CASE", "clone_success_filter")
 
@@ -107,6 +154,81 @@ func TestCloneRepoFallsBackWhenFilterUnsupported(t *testing.T) {
 	}
 }
 
+func TestCloneRepoFallsBackToStoredToken(t *testing.T) {
+	origExec := execCommand
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		execCommand = origExec
+		ghPathFn = origPath
+		SetConfigSource(nil)
+	})
+	execCommand = stubExecCommand()
+	// Inherited git config and tracing must not reach the clone.
+	t.Setenv("GIT_CONFIG_COUNT", "2")
+	t.Setenv("GIT_CONFIG_KEY_1", "core.sshCommand")
+	t.Setenv("GIT_TRACE_CURL", "1")
+
+	destPath := filepath.Join(t.TempDir(), "repo.git")
+	ghPathFn = func() string { return "" }
+	if err := CloneRepo("acme/private", destPath); !errors.Is(err, ErrGhNotFound) {
+		t.Fatalf("CloneRepo without gh or a token = %v, want ErrGhNotFound", err)
+	}
+
+	SetConfigSource(func() Config { return Config{Token: "secret-token"} })
+	t.Setenv("FOG_GHCLI_TEST_CASE", "token_clone")
+	if err := CloneRepo("acme/private", destPath); err != nil {
+		t.Fatalf("CloneRepo without gh = %v, want a token clone", err)
+	}
+
+	// gh installed but not logged in falls back too.
+	ghPathFn = func() string { return "/test/gh" }
+	if err := CloneRepo("acme/private", destPath); err != nil {
+		t.Fatalf("CloneRepo with gh logged out = %v, want a token clone", err)
+	}
+
+	t.Setenv("FOG_GHCLI_TEST_CASE", "token_clone_fail")
+	err := CloneRepo("acme/private", destPath)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("error leaks the token: %v", err)
+	}
+}
+
+func TestCloneRepoPrefersAuthenticatedGh(t *testing.T) {
+	t.Setenv("FOG_GHCLI_TEST_CASE", "clone_gh_authed")
+
+	origExec := execCommand
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		execCommand = origExec
+		ghPathFn = origPath
+		SetConfigSource(nil)
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+	execCommand = stubExecCommand()
+	SetConfigSource(func() Config { return Config{Token: "secret-token"} })
+
+	destPath := filepath.Join(t.TempDir(), "repo.git")
+	if err := CloneRepo("acme/service", destPath); err != nil {
+		t.Fatalf("CloneRepo returned error: %v", err)
+	}
+}
+
+func TestSplitCloneName(t *testing.T) {
+	for fullName, want := range map[string][2]string{
+		"acme/api":                 {"github.com", "acme/api"},
+		"GHE.example.com/corp/app": {"ghe.example.com", "corp/app"},
+	} {
+		host, name := splitCloneName(fullName)
+		if host != want[0] || name != want[1] {
+			t.Fatalf("splitCloneName(%q) = %q, %q; want %q, %q", fullName, host, name, want[0], want[1])
+		}
+	}
+}
+
 func TestDiscoverReposOrgRepoListErrorMentionsOwner(t *testing.T) {
 	t.Setenv("FOG_GHCLI_TEST_CASE", "org_repo_list_error")
 
@@ -120,7 +242,7 @@ func TestDiscoverReposOrgRepoListErrorMentionsOwner(t *testing.T) {
 	ghPathFn = func() string { return "/test/gh" }
 	execCommand = stubExecCommand()
 
-	_, err := DiscoverRepos()
+	_, err := DiscoverRepos("")
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -186,14 +308,14 @@ func TestCreatePRWithContextBuildsArgsAndWrapsOutput(t *testing.T) {
 	var gotDir, gotName string
 	var gotArgs []string
 	sentinel := errors.New("boom")
-	procRun = func(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	procRun = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
 		gotDir = dir
 		gotName = name
 		gotArgs = append([]string(nil), args...)
 		return []byte("nope\n"), sentinel
 	}
 
-	_, err := CreatePRWithContext(context.Background(), "/repo", "my title", "my body", "main", "feature", true)
+	_, err := CreatePRWithContext(context.Background(), "/repo", "", "my title", "my body", "main", "feature", true)
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -219,6 +341,141 @@ func TestCreatePRWithContextBuildsArgsAndWrapsOutput(t *testing.T) {
 	}
 }
 
+func TestCreatePRWithContextPassesConfiguredToken(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+		SetConfigSource(nil)
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+	SetConfigSource(func() Config { return Config{Token: "secret-token"} })
+	t.Setenv("GH_TOKEN", "ambient-token")
+
+	var gotEnv []string
+	procRun = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		gotEnv = append([]string(nil), env...)
+		return []byte("https://example.com/pr/1\n"), nil
+	}
+
+	if _, err := CreatePRWithContext(context.Background(), "/repo", "", "t", "b", "main", "feature", false); err != nil {
+		t.Fatalf("CreatePRWithContext returned error: %v", err)
+	}
+
+	var tokens []string
+	for _, kv := range gotEnv {
+		if strings.HasPrefix(kv, "GH_TOKEN=") {
+			tokens = append(tokens, kv)
+		}
+	}
+	if want := []string{"GH_TOKEN=secret-token"}; !reflect.DeepEqual(tokens, want) {
+		t.Fatalf("unexpected GH_TOKEN entries: got %v want %v", tokens, want)
+	}
+}
+
+func TestCreatePRWithContextPassesHost(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+	t.Setenv("GH_HOST", "github.com")
+
+	var gotEnv []string
+	procRun = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		gotEnv = append([]string(nil), env...)
+		return nil, nil
+	}
+
+	if _, err := CreatePRWithContext(context.Background(), "/repo", "ghe.example.com", "t", "b", "main", "feature", false); err != nil {
+		t.Fatalf("CreatePRWithContext returned error: %v", err)
+	}
+
+	var hosts []string
+	for _, kv := range gotEnv {
+		if strings.HasPrefix(kv, "GH_HOST=") {
+			hosts = append(hosts, kv)
+		}
+	}
+	if want := []string{"GH_HOST=ghe.example.com"}; !reflect.DeepEqual(hosts, want) {
+		t.Fatalf("unexpected GH_HOST entries: got %v want %v", hosts, want)
+	}
+}
+
+func TestCreatePRWithContextInheritsEnvWithoutToken(t *testing.T) {
+	origProcRun := procRun
+	origPath := ghPathFn
+	t.Cleanup(func() {
+		procRun = origProcRun
+		ghPathFn = origPath
+	})
+
+	ghPathFn = func() string { return "/test/gh" }
+
+	gotEnv := []string{"sentinel"}
+	procRun = func(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+		gotEnv = env
+		return nil, nil
+	}
+
+	if _, err := CreatePRWithContext(context.Background(), "/repo", "", "t", "b", "main", "feature", false); err != nil {
+		t.Fatalf("CreatePRWithContext returned error: %v", err)
+	}
+	if gotEnv != nil {
+		t.Fatalf("expected inherited (nil) env, got %v", gotEnv)
+	}
+}
+
+func TestGhPathPrefersConfiguredPath(t *testing.T) {
+	t.Cleanup(func() { SetConfigSource(nil) })
+
+	bin := filepath.Join(t.TempDir(), "gh")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write fake gh: %v", err)
+	}
+
+	SetConfigSource(func() Config { return Config{Path: bin} })
+	if got := ghPath(); got != bin {
+		t.Fatalf("unexpected gh path: got %q want %q", got, bin)
+	}
+
+	SetConfigSource(func() Config { return Config{Path: filepath.Join(t.TempDir(), "missing")} })
+	if got := ghPath(); got != "" {
+		t.Fatalf("expected missing configured gh to report unavailable, got %q", got)
+	}
+}
+
+type fakeConfigStore struct {
+	settings map[string]string
+	token    string
+}
+
+func (f fakeConfigStore) GetSetting(key string) (string, bool, error) {
+	v, ok := f.settings[key]
+	return v, ok, nil
+}
+
+func (f fakeConfigStore) GetGitHubToken() (string, bool, error) {
+	return f.token, f.token != "", nil
+}
+
+func TestStoreConfigSourceReadsPathAndToken(t *testing.T) {
+	src := StoreConfigSource(fakeConfigStore{
+		settings: map[string]string{SettingGhPath: " /opt/gh/bin/gh "},
+		token:    "tok",
+	})
+	got := src()
+	want := Config{Path: "/opt/gh/bin/gh", Token: "tok"}
+	if got != want {
+		t.Fatalf("unexpected config: got %+v want %+v", got, want)
+	}
+}
+
 func stubExecCommand() func(string, ...string) *exec.Cmd {
 	return func(name string, args ...string) *exec.Cmd {
 		cs := []string{"-test.run=TestHelperProcess", "--", name}
@@ -253,6 +510,15 @@ func TestHelperProcess(t *testing.T) {
 	}
 
 	testCase := os.Getenv("FOG_GHCLI_TEST_CASE")
+	if testCase == "enterprise" {
+		if os.Getenv("GH_HOST") != "ghe.example.com" {
+			os.Exit(3)
+		}
+		if ghArgs[0] == "repo" && ghArgs[1] == "list" {
+			_, _ = os.Stdout.WriteString(`[{"id":"E1","name":"billing","nameWithOwner":"corp/billing","url":"https://ghe.example.com/corp/billing","isPrivate":true,"defaultBranchRef":{"name":"main"},"owner":{"login":"corp"}}]`)
+		}
+		os.Exit(0)
+	}
 	switch ghArgs[0] {
 	case "org":
 		if ghArgs[1] != "list" {
@@ -336,7 +602,7 @@ func TestHelperProcess(t *testing.T) {
 			}
 
 			switch testCase {
-			case "clone_success_filter":
+			case "clone_success_filter", "clone_gh_authed":
 				if !hasFilter {
 					os.Exit(2)
 				}
@@ -356,6 +622,34 @@ func TestHelperProcess(t *testing.T) {
 		default:
 			os.Exit(2)
 		}
+	case "auth":
+		if testCase == "clone_gh_authed" {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	case "clone":
+		// git clone --bare <url> <dir>, from the stored-token fallback.
+		switch testCase {
+		case "token_clone":
+			if ghArgs[2] != "https://github.com/acme/private.git" || strings.Contains(strings.Join(ghArgs, " "), "secret-token") {
+				os.Exit(3)
+			}
+			header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte("x-access-token:secret-token"))
+			if os.Getenv("GIT_CONFIG_COUNT") != "1" ||
+				os.Getenv("GIT_CONFIG_KEY_0") != "http.https://github.com/.extraHeader" ||
+				os.Getenv("GIT_CONFIG_VALUE_0") != header ||
+				os.Getenv("GIT_CONFIG_KEY_1") != "" ||
+				os.Getenv("GIT_TERMINAL_PROMPT") != "0" ||
+				os.Getenv("GIT_TRACE_CURL") != "" {
+				os.Exit(4)
+			}
+			os.Exit(0)
+		case "token_clone_fail":
+			_, _ = os.Stderr.WriteString("fatal: Authentication failed for secret-token\n")
+			os.Exit(128)
+		default:
+			os.Exit(2)
+		}
 	case "pr":
 		if ghArgs[1] != "create" {
 			os.Exit(2)
@@ -3,6 +3,7 @@ package git
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -19,6 +20,7 @@ import (
 type Git struct {
 	repoPath string
 	ctx      context.Context
+	signing  Signing
 }
 
 // New creates a new Git instance for the given repository path.
@@ -137,6 +139,13 @@ func (g *Git) DeleteBranch(branch string, force bool) error {
 	return err
 }
 
+// RenameBranch renames a local branch with git branch -m. A worktree that has
+// the branch checked out follows the rename. It fails when newName exists.
+func (g *Git) RenameBranch(oldName, newName string) error {
+	_, err := g.exec("branch", "-m", oldName, newName)
+	return err
+}
+
 // ListBranches returns a list of local branches.
 func (g *Git) ListBranches() ([]string, error) {
 	out, err := g.exec("branch", "--list", "--format=%(refname:short)")
@@ -152,6 +161,39 @@ func (g *Git) ListBranches() ([]string, error) {
 	return branches, nil
 }
 
+// UnmergedBranches returns the local branches with commits not on base.
+func (g *Git) UnmergedBranches(base string) ([]string, error) {
+	out, err := g.exec("branch", "--list", "--no-merged", base, "--format=%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			branches = append(branches, trimmed)
+		}
+	}
+	return branches, nil
+}
+
+// BranchHasOwnCommits reports whether branch ever moved on from the commit it
+// was created at, going by its reflog. A branch nobody committed on still
+// points at where it started, which is already on the branch it came from, so
+// ancestry alone cannot tell it from a merged one. A branch without a reflog
+// counts as not.
+func (g *Git) BranchHasOwnCommits(branch string) bool {
+	out, err := g.exec("reflog", "show", "--format=%H", "refs/heads/"+branch, "--")
+	if err != nil {
+		return false
+	}
+	entries := strings.Fields(out)
+	if len(entries) == 0 {
+		return false
+	}
+	// Newest first: the tip, back to the commit the branch was created at.
+	return entries[0] != entries[len(entries)-1]
+}
+
 // Diff returns the full patch for a diff reference (e.g. "main...feature").
 func (g *Git) Diff(ref string) (string, error) {
 	return g.exec("diff", "--no-color", ref)
@@ -162,6 +204,36 @@ func (g *Git) DiffStat(ref string) (string, error) {
 	return g.exec("diff", "--stat", "--no-color", ref)
 }
 
+// UntrackedFiles lists files in the worktree that git does not track and does
+// not ignore.
+func (g *Git) UntrackedFiles() ([]string, error) {
+	out, err := g.exec("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			files = append(files, trimmed)
+		}
+	}
+	return files, nil
+}
+
+// UntrackedDiff returns a patch that adds path, as `git diff` would show it
+// once staged. path is relative to the worktree.
+func (g *Git) UntrackedDiff(path string) (string, error) {
+	args := []string{"diff", "--no-color", "--no-index", "--", os.DevNull, path}
+	output, err := proc.Run(g.context(), g.repoPath, "git", args...)
+	patch := strings.TrimSpace(string(output))
+	// --no-index exits 1 when the inputs differ, which for a new file is always,
+	// but also on errors such as a missing path; only a patch means success.
+	if code, ok := proc.ExitCode(err); err != nil && (!ok || code != 1 || !strings.HasPrefix(patch, "diff --git")) {
+		return "", fmt.Errorf("git %s: %w\n%s", strings.Join(args, " "), err, string(output))
+	}
+	return patch, nil
+}
+
 // GetDefaultBranch attempts to determine the default branch of the repository.
 func (g *Git) GetDefaultBranch() (string, error) {
 	// 1. Try to get the symbolic ref of HEAD (works for non-bare repos and some bare repos)
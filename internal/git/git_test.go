@@ -0,0 +1,74 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUntrackedFilesAndDiff(t *testing.T) {
+	dir := initRepo(t)
+	write(t, dir, ".gitignore", "ignored.txt\n")
+	write(t, dir, "ignored.txt", "secret")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	write(t, dir, filepath.Join("sub", "new.txt"), "hello\n")
+	g := New(dir)
+
+	files, err := g.UntrackedFiles()
+	if err != nil {
+		t.Fatalf("UntrackedFiles: %v", err)
+	}
+	if strings.Join(files, ",") != ".gitignore,sub/new.txt" {
+		t.Fatalf("unexpected untracked files: %v", files)
+	}
+
+	patch, err := g.UntrackedDiff("sub/new.txt")
+	if err != nil {
+		t.Fatalf("UntrackedDiff: %v", err)
+	}
+	if !strings.Contains(patch, "new file mode") || !strings.Contains(patch, "+hello") {
+		t.Fatalf("expected a new-file patch, got:\n%s", patch)
+	}
+
+	if _, err := g.UntrackedDiff("missing.txt"); err == nil {
+		t.Fatal("expected an error for a path that does not exist")
+	}
+}
+
+func TestBranchHasOwnCommits(t *testing.T) {
+	dir := initRepo(t)
+	g := New(dir)
+	base, err := g.HeadSHA()
+	if err != nil {
+		t.Fatalf("HeadSHA: %v", err)
+	}
+	for _, args := range [][]string{{"branch", "fresh"}, {"checkout", "-q", "-b", "worked"}} {
+		if _, err := g.exec(args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	write(t, dir, "a.txt", "a")
+	if err := g.StageAll(); err != nil {
+		t.Fatalf("StageAll: %v", err)
+	}
+	if _, err := g.Commit("work"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// Both end up on the branch they came from, but only one was worked on.
+	if !g.IsAncestor("fresh", "worked") {
+		t.Fatal("fresh should be an ancestor of worked")
+	}
+	if g.BranchHasOwnCommits("fresh") {
+		t.Errorf("a branch left at %s has no commits of its own", base)
+	}
+	if !g.BranchHasOwnCommits("worked") {
+		t.Error("a committed-on branch should have commits of its own")
+	}
+	if g.BranchHasOwnCommits("missing") {
+		t.Error("a missing branch has no commits")
+	}
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Field and record separators for the log format. Commit messages can contain
+// any printable text, so the output is split on ASCII unit/record separators
+// rather than newlines.
+const (
+	logFieldSep  = "\x1f"
+	logRecordSep = "\x1e"
+	logFormat    = "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%B%x1e"
+)
+
+// Commit is one entry from Log.
+type Commit struct {
+	SHA         string
+	Author      string
+	AuthorEmail string
+	Timestamp   time.Time
+	// Message is the full commit message, subject and body, trimmed.
+	Message string
+}
+
+// Log returns the commits in a revision range (e.g. "main..feature"), newest
+// first. An empty range returns no commits and no error.
+func (g *Git) Log(revRange string) ([]Commit, error) {
+	if strings.TrimSpace(revRange) == "" {
+		return nil, fmt.Errorf("revision range cannot be empty")
+	}
+	out, err := g.exec("log", "--no-color", logFormat, revRange, "--")
+	if err != nil {
+		return nil, err
+	}
+	return parseLog(out)
+}
+
+func parseLog(output string) ([]Commit, error) {
+	var commits []Commit
+	for record := range strings.SplitSeq(output, logRecordSep) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, logFieldSep, 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("parse git log: malformed record %q", record)
+		}
+		ts, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("parse git log timestamp %q: %w", fields[3], err)
+		}
+		commits = append(commits, Commit{
+			SHA:         fields[0],
+			Author:      fields[1],
+			AuthorEmail: fields[2],
+			Timestamp:   ts,
+			Message:     strings.TrimSpace(fields[4]),
+		})
+	}
+	return commits, nil
+}
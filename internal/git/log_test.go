@@ -0,0 +1,64 @@
+package git
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLogReturnsBranchCommitsNewestFirst(t *testing.T) {
+	dir := initRepo(t)
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	run("branch", "-M", "main")
+	run("checkout", "-b", "feature")
+
+	g := New(dir)
+	commits, err := g.Log("main..feature")
+	if err != nil {
+		t.Fatalf("Log on empty range: %v", err)
+	}
+	if len(commits) != 0 {
+		t.Fatalf("expected no commits, got %+v", commits)
+	}
+
+	write(t, dir, "a.txt", "a")
+	run("add", ".")
+	run("commit", "-m", "feat: first")
+	write(t, dir, "b.txt", "b")
+	run("add", ".")
+	run("commit", "-m", "fix: second\n\nWith a body line.")
+
+	commits, err = g.Log("main..feature")
+	if err != nil {
+		t.Fatalf("Log: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(commits))
+	}
+	if commits[0].Message != "fix: second\n\nWith a body line." {
+		t.Errorf("unexpected newest message: %q", commits[0].Message)
+	}
+	if commits[1].Message != "feat: first" {
+		t.Errorf("unexpected oldest message: %q", commits[1].Message)
+	}
+	head, err := g.HeadSHA()
+	if err != nil {
+		t.Fatalf("HeadSHA: %v", err)
+	}
+	if commits[0].SHA != head {
+		t.Errorf("expected newest SHA %s, got %s", head, commits[0].SHA)
+	}
+	if commits[0].Author != "Test User" || commits[0].AuthorEmail != "test@example.com" {
+		t.Errorf("unexpected author: %q <%s>", commits[0].Author, commits[0].AuthorEmail)
+	}
+	if commits[0].Timestamp.IsZero() {
+		t.Error("expected a parsed timestamp")
+	}
+}
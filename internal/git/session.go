@@ -2,6 +2,7 @@ package git
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -26,13 +27,41 @@ func (g *Git) StageAll() error {
 	return err
 }
 
+// Signing says how Commit signs commits. The zero value leaves signing to the
+// repository's own configuration.
+type Signing struct {
+	// Format is git's gpg.format: "openpgp" or "ssh". Empty does not sign.
+	Format string
+	// Key is user.signingkey: a GPG key ID, or for ssh a key file or a
+	// "key::" literal. Empty lets git choose, which only works for openpgp.
+	Key string
+}
+
+// WithSigning returns a copy of g whose commits are signed as s says.
+func (g *Git) WithSigning(s Signing) *Git {
+	clone := *g
+	clone.signing = s
+	return &clone
+}
+
 // Commit records the staged changes with the given message and returns the new
-// commit SHA.
+// commit SHA. Signing settings are passed as -c overrides, so the repository's
+// config is left untouched.
 func (g *Git) Commit(message string) (string, error) {
 	if strings.TrimSpace(message) == "" {
 		return "", fmt.Errorf("commit message cannot be empty")
 	}
-	if _, err := g.exec("commit", "-m", message); err != nil {
+	var args []string
+	if g.signing.Format != "" {
+		args = append(args, "-c", "gpg.format="+g.signing.Format)
+		if g.signing.Key != "" {
+			args = append(args, "-c", "user.signingkey="+g.signing.Key)
+		}
+		args = append(args, "commit", "-S")
+	} else {
+		args = append(args, "commit")
+	}
+	if _, err := g.exec(append(args, "-m", message)...); err != nil {
 		return "", err
 	}
 	return g.HeadSHA()
@@ -54,6 +83,126 @@ func (g *Git) Push(branch string, setUpstream bool) error {
 	return err
 }
 
+// UpstreamRemote returns the remote branch is configured to track, or ""
+// when it tracks none. It reads the branch config rather than resolving
+// @{upstream}, which needs a remote-tracking ref bare clones do not keep.
+func (g *Git) UpstreamRemote(branch string) string {
+	remote, err := g.exec("config", "--get", "branch."+branch+".remote")
+	if err != nil {
+		return ""
+	}
+	return remote
+}
+
+// ForcePushWithLease pushes branch to origin, replacing its history, but only
+// while origin's branch is still at expectSHA. Work pushed by anyone else in
+// the meantime makes the push fail rather than be overwritten.
+func (g *Git) ForcePushWithLease(branch, expectSHA string) error {
+	_, err := g.exec("push", "--force-with-lease="+branch+":"+expectSHA, "origin", branch)
+	return err
+}
+
+// RemoteBranchSHA returns the commit origin's branch points at, or "" when
+// origin has no such branch. It asks the remote rather than reading a
+// remote-tracking ref, which bare clones do not keep.
+func (g *Git) RemoteBranchSHA(branch string) (string, error) {
+	output, err := g.exec("ls-remote", "origin", "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	sha, _, _ := strings.Cut(output, "\t")
+	return strings.TrimSpace(sha), nil
+}
+
+// MergeBase returns the best common ancestor of a and b.
+func (g *Git) MergeBase(a, b string) (string, error) {
+	return g.exec("merge-base", a, b)
+}
+
+// IsAncestor reports whether commit a is reachable from b. A commit missing
+// from the local repository counts as not.
+func (g *Git) IsAncestor(a, b string) bool {
+	_, err := g.exec("merge-base", "--is-ancestor", a, b)
+	return err == nil
+}
+
+// CountCommits returns the number of commits in revRange, e.g. "base..HEAD".
+func (g *Git) CountCommits(revRange string) (int, error) {
+	output, err := g.exec("rev-list", "--count", revRange)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(output)
+	if err != nil {
+		return 0, fmt.Errorf("parse commit count %q: %w", output, err)
+	}
+	return n, nil
+}
+
+// AheadBehind counts the commits HEAD has that ref lacks (ahead) and the
+// commits ref has that HEAD lacks (behind).
+func (g *Git) AheadBehind(ref string) (ahead, behind int, err error) {
+	output, err := g.exec("rev-list", "--left-right", "--count", "HEAD..."+ref)
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("parse ahead/behind %q", output)
+	}
+	if ahead, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, fmt.Errorf("parse ahead count %q: %w", fields[0], err)
+	}
+	if behind, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, fmt.Errorf("parse behind count %q: %w", fields[1], err)
+	}
+	return ahead, behind, nil
+}
+
+// RefExists reports whether ref names a commit, e.g. "origin/main".
+func (g *Git) RefExists(ref string) bool {
+	if strings.TrimSpace(ref) == "" {
+		return false
+	}
+	_, err := g.exec("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	return err == nil
+}
+
+// ResolveCommit returns the SHA of the commit ref names, e.g. "abc123^".
+func (g *Git) ResolveCommit(ref string) (string, error) {
+	return g.exec("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+}
+
+// ResetSoft moves HEAD to ref, keeping every change since then staged.
+func (g *Git) ResetSoft(ref string) error {
+	_, err := g.exec("reset", "--soft", ref)
+	return err
+}
+
+// DiscardChanges throws away every uncommitted change: tracked files go back
+// to HEAD and untracked files and directories are removed. Ignored files stay.
+func (g *Git) DiscardChanges() error {
+	if _, err := g.exec("reset", "--hard", "HEAD"); err != nil {
+		return err
+	}
+	_, err := g.exec("clean", "-fd")
+	return err
+}
+
+// Stash moves every uncommitted change, untracked files included, onto the
+// stash under message, leaving the worktree clean. Ignored files stay.
+func (g *Git) Stash(message string) error {
+	_, err := g.exec("stash", "push", "--include-untracked", "--message", message)
+	return err
+}
+
+// StashPop restores the most recent stash entry and drops it. When the entry
+// does not apply cleanly, git keeps it on the stash.
+func (g *Git) StashPop() error {
+	_, err := g.exec("stash", "pop")
+	return err
+}
+
 // StagedDiff describes the staged changes: a name/status list, a stat summary,
 // and the patch itself. The patch is returned whole; callers decide how much of
 // it to keep.
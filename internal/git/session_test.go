@@ -57,6 +57,65 @@ func TestIsDirty(t *testing.T) {
 	}
 }
 
+func TestDiscardChanges(t *testing.T) {
+	dir := initRepo(t)
+	g := New(dir)
+	write(t, dir, "kept.txt", "committed")
+	if err := g.StageAll(); err != nil {
+		t.Fatalf("StageAll: %v", err)
+	}
+	if _, err := g.Commit("add kept"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	write(t, dir, "kept.txt", "edited")
+	if err := os.MkdirAll(filepath.Join(dir, "scratch"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	write(t, dir, "scratch/new.txt", "untracked")
+
+	if err := g.DiscardChanges(); err != nil {
+		t.Fatalf("DiscardChanges: %v", err)
+	}
+	if dirty, err := g.IsDirty(); err != nil || dirty {
+		t.Fatalf("IsDirty after discard = %v, %v; want clean", dirty, err)
+	}
+	if body, _ := os.ReadFile(filepath.Join(dir, "kept.txt")); string(body) != "committed" {
+		t.Errorf("kept.txt = %q, want the committed content", body)
+	}
+}
+
+func TestStashAndStashPop(t *testing.T) {
+	dir := initRepo(t)
+	g := New(dir)
+	write(t, dir, "tracked.txt", "committed")
+	if err := g.StageAll(); err != nil {
+		t.Fatalf("StageAll: %v", err)
+	}
+	if _, err := g.Commit("add tracked"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	write(t, dir, "tracked.txt", "edited")
+	write(t, dir, "debug.txt", "untracked")
+	if err := g.Stash("local edits"); err != nil {
+		t.Fatalf("Stash: %v", err)
+	}
+	if dirty, err := g.IsDirty(); err != nil || dirty {
+		t.Fatalf("IsDirty after stash = %v, %v; want clean", dirty, err)
+	}
+
+	if err := g.StashPop(); err != nil {
+		t.Fatalf("StashPop: %v", err)
+	}
+	if body, _ := os.ReadFile(filepath.Join(dir, "tracked.txt")); string(body) != "edited" {
+		t.Errorf("tracked.txt = %q, want the stashed edit back", body)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "debug.txt")); err != nil {
+		t.Errorf("untracked file not restored: %v", err)
+	}
+}
+
 func TestStageAllAndCommit(t *testing.T) {
 	dir := initRepo(t)
 	g := New(dir)
@@ -146,6 +205,79 @@ func TestPushFailsWithoutRemote(t *testing.T) {
 	}
 }
 
+func commitFile(t *testing.T, g *Git, dir, name string) string {
+	t.Helper()
+	write(t, dir, name, name)
+	if err := g.StageAll(); err != nil {
+		t.Fatalf("StageAll: %v", err)
+	}
+	sha, err := g.Commit("add " + name)
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	return sha
+}
+
+func TestSquashPrimitives(t *testing.T) {
+	remote := t.TempDir()
+	if out, err := exec.Command("git", "init", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v\n%s", err, out)
+	}
+	dir := initRepo(t)
+	g := New(dir)
+	if _, err := g.exec("remote", "add", "origin", remote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.exec("branch", "-M", "feature"); err != nil {
+		t.Fatal(err)
+	}
+	base, _ := g.HeadSHA()
+
+	if sha, err := g.RemoteBranchSHA("feature"); err != nil || sha != "" {
+		t.Fatalf("RemoteBranchSHA before push = %q, %v; want empty", sha, err)
+	}
+
+	first := commitFile(t, g, dir, "a.txt")
+	commitFile(t, g, dir, "b.txt")
+	if n, err := g.CountCommits(base + "..HEAD"); err != nil || n != 2 {
+		t.Fatalf("CountCommits = %d, %v; want 2", n, err)
+	}
+	if mb, err := g.MergeBase(base, "HEAD"); err != nil || mb != base {
+		t.Fatalf("MergeBase = %q, %v; want %q", mb, err, base)
+	}
+	if !g.IsAncestor(first, "HEAD") || g.IsAncestor("HEAD", first) {
+		t.Fatal("IsAncestor gave the wrong answer")
+	}
+
+	if err := g.Push("feature", true); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	pushed, err := g.RemoteBranchSHA("feature")
+	if err != nil {
+		t.Fatalf("RemoteBranchSHA: %v", err)
+	}
+	if head, _ := g.HeadSHA(); pushed != head {
+		t.Fatalf("RemoteBranchSHA = %q, want %q", pushed, head)
+	}
+
+	if err := g.ResetSoft(base); err != nil {
+		t.Fatalf("ResetSoft: %v", err)
+	}
+	squashed, err := g.Commit("squashed")
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := g.ForcePushWithLease("feature", "0000000000000000000000000000000000000000"); err == nil {
+		t.Fatal("force push with a stale lease succeeded")
+	}
+	if err := g.ForcePushWithLease("feature", pushed); err != nil {
+		t.Fatalf("ForcePushWithLease: %v", err)
+	}
+	if sha, _ := g.RemoteBranchSHA("feature"); sha != squashed {
+		t.Fatalf("remote branch = %q after force push, want %q", sha, squashed)
+	}
+}
+
 // The point of routing internal/git through internal/proc: a cancelled context
 // stops the git process instead of leaking it.
 func TestCommandsRespectContextCancellation(t *testing.T) {
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+)
+
+// Snapshot is a worktree as it stood at one moment: the commit HEAD was at
+// and a tree of every file, uncommitted changes and untracked files included.
+// Ignored files are not part of it.
+type Snapshot struct {
+	Head string
+	Tree string
+}
+
+// TakeSnapshot records the worktree without touching it, its index or the
+// stash: the tree is written through a throwaway copy of the index.
+func (g *Git) TakeSnapshot() (Snapshot, error) {
+	head, err := g.HeadSHA()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	indexPath, err := g.exec("rev-parse", "--git-path", "index")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if !filepath.IsAbs(indexPath) {
+		indexPath = filepath.Join(g.repoPath, indexPath)
+	}
+	dir, err := os.MkdirTemp("", "fog-snapshot-")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("create snapshot index: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	tmpIndex := filepath.Join(dir, "index")
+	// Starting from the real index keeps git's stat cache, so only changed
+	// files are hashed.
+	if data, err := os.ReadFile(indexPath); err == nil {
+		if err := os.WriteFile(tmpIndex, data, 0o600); err != nil {
+			return Snapshot{}, fmt.Errorf("create snapshot index: %w", err)
+		}
+	}
+	env := append(os.Environ(), "GIT_INDEX_FILE="+tmpIndex)
+	if _, err := g.execEnv(env, "add", "--all"); err != nil {
+		return Snapshot{}, err
+	}
+	tree, err := g.execEnv(env, "write-tree")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Head: head, Tree: tree}, nil
+}
+
+// RestoreSnapshot puts the worktree back to s: HEAD moves back to s.Head,
+// files are rewritten to their snapshot contents, and files created since are
+// removed. Changes that were uncommitted come back unstaged.
+func (g *Git) RestoreSnapshot(s Snapshot) error {
+	steps := [][]string{
+		{"reset", "--hard", s.Head},
+		{"read-tree", "--reset", "-u", s.Tree},
+		{"clean", "-fd"},
+		{"reset", "--quiet"},
+	}
+	for _, args := range steps {
+		if _, err := g.exec(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execEnv is exec with env as the child's whole environment.
+func (g *Git) execEnv(env []string, args ...string) (string, error) {
+	output, err := proc.RunEnv(g.context(), g.repoPath, env, "git", args...)
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w\n%s", strings.Join(args, " "), err, string(output))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRestoresCommitAndFiles(t *testing.T) {
+	dir := initRepo(t)
+	g := New(dir)
+	write(t, dir, "tracked.txt", "committed")
+	if err := g.StageAll(); err != nil {
+		t.Fatalf("StageAll: %v", err)
+	}
+	if _, err := g.Commit("add tracked"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	write(t, dir, "tracked.txt", "edited")
+	write(t, dir, "untracked.txt", "mine")
+
+	snap, err := g.TakeSnapshot()
+	if err != nil {
+		t.Fatalf("TakeSnapshot: %v", err)
+	}
+	if status, _ := g.exec("status", "--porcelain"); status != "M tracked.txt\n?? untracked.txt" {
+		t.Fatalf("status after snapshot = %q, want the worktree untouched", status)
+	}
+
+	write(t, dir, "later.txt", "later")
+	if err := g.StageAll(); err != nil {
+		t.Fatalf("StageAll: %v", err)
+	}
+	if _, err := g.Commit("later"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	write(t, dir, "stray.txt", "stray")
+	if err := os.Remove(filepath.Join(dir, "untracked.txt")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	if err := g.RestoreSnapshot(snap); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	if head, _ := g.HeadSHA(); head != snap.Head {
+		t.Errorf("HEAD = %s, want %s", head, snap.Head)
+	}
+	if status, _ := g.exec("status", "--porcelain"); status != "M tracked.txt\n?? untracked.txt" {
+		t.Errorf("status after restore = %q, want the snapshot's changes back", status)
+	}
+	if body, _ := os.ReadFile(filepath.Join(dir, "tracked.txt")); string(body) != "edited" {
+		t.Errorf("tracked.txt = %q, want the uncommitted edit", body)
+	}
+}
@@ -27,6 +27,13 @@ func (g *Git) AddWorktreeNewBranch(path, branch, startPoint string) error {
 	return err
 }
 
+// AddWorktreeDetached creates a worktree with a detached HEAD at startPoint,
+// without creating a branch
+func (g *Git) AddWorktreeDetached(path, startPoint string) error {
+	_, err := g.exec("worktree", "add", "--detach", path, startPoint)
+	return err
+}
+
 // RemoveWorktree removes a worktree
 func (g *Git) RemoveWorktree(path string, force bool) error {
 	args := []string{"worktree", "remove"}
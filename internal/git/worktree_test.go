@@ -32,6 +32,27 @@ func TestBranchExistsAndAddWorktreeNewBranch(t *testing.T) {
 	}
 }
 
+func TestAddWorktreeDetachedCreatesNoBranch(t *testing.T) {
+	repo := initGitRepo(t)
+	g := New(repo)
+
+	wtPath := filepath.Join(filepath.Dir(repo), "scratch-wt")
+	if err := g.AddWorktreeDetached(wtPath, "main"); err != nil {
+		t.Fatalf("AddWorktreeDetached failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "README.md")); err != nil {
+		t.Fatalf("expected checkout in detached worktree: %v", err)
+	}
+
+	out, err := exec.Command("git", "-C", repo, "branch", "--list").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git branch failed: %v\n%s", err, out)
+	}
+	if branches := strings.Fields(strings.ReplaceAll(string(out), "*", "")); len(branches) != 1 || branches[0] != "main" {
+		t.Fatalf("unexpected branches after detached worktree: %q", out)
+	}
+}
+
 func initGitRepo(t *testing.T) string {
 	t.Helper()
 
@@ -0,0 +1,43 @@
+// Package logging configures fogd's leveled, structured logger.
+//
+// Packages log through log/slog's default logger with fields such as
+// session_id, run_id and job_id; Setup points that logger at the daemon's
+// output and level. Lines still written with the log package go through the
+// same handler at info.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// EnvLevel names the environment variable that sets the level when
+// --log-level is not given.
+const EnvLevel = "FOG_LOG_LEVEL"
+
+// ParseLevel parses debug, info, warn (or warning) and error, ignoring case.
+// An empty level is info.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q: expected debug, info, warn or error", level)
+	}
+}
+
+// Setup installs a text logger writing to w at level as slog's default and
+// returns it.
+func Setup(w io.Writer, level slog.Level) *slog.Logger {
+	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(logger)
+	return logger
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"info":    slog.LevelInfo,
+		"DEBUG":   slog.LevelDebug,
+		" warn ":  slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+	} {
+		got, err := ParseLevel(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseLevel(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Fatal("expected an unknown level to be rejected")
+	}
+}
+
+func TestSetupFiltersByLevelAndKeepsFields(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var buf bytes.Buffer
+	Setup(&buf, slog.LevelInfo)
+	slog.Debug("noisy", "run_id", "run-1")
+	slog.Info("run completed", "session_id", "session-1", "run_id", "run-1")
+	log.Printf("plain line")
+
+	out := buf.String()
+	if strings.Contains(out, "noisy") {
+		t.Fatalf("debug line written at info:\n%s", out)
+	}
+	for _, want := range []string{"level=INFO", `msg="run completed"`, "session_id=session-1", "run_id=run-1", `msg="plain line"`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -191,6 +192,45 @@ func (s *Store) DeleteWorktree(name string) error {
 	})
 }
 
+// PruneMissing removes metadata for worktrees whose directories no longer
+// exist and returns their names, sorted. With dryRun it only reports them.
+func (s *Store) PruneMissing(dryRun bool) ([]string, error) {
+	var missing []string
+	collect := func(meta *Metadata) {
+		for name, wt := range meta.Worktrees {
+			if wt == nil || wt.Path == "" {
+				missing = append(missing, name)
+				continue
+			}
+			if _, err := os.Stat(wt.Path); os.IsNotExist(err) {
+				missing = append(missing, name)
+			}
+		}
+		slices.Sort(missing)
+	}
+
+	if dryRun {
+		meta, err := s.Get()
+		if err != nil {
+			return nil, err
+		}
+		collect(meta)
+		return missing, nil
+	}
+
+	err := s.Update(func(meta *Metadata) error {
+		collect(meta)
+		for _, name := range missing {
+			delete(meta.Worktrees, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
 // UpdateLastOpened updates the last opened timestamp
 func (s *Store) UpdateLastOpened(name string) error {
 	return s.Update(func(meta *Metadata) error {
@@ -39,6 +39,43 @@ func TestNewUsesGitCommonDirInWorktree(t *testing.T) {
 	}
 }
 
+func TestPruneMissingDropsWorktreesWhoseDirectoryIsGone(t *testing.T) {
+	repo := initTestRepo(t)
+	store, err := New(repo)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	kept := t.TempDir()
+	for name, path := range map[string]string{
+		"kept":   kept,
+		"gone-b": filepath.Join(repo, "missing-b"),
+		"gone-a": filepath.Join(repo, "missing-a"),
+	} {
+		if err := store.SetWorktree(name, &WorktreeMetadata{Path: path}); err != nil {
+			t.Fatalf("SetWorktree(%s) failed: %v", name, err)
+		}
+	}
+
+	missing, err := store.PruneMissing(true)
+	if err != nil || strings.Join(missing, ",") != "gone-a,gone-b" {
+		t.Fatalf("dry run = %v, %v; want gone-a,gone-b", missing, err)
+	}
+	if wt, _ := store.GetWorktree("gone-a"); wt == nil {
+		t.Fatal("dry run deleted metadata")
+	}
+
+	if missing, err = store.PruneMissing(false); err != nil || len(missing) != 2 {
+		t.Fatalf("PruneMissing = %v, %v; want two names", missing, err)
+	}
+	meta, err := store.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(meta.Worktrees) != 1 || meta.Worktrees["kept"] == nil {
+		t.Fatalf("worktrees after prune = %v, want only kept", meta.Worktrees)
+	}
+}
+
 func initTestRepo(t *testing.T) string {
 	t.Helper()
 
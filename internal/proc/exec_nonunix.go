@@ -17,11 +17,19 @@ var ErrCanceled = errors.New("process canceled")
 
 // Run executes a command and returns combined stdout/stderr.
 func Run(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	return RunEnv(ctx, dir, nil, name, args...)
+}
+
+// RunEnv is Run with an explicit environment for the child process. A nil env
+// inherits the parent's; a non-nil env replaces it entirely, including when
+// empty.
+func RunEnv(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Dir = dir
+	cmd.Env = env
 	out, err := cmd.CombinedOutput()
 	if ctx.Err() != nil {
 		return out, fmt.Errorf("%w: %v", ErrCanceled, ctx.Err())
@@ -19,6 +19,13 @@ var ErrCanceled = errors.New("process canceled")
 
 // Run executes a command in its own process group and returns combined stdout/stderr.
 func Run(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	return RunEnv(ctx, dir, nil, name, args...)
+}
+
+// RunEnv is Run with an explicit environment for the child process. A nil env
+// inherits the parent's; a non-nil env replaces it entirely, including when
+// empty.
+func RunEnv(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -28,6 +35,7 @@ func Run(ctx context.Context, dir, name string, args ...string) ([]byte, error)
 
 	cmd := exec.Command(name, args...)
 	cmd.Dir = dir
+	cmd.Env = env
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	var out bytes.Buffer
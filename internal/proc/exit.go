@@ -0,0 +1,25 @@
+package proc
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// ExitCode reports the exit status carried by an error from Run or its
+// variants. ok is false when the process did not run to exit — it failed to
+// start or was canceled — so callers can tell "exited 2" from "never ran".
+func ExitCode(err error) (code int, ok bool) {
+	if errors.Is(err, ErrCanceled) {
+		return 0, false
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+	code = exitErr.ExitCode()
+	if code < 0 {
+		// Killed by a signal rather than exiting.
+		return 0, false
+	}
+	return code, true
+}
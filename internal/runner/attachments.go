@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+const (
+	// attachmentMaxBytes caps each attached file in the prompt.
+	attachmentMaxBytes = 32 << 10
+	// attachmentsMaxBytes caps all attached files together; files past it
+	// are skipped.
+	attachmentsMaxBytes = 128 << 10
+	// contextTextMaxBytes caps pasted context text in the prompt.
+	contextTextMaxBytes = 32 << 10
+)
+
+// normalizeAttachments cleans attachment paths like focus paths: relative to
+// the worktree, never outside it, blanks and duplicates dropped. Whether the
+// files exist is only known once the worktree is checked out.
+func normalizeAttachments(paths []string) ([]string, error) {
+	return normalizeWorktreePaths("attachment", paths)
+}
+
+// attachmentContext builds the block put ahead of the first run's prompt from
+// its attachments and pasted context text, or "" when there are neither.
+// Files are read from the session worktree, after the setup command. A file
+// that is missing, a directory, binary, outside the worktree through a
+// symlink or past the size budget is skipped with an attachment_skipped
+// event rather than failing the run.
+func (r *Runner) attachmentContext(runID, worktreePath string, paths []string, contextText string) string {
+	contextText = strings.TrimSpace(contextText)
+	if len(paths) == 0 && contextText == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	if contextText != "" {
+		b.WriteString("Context provided with this task:\n<context>\n")
+		b.WriteString(capBytes(contextText, contextTextMaxBytes))
+		b.WriteString("\n</context>\n\n")
+	}
+
+	var files strings.Builder
+	root, rootErr := os.OpenRoot(worktreePath)
+	if rootErr == nil {
+		defer func() { _ = root.Close() }()
+	}
+	budget := attachmentsMaxBytes
+	for _, p := range paths {
+		content, err := "", rootErr
+		if err == nil {
+			content, err = readAttachment(root, p, min(attachmentMaxBytes, budget))
+		}
+		if err != nil {
+			_ = r.runs.AppendRunEvent(state.RunEvent{
+				RunID:   runID,
+				Type:    "attachment_skipped",
+				Message: fmt.Sprintf("Skipped attachment %s: %v", p, err),
+				Data:    p,
+			})
+			continue
+		}
+		budget -= len(content)
+		fmt.Fprintf(&files, "<file path=%q>\n%s\n</file>\n", p, content)
+	}
+	if files.Len() > 0 {
+		b.WriteString("Files attached to this task, relative to the repository root:\n")
+		b.WriteString(files.String())
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	b.WriteString("Task:\n")
+	return b.String()
+}
+
+// readAttachment reads the worktree file at p through root, which refuses
+// paths and symlinks leading out of the worktree. Content past limit is cut
+// and marked.
+func readAttachment(root *os.Root, p string, limit int) (string, error) {
+	if limit <= 0 {
+		return "", fmt.Errorf("attachments exceed %d KiB in total", attachmentsMaxBytes>>10)
+	}
+	f, err := root.Open(filepath.FromSlash(p))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", errors.New("not found")
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", errors.New("is a directory")
+	}
+	data, err := io.ReadAll(io.LimitReader(f, int64(limit)+1))
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", errors.New("looks like a binary file")
+	}
+	return capBytes(string(data), limit), nil
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeAttachments(t *testing.T) {
+	got, err := normalizeAttachments([]string{" docs/spec.md ", "./docs/spec.md", "", "src/../main.go"})
+	if err != nil {
+		t.Fatalf("normalizeAttachments: %v", err)
+	}
+	if strings.Join(got, ",") != "docs/spec.md,main.go" {
+		t.Fatalf("attachments = %v", got)
+	}
+	for _, bad := range []string{"../secrets.env", "/etc/passwd", "docs/../../x"} {
+		if _, err := normalizeAttachments([]string{bad}); err == nil || !strings.Contains(err.Error(), "attachment") {
+			t.Errorf("normalizeAttachments(%q) = %v, want an attachment error", bad, err)
+		}
+	}
+}
+
+func TestExecuteSessionRunPrependsAttachments(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(store, tool, nil)
+
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "spec.md", "OTP codes expire after 30 seconds.\n")
+	writeFile(t, wt, "logo.png", "\x89PNG\x00\x01")
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("hunter2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(wt, "escape.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:      "add OTP login",
+		BaseBranch:  "main",
+		CommitMsg:   "feat: otp",
+		Attachments: []string{"spec.md", "missing.md", "logo.png", "escape.txt"},
+		ContextText: "Use the existing mailer.",
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+
+	prompt := tool.request().Prompt
+	for _, want := range []string{
+		"<context>\nUse the existing mailer.\n</context>",
+		"<file path=\"spec.md\">\nOTP codes expire after 30 seconds.\n",
+		"Task:\nadd OTP login",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "hunter2") || strings.Contains(prompt, "PNG") {
+		t.Errorf("prompt includes a skipped file:\n%s", prompt)
+	}
+	if got := countEvents(store, "attachment_skipped"); got != 3 {
+		t.Errorf("attachment_skipped events = %d, want 3 (%v)", got, store.eventTypes())
+	}
+	// The stored prompt stays what the user typed.
+	if got := store.runs["run-1"].Prompt; strings.Contains(got, "<file") {
+		t.Errorf("run prompt includes attachments: %q", got)
+	}
+}
+
+func TestAttachmentContextCapsFiles(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+
+	wt := t.TempDir()
+	big := strings.Repeat("x", attachmentMaxBytes+100)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		writeFile(t, wt, name, big)
+	}
+
+	got := r.attachmentContext("run-1", wt, []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"}, "")
+	if n := strings.Count(got, "...[truncated]"); n != 4 {
+		t.Fatalf("truncated files = %d, want 4 within the total budget", n)
+	}
+	if ev, ok := store.eventOfType("attachment_skipped"); !ok || ev.Data != "e.txt" {
+		t.Fatalf("expected e.txt skipped past the budget, got %+v (found=%v)", ev, ok)
+	}
+	if r.attachmentContext("run-1", wt, nil, "  ") != "" {
+		t.Fatal("empty attachments and context should add nothing")
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// BranchStatus is how far a session branch has drifted from its base.
+type BranchStatus struct {
+	// Base is the ref compared against: origin/<base> when the repo has it,
+	// else the local base branch.
+	Base   string `json:"base"`
+	Ahead  int    `json:"ahead"`
+	Behind int    `json:"behind"`
+}
+
+// CheckBranchStatus counts the commits the branch checked out in worktreePath
+// has over baseBranch and lacks from it. It compares against origin/<base>
+// as of the last fetch, falling back to the local branch when there is no
+// remote-tracking ref; nothing is fetched.
+func (r *Runner) CheckBranchStatus(worktreePath, baseBranch string) (BranchStatus, error) {
+	worktreePath = strings.TrimSpace(worktreePath)
+	baseBranch = strings.TrimSpace(baseBranch)
+	if worktreePath == "" || baseBranch == "" {
+		return BranchStatus{}, fmt.Errorf("worktree path and base branch are required")
+	}
+
+	g := git.New(worktreePath)
+	base := "origin/" + baseBranch
+	if !g.RefExists(base) {
+		base = baseBranch
+	}
+	ahead, behind, err := g.AheadBehind(base)
+	if err != nil {
+		return BranchStatus{}, fmt.Errorf("compare with %s: %w", base, err)
+	}
+	return BranchStatus{Base: base, Ahead: ahead, Behind: behind}, nil
+}
+
+// SessionBranchStatus is CheckBranchStatus for a session's worktree and its
+// repo's default branch.
+func (r *Runner) SessionBranchStatus(sessionID string) (BranchStatus, error) {
+	_, worktreePath, baseBranch, err := r.sessionBranchContext(sessionID)
+	if err != nil {
+		return BranchStatus{}, err
+	}
+	return r.CheckBranchStatus(worktreePath, baseBranch)
+}
+
+// recordBranchStatus appends a branch_status event saying how far the run's
+// branch is from base. It is informational: a failed check records nothing.
+func (r *Runner) recordBranchStatus(runID, worktreePath, baseBranch string) {
+	status, err := r.CheckBranchStatus(worktreePath, baseBranch)
+	if err != nil {
+		return
+	}
+	data, _ := json.Marshal(status)
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   runID,
+		Type:    "branch_status",
+		Message: fmt.Sprintf("Branch is %d ahead and %d behind %s", status.Ahead, status.Behind, status.Base),
+		Data:    string(data),
+	})
+}
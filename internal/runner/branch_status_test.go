@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheckBranchStatusPrefersRemoteTrackingBase(t *testing.T) {
+	dir := initTestWorktree(t)
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("branch", "base")
+	git("checkout", "-q", "-b", "fog/feature")
+	git("commit", "-q", "--allow-empty", "-m", "feature work")
+	git("checkout", "-q", "base")
+	for _, msg := range []string{"one", "two", "three"} {
+		git("commit", "-q", "--allow-empty", "-m", msg)
+	}
+	git("update-ref", "refs/remotes/origin/base", git("rev-parse", "base~1"))
+	git("checkout", "-q", "fog/feature")
+
+	store := newFakeRunStore()
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, fakeSettings{})
+
+	status, err := r.CheckBranchStatus(dir, "base")
+	if err != nil {
+		t.Fatalf("CheckBranchStatus: %v", err)
+	}
+	if status != (BranchStatus{Base: "origin/base", Ahead: 1, Behind: 2}) {
+		t.Fatalf("status = %+v, want 1 ahead and 2 behind origin/base", status)
+	}
+
+	git("update-ref", "-d", "refs/remotes/origin/base")
+	if status, err = r.CheckBranchStatus(dir, "base"); err != nil || status.Base != "base" || status.Behind != 3 {
+		t.Fatalf("without a remote ref: status = %+v, err = %v; want 3 behind local base", status, err)
+	}
+
+	r.recordBranchStatus("run-1", dir, "base")
+	event, ok := store.eventOfType("branch_status")
+	if !ok {
+		t.Fatal("no branch_status event recorded")
+	}
+	var recorded BranchStatus
+	if err := json.Unmarshal([]byte(event.Data), &recorded); err != nil || recorded != status {
+		t.Fatalf("event data = %q (%v), want %+v", event.Data, err, status)
+	}
+
+	if _, err := r.CheckBranchStatus(dir, "no-such-branch"); err == nil {
+		t.Fatal("expected an error for a missing base branch")
+	}
+}
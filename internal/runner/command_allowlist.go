@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SettingCommandAllowlist holds the programs setup and validation commands may
+// run, as a JSON array such as ["make", "npm", "pnpm"]. A command is allowed
+// when its first word is one of them. Unset or empty allows any command that
+// passes ValidateShellCommand.
+const SettingCommandAllowlist = "command_allowlist"
+
+// ErrCommandNotAllowed is returned when a session's setup or validation
+// command does not start with a program on the command allowlist.
+var ErrCommandNotAllowed = errors.New("command not allowed")
+
+// ParseCommandAllowlist parses and checks a command_allowlist value. Empty
+// parses to nil.
+func ParseCommandAllowlist(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var parsed []string
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("must be a JSON array of command names: %w", err)
+	}
+	return NormalizeCommandAllowlist(parsed)
+}
+
+// NormalizeCommandAllowlist trims and deduplicates allowlist entries. An
+// entry is one word: blank entries, entries containing whitespace and entries
+// ValidateShellCommand rejects are errors.
+func NormalizeCommandAllowlist(entries []string) ([]string, error) {
+	var out []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.ContainsFunc(entry, isSpaceOrControl) {
+			return nil, fmt.Errorf("invalid command %q: must be a single word", entry)
+		}
+		if err := ValidateShellCommand(entry); err != nil {
+			return nil, fmt.Errorf("invalid command %q: %w", entry, err)
+		}
+		if !slices.Contains(out, entry) {
+			out = append(out, entry)
+		}
+	}
+	return out, nil
+}
+
+// CommandAllowlist reads command_allowlist. A malformed value is an error
+// rather than an empty list, so a typo never lifts the restriction.
+func (r *Runner) CommandAllowlist() ([]string, error) {
+	if r.settings == nil {
+		return nil, nil
+	}
+	raw, found, err := r.settings.GetSetting(SettingCommandAllowlist)
+	if err != nil || !found {
+		return nil, err
+	}
+	allowlist, err := ParseCommandAllowlist(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", SettingCommandAllowlist, err)
+	}
+	return allowlist, nil
+}
+
+// CheckSessionCommands reports whether opts would be rejected by the command
+// allowlist when the session starts, for callers that plan a session without
+// starting it.
+func (r *Runner) CheckSessionCommands(opts StartSessionOptions) error {
+	return r.checkSessionCommands(strings.TrimSpace(opts.SetupCmd), opts.Validate, strings.TrimSpace(opts.ValidateCmd))
+}
+
+// checkSessionCommands holds the commands a session will run to the command
+// allowlist: the setup command, and the validation command when validation
+// is on.
+func (r *Runner) checkSessionCommands(setupCmd string, validate bool, validateCmd string) error {
+	allowlist, err := r.CommandAllowlist()
+	if err != nil || len(allowlist) == 0 {
+		return err
+	}
+	if err := checkCommandAllowed(allowlist, setupCmd); err != nil {
+		return fmt.Errorf("setup_cmd: %w", err)
+	}
+	if validate {
+		if err := checkCommandAllowed(allowlist, validateCmd); err != nil {
+			return fmt.Errorf("validate_cmd: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkCommandAllowed reports whether cmd's first word is on allowlist. An
+// empty command runs nothing and is allowed.
+func checkCommandAllowed(allowlist []string, cmd string) error {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return nil
+	}
+	if !slices.Contains(allowlist, fields[0]) {
+		return fmt.Errorf("%w: %q is not on the command allowlist (%s)", ErrCommandNotAllowed, fields[0], strings.Join(allowlist, ", "))
+	}
+	return nil
+}
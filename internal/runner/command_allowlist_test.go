@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommandAllowlist(t *testing.T) {
+	got, err := ParseCommandAllowlist(`[" make ", "npm", "make", "./scripts/test.sh"]`)
+	if err != nil {
+		t.Fatalf("ParseCommandAllowlist: %v", err)
+	}
+	if want := []string{"make", "npm", "./scripts/test.sh"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("allowlist = %v, want %v", got, want)
+	}
+	for _, raw := range []string{`{"make": true}`, `[""]`, `["npm run"]`, `["make;reboot"]`} {
+		if _, err := ParseCommandAllowlist(raw); err == nil {
+			t.Errorf("ParseCommandAllowlist(%s) = nil error", raw)
+		}
+	}
+}
+
+func TestCheckSessionCommands(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, fakeSettings{SettingCommandAllowlist: `["make", "npm"]`})
+	cases := []struct {
+		setup, validate string
+		validating      bool
+		ok              bool
+	}{
+		{setup: "npm ci", validate: "make test", validating: true, ok: true},
+		{setup: "", validate: "", validating: true, ok: true},
+		{setup: "curl example.com", ok: false},
+		{setup: "npm ci", validate: "go test ./...", validating: true, ok: false},
+		// Validation off: its command never runs, so it is not held to the list.
+		{setup: "npm ci", validate: "go test ./...", validating: false, ok: true},
+		// The first word must match exactly, not merely start with an entry.
+		{setup: "makefile-gen", ok: false},
+	}
+	for _, tc := range cases {
+		err := r.checkSessionCommands(tc.setup, tc.validating, tc.validate)
+		if (err == nil) != tc.ok || (err != nil && !errors.Is(err, ErrCommandNotAllowed)) {
+			t.Errorf("checkSessionCommands(%q, %v, %q) = %v, want ok=%v", tc.setup, tc.validating, tc.validate, err, tc.ok)
+		}
+	}
+
+	// No allowlist keeps the old behaviour.
+	r = newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, nil)
+	if err := r.checkSessionCommands("curl example.com", true, "go test ./..."); err != nil {
+		t.Fatalf("checkSessionCommands without an allowlist = %v", err)
+	}
+}
+
+func TestPrepareSessionRejectsCommandOffAllowlist(t *testing.T) {
+	store := newFakeRunStore()
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, fakeSettings{SettingCommandAllowlist: `["make"]`})
+
+	_, _, _, err := r.prepareSession(StartSessionOptions{
+		RepoName:   "acme/api",
+		RepoPath:   t.TempDir(),
+		Branch:     "fog/feature",
+		Tool:       "claude",
+		Prompt:     "add a feature",
+		BaseBranch: "main",
+		SetupCmd:   "npm ci",
+	})
+	if !errors.Is(err, ErrCommandNotAllowed) {
+		t.Fatalf("prepareSession err = %v, want ErrCommandNotAllowed", err)
+	}
+	if len(store.sessions) != 0 {
+		t.Fatalf("a session was created despite the rejected command")
+	}
+}
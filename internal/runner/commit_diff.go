@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+)
+
+// SettingCommitDiffBudget is how many characters of the staged diff are given
+// to the tool when it writes a commit message. Unset uses
+// DefaultCommitDiffBudget.
+const SettingCommitDiffBudget = "commit_diff_budget"
+
+const (
+	// DefaultCommitDiffBudget is the budget when none is configured.
+	DefaultCommitDiffBudget = 12000
+	// MaxCommitDiffBudget caps commit_diff_budget; past it the prompt costs
+	// more than a better message is worth.
+	MaxCommitDiffBudget = 200000
+)
+
+// CommitDiffBudget reads commit_diff_budget. 0 is allowed and leaves the
+// patch out entirely. An unset, malformed or out-of-range value falls back to
+// DefaultCommitDiffBudget.
+func (r *Runner) CommitDiffBudget() int {
+	if r.settings == nil {
+		return DefaultCommitDiffBudget
+	}
+	raw, found, err := r.settings.GetSetting(SettingCommitDiffBudget)
+	if err != nil || !found {
+		return DefaultCommitDiffBudget
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 0 || n > MaxCommitDiffBudget {
+		return DefaultCommitDiffBudget
+	}
+	return n
+}
+
+func stagedDiffSummary(ctx context.Context, workdir string, budget int) (string, error) {
+	diff, err := git.New(workdir).WithContext(ctx).StagedChanges()
+	if err != nil {
+		return "", err
+	}
+	return formatDiffSummary(diff, budget), nil
+}
+
+// formatDiffSummary fits a staged diff into budget characters. The name-status
+// and stat listings are always included in full, since they are small and name
+// every changed file; the patch gets whatever budget they leave.
+func formatDiffSummary(diff git.StagedDiff, budget int) string {
+	head := "Name status:\n" + diff.NameStatus + "\n\nStat:\n" + diff.Stat
+	patch := strings.TrimSpace(diff.Patch)
+	room := budget - len(head)
+	switch {
+	case patch == "":
+		return strings.TrimSpace(head)
+	case len(patch) <= room:
+		return strings.TrimSpace(head + "\n\nPatch:\n" + patch)
+	case room <= 0:
+		return strings.TrimSpace(head + "\n\nPatch omitted: the file list above exceeds the diff budget.")
+	}
+	return strings.TrimSpace(head + "\n\nPatch (truncated):\n" + truncate(patch, room))
+}
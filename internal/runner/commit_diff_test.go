@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/git"
+)
+
+func TestFormatDiffSummaryKeepsFileListsWhenTruncating(t *testing.T) {
+	diff := git.StagedDiff{
+		NameStatus: "M\ta.go\nA\tb.go",
+		Stat:       " a.go | 2 +-\n b.go | 400 ++++\n 2 files changed",
+		Patch:      strings.Repeat("+line\n", 500),
+	}
+
+	full := formatDiffSummary(diff, 100000)
+	if !strings.Contains(full, "Patch:\n") || strings.Contains(full, "truncated") {
+		t.Fatalf("patch within budget should be sent whole:\n%s", full)
+	}
+
+	cut := formatDiffSummary(diff, 300)
+	if !strings.Contains(cut, diff.NameStatus) || !strings.Contains(cut, diff.Stat) {
+		t.Fatalf("file lists were cut:\n%s", cut)
+	}
+	if !strings.Contains(cut, "Patch (truncated):\n") || len(cut) > 300+len("\n\nPatch (truncated):\n...") {
+		t.Fatalf("patch not truncated to the budget (%d chars):\n%s", len(cut), cut)
+	}
+
+	none := formatDiffSummary(diff, 0)
+	if !strings.Contains(none, diff.Stat) || strings.Contains(none, "+line") {
+		t.Fatalf("budget 0 should omit the patch:\n%s", none)
+	}
+}
+
+func TestCommitDiffBudgetFallsBackToDefault(t *testing.T) {
+	for raw, want := range map[string]int{"": DefaultCommitDiffBudget, "junk": DefaultCommitDiffBudget, "-5": DefaultCommitDiffBudget, "300000": DefaultCommitDiffBudget, "0": 0, "4000": 4000} {
+		settings := fakeSettings{}
+		if raw != "" {
+			settings[SettingCommitDiffBudget] = raw
+		}
+		r := newTestRunner(newFakeRunStore(), &fakeTool{}, settings)
+		if got := r.CommitDiffBudget(); got != want {
+			t.Errorf("commit_diff_budget %q = %d, want %d", raw, got, want)
+		}
+	}
+}
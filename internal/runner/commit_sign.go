@@ -0,0 +1,134 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/proc"
+)
+
+// SettingCommitSign is the settings key for how Fog signs the commits it
+// makes: none (the default), gpg or ssh. Repos that require signed commits
+// reject an unsigned pushed branch.
+const SettingCommitSign = "commit_sign"
+
+// SettingCommitSigningKey is the key commits are signed with: a GPG key ID
+// for gpg, or a key file path (or git's "key::" literal) for ssh. gpg may
+// leave it empty to sign with the default secret key.
+const SettingCommitSigningKey = "commit_signing_key"
+
+// Commit signing modes.
+const (
+	CommitSignNone = "none"
+	CommitSignGPG  = "gpg"
+	CommitSignSSH  = "ssh"
+)
+
+// gpgListSecretKeys lists the secret keys matching key, or every secret key
+// when key is empty. Tests swap it out.
+var gpgListSecretKeys = func(ctx context.Context, key string) ([]byte, error) {
+	args := []string{"--batch", "--with-colons", "--list-secret-keys"}
+	if key != "" {
+		args = append(args, key)
+	}
+	return proc.Run(ctx, "", "gpg", args...)
+}
+
+// ValidateCommitSign checks a commit_sign value before it is stored. Empty is
+// valid and means none.
+func ValidateCommitSign(mode string) error {
+	switch strings.TrimSpace(mode) {
+	case "", CommitSignNone, CommitSignGPG, CommitSignSSH:
+		return nil
+	default:
+		return fmt.Errorf("commit_sign must be %s, %s or %s", CommitSignNone, CommitSignGPG, CommitSignSSH)
+	}
+}
+
+// CommitSign returns the commit_sign mode, none when unset or invalid, and
+// the signing key.
+func (r *Runner) CommitSign() (mode, key string) {
+	if r.settings == nil {
+		return CommitSignNone, ""
+	}
+	if raw, found, err := r.settings.GetSetting(SettingCommitSign); err == nil && found && ValidateCommitSign(raw) == nil {
+		mode = strings.TrimSpace(raw)
+	}
+	if mode == "" {
+		mode = CommitSignNone
+	}
+	if raw, found, err := r.settings.GetSetting(SettingCommitSigningKey); err == nil && found {
+		key = strings.TrimSpace(raw)
+	}
+	return mode, key
+}
+
+// commitSigning returns how commits should be signed, having checked that the
+// signing key is usable so a missing key fails with a clear message rather
+// than an opaque git error.
+func (r *Runner) commitSigning(ctx context.Context) (git.Signing, error) {
+	mode, key := r.CommitSign()
+	switch mode {
+	case CommitSignGPG:
+		out, err := gpgListSecretKeys(ctx, key)
+		if err == nil && !strings.Contains(string(out), "sec:") {
+			err = fmt.Errorf("no secret keys listed")
+		}
+		if err != nil {
+			if key == "" {
+				return git.Signing{}, fmt.Errorf("commit_sign=gpg: no usable gpg secret key: %w", err)
+			}
+			return git.Signing{}, fmt.Errorf("commit_sign=gpg: signing key %q is not in the gpg keyring: %w", key, err)
+		}
+		return git.Signing{Format: "openpgp", Key: key}, nil
+	case CommitSignSSH:
+		if key == "" {
+			return git.Signing{}, fmt.Errorf("commit_sign=ssh: commit_signing_key is not set")
+		}
+		if !strings.HasPrefix(key, "key::") {
+			path, err := expandHome(key)
+			if err != nil {
+				return git.Signing{}, fmt.Errorf("commit_sign=ssh: %w", err)
+			}
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				return git.Signing{}, fmt.Errorf("commit_sign=ssh: signing key %q is not a readable file", key)
+			}
+			key = path
+		}
+		return git.Signing{Format: "ssh", Key: key}, nil
+	default:
+		return git.Signing{}, nil
+	}
+}
+
+// commit records g's staged changes as msg, signed as commit_sign says.
+func (r *Runner) commit(ctx context.Context, g *git.Git, msg string) (string, error) {
+	signing, err := r.commitSigning(ctx)
+	if err != nil {
+		return "", err
+	}
+	sha, err := g.WithSigning(signing).Commit(msg)
+	if err != nil {
+		if signing.Format != "" {
+			mode, _ := r.CommitSign()
+			return "", fmt.Errorf("signed git commit failed (commit_sign=%s): %w", mode, err)
+		}
+		return "", fmt.Errorf("git commit failed: %w", err)
+	}
+	return sha, nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitSessionChangesSignsWithSSHKey(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v\n%s", err, out)
+	}
+
+	r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, fakeSettings{
+		SettingCommitSign:       CommitSignSSH,
+		SettingCommitSigningKey: key,
+	})
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "feature.txt", "work")
+
+	if _, _, changed, err := r.commitSessionChanges(context.Background(), "claude", wt, "", "feat: signed", ""); err != nil || !changed {
+		t.Fatalf("commitSessionChanges = changed %v, err %v", changed, err)
+	}
+	if raw := gitOut(t, wt, "cat-file", "commit", "HEAD"); !strings.Contains(raw, "-----BEGIN SSH SIGNATURE-----") {
+		t.Fatalf("HEAD is not ssh-signed:\n%s", raw)
+	}
+	cmd := exec.Command("git", "config", "--get", "gpg.format")
+	cmd.Dir = wt
+	if out, err := cmd.Output(); err == nil {
+		t.Fatalf("signing config leaked into the worktree: gpg.format=%s", out)
+	}
+}
+
+func TestCommitSessionChangesRejectsMissingSigningKey(t *testing.T) {
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "feature.txt", "work")
+
+	r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, fakeSettings{
+		SettingCommitSign:       CommitSignSSH,
+		SettingCommitSigningKey: filepath.Join(t.TempDir(), "missing"),
+	})
+	if _, _, _, err := r.commitSessionChanges(context.Background(), "claude", wt, "", "feat: x", ""); err == nil || !strings.Contains(err.Error(), "not a readable file") {
+		t.Fatalf("err = %v, want a missing ssh key error", err)
+	}
+
+	prev := gpgListSecretKeys
+	t.Cleanup(func() { gpgListSecretKeys = prev })
+	gpgListSecretKeys = func(context.Context, string) ([]byte, error) { return nil, errors.New("exit status 2") }
+	r.settings = fakeSettings{SettingCommitSign: CommitSignGPG, SettingCommitSigningKey: "ABCD1234"}
+	if _, _, _, err := r.commitSessionChanges(context.Background(), "claude", wt, "", "feat: x", ""); err == nil || !strings.Contains(err.Error(), `"ABCD1234" is not in the gpg keyring`) {
+		t.Fatalf("err = %v, want a missing gpg key error", err)
+	}
+	if got := gitOut(t, wt, "rev-list", "--count", "HEAD"); got != "1" {
+		t.Fatalf("commit count = %s, want nothing committed", got)
+	}
+}
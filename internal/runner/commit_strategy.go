@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+)
+
+// Commit strategies, stored on the session and applied by the run that pushes.
+const (
+	// CommitStrategyPerRun keeps one commit per run. It is the default.
+	CommitStrategyPerRun = "per_run"
+	// CommitStrategySquash folds the commits not yet on origin into one.
+	CommitStrategySquash = "squash"
+	// CommitStrategySquashForce folds every commit since the base branch into
+	// one, rewriting commits already pushed and force-pushing the result.
+	CommitStrategySquashForce = "squash_force"
+)
+
+// normalizeCommitStrategy validates a requested strategy. Empty selects the
+// default.
+func normalizeCommitStrategy(strategy string) (string, error) {
+	switch s := strings.TrimSpace(strategy); s {
+	case "":
+		return CommitStrategyPerRun, nil
+	case CommitStrategyPerRun, CommitStrategySquash, CommitStrategySquashForce:
+		return s, nil
+	default:
+		return "", fmt.Errorf("commit_strategy must be %s, %s or %s", CommitStrategyPerRun, CommitStrategySquash, CommitStrategySquashForce)
+	}
+}
+
+// squashResult describes what squashSessionCommits did.
+type squashResult struct {
+	// SHA is the branch head afterwards.
+	SHA string
+	// Squashed is how many commits were folded; zero means nothing changed.
+	Squashed int
+	// Lease is the origin head the rewrite replaced. Non-empty means the
+	// branch must be force-pushed, guarded by this SHA.
+	Lease string
+}
+
+// squashSessionCommits folds the session's commits into one carrying msg,
+// via a soft reset and a single commit. Without force only commits origin
+// does not have yet are folded, so published history is never rewritten. If
+// origin's branch has moved to a commit this worktree lacks, nothing is
+// squashed and the push is left to fail as it would have.
+func (r *Runner) squashSessionCommits(ctx context.Context, workdir, baseBranch, branch, msg string, force bool) (squashResult, error) {
+	g := git.New(workdir).WithContext(ctx)
+
+	head, err := g.HeadSHA()
+	if err != nil {
+		return squashResult{}, fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	remote, err := g.RemoteBranchSHA(branch)
+	if err != nil {
+		return squashResult{}, fmt.Errorf("git ls-remote failed: %w", err)
+	}
+	if remote != "" && !g.IsAncestor(remote, head) {
+		return squashResult{SHA: head}, nil
+	}
+
+	onto := remote
+	if onto == "" || force {
+		onto, err = g.MergeBase(baseBranch, head)
+		if err != nil {
+			return squashResult{}, fmt.Errorf("git merge-base failed: %w", err)
+		}
+	}
+	count, err := g.CountCommits(onto + ".." + head)
+	if err != nil {
+		return squashResult{}, fmt.Errorf("git rev-list failed: %w", err)
+	}
+	if count < 2 {
+		return squashResult{SHA: head}, nil
+	}
+
+	if err := g.ResetSoft(onto); err != nil {
+		return squashResult{}, fmt.Errorf("git reset failed: %w", err)
+	}
+	sha, err := r.commit(ctx, g, msg)
+	if err != nil {
+		// Put the branch back where it was rather than leave the run's work
+		// staged on an older head.
+		_ = g.ResetSoft(head)
+		return squashResult{}, err
+	}
+	result := squashResult{SHA: sha, Squashed: count}
+	if remote != "" && onto != remote {
+		result.Lease = remote
+	}
+	return result, nil
+}
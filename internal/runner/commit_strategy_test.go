@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func gitOut(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// squashWorktree returns a worktree on fog/test with a remote, a main branch at
+// the initial commit and one earlier session commit on top.
+func squashWorktree(t *testing.T) string {
+	t.Helper()
+	wt := initTestWorktreeWithRemote(t)
+	gitOut(t, wt, "branch", "main")
+	writeFile(t, wt, "first.txt", "first run")
+	gitOut(t, wt, "add", ".")
+	gitOut(t, wt, "commit", "-m", "feat: first run")
+	return wt
+}
+
+func TestNormalizeCommitStrategy(t *testing.T) {
+	for in, want := range map[string]string{"": CommitStrategyPerRun, " squash ": CommitStrategySquash, "squash_force": CommitStrategySquashForce} {
+		got, err := normalizeCommitStrategy(in)
+		if err != nil || got != want {
+			t.Errorf("normalizeCommitStrategy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := normalizeCommitStrategy("rebase"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+func TestExecuteSessionRunSquashesBeforeFirstPush(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	pub := &fakePublisher{available: true, url: "https://example.invalid/pr/7"}
+	r := newTestRunnerWithPublisher(store, &fakeTool{name: "claude", available: true, output: "ok"}, nil, pub)
+
+	wt := squashWorktree(t)
+	writeFile(t, wt, "second.txt", "second run")
+	session := testSession(wt)
+	session.AutoPR = true
+	session.CommitStrategy = CommitStrategySquash
+
+	if err := r.executeSessionRun(session, testRun(wt), sessionRunOptions{
+		Prompt:     "add a feature",
+		BaseBranch: "main",
+		CommitMsg:  "feat: whole feature",
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+
+	if got := gitOut(t, wt, "rev-list", "--count", "main..HEAD"); got != "1" {
+		t.Fatalf("commits on branch = %s, want 1", got)
+	}
+	if got := gitOut(t, wt, "log", "-1", "--format=%s"); got != "feat: whole feature" {
+		t.Errorf("squashed commit subject = %q", got)
+	}
+	remote := strings.Fields(gitOut(t, wt, "ls-remote", "origin", "refs/heads/fog/test"))
+	if len(remote) == 0 || remote[0] != gitOut(t, wt, "rev-parse", "HEAD") {
+		t.Errorf("origin branch = %v, want the squashed head", remote)
+	}
+	if lastString(store.runStates) != "COMPLETED" {
+		t.Errorf("run state = %v, want COMPLETED", store.runStates)
+	}
+	if ev, ok := store.eventOfType("commit"); !ok || !strings.Contains(ev.Message, "Squashed 2 commits") {
+		t.Errorf("missing squash event, got %+v", ev)
+	}
+}
+
+func TestSquashSessionCommitsLeavesPushedCommitsUnlessForced(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), &fakeTool{}, nil)
+	wt := squashWorktree(t)
+	gitOut(t, wt, "push", "-u", "origin", "fog/test")
+	pushed := gitOut(t, wt, "rev-parse", "HEAD")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		writeFile(t, wt, name, name)
+		gitOut(t, wt, "add", ".")
+		gitOut(t, wt, "commit", "-m", "add "+name)
+	}
+
+	res, err := r.squashSessionCommits(context.Background(), wt, "main", "fog/test", "feat: follow-up", false)
+	if err != nil {
+		t.Fatalf("squash: %v", err)
+	}
+	if res.Squashed != 2 || res.Lease != "" {
+		t.Fatalf("unforced squash = %+v, want 2 commits folded and no force push", res)
+	}
+	if got := gitOut(t, wt, "rev-parse", "HEAD~1"); got != pushed {
+		t.Fatalf("squash rewrote pushed history: HEAD~1 = %s, want %s", got, pushed)
+	}
+
+	res, err = r.squashSessionCommits(context.Background(), wt, "main", "fog/test", "feat: everything", true)
+	if err != nil {
+		t.Fatalf("forced squash: %v", err)
+	}
+	if res.Squashed != 2 || res.Lease != pushed {
+		t.Fatalf("forced squash = %+v, want 2 commits folded with lease %s", res, pushed)
+	}
+	if got := gitOut(t, wt, "rev-list", "--count", "main..HEAD"); got != "1" {
+		t.Fatalf("commits on branch after forced squash = %s, want 1", got)
+	}
+	if err := r.forcePushBranch(context.Background(), wt, "fog/test", res.Lease); err != nil {
+		t.Fatalf("force push: %v", err)
+	}
+}
+
+func TestResolveLaunchRejectsUnknownCommitStrategy(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{})
+	req := validRequest()
+	req.CommitStrategy = "rebase"
+	if _, err := r.resolveLaunch(req); !errors.Is(err, ErrInvalidLaunch) {
+		t.Fatalf("expected ErrInvalidLaunch, got %v", err)
+	}
+}
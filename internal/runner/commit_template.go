@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SettingCommitTemplate is the message a run commits with when no commit_msg
+// was given, such as "chore({branch}): {prompt}". Placeholders are {branch},
+// {prompt} (its first line), {session_id} and {date} (YYYY-MM-DD, UTC).
+const SettingCommitTemplate = "commit_template"
+
+// Commit message modes: how a run that was given no commit_msg picks one.
+const (
+	// CommitMsgModeAI uses the message the tool wrote in its output, else asks
+	// the tool for one. It is the default when commit_template is unset.
+	CommitMsgModeAI = "ai"
+	// CommitMsgModeTemplate renders commit_template. It is the default when
+	// commit_template is set.
+	CommitMsgModeTemplate = "template"
+	// CommitMsgModeFixed uses "feat: <prompt>" without calling the tool.
+	CommitMsgModeFixed = "fixed"
+)
+
+// maxCommitSubject is the longest first line a rendered template may have.
+const maxCommitSubject = 72
+
+var commitPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+var commitPlaceholders = []string{"branch", "prompt", "session_id", "date"}
+
+// normalizeCommitMsgMode validates a requested mode. Empty stays empty and is
+// resolved against commit_template when the run commits.
+func normalizeCommitMsgMode(mode string) (string, error) {
+	switch m := strings.TrimSpace(mode); m {
+	case "", CommitMsgModeAI, CommitMsgModeTemplate, CommitMsgModeFixed:
+		return m, nil
+	default:
+		return "", fmt.Errorf("commit_msg_mode must be %s, %s or %s", CommitMsgModeAI, CommitMsgModeTemplate, CommitMsgModeFixed)
+	}
+}
+
+// ValidateCommitTemplate rejects a template naming a placeholder Fog does not
+// fill, so a typo is caught when the setting is saved rather than at commit.
+func ValidateCommitTemplate(template string) error {
+	for _, match := range commitPlaceholder.FindAllStringSubmatch(template, -1) {
+		if !isCommitPlaceholder(match[1]) {
+			return fmt.Errorf("unknown placeholder {%s}; use %s", match[1], "{"+strings.Join(commitPlaceholders, "}, {")+"}")
+		}
+	}
+	return nil
+}
+
+func isCommitPlaceholder(name string) bool {
+	for _, known := range commitPlaceholders {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// CommitTemplate reads commit_template. Unset or blank means no template.
+func (r *Runner) CommitTemplate() string {
+	if r.settings == nil {
+		return ""
+	}
+	raw, found, err := r.settings.GetSetting(SettingCommitTemplate)
+	if err != nil || !found {
+		return ""
+	}
+	return strings.TrimSpace(raw)
+}
+
+// renderCommitTemplate fills template for session and prompt. It fails on an
+// unknown placeholder, an empty result or a first line over 72 characters.
+func renderCommitTemplate(template string, session state.Session, prompt string, now time.Time) (string, error) {
+	if err := ValidateCommitTemplate(template); err != nil {
+		return "", err
+	}
+	promptLine, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	values := map[string]string{
+		"branch":     session.Branch,
+		"prompt":     strings.TrimSpace(promptLine),
+		"session_id": session.ID,
+		"date":       now.UTC().Format("2006-01-02"),
+	}
+	msg := commitPlaceholder.ReplaceAllStringFunc(template, func(token string) string {
+		return values[strings.Trim(token, "{}")]
+	})
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		return "", fmt.Errorf("renders to an empty message")
+	}
+	subject, _, _ := strings.Cut(msg, "\n")
+	if n := len([]rune(subject)); n > maxCommitSubject {
+		return "", fmt.Errorf("first line is %d characters, over %d", n, maxCommitSubject)
+	}
+	return msg, nil
+}
+
+// commitMessageFor picks the message a run commits with. Empty means generate
+// one with the tool. A template that cannot be rendered falls back to the
+// fixed message and says why in a commit event.
+func (r *Runner) commitMessageFor(session state.Session, run state.Run, opts sessionRunOptions, aiOutput string) string {
+	if opts.CommitMsg != "" {
+		return opts.CommitMsg
+	}
+	template := r.CommitTemplate()
+	mode := opts.CommitMsgMode
+	if mode == "" {
+		mode = CommitMsgModeAI
+		if template != "" {
+			mode = CommitMsgModeTemplate
+		}
+	}
+
+	switch mode {
+	case CommitMsgModeFixed:
+		return fallbackCommitMessage(opts.Prompt)
+	case CommitMsgModeTemplate:
+		reason := "commit_template is not set"
+		if template != "" {
+			msg, err := renderCommitTemplate(template, session, opts.Prompt, time.Now())
+			if err == nil {
+				return msg
+			}
+			reason = "commit_template " + err.Error()
+		}
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "commit",
+			Message: reason + "; using the default message",
+		})
+		return fallbackCommitMessage(opts.Prompt)
+	default:
+		return extractCommitMessage(aiOutput)
+	}
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestRenderCommitTemplate(t *testing.T) {
+	session := state.Session{ID: "sess-1", Branch: "fog/otp"}
+	now := time.Date(2026, 3, 4, 23, 0, 0, 0, time.FixedZone("X", -5*3600))
+
+	got, err := renderCommitTemplate("chore({branch}): {prompt}\n\nSession {session_id} on {date}", session, "add OTP login\nwith Redis", now)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if want := "chore(fog/otp): add OTP login\n\nSession sess-1 on 2026-03-05"; got != want {
+		t.Fatalf("render = %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct{ template, prompt, want string }{
+		{"feat: {ticket}", "x", "unknown placeholder {ticket}"},
+		{"{prompt}", strings.Repeat("a", 73), "over 72"},
+		{"{prompt}", "", "empty"},
+	} {
+		if _, err := renderCommitTemplate(tc.template, session, tc.prompt, now); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("render(%q, %q) error = %v, want %q", tc.template, tc.prompt, err, tc.want)
+		}
+	}
+}
+
+func TestCommitMessageForModes(t *testing.T) {
+	session := state.Session{ID: "sess-1", Branch: "fog/otp"}
+	run := state.Run{ID: "run-1"}
+	aiOutput := "done\n<commit_message>fix: from the tool</commit_message>"
+	template := fakeSettings{SettingCommitTemplate: "chore({branch}): {prompt}"}
+
+	cases := []struct {
+		name     string
+		settings fakeSettings
+		opts     sessionRunOptions
+		want     string
+	}{
+		{"explicit message wins", template, sessionRunOptions{Prompt: "add otp", CommitMsg: "fix: mine", CommitMsgMode: CommitMsgModeAI}, "fix: mine"},
+		{"default uses template when set", template, sessionRunOptions{Prompt: "add otp"}, "chore(fog/otp): add otp"},
+		{"default uses ai without template", fakeSettings{}, sessionRunOptions{Prompt: "add otp"}, "fix: from the tool"},
+		{"ai overrides template", template, sessionRunOptions{Prompt: "add otp", CommitMsgMode: CommitMsgModeAI}, "fix: from the tool"},
+		{"fixed", template, sessionRunOptions{Prompt: "add otp", CommitMsgMode: CommitMsgModeFixed}, fallbackCommitMessage("add otp")},
+		{"template without setting falls back", fakeSettings{}, sessionRunOptions{Prompt: "add otp", CommitMsgMode: CommitMsgModeTemplate}, fallbackCommitMessage("add otp")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, tc.settings)
+			if got := r.commitMessageFor(session, run, tc.opts, aiOutput); got != tc.want {
+				t.Fatalf("commitMessageFor = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCommitMessageForRecordsTemplateFallback(t *testing.T) {
+	store := newFakeRunStore()
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, fakeSettings{SettingCommitTemplate: "feat: {ticket}"})
+
+	got := r.commitMessageFor(state.Session{ID: "sess-1"}, state.Run{ID: "run-1"}, sessionRunOptions{Prompt: "add otp"}, "")
+	if got != fallbackCommitMessage("add otp") {
+		t.Fatalf("commitMessageFor = %q, want the fixed message", got)
+	}
+	event, ok := store.eventOfType("commit")
+	if !ok || !strings.Contains(event.Message, "{ticket}") {
+		t.Fatalf("commit event = %+v, %v; want one naming the bad placeholder", event, ok)
+	}
+}
+
+func TestNormalizeCommitMsgModeRejectsUnknown(t *testing.T) {
+	if _, err := normalizeCommitMsgMode("random"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
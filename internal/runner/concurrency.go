@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SettingMaxConcurrentRuns is the settings key for how many runs may be in
+// their AI phase at once. Zero or a negative value disables the limit.
+const SettingMaxConcurrentRuns = "max_concurrent_runs"
+
+// defaultMaxConcurrentRuns applies when max_concurrent_runs is unset or
+// unparsable. A burst of Slack commands would otherwise start one AI process
+// per command.
+const defaultMaxConcurrentRuns = 4
+
+// ErrQueueDrained is the cause a run waiting for a run slot is cancelled
+// with by DrainQueue.
+var ErrQueueDrained = errors.New("queue drained")
+
+// slotWaiter is a run blocked in acquireRunSlot. cancel ends its wait.
+type slotWaiter struct {
+	cancel context.CancelCauseFunc
+}
+
+// RunStats is a snapshot of the runner's load.
+type RunStats struct {
+	// ActiveRuns hold a run slot: they are in or past their AI phase.
+	ActiveRuns int
+	// QueuedRuns are waiting for a slot in the QUEUED state.
+	QueuedRuns        int
+	MaxConcurrentRuns int
+	// QueueDepth counts background runs accepted and not finished, queued
+	// ones included; see QueueDepth.
+	QueueDepth    int
+	MaxQueuedRuns int
+}
+
+// MaxConcurrentRuns reads the configured run slot count, falling back to the
+// default for an unset or malformed value. Zero or less means unlimited.
+func (r *Runner) MaxConcurrentRuns() int {
+	if r.settings == nil {
+		return defaultMaxConcurrentRuns
+	}
+	raw, found, err := r.settings.GetSetting(SettingMaxConcurrentRuns)
+	if err != nil || !found {
+		return defaultMaxConcurrentRuns
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return defaultMaxConcurrentRuns
+	}
+	return n
+}
+
+// Stats reports how many runs hold or await a run slot.
+func (r *Runner) Stats() RunStats {
+	maxConcurrent := r.MaxConcurrentRuns()
+	maxQueued := r.MaxQueuedRuns()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return RunStats{
+		ActiveRuns:        r.running,
+		QueuedRuns:        len(r.waiters),
+		MaxConcurrentRuns: maxConcurrent,
+		QueueDepth:        r.queued,
+		MaxQueuedRuns:     maxQueued,
+	}
+}
+
+// acquireRunSlot blocks until fewer than max_concurrent_runs runs hold a slot,
+// or ctx ends. When no slot is free it calls onWait once before waiting. The
+// returned release must be called when the run ends; calls after the first
+// are no-ops.
+//
+// This is a counter rather than a semaphore.Weighted because the limit is
+// re-read on every attempt: a semaphore's size is fixed when it is made, and
+// max_concurrent_runs can change while the daemon runs. A raised limit takes
+// effect for waiters at the next release.
+//
+// A waiter is registered in r.waiters, and taken out under the same lock that
+// hands it a slot, so DrainQueue never cancels a run that got one.
+func (r *Runner) acquireRunSlot(ctx context.Context, onWait func()) (release func(), err error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var w *slotWaiter
+	for {
+		limit := r.MaxConcurrentRuns()
+
+		r.mu.Lock()
+		if ctx.Err() != nil {
+			delete(r.waiters, w)
+			r.mu.Unlock()
+			return nil, context.Cause(ctx)
+		}
+		if limit <= 0 || r.running < limit {
+			r.running++
+			delete(r.waiters, w)
+			r.mu.Unlock()
+			return r.runSlotRelease(), nil
+		}
+		if r.slotFreed == nil {
+			r.slotFreed = make(chan struct{})
+		}
+		freed := r.slotFreed
+		first := w == nil
+		if first {
+			w = &slotWaiter{cancel: cancel}
+			if r.waiters == nil {
+				r.waiters = make(map[*slotWaiter]struct{})
+			}
+			r.waiters[w] = struct{}{}
+		}
+		r.mu.Unlock()
+
+		if first && onWait != nil {
+			onWait()
+		}
+		select {
+		case <-ctx.Done():
+		case <-freed:
+		}
+	}
+}
+
+// DrainQueue cancels every run still waiting for a run slot and returns how
+// many it cancelled. Runs holding a slot are left alone. A drained run ends
+// like a cancelled one: marked CANCELLED with a cancelled event, its session
+// released, as it unwinds.
+func (r *Runner) DrainQueue() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.waiters)
+	for w := range r.waiters {
+		w.cancel(fmt.Errorf("%w: %w", ErrQueueDrained, context.Canceled))
+	}
+	clear(r.waiters)
+	return n
+}
+
+func (r *Runner) runSlotRelease() func() {
+	released := false
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if released {
+			return
+		}
+		released = true
+		r.running--
+		// Wake every waiter; each re-checks the limit and all but the
+		// winners wait again.
+		if r.slotFreed != nil {
+			close(r.slotFreed)
+			r.slotFreed = nil
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAcquireRunSlotWaitsForRelease(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, fakeSettings{SettingMaxConcurrentRuns: "1"})
+
+	release, err := r.acquireRunSlot(context.Background(), func() { t.Error("the first run should not wait") })
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	waited := make(chan struct{})
+	acquired := make(chan func())
+	go func() {
+		second, err := r.acquireRunSlot(context.Background(), func() { close(waited) })
+		if err != nil {
+			t.Errorf("second acquire: %v", err)
+		}
+		acquired <- second
+	}()
+
+	<-waited
+	if stats := r.Stats(); stats.ActiveRuns != 1 || stats.QueuedRuns != 1 {
+		t.Fatalf("stats while waiting = %+v, want 1 active and 1 queued", stats)
+	}
+	select {
+	case <-acquired:
+		t.Fatal("second run got a slot while the first held the only one")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	release() // a second call must not free another slot
+	second := <-acquired
+	if stats := r.Stats(); stats.ActiveRuns != 1 || stats.QueuedRuns != 0 {
+		t.Fatalf("stats after hand-over = %+v, want 1 active and 0 queued", stats)
+	}
+	second()
+	if stats := r.Stats(); stats.ActiveRuns != 0 {
+		t.Fatalf("stats after release = %+v, want 0 active", stats)
+	}
+}
+
+func TestAcquireRunSlotGivesUpWhenCancelled(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, fakeSettings{SettingMaxConcurrentRuns: "1"})
+	release, err := r.acquireRunSlot(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = r.acquireRunSlot(ctx, cancel)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquire after cancel = %v, want context.Canceled", err)
+	}
+	if stats := r.Stats(); stats.QueuedRuns != 0 {
+		t.Fatalf("a cancelled waiter is still counted: %+v", stats)
+	}
+}
+
+func TestExecuteSessionRunQueuesWhenSlotsAreTaken(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(store, tool, fakeSettings{SettingMaxConcurrentRuns: "1"})
+
+	hold, err := r.acquireRunSlot(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	wt := initTestWorktree(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{Prompt: "add a feature", BaseBranch: "main"})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := store.eventOfType("queued"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("run never recorded a queued event")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if stats := r.Stats(); stats.QueuedRuns != 1 {
+		t.Fatalf("stats while queued = %+v, want 1 queued", stats)
+	}
+	if tool.request().Prompt != "" {
+		t.Fatal("the tool ran while every slot was taken")
+	}
+
+	hold()
+	if err := <-done; err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	store.mu.Lock()
+	states := slices.Clone(store.runStates)
+	store.mu.Unlock()
+	if i := slices.Index(states, "QUEUED"); i < 0 || i > slices.Index(states, "AI_RUNNING") {
+		t.Fatalf("run states = %v, want QUEUED before AI_RUNNING", states)
+	}
+}
+
+func TestDrainQueueCancelsOnlyWaitingRuns(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(store, tool, fakeSettings{SettingMaxConcurrentRuns: "1"})
+
+	hold, err := r.acquireRunSlot(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer hold()
+
+	wt := initTestWorktree(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{Prompt: "add a feature", BaseBranch: "main"})
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Stats().QueuedRuns != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("run never queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := r.DrainQueue(); n != 1 {
+		t.Fatalf("DrainQueue = %d, want 1", n)
+	}
+	if err := <-done; !errors.Is(err, ErrQueueDrained) {
+		t.Fatalf("executeSessionRun = %v, want ErrQueueDrained", err)
+	}
+	if got := store.runs["run-1"].State; got != "CANCELLED" {
+		t.Fatalf("drained run state = %q, want CANCELLED", got)
+	}
+	if !store.busyCleared() {
+		t.Fatal("drained run's session is still busy")
+	}
+	if tool.request().Prompt != "" {
+		t.Fatal("the tool ran for a drained run")
+	}
+	if stats := r.Stats(); stats.ActiveRuns != 1 || stats.QueuedRuns != 0 {
+		t.Fatalf("stats after drain = %+v, want the running slot kept and none queued", stats)
+	}
+	if n := r.DrainQueue(); n != 0 {
+		t.Fatalf("DrainQueue on an empty queue = %d, want 0", n)
+	}
+}
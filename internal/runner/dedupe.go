@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SettingDedupePrompts is the settings key that opts into rejecting a
+// follow-up whose prompt repeats the session's still-running latest run.
+const SettingDedupePrompts = "dedupe_prompts"
+
+// dedupePromptWindow bounds how recent the in-flight run must be for a repeat
+// of its prompt to count as an accidental double-submit.
+const dedupePromptWindow = 30 * time.Second
+
+// ErrDuplicatePrompt is returned by follow-ups rejected as a double-submit.
+var ErrDuplicatePrompt = errors.New("duplicate prompt")
+
+func (r *Runner) dedupePromptsEnabled() bool {
+	if r.settings == nil {
+		return false
+	}
+	raw, found, err := r.settings.GetSetting(SettingDedupePrompts)
+	return err == nil && found && strings.TrimSpace(raw) == "true"
+}
+
+// checkDuplicatePrompt rejects prompt when dedupe_prompts is on and the
+// session's latest run has the same prompt, has not left CREATED, QUEUED or
+// AI_RUNNING, and was created within dedupePromptWindow. It is consulted
+// only once a follow-up has lost the session's busy claim, to tell a
+// double-submit from an ordinary busy session; the claim is what refuses it.
+func (r *Runner) checkDuplicatePrompt(sessionID, prompt string) error {
+	if !r.dedupePromptsEnabled() {
+		return nil
+	}
+	latest, found, err := r.runs.GetLatestRun(sessionID)
+	if err != nil || !found {
+		return err
+	}
+	if latest.State != "CREATED" && latest.State != "QUEUED" && latest.State != "AI_RUNNING" {
+		return nil
+	}
+	if strings.TrimSpace(latest.Prompt) != prompt || time.Since(latest.CreatedAt) > dedupePromptWindow {
+		return nil
+	}
+	return fmt.Errorf("%w: run %s with the same prompt is still running", ErrDuplicatePrompt, latest.ID)
+}
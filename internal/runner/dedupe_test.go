@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func seedInFlightRun(store *fakeRunStore, runState, prompt string, age time.Duration) {
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"].Busy = true
+	store.sessions["session-1"].WorktreePath = "/tmp/wt"
+	store.runs["run-1"].State = runState
+	store.runs["run-1"].Prompt = prompt
+	store.runs["run-1"].CreatedAt = time.Now().Add(-age)
+}
+
+func TestPrepareFollowUpRunRejectsDuplicatePrompt(t *testing.T) {
+	store := newFakeRunStore()
+	seedInFlightRun(store, "AI_RUNNING", "fix the tests", time.Second)
+	r := newTestRunner(store, &fakeTool{}, fakeSettings{SettingDedupePrompts: "true"})
+
+	_, _, _, err := r.prepareFollowUpRun("session-1", "  fix the tests ")
+	if !errors.Is(err, ErrDuplicatePrompt) {
+		t.Fatalf("expected ErrDuplicatePrompt, got %v", err)
+	}
+}
+
+func TestPrepareFollowUpRunDedupeIsOptIn(t *testing.T) {
+	store := newFakeRunStore()
+	seedInFlightRun(store, "AI_RUNNING", "fix the tests", time.Second)
+	r := newTestRunner(store, &fakeTool{}, nil)
+
+	_, _, _, err := r.prepareFollowUpRun("session-1", "fix the tests")
+	if err == nil || !strings.Contains(err.Error(), "is busy") {
+		t.Fatalf("expected busy session error, got %v", err)
+	}
+}
+
+func TestCheckDuplicatePromptIgnoresNonMatches(t *testing.T) {
+	cases := []struct {
+		name   string
+		state  string
+		prompt string
+		age    time.Duration
+	}{
+		{"different prompt", "AI_RUNNING", "something else", time.Second},
+		{"finished run", "COMPLETED", "fix the tests", time.Second},
+		{"outside window", "CREATED", "fix the tests", 2 * dedupePromptWindow},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newFakeRunStore()
+			seedInFlightRun(store, tc.state, tc.prompt, tc.age)
+			r := newTestRunner(store, &fakeTool{}, fakeSettings{SettingDedupePrompts: "true"})
+
+			if err := r.checkDuplicatePrompt("session-1", "fix the tests"); err != nil {
+				t.Fatalf("unexpected rejection: %v", err)
+			}
+		})
+	}
+}
+
+func TestPrepareFollowUpRunAdmitsOneOfRacingDuplicates(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"].WorktreePath = initTestWorktree(t)
+	store.runs["run-1"].State = "COMPLETED"
+	r := newTestRunner(store, &fakeTool{}, fakeSettings{SettingDedupePrompts: "true"})
+	r.repos = fakeRepos{}
+
+	const submits = 8
+	errs := make(chan error, submits)
+	var start sync.WaitGroup
+	start.Add(1)
+	for range submits {
+		go func() {
+			start.Wait()
+			_, _, _, err := r.prepareFollowUpRun("session-1", "fix the tests")
+			errs <- err
+		}()
+	}
+	start.Done()
+
+	admitted := 0
+	for range submits {
+		err := <-errs
+		switch {
+		case err == nil:
+			admitted++
+		case errors.Is(err, ErrDuplicatePrompt), errors.Is(err, ErrSessionBusy):
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if admitted != 1 {
+		t.Fatalf("%d of %d identical follow-ups were admitted, want 1", admitted, submits)
+	}
+}
@@ -20,7 +20,7 @@ import (
 
 // RunStore is the session and run state the runner reads and writes.
 //
-// Narrow by intent: 14 methods against *state.Store's 43. Repos, settings,
+// Narrow by intent: 24 methods against *state.Store's 80. Repos, settings,
 // secrets and task state are reached through their own seams or not at all.
 //
 // This is the runner's only route to session and run persistence — the Runner
@@ -37,10 +37,20 @@ type RunStore interface {
 	AppendRunEvent(event state.RunEvent) error
 	ListRuns(sessionID string) ([]state.Run, error)
 	ListRunEvents(runID string, limit int) ([]state.RunEvent, error)
+	ListRunEventsExcept(runID string, skipTypes ...string) ([]state.RunEvent, error)
 	GetLatestRun(sessionID string) (state.Run, bool, error)
 	UpdateSessionStatus(id, status string) error
 	SetSessionBusy(id string, busy bool) error
+	ClaimSessionBusy(id string) (bool, error)
 	SetSessionPRURL(id, prURL string) error
+	SetSessionBranch(id, branch string) error
+	SetSessionPaused(id string, paused bool) error
+	ArchiveSession(id string) error
+	UnarchiveSession(id string) error
+	MarkSessionWorktreePruned(id string) error
+	DeleteSession(id string) error
+	AddRunUsage(runID string, usage state.RunUsage) error
+	RecoverOrphanedRuns(isLive func(sessionID string) bool) ([]state.Run, error)
 }
 
 // SettingsReader reads user preferences that alter how a run behaves.
@@ -70,7 +80,7 @@ type RepoReader interface {
 // not installed" and "opening the PR failed" indistinguishable.
 type Publisher interface {
 	Available() bool
-	CreatePR(ctx context.Context, workdir, title, body, baseBranch, branch string, draft bool) (string, error)
+	CreatePR(ctx context.Context, workdir, host, title, body, baseBranch, branch string, draft bool) (string, error)
 }
 
 // ghPublisher is the production Publisher, backed by the gh CLI.
@@ -78,8 +88,8 @@ type ghPublisher struct{}
 
 func (ghPublisher) Available() bool { return ghcli.IsGhAvailable() }
 
-func (ghPublisher) CreatePR(ctx context.Context, workdir, title, body, baseBranch, branch string, draft bool) (string, error) {
-	return ghcli.CreatePRWithContext(ctx, workdir, title, body, baseBranch, branch, draft)
+func (ghPublisher) CreatePR(ctx context.Context, workdir, host, title, body, baseBranch, branch string, draft bool) (string, error) {
+	return ghcli.CreatePRWithContext(ctx, workdir, host, title, body, baseBranch, branch, draft)
 }
 
 // ToolFactory resolves a canonical tool name to an AI tool adapter.
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SettingFollowupDirtyPolicy selects what a follow-up does when the session
+// worktree has uncommitted changes, left by an interrupted run or made by hand.
+// Unset keeps the old behaviour: the changes ride along into the next commit.
+const SettingFollowupDirtyPolicy = "followup_dirty_policy"
+
+// Follow-up dirty worktree policies.
+const (
+	// DirtyPolicyFail rejects the follow-up with ErrDirtyWorktree.
+	DirtyPolicyFail = "fail"
+	// DirtyPolicyCommit commits the leftover changes on their own first.
+	DirtyPolicyCommit = "commit"
+	// DirtyPolicyReset discards the leftover changes.
+	DirtyPolicyReset = "reset"
+	// DirtyPolicyStash stashes the changes for the run and restores them
+	// once it ends, so they stay out of its commit.
+	DirtyPolicyStash = "stash"
+	// DirtyPolicyInclude lets the changes into the run's commit, like unset,
+	// but records that it did.
+	DirtyPolicyInclude = "include"
+)
+
+// leftoverCommitMsg is the message of the commit DirtyPolicyCommit makes.
+const leftoverCommitMsg = "chore: keep changes left in the worktree by an earlier run"
+
+// ErrDirtyWorktree is returned by follow-ups refused under DirtyPolicyFail.
+var ErrDirtyWorktree = errors.New("worktree has uncommitted changes")
+
+// ValidDirtyPolicy reports whether policy is a known followup_dirty_policy.
+// Empty is valid and turns the check off.
+func ValidDirtyPolicy(policy string) bool {
+	switch strings.TrimSpace(policy) {
+	case "", DirtyPolicyFail, DirtyPolicyCommit, DirtyPolicyReset, DirtyPolicyStash, DirtyPolicyInclude:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Runner) followupDirtyPolicy() string {
+	if r.settings == nil {
+		return ""
+	}
+	raw, found, err := r.settings.GetSetting(SettingFollowupDirtyPolicy)
+	if err != nil || !found || !ValidDirtyPolicy(raw) {
+		return ""
+	}
+	return strings.TrimSpace(raw)
+}
+
+// dirtyPreflight applies followup_dirty_policy to the session worktree before
+// runID, a follow-up run. It returns the run event type and message describing
+// what it did, both empty when nothing was needed, and whether it stashed
+// changes that restoreStash must put back.
+func (r *Runner) dirtyPreflight(worktreePath, runID string) (eventType, message string, stashed bool, err error) {
+	policy := r.followupDirtyPolicy()
+	if policy == "" {
+		return "", "", false, nil
+	}
+	g := git.New(worktreePath)
+	dirty, err := g.IsDirty()
+	if err != nil {
+		return "", "", false, fmt.Errorf("git status failed: %w", err)
+	}
+	if !dirty {
+		return "", "", false, nil
+	}
+
+	switch policy {
+	case DirtyPolicyCommit:
+		if err := g.StageAll(); err != nil {
+			return "", "", false, fmt.Errorf("git add failed: %w", err)
+		}
+		sha, err := r.commit(r.baseCtx, g, leftoverCommitMsg)
+		if err != nil {
+			return "", "", false, err
+		}
+		return "commit", "Committed leftover worktree changes as " + sha, false, nil
+	case DirtyPolicyReset:
+		if err := g.DiscardChanges(); err != nil {
+			return "", "", false, fmt.Errorf("git reset failed: %w", err)
+		}
+		return "cleanup", "Discarded leftover worktree changes", false, nil
+	case DirtyPolicyStash:
+		if err := g.Stash("fog: worktree changes set aside for run " + runID); err != nil {
+			return "", "", false, fmt.Errorf("git stash failed: %w", err)
+		}
+		return "stash", "Stashed uncommitted worktree changes; they are restored when the run ends", true, nil
+	case DirtyPolicyInclude:
+		return "dirty_worktree", "Uncommitted worktree changes are included in this run's commit", false, nil
+	default:
+		return "", "", false, fmt.Errorf("%w; commit, stash or discard them, or change %s", ErrDirtyWorktree, SettingFollowupDirtyPolicy)
+	}
+}
+
+// restoreStash puts back the changes dirtyPreflight stashed for run. When they
+// do not apply on top of what the run left, git keeps them on the stash and
+// the run event says so.
+func (r *Runner) restoreStash(run state.Run) {
+	event := state.RunEvent{
+		RunID:   run.ID,
+		Type:    "stash",
+		Message: "Restored stashed worktree changes",
+	}
+	if err := git.New(run.WorktreePath).StashPop(); err != nil {
+		event.Type = "error"
+		event.Message = fmt.Sprintf("Could not restore stashed worktree changes; they remain in git stash: %v", err)
+	}
+	_ = r.runs.AppendRunEvent(event)
+}
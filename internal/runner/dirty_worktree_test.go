@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// dirtyFollowUpRunner seeds a finished session whose worktree holds an
+// uncommitted file, with followup_dirty_policy set to policy.
+func dirtyFollowUpRunner(t *testing.T, policy string) (*Runner, *fakeRunStore, string) {
+	t.Helper()
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "leftover.txt", "from an interrupted run")
+
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"].WorktreePath = wt
+	store.runs["run-1"].State = "FAILED"
+	settings := fakeSettings{}
+	if policy != "" {
+		settings[SettingFollowupDirtyPolicy] = policy
+	}
+	r := newTestRunner(store, &fakeTool{}, settings)
+	r.repos = fakeRepos{}
+	return r, store, wt
+}
+
+func TestPrepareFollowUpRunDirtyPolicyFail(t *testing.T) {
+	r, store, _ := dirtyFollowUpRunner(t, DirtyPolicyFail)
+
+	_, _, _, err := r.prepareFollowUpRun("session-1", "carry on")
+	if !errors.Is(err, ErrDirtyWorktree) {
+		t.Fatalf("expected ErrDirtyWorktree, got %v", err)
+	}
+	if store.sessions["session-1"].Busy {
+		t.Fatal("a rejected follow-up must release the session")
+	}
+}
+
+func TestPrepareFollowUpRunDirtyPolicyCommit(t *testing.T) {
+	r, store, wt := dirtyFollowUpRunner(t, DirtyPolicyCommit)
+
+	if _, _, _, err := r.prepareFollowUpRun("session-1", "carry on"); err != nil {
+		t.Fatalf("prepareFollowUpRun: %v", err)
+	}
+	if got := gitOut(t, wt, "status", "--porcelain"); got != "" {
+		t.Fatalf("worktree still dirty: %q", got)
+	}
+	if got := gitOut(t, wt, "log", "-1", "--format=%s"); got != leftoverCommitMsg {
+		t.Fatalf("last commit = %q, want the leftover commit", got)
+	}
+	if ev, ok := store.eventOfType("commit"); !ok || !strings.Contains(ev.Message, "leftover") {
+		t.Fatalf("missing leftover commit event, got %+v", ev)
+	}
+}
+
+func TestPrepareFollowUpRunDirtyPolicyReset(t *testing.T) {
+	r, store, wt := dirtyFollowUpRunner(t, DirtyPolicyReset)
+
+	if _, _, _, err := r.prepareFollowUpRun("session-1", "carry on"); err != nil {
+		t.Fatalf("prepareFollowUpRun: %v", err)
+	}
+	if got := gitOut(t, wt, "status", "--porcelain"); got != "" {
+		t.Fatalf("worktree still dirty: %q", got)
+	}
+	if _, ok := store.eventOfType("cleanup"); !ok {
+		t.Fatal("missing cleanup event")
+	}
+}
+
+func TestFollowUpDirtyPolicyStashKeepsChangesOutOfTheCommit(t *testing.T) {
+	r, store, wt := dirtyFollowUpRunner(t, DirtyPolicyStash)
+	store.sessions["session-1"].Tool = "claude"
+	r.tools = toolFactory(&fakeTool{name: "claude", available: true, block: func(context.Context) error {
+		writeFile(t, wt, "feature.txt", "from the AI")
+		return nil
+	}})
+
+	session, run, opts, err := r.prepareFollowUpRun("session-1", "carry on")
+	if err != nil {
+		t.Fatalf("prepareFollowUpRun: %v", err)
+	}
+	if got := gitOut(t, wt, "status", "--porcelain"); got != "" {
+		t.Fatalf("worktree not stashed before the run: %q", got)
+	}
+	if ev, ok := store.eventOfType("stash"); !ok || !strings.Contains(ev.Message, "Stashed") {
+		t.Fatalf("missing stash event, got %+v", ev)
+	}
+
+	if err := r.executeSessionRun(session, run, opts); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	if got := gitOut(t, wt, "show", "--name-only", "--format=", "HEAD"); got != "feature.txt" {
+		t.Fatalf("run commit touched %q, want only feature.txt", got)
+	}
+	if got := gitOut(t, wt, "status", "--porcelain"); !strings.Contains(got, "leftover.txt") {
+		t.Fatalf("stashed change not restored, status %q", got)
+	}
+	if got := gitOut(t, wt, "stash", "list"); got != "" {
+		t.Fatalf("stash entry left behind: %q", got)
+	}
+}
+
+func TestPrepareFollowUpRunDirtyPolicyIncludeRecordsEvent(t *testing.T) {
+	r, store, wt := dirtyFollowUpRunner(t, DirtyPolicyInclude)
+
+	if _, _, _, err := r.prepareFollowUpRun("session-1", "carry on"); err != nil {
+		t.Fatalf("prepareFollowUpRun: %v", err)
+	}
+	if got := gitOut(t, wt, "status", "--porcelain"); !strings.Contains(got, "leftover.txt") {
+		t.Fatalf("include must leave the worktree alone, status %q", got)
+	}
+	if _, ok := store.eventOfType("dirty_worktree"); !ok {
+		t.Fatal("missing dirty_worktree event")
+	}
+}
+
+func TestPrepareFollowUpRunDirtyCheckIsOptIn(t *testing.T) {
+	r, _, wt := dirtyFollowUpRunner(t, "")
+
+	if _, _, _, err := r.prepareFollowUpRun("session-1", "carry on"); err != nil {
+		t.Fatalf("prepareFollowUpRun: %v", err)
+	}
+	if got := gitOut(t, wt, "status", "--porcelain"); !strings.Contains(got, "leftover.txt") {
+		t.Fatalf("unset policy must leave the worktree alone, status %q", got)
+	}
+}
@@ -3,7 +3,10 @@ package runner
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/darkLord19/foglet/internal/ai"
 	"github.com/darkLord19/foglet/internal/power"
@@ -178,6 +181,8 @@ func (f *fakeRunStore) ListRuns(sessionID string) ([]state.Run, error) {
 			out = append(out, *run)
 		}
 	}
+	// Newest first, like the store.
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
 	return out, nil
 }
 
@@ -196,6 +201,21 @@ func (f *fakeRunStore) ListRunEvents(runID string, limit int) ([]state.RunEvent,
 	return out, nil
 }
 
+func (f *fakeRunStore) ListRunEventsExcept(runID string, skipTypes ...string) ([]state.RunEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("ListRunEventsExcept"); err != nil {
+		return nil, err
+	}
+	var out []state.RunEvent
+	for _, e := range f.events {
+		if e.RunID == runID && !slices.Contains(skipTypes, e.Type) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
 func (f *fakeRunStore) GetLatestRun(sessionID string) (state.Run, bool, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -225,9 +245,30 @@ func (f *fakeRunStore) SetSessionBusy(id string, busy bool) error {
 		return err
 	}
 	f.busyWrites = append(f.busyWrites, busy)
+	if session, ok := f.sessions[id]; ok {
+		session.Busy = busy
+	}
 	return nil
 }
 
+func (f *fakeRunStore) ClaimSessionBusy(id string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("ClaimSessionBusy"); err != nil {
+		return false, err
+	}
+	session, ok := f.sessions[id]
+	if !ok {
+		return false, fmt.Errorf("%w: session %s", state.ErrNotFound, id)
+	}
+	if session.Busy {
+		return false, nil
+	}
+	session.Busy = true
+	f.busyWrites = append(f.busyWrites, true)
+	return true, nil
+}
+
 func (f *fakeRunStore) SetSessionPRURL(id, prURL string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -238,6 +279,119 @@ func (f *fakeRunStore) SetSessionPRURL(id, prURL string) error {
 	return nil
 }
 
+func (f *fakeRunStore) SetSessionBranch(id, branch string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("SetSessionBranch"); err != nil {
+		return err
+	}
+	if s, ok := f.sessions[id]; ok {
+		s.Branch = branch
+	}
+	return nil
+}
+
+func (f *fakeRunStore) MarkSessionWorktreePruned(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("MarkSessionWorktreePruned"); err != nil {
+		return err
+	}
+	if s, ok := f.sessions[id]; ok {
+		now := time.Now().UTC()
+		s.WorktreePrunedAt = &now
+	}
+	return nil
+}
+
+func (f *fakeRunStore) SetSessionPaused(id string, paused bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("SetSessionPaused"); err != nil {
+		return err
+	}
+	if s, ok := f.sessions[id]; ok {
+		s.Paused = paused
+	}
+	return nil
+}
+
+func (f *fakeRunStore) ArchiveSession(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("ArchiveSession"); err != nil {
+		return err
+	}
+	if s, ok := f.sessions[id]; ok {
+		now := time.Now().UTC()
+		s.ArchivedAt = &now
+	}
+	return nil
+}
+
+func (f *fakeRunStore) UnarchiveSession(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("UnarchiveSession"); err != nil {
+		return err
+	}
+	if s, ok := f.sessions[id]; ok {
+		s.ArchivedAt = nil
+	}
+	return nil
+}
+
+func (f *fakeRunStore) DeleteSession(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("DeleteSession"); err != nil {
+		return err
+	}
+	delete(f.sessions, id)
+	return nil
+}
+
+func (f *fakeRunStore) AddRunUsage(runID string, usage state.RunUsage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("AddRunUsage"); err != nil {
+		return err
+	}
+	if run, ok := f.runs[runID]; ok {
+		run.InputTokens += usage.InputTokens
+		run.OutputTokens += usage.OutputTokens
+		run.CostUSD += usage.CostUSD
+	}
+	return nil
+}
+
+func (f *fakeRunStore) RecoverOrphanedRuns(isLive func(sessionID string) bool) ([]state.Run, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("RecoverOrphanedRuns"); err != nil {
+		return nil, err
+	}
+	var failed []state.Run
+	for id, sess := range f.sessions {
+		if !sess.Busy || isLive(id) {
+			continue
+		}
+		sess.Busy = false
+		f.busyWrites = append(f.busyWrites, false)
+		if id != f.latestSession || f.latestRunID == "" {
+			continue
+		}
+		run := f.runs[f.latestRunID]
+		if run.State == "COMPLETED" || run.State == "FAILED" || run.State == "CANCELLED" {
+			continue
+		}
+		run.State = "FAILED"
+		f.events = append(f.events, state.RunEvent{RunID: run.ID, Type: "interrupted"})
+		failed = append(failed, *run)
+	}
+	return failed, nil
+}
+
 // eventTypes returns the ordered list of appended event types.
 func (f *fakeRunStore) eventTypes() []string {
 	f.mu.Lock()
@@ -302,6 +456,7 @@ type fakeTool struct {
 	conversationID string
 	err            error
 	chunks         []string
+	usage          ai.Usage
 
 	// gotRequest captures what the pipeline asked for, so tests can assert the
 	// prompt, model and resumed conversation id crossing the seam.
@@ -316,6 +471,9 @@ type fakeTool struct {
 
 func (f *fakeTool) Name() string      { return f.name }
 func (f *fakeTool) IsAvailable() bool { return f.available }
+func (f *fakeTool) Capabilities() ai.Capabilities {
+	return ai.Capabilities{Streaming: true, Models: true, Resume: true}
+}
 
 func (f *fakeTool) ExecuteStream(ctx context.Context, req ai.ExecuteRequest, onChunk func(string)) (*ai.Result, error) {
 	f.mu.Lock()
@@ -331,17 +489,18 @@ func (f *fakeTool) ExecuteStream(ctx context.Context, req ai.ExecuteRequest, onC
 
 	if f.block != nil {
 		if err := f.block(ctx); err != nil {
-			return &ai.Result{Success: false, Error: err}, err
+			return &ai.Result{Success: false, Error: err, Usage: f.usage}, err
 		}
 	}
 
 	if f.err != nil {
-		return &ai.Result{Success: false, Output: f.output, Error: f.err}, f.err
+		return &ai.Result{Success: false, Output: f.output, Error: f.err, Usage: f.usage}, f.err
 	}
 	return &ai.Result{
 		Success:        true,
 		Output:         f.output,
 		ConversationID: f.conversationID,
+		Usage:          f.usage,
 	}, nil
 }
 
@@ -380,8 +539,7 @@ func newTestRunnerWithPublisher(store *fakeRunStore, tool *fakeTool, settings fa
 	if settings == nil {
 		settings = fakeSettings{}
 	}
-	return &Runner{
-		runs:      store,
+	r := &Runner{
 		settings:  settings,
 		tools:     toolFactory(tool),
 		publisher: pub,
@@ -389,6 +547,8 @@ func newTestRunnerWithPublisher(store *fakeRunStore, tool *fakeTool, settings fa
 		power:     newSilentInhibitor(),
 		active:    map[string]*activeRun{},
 	}
+	r.runs = publishingRunStore{RunStore: store, hub: &r.changes}
+	return r
 }
 
 // fakePublisher stands in for the gh CLI.
@@ -398,19 +558,22 @@ type fakePublisher struct {
 	url       string
 	err       error
 	calls     int
+	gotHost   string
 	gotBase   string
 	gotBranch string
 	gotTitle  string
+	gotBody   string
 	gotDraft  bool
 }
 
 func (f *fakePublisher) Available() bool { return f.available }
 
-func (f *fakePublisher) CreatePR(_ context.Context, _, title, _, baseBranch, branch string, draft bool) (string, error) {
+func (f *fakePublisher) CreatePR(_ context.Context, _, host, title, body, baseBranch, branch string, draft bool) (string, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.calls++
-	f.gotTitle, f.gotBase, f.gotBranch, f.gotDraft = title, baseBranch, branch, draft
+	f.gotHost = host
+	f.gotTitle, f.gotBody, f.gotBase, f.gotBranch, f.gotDraft = title, body, baseBranch, branch, draft
 	if f.err != nil {
 		return "", f.err
 	}
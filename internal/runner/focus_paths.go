@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// normalizeFocusPaths cleans focus paths and rejects any that would point
+// outside the session worktree. Paths are relative to the worktree root and
+// need not exist yet: the tool may be asked to create them. Blank entries and
+// duplicates are dropped.
+func normalizeFocusPaths(paths []string) ([]string, error) {
+	return normalizeWorktreePaths("focus path", paths)
+}
+
+// normalizeWorktreePaths cleans worktree-relative paths, rejecting absolute
+// ones and any that climb out of the worktree. kind names a path in errors.
+func normalizeWorktreePaths(kind string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, raw := range paths {
+		p := strings.TrimSpace(raw)
+		if p == "" {
+			continue
+		}
+		if filepath.IsAbs(p) {
+			return nil, fmt.Errorf("%s %q must be relative to the worktree", kind, raw)
+		}
+		p = filepath.Clean(p)
+		if !filepath.IsLocal(p) {
+			return nil, fmt.Errorf("%s %q is outside the worktree", kind, raw)
+		}
+		p = filepath.ToSlash(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// focusPathsInstructions is appended to the tool prompt when a session names
+// focus paths. None of the supported CLIs can restrict a run to part of the
+// worktree, so the prompt is the only place the paths go.
+func focusPathsInstructions(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nFocus your changes on these paths, relative to the repository root:\n")
+	for _, p := range paths {
+		b.WriteString("- " + p + "\n")
+	}
+	return b.String()
+}
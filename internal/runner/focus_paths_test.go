@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeFocusPaths(t *testing.T) {
+	got, err := normalizeFocusPaths([]string{" internal/api/ ", "", "./docs/API.md", "internal/api", "a/../b"})
+	if err != nil {
+		t.Fatalf("normalizeFocusPaths: %v", err)
+	}
+	want := []string{"internal/api", "docs/API.md", "b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("normalized paths = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeFocusPathsRejectsPathsOutsideWorktree(t *testing.T) {
+	for _, p := range []string{"/etc/passwd", "..", "../sibling", "a/../../b"} {
+		if _, err := normalizeFocusPaths([]string{p}); err == nil {
+			t.Errorf("focus path %q accepted, want error", p)
+		}
+	}
+}
+
+func TestExecuteSessionRunAddsFocusPathsToToolPrompt(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "ok"}
+	r := newTestRunner(store, tool, nil)
+
+	wt := initTestWorktree(t)
+	if err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:     "tidy the handlers",
+		BaseBranch: "main",
+		CommitMsg:  "chore: tidy",
+		FocusPaths: []string{"internal/api", "docs/API.md"},
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+
+	prompt := tool.request().Prompt
+	if !strings.HasPrefix(prompt, "tidy the handlers\n\nFocus your changes on these paths") {
+		t.Fatalf("focus instructions missing from prompt: %q", prompt)
+	}
+	for _, p := range []string{"- internal/api\n", "- docs/API.md\n"} {
+		if !strings.Contains(prompt, p) {
+			t.Errorf("prompt missing %q: %q", p, prompt)
+		}
+	}
+}
+
+func TestResolveLaunchRejectsFocusPathOutsideWorktree(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{})
+
+	req := validRequest()
+	req.FocusPaths = []string{"../other-repo"}
+	if _, err := r.resolveLaunch(req); !errors.Is(err, ErrInvalidLaunch) {
+		t.Fatalf("expected ErrInvalidLaunch, got %v", err)
+	}
+
+	req.FocusPaths = []string{"internal/api/"}
+	opts, err := r.resolveLaunch(req)
+	if err != nil {
+		t.Fatalf("resolveLaunch: %v", err)
+	}
+	if !reflect.DeepEqual(opts.FocusPaths, []string{"internal/api"}) {
+		t.Fatalf("focus paths = %q", opts.FocusPaths)
+	}
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// forkTranscriptMaxBytes bounds the replayed transcript. When a session's
+// history is larger, the oldest turns are dropped first.
+const forkTranscriptMaxBytes = 64 << 10
+
+// buildForkTranscript renders every run of the source session as a
+// prompt/output pair, oldest first, from the ai_output events already stored.
+// No tool is invoked. The kept turns fit in maxBytes; a one-line notice marks
+// anything dropped.
+func (r *Runner) buildForkTranscript(sourceSession state.Session, maxBytes int) (string, error) {
+	if r.runs == nil {
+		return "", errors.New("state store not configured")
+	}
+	runs, err := r.runs.ListRuns(sourceSession.ID)
+	if err != nil {
+		return "", err
+	}
+	slices.SortStableFunc(runs, func(a, b state.Run) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	turns := make([]string, 0, len(runs))
+	for i, run := range runs {
+		events, err := r.runs.ListRunEvents(run.ID, 2000)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "## Turn %d (%s)\n", i+1, run.State)
+		b.WriteString("Prompt:\n" + strings.TrimSpace(run.Prompt) + "\n")
+		for _, event := range events {
+			if event.Type != "ai_output" || strings.TrimSpace(event.Message) == "" {
+				continue
+			}
+			b.WriteString("\nOutput:\n" + strings.TrimSpace(event.Message) + "\n")
+		}
+		if msg := strings.TrimSpace(run.Error); msg != "" {
+			b.WriteString("\nError: " + msg + "\n")
+		}
+		turns = append(turns, b.String())
+	}
+	return fitTranscript(turns, maxBytes), nil
+}
+
+// fitTranscript joins turns, keeping the newest ones that fit in maxBytes.
+// If even the newest turn is too large, its tail is kept.
+func fitTranscript(turns []string, maxBytes int) string {
+	if len(turns) == 0 {
+		return ""
+	}
+	kept := 0
+	size := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		next := len(turns[i]) + 1
+		if size+next > maxBytes {
+			break
+		}
+		size += next
+		kept++
+	}
+
+	if kept == 0 {
+		last := turns[len(turns)-1]
+		return "[earlier output omitted]\n" + strings.ToValidUTF8(last[len(last)-maxBytes:], "")
+	}
+	out := strings.Join(turns[len(turns)-kept:], "\n")
+	if dropped := len(turns) - kept; dropped > 0 {
+		out = fmt.Sprintf("[%d earlier turns omitted]\n\n", dropped) + out
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestBuildForkTranscriptReplaysRunsOldestFirst(t *testing.T) {
+	store := newFakeRunStore()
+	r := newTestRunner(store, &fakeTool{}, nil)
+
+	base := time.Now()
+	first := state.Run{ID: "run-1", SessionID: "session-1", Prompt: "add login", State: "COMPLETED", CreatedAt: base}
+	second := state.Run{ID: "run-2", SessionID: "session-1", Prompt: "add logout", State: "FAILED", Error: "tests failed", CreatedAt: base.Add(time.Minute)}
+	if err := store.CreateRun(second); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if err := store.CreateRun(first); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	_ = store.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: "ai_output", Message: "login done"})
+	_ = store.AppendRunEvent(state.RunEvent{RunID: "run-1", Type: "setup", Message: "ignored"})
+	_ = store.AppendRunEvent(state.RunEvent{RunID: "run-2", Type: "ai_output", Message: "logout half done"})
+
+	got, err := r.buildForkTranscript(state.Session{ID: "session-1"}, forkTranscriptMaxBytes)
+	if err != nil {
+		t.Fatalf("buildForkTranscript: %v", err)
+	}
+	for _, want := range []string{"add login", "login done", "add logout", "logout half done", "Error: tests failed"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("transcript missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "ignored") {
+		t.Fatalf("transcript includes non-output events:\n%s", got)
+	}
+	if strings.Index(got, "add login") > strings.Index(got, "add logout") {
+		t.Fatalf("turns out of order:\n%s", got)
+	}
+}
+
+func TestFitTranscriptDropsOldestTurns(t *testing.T) {
+	turns := []string{strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40)}
+
+	got := fitTranscript(turns, 90)
+	if strings.Contains(got, "aaa") {
+		t.Fatalf("expected oldest turn dropped: %q", got)
+	}
+	if !strings.HasPrefix(got, "[1 earlier turns omitted]") || !strings.Contains(got, "bbb") || !strings.Contains(got, "ccc") {
+		t.Fatalf("unexpected transcript: %q", got)
+	}
+
+	got = fitTranscript(turns, 10)
+	if want := "[earlier output omitted]\n" + strings.Repeat("c", 10); got != want {
+		t.Fatalf("unexpected tail: got %q want %q", got, want)
+	}
+}
+
+func TestGenerateForkSummaryStopsWhenContextCancelled(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{
+		name:      "claude",
+		available: true,
+		block: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	r := newTestRunner(store, tool, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := r.generateForkSummary(ctx, state.Session{ID: "session-1"}, "next step", "claude")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: got %v want %v", err, context.Canceled)
+	}
+}
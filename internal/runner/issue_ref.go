@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxIssueRefLen bounds an issue reference; anything longer is not a ticket
+// id or URL but a paste accident.
+const maxIssueRefLen = 200
+
+// normalizeIssueRef validates a session's issue reference. A bare number is
+// taken as a GitHub issue in the same repo and gains its #. Anything else is
+// kept as given, so owner/repo#123, PROJ-123 and issue URLs all work, but it
+// must be a single token: the reference goes into a commit trailer line.
+func normalizeIssueRef(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", nil
+	}
+	if len(ref) > maxIssueRefLen {
+		return "", fmt.Errorf("issue_ref is longer than %d characters", maxIssueRefLen)
+	}
+	if strings.IndexFunc(ref, func(c rune) bool { return unicode.IsSpace(c) || unicode.IsControl(c) }) >= 0 {
+		return "", fmt.Errorf("issue_ref %q must not contain whitespace", ref)
+	}
+	if strings.Trim(ref, "0123456789") == "" {
+		ref = "#" + ref
+	}
+	return ref, nil
+}
+
+// withIssueTrailer adds a Refs trailer for ref to a commit message, unless
+// the message already carries it.
+func withIssueTrailer(msg, ref string) string {
+	if ref == "" {
+		return msg
+	}
+	trailer := "Refs: " + ref
+	if strings.Contains(msg, trailer) {
+		return msg
+	}
+	return strings.TrimRight(msg, "\n") + "\n\n" + trailer
+}
+
+// issueRefPRLine is the line naming the issue in a PR body. Closes is one of
+// GitHub's closing keywords, so merging the PR closes a same-host issue.
+func issueRefPRLine(ref string, closeIssue bool) string {
+	if ref == "" {
+		return ""
+	}
+	if closeIssue {
+		return "Closes " + ref
+	}
+	return "Refs: " + ref
+}
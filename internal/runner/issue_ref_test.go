@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeIssueRef(t *testing.T) {
+	cases := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "", want: ""},
+		{in: " 123 ", want: "#123"},
+		{in: "#123", want: "#123"},
+		{in: "acme/api#7", want: "acme/api#7"},
+		{in: "PROJ-42", want: "PROJ-42"},
+		{in: "https://example.invalid/issues/9", want: "https://example.invalid/issues/9"},
+		{in: "#1 and #2", wantErr: true},
+		{in: "#1\nSigned-off-by: someone", wantErr: true},
+		{in: strings.Repeat("x", maxIssueRefLen+1), wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := normalizeIssueRef(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("normalizeIssueRef(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("normalizeIssueRef(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWithIssueTrailerDoesNotRepeat(t *testing.T) {
+	msg := withIssueTrailer("feat: x\n", "#7")
+	if msg != "feat: x\n\nRefs: #7" {
+		t.Fatalf("msg = %q", msg)
+	}
+	if again := withIssueTrailer(msg, "#7"); again != msg {
+		t.Fatalf("trailer added twice: %q", again)
+	}
+}
+
+func TestExecuteSessionRunCitesIssueInCommitAndPR(t *testing.T) {
+	for _, closeIssue := range []bool{false, true} {
+		store := newFakeRunStore()
+		store.seed("session-1", "run-1")
+		pub := &fakePublisher{available: true, url: "https://example.invalid/pr/7"}
+		r := newTestRunnerWithPublisher(store, &fakeTool{name: "claude", available: true, output: "ok"}, nil, pub)
+
+		wt := initTestWorktreeWithRemote(t)
+		writeFile(t, wt, "feature.txt", "work")
+
+		session := testSession(wt)
+		session.AutoPR = true
+		session.IssueRef = "#123"
+		session.CloseIssue = closeIssue
+
+		if err := r.executeSessionRun(session, testRun(wt), sessionRunOptions{
+			Prompt:     "add a feature",
+			BaseBranch: "main",
+			CommitMsg:  "feat: x",
+		}); err != nil {
+			t.Fatalf("executeSessionRun: %v", err)
+		}
+
+		if got := gitOutput(t, wt, "log", "-1", "--format=%B"); got != "feat: x\n\nRefs: #123" {
+			t.Errorf("commit message = %q", got)
+		}
+		want := "Refs: #123"
+		if closeIssue {
+			want = "Closes #123"
+		}
+		if !strings.HasSuffix(pub.gotBody, "\n\n"+want) {
+			t.Errorf("close_issue=%v: PR body = %q, want it to end with %q", closeIssue, pub.gotBody, want)
+		}
+	}
+}
@@ -19,17 +19,25 @@ import (
 type LaunchRequest struct {
 	// Entrypoint names the caller in error messages ("api", "task", "cloud").
 	Entrypoint string
+	// Origin is recorded on the session as the interface that created it
+	// ("api", "desktop", "slack", "cloud"). Defaults to Entrypoint.
+	Origin string
 
 	RepoName string
 	Prompt   string
 
-	// Tool falls back to the configured default_tool.
+	// Tool falls back to the repo's default tool, then default_tool. Model
+	// falls back to the repo's default model when the tool is the repo's.
 	Tool  string
 	Model string
 
 	// BranchName is derived from Prompt when empty.
 	BranchName string
-	// BaseBranch falls back to the repo's default branch, then "main".
+	// User fills the {user} token of branch_prefix when BranchName is empty;
+	// it falls back to default_user.
+	User string
+	// BaseBranch falls back to the repo's default branch, then
+	// default_base_branch, then "main".
 	BaseBranch string
 
 	AutoPR      bool
@@ -39,6 +47,10 @@ type LaunchRequest struct {
 	CommitMsg   string
 	PRTitle     string
 
+	// ValidateSuccessCodes lists non-zero ValidateCmd exit codes that count as
+	// a pass. Zero always passes.
+	ValidateSuccessCodes []int
+
 	// RejectProtectedBranch refuses to run against an integration branch.
 	//
 	// Set it for launches that did not originate at this machine. A Slack
@@ -48,6 +60,39 @@ type LaunchRequest struct {
 
 	// Async schedules the run in the background and returns immediately.
 	Async bool
+
+	// Ephemeral starts a scratch session; see StartSessionOptions.Ephemeral.
+	// BranchName is ignored and AutoPR is rejected.
+	Ephemeral bool
+
+	// CommitStrategy is per_run (the default), squash or squash_force.
+	CommitStrategy string
+
+	// CommitMsgMode is ai, template or fixed; see
+	// StartSessionOptions.CommitMsgMode.
+	CommitMsgMode string
+
+	// FocusPaths are worktree-relative paths the run should confine its
+	// changes to; see StartSessionOptions.FocusPaths.
+	FocusPaths []string
+
+	// Attachments and ContextText seed the first run's prompt; see
+	// StartSessionOptions.Attachments.
+	Attachments []string
+	ContextText string
+
+	// SelfReview adds a review pass after the first run's commit; see
+	// StartSessionOptions.SelfReview. Rejected for ephemeral sessions.
+	SelfReview bool
+
+	// IssueRef and CloseIssue link the session to a tracking issue; see
+	// StartSessionOptions.IssueRef. Rejected for ephemeral sessions.
+	IssueRef   string
+	CloseIssue bool
+
+	// Env is extra environment for every run of the session; see
+	// StartSessionOptions.Env.
+	Env map[string]string
 }
 
 // ErrUnknownRepo is returned when the named repo is not managed by Fog.
@@ -80,6 +125,14 @@ func (r *Runner) Launch(req LaunchRequest) (state.Session, state.Run, error) {
 	return r.StartSession(opts)
 }
 
+// PreviewLaunch resolves a LaunchRequest exactly as Launch would and returns
+// the result without creating a worktree, session or run. A generated branch
+// name is the one Launch would pick now; a session created meanwhile on the
+// same name can still push a later launch to the next suffix.
+func (r *Runner) PreviewLaunch(req LaunchRequest) (StartSessionOptions, error) {
+	return r.resolveLaunch(req)
+}
+
 // resolveLaunch turns intent into a fully-resolved StartSessionOptions.
 func (r *Runner) resolveLaunch(req LaunchRequest) (StartSessionOptions, error) {
 	if r.repos == nil || r.settings == nil {
@@ -107,17 +160,72 @@ func (r *Runner) resolveLaunch(req LaunchRequest) (StartSessionOptions, error) {
 	if entrypoint == "" {
 		entrypoint = "api"
 	}
-	tool, err := toolcfg.ResolveTool(req.Tool, r.settings, entrypoint)
+	origin := strings.TrimSpace(req.Origin)
+	if origin == "" {
+		origin = entrypoint
+	}
+	// A checked-in .fog.yaml fills what the request leaves out. Its tool
+	// yields to a repo default tool set through Fog, the user's own choice;
+	// its base branch beats the default branch recorded at import.
+	repoCfg, err := LoadRepoConfig(repo.BaseWorktreePath)
+	if err != nil {
+		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
+	}
+	repoTool := repo.DefaultTool
+	if strings.TrimSpace(repoTool) == "" {
+		repoTool = repoCfg.Tool
+	}
+	tool, err := toolcfg.ResolveRepoTool(req.Tool, repoTool, r.settings, entrypoint)
 	if err != nil {
 		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
 	}
 
-	branch, err := r.ResolveBranch(repo.BaseWorktreePath, req.BranchName, prompt)
+	var branch string
+	if req.Ephemeral {
+		if req.AutoPR {
+			return StartSessionOptions{}, fmt.Errorf("%w: ephemeral sessions cannot open pull requests", ErrInvalidLaunch)
+		}
+		if req.SelfReview {
+			return StartSessionOptions{}, fmt.Errorf("%w: ephemeral sessions cannot self-review, they never commit", ErrInvalidLaunch)
+		}
+		if strings.TrimSpace(req.IssueRef) != "" {
+			return StartSessionOptions{}, fmt.Errorf("%w: ephemeral sessions cannot reference an issue, they never commit", ErrInvalidLaunch)
+		}
+	} else {
+		branch, err = r.ResolveBranch(repo.BaseWorktreePath, req.BranchName, prompt, branchname.PrefixVars{User: req.User, Tool: tool})
+		if err != nil {
+			return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
+		}
+		if req.RejectProtectedBranch && branchname.IsProtected(branch) {
+			return StartSessionOptions{}, fmt.Errorf("%w: protected branch %q is not allowed", ErrInvalidLaunch, branch)
+		}
+	}
+	if err := checkExitCodes(req.ValidateSuccessCodes); err != nil {
+		return StartSessionOptions{}, fmt.Errorf("%w: validate_success_codes: %s", ErrInvalidLaunch, err)
+	}
+	commitStrategy, err := normalizeCommitStrategy(req.CommitStrategy)
 	if err != nil {
 		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
 	}
-	if req.RejectProtectedBranch && branchname.IsProtected(branch) {
-		return StartSessionOptions{}, fmt.Errorf("%w: protected branch %q is not allowed", ErrInvalidLaunch, branch)
+	commitMsgMode, err := normalizeCommitMsgMode(req.CommitMsgMode)
+	if err != nil {
+		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
+	}
+	focusPaths, err := normalizeFocusPaths(req.FocusPaths)
+	if err != nil {
+		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
+	}
+	attachments, err := normalizeAttachments(req.Attachments)
+	if err != nil {
+		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
+	}
+	issueRef, err := normalizeIssueRef(req.IssueRef)
+	if err != nil {
+		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
+	}
+	env, err := normalizeSessionEnv(req.Env)
+	if err != nil {
+		return StartSessionOptions{}, fmt.Errorf("%w: %s", ErrInvalidLaunch, err)
 	}
 
 	return StartSessionOptions{
@@ -125,26 +233,75 @@ func (r *Runner) resolveLaunch(req LaunchRequest) (StartSessionOptions, error) {
 		RepoPath:    repo.BaseWorktreePath,
 		Branch:      branch,
 		Tool:        tool,
-		Model:       strings.TrimSpace(req.Model),
+		Model:       toolcfg.ResolveRepoModel(req.Model, tool, repo.DefaultTool, repo.DefaultModel),
 		Prompt:      prompt,
 		AutoPR:      req.AutoPR,
-		SetupCmd:    strings.TrimSpace(req.SetupCmd),
+		SetupCmd:    firstNonEmpty(req.SetupCmd, repoCfg.SetupCmd),
 		Validate:    req.Validate,
-		ValidateCmd: strings.TrimSpace(req.ValidateCmd),
-		BaseBranch:  resolveBaseBranch(req.BaseBranch, repo.DefaultBranch),
+		ValidateCmd: firstNonEmpty(req.ValidateCmd, repoCfg.ValidateCmd),
+		BaseBranch:  r.resolveBaseBranch(req.BaseBranch, firstNonEmpty(repoCfg.BaseBranch, repo.DefaultBranch)),
 		CommitMsg:   strings.TrimSpace(req.CommitMsg),
 		PRTitle:     strings.TrimSpace(req.PRTitle),
+
+		ValidateSuccessCodes: req.ValidateSuccessCodes,
+		Ephemeral:            req.Ephemeral,
+		Origin:               origin,
+		CommitStrategy:       commitStrategy,
+		CommitMsgMode:        commitMsgMode,
+		FocusPaths:           focusPaths,
+		Attachments:          attachments,
+		ContextText:          strings.TrimSpace(req.ContextText),
+		SelfReview:           req.SelfReview,
+		IssueRef:             issueRef,
+		CloseIssue:           issueRef != "" && req.CloseIssue,
+		Env:                  env,
 	}, nil
 }
 
-// resolveBaseBranch applies the requested base, then the repo's default, then
-// "main". This rule previously appeared verbatim at six call sites.
-func resolveBaseBranch(requested, repoDefault string) string {
+// checkExitCodes rejects values a process cannot exit with.
+func checkExitCodes(codes []int) error {
+	for _, code := range codes {
+		if code < 0 || code > 255 {
+			return fmt.Errorf("exit code %d is out of range 0-255", code)
+		}
+	}
+	return nil
+}
+
+// SettingDefaultBaseBranch is the settings key for the base branch sessions
+// use when neither the request nor the repo names one.
+const SettingDefaultBaseBranch = "default_base_branch"
+
+// ValidateDefaultBaseBranch checks a default_base_branch before it is stored.
+// Empty is valid and means "main".
+func ValidateDefaultBaseBranch(branch string) error {
+	if strings.TrimSpace(branch) == "" {
+		return nil
+	}
+	_, err := branchname.Validate(branch)
+	return err
+}
+
+// ResolveBaseBranch picks the base branch for a session on repo: requested,
+// then the repo's default branch, then default_base_branch, then "main".
+func (r *Runner) ResolveBaseBranch(requested string, repo state.Repo) string {
+	return r.resolveBaseBranch(requested, repo.DefaultBranch)
+}
+
+// resolveBaseBranch is ResolveBaseBranch for callers that have already picked
+// the repo's default, as a launch does when .fog.yaml overrides it. Every path
+// that needs a base branch goes through here so diffs and PR bases agree.
+func (r *Runner) resolveBaseBranch(requested, repoDefault string) string {
 	if branch := strings.TrimSpace(requested); branch != "" {
 		return branch
 	}
 	if branch := strings.TrimSpace(repoDefault); branch != "" {
 		return branch
 	}
+	if r.settings != nil {
+		if branch, found, err := r.settings.GetSetting(SettingDefaultBaseBranch); err == nil && found && strings.TrimSpace(branch) != "" {
+			return strings.TrimSpace(branch)
+		}
+	}
 	return "main"
 }
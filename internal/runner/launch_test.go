@@ -72,24 +72,27 @@ func TestResolveLaunchRejectsUnknownRepo(t *testing.T) {
 
 func TestResolveLaunchBaseBranchFallback(t *testing.T) {
 	// The rule that previously existed verbatim at six call sites: requested,
-	// then the repo default, then "main".
+	// then the repo default, then default_base_branch, then "main".
 	tests := []struct {
-		name        string
-		requested   string
-		repoDefault string
-		want        string
+		name          string
+		requested     string
+		repoDefault   string
+		globalDefault string
+		want          string
 	}{
-		{"explicit request wins", "release/2.0", "develop", "release/2.0"},
-		{"falls back to repo default", "", "develop", "develop"},
-		{"falls back to main", "", "", "main"},
-		{"blank request is not a request", "   ", "develop", "develop"},
-		{"blank repo default falls through", "", "  ", "main"},
+		{"explicit request wins", "release/2.0", "develop", "trunk", "release/2.0"},
+		{"falls back to repo default", "", "develop", "trunk", "develop"},
+		{"falls back to the global setting", "", "", "trunk", "trunk"},
+		{"falls back to main", "", "", "", "main"},
+		{"blank request is not a request", "   ", "develop", "", "develop"},
+		{"blank repo default falls through", "", "  ", "", "main"},
+		{"blank global setting falls through", "", "", "  ", "main"},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			repos := fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: tc.repoDefault}}
-			r := newLaunchRunner(repos, fakeSettings{})
+			r := newLaunchRunner(repos, fakeSettings{SettingDefaultBaseBranch: tc.globalDefault})
 
 			req := validRequest()
 			req.BaseBranch = tc.requested
@@ -101,6 +104,9 @@ func TestResolveLaunchBaseBranchFallback(t *testing.T) {
 			if opts.BaseBranch != tc.want {
 				t.Errorf("BaseBranch = %q, want %q", opts.BaseBranch, tc.want)
 			}
+			if got := r.ResolveBaseBranch(tc.requested, repos["acme/api"]); got != tc.want {
+				t.Errorf("ResolveBaseBranch = %q, want %q", got, tc.want)
+			}
 		})
 	}
 }
@@ -120,6 +126,56 @@ func TestResolveLaunchToolFallsBackToDefault(t *testing.T) {
 	}
 }
 
+func TestResolveLaunchPrefersRepoDefaults(t *testing.T) {
+	repos := launchRepos()
+	repo := repos["acme/api"]
+	repo.DefaultTool = "claude"
+	repo.DefaultModel = "opus"
+	repos["acme/api"] = repo
+	r := newLaunchRunner(repos, fakeSettings{"default_tool": "cursor"})
+
+	req := validRequest()
+	req.Tool = ""
+	opts, err := r.resolveLaunch(req)
+	if err != nil {
+		t.Fatalf("resolveLaunch: %v", err)
+	}
+	if opts.Tool != "claude" || opts.Model != "opus" {
+		t.Errorf("Tool/Model = %q/%q, want the repo defaults claude/opus", opts.Tool, opts.Model)
+	}
+
+	// An explicit other tool does not inherit the repo's model.
+	req.Tool = "cursor"
+	opts, err = r.resolveLaunch(req)
+	if err != nil {
+		t.Fatalf("resolveLaunch: %v", err)
+	}
+	if opts.Tool != "cursor" || opts.Model != "" {
+		t.Errorf("Tool/Model = %q/%q, want cursor with no model", opts.Tool, opts.Model)
+	}
+}
+
+func TestResolveLaunchOriginDefaultsToEntrypoint(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{})
+
+	for _, tc := range []struct{ entrypoint, origin, want string }{
+		{"", "", "api"},
+		{"slack", "", "slack"},
+		{"api", "desktop", "desktop"},
+	} {
+		req := validRequest()
+		req.Entrypoint = tc.entrypoint
+		req.Origin = tc.origin
+		opts, err := r.resolveLaunch(req)
+		if err != nil {
+			t.Fatalf("resolveLaunch: %v", err)
+		}
+		if opts.Origin != tc.want {
+			t.Errorf("entrypoint %q origin %q: Origin = %q, want %q", tc.entrypoint, tc.origin, opts.Origin, tc.want)
+		}
+	}
+}
+
 func TestResolveLaunchExplicitToolWins(t *testing.T) {
 	r := newLaunchRunner(launchRepos(), fakeSettings{"default_tool": "cursor"})
 
@@ -183,6 +239,32 @@ func TestResolveLaunchRejectsInvalidBranch(t *testing.T) {
 	}
 }
 
+func TestResolveLaunchEnforcesBranchNameRegex(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{SettingBranchNameRegex: `^(feat|fix|chore)/`})
+
+	req := validRequest()
+	req.BranchName = "fog/otp-login"
+	_, err := r.resolveLaunch(req)
+	if !errors.Is(err, ErrInvalidLaunch) || !strings.Contains(err.Error(), "^(feat|fix|chore)/") {
+		t.Fatalf("error = %v, want ErrInvalidLaunch naming the pattern", err)
+	}
+
+	req.BranchName = "feat/otp-login"
+	if _, err := r.resolveLaunch(req); err != nil {
+		t.Fatalf("conforming branch rejected: %v", err)
+	}
+}
+
+func TestResolveLaunchIgnoresMalformedBranchNameRegex(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{SettingBranchNameRegex: "(unclosed"})
+
+	req := validRequest()
+	req.BranchName = "fog/otp-login"
+	if _, err := r.resolveLaunch(req); err != nil {
+		t.Fatalf("malformed pattern should be ignored, got %v", err)
+	}
+}
+
 // The divergence that motivated this module: the board's launch path built a
 // 7-field options struct where the sessions API built a 13-field one, so a card
 // could not open a PR or run validation. One resolver means one option set.
@@ -200,10 +282,16 @@ func TestResolveLaunchCarriesEveryRunOption(t *testing.T) {
 		ValidateCmd: "make test",
 		CommitMsg:   "feat: otp",
 		PRTitle:     "Add OTP login",
+		SelfReview:  true,
+		Attachments: []string{"docs/otp.md"},
+		ContextText: "Codes expire after 30s.",
 	})
 	if err != nil {
 		t.Fatalf("resolveLaunch: %v", err)
 	}
+	if len(opts.Attachments) != 1 {
+		t.Errorf("Attachments was dropped during resolution (got %v)", opts.Attachments)
+	}
 
 	for name, got := range map[string]any{
 		"RepoName":    opts.RepoName,
@@ -215,6 +303,8 @@ func TestResolveLaunchCarriesEveryRunOption(t *testing.T) {
 		"ValidateCmd": opts.ValidateCmd,
 		"CommitMsg":   opts.CommitMsg,
 		"PRTitle":     opts.PRTitle,
+		"SelfReview":  opts.SelfReview,
+		"ContextText": opts.ContextText,
 	} {
 		if got == "" || got == false {
 			t.Errorf("%s was dropped during resolution (got %v)", name, got)
@@ -222,6 +312,27 @@ func TestResolveLaunchCarriesEveryRunOption(t *testing.T) {
 	}
 }
 
+func TestResolveLaunchRejectsOutOfRangeSuccessCodes(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{})
+
+	req := validRequest()
+	req.ValidateSuccessCodes = []int{1, 256}
+	if _, err := r.resolveLaunch(req); !errors.Is(err, ErrInvalidLaunch) {
+		t.Fatalf("expected ErrInvalidLaunch, got %v", err)
+	}
+}
+
+func TestResolveLaunchRejectsEphemeralSelfReview(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{})
+
+	req := validRequest()
+	req.Ephemeral = true
+	req.SelfReview = true
+	if _, err := r.resolveLaunch(req); !errors.Is(err, ErrInvalidLaunch) {
+		t.Fatalf("expected ErrInvalidLaunch, got %v", err)
+	}
+}
+
 func TestResolveLaunchTrimsPassThroughFields(t *testing.T) {
 	r := newLaunchRunner(launchRepos(), fakeSettings{})
 
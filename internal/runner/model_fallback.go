@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+// SettingModelFallbacks holds an ordered list of models per AI tool, as a
+// JSON object such as {"claude": ["sonnet", "haiku"]}. When the tool rejects
+// a call's model as unknown, retired or out of quota, the call is made again
+// with the next model in its tool's list. Unset falls back to nothing.
+const SettingModelFallbacks = "model_fallbacks"
+
+// ParseModelFallbacks parses and checks a model_fallbacks value, keying it by
+// each tool's canonical name. Empty parses to nil.
+func ParseModelFallbacks(raw string) (map[string][]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var parsed map[string][]string
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of tool name to model list: %w", err)
+	}
+	return NormalizeModelFallbacks(parsed)
+}
+
+// NormalizeModelFallbacks checks fallbacks as ParseModelFallbacks does and
+// keys them by each tool's canonical name. Models are trimmed and
+// deduplicated; unknown tools and models that are blank, contain whitespace
+// or start with "-" are errors. A tool with no models is dropped.
+func NormalizeModelFallbacks(fallbacks map[string][]string) (map[string][]string, error) {
+	out := make(map[string][]string, len(fallbacks))
+	for name, models := range fallbacks {
+		tool, err := ai.GetTool(name)
+		if err != nil {
+			return nil, err
+		}
+		var chain []string
+		for _, model := range models {
+			model = strings.TrimSpace(model)
+			if model == "" || strings.HasPrefix(model, "-") || strings.ContainsFunc(model, isSpaceOrControl) {
+				return nil, fmt.Errorf("%s: invalid model %q", tool.Name(), model)
+			}
+			if !slices.Contains(chain, model) {
+				chain = append(chain, model)
+			}
+		}
+		if len(chain) > 0 {
+			out[tool.Name()] = chain
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func isSpaceOrControl(r rune) bool {
+	return r <= ' ' || r == 0x7f
+}
+
+// ModelFallbacks reads model_fallbacks. A malformed value is an error, as
+// with tool_args.
+func (r *Runner) ModelFallbacks() (map[string][]string, error) {
+	if r.settings == nil {
+		return nil, nil
+	}
+	raw, found, err := r.settings.GetSetting(SettingModelFallbacks)
+	if err != nil || !found {
+		return nil, err
+	}
+	fallbacks, err := ParseModelFallbacks(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", SettingModelFallbacks, err)
+	}
+	return fallbacks, nil
+}
+
+// nextFallbackModel returns the model to try after model failed, and false
+// when the chain is spent. The chain restarts past model when model is in it,
+// so a session already on a fallback does not go back up the list.
+func nextFallbackModel(chain []string, model string, tried []string) (string, bool) {
+	start := 0
+	if i := slices.Index(chain, model); i >= 0 {
+		start = i + 1
+	}
+	for _, next := range chain[start:] {
+		if !slices.Contains(tried, next) {
+			return next, true
+		}
+	}
+	return "", false
+}
+
+// modelFallbackMessage describes a fallback for the model_fallback event.
+func modelFallbackMessage(err error, from, to string) string {
+	reason := err.Error()
+	var m *ai.ModelUnavailableError
+	if errors.As(err, &m) && m.Reason != "" {
+		reason = m.Reason
+	}
+	if from == "" {
+		from = "the default model"
+	}
+	return fmt.Sprintf("Model %s unavailable (%s); falling back to %s", from, reason, to)
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+func TestParseModelFallbacks(t *testing.T) {
+	got, err := ParseModelFallbacks(`{"claude-code": [" haiku ", "opus", "haiku"], "cursor": []}`)
+	if err != nil {
+		t.Fatalf("ParseModelFallbacks: %v", err)
+	}
+	if want := map[string][]string{"claude": {"haiku", "opus"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("fallbacks = %v, want %v", got, want)
+	}
+	for _, raw := range []string{`["haiku"]`, `{"vim": ["x"]}`, `{"claude": ["--dangerous"]}`, `{"claude": ["a b"]}`, `{"claude": [""]}`} {
+		if _, err := ParseModelFallbacks(raw); err == nil {
+			t.Errorf("ParseModelFallbacks(%s) = nil error", raw)
+		}
+	}
+}
+
+func TestNextFallbackModel(t *testing.T) {
+	chain := []string{"opus", "sonnet", "haiku"}
+	cases := []struct {
+		model string
+		tried []string
+		want  string
+		ok    bool
+	}{
+		{model: "custom", tried: []string{"custom"}, want: "opus", ok: true},
+		{model: "sonnet", tried: []string{"sonnet"}, want: "haiku", ok: true},
+		{model: "haiku", tried: []string{"opus", "haiku"}, ok: false},
+		{model: "", tried: []string{"", "opus"}, want: "sonnet", ok: true},
+	}
+	for _, tc := range cases {
+		got, ok := nextFallbackModel(chain, tc.model, tc.tried)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("nextFallbackModel(%q, %v) = %q, %v; want %q, %v", tc.model, tc.tried, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// modelTool fails every call whose model is in unavailable.
+func modelTool(unavailable ...string) *fakeTool {
+	tool := &fakeTool{name: "claude", available: true, output: "done", usage: ai.Usage{InputTokens: 10}}
+	tool.block = func(context.Context) error {
+		for _, m := range unavailable {
+			if tool.gotRequest.Model == m {
+				return &ai.ModelUnavailableError{Err: errors.New("exit status 1"), Reason: "model not found"}
+			}
+		}
+		return nil
+	}
+	return tool
+}
+
+func TestRunFallsBackToNextModel(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := modelTool("sonnet", "opus")
+	r := newTestRunner(store, tool, fakeSettings{SettingModelFallbacks: `{"claude": ["opus", "haiku"]}`})
+
+	wt := initTestWorktree(t)
+	if err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:     "add a feature",
+		BaseBranch: "main",
+		CommitMsg:  "feat: add a feature",
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	if tool.calls != 3 || tool.request().Model != "haiku" {
+		t.Fatalf("calls = %d, last model %q; want 3 ending on haiku", tool.calls, tool.request().Model)
+	}
+	if got := countEvents(store, "model_fallback"); got != 2 {
+		t.Fatalf("model_fallback events = %d, want 2 (%v)", got, store.eventTypes())
+	}
+	if got := store.runs["run-1"].State; got != "COMPLETED" {
+		t.Errorf("run state = %q, want COMPLETED", got)
+	}
+}
+
+func TestModelFallbackOnlyOnModelErrors(t *testing.T) {
+	settings := fakeSettings{SettingModelFallbacks: `{"claude": ["haiku"]}`}
+
+	tool := &fakeTool{name: "claude", available: true, err: errors.New("tests failed")}
+	r := newTestRunner(newFakeRunStore(), tool, settings)
+	if _, _, _, err := r.runToolWithOptions(context.Background(), "", "claude", t.TempDir(), "fix it", "sonnet", "", nil, nil); err == nil || tool.calls != 1 {
+		t.Fatalf("err = %v after %d calls; want the task failure without a fallback", err, tool.calls)
+	}
+
+	// A spent chain surfaces the last model error.
+	tool = modelTool("sonnet", "haiku")
+	r = newTestRunner(newFakeRunStore(), tool, settings)
+	_, _, usage, err := r.runToolWithOptions(context.Background(), "", "claude", t.TempDir(), "fix it", "sonnet", "", nil, nil)
+	if !ai.IsModelUnavailable(err) || tool.calls != 2 {
+		t.Fatalf("err = %v after %d calls; want a model error after 2", err, tool.calls)
+	}
+	if usage.InputTokens != 20 {
+		t.Fatalf("usage = %+v, want both attempts counted", usage)
+	}
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SettingMaxQueuedRuns is the settings key for the background-run high-water
+// mark. Zero or a negative value disables the limit.
+const SettingMaxQueuedRuns = "max_queued_runs"
+
+// defaultMaxQueuedRuns applies when max_queued_runs is unset or unparsable.
+const defaultMaxQueuedRuns = 32
+
+// ErrQueueFull is returned by the async entry points when the number of
+// background runs has reached the configured high-water mark. Nothing is
+// created when it is returned, so the caller can simply retry later.
+var ErrQueueFull = errors.New("run queue is full")
+
+// QueueDepth reports how many background runs are accepted but not finished.
+func (r *Runner) QueueDepth() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.queued
+}
+
+// MaxQueuedRuns reads the configured high-water mark, falling back to the
+// default for an unset or malformed value. Zero or less means unlimited.
+func (r *Runner) MaxQueuedRuns() int {
+	if r.settings == nil {
+		return defaultMaxQueuedRuns
+	}
+	raw, found, err := r.settings.GetSetting(SettingMaxQueuedRuns)
+	if err != nil || !found {
+		return defaultMaxQueuedRuns
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return defaultMaxQueuedRuns
+	}
+	return n
+}
+
+// reserveQueueSlot claims room for one background run. The returned release
+// must be called when the run finishes or fails to start; calls after the
+// first are no-ops.
+func (r *Runner) reserveQueueSlot() (release func(), err error) {
+	limit := r.MaxQueuedRuns()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if limit > 0 && r.queued >= limit {
+		return nil, fmt.Errorf("%w: %d background runs (limit %d)", ErrQueueFull, r.queued, limit)
+	}
+	r.queued++
+
+	released := false
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if released {
+			return
+		}
+		released = true
+		r.queued--
+	}, nil
+}
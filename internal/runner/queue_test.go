@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReserveQueueSlotRejectsAtHighWaterMark(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), nil, fakeSettings{SettingMaxQueuedRuns: "2"})
+
+	first, err := r.reserveQueueSlot()
+	if err != nil {
+		t.Fatalf("unexpected error reserving first slot: %v", err)
+	}
+	if _, err := r.reserveQueueSlot(); err != nil {
+		t.Fatalf("unexpected error reserving second slot: %v", err)
+	}
+	if _, err := r.reserveQueueSlot(); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("unexpected error at high-water mark: got %v want %v", err, ErrQueueFull)
+	}
+	if got := r.QueueDepth(); got != 2 {
+		t.Fatalf("unexpected queue depth: got %d want 2", got)
+	}
+
+	first()
+	first()
+	if got := r.QueueDepth(); got != 1 {
+		t.Fatalf("unexpected queue depth after release: got %d want 1", got)
+	}
+	if _, err := r.reserveQueueSlot(); err != nil {
+		t.Fatalf("unexpected error after release: %v", err)
+	}
+}
+
+func TestReserveQueueSlotZeroDisablesLimit(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), nil, fakeSettings{SettingMaxQueuedRuns: "0"})
+	for i := 0; i < defaultMaxQueuedRuns+1; i++ {
+		if _, err := r.reserveQueueSlot(); err != nil {
+			t.Fatalf("unexpected error on reservation %d: %v", i, err)
+		}
+	}
+}
+
+func TestMaxQueuedRunsFallsBackToDefault(t *testing.T) {
+	for _, raw := range []string{"", "lots"} {
+		r := newTestRunner(newFakeRunStore(), nil, fakeSettings{SettingMaxQueuedRuns: raw})
+		if got := r.MaxQueuedRuns(); got != defaultMaxQueuedRuns {
+			t.Fatalf("unexpected limit for %q: got %d want %d", raw, got, defaultMaxQueuedRuns)
+		}
+	}
+}
+
+func TestStartSessionAsyncRejectsWhenQueueFull(t *testing.T) {
+	store := newFakeRunStore()
+	r := newTestRunner(store, nil, fakeSettings{SettingMaxQueuedRuns: "1"})
+	if _, err := r.reserveQueueSlot(); err != nil {
+		t.Fatalf("unexpected error reserving slot: %v", err)
+	}
+
+	_, _, err := r.StartSessionAsync(StartSessionOptions{RepoName: "acme/api", Prompt: "do it"})
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("unexpected error: got %v want %v", err, ErrQueueFull)
+	}
+	if len(store.sessions) != 0 {
+		t.Fatalf("expected no session to be created, got %d", len(store.sessions))
+	}
+}
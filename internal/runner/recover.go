@@ -0,0 +1,22 @@
+package runner
+
+import "github.com/darkLord19/foglet/internal/state"
+
+// RecoverOrphanedRuns fails runs left in flight by a process that exited
+// mid-run and releases their sessions; see state.Store.RecoverOrphanedRuns.
+// Sessions this runner is executing are left alone.
+//
+// New does not call this: the fog CLI builds a Runner too, and one started
+// beside a running daemon would see the daemon's runs as orphaned. Only the
+// process that owns execution — the daemon — should call it, once, at start.
+func (r *Runner) RecoverOrphanedRuns() ([]state.Run, error) {
+	if r.runs == nil {
+		return nil, nil
+	}
+	return r.runs.RecoverOrphanedRuns(func(sessionID string) bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		_, live := r.active[sessionID]
+		return live
+	})
+}
@@ -0,0 +1,28 @@
+package runner
+
+import "testing"
+
+func TestRecoverOrphanedRunsSkipsActiveSessions(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"].Busy = true
+	store.runs["run-1"].State = "AI_RUNNING"
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, fakeSettings{})
+
+	r.active["session-1"] = &activeRun{}
+	if failed, err := r.RecoverOrphanedRuns(); err != nil || len(failed) != 0 {
+		t.Fatalf("recover with the session active = %+v (%v), want nothing failed", failed, err)
+	}
+	delete(r.active, "session-1")
+
+	failed, err := r.RecoverOrphanedRuns()
+	if err != nil {
+		t.Fatalf("RecoverOrphanedRuns: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != "run-1" {
+		t.Fatalf("failed = %+v, want run-1", failed)
+	}
+	if _, ok := store.eventOfType("interrupted"); !ok || !store.busyCleared() {
+		t.Fatal("want an interrupted event and the session released")
+	}
+}
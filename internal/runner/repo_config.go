@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfigFile is the file a repo checks in to give Fog per-repo defaults.
+// It is read from the repo's base worktree, so it follows the base branch as
+// last fetched.
+const RepoConfigFile = ".fog.yaml"
+
+// RepoConfig is the contents of RepoConfigFile. Every field is a default that
+// applies only when a request leaves it empty.
+type RepoConfig struct {
+	SetupCmd    string `yaml:"setup_cmd"`
+	ValidateCmd string `yaml:"validate_cmd"`
+	Tool        string `yaml:"tool"`
+	BaseBranch  string `yaml:"base_branch"`
+}
+
+// dangerousShellChars enable shell injection when a command is passed through
+// sh -c.
+var dangerousShellChars = []string{";", "||", "&&", "|", "`", "$(", "${", ">", "<", "\n", "\r"}
+
+// ValidateShellCommand rejects a command containing any of
+// dangerousShellChars. An empty command is valid.
+func ValidateShellCommand(cmd string) error {
+	cmd = strings.TrimSpace(cmd)
+	for _, ch := range dangerousShellChars {
+		if strings.Contains(cmd, ch) {
+			return fmt.Errorf("contains forbidden character sequence %q", ch)
+		}
+	}
+	return nil
+}
+
+// LoadRepoConfig reads RepoConfigFile from repoPath. A missing file, or an
+// empty repoPath, is an empty config. Unknown keys are an error so a typo
+// does not silently drop a setting, and the commands are held to the same
+// rules as commands sent over the API.
+func LoadRepoConfig(repoPath string) (RepoConfig, error) {
+	repoPath = strings.TrimSpace(repoPath)
+	if repoPath == "" {
+		return RepoConfig{}, nil
+	}
+	path := filepath.Join(repoPath, RepoConfigFile)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return RepoConfig{}, nil
+	}
+	if err != nil {
+		return RepoConfig{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var cfg RepoConfig
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return RepoConfig{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	cfg.SetupCmd = strings.TrimSpace(cfg.SetupCmd)
+	cfg.ValidateCmd = strings.TrimSpace(cfg.ValidateCmd)
+	cfg.Tool = strings.TrimSpace(cfg.Tool)
+	cfg.BaseBranch = strings.TrimSpace(cfg.BaseBranch)
+
+	if err := ValidateShellCommand(cfg.SetupCmd); err != nil {
+		return RepoConfig{}, fmt.Errorf("%s: setup_cmd %w", path, err)
+	}
+	if err := ValidateShellCommand(cfg.ValidateCmd); err != nil {
+		return RepoConfig{}, fmt.Errorf("%s: validate_cmd %w", path, err)
+	}
+	return cfg, nil
+}
+
+// applyRepoConfig fills the fields of opts left empty from the repo's
+// RepoConfigFile.
+func applyRepoConfig(opts *StartSessionOptions) error {
+	if opts.SetupCmd != "" && opts.ValidateCmd != "" && opts.Tool != "" && opts.BaseBranch != "" {
+		return nil
+	}
+	cfg, err := LoadRepoConfig(opts.RepoPath)
+	if err != nil {
+		return err
+	}
+	if opts.SetupCmd == "" {
+		opts.SetupCmd = cfg.SetupCmd
+	}
+	if opts.ValidateCmd == "" {
+		opts.ValidateCmd = cfg.ValidateCmd
+	}
+	if opts.Tool == "" {
+		opts.Tool = cfg.Tool
+	}
+	if opts.BaseBranch == "" {
+		opts.BaseBranch = cfg.BaseBranch
+	}
+	return nil
+}
+
+// firstNonEmpty returns the first of values that is not blank, trimmed.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRepoConfig(t *testing.T, dir, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, RepoConfigFile), []byte(body), 0o644); err != nil {
+		t.Fatalf("write %s: %v", RepoConfigFile, err)
+	}
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	dir := t.TempDir()
+	if cfg, err := LoadRepoConfig(dir); err != nil || cfg != (RepoConfig{}) {
+		t.Fatalf("missing file = %+v, %v; want an empty config", cfg, err)
+	}
+
+	writeRepoConfig(t, dir, "setup_cmd: npm ci\nvalidate_cmd: ' npm test '\ntool: codex\nbase_branch: develop\n")
+	cfg, err := LoadRepoConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadRepoConfig: %v", err)
+	}
+	want := RepoConfig{SetupCmd: "npm ci", ValidateCmd: "npm test", Tool: "codex", BaseBranch: "develop"}
+	if cfg != want {
+		t.Fatalf("config = %+v, want %+v", cfg, want)
+	}
+
+	writeRepoConfig(t, dir, "")
+	if cfg, err := LoadRepoConfig(dir); err != nil || cfg != (RepoConfig{}) {
+		t.Fatalf("empty file = %+v, %v; want an empty config", cfg, err)
+	}
+
+	for body, wantErr := range map[string]string{
+		"setup_commd: npm ci\n":            "setup_commd",
+		"setup_cmd: npm ci && npm build\n": "setup_cmd contains forbidden",
+		"validate_cmd: go test | tee\n":    "validate_cmd contains forbidden",
+		"tool: [claude\n":                  "parse",
+	} {
+		writeRepoConfig(t, dir, body)
+		if _, err := LoadRepoConfig(dir); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("LoadRepoConfig(%q) = %v, want an error mentioning %q", body, err, wantErr)
+		}
+	}
+}
+
+func TestResolveLaunchAppliesRepoConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoConfig(t, dir, "setup_cmd: make deps\nvalidate_cmd: make test\ntool: codex\nbase_branch: trunk\n")
+	repos := fakeRepos{"acme/api": {Name: "acme/api", BaseWorktreePath: dir, DefaultBranch: "develop"}}
+	r := newLaunchRunner(repos, fakeSettings{"default_tool": "claude"})
+
+	req := validRequest()
+	req.Tool = ""
+	opts, err := r.resolveLaunch(req)
+	if err != nil {
+		t.Fatalf("resolveLaunch: %v", err)
+	}
+	if opts.Tool != "codex" || opts.BaseBranch != "trunk" || opts.SetupCmd != "make deps" || opts.ValidateCmd != "make test" {
+		t.Fatalf("opts = tool %q base %q setup %q validate %q, want the .fog.yaml values", opts.Tool, opts.BaseBranch, opts.SetupCmd, opts.ValidateCmd)
+	}
+
+	req = validRequest()
+	req.BaseBranch = "main"
+	req.SetupCmd = "npm ci"
+	opts, err = r.resolveLaunch(req)
+	if err != nil {
+		t.Fatalf("resolveLaunch: %v", err)
+	}
+	if opts.Tool != "claude" || opts.BaseBranch != "main" || opts.SetupCmd != "npm ci" || opts.ValidateCmd != "make test" {
+		t.Fatalf("opts = tool %q base %q setup %q validate %q, want request values to win", opts.Tool, opts.BaseBranch, opts.SetupCmd, opts.ValidateCmd)
+	}
+
+	repo := repos["acme/api"]
+	repo.DefaultTool = "cursor"
+	repos["acme/api"] = repo
+	req = validRequest()
+	req.Tool = ""
+	if opts, err = r.resolveLaunch(req); err != nil || opts.Tool != "cursor" {
+		t.Fatalf("tool = %q (%v), want the repo default tool set through Fog", opts.Tool, err)
+	}
+
+	writeRepoConfig(t, dir, "setup_cmd: make; rm -rf /\n")
+	if _, err := r.resolveLaunch(validRequest()); err == nil || !strings.Contains(err.Error(), "setup_cmd") {
+		t.Fatalf("resolveLaunch with a bad setup_cmd = %v, want an error", err)
+	}
+}
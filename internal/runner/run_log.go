@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SettingRunLogFiles is the settings key that opts into writing each run's
+// full AI streaming output to a log file, alongside the truncated ai_stream
+// events kept in the database.
+const SettingRunLogFiles = "run_log_files"
+
+// SetRunLogDir sets the directory run log files are written to, one
+// <run_id>.log per run. The daemon passes $FOG_HOME/logs; with no directory
+// set, no log files are written even when run_log_files is on.
+func (r *Runner) SetRunLogDir(dir string) {
+	r.runLogDir = strings.TrimSpace(dir)
+}
+
+// RunLogPath returns where runID's log file lives, whether or not it exists.
+// It is empty when no log directory is set.
+func (r *Runner) RunLogPath(runID string) string {
+	runID = strings.TrimSpace(runID)
+	// Run IDs are UUIDs; anything with a separator is not one of ours.
+	if r.runLogDir == "" || runID == "" || strings.ContainsAny(runID, `/\`) || runID == "." || runID == ".." {
+		return ""
+	}
+	return filepath.Join(r.runLogDir, runID+".log")
+}
+
+func (r *Runner) runLogFilesEnabled() bool {
+	if r.settings == nil {
+		return false
+	}
+	raw, found, err := r.settings.GetSetting(SettingRunLogFiles)
+	return err == nil && found && strings.TrimSpace(raw) == "true"
+}
+
+// openRunLog opens runID's log file for appending, or returns nil when run
+// log files are off. Failing to open it only loses the file, not the run.
+func (r *Runner) openRunLog(runID string) *os.File {
+	path := r.RunLogPath(runID)
+	if path == "" || !r.runLogFilesEnabled() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		slog.Warn("create run log dir failed", "run_id", runID, "err", err)
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		slog.Warn("open run log failed", "run_id", runID, "err", err)
+		return nil
+	}
+	return f
+}
+
+// newRunStreamWriter returns the stream writer for one AI call of runID,
+// teeing to the run's log file when run log files are on. Callers Close it
+// once the call returns.
+func (r *Runner) newRunStreamWriter(runID string) *runStreamWriter {
+	w := newRunStreamWriter(r.runs, runID)
+	if f := r.openRunLog(runID); f != nil {
+		w.log = f
+	}
+	return w
+}
+
+// RemoveRunLog deletes runID's log file. A missing file is not an error.
+func (r *Runner) RemoveRunLog(runID string) error {
+	path := r.RunLogPath(runID)
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove run log: %w", err)
+	}
+	return nil
+}
+
+// RemoveSessionRunLogs deletes the log files of every run in sessionID, ahead
+// of deleting the session.
+func (r *Runner) RemoveSessionRunLogs(sessionID string) error {
+	if r.runs == nil || r.runLogDir == "" {
+		return nil
+	}
+	runs, err := r.runs.ListRuns(strings.TrimSpace(sessionID))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, run := range runs {
+		errs = append(errs, r.RemoveRunLog(run.ID))
+	}
+	return errors.Join(errs...)
+}
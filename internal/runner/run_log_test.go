@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunStreamWriterTeesFullOutputToRunLog(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, &fakeTool{}, fakeSettings{SettingRunLogFiles: "true"})
+	dir := filepath.Join(t.TempDir(), "logs")
+	r.SetRunLogDir(dir)
+
+	chunk := strings.Repeat("x", 9000)
+	for range 2 {
+		w := r.newRunStreamWriter("run-1")
+		w.Append(chunk)
+		w.Close()
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "run-1.log"))
+	if err != nil {
+		t.Fatalf("read run log: %v", err)
+	}
+	if len(data) != 2*len(chunk) {
+		t.Fatalf("run log holds %d bytes, want both calls' %d", len(data), 2*len(chunk))
+	}
+	event, ok := store.eventOfType("ai_stream")
+	if !ok || len(event.Data) >= len(chunk) {
+		t.Fatalf("ai_stream event should stay truncated, got %d bytes", len(event.Data))
+	}
+
+	if err := r.RemoveSessionRunLogs("session-1"); err != nil {
+		t.Fatalf("remove run logs: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-1.log")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("run log left after removal: %v", err)
+	}
+}
+
+func TestRunLogFilesAreOptIn(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, &fakeTool{}, nil)
+	dir := t.TempDir()
+	r.SetRunLogDir(dir)
+
+	w := r.newRunStreamWriter("run-1")
+	w.Append("hello")
+	w.Close()
+	if _, err := os.Stat(filepath.Join(dir, "run-1.log")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("run log written with run_log_files off: %v", err)
+	}
+	if got := r.RunLogPath("../run-1"); got != "" {
+		t.Fatalf("RunLogPath accepted a path: %q", got)
+	}
+}
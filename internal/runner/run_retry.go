@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/ai"
+	"github.com/darkLord19/foglet/internal/git"
+)
+
+// SettingRunRetryCount is how many times a run's AI phase is retried after a
+// transient tool failure (provider overload, rate limiting, network errors).
+// Unset or 0 turns retries off.
+const SettingRunRetryCount = "run_retry_count"
+
+// MaxRunRetryCount caps run_retry_count, so a provider outage fails runs in
+// minutes rather than hours.
+const MaxRunRetryCount = 5
+
+// defaultRunRetryBackoff is the wait before the first retry; each further
+// retry waits twice as long as the one before.
+const defaultRunRetryBackoff = 15 * time.Second
+
+// RunRetryCount reads run_retry_count, clamped to MaxRunRetryCount. An unset,
+// malformed or negative value means no retries.
+func (r *Runner) RunRetryCount() int {
+	if r.settings == nil {
+		return 0
+	}
+	raw, found, err := r.settings.GetSetting(SettingRunRetryCount)
+	if err != nil || !found {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return min(n, MaxRunRetryCount)
+}
+
+// shouldRetryAI reports whether an AI failure on the given attempt (0 for the
+// first) may be retried: it must be classified transient by the ai package and
+// the retry budget not yet spent.
+func (r *Runner) shouldRetryAI(err error, attempt int) bool {
+	return err != nil && ai.IsTransient(err) && attempt < r.RunRetryCount()
+}
+
+// waitRunRetry sleeps before retry number n (1-based), returning early with
+// the context error if the run is cancelled meanwhile.
+func (r *Runner) waitRunRetry(ctx context.Context, n int) error {
+	d := r.retryBackoff << (n - 1)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// runStartPoint snapshots the worktree as the AI phase starts, for
+// resetForRetry to put it back to.
+func runStartPoint(worktreePath string) (git.Snapshot, error) {
+	return git.New(worktreePath).TakeSnapshot()
+}
+
+// resetForRetry puts the worktree back to start, discarding whatever a failed
+// attempt edited or committed, so a retry starts from the same commit and
+// files as the first attempt did. Leftovers a follow-up let into the run are
+// part of start and survive.
+func resetForRetry(worktreePath string, start git.Snapshot, startErr error) error {
+	if startErr != nil {
+		return fmt.Errorf("reset worktree for retry: the run's starting point is unknown: %w", startErr)
+	}
+	if err := git.New(worktreePath).RestoreSnapshot(start); err != nil {
+		return fmt.Errorf("reset worktree for retry: %w", err)
+	}
+	return nil
+}
+
+// runRetryMessage describes a retry for the run_retry event.
+func runRetryMessage(err error, n, limit int) string {
+	reason := err.Error()
+	var t *ai.TransientError
+	if errors.As(err, &t) && t.Reason != "" {
+		reason = t.Reason
+	}
+	return fmt.Sprintf("AI tool failed transiently (%s); retrying %d/%d", reason, n, limit)
+}
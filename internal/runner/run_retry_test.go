@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+// flakyTool fails its first failures calls with err, then succeeds.
+func flakyTool(failures int, err error) *fakeTool {
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	tool.block = func(context.Context) error {
+		// The runner calls the tool sequentially, so calls is stable here.
+		if tool.calls <= failures {
+			return err
+		}
+		return nil
+	}
+	return tool
+}
+
+func retryRun(t *testing.T, tool *fakeTool, retries string) (*fakeRunStore, error) {
+	t.Helper()
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, tool, fakeSettings{SettingRunRetryCount: retries})
+
+	wt := initTestWorktree(t)
+	err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:     "add a feature",
+		BaseBranch: "main",
+		CommitMsg:  "feat: add a feature",
+	})
+	return store, err
+}
+
+func countEvents(store *fakeRunStore, typ string) int {
+	n := 0
+	for _, got := range store.eventTypes() {
+		if got == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRunRetriesTransientAIFailure(t *testing.T) {
+	transient := &ai.TransientError{Err: errors.New("exit status 1"), Reason: "overloaded"}
+	tool := flakyTool(1, transient)
+	store, err := retryRun(t, tool, "2")
+	if err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	if tool.calls != 2 {
+		t.Fatalf("tool calls = %d, want 2", tool.calls)
+	}
+	if got := countEvents(store, "run_retry"); got != 1 {
+		t.Fatalf("run_retry events = %d, want 1 (%v)", got, store.eventTypes())
+	}
+	if got := store.runs["run-1"].State; got != "COMPLETED" {
+		t.Errorf("run state = %q, want COMPLETED", got)
+	}
+}
+
+func TestRunRetryGivesUpAfterLimit(t *testing.T) {
+	transient := &ai.TransientError{Err: errors.New("exit status 1"), Reason: "rate limit"}
+	tool := flakyTool(10, transient)
+	store, err := retryRun(t, tool, "1")
+	if !ai.IsTransient(err) {
+		t.Fatalf("expected the transient error once retries are spent, got %v", err)
+	}
+	if tool.calls != 2 {
+		t.Fatalf("tool calls = %d, want 2", tool.calls)
+	}
+	if got := store.runs["run-1"].State; got != "FAILED" {
+		t.Errorf("run state = %q, want FAILED", got)
+	}
+}
+
+func TestRunRetrySkipsDeterministicFailure(t *testing.T) {
+	tool := flakyTool(10, errors.New("I can't help with that"))
+	store, err := retryRun(t, tool, "3")
+	if err == nil {
+		t.Fatal("expected the AI failure")
+	}
+	if tool.calls != 1 {
+		t.Fatalf("tool calls = %d, want 1", tool.calls)
+	}
+	if slices.Contains(store.eventTypes(), "run_retry") {
+		t.Fatal("deterministic failures must not be retried")
+	}
+}
+
+func TestRunRetryCountIsCapped(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), &fakeTool{}, fakeSettings{SettingRunRetryCount: "50"})
+	if got := r.RunRetryCount(); got != MaxRunRetryCount {
+		t.Fatalf("runRetryCount = %d, want %d", got, MaxRunRetryCount)
+	}
+}
+
+func TestRunRetryStartsFromTheRunsStartingPoint(t *testing.T) {
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "spec.md", "the spec")
+	start := gitOutput(t, wt, "rev-parse", "HEAD")
+
+	type attempt struct {
+		conversationID, prompt, head string
+		stray, spec                  bool
+	}
+	var attempts []attempt
+	transient := &ai.TransientError{Err: errors.New("exit status 1"), Reason: "overloaded"}
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	tool.block = func(context.Context) error {
+		head, _ := exec.Command("git", "-C", wt, "rev-parse", "HEAD").Output()
+		_, strayErr := os.Stat(filepath.Join(wt, "stray.txt"))
+		_, specErr := os.Stat(filepath.Join(wt, "spec.md"))
+		attempts = append(attempts, attempt{
+			conversationID: tool.gotRequest.ConversationID,
+			prompt:         tool.gotRequest.Prompt,
+			head:           strings.TrimSpace(string(head)),
+			stray:          strayErr == nil,
+			spec:           specErr == nil,
+		})
+		if tool.calls > 1 {
+			return nil
+		}
+		// The failed attempt leaves a commit and an untracked file behind.
+		_ = os.WriteFile(filepath.Join(wt, "half.txt"), []byte("half"), 0o644)
+		_ = exec.Command("git", "-C", wt, "add", "half.txt").Run()
+		_ = exec.Command("git", "-C", wt, "commit", "-m", "half done").Run()
+		_ = os.WriteFile(filepath.Join(wt, "stray.txt"), []byte("stray"), 0o644)
+		return transient
+	}
+
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, tool, fakeSettings{SettingRunRetryCount: "1"})
+	err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:            "add a feature",
+		BaseBranch:        "main",
+		CommitMsg:         "feat: add a feature",
+		Attachments:       []string{"spec.md"},
+		ContextText:       "pasted context",
+		ConversationID:    "conv-before",
+		HasConversationID: true,
+	})
+	if err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(attempts))
+	}
+	first, retry := attempts[0], attempts[1]
+	if first.conversationID != "conv-before" || retry.conversationID != "" {
+		t.Errorf("conversations = %q then %q, want conv-before then a fresh one", first.conversationID, retry.conversationID)
+	}
+	if retry.head != start || retry.stray || !retry.spec {
+		t.Errorf("retry started at %s (stray file %v, spec.md %v), want the worktree as the run found it at %s",
+			retry.head, retry.stray, retry.spec, start)
+	}
+	if retry.prompt != first.prompt || !strings.Contains(retry.prompt, "the spec") || !strings.Contains(retry.prompt, "pasted context") {
+		t.Errorf("retry prompt lost the attachments or context:\n%s", retry.prompt)
+	}
+	if got := gitOutput(t, wt, "show", "--name-only", "--format=", "HEAD"); got != "spec.md" {
+		t.Errorf("run committed %q, want only spec.md", got)
+	}
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingRunTimeoutSeconds bounds how long a run may take from setup to PR,
+// not counting time spent queued for a run slot, for runs that do not set
+// their own timeout. Unset or 0 means no limit.
+const SettingRunTimeoutSeconds = "run_timeout_seconds"
+
+// RunTimeout reads run_timeout_seconds. An unset, malformed or negative value
+// means no limit and returns 0.
+func (r *Runner) RunTimeout() time.Duration {
+	if r.settings == nil {
+		return 0
+	}
+	raw, found, err := r.settings.GetSetting(SettingRunTimeoutSeconds)
+	if err != nil || !found {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// runTimeout is the deadline for one run: its own Timeout when set, otherwise
+// run_timeout_seconds.
+func (r *Runner) runTimeout(opts sessionRunOptions) time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return r.RunTimeout()
+}
+
+// withRunClock bounds parent by what is left of a run's timeout. A run with
+// no timeout is bounded only by parent; one whose budget is spent gets a
+// context already past its deadline.
+func withRunClock(parent context.Context, timeout, budget time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, budget)
+}
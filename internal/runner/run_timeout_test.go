@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTimeoutSetting(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":     0,
+		"0":    0,
+		"-5":   0,
+		"soon": 0,
+		"90":   90 * time.Second,
+	}
+	for raw, want := range cases {
+		settings := fakeSettings{}
+		if raw != "" {
+			settings[SettingRunTimeoutSeconds] = raw
+		}
+		r := newTestRunner(newFakeRunStore(), nil, settings)
+		if got := r.RunTimeout(); got != want {
+			t.Errorf("RunTimeout(%q) = %s, want %s", raw, got, want)
+		}
+	}
+}
+
+func TestExecuteSessionRunTimesOutHungTool(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{
+		name:      "claude",
+		available: true,
+		block: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	// The per-run timeout wins over the setting.
+	r := newTestRunner(store, tool, fakeSettings{SettingRunTimeoutSeconds: "3600"})
+
+	wt := initTestWorktree(t)
+	err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:     "add a feature",
+		BaseBranch: "main",
+		Timeout:    50 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if got := lastString(store.runStates); got != "FAILED" {
+		t.Errorf("terminal run state = %q, want FAILED", got)
+	}
+	ev, found := store.eventOfType("timeout")
+	if !found {
+		t.Fatalf("no timeout event, got %v", store.eventTypes())
+	}
+	if ev.Message != "ai: timed out after 50ms" {
+		t.Errorf("timeout message = %q", ev.Message)
+	}
+	if _, found := store.eventOfType("cancelled"); found {
+		t.Error("timeout was recorded as a cancellation")
+	}
+	if !store.busyCleared() {
+		t.Error("session left busy after timeout")
+	}
+}
+
+func TestExecuteSessionRunTimesOutSetupFromSetting(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(store, tool, fakeSettings{SettingRunTimeoutSeconds: "1"})
+
+	wt := initTestWorktree(t)
+	start := time.Now()
+	err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:     "add a feature",
+		BaseBranch: "main",
+		SetupCmd:   "sleep 30",
+	})
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Fatalf("setup ran for %s despite the timeout", elapsed)
+	}
+	ev, found := store.eventOfType("timeout")
+	if !found || ev.Message != "setup: timed out after 1s" {
+		t.Fatalf("timeout event = %+v (found %v)", ev, found)
+	}
+	if tool.calls != 0 {
+		t.Errorf("tool ran %d times after setup timed out", tool.calls)
+	}
+	if !store.busyCleared() {
+		t.Error("session left busy after timeout")
+	}
+}
+
+func TestExecuteSessionRunTimeoutExcludesQueuedTime(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(store, tool, fakeSettings{SettingMaxConcurrentRuns: "1"})
+
+	hold, err := r.acquireRunSlot(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	wt := initTestWorktree(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+			Prompt:     "add a feature",
+			BaseBranch: "main",
+			Timeout:    time.Second,
+		})
+	}()
+
+	// Queue for longer than the whole timeout before a slot frees up.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := store.eventOfType("queued"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("run never recorded a queued event")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	hold()
+
+	if err := <-done; err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	if _, found := store.eventOfType("timeout"); found {
+		t.Fatal("time spent queued counted against the run timeout")
+	}
+	if tool.request().Prompt == "" {
+		t.Fatal("the tool never ran")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/darkLord19/foglet/internal/ai"
 	"github.com/darkLord19/foglet/internal/config"
@@ -25,6 +26,22 @@ type Runner struct {
 	power     *power.Inhibitor
 	mu        sync.Mutex
 	active    map[string]*activeRun
+	// queued counts background runs accepted by the async entry points and
+	// not yet finished. Guarded by mu.
+	queued int
+	// running counts runs holding a run slot and waiters holds those blocked
+	// on one; slotFreed is closed when a slot is released. Guarded by mu.
+	running   int
+	waiters   map[*slotWaiter]struct{}
+	slotFreed chan struct{}
+	// retryBackoff is the wait before the first run_retry_count retry. Zero
+	// retries immediately.
+	retryBackoff time.Duration
+	// runLogDir holds run log files; see SetRunLogDir.
+	runLogDir string
+	// changes publishes the session writes made through runs; see
+	// SubscribeSessionChanges.
+	changes sessionHub
 }
 
 // New creates a new runner. The state store st is optional (may be nil).
@@ -38,11 +55,13 @@ func New(st *state.Store) *Runner {
 		baseCtx:   context.Background(),
 		power:     power.New(),
 		active:    make(map[string]*activeRun),
+
+		retryBackoff: defaultRunRetryBackoff,
 	}
 	// Assigned only when non-nil: a nil *state.Store stored in an interface is
 	// itself non-nil, which would turn every nil-store guard into a panic.
 	if st != nil {
-		r.runs = st
+		r.runs = publishingRunStore{RunStore: st, hub: &r.changes}
 		r.repos = st
 		r.settings = st
 	}
@@ -67,7 +86,7 @@ func (r *Runner) keepAwakeEnabled() bool {
 	return val == "true"
 }
 
-func (r *Runner) createWorktreePathWithName(repoPath, name, branch, baseBranch string) (string, error) {
+func (r *Runner) createWorktreePathWithName(repoPath, worktreeDir, name, branch, baseBranch string) (string, error) {
 	name = strings.TrimSpace(name)
 	branch = strings.TrimSpace(branch)
 	baseBranch = strings.TrimSpace(baseBranch)
@@ -83,22 +102,11 @@ func (r *Runner) createWorktreePathWithName(repoPath, name, branch, baseBranch s
 		return "", fmt.Errorf("worktree branch is required")
 	}
 
-	// Load wtx config to get worktree directory preference
-	cfg, err := config.Load()
-	if err != nil {
-		return "", fmt.Errorf("load wtx config: %w", err)
-	}
-
-	root, err := g.GetRepoRoot()
+	worktreePath, err := worktreePathFor(g, worktreeDir, name)
 	if err != nil {
-		return "", fmt.Errorf("get repo root: %w", err)
+		return "", err
 	}
 
-	// Construct worktree path using wtx config.
-	// WorktreeDir is typically relative to the repo root (default: ../worktrees).
-	// If WorktreeDir is absolute, filepath.Join will use it as-is.
-	worktreePath := filepath.Join(root, cfg.WorktreeDir, name)
-
 	if g.BranchExists(branch) {
 		if err := g.AddWorktree(worktreePath, branch); err != nil {
 			return "", fmt.Errorf("create worktree: %w", err)
@@ -115,3 +123,54 @@ func (r *Runner) createWorktreePathWithName(repoPath, name, branch, baseBranch s
 
 	return worktreePath, nil
 }
+
+// createDetachedWorktree checks out startPoint in a new worktree with a
+// detached HEAD, so no branch is created.
+func (r *Runner) createDetachedWorktree(repoPath, worktreeDir, name, startPoint string) (string, error) {
+	name = strings.TrimSpace(name)
+	startPoint = strings.TrimSpace(startPoint)
+
+	g := git.New(repoPath)
+	if !g.IsRepo() {
+		return "", fmt.Errorf("not a git repository: %s", repoPath)
+	}
+	if name == "" {
+		return "", fmt.Errorf("worktree name is required")
+	}
+	if startPoint == "" {
+		return "", fmt.Errorf("base branch is required")
+	}
+
+	worktreePath, err := worktreePathFor(g, worktreeDir, name)
+	if err != nil {
+		return "", err
+	}
+	if err := g.AddWorktreeDetached(worktreePath, startPoint); err != nil {
+		return "", fmt.Errorf("create detached worktree (start=%s): %w", startPoint, err)
+	}
+	return worktreePath, nil
+}
+
+// worktreePathFor places a named worktree in worktreeDir, or according to
+// the wtx config when worktreeDir is empty.
+func worktreePathFor(g *git.Git, worktreeDir, name string) (string, error) {
+	if worktreeDir != "" {
+		return filepath.Join(worktreeDir, name), nil
+	}
+
+	// Load wtx config to get worktree directory preference
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("load wtx config: %w", err)
+	}
+
+	root, err := g.GetRepoRoot()
+	if err != nil {
+		return "", fmt.Errorf("get repo root: %w", err)
+	}
+
+	// Construct worktree path using wtx config.
+	// WorktreeDir is typically relative to the repo root (default: ../worktrees).
+	// If WorktreeDir is absolute, filepath.Join will use it as-is.
+	return filepath.Join(root, cfg.WorktreeDir, name), nil
+}
@@ -25,7 +25,7 @@ func TestCreateWorktreePathWithNameHasExplicitBase(t *testing.T) {
 
 	r := New(nil)
 
-	wtPath, err := r.createWorktreePathWithName(repo, "feature", "feature", baseBranch)
+	wtPath, err := r.createWorktreePathWithName(repo, "", "feature", "feature", baseBranch)
 	if err != nil {
 		t.Fatalf("createWorktreePathWithName returned error: %v", err)
 	}
@@ -60,7 +60,7 @@ func TestCreateWorktreePathWithNameAllowsEmptyBaseForExistingBranch(t *testing.T
 
 	r := New(nil)
 
-	wtPath, err := r.createWorktreePathWithName(repo, "existing-wt", "existing", "")
+	wtPath, err := r.createWorktreePathWithName(repo, "", "existing-wt", "existing", "")
 	if err != nil {
 		t.Fatalf("createWorktreePathWithName returned error: %v", err)
 	}
@@ -69,6 +69,53 @@ func TestCreateWorktreePathWithNameAllowsEmptyBaseForExistingBranch(t *testing.T
 	}
 }
 
+func TestSessionWorktreeDirPrefersRepoRootAndChecksItIsWritable(t *testing.T) {
+	repo := initGitRepo(t, "master")
+	globalRoot := t.TempDir()
+	repoRoot := t.TempDir()
+
+	r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, fakeSettings{SettingDefaultWorktreeRoot: globalRoot})
+	r.repos = fakeRepos{
+		"acme/api": {Name: "acme/api"},
+		"acme/web": {Name: "acme/web", WorktreeRoot: repoRoot},
+	}
+
+	dir, err := r.sessionWorktreeDir("acme/api")
+	if err != nil || dir != filepath.Join(globalRoot, "acme", "api") {
+		t.Fatalf("global root dir = %q, %v", dir, err)
+	}
+	dir, err = r.sessionWorktreeDir("acme/web")
+	if err != nil || dir != filepath.Join(repoRoot, "acme", "web") {
+		t.Fatalf("repo root dir = %q, %v", dir, err)
+	}
+
+	wtPath, err := r.createWorktreePathWithName(repo, dir, "feature", "feature", "master")
+	if err != nil {
+		t.Fatalf("createWorktreePathWithName returned error: %v", err)
+	}
+	if wtPath != filepath.Join(repoRoot, "acme", "web", "feature") {
+		t.Fatalf("worktree path = %q, want it under the repo's root", wtPath)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "README.md")); err != nil {
+		t.Fatalf("expected a checkout under the root: %v", err)
+	}
+
+	// A root that cannot be created fails before any git work
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r.settings = fakeSettings{SettingDefaultWorktreeRoot: blocker}
+	if _, err := r.sessionWorktreeDir("acme/api"); err == nil || !strings.Contains(err.Error(), "not writable") {
+		t.Fatalf("unwritable root err = %v", err)
+	}
+
+	r.settings = fakeSettings{}
+	if dir, err := r.sessionWorktreeDir("acme/api"); err != nil || dir != "" {
+		t.Fatalf("no root configured = %q, %v; want the wtx config placement", dir, err)
+	}
+}
+
 func initGitRepo(t *testing.T, defaultBranch string) string {
 	t.Helper()
 
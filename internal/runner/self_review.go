@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// selfReviewDiffMaxBytes bounds the diff handed to the reviewer. The tool runs
+// in the worktree and can read anything the truncated diff leaves out.
+const selfReviewDiffMaxBytes = 64 << 10
+
+// selfReviewCommitMsg is used when the reviewer suggests no message of its own.
+const selfReviewCommitMsg = "fix: address self-review findings"
+
+// selfReviewPrompt asks for a review of the branch diff. The review runs in a
+// fresh conversation so it judges the code rather than the reasoning that
+// produced it.
+func selfReviewPrompt(baseBranch, diff string) string {
+	var b strings.Builder
+	b.WriteString("Review the changes on this branch relative to " + baseBranch + " for bugs, ")
+	b.WriteString("regressions and security problems. Fix only critical issues, directly in the ")
+	b.WriteString("working tree; do not refactor, restyle or extend the change. If nothing needs ")
+	b.WriteString("fixing, change no files and say so.\n\nDiff:\n")
+	b.WriteString(diff)
+	return b.String()
+}
+
+// selfReview runs the review pass after a run's commit and commits whatever it
+// fixes. It returns the new commit's SHA, or "" when the reviewer changed
+// nothing. A reviewer that fails is recorded and its partial edits discarded:
+// the implementation commit stands on its own, so only cancellation and git
+// failures end the run.
+//
+// When the run validates, the review's edits are validated again before they
+// are committed, as the implementation's were; edits that fail are discarded
+// the same way, so a review can never land a commit that breaks validation.
+func (r *Runner) selfReview(ctx context.Context, session state.Session, run state.Run, opts sessionRunOptions) (string, error) {
+	baseBranch := opts.BaseBranch
+	if err := r.setRunPhase(session.ID, run.ID, "REVIEWING"); err != nil {
+		return "", err
+	}
+	g := git.New(run.WorktreePath).WithContext(ctx)
+	diff, err := g.Diff(baseBranch + "...HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   run.ID,
+		Type:    "review_start",
+		Message: "Running self-review",
+	})
+
+	streamWriter := r.newRunStreamWriter(run.ID)
+	output, _, usage, err := r.runToolWithOptions(
+		ctx,
+		run.ID,
+		session.Tool,
+		run.WorktreePath,
+		selfReviewPrompt(baseBranch, truncate(diff, selfReviewDiffMaxBytes))+commitMsgInstructions,
+		session.Model,
+		"",
+		envEntries(session.Env),
+		streamWriter.Append,
+	)
+	streamWriter.Close()
+	r.recordRunUsage(run.ID, usage)
+	if strings.TrimSpace(output) != "" {
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "review_output",
+			Message: truncate(output, 8000),
+		})
+	}
+	if isCanceledError(err) {
+		return "", err
+	}
+	if err != nil {
+		msg := "Self-review failed: " + err.Error()
+		if discardErr := g.DiscardChanges(); discardErr != nil {
+			msg += "; could not discard its edits: " + discardErr.Error()
+		}
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "review",
+			Message: msg,
+		})
+		return "", nil
+	}
+
+	if opts.Validate && opts.ValidateCmd != "" {
+		dirty, err := g.IsDirty()
+		if err != nil {
+			return "", fmt.Errorf("git status failed: %w", err)
+		}
+		if dirty {
+			code, out, err := r.runShellAllowing(ctx, run.WorktreePath, opts.ValidateCmd, envEntries(session.Env), opts.ValidateSuccessCodes)
+			r.recordValidateOutput(run.ID, code, out, err)
+			if isCanceledError(err) {
+				return "", err
+			}
+			if err != nil {
+				msg := "Self-review edits failed validation and were discarded: " + err.Error()
+				if discardErr := g.DiscardChanges(); discardErr != nil {
+					msg += "; could not discard them: " + discardErr.Error()
+				}
+				_ = r.runs.AppendRunEvent(state.RunEvent{
+					RunID:   run.ID,
+					Type:    "review",
+					Message: msg,
+				})
+				return "", nil
+			}
+		}
+	}
+
+	msg := extractCommitMessage(output)
+	if msg == "" {
+		msg = selfReviewCommitMsg
+	}
+	sha, _, changed, err := r.commitSessionChanges(ctx, session.Tool, run.WorktreePath, "", msg, session.IssueRef)
+	if err != nil {
+		return "", err
+	}
+	if !changed {
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "review",
+			Message: "Self-review found nothing to fix",
+		})
+		return "", nil
+	}
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   run.ID,
+		Type:    "review",
+		Message: "Self-review committed fixes as " + sha,
+	})
+	return sha, nil
+}
@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// selfReviewRun runs a session whose first tool call writes feature.txt and
+// whose second, the review, calls review. The base branch is the worktree's
+// initial commit. A non-empty validateCmd validates the run.
+func selfReviewRun(t *testing.T, validateCmd string, review func(wt string) error) (*fakeRunStore, *fakeTool, string, error) {
+	t.Helper()
+	wt := initTestWorktree(t)
+	if out, err := exec.Command("git", "-C", wt, "branch", "base").CombinedOutput(); err != nil {
+		t.Fatalf("git branch: %v\n%s", err, out)
+	}
+	writeFile(t, wt, "feature.txt", "work")
+
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	tool.block = func(context.Context) error {
+		if tool.calls == 2 {
+			return review(wt)
+		}
+		return nil
+	}
+	r := newTestRunner(store, tool, nil)
+
+	err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:      "add a feature",
+		BaseBranch:  "base",
+		CommitMsg:   "feat: add a feature",
+		SelfReview:  true,
+		Validate:    validateCmd != "",
+		ValidateCmd: validateCmd,
+	})
+	return store, tool, wt, err
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestSelfReviewCommitsFixes(t *testing.T) {
+	store, tool, wt, err := selfReviewRun(t, "", func(wt string) error {
+		return os.WriteFile(filepath.Join(wt, "feature.txt"), []byte("fixed work"), 0o644)
+	})
+	if err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+
+	req := tool.request()
+	if req.ConversationID != "" || !strings.Contains(req.Prompt, "+work") {
+		t.Fatalf("review should start fresh with the branch diff, got %+v", req)
+	}
+	if got := gitOutput(t, wt, "log", "--format=%s", "base..HEAD"); got != selfReviewCommitMsg+"\nfeat: add a feature" {
+		t.Fatalf("commits = %q", got)
+	}
+	run := store.runs["run-1"]
+	if run.State != "COMPLETED" || run.CommitSHA != gitOutput(t, wt, "rev-parse", "HEAD") {
+		t.Fatalf("run = %+v", run)
+	}
+	for _, typ := range []string{"review_start", "review"} {
+		if _, ok := store.eventOfType(typ); !ok {
+			t.Errorf("missing %s event in %v", typ, store.eventTypes())
+		}
+	}
+}
+
+func TestSelfReviewFailureKeepsImplementation(t *testing.T) {
+	store, _, wt, err := selfReviewRun(t, "", func(wt string) error {
+		_ = os.WriteFile(filepath.Join(wt, "half.txt"), []byte("partial"), 0o644)
+		return errors.New("exit status 1")
+	})
+	if err != nil {
+		t.Fatalf("a failed review must not fail the run: %v", err)
+	}
+	if got := gitOutput(t, wt, "log", "--format=%s", "base..HEAD"); got != "feat: add a feature" {
+		t.Fatalf("commits = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "half.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected the reviewer's partial edits discarded, stat err = %v", err)
+	}
+	ev, ok := store.eventOfType("review")
+	if !ok || !strings.Contains(ev.Message, "Self-review failed") {
+		t.Fatalf("review event = %+v (found=%v)", ev, ok)
+	}
+	if got := store.runs["run-1"].State; got != "COMPLETED" {
+		t.Fatalf("run state = %q, want COMPLETED", got)
+	}
+}
+
+func TestSelfReviewEditsMustPassValidation(t *testing.T) {
+	const validate = "test ! -e broken.txt"
+	store, _, wt, err := selfReviewRun(t, validate, func(wt string) error {
+		return os.WriteFile(filepath.Join(wt, "broken.txt"), []byte("regression"), 0o644)
+	})
+	if err != nil {
+		t.Fatalf("a review that fails validation must not fail the run: %v", err)
+	}
+	if got := gitOutput(t, wt, "log", "--format=%s", "base..HEAD"); got != "feat: add a feature" {
+		t.Fatalf("commits = %q, want only the implementation", got)
+	}
+	if _, err := os.Stat(filepath.Join(wt, "broken.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected the failing review edits discarded, stat err = %v", err)
+	}
+	ev, ok := store.eventOfType("review")
+	if !ok || !strings.Contains(ev.Message, "failed validation") {
+		t.Fatalf("review event = %+v (found=%v)", ev, ok)
+	}
+	run := store.runs["run-1"]
+	if run.State != "COMPLETED" || run.CommitSHA != gitOutput(t, wt, "rev-parse", "HEAD") {
+		t.Fatalf("run = %+v", run)
+	}
+
+	// Review edits that pass are committed as before.
+	_, _, wt, err = selfReviewRun(t, validate, func(wt string) error {
+		return os.WriteFile(filepath.Join(wt, "feature.txt"), []byte("fixed work"), 0o644)
+	})
+	if err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	if got := gitOutput(t, wt, "log", "--format=%s", "base..HEAD"); got != selfReviewCommitMsg+"\nfeat: add a feature" {
+		t.Fatalf("commits = %q", got)
+	}
+}
@@ -11,6 +11,10 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrSessionPaused is returned for a follow-up or fork of a paused session.
+// Someone is working in its worktree by hand and asked the AI to stay out.
+var ErrSessionPaused = errors.New("session is paused")
+
 // StartSessionOptions configures the first run in a new session.
 type StartSessionOptions struct {
 	RepoName    string
@@ -23,9 +27,59 @@ type StartSessionOptions struct {
 	SetupCmd    string
 	Validate    bool
 	ValidateCmd string
-	BaseBranch  string
-	CommitMsg   string
-	PRTitle     string
+	// ValidateSuccessCodes lists non-zero ValidateCmd exit codes that count as
+	// a pass, for tools that signal warnings with their own codes.
+	ValidateSuccessCodes []int
+	BaseBranch           string
+	CommitMsg            string
+	// CommitMsgMode decides the first run's commit message when CommitMsg is
+	// empty: ai, template or fixed (see the CommitMsgMode constants). Empty
+	// means template when commit_template is set, else ai. Follow-up runs
+	// always use the default.
+	CommitMsgMode string
+	PRTitle       string
+	// Ephemeral starts a scratch session: the run works in a detached
+	// worktree at BaseBranch, Branch is ignored, nothing is committed, pushed
+	// or opened as a PR, and the worktree is removed when the run ends.
+	Ephemeral bool
+	// Origin names the interface that created the session (cli, api,
+	// desktop, slack, cloud) and is stored on it as-is.
+	Origin string
+	// CommitStrategy is one of the CommitStrategy constants; empty means
+	// per_run. It applies to every run of the session that pushes.
+	CommitStrategy string
+	// FocusPaths are worktree-relative paths the first run is asked to
+	// confine its changes to. They are added to the tool prompt only, not to
+	// the stored run prompt.
+	FocusPaths []string
+	// Attachments are worktree-relative files whose contents are put ahead
+	// of the first run's tool prompt, and ContextText is pasted text put
+	// there too; see attachmentContext. Neither is stored on the run.
+	Attachments []string
+	ContextText string
+	// SelfReview runs the tool a second time after the first run commits,
+	// asking it to review the branch diff and fix critical issues, before
+	// anything is pushed. Ignored for ephemeral sessions.
+	SelfReview bool
+	// IssueRef names the tracking issue the session works on: #123,
+	// owner/repo#123, PROJ-123 or a URL; a bare number gains a #. It is
+	// stored on the session, added as a Refs trailer to every commit the
+	// session makes and cited in its PR body.
+	IssueRef string
+	// CloseIssue writes "Closes <IssueRef>" in the PR body instead of
+	// "Refs: <IssueRef>", so merging the PR closes the issue.
+	CloseIssue bool
+	// Env is added to the environment of the setup command, the validation
+	// command and the AI tool. It is stored on the session, so follow-up runs
+	// get it too.
+	Env map[string]string
+	// Timeout bounds the first run; when it passes the run fails with a
+	// timeout event. Zero uses run_timeout_seconds. Follow-up runs always use
+	// the setting.
+	Timeout time.Duration
+	// ParentSessionID is the session this one was forked from, stored on
+	// it; ForkSession sets it.
+	ParentSessionID string
 }
 
 // StartSession creates a new session (branch/worktree) and executes the initial prompt.
@@ -39,12 +93,21 @@ func (r *Runner) StartSession(opts StartSessionOptions) (state.Session, state.Ru
 }
 
 // StartSessionAsync creates a new session and starts the initial run in the background.
+//
+// It returns ErrQueueFull, without creating anything, when the number of
+// background runs has reached max_queued_runs.
 func (r *Runner) StartSessionAsync(opts StartSessionOptions) (state.Session, state.Run, error) {
+	release, err := r.reserveQueueSlot()
+	if err != nil {
+		return state.Session{}, state.Run{}, err
+	}
 	session, run, execOpts, err := r.prepareSession(opts)
 	if err != nil {
+		release()
 		return state.Session{}, state.Run{}, err
 	}
 	go func(s state.Session, ru state.Run, eo sessionRunOptions) {
+		defer release()
 		_ = r.executeSessionRun(s, ru, eo)
 	}(session, run, execOpts)
 	return session, run, nil
@@ -71,12 +134,19 @@ func (r *Runner) ContinueSession(sessionID, prompt string) (state.Run, error) {
 }
 
 // ContinueSessionAsync appends one follow-up run and executes it in the background.
+// Like StartSessionAsync it returns ErrQueueFull when the queue is saturated.
 func (r *Runner) ContinueSessionAsync(sessionID, prompt string) (state.Run, error) {
+	release, err := r.reserveQueueSlot()
+	if err != nil {
+		return state.Run{}, err
+	}
 	session, run, execOpts, err := r.prepareFollowUpRun(sessionID, prompt)
 	if err != nil {
+		release()
 		return state.Run{}, err
 	}
 	go func(s state.Session, ru state.Run, eo sessionRunOptions) {
+		defer release()
 		_ = r.executeSessionRun(s, ru, eo)
 	}(session, run, execOpts)
 	return run, nil
@@ -93,9 +163,29 @@ type ForkSessionOptions struct {
 	SetupCmd    string
 	Validate    bool
 	ValidateCmd string
-	BaseBranch  string
-	CommitMsg   string
-	PRTitle     string
+	// ValidateSuccessCodes is as for StartSessionOptions.
+	ValidateSuccessCodes []int
+	BaseBranch           string
+	CommitMsg            string
+	PRTitle              string
+	// FullTranscriptContext replays the source session's prompts and AI
+	// outputs into the fork's first prompt instead of asking the tool for a
+	// summary. Higher fidelity, more tokens; bounded by forkTranscriptMaxBytes.
+	FullTranscriptContext bool
+	// Origin is as for StartSessionOptions: where the fork was requested,
+	// not where the source session came from.
+	Origin string
+	// CommitStrategy falls back to the source session's.
+	CommitStrategy string
+	// FocusPaths is as for StartSessionOptions.
+	FocusPaths []string
+	// SelfReview is as for StartSessionOptions.
+	SelfReview bool
+	// IssueRef falls back to the source session's, and CloseIssue with it.
+	IssueRef   string
+	CloseIssue bool
+	// Env replaces the source session's environment; nil keeps it.
+	Env map[string]string
 }
 
 // ForkSession creates a new session from an existing one and runs immediately.
@@ -143,6 +233,43 @@ func (r *Runner) prepareSession(opts StartSessionOptions) (state.Session, state.
 	opts.ValidateCmd = strings.TrimSpace(opts.ValidateCmd)
 	opts.BaseBranch = strings.TrimSpace(opts.BaseBranch)
 	opts.CommitMsg = strings.TrimSpace(opts.CommitMsg)
+	if err := applyRepoConfig(&opts); err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	if err := r.checkSessionCommands(opts.SetupCmd, opts.Validate, opts.ValidateCmd); err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+
+	if opts.Ephemeral {
+		if opts.AutoPR {
+			return state.Session{}, state.Run{}, sessionRunOptions{}, errors.New("ephemeral sessions cannot open pull requests")
+		}
+		opts.Branch = scratchBranchLabel
+	}
+	commitStrategy, err := normalizeCommitStrategy(opts.CommitStrategy)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	commitMsgMode, err := normalizeCommitMsgMode(opts.CommitMsgMode)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	focusPaths, err := normalizeFocusPaths(opts.FocusPaths)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	attachments, err := normalizeAttachments(opts.Attachments)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	issueRef, err := normalizeIssueRef(opts.IssueRef)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	env, err := normalizeSessionEnv(opts.Env)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
 
 	switch {
 	case opts.RepoName == "":
@@ -159,26 +286,44 @@ func (r *Runner) prepareSession(opts StartSessionOptions) (state.Session, state.
 		return state.Session{}, state.Run{}, sessionRunOptions{}, errors.New("base branch is required")
 	}
 
+	worktreeDir, err := r.sessionWorktreeDir(opts.RepoName)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+
 	runID := uuid.New().String()
-	worktreeName := runWorktreeName(opts.Branch, runID)
-	worktreePath, err := r.createWorktreePathWithName(opts.RepoPath, worktreeName, opts.Branch, opts.BaseBranch)
+	var worktreePath string
+	if opts.Ephemeral {
+		worktreePath, err = r.createDetachedWorktree(opts.RepoPath, worktreeDir, runWorktreeName("scratch", runID), opts.BaseBranch)
+	} else {
+		worktreeName := runWorktreeName(opts.Branch, runID)
+		worktreePath, err = r.createWorktreePathWithName(opts.RepoPath, worktreeDir, worktreeName, opts.Branch, opts.BaseBranch)
+	}
 	if err != nil {
 		return state.Session{}, state.Run{}, sessionRunOptions{}, err
 	}
 
 	now := time.Now().UTC()
 	session := state.Session{
-		ID:           uuid.New().String(),
-		RepoName:     opts.RepoName,
-		Branch:       opts.Branch,
-		WorktreePath: worktreePath,
-		Tool:         opts.Tool,
-		Model:        opts.Model,
-		AutoPR:       opts.AutoPR,
-		Status:       "CREATED",
-		Busy:         true,
-		CreatedAt:    now,
-		UpdatedAt:    now,
+		ID:             uuid.New().String(),
+		RepoName:       opts.RepoName,
+		Branch:         opts.Branch,
+		WorktreePath:   worktreePath,
+		Tool:           opts.Tool,
+		Model:          opts.Model,
+		AutoPR:         opts.AutoPR,
+		Status:         "CREATED",
+		Busy:           true,
+		Ephemeral:      opts.Ephemeral,
+		Origin:         opts.Origin,
+		CommitStrategy: commitStrategy,
+		IssueRef:       issueRef,
+		CloseIssue:     issueRef != "" && opts.CloseIssue,
+		Env:            env,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+
+		ParentSessionID: strings.TrimSpace(opts.ParentSessionID),
 	}
 	if err := r.runs.CreateSession(session); err != nil {
 		return state.Session{}, state.Run{}, sessionRunOptions{}, err
@@ -190,6 +335,8 @@ func (r *Runner) prepareSession(opts StartSessionOptions) (state.Session, state.
 		Prompt:       opts.Prompt,
 		WorktreePath: worktreePath,
 		State:        "CREATED",
+		Attachments:  attachments,
+		ContextText:  strings.TrimSpace(opts.ContextText),
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -199,13 +346,22 @@ func (r *Runner) prepareSession(opts StartSessionOptions) (state.Session, state.
 	}
 
 	return session, run, sessionRunOptions{
-		Prompt:      opts.Prompt,
-		SetupCmd:    opts.SetupCmd,
-		Validate:    opts.Validate,
-		ValidateCmd: opts.ValidateCmd,
-		BaseBranch:  opts.BaseBranch,
-		CommitMsg:   opts.CommitMsg,
-		PRTitle:     opts.PRTitle,
+		Prompt:               opts.Prompt,
+		SetupCmd:             opts.SetupCmd,
+		Validate:             opts.Validate,
+		ValidateCmd:          opts.ValidateCmd,
+		ValidateSuccessCodes: opts.ValidateSuccessCodes,
+		BaseBranch:           opts.BaseBranch,
+		CommitMsg:            opts.CommitMsg,
+		CommitMsgMode:        commitMsgMode,
+		PRTitle:              opts.PRTitle,
+		Ephemeral:            opts.Ephemeral,
+		RepoPath:             opts.RepoPath,
+		FocusPaths:           focusPaths,
+		Attachments:          attachments,
+		ContextText:          strings.TrimSpace(opts.ContextText),
+		SelfReview:           opts.SelfReview,
+		Timeout:              opts.Timeout,
 	}, nil
 }
 
@@ -229,19 +385,69 @@ func (r *Runner) prepareFollowUpRun(sessionID, prompt string) (state.Session, st
 	if !found {
 		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q: %w", sessionID, state.ErrNotFound)
 	}
-	if session.Busy {
-		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q is busy", sessionID)
+	if session.Paused {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: resume session %q to run follow-ups", ErrSessionPaused, sessionID)
+	}
+	if session.Ephemeral {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q is a scratch session and takes no follow-ups", sessionID)
 	}
-	if err := r.runs.SetSessionBusy(session.ID, true); err != nil {
+	run, opts, err := r.createFollowUpRun(session, prompt, nil, "")
+	if err != nil {
 		return state.Session{}, state.Run{}, sessionRunOptions{}, err
 	}
+	return session, run, opts, nil
+}
+
+// createFollowUpRun claims a checked session busy and records a new run in
+// its existing worktree, after the dirty-worktree preflight. Every error path
+// clears the busy flag again. A session whose worktree was pruned is refused
+// with ErrWorktreePruned, and one already busy with ErrSessionBusy, or with
+// ErrDuplicatePrompt when prompt repeats the run holding it.
+// claimIdleSession marks an idle session busy for an operation that must not
+// overlap a run, such as a rename or a squash, and returns the func that
+// releases it. The claim is a single compare-and-set, so a run admitted
+// between a check and the mark can no longer slip underneath.
+func (r *Runner) claimIdleSession(sessionID string) (func(), error) {
+	claimed, err := r.runs.ClaimSessionBusy(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, fmt.Errorf("%w: session %q has a run in progress", ErrSessionBusy, sessionID)
+	}
+	return func() { _ = r.runs.SetSessionBusy(sessionID, false) }, nil
+}
+
+// createFollowUpRun claims session and creates its next run. attachments and
+// contextText are put ahead of the run's prompt; only a retry passes any.
+func (r *Runner) createFollowUpRun(session state.Session, prompt string, attachments []string, contextText string) (state.Run, sessionRunOptions, error) {
+	if session.WorktreePrunedAt != nil {
+		return state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: session %q has no worktree; its branch %s is kept", ErrWorktreePruned, session.ID, session.Branch)
+	}
+	// The claim alone decides who gets the session: of two identical
+	// submits racing here exactly one wins. The duplicate check only words
+	// the loser's refusal, so it cannot let a second run through.
+	claimed, err := r.runs.ClaimSessionBusy(session.ID)
+	if err != nil {
+		return state.Run{}, sessionRunOptions{}, err
+	}
+	if !claimed {
+		if err := r.checkDuplicatePrompt(session.ID, prompt); err != nil {
+			return state.Run{}, sessionRunOptions{}, err
+		}
+		return state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: session %q has a run in progress", ErrSessionBusy, session.ID)
+	}
 	worktreePath := strings.TrimSpace(session.WorktreePath)
 	if worktreePath == "" {
 		_ = r.runs.SetSessionBusy(session.ID, false)
-		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q has no worktree path", session.ID)
+		return state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q has no worktree path", session.ID)
 	}
-
 	runID := uuid.New().String()
+	preflightType, preflightMsg, stashed, err := r.dirtyPreflight(worktreePath, runID)
+	if err != nil {
+		_ = r.runs.SetSessionBusy(session.ID, false)
+		return state.Run{}, sessionRunOptions{}, err
+	}
 
 	now := time.Now().UTC()
 	run := state.Run{
@@ -250,26 +456,41 @@ func (r *Runner) prepareFollowUpRun(sessionID, prompt string) (state.Session, st
 		Prompt:       prompt,
 		WorktreePath: worktreePath,
 		State:        "CREATED",
+		Attachments:  attachments,
+		ContextText:  contextText,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 	if err := r.runs.CreateRun(run); err != nil {
+		if stashed {
+			r.restoreStash(run)
+		}
 		_ = r.runs.SetSessionBusy(session.ID, false)
-		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+		return state.Run{}, sessionRunOptions{}, err
 	}
 	if err := r.runs.UpdateSessionStatus(session.ID, "CREATED"); err != nil {
+		if stashed {
+			r.restoreStash(run)
+		}
 		_ = r.runs.SetSessionBusy(session.ID, false)
-		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+		return state.Run{}, sessionRunOptions{}, err
+	}
+	if preflightType != "" {
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    preflightType,
+			Message: preflightMsg,
+		})
 	}
 
 	repo, _, _ := r.repos.GetRepoByName(session.RepoName)
-	baseBranch := strings.TrimSpace(repo.DefaultBranch)
-	if baseBranch == "" {
-		baseBranch = "main"
-	}
-	return session, run, sessionRunOptions{
-		Prompt:     prompt,
-		BaseBranch: baseBranch,
+	baseBranch := r.ResolveBaseBranch("", repo)
+	return run, sessionRunOptions{
+		Prompt:       prompt,
+		BaseBranch:   baseBranch,
+		Attachments:  attachments,
+		ContextText:  contextText,
+		RestoreStash: stashed,
 	}, nil
 }
 
@@ -296,6 +517,13 @@ func (r *Runner) prepareForkSession(sourceSessionID string, opts ForkSessionOpti
 	case opts.Prompt == "":
 		return StartSessionOptions{}, state.Session{}, errors.New("prompt is required")
 	}
+	if err := checkExitCodes(opts.ValidateSuccessCodes); err != nil {
+		return StartSessionOptions{}, state.Session{}, fmt.Errorf("validate_success_codes: %w", err)
+	}
+	focusPaths, err := normalizeFocusPaths(opts.FocusPaths)
+	if err != nil {
+		return StartSessionOptions{}, state.Session{}, err
+	}
 
 	sourceSession, found, err := r.runs.GetSession(sourceSessionID)
 	if err != nil {
@@ -304,9 +532,18 @@ func (r *Runner) prepareForkSession(sourceSessionID string, opts ForkSessionOpti
 	if !found {
 		return StartSessionOptions{}, state.Session{}, fmt.Errorf("session %q: %w", sourceSessionID, state.ErrNotFound)
 	}
+	if sourceSession.Paused {
+		return StartSessionOptions{}, state.Session{}, fmt.Errorf("%w: resume session %q to fork it", ErrSessionPaused, sourceSessionID)
+	}
 	if sourceSession.Busy {
 		return StartSessionOptions{}, state.Session{}, fmt.Errorf("session %q is busy", sourceSessionID)
 	}
+	if sourceSession.Ephemeral {
+		return StartSessionOptions{}, state.Session{}, fmt.Errorf("session %q is a scratch session and cannot be forked", sourceSessionID)
+	}
+	if sourceSession.WorktreePrunedAt != nil {
+		return StartSessionOptions{}, state.Session{}, fmt.Errorf("%w: session %q", ErrWorktreePruned, sourceSessionID)
+	}
 	sourceWorktreePath := strings.TrimSpace(sourceSession.WorktreePath)
 	if sourceWorktreePath == "" {
 		return StartSessionOptions{}, state.Session{}, fmt.Errorf("session %q has no worktree path", sourceSessionID)
@@ -341,16 +578,36 @@ func (r *Runner) prepareForkSession(sourceSessionID string, opts ForkSessionOpti
 		autoPR = opts.AutoPR
 	}
 
-	baseBranch := opts.BaseBranch
-	if baseBranch == "" {
-		baseBranch = strings.TrimSpace(repo.DefaultBranch)
-		if baseBranch == "" {
-			baseBranch = "main"
-		}
+	commitStrategy := strings.TrimSpace(opts.CommitStrategy)
+	if commitStrategy == "" {
+		commitStrategy = sourceSession.CommitStrategy
+	}
+	if commitStrategy, err = normalizeCommitStrategy(commitStrategy); err != nil {
+		return StartSessionOptions{}, state.Session{}, err
+	}
+
+	issueRef, closeIssue := strings.TrimSpace(opts.IssueRef), opts.CloseIssue
+	if issueRef == "" {
+		issueRef, closeIssue = sourceSession.IssueRef, sourceSession.CloseIssue
+	}
+
+	env := opts.Env
+	if env == nil {
+		env = sourceSession.Env
 	}
 
+	baseBranch := r.ResolveBaseBranch(opts.BaseBranch, repo)
+
 	finalPrompt := opts.Prompt
-	if summary, err := r.generateForkSummary(sourceSession, opts.Prompt, tool); err == nil {
+	if opts.FullTranscriptContext {
+		transcript, err := r.buildForkTranscript(sourceSession, forkTranscriptMaxBytes)
+		if err != nil {
+			return StartSessionOptions{}, state.Session{}, fmt.Errorf("build fork transcript: %w", err)
+		}
+		if transcript != "" {
+			finalPrompt = opts.Prompt + "\n\nTranscript of source session:\n" + transcript
+		}
+	} else if summary, err := r.generateForkSummary(r.baseCtx, sourceSession, opts.Prompt, tool); err == nil {
 		summary = strings.TrimSpace(summary)
 		if summary != "" {
 			finalPrompt = strings.TrimSpace(opts.Prompt) + "\n\nContext from source session:\n" + summary
@@ -371,6 +628,16 @@ func (r *Runner) prepareForkSession(sourceSessionID string, opts ForkSessionOpti
 		BaseBranch:  baseBranch,
 		CommitMsg:   opts.CommitMsg,
 		PRTitle:     opts.PRTitle,
+
+		ValidateSuccessCodes: opts.ValidateSuccessCodes,
+		Origin:               strings.TrimSpace(opts.Origin),
+		CommitStrategy:       commitStrategy,
+		FocusPaths:           focusPaths,
+		SelfReview:           opts.SelfReview,
+		IssueRef:             issueRef,
+		CloseIssue:           closeIssue,
+		Env:                  env,
+		ParentSessionID:      sourceSession.ID,
 	}, sourceSession, nil
 }
 
@@ -488,6 +755,34 @@ func (r *Runner) CancelSessionLatestRun(sessionID string) (state.Run, error) {
 	return latest, nil
 }
 
+// CancelRunIfActive cancels runID when it is the session's in-flight run and
+// reports whether it was. A run that is not executing is left alone.
+func (r *Runner) CancelRunIfActive(sessionID, runID string) bool {
+	sessionID = strings.TrimSpace(sessionID)
+	runID = strings.TrimSpace(runID)
+
+	r.mu.Lock()
+	current, ok := r.active[sessionID]
+	if !ok || current == nil || strings.TrimSpace(current.runID) != runID {
+		r.mu.Unlock()
+		return false
+	}
+	cancel := current.cancel
+	r.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if r.runs != nil {
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   runID,
+			Type:    "cancel_requested",
+			Message: "Cancellation requested by user",
+		})
+	}
+	return true
+}
+
 func (r *Runner) lookupConversationID(sessionID, currentRunID string) string {
 	if r.runs == nil {
 		return ""
@@ -4,42 +4,116 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/darkLord19/foglet/internal/ai"
-	"github.com/darkLord19/foglet/internal/git"
 	"github.com/darkLord19/foglet/internal/proc"
 	"github.com/darkLord19/foglet/internal/state"
 )
 
+// SettingScratchDir is the settings key for the directory scratch AI calls
+// (commit messages, fork summaries) run in. Unset uses the system temp dir.
+const SettingScratchDir = "scratch_dir"
+
+// scratchDir creates a fresh directory for a scratch AI call under
+// scratch_dir, creating scratch_dir itself when missing. The caller removes
+// the returned directory.
+func (r *Runner) scratchDir(pattern string) (string, error) {
+	parent := ""
+	if r.settings != nil {
+		if raw, found, err := r.settings.GetSetting(SettingScratchDir); err == nil && found {
+			parent = strings.TrimSpace(raw)
+		}
+	}
+	if parent != "" {
+		if err := os.MkdirAll(parent, 0o700); err != nil {
+			return "", fmt.Errorf("create scratch_dir: %w", err)
+		}
+	}
+	return os.MkdirTemp(parent, pattern)
+}
+
 func (r *Runner) runTool(ctx context.Context, toolName, workdir, prompt string) (string, error) {
-	output, _, err := r.runToolWithOptions(ctx, toolName, workdir, prompt, "", "", nil)
+	output, _, _, err := r.runToolWithOptions(ctx, "", toolName, workdir, prompt, "", "", nil, nil)
 	return output, err
 }
 
+// runToolWithOptions runs one AI call. When the tool rejects model as
+// unavailable, the call is made again with each model_fallbacks entry for the
+// tool in turn, each switch recorded as a model_fallback event on runID (when
+// set). The usage returned covers every attempt.
 func (r *Runner) runToolWithOptions(
 	ctx context.Context,
-	toolName, workdir, prompt, model, conversationID string,
+	runID, toolName, workdir, prompt, model, conversationID string,
+	env []string,
 	onChunk func(string),
-) (string, string, error) {
+) (string, string, ai.Usage, error) {
 	tool, err := r.tools(toolName)
 	if err != nil {
-		return "", "", err
+		return "", "", ai.Usage{}, err
 	}
 	if !tool.IsAvailable() {
-		return "", "", fmt.Errorf("AI tool %s not available", toolName)
+		return "", "", ai.Usage{}, fmt.Errorf("AI tool %s not available", toolName)
+	}
+	wrapper, err := r.ToolExecWrapper()
+	if err != nil {
+		return "", "", ai.Usage{}, err
+	}
+	toolArgs, err := r.ToolArgs()
+	if err != nil {
+		return "", "", ai.Usage{}, err
+	}
+	fallbacks, err := r.ModelFallbacks()
+	if err != nil {
+		return "", "", ai.Usage{}, err
 	}
 
-	result, err := tool.ExecuteStream(ctx, ai.ExecuteRequest{
-		Workdir:        workdir,
-		Prompt:         prompt,
-		Model:          model,
-		ConversationID: conversationID,
-	}, onChunk)
+	model = strings.TrimSpace(model)
+	tried := []string{model}
+	var usage ai.Usage
+	var result *ai.Result
+	for {
+		result, err = tool.ExecuteStream(ctx, ai.ExecuteRequest{
+			Workdir:        workdir,
+			Prompt:         prompt,
+			Model:          model,
+			ConversationID: conversationID,
+			Env:            env,
+			Wrapper:        wrapper,
+			ExtraArgs:      toolArgs[tool.Name()],
+		}, onChunk)
+		if result != nil {
+			usage.InputTokens += result.Usage.InputTokens
+			usage.OutputTokens += result.Usage.OutputTokens
+			usage.CostUSD += result.Usage.CostUSD
+		}
+		if !ai.IsModelUnavailable(err) {
+			break
+		}
+		next, ok := nextFallbackModel(fallbacks[tool.Name()], model, tried)
+		if !ok {
+			break
+		}
+		msg := modelFallbackMessage(err, model, next)
+		if runID != "" {
+			_ = r.runs.AppendRunEvent(state.RunEvent{
+				RunID:   runID,
+				Type:    "model_fallback",
+				Message: msg,
+				Data:    next,
+			})
+		} else {
+			slog.Warn(msg, "tool", tool.Name())
+		}
+		model = next
+		tried = append(tried, next)
+	}
 	if result == nil {
-		return "", "", err
+		return "", "", usage, err
 	}
 
 	output := strings.TrimSpace(result.Output)
@@ -48,34 +122,62 @@ func (r *Runner) runToolWithOptions(
 	// When the tool returns an error, preserve any output so the caller can
 	// persist logs for debugging.
 	if err != nil {
-		return output, nextConversationID, err
+		return output, nextConversationID, usage, err
 	}
 	if !result.Success {
-		return output, nextConversationID, fmt.Errorf("AI execution failed: %s", output)
+		return output, nextConversationID, usage, fmt.Errorf("AI execution failed: %s", output)
+	}
+	return output, nextConversationID, usage, nil
+}
+
+// recordRunUsage adds one AI call's usage to the run's totals. A tool that
+// reported nothing writes nothing, so its runs show no usage rather than
+// zeros that look measured.
+func (r *Runner) recordRunUsage(runID string, usage ai.Usage) {
+	if usage.IsZero() {
+		return
 	}
-	return output, nextConversationID, nil
+	_ = r.runs.AddRunUsage(runID, state.RunUsage{
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CostUSD:      usage.CostUSD,
+	})
+}
+
+// runShell runs cmdline with sh in workdir. env entries are added to the
+// daemon's environment.
+func (r *Runner) runShell(ctx context.Context, workdir, cmdline string, env []string) error {
+	_, _, err := r.runShellAllowing(ctx, workdir, cmdline, env, nil)
+	return err
 }
 
-func (r *Runner) runShell(ctx context.Context, workdir, cmdline string) error {
+// runShellAllowing is runShell for commands whose non-zero exits can still mean
+// success. A non-zero exit listed in okCodes is not an error; the code is
+// returned so the caller can record that it was tolerated. The command's
+// combined output is returned too, whether or not it failed.
+func (r *Runner) runShellAllowing(ctx context.Context, workdir, cmdline string, env []string, okCodes []int) (int, []byte, error) {
 	cmdline = strings.TrimSpace(cmdline)
 	if cmdline == "" {
-		return nil
+		return 0, nil, nil
 	}
 
-	output, err := proc.Run(ctx, workdir, "sh", "-c", cmdline)
+	output, err := proc.RunEnv(ctx, workdir, shellEnv(env), "sh", "-c", cmdline)
 	if err != nil {
-		return withOutput(err, output)
+		if code, ok := proc.ExitCode(err); ok && slices.Contains(okCodes, code) {
+			return code, output, nil
+		}
+		return 0, output, withOutput(err, output)
 	}
-	return nil
+	return 0, output, nil
 }
 
 func (r *Runner) generateCommitMessage(ctx context.Context, toolName, workdir, prompt string) (string, error) {
-	summary, err := stagedDiffSummary(ctx, workdir)
+	summary, err := stagedDiffSummary(ctx, workdir, r.CommitDiffBudget())
 	if err != nil {
 		return "", err
 	}
 
-	tempDir, err := os.MkdirTemp("", "fog-commit-msg-*")
+	tempDir, err := r.scratchDir("fog-commit-msg-*")
 	if err != nil {
 		return "", err
 	}
@@ -106,19 +208,6 @@ func (r *Runner) generateCommitMessage(ctx context.Context, toolName, workdir, p
 	return msg, nil
 }
 
-func stagedDiffSummary(ctx context.Context, workdir string) (string, error) {
-	diff, err := git.New(workdir).WithContext(ctx).StagedChanges()
-	if err != nil {
-		return "", err
-	}
-
-	return strings.TrimSpace(
-		"Name status:\n" + diff.NameStatus +
-			"\n\nStat:\n" + diff.Stat +
-			"\n\nPatch (truncated):\n" + truncate(diff.Patch, 12000),
-	), nil
-}
-
 func normalizeCommitMessage(raw string) string {
 	msg := strings.TrimSpace(raw)
 	if after, ok := strings.CutPrefix(msg, "```"); ok {
@@ -134,7 +223,10 @@ func normalizeCommitMessage(raw string) string {
 	return truncate(msg, 5000)
 }
 
-func (r *Runner) generateForkSummary(sourceSession state.Session, forkPrompt, toolName string) (string, error) {
+// generateForkSummary asks the tool to condense the source session. The call
+// is bounded by ctx as well as its own timeout, so shutting down the daemon
+// stops it like any other AI call.
+func (r *Runner) generateForkSummary(ctx context.Context, sourceSession state.Session, forkPrompt, toolName string) (string, error) {
 	if r.runs == nil {
 		return "", errors.New("state store not configured")
 	}
@@ -183,10 +275,10 @@ func (r *Runner) generateForkSummary(sourceSession state.Session, forkPrompt, to
 		contextBuilder.String(),
 	))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
-	tempDir, err := os.MkdirTemp("", "fog-fork-summary-*")
+	tempDir, err := r.scratchDir("fog-fork-summary-*")
 	if err != nil {
 		return "", err
 	}
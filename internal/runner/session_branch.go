@@ -3,6 +3,7 @@ package runner
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/darkLord19/foglet/internal/branchname"
@@ -10,11 +11,68 @@ import (
 	"github.com/darkLord19/foglet/internal/state"
 )
 
+// SettingBranchNameRegex holds an optional pattern every session branch name
+// must match, on top of the built-in rules. Unset or malformed means no
+// extra constraint.
+const SettingBranchNameRegex = "branch_name_regex"
+
+// SettingDefaultUser is the settings key for the name a branch_prefix's
+// {user} token expands to when a launch does not name a user.
+const SettingDefaultUser = "default_user"
+
 // ResolveBranch resolves a unique branch name for a session.
 // If requested is non-empty, it validates and returns it.
-// Otherwise, it generates a slug from the prompt and ensures uniqueness.
-func (r *Runner) ResolveBranch(repoPath, requested, prompt string) (string, error) {
-	return branchname.Resolve(requested, r.branchPrefix(), prompt, git.New(repoPath).BranchExists)
+// Otherwise, it generates a slug from the prompt under branch_prefix, with
+// the prefix's tokens filled from vars, and ensures uniqueness. vars.User
+// falls back to default_user.
+// Either way the name must also match branch_name_regex when one is set.
+func (r *Runner) ResolveBranch(repoPath, requested, prompt string, vars branchname.PrefixVars) (string, error) {
+	var prefix string
+	if strings.TrimSpace(requested) == "" {
+		if strings.TrimSpace(vars.User) == "" {
+			vars.User = r.defaultUser()
+		}
+		var err error
+		if prefix, err = branchname.ExpandPrefix(r.branchPrefix(), vars); err != nil {
+			return "", err
+		}
+	}
+	branch, err := branchname.Resolve(requested, prefix, prompt, git.New(repoPath).BranchExists)
+	if err != nil {
+		return "", err
+	}
+	if pattern := r.branchNamePattern(); pattern != nil && !pattern.MatchString(branch) {
+		return "", fmt.Errorf("branch name %q must match %s", branch, pattern)
+	}
+	return branch, nil
+}
+
+// branchNamePattern returns the configured branch name pattern, or nil.
+func (r *Runner) branchNamePattern() *regexp.Regexp {
+	if r == nil || r.settings == nil {
+		return nil
+	}
+	stored, found, err := r.settings.GetSetting(SettingBranchNameRegex)
+	if err != nil || !found || strings.TrimSpace(stored) == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(strings.TrimSpace(stored))
+	if err != nil {
+		return nil
+	}
+	return pattern
+}
+
+// defaultUser returns default_user, or "" when unset.
+func (r *Runner) defaultUser() string {
+	if r == nil || r.settings == nil {
+		return ""
+	}
+	stored, found, err := r.settings.GetSetting(SettingDefaultUser)
+	if err != nil || !found {
+		return ""
+	}
+	return strings.TrimSpace(stored)
 }
 
 // branchPrefix returns the configured branch prefix, or "" to accept the default.
@@ -30,52 +88,198 @@ func (r *Runner) branchPrefix() string {
 }
 
 // SessionDiff returns the diff stat and diff patch for a session's branch
-// against its base branch.
+// against its base branch. Once a run is accepted the diff stops at that run's
+// work, so later runs do not change what the session reports as its result.
 func (r *Runner) SessionDiff(sessionID string) (diffStat, diffPatch string, err error) {
-	if r.runs == nil {
-		return "", "", errors.New("state store not configured")
-	}
-	session, found, err := r.runs.GetSession(sessionID)
+	session, worktreePath, baseBranch, err := r.sessionBranchContext(sessionID)
 	if err != nil {
 		return "", "", err
 	}
-	if !found {
-		return "", "", fmt.Errorf("session %q: %w", sessionID, state.ErrNotFound)
+
+	target := session.Branch
+	if session.AcceptedRunID != "" {
+		target, err = r.acceptedRunRef(session.ID, session.AcceptedRunID, baseBranch)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
-	repo, found, err := r.repos.GetRepoByName(session.RepoName)
+	g := git.New(worktreePath)
+	diffRef := fmt.Sprintf("%s...%s", baseBranch, target)
+
+	stat, err := g.DiffStat(diffRef)
 	if err != nil {
-		return "", "", err
+		return "", "", fmt.Errorf("git diff stat: %w", err)
 	}
-	if !found {
-		return "", "", fmt.Errorf("repo %q: %w", session.RepoName, state.ErrNotFound)
+
+	patch, err := g.Diff(diffRef)
+	if err != nil {
+		return "", "", fmt.Errorf("git diff: %w", err)
 	}
 
-	worktreePath := strings.TrimSpace(session.WorktreePath)
-	if latest, found, err := r.runs.GetLatestRun(session.ID); err == nil && found && strings.TrimSpace(latest.WorktreePath) != "" {
-		worktreePath = strings.TrimSpace(latest.WorktreePath)
+	return strings.TrimSpace(stat), strings.TrimSpace(patch), nil
+}
+
+// RunDiff returns what a single run committed: the diff from the commit
+// before its work to run.CommitSHA, with that starting commit's SHA. The
+// starting commit is the commit of the session's previous committing run when
+// the run built on it, else the run's parent commit, so a self-review fix
+// commit is included with the work it reviewed. A run that committed nothing
+// has an empty diff.
+func (r *Runner) RunDiff(run state.Run) (from, diffStat, diffPatch string, err error) {
+	_, worktreePath, _, err := r.sessionBranchContext(run.SessionID)
+	if err != nil {
+		return "", "", "", err
 	}
-	if worktreePath == "" {
-		return "", "", errors.New("session has no worktree path")
+	sha := strings.TrimSpace(run.CommitSHA)
+	if sha == "" {
+		return "", "", "", nil
+	}
+
+	g := git.New(worktreePath)
+	if from, err = g.ResolveCommit(sha + "^"); err != nil {
+		return "", "", "", fmt.Errorf("resolve parent of %s: %w", sha, err)
+	}
+	runs, err := r.runs.ListRuns(run.SessionID)
+	if err != nil {
+		return "", "", "", err
+	}
+	seen := false
+	for _, prev := range runs { // newest first
+		if prev.ID == run.ID {
+			seen = true
+			continue
+		}
+		prevSHA := strings.TrimSpace(prev.CommitSHA)
+		if !seen || prevSHA == "" || prevSHA == sha {
+			continue
+		}
+		// A squashed or rebased session may no longer contain it
+		if g.IsAncestor(prevSHA, sha) {
+			from = prevSHA
+		}
+		break
 	}
 
-	baseBranch := strings.TrimSpace(repo.DefaultBranch)
-	if baseBranch == "" {
-		baseBranch = "main"
+	diffRef := fmt.Sprintf("%s..%s", from, sha)
+	stat, err := g.DiffStat(diffRef)
+	if err != nil {
+		return "", "", "", fmt.Errorf("git diff stat: %w", err)
+	}
+	patch, err := g.Diff(diffRef)
+	if err != nil {
+		return "", "", "", fmt.Errorf("git diff: %w", err)
+	}
+	return from, strings.TrimSpace(stat), strings.TrimSpace(patch), nil
+}
+
+// SessionUntracked returns the files in a session's worktree that git neither
+// tracks nor ignores, with a patch adding each one. These are changes a run
+// made but never committed, which SessionDiff cannot see.
+func (r *Runner) SessionUntracked(sessionID string) (files []string, patch string, err error) {
+	_, worktreePath, _, err := r.sessionBranchContext(sessionID)
+	if err != nil {
+		return nil, "", err
 	}
 
 	g := git.New(worktreePath)
-	diffRef := fmt.Sprintf("%s...%s", baseBranch, session.Branch)
+	files, err = g.UntrackedFiles()
+	if err != nil {
+		return nil, "", fmt.Errorf("git ls-files: %w", err)
+	}
+	patches := make([]string, 0, len(files))
+	for _, file := range files {
+		p, err := g.UntrackedDiff(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("git diff %s: %w", file, err)
+		}
+		patches = append(patches, p)
+	}
+	return files, strings.Join(patches, "\n"), nil
+}
 
-	stat, err := g.DiffStat(diffRef)
+// SessionCommits returns the commits on a session's branch that are not on its
+// base branch, newest first. A session that has not committed anything yet
+// returns an empty slice.
+func (r *Runner) SessionCommits(sessionID string) ([]git.Commit, error) {
+	session, worktreePath, baseBranch, err := r.sessionBranchContext(sessionID)
 	if err != nil {
-		return "", "", fmt.Errorf("git diff stat: %w", err)
+		return nil, err
 	}
 
-	patch, err := g.Diff(diffRef)
+	commits, err := git.New(worktreePath).Log(fmt.Sprintf("%s..%s", baseBranch, session.Branch))
 	if err != nil {
-		return "", "", fmt.Errorf("git diff: %w", err)
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	if commits == nil {
+		commits = []git.Commit{}
 	}
+	return commits, nil
+}
 
-	return strings.TrimSpace(stat), strings.TrimSpace(patch), nil
+// acceptedRunRef returns the commit the session's branch was at when the
+// accepted run finished: that run's commit, or the newest earlier one when it
+// committed nothing. With no commit at all it returns baseBranch, an empty diff.
+func (r *Runner) acceptedRunRef(sessionID, acceptedRunID, baseBranch string) (string, error) {
+	runs, err := r.runs.ListRuns(sessionID)
+	if err != nil {
+		return "", err
+	}
+	seen := false
+	for _, run := range runs { // newest first
+		if run.ID == acceptedRunID {
+			seen = true
+		}
+		if seen && strings.TrimSpace(run.CommitSHA) != "" {
+			return strings.TrimSpace(run.CommitSHA), nil
+		}
+	}
+	if !seen {
+		return "", fmt.Errorf("accepted run %q: %w", acceptedRunID, state.ErrNotFound)
+	}
+	return baseBranch, nil
+}
+
+// errScratchNoBranch is returned for branch operations on an ephemeral session.
+var errScratchNoBranch = errors.New("scratch sessions have no branch")
+
+// sessionBranchContext loads a session together with the worktree its latest
+// run used and the base branch its work is compared against. A session whose
+// worktree was pruned gets ErrWorktreePruned rather than git errors from a
+// directory that is gone.
+func (r *Runner) sessionBranchContext(sessionID string) (session state.Session, worktreePath, baseBranch string, err error) {
+	if r.runs == nil {
+		return state.Session{}, "", "", errors.New("state store not configured")
+	}
+	session, found, err := r.runs.GetSession(sessionID)
+	if err != nil {
+		return state.Session{}, "", "", err
+	}
+	if !found {
+		return state.Session{}, "", "", fmt.Errorf("session %q: %w", sessionID, state.ErrNotFound)
+	}
+	if session.Ephemeral {
+		return state.Session{}, "", "", errScratchNoBranch
+	}
+	if session.WorktreePrunedAt != nil {
+		return state.Session{}, "", "", fmt.Errorf("%w: session %q", ErrWorktreePruned, sessionID)
+	}
+
+	repo, found, err := r.repos.GetRepoByName(session.RepoName)
+	if err != nil {
+		return state.Session{}, "", "", err
+	}
+	if !found {
+		return state.Session{}, "", "", fmt.Errorf("repo %q: %w", session.RepoName, state.ErrNotFound)
+	}
+
+	worktreePath = strings.TrimSpace(session.WorktreePath)
+	if latest, found, err := r.runs.GetLatestRun(session.ID); err == nil && found && strings.TrimSpace(latest.WorktreePath) != "" {
+		worktreePath = strings.TrimSpace(latest.WorktreePath)
+	}
+	if worktreePath == "" {
+		return state.Session{}, "", "", errors.New("session has no worktree path")
+	}
+
+	return session, worktreePath, r.ResolveBaseBranch("", repo), nil
 }
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestSessionCommitsListsBranchHistory(t *testing.T) {
+	wt := initTestWorktree(t)
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = wt
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	run("branch", "-M", "main")
+	run("checkout", "-b", "fog/test")
+
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"] = &state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/test", WorktreePath: wt}
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+
+	commits, err := r.SessionCommits("session-1")
+	if err != nil {
+		t.Fatalf("SessionCommits before any work: %v", err)
+	}
+	if commits == nil || len(commits) != 0 {
+		t.Fatalf("expected an empty, non-nil slice, got %#v", commits)
+	}
+
+	writeFile(t, wt, "one.txt", "1")
+	run("add", ".")
+	run("commit", "-m", "feat: run one")
+	writeFile(t, wt, "two.txt", "2")
+	run("add", ".")
+	run("commit", "-m", "feat: run two")
+
+	commits, err = r.SessionCommits("session-1")
+	if err != nil {
+		t.Fatalf("SessionCommits: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(commits))
+	}
+	if commits[0].Message != "feat: run two" || commits[1].Message != "feat: run one" {
+		t.Fatalf("expected newest first, got %q then %q", commits[0].Message, commits[1].Message)
+	}
+}
+
+func TestSessionDiffStopsAtAcceptedRun(t *testing.T) {
+	wt := initTestWorktree(t)
+	gitOut(t, wt, "branch", "-M", "main")
+	gitOut(t, wt, "checkout", "-b", "fog/test")
+	writeFile(t, wt, "one.txt", "1")
+	gitOut(t, wt, "add", ".")
+	gitOut(t, wt, "commit", "-m", "feat: run one")
+	first := gitOut(t, wt, "rev-parse", "HEAD")
+	writeFile(t, wt, "two.txt", "2")
+	gitOut(t, wt, "add", ".")
+	gitOut(t, wt, "commit", "-m", "feat: run two")
+
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"] = &state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/test", WorktreePath: wt, AcceptedRunID: "run-1"}
+	now := time.Now()
+	store.runs["run-1"] = &state.Run{ID: "run-1", SessionID: "session-1", CommitSHA: first, CreatedAt: now.Add(-2 * time.Minute)}
+	store.runs["run-2"] = &state.Run{ID: "run-2", SessionID: "session-1", CreatedAt: now.Add(-time.Minute)}
+	store.runs["run-3"] = &state.Run{ID: "run-3", SessionID: "session-1", CommitSHA: gitOut(t, wt, "rev-parse", "HEAD"), CreatedAt: now}
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+
+	stat, _, err := r.SessionDiff("session-1")
+	if err != nil {
+		t.Fatalf("SessionDiff: %v", err)
+	}
+	if !strings.Contains(stat, "one.txt") || strings.Contains(stat, "two.txt") {
+		t.Fatalf("diff should stop at run-1's commit, got stat:\n%s", stat)
+	}
+
+	// run-2 committed nothing, so accepting it shows the branch as run-1 left it.
+	store.sessions["session-1"].AcceptedRunID = "run-2"
+	stat, _, err = r.SessionDiff("session-1")
+	if err != nil {
+		t.Fatalf("SessionDiff: %v", err)
+	}
+	if !strings.Contains(stat, "one.txt") || strings.Contains(stat, "two.txt") {
+		t.Fatalf("diff for a run without a commit should fall back to the prior one, got stat:\n%s", stat)
+	}
+}
+
+func TestRunDiffShowsOnlyThatRunsWork(t *testing.T) {
+	wt := initTestWorktree(t)
+	gitOut(t, wt, "branch", "-M", "main")
+	gitOut(t, wt, "checkout", "-b", "fog/test")
+	writeFile(t, wt, "one.txt", "1")
+	gitOut(t, wt, "add", ".")
+	gitOut(t, wt, "commit", "-m", "feat: run one")
+	first := gitOut(t, wt, "rev-parse", "HEAD")
+	// run-3 made two commits, as a self-review fix does
+	writeFile(t, wt, "two.txt", "2")
+	gitOut(t, wt, "add", ".")
+	gitOut(t, wt, "commit", "-m", "feat: run three")
+	writeFile(t, wt, "three.txt", "3")
+	gitOut(t, wt, "add", ".")
+	gitOut(t, wt, "commit", "-m", "fix: review")
+	third := gitOut(t, wt, "rev-parse", "HEAD")
+
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"] = &state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/test", WorktreePath: wt}
+	now := time.Now()
+	store.runs["run-1"] = &state.Run{ID: "run-1", SessionID: "session-1", State: "COMPLETED", CommitSHA: first, CreatedAt: now.Add(-2 * time.Minute)}
+	store.runs["run-2"] = &state.Run{ID: "run-2", SessionID: "session-1", State: "COMPLETED", CreatedAt: now.Add(-time.Minute)}
+	store.runs["run-3"] = &state.Run{ID: "run-3", SessionID: "session-1", State: "COMPLETED", CommitSHA: third, CreatedAt: now}
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+
+	from, stat, patch, err := r.RunDiff(*store.runs["run-3"])
+	if err != nil {
+		t.Fatalf("RunDiff(run-3): %v", err)
+	}
+	if from != first || strings.Contains(stat, "one.txt") || !strings.Contains(stat, "two.txt") || !strings.Contains(patch, "three.txt") {
+		t.Fatalf("run-3 diff from %s, stat:\n%s", from, stat)
+	}
+
+	from, stat, _, err = r.RunDiff(*store.runs["run-1"])
+	if err != nil {
+		t.Fatalf("RunDiff(run-1): %v", err)
+	}
+	if from == "" || !strings.Contains(stat, "one.txt") || strings.Contains(stat, "two.txt") {
+		t.Fatalf("run-1 diff from %q, stat:\n%s", from, stat)
+	}
+
+	if from, stat, patch, err = r.RunDiff(*store.runs["run-2"]); err != nil || from != "" || stat != "" || patch != "" {
+		t.Fatalf("run without a commit: %q %q %q %v; want an empty diff", from, stat, patch, err)
+	}
+}
+
+func TestSessionCommitsUnknownSession(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), &fakeTool{name: "claude", available: true}, nil)
+
+	_, err := r.SessionCommits("missing")
+	if !errors.Is(err, state.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSessionUntrackedReportsUncommittedNewFiles(t *testing.T) {
+	wt := initTestWorktree(t)
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"] = &state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/test", WorktreePath: wt}
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+
+	files, patch, err := r.SessionUntracked("session-1")
+	if err != nil {
+		t.Fatalf("SessionUntracked on a clean worktree: %v", err)
+	}
+	if len(files) != 0 || patch != "" {
+		t.Fatalf("expected nothing untracked, got %v %q", files, patch)
+	}
+
+	writeFile(t, wt, "a.txt", "alpha\n")
+	writeFile(t, wt, "b.txt", "beta\n")
+	files, patch, err = r.SessionUntracked("session-1")
+	if err != nil {
+		t.Fatalf("SessionUntracked: %v", err)
+	}
+	if strings.Join(files, ",") != "a.txt,b.txt" {
+		t.Fatalf("unexpected untracked files: %v", files)
+	}
+	if !strings.Contains(patch, "+alpha") || !strings.Contains(patch, "+beta") {
+		t.Fatalf("expected both files in the patch, got:\n%s", patch)
+	}
+}
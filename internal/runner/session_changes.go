@@ -0,0 +1,223 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// Kinds of SessionChange.
+const (
+	SessionChangeStatus   = "status"
+	SessionChangeBusy     = "busy"
+	SessionChangeRun      = "run"
+	SessionChangePR       = "pr"
+	SessionChangeBranch   = "branch"
+	SessionChangePaused   = "paused"
+	SessionChangeArchived = "archived"
+	// SessionChangePruned is the worktree janitor removing the worktree.
+	SessionChangePruned  = "worktree_pruned"
+	SessionChangeDeleted = "deleted"
+)
+
+// SessionChange is one change to what a session list shows, published as the
+// runner writes it. Only the field matching Kind is set; pruned and deleted
+// changes carry none.
+type SessionChange struct {
+	SessionID string    `json:"session_id"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status,omitempty"`
+	Busy      *bool     `json:"busy,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	PRURL     string    `json:"pr_url,omitempty"`
+	Branch    string    `json:"branch,omitempty"`
+	Paused    *bool     `json:"paused,omitempty"`
+	Archived  *bool     `json:"archived,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// sessionHub fans session changes out to subscribers. The zero value is ready
+// to use.
+type sessionHub struct {
+	mu   sync.RWMutex
+	subs map[chan SessionChange]struct{}
+}
+
+func (h *sessionHub) subscribe(buffer int) (<-chan SessionChange, func()) {
+	ch := make(chan SessionChange, max(buffer, 1))
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = make(map[chan SessionChange]struct{})
+	}
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (h *sessionHub) publish(change SessionChange) {
+	change.At = time.Now().UTC()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subs {
+		select {
+		case ch <- change:
+		default: // the subscriber is behind; it misses this change
+		}
+	}
+}
+
+// SubscribeSessionChanges returns a channel receiving every session change
+// this runner makes from now on, and a func that ends the subscription and
+// closes the channel. As with state.Store.SubscribeRunEvents, delivery never
+// blocks a run: a subscriber whose buffer is full misses changes.
+func (r *Runner) SubscribeSessionChanges(buffer int) (<-chan SessionChange, func()) {
+	return r.changes.subscribe(buffer)
+}
+
+// SetSessionPaused pauses or resumes a session. Neither touches a run already
+// in flight.
+func (r *Runner) SetSessionPaused(sessionID string, paused bool) error {
+	if r.runs == nil {
+		return errors.New("state store not configured")
+	}
+	return r.runs.SetSessionPaused(sessionID, paused)
+}
+
+// ArchiveSession hides a session from the default session list, keeping its
+// records. It is the caller's to refuse a session with a run in flight.
+func (r *Runner) ArchiveSession(sessionID string) error {
+	if r.runs == nil {
+		return errors.New("state store not configured")
+	}
+	return r.runs.ArchiveSession(sessionID)
+}
+
+// UnarchiveSession returns an archived session to the default session list.
+func (r *Runner) UnarchiveSession(sessionID string) error {
+	if r.runs == nil {
+		return errors.New("state store not configured")
+	}
+	return r.runs.UnarchiveSession(sessionID)
+}
+
+// DeleteSession removes a session's records: the session, its runs and their
+// events. The worktree is RemoveSessionWorktree's to remove first.
+func (r *Runner) DeleteSession(sessionID string) error {
+	if r.runs == nil {
+		return errors.New("state store not configured")
+	}
+	return r.runs.DeleteSession(sessionID)
+}
+
+// publishingRunStore is a RunStore that publishes a SessionChange after each
+// successful write a session list shows: status, busy flag, new run, PR,
+// branch, paused and archived flags, worktree pruning and deletion. Every
+// session mutation goes through it, including the ones the API makes outside
+// a run, so a subscriber never has to poll to notice one.
+type publishingRunStore struct {
+	RunStore
+	hub *sessionHub
+}
+
+func (s publishingRunStore) UpdateSessionStatus(id, status string) error {
+	if err := s.RunStore.UpdateSessionStatus(id, status); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangeStatus, Status: status})
+	return nil
+}
+
+func (s publishingRunStore) SetSessionBusy(id string, busy bool) error {
+	if err := s.RunStore.SetSessionBusy(id, busy); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangeBusy, Busy: &busy})
+	return nil
+}
+
+func (s publishingRunStore) ClaimSessionBusy(id string) (bool, error) {
+	claimed, err := s.RunStore.ClaimSessionBusy(id)
+	if err != nil || !claimed {
+		return claimed, err
+	}
+	busy := true
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangeBusy, Busy: &busy})
+	return true, nil
+}
+
+func (s publishingRunStore) SetSessionPRURL(id, prURL string) error {
+	if err := s.RunStore.SetSessionPRURL(id, prURL); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangePR, PRURL: prURL})
+	return nil
+}
+
+func (s publishingRunStore) CreateRun(run state.Run) error {
+	if err := s.RunStore.CreateRun(run); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: run.SessionID, Kind: SessionChangeRun, RunID: run.ID})
+	return nil
+}
+
+func (s publishingRunStore) SetSessionBranch(id, branch string) error {
+	if err := s.RunStore.SetSessionBranch(id, branch); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangeBranch, Branch: branch})
+	return nil
+}
+
+func (s publishingRunStore) SetSessionPaused(id string, paused bool) error {
+	if err := s.RunStore.SetSessionPaused(id, paused); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangePaused, Paused: &paused})
+	return nil
+}
+
+func (s publishingRunStore) ArchiveSession(id string) error {
+	if err := s.RunStore.ArchiveSession(id); err != nil {
+		return err
+	}
+	archived := true
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangeArchived, Archived: &archived})
+	return nil
+}
+
+func (s publishingRunStore) UnarchiveSession(id string) error {
+	if err := s.RunStore.UnarchiveSession(id); err != nil {
+		return err
+	}
+	archived := false
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangeArchived, Archived: &archived})
+	return nil
+}
+
+func (s publishingRunStore) MarkSessionWorktreePruned(id string) error {
+	if err := s.RunStore.MarkSessionWorktreePruned(id); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangePruned})
+	return nil
+}
+
+func (s publishingRunStore) DeleteSession(id string) error {
+	if err := s.RunStore.DeleteSession(id); err != nil {
+		return err
+	}
+	s.hub.publish(SessionChange{SessionID: id, Kind: SessionChangeDeleted})
+	return nil
+}
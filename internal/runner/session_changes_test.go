@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSessionChangesPublishedOnWrite(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+
+	changes, unsubscribe := r.SubscribeSessionChanges(8)
+	if err := r.runs.SetSessionBusy("session-1", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.runs.UpdateSessionStatus("session-1", "RUNNING"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.runs.SetSessionPRURL("session-1", "https://github.com/acme/api/pull/7"); err != nil {
+		t.Fatal(err)
+	}
+	// A failed write publishes nothing.
+	store.fail("UpdateSessionStatus", errors.New("disk full"))
+	if err := r.runs.UpdateSessionStatus("session-1", "FAILED"); err == nil {
+		t.Fatal("UpdateSessionStatus succeeded despite the store failing")
+	}
+
+	want := []SessionChange{
+		{SessionID: "session-1", Kind: SessionChangeBusy},
+		{SessionID: "session-1", Kind: SessionChangeStatus, Status: "RUNNING"},
+		{SessionID: "session-1", Kind: SessionChangePR, PRURL: "https://github.com/acme/api/pull/7"},
+	}
+	for i, w := range want {
+		got := <-changes
+		if got.SessionID != w.SessionID || got.Kind != w.Kind || got.Status != w.Status || got.PRURL != w.PRURL || got.At.IsZero() {
+			t.Fatalf("change %d = %+v, want %+v", i, got, w)
+		}
+		if got.Kind == SessionChangeBusy && (got.Busy == nil || !*got.Busy) {
+			t.Fatalf("busy change = %+v, want busy true", got)
+		}
+	}
+	select {
+	case extra := <-changes:
+		t.Fatalf("unexpected change %+v", extra)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-changes; ok {
+		t.Fatal("channel still open after unsubscribe")
+	}
+	// Publishing with no subscribers, or to a full one, never blocks.
+	slow, stop := r.SubscribeSessionChanges(1)
+	defer stop()
+	for range 3 {
+		if err := r.runs.SetSessionBusy("session-1", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(slow) != 1 {
+		t.Fatalf("slow subscriber holds %d changes, want 1", len(slow))
+	}
+}
+
+func TestSessionChangesPublishedOutsideRuns(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+
+	changes, unsubscribe := r.SubscribeSessionChanges(16)
+	defer unsubscribe()
+	steps := []func() error{
+		func() error { return r.SetSessionPaused("session-1", true) },
+		func() error { return r.ArchiveSession("session-1") },
+		func() error { return r.UnarchiveSession("session-1") },
+		func() error { return r.runs.SetSessionBranch("session-1", "fog/renamed") },
+		func() error { return r.runs.MarkSessionWorktreePruned("session-1") },
+		func() error { return r.DeleteSession("session-1") },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	wantKinds := []string{
+		SessionChangePaused, SessionChangeArchived, SessionChangeArchived,
+		SessionChangeBranch, SessionChangePruned, SessionChangeDeleted,
+	}
+	for i, kind := range wantKinds {
+		got := <-changes
+		if got.SessionID != "session-1" || got.Kind != kind {
+			t.Fatalf("change %d = %+v, want kind %s", i, got, kind)
+		}
+		switch i {
+		case 0:
+			if got.Paused == nil || !*got.Paused {
+				t.Errorf("paused change = %+v, want paused true", got)
+			}
+		case 1, 2:
+			if got.Archived == nil || *got.Archived != (i == 1) {
+				t.Errorf("archive change %d = %+v", i, got)
+			}
+		case 3:
+			if got.Branch != "fog/renamed" {
+				t.Errorf("branch change = %+v, want fog/renamed", got)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// ErrSessionBusy is returned when a session cannot be changed because a run
+// is executing in it.
+var ErrSessionBusy = errors.New("session is busy")
+
+// RemoveSessionWorktree removes a session's worktree ahead of deleting the
+// session. It refuses while a run is in flight, whether the store says so or
+// the runner still holds the run's cancel func, and refuses a worktree with
+// uncommitted changes with ErrDirtyWorktree unless force is set.
+//
+// Unlike RemoveSessionArtifacts the branch is kept: committed work stays
+// reachable after the session is gone. A worktree that is already missing is
+// not an error.
+func (r *Runner) RemoveSessionWorktree(sessionID string, force bool) error {
+	if r.runs == nil || r.repos == nil {
+		return errors.New("state store not configured")
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	session, found, err := r.runs.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("session %q: %w", sessionID, state.ErrNotFound)
+	}
+
+	r.mu.Lock()
+	_, active := r.active[sessionID]
+	r.mu.Unlock()
+	if session.Busy || active {
+		return fmt.Errorf("%w: session %q has a run in progress", ErrSessionBusy, sessionID)
+	}
+
+	wt := strings.TrimSpace(session.WorktreePath)
+	if wt == "" {
+		return nil
+	}
+	if _, err := os.Stat(wt); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if !force {
+		dirty, err := git.New(wt).IsDirty()
+		if err != nil {
+			return fmt.Errorf("git status failed: %w", err)
+		}
+		if dirty {
+			return ErrDirtyWorktree
+		}
+	}
+
+	repo, found, err := r.repos.GetRepoByName(session.RepoName)
+	if err != nil {
+		return err
+	}
+	if !found || strings.TrimSpace(repo.BaseWorktreePath) == "" {
+		return fmt.Errorf("%w: %s", ErrUnknownRepo, session.RepoName)
+	}
+	if err := git.New(repo.BaseWorktreePath).RemoveWorktree(wt, force); err != nil {
+		return fmt.Errorf("remove worktree %s: %w", wt, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// normalizeSessionEnv validates a session's extra environment. Names are
+// trimmed and must be non-empty without '=' or NUL; values must not contain
+// NUL, which no environment can hold. An empty map is returned as nil.
+func normalizeSessionEnv(env map[string]string) (map[string]string, error) {
+	if len(env) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(env))
+	for rawName, value := range env {
+		name := strings.TrimSpace(rawName)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("env: variable name must not be empty")
+		case strings.ContainsAny(name, "=\x00"):
+			return nil, fmt.Errorf("env: variable name %q must not contain '=' or NUL", rawName)
+		case strings.ContainsRune(value, 0):
+			return nil, fmt.Errorf("env: value of %s must not contain NUL", name)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// envEntries turns a session environment into KEY=value entries, sorted so
+// runs are reproducible.
+func envEntries(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(env))
+	for name, value := range env {
+		out = append(out, name+"="+value)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// shellEnv is the environment for setup and validation commands: the daemon's
+// own, with the session's entries added last so they take precedence. nil
+// means inherit unchanged.
+func shellEnv(extra []string) []string {
+	if len(extra) == 0 {
+		return nil
+	}
+	return append(os.Environ(), extra...)
+}
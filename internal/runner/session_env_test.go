@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSessionEnv(t *testing.T) {
+	got, err := normalizeSessionEnv(map[string]string{" NODE_ENV ": "test", "EMPTY": ""})
+	if err != nil {
+		t.Fatalf("normalizeSessionEnv: %v", err)
+	}
+	if len(got) != 2 || got["NODE_ENV"] != "test" {
+		t.Fatalf("env = %v", got)
+	}
+
+	for _, env := range []map[string]string{
+		{"": "x"},
+		{"A=B": "x"},
+		{"A\x00": "x"},
+		{"A": "x\x00y"},
+	} {
+		if _, err := normalizeSessionEnv(env); err == nil {
+			t.Errorf("normalizeSessionEnv(%q) should fail", env)
+		}
+	}
+}
+
+func TestExecuteSessionRunPassesSessionEnv(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(store, tool, nil)
+
+	wt := initTestWorktree(t)
+	session := testSession(wt)
+	session.Env = map[string]string{"FOG_TEST_TARGET": "web", "NODE_ENV": "test"}
+
+	if err := r.executeSessionRun(session, testRun(wt), sessionRunOptions{
+		Prompt:     "build it",
+		SetupCmd:   `printf '%s' "$FOG_TEST_TARGET" > setup.out`,
+		BaseBranch: "main",
+		CommitMsg:  "feat: build it",
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+
+	if env := tool.request().Env; !slices.Equal(env, []string{"FOG_TEST_TARGET=web", "NODE_ENV=test"}) {
+		t.Errorf("tool env = %v", env)
+	}
+	out, err := os.ReadFile(filepath.Join(wt, "setup.out"))
+	if err != nil {
+		t.Fatalf("read setup output: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "web" {
+		t.Errorf("setup saw FOG_TEST_TARGET=%q, want web", out)
+	}
+}
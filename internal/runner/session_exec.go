@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 
+	"github.com/darkLord19/foglet/internal/ai"
 	"github.com/darkLord19/foglet/internal/state"
 	"github.com/darkLord19/foglet/internal/util"
 )
@@ -32,9 +35,40 @@ type sessionRunOptions struct {
 	SetupCmd    string
 	Validate    bool
 	ValidateCmd string
-	BaseBranch  string
-	CommitMsg   string
-	PRTitle     string
+	// ValidateSuccessCodes are non-zero exit codes of ValidateCmd that still
+	// count as a pass. Zero always passes.
+	ValidateSuccessCodes []int
+	BaseBranch           string
+	CommitMsg            string
+	// CommitMsgMode is one of the CommitMsgMode constants, or empty to pick
+	// by commit_template; see StartSessionOptions.CommitMsgMode.
+	CommitMsgMode string
+	PRTitle       string
+	// Ephemeral skips commit, push and PR and removes the worktree from
+	// RepoPath once the run ends.
+	Ephemeral bool
+	RepoPath  string
+	// FocusPaths are appended to the tool prompt; see
+	// StartSessionOptions.FocusPaths.
+	FocusPaths []string
+	// Attachments and ContextText are put ahead of the tool prompt; see
+	// StartSessionOptions.Attachments.
+	Attachments []string
+	ContextText string
+	// SelfReview runs the review pass after a run that committed; see
+	// StartSessionOptions.SelfReview.
+	SelfReview bool
+	// Timeout overrides run_timeout_seconds for this run; see
+	// StartSessionOptions.Timeout.
+	Timeout time.Duration
+	// ConversationID is the tool conversation to resume when
+	// HasConversationID is set, empty meaning a fresh one. Otherwise the
+	// latest conversation recorded in the session is resumed.
+	ConversationID    string
+	HasConversationID bool
+	// RestoreStash pops the stash entry the dirty-worktree preflight made
+	// once the run ends; see DirtyPolicyStash.
+	RestoreStash bool
 }
 
 func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts sessionRunOptions) (retErr error) {
@@ -61,18 +95,43 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 	if strings.TrimSpace(run.WorktreePath) == "" {
 		return errors.New("run worktree path is required")
 	}
-	ctx, cancel := context.WithCancel(r.baseCtx)
+	if opts.Ephemeral {
+		defer r.removeScratchWorktree(run, opts.RepoPath)
+	}
+	if opts.RestoreStash {
+		defer r.restoreStash(run)
+	}
+	// A hung tool or setup command would otherwise hold the session busy
+	// forever, blocking every follow-up. The clock stops while the run waits
+	// for a run slot, so time spent QUEUED behind other runs never counts
+	// against it; runCtx carries only cancellation.
+	timeout := r.runTimeout(opts)
+	runCtx, cancel := context.WithCancel(r.baseCtx)
 	r.registerActiveRun(session.ID, run.ID, cancel)
 	defer func() {
 		r.clearActiveRun(session.ID, run.ID)
 		cancel()
 	}()
+	ctx, stopClock := withRunClock(runCtx, timeout, timeout)
+	defer func() { stopClock() }()
+	clockStarted := time.Now()
+	slog.Info("run started", "session_id", session.ID, "run_id", run.ID, "repo", session.RepoName, "tool", session.Tool)
 
 	fail := func(phase string, err error) error {
+		// The deadline is checked on ctx rather than err: a tool killed by
+		// it usually reports only its exit status.
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		if timedOut {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
 		terminalState := "FAILED"
 		eventType := "error"
 		message := phase + ": " + err.Error()
-		if isCanceledError(err) {
+		switch {
+		case timedOut:
+			eventType = "timeout"
+			message = fmt.Sprintf("%s: timed out after %s", phase, timeout)
+		case isCanceledError(err):
 			terminalState = "CANCELLED"
 			eventType = "cancelled"
 			message = phase + ": canceled"
@@ -84,10 +143,11 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 		})
 		_ = r.runs.CompleteRun(run.ID, terminalState, "", "", err.Error())
 		_ = r.updateSessionStatusIfLatest(session.ID, run.ID, terminalState)
+		slog.Warn("run ended", "session_id", session.ID, "run_id", run.ID, "state", terminalState, "phase", phase, "err", err)
 		if r.notificationsEnabled() {
 			title := "Fog Session Failed"
 			msg := fmt.Sprintf("Failed on %s (%s): %v", session.Branch, session.RepoName, err)
-			if isCanceledError(err) {
+			if !timedOut && isCanceledError(err) {
 				title = "Fog Session Cancelled"
 				msg = fmt.Sprintf("Cancelled on %s (%s)", session.Branch, session.RepoName)
 			}
@@ -105,11 +165,31 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 			Type:    "setup",
 			Message: "Running setup command",
 		})
-		if err := r.runShell(ctx, run.WorktreePath, opts.SetupCmd); err != nil {
+		if err := r.runShell(ctx, run.WorktreePath, opts.SetupCmd, envEntries(session.Env)); err != nil {
 			return fail("setup", err)
 		}
 	}
 
+	if !opts.Ephemeral {
+		r.recordBranchStatus(run.ID, run.WorktreePath, opts.BaseBranch)
+	}
+
+	remaining := timeout - time.Since(clockStarted)
+	stopClock()
+	releaseSlot, slotErr := r.acquireRunSlot(runCtx, func() {
+		_ = r.setRunPhase(session.ID, run.ID, "QUEUED")
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "queued",
+			Message: fmt.Sprintf("Waiting for one of %d run slots", r.MaxConcurrentRuns()),
+		})
+	})
+	if slotErr != nil {
+		return fail("queue", slotErr)
+	}
+	defer releaseSlot()
+	ctx, stopClock = withRunClock(runCtx, timeout, remaining)
+
 	if err := r.setRunPhase(session.ID, run.ID, "AI_RUNNING"); err != nil {
 		return err
 	}
@@ -118,18 +198,54 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 		Type:    "ai_start",
 		Message: "Running AI tool",
 	})
-	streamWriter := newRunStreamWriter(r.runs, run.ID)
-	conversationID := r.lookupConversationID(session.ID, run.ID)
-	aiOutput, nextConversationID, err := r.runToolWithOptions(
-		ctx,
-		session.Tool,
-		run.WorktreePath,
-		opts.Prompt+commitMsgInstructions,
-		session.Model,
-		conversationID,
-		streamWriter.Append,
-	)
-	streamWriter.Flush()
+	toolPrompt := r.attachmentContext(run.ID, run.WorktreePath, opts.Attachments, opts.ContextText) +
+		opts.Prompt + focusPathsInstructions(opts.FocusPaths) + commitMsgInstructions
+	conversationID := opts.ConversationID
+	if !opts.HasConversationID {
+		conversationID = r.lookupConversationID(session.ID, run.ID)
+	}
+	start, startErr := runStartPoint(run.WorktreePath)
+	var aiOutput, nextConversationID string
+	var usage ai.Usage
+	var err error
+	for attempt := 0; ; attempt++ {
+		streamWriter := r.newRunStreamWriter(run.ID)
+		aiOutput, nextConversationID, usage, err = r.runToolWithOptions(
+			ctx,
+			run.ID,
+			session.Tool,
+			run.WorktreePath,
+			toolPrompt,
+			session.Model,
+			conversationID,
+			envEntries(session.Env),
+			streamWriter.Append,
+		)
+		streamWriter.Close()
+		// A failed attempt was still paid for.
+		r.recordRunUsage(run.ID, usage)
+		if !r.shouldRetryAI(err, attempt) {
+			break
+		}
+		// Each retry is a fresh attempt: a new conversation, in a worktree
+		// put back to the commit and files the run started from, with the
+		// same prompt and attachments. Nothing the failed attempt said or wrote carries
+		// over into it.
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   run.ID,
+			Type:    "run_retry",
+			Message: runRetryMessage(err, attempt+1, r.RunRetryCount()),
+		})
+		if waitErr := r.waitRunRetry(ctx, attempt+1); waitErr != nil {
+			err = waitErr
+			break
+		}
+		if resetErr := resetForRetry(run.WorktreePath, start, startErr); resetErr != nil {
+			err = resetErr
+			break
+		}
+		conversationID = ""
+	}
 	if err != nil {
 		if strings.TrimSpace(aiOutput) != "" {
 			_ = r.runs.AppendRunEvent(state.RunEvent{
@@ -159,21 +275,43 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 		if err := r.setRunPhase(session.ID, run.ID, "VALIDATING"); err != nil {
 			return err
 		}
-		if err := r.runShell(ctx, run.WorktreePath, opts.ValidateCmd); err != nil {
+		code, output, err := r.runShellAllowing(ctx, run.WorktreePath, opts.ValidateCmd, envEntries(session.Env), opts.ValidateSuccessCodes)
+		r.recordValidateOutput(run.ID, code, output, err)
+		if err != nil {
+			if !isCanceledError(err) {
+				msg, discardErr := r.discardAfterFailedValidation(run.WorktreePath)
+				if discardErr != nil {
+					msg = "Could not discard changes after failed validation: " + discardErr.Error()
+				}
+				if msg != "" {
+					_ = r.runs.AppendRunEvent(state.RunEvent{
+						RunID:   run.ID,
+						Type:    "cleanup",
+						Message: msg,
+					})
+				}
+			}
 			return fail("validate", err)
 		}
+		if code != 0 {
+			_ = r.runs.AppendRunEvent(state.RunEvent{
+				RunID:   run.ID,
+				Type:    "validate",
+				Message: fmt.Sprintf("Validation exited %d, accepted as success", code),
+			})
+		}
 	}
 
-	if err := r.setRunPhase(session.ID, run.ID, "COMMITTED"); err != nil {
-		return err
+	if opts.Ephemeral {
+		return r.completeRun(session, run, "", "")
 	}
 
-	extractedMsg := opts.CommitMsg
-	if extractedMsg == "" {
-		extractedMsg = extractCommitMessage(aiOutput)
+	if err := r.setRunPhase(session.ID, run.ID, "COMMITTED"); err != nil {
+		return err
 	}
 
-	commitSHA, commitMsg, changed, err := r.commitSessionChanges(ctx, session.Tool, run.WorktreePath, opts.Prompt, extractedMsg)
+	plannedMsg := r.commitMessageFor(session, run, opts, aiOutput)
+	commitSHA, commitMsg, changed, err := r.commitSessionChanges(ctx, session.Tool, run.WorktreePath, opts.Prompt, plannedMsg, session.IssueRef)
 	if err != nil {
 		return fail("commit", err)
 	}
@@ -184,36 +322,80 @@ func (r *Runner) executeSessionRun(session state.Session, run state.Run, opts se
 			Message: "No changes to commit",
 		})
 	}
+	if changed && opts.SelfReview {
+		reviewSHA, err := r.selfReview(ctx, session, run, opts)
+		if err != nil {
+			return fail("review", err)
+		}
+		if reviewSHA != "" {
+			commitSHA = reviewSHA
+		}
+	}
 
 	// Push only when PR mode is enabled or a PR already exists for this session.
 	if changed && (session.AutoPR || strings.TrimSpace(session.PRURL) != "") {
+		var lease string
+		if strategy := session.CommitStrategy; strategy == CommitStrategySquash || strategy == CommitStrategySquashForce {
+			squash, err := r.squashSessionCommits(ctx, run.WorktreePath, opts.BaseBranch, session.Branch, commitMsg, strategy == CommitStrategySquashForce)
+			if err != nil {
+				return fail("squash", err)
+			}
+			if squash.Squashed > 0 {
+				commitSHA, lease = squash.SHA, squash.Lease
+				_ = r.runs.AppendRunEvent(state.RunEvent{
+					RunID:   run.ID,
+					Type:    "commit",
+					Message: fmt.Sprintf("Squashed %d commits into one", squash.Squashed),
+				})
+			}
+		}
 		setUpstream := strings.TrimSpace(session.PRURL) == ""
-		if err := r.pushBranch(ctx, run.WorktreePath, session.Branch, setUpstream); err != nil {
+		if lease != "" {
+			if err := r.forcePushBranch(ctx, run.WorktreePath, session.Branch, lease); err != nil {
+				return fail("push", err)
+			}
+		} else if err := r.pushBranch(ctx, run.WorktreePath, session.Branch, setUpstream); err != nil {
 			return fail("push", err)
 		}
 		if session.AutoPR && strings.TrimSpace(session.PRURL) == "" {
-			prURL, err := r.createDraftPR(ctx, run.WorktreePath, opts.BaseBranch, session.Branch, opts.Prompt, session.Tool, session.ID, opts.PRTitle)
-			if err != nil {
+			prURL, err := r.createDraftPR(ctx, session, run.WorktreePath, opts.BaseBranch, opts.Prompt, opts.PRTitle)
+			switch {
+			case isCanceledError(err):
 				return fail("create-pr", err)
+			case err != nil:
+				// The branch is pushed, so the work is safe. Failing the run here
+				// used to throw away a good result over a flaky gh call.
+				r.recordPendingPR(run.ID, pendingPR{
+					BaseBranch: opts.BaseBranch,
+					PRTitle:    opts.PRTitle,
+					Prompt:     opts.Prompt,
+				}, err)
+			default:
+				if err := r.runs.SetSessionPRURL(session.ID, prURL); err != nil {
+					return fail("store-pr", err)
+				}
+				session.PRURL = prURL
+				_ = r.runs.AppendRunEvent(state.RunEvent{
+					RunID:   run.ID,
+					Type:    "pr",
+					Message: "Draft PR created: " + prURL,
+				})
 			}
-			if err := r.runs.SetSessionPRURL(session.ID, prURL); err != nil {
-				return fail("store-pr", err)
-			}
-			session.PRURL = prURL
-			_ = r.runs.AppendRunEvent(state.RunEvent{
-				RunID:   run.ID,
-				Type:    "pr",
-				Message: "Draft PR created: " + prURL,
-			})
 		}
 	}
 
+	return r.completeRun(session, run, commitSHA, commitMsg)
+}
+
+// completeRun records a successful run as COMPLETED and notifies.
+func (r *Runner) completeRun(session state.Session, run state.Run, commitSHA, commitMsg string) error {
 	if err := r.runs.CompleteRun(run.ID, "COMPLETED", commitSHA, commitMsg, ""); err != nil {
 		return err
 	}
 	if err := r.updateSessionStatusIfLatest(session.ID, run.ID, "COMPLETED"); err != nil {
 		return err
 	}
+	slog.Info("run completed", "session_id", session.ID, "run_id", run.ID, "commit_sha", commitSHA)
 	_ = r.runs.AppendRunEvent(state.RunEvent{
 		RunID:   run.ID,
 		Type:    "complete",
@@ -230,6 +412,7 @@ func (r *Runner) setRunPhase(sessionID, runID, phase string) error {
 	if err := r.runs.SetRunState(runID, phase); err != nil {
 		return err
 	}
+	slog.Debug("run phase", "session_id", sessionID, "run_id", runID, "phase", phase)
 	return r.updateSessionStatusIfLatest(sessionID, runID, phase)
 }
 
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/darkLord19/foglet/internal/ai"
 	"github.com/darkLord19/foglet/internal/state"
 )
 
@@ -194,6 +195,66 @@ func TestExecuteSessionRunSkipsOptionalPhases(t *testing.T) {
 	}
 }
 
+// A validator that signals warnings with exit 1 passes when 1 is allowed, and
+// the tolerated exit is recorded.
+func TestExecuteSessionRunAcceptsAllowedValidateExitCode(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true, output: "done"}, nil)
+
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "feature.txt", "work")
+
+	if err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:               "add a feature",
+		BaseBranch:           "main",
+		Validate:             true,
+		ValidateCmd:          "echo 2 warnings; exit 1",
+		ValidateSuccessCodes: []int{1},
+		CommitMsg:            "feat: add a feature",
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+
+	ev, ok := store.eventOfType("validate")
+	if !ok || !strings.Contains(ev.Message, "exited 1") {
+		t.Errorf("expected a validate event recording exit 1, got %+v (found=%v)", ev, ok)
+	}
+	if ev, ok := store.eventOfType("validate_output"); !ok || ev.Message != "2 warnings" || ev.Data != "1" {
+		t.Errorf("expected validate_output with the output and exit 1, got %+v (found=%v)", ev, ok)
+	}
+	if got := store.runs["run-1"].State; got != "COMPLETED" {
+		t.Errorf("run state = %q, want COMPLETED", got)
+	}
+}
+
+func TestExecuteSessionRunFailsOnDisallowedValidateExitCode(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true, output: "done"}, nil)
+
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "feature.txt", "work")
+
+	err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:               "add a feature",
+		BaseBranch:           "main",
+		Validate:             true,
+		ValidateCmd:          "echo FAIL: TestLogin >&2; exit 2",
+		ValidateSuccessCodes: []int{1},
+		CommitMsg:            "feat: add a feature",
+	})
+	if err == nil {
+		t.Fatal("expected validation failure for exit 2")
+	}
+	if ev, ok := store.eventOfType("validate_output"); !ok || ev.Message != "FAIL: TestLogin" || ev.Data != "2" {
+		t.Errorf("expected validate_output with stderr and exit 2, got %+v (found=%v)", ev, ok)
+	}
+	if got := store.runs["run-1"].State; got != "FAILED" {
+		t.Errorf("run state = %q, want FAILED", got)
+	}
+}
+
 func TestExecuteSessionRunEmitsEventsInOrder(t *testing.T) {
 	store := newFakeRunStore()
 	store.seed("session-1", "run-1")
@@ -379,6 +440,67 @@ func TestExecuteSessionRunMarksCancellationCancelled(t *testing.T) {
 	}
 }
 
+func TestExecuteSessionRunCancelStopsCommitMessageGeneration(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	wt := initTestWorktree(t)
+
+	// The agent call edits a file and returns; the commit-message call that
+	// follows blocks until the run's context is cancelled.
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	tool.block = func(ctx context.Context) error {
+		tool.mu.Lock()
+		calls := tool.calls
+		tool.mu.Unlock()
+		if calls == 1 {
+			writeFile(t, wt, "feature.txt", "hello\n")
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r := newTestRunner(store, tool, nil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+			Prompt:     "add a feature",
+			BaseBranch: "main",
+		})
+	}()
+
+	cancel := waitForActiveRun(t, r, "session-1")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tool.mu.Lock()
+		calls := tool.calls
+		tool.mu.Unlock()
+		if calls >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("commit message generation never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected a cancellation error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run kept going after cancel during commit message generation")
+	}
+	if got := lastString(store.runStates); got != "CANCELLED" {
+		t.Errorf("terminal run state = %q, want CANCELLED", got)
+	}
+	if _, found := store.eventOfType("commit"); found {
+		t.Error("changes were committed after the run was cancelled")
+	}
+}
+
 func TestExecuteSessionRunFailsWhenToolUnavailable(t *testing.T) {
 	store := newFakeRunStore()
 	store.seed("session-1", "run-1")
@@ -673,6 +795,7 @@ func TestExecuteSessionRunOpensDraftPRWhenAutoPRSet(t *testing.T) {
 	store.seed("session-1", "run-1")
 	pub := &fakePublisher{available: true, url: "https://example.invalid/pr/7"}
 	r := newTestRunnerWithPublisher(store, &fakeTool{name: "claude", available: true, output: "ok"}, nil, pub)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", Host: "ghe.example.com"}}
 
 	wt := initTestWorktreeWithRemote(t)
 	writeFile(t, wt, "feature.txt", "work")
@@ -701,6 +824,9 @@ func TestExecuteSessionRunOpensDraftPRWhenAutoPRSet(t *testing.T) {
 	if pub.gotTitle != "Add a feature" {
 		t.Errorf("PR title = %q, want the supplied one", pub.gotTitle)
 	}
+	if pub.gotHost != "ghe.example.com" {
+		t.Errorf("PR host = %q, want the repo's host", pub.gotHost)
+	}
 	if len(store.prURLs) != 1 || store.prURLs[0] != "https://example.invalid/pr/7" {
 		t.Errorf("PR URL not persisted: %v", store.prURLs)
 	}
@@ -709,7 +835,9 @@ func TestExecuteSessionRunOpensDraftPRWhenAutoPRSet(t *testing.T) {
 	}
 }
 
-func TestExecuteSessionRunFailsRunWhenPRCreationFails(t *testing.T) {
+// A failed PR attempt after a successful push completes the run and leaves a
+// pr_pending record to retry from, instead of failing the pushed work.
+func TestExecuteSessionRunCompletesWithPendingPRWhenPRCreationFails(t *testing.T) {
 	store := newFakeRunStore()
 	store.seed("session-1", "run-1")
 	pub := &fakePublisher{available: true, err: errors.New("gh exploded")}
@@ -722,19 +850,29 @@ func TestExecuteSessionRunFailsRunWhenPRCreationFails(t *testing.T) {
 	session.AutoPR = true
 
 	if err := r.executeSessionRun(session, testRun(wt), sessionRunOptions{
-		Prompt: "add a feature", BaseBranch: "main", CommitMsg: "feat: x",
-	}); err == nil {
-		t.Fatal("expected the PR failure to fail the run")
+		Prompt: "add a feature", BaseBranch: "develop", CommitMsg: "feat: x", PRTitle: "Add it",
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
 	}
-	if got := lastString(store.runStates); got != "FAILED" {
-		t.Errorf("terminal state = %q, want FAILED", got)
+	if got := lastString(store.runStates); got != "COMPLETED" {
+		t.Errorf("terminal state = %q, want COMPLETED", got)
+	}
+	event, found := store.eventOfType(prPendingEventType)
+	if !found || !strings.Contains(event.Message, "gh exploded") {
+		t.Fatalf("no pr_pending event recorded (found=%v, event=%+v)", found, event)
+	}
+	if !strings.Contains(event.Data, `"base_branch":"develop"`) || !strings.Contains(event.Data, `"pr_title":"Add it"`) {
+		t.Errorf("pending PR data = %s, want the attempted base and title", event.Data)
+	}
+	if len(store.prURLs) != 0 {
+		t.Errorf("PR URL stored despite failure: %v", store.prURLs)
 	}
 	if !store.busyCleared() {
 		t.Error("session left busy after a PR failure")
 	}
 }
 
-func TestExecuteSessionRunFailsWhenGhUnavailable(t *testing.T) {
+func TestExecuteSessionRunRecordsPendingPRWhenGhUnavailable(t *testing.T) {
 	store := newFakeRunStore()
 	store.seed("session-1", "run-1")
 	pub := &fakePublisher{available: false}
@@ -746,15 +884,17 @@ func TestExecuteSessionRunFailsWhenGhUnavailable(t *testing.T) {
 	session := testSession(wt)
 	session.AutoPR = true
 
-	err := r.executeSessionRun(session, testRun(wt), sessionRunOptions{
+	if err := r.executeSessionRun(session, testRun(wt), sessionRunOptions{
 		Prompt: "add a feature", BaseBranch: "main", CommitMsg: "feat: x",
-	})
-	if err == nil {
-		t.Fatal("expected an error when gh is unavailable")
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
 	}
 	if pub.calls != 0 {
 		t.Error("publisher was called despite reporting unavailable")
 	}
+	if _, found := store.eventOfType(prPendingEventType); !found {
+		t.Error("expected a pr_pending event when gh is unavailable")
+	}
 }
 
 // An existing PR means the branch is pushed but no second PR is opened.
@@ -783,3 +923,28 @@ func TestExecuteSessionRunPushesWithoutReopeningExistingPR(t *testing.T) {
 		t.Errorf("PR URL rewritten: %v", store.prURLs)
 	}
 }
+
+func TestExecuteSessionRunRecordsToolUsage(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	tool := &fakeTool{
+		name:      "claude",
+		available: true,
+		output:    "done",
+		usage:     ai.Usage{InputTokens: 1200, OutputTokens: 300, CostUSD: 0.04},
+	}
+	r := newTestRunner(store, tool, nil)
+
+	wt := initTestWorktree(t)
+	if err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:     "add a feature",
+		BaseBranch: "main",
+		CommitMsg:  "feat: add a feature",
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+	run := store.runs["run-1"]
+	if run.InputTokens != 1200 || run.OutputTokens != 300 || run.CostUSD != 0.04 {
+		t.Fatalf("run usage = %d/%d/%v", run.InputTokens, run.OutputTokens, run.CostUSD)
+	}
+}
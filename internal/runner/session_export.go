@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SessionExportVersion is the format version of a SessionExport. It changes
+// only when a field changes meaning or goes away.
+const SessionExportVersion = 1
+
+const (
+	// exportEventMaxBytes caps each event's message and data in an export.
+	exportEventMaxBytes = 8000
+	// exportDiffMaxBytes caps the exported patch.
+	exportDiffMaxBytes = 1 << 20
+)
+
+// redacted replaces every secret an export leaves out.
+const redacted = "[REDACTED]"
+
+// SessionExport is a self-contained record of a session: what was asked, what
+// the tool did and what it produced, for attaching to a review or PR.
+type SessionExport struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Session    state.Session      `json:"session"`
+	Runs       []SessionExportRun `json:"runs"`
+	Diff       *SessionExportDiff `json:"diff,omitempty"`
+	// DiffError says why Diff is missing, such as a removed worktree.
+	DiffError string `json:"diff_error,omitempty"`
+}
+
+// SessionExportRun is one run of an export with its events, oldest first.
+// Streamed output chunks are left out, as in a repo export; the full output
+// is in the ai_output events.
+type SessionExportRun struct {
+	state.Run
+	Events []state.RunEvent `json:"events"`
+}
+
+// SessionExportDiff is the session's final diff against its base branch.
+type SessionExportDiff struct {
+	Stat      string `json:"stat"`
+	Patch     string `json:"patch"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// secretPatterns match credentials that commonly end up in prompts, tool
+// output and errors.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{20,}\b`),
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{16,}`),
+	regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`),
+}
+
+// ExportSession collects a session, its runs and their events, and its final
+// diff into a SessionExport. Session env values and anything that looks like
+// a credential are redacted, and event payloads are capped. A diff that
+// cannot be computed is reported in DiffError rather than failing the export.
+func (r *Runner) ExportSession(sessionID string) (SessionExport, error) {
+	if r.runs == nil {
+		return SessionExport{}, errors.New("state store not configured")
+	}
+	session, found, err := r.runs.GetSession(strings.TrimSpace(sessionID))
+	if err != nil {
+		return SessionExport{}, err
+	}
+	if !found {
+		return SessionExport{}, fmt.Errorf("session %q: %w", sessionID, state.ErrNotFound)
+	}
+	runs, err := r.runs.ListRuns(session.ID)
+	if err != nil {
+		return SessionExport{}, err
+	}
+
+	red := newRedactor(session.Env)
+	session.Env = RedactEnv(session.Env)
+	out := SessionExport{
+		Version:    SessionExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Session:    session,
+		Runs:       make([]SessionExportRun, 0, len(runs)),
+	}
+
+	// ListRuns is newest first; a transcript reads oldest first.
+	for _, run := range slices.Backward(runs) {
+		events, err := r.runs.ListRunEventsExcept(run.ID, "ai_stream")
+		if err != nil {
+			return SessionExport{}, err
+		}
+		for i := range events {
+			events[i].Message = red.redact(capBytes(events[i].Message, exportEventMaxBytes))
+			events[i].Data = red.redact(capBytes(events[i].Data, exportEventMaxBytes))
+		}
+		run.Prompt = red.redact(run.Prompt)
+		run.CommitMsg = red.redact(run.CommitMsg)
+		run.Error = red.redact(run.Error)
+		out.Runs = append(out.Runs, SessionExportRun{Run: run, Events: events})
+	}
+
+	stat, patch, err := r.SessionDiff(session.ID)
+	if err != nil {
+		out.DiffError = red.redact(err.Error())
+		return out, nil
+	}
+	out.Diff = &SessionExportDiff{
+		Stat:      stat,
+		Patch:     red.redact(capBytes(patch, exportDiffMaxBytes)),
+		Truncated: len(patch) > exportDiffMaxBytes,
+	}
+	return out, nil
+}
+
+// RedactEnv returns env with every value replaced by a redaction marker, so
+// responses can show which variables a session sets without their values.
+func RedactEnv(env map[string]string) map[string]string {
+	if len(env) == 0 {
+		return env
+	}
+	out := make(map[string]string, len(env))
+	for key := range env {
+		out[key] = redacted
+	}
+	return out
+}
+
+// redactor blanks out a session's env values and well-known credential
+// formats.
+type redactor struct {
+	values []string
+}
+
+func newRedactor(env map[string]string) redactor {
+	var values []string
+	for _, value := range env {
+		// Short values such as "1" or "dev" would blank out ordinary text.
+		if value = strings.TrimSpace(value); len(value) >= 6 {
+			values = append(values, value)
+		}
+	}
+	// Longest first, so a value containing another is replaced whole.
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) })
+	return redactor{values: values}
+}
+
+func (r redactor) redact(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, redacted)
+	}
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			// Keep the URL around userinfo so the host stays readable.
+			if strings.HasPrefix(match, "://") {
+				return "://" + redacted + "@"
+			}
+			return redacted
+		})
+	}
+	return s
+}
+
+// capBytes cuts s to at most max bytes on a rune boundary, marking the cut.
+// Unlike truncate it keeps surrounding whitespace, so a patch still applies
+// up to the cut.
+func capBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...[truncated]"
+}
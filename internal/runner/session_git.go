@@ -9,9 +9,12 @@ import (
 
 	"github.com/darkLord19/foglet/internal/git"
 	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/state"
 )
 
-func (r *Runner) commitSessionChanges(ctx context.Context, toolName, workdir, prompt, commitMsg string) (sha, finalMsg string, changed bool, err error) {
+// commitSessionChanges commits everything in workdir, generating a message
+// when commitMsg is empty. A non-empty issueRef is added as a Refs trailer.
+func (r *Runner) commitSessionChanges(ctx context.Context, toolName, workdir, prompt, commitMsg, issueRef string) (sha, finalMsg string, changed bool, err error) {
 	g := git.New(workdir).WithContext(ctx)
 
 	dirty, err := g.IsDirty()
@@ -38,10 +41,11 @@ func (r *Runner) commitSessionChanges(ctx context.Context, toolName, workdir, pr
 			finalMsg = generated
 		}
 	}
+	finalMsg = withIssueTrailer(finalMsg, issueRef)
 
-	sha, err = g.Commit(finalMsg)
+	sha, err = r.commit(ctx, g, finalMsg)
 	if err != nil {
-		return "", "", false, fmt.Errorf("git commit failed: %w", err)
+		return "", "", false, err
 	}
 	return sha, finalMsg, true, nil
 }
@@ -53,18 +57,36 @@ func (r *Runner) pushBranch(ctx context.Context, workdir, branch string, setUpst
 	return nil
 }
 
-func (r *Runner) createDraftPR(ctx context.Context, workdir, baseBranch, branch, prompt, tool, sessionID, customTitle string) (string, error) {
+// forcePushBranch replaces origin's branch, provided it is still at lease.
+func (r *Runner) forcePushBranch(ctx context.Context, workdir, branch, lease string) error {
+	if err := git.New(workdir).WithContext(ctx).ForcePushWithLease(branch, lease); err != nil {
+		return fmt.Errorf("git push --force-with-lease failed: %w", err)
+	}
+	return nil
+}
+
+func (r *Runner) createDraftPR(ctx context.Context, session state.Session, workdir, baseBranch, prompt, customTitle string) (string, error) {
 	if r.publisher == nil || !r.publisher.Available() {
 		return "", fmt.Errorf("gh CLI not available")
 	}
 	title := resolvePRTitle(customTitle, prompt)
 	body := fmt.Sprintf("Generated by Fog session\n\nSession ID: %s\nAI Tool: %s\n\nPrompt:\n%s",
-		sessionID,
-		tool,
+		session.ID,
+		session.Tool,
 		strings.TrimSpace(prompt),
 	)
+	if line := issueRefPRLine(session.IssueRef, session.CloseIssue); line != "" {
+		body += "\n\n" + line
+	}
 
-	return r.publisher.CreatePR(ctx, workdir, title, body, baseBranch, branch, true)
+	// The repo's host keeps PRs for GitHub Enterprise repos off github.com.
+	var host string
+	if r.repos != nil {
+		if repo, found, err := r.repos.GetRepoByName(session.RepoName); err == nil && found {
+			host = strings.TrimSpace(repo.Host)
+		}
+	}
+	return r.publisher.CreatePR(ctx, workdir, host, title, body, baseBranch, session.Branch, true)
 }
 
 func withOutput(err error, output []byte) error {
@@ -85,6 +107,33 @@ func isCanceledError(err error) bool {
 var nonWorktreeNameChar = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
 
 func runWorktreeName(branch, runID string) string {
+	suffix := strings.TrimSpace(runID)
+	if len(suffix) > 8 {
+		suffix = suffix[:8]
+	}
+	suffix = nonWorktreeNameChar.ReplaceAllString(suffix, "")
+	if suffix == "" {
+		suffix = "latest"
+	}
+	return worktreeNamePrefix(branch) + "-" + suffix
+}
+
+// PlanWorktreePath returns where a new session on branch would get its
+// worktree. The real name ends in the first run's ID, which is only minted at
+// launch, so the returned path ends in runIDPlaceholder instead. worktreeDir
+// is the repo's WorktreeDir.
+func PlanWorktreePath(repoPath, worktreeDir, branch string) (string, error) {
+	g := git.New(repoPath)
+	if !g.IsRepo() {
+		return "", fmt.Errorf("not a git repository: %s", repoPath)
+	}
+	return worktreePathFor(g, worktreeDir, worktreeNamePrefix(branch)+"-"+runIDPlaceholder)
+}
+
+// runIDPlaceholder stands in for the run ID suffix of a planned worktree.
+const runIDPlaceholder = "<run-id>"
+
+func worktreeNamePrefix(branch string) string {
 	branch = nonWorktreeNameChar.ReplaceAllString(strings.TrimSpace(branch), "-")
 	branch = strings.Trim(branch, "-._")
 	if branch == "" {
@@ -97,15 +146,7 @@ func runWorktreeName(branch, runID string) string {
 			branch = "run"
 		}
 	}
-	suffix := strings.TrimSpace(runID)
-	if len(suffix) > 8 {
-		suffix = suffix[:8]
-	}
-	suffix = nonWorktreeNameChar.ReplaceAllString(suffix, "")
-	if suffix == "" {
-		suffix = "latest"
-	}
-	return branch + "-" + suffix
+	return branch
 }
 
 func fallbackCommitMessage(prompt string) string {
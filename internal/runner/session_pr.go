@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// prPendingEventType marks a run whose work was committed and pushed but whose
+// draft PR could not be opened. The run still completes; the PR can be retried
+// with RetrySessionPR.
+const prPendingEventType = "pr_pending"
+
+// ErrPRNotPending is returned by RetrySessionPR when the session is not in a
+// state where opening a PR makes sense: it already has one, is busy, or is a
+// scratch session.
+var ErrPRNotPending = errors.New("no pull request pending")
+
+// pendingPR is the data of a pr_pending event: what the failed attempt would
+// have used, so a retry opens the same PR.
+type pendingPR struct {
+	BaseBranch string `json:"base_branch"`
+	PRTitle    string `json:"pr_title,omitempty"`
+	Prompt     string `json:"prompt,omitempty"`
+}
+
+// RetryPROptions overrides what RetrySessionPR recovers from the failed
+// attempt. Empty fields keep the recorded value.
+type RetryPROptions struct {
+	BaseBranch string
+	PRTitle    string
+}
+
+// recordPendingPR notes a failed PR attempt on the run. The push already
+// succeeded, so the work is safe; only the PR is missing.
+func (r *Runner) recordPendingPR(runID string, pending pendingPR, cause error) {
+	data, _ := json.Marshal(pending)
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   runID,
+		Type:    prPendingEventType,
+		Message: "Draft PR not created: " + cause.Error(),
+		Data:    string(data),
+	})
+}
+
+// RetrySessionPR opens the draft PR for a session whose earlier attempt
+// failed after its branch was pushed. It returns the PR URL.
+func (r *Runner) RetrySessionPR(sessionID string, opts RetryPROptions) (string, error) {
+	// Claimed before the session is read, so a run or another retry cannot
+	// push to the branch or open the PR while this one does.
+	release, err := r.claimIdleSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	session, worktreePath, repoBase, err := r.sessionBranchContext(sessionID)
+	if errors.Is(err, errScratchNoBranch) {
+		return "", fmt.Errorf("%w: %s", ErrPRNotPending, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	if url := strings.TrimSpace(session.PRURL); url != "" {
+		return "", fmt.Errorf("%w: session already has %s", ErrPRNotPending, url)
+	}
+	latest, found, err := r.runs.GetLatestRun(session.ID)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("%w: session %q has no runs", ErrPRNotPending, session.ID)
+	}
+
+	pending, ok := r.lookupPendingPR(session.ID)
+	if !ok {
+		pending = pendingPR{BaseBranch: repoBase, Prompt: latest.Prompt}
+	}
+	if base := strings.TrimSpace(opts.BaseBranch); base != "" {
+		pending.BaseBranch = base
+	}
+	if title := strings.TrimSpace(opts.PRTitle); title != "" {
+		pending.PRTitle = title
+	}
+	if strings.TrimSpace(pending.BaseBranch) == "" {
+		pending.BaseBranch = repoBase
+	}
+
+	prURL, err := r.createDraftPR(r.baseCtx, session, worktreePath, pending.BaseBranch, pending.Prompt, pending.PRTitle)
+	if err != nil {
+		return "", err
+	}
+	if err := r.runs.SetSessionPRURL(session.ID, prURL); err != nil {
+		return "", err
+	}
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   latest.ID,
+		Type:    "pr",
+		Message: "Draft PR created: " + prURL,
+	})
+	return prURL, nil
+}
+
+// lookupPendingPR finds the most recent pr_pending record for a session.
+func (r *Runner) lookupPendingPR(sessionID string) (pendingPR, bool) {
+	runs, err := r.runs.ListRuns(sessionID)
+	if err != nil {
+		return pendingPR{}, false
+	}
+	for _, run := range runs {
+		events, err := r.runs.ListRunEvents(run.ID, 2000)
+		if err != nil {
+			continue
+		}
+		for i := len(events) - 1; i >= 0; i-- {
+			if strings.TrimSpace(events[i].Type) != prPendingEventType {
+				continue
+			}
+			var pending pendingPR
+			if err := json.Unmarshal([]byte(events[i].Data), &pending); err != nil {
+				continue
+			}
+			return pending, true
+		}
+	}
+	return pendingPR{}, false
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func newPRRetryRunner(t *testing.T, pub *fakePublisher) (*Runner, *fakeRunStore) {
+	t.Helper()
+	wt := initTestWorktree(t)
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"] = &state.Session{ID: "session-1", RepoName: "acme/api", Branch: "fog/test", WorktreePath: wt, Tool: "claude"}
+	store.runs["run-1"].Prompt = "add a feature"
+	r := newTestRunnerWithPublisher(store, &fakeTool{name: "claude", available: true}, nil, pub)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+	return r, store
+}
+
+func TestRetrySessionPRUsesPendingRecord(t *testing.T) {
+	pub := &fakePublisher{available: true, url: "https://example.invalid/pr/3"}
+	r, store := newPRRetryRunner(t, pub)
+	r.recordPendingPR("run-1", pendingPR{BaseBranch: "develop", PRTitle: "Add it", Prompt: "add a feature"}, errors.New("gh flaked"))
+
+	url, err := r.RetrySessionPR("session-1", RetryPROptions{})
+	if err != nil {
+		t.Fatalf("RetrySessionPR: %v", err)
+	}
+	if url != "https://example.invalid/pr/3" {
+		t.Errorf("url = %q", url)
+	}
+	if pub.gotBase != "develop" || pub.gotBranch != "fog/test" || pub.gotTitle != "Add it" {
+		t.Errorf("PR opened with base/branch/title = %q/%q/%q, want the recorded ones", pub.gotBase, pub.gotBranch, pub.gotTitle)
+	}
+	if len(store.prURLs) != 1 || store.prURLs[0] != url {
+		t.Errorf("PR URL not persisted: %v", store.prURLs)
+	}
+	if _, found := store.eventOfType("pr"); !found {
+		t.Error("no pr event recorded")
+	}
+}
+
+func TestRetrySessionPROverridesAndFallsBackToRepoDefault(t *testing.T) {
+	pub := &fakePublisher{available: true, url: "https://example.invalid/pr/4"}
+	r, _ := newPRRetryRunner(t, pub)
+
+	if _, err := r.RetrySessionPR("session-1", RetryPROptions{PRTitle: "Custom"}); err != nil {
+		t.Fatalf("RetrySessionPR: %v", err)
+	}
+	if pub.gotBase != "main" {
+		t.Errorf("base = %q, want the repo default", pub.gotBase)
+	}
+	if pub.gotTitle != "Custom" {
+		t.Errorf("title = %q, want the override", pub.gotTitle)
+	}
+}
+
+func TestRetrySessionPRRejectsSessionWithPR(t *testing.T) {
+	pub := &fakePublisher{available: true, url: "https://example.invalid/pr/5"}
+	r, store := newPRRetryRunner(t, pub)
+	store.sessions["session-1"].PRURL = "https://example.invalid/pr/1"
+
+	_, err := r.RetrySessionPR("session-1", RetryPROptions{})
+	if !errors.Is(err, ErrPRNotPending) {
+		t.Fatalf("expected ErrPRNotPending, got %v", err)
+	}
+	if pub.calls != 0 {
+		t.Error("publisher called for a session that already has a PR")
+	}
+}
+
+func TestRetrySessionPRReturnsPublisherError(t *testing.T) {
+	pub := &fakePublisher{available: true, err: errors.New("still down")}
+	r, store := newPRRetryRunner(t, pub)
+
+	if _, err := r.RetrySessionPR("session-1", RetryPROptions{}); err == nil || errors.Is(err, ErrPRNotPending) {
+		t.Fatalf("expected the publisher error, got %v", err)
+	}
+	if len(store.prURLs) != 0 {
+		t.Errorf("PR URL stored despite failure: %v", store.prURLs)
+	}
+}
+
+func TestRetrySessionPRClaimsTheSession(t *testing.T) {
+	pub := &fakePublisher{available: true, url: "https://example.invalid/pr/6"}
+	r, store := newPRRetryRunner(t, pub)
+	store.sessions["session-1"].Busy = true
+
+	if _, err := r.RetrySessionPR("session-1", RetryPROptions{}); !errors.Is(err, ErrSessionBusy) {
+		t.Fatalf("retry on a busy session: err = %v, want ErrSessionBusy", err)
+	}
+	if pub.calls != 0 {
+		t.Error("publisher called while a run held the session")
+	}
+
+	store.sessions["session-1"].Busy = false
+	if _, err := r.RetrySessionPR("session-1", RetryPROptions{}); err != nil {
+		t.Fatalf("RetrySessionPR: %v", err)
+	}
+	if store.sessions["session-1"].Busy {
+		t.Error("session left busy after the retry")
+	}
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/branchname"
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+var (
+	// ErrInvalidBranch is returned for a branch name a session cannot take.
+	ErrInvalidBranch = errors.New("invalid branch name")
+	// ErrBranchExists is returned when renaming onto a branch the repo
+	// already has.
+	ErrBranchExists = errors.New("branch already exists")
+	// ErrBranchPublished is returned when renaming or squashing a branch that
+	// was pushed or had a pull request opened from it, which would orphan the
+	// remote branch or rewrite its history.
+	ErrBranchPublished = errors.New("session branch is published")
+)
+
+// checkBranchUnpublished refuses a session whose branch has a PR, or tracks
+// a remote branch: the first push sets the upstream, and a push whose PR
+// could not be opened still leaves a published branch without a PR URL.
+func checkBranchUnpublished(session state.Session, g *git.Git) error {
+	if strings.TrimSpace(session.PRURL) != "" {
+		return fmt.Errorf("%w: %s", ErrBranchPublished, session.PRURL)
+	}
+	if remote := g.UpstreamRemote(session.Branch); remote != "" {
+		return fmt.Errorf("%w: %s was pushed to %s", ErrBranchPublished, session.Branch, remote)
+	}
+	return nil
+}
+
+// RenameSessionBranch renames a session's branch with git branch -m and
+// records the new name. The worktree follows the branch, and its directory
+// keeps its name. It refuses once the branch was pushed or a PR exists, while
+// a run is in flight, and for a name that is invalid, protected, taken or
+// outside branch_name_regex.
+func (r *Runner) RenameSessionBranch(sessionID, branch string) (state.Session, error) {
+	session, worktreePath, _, err := r.sessionBranchContext(strings.TrimSpace(sessionID))
+	if errors.Is(err, errScratchNoBranch) {
+		return state.Session{}, fmt.Errorf("%w: %w", ErrInvalidBranch, err)
+	}
+	if err != nil {
+		return state.Session{}, err
+	}
+	g := git.New(worktreePath)
+	if err := checkBranchUnpublished(session, g); err != nil {
+		return state.Session{}, err
+	}
+	release, err := r.claimIdleSession(session.ID)
+	if err != nil {
+		return state.Session{}, err
+	}
+	defer release()
+
+	branch, err = branchname.Validate(branch)
+	if err != nil {
+		return state.Session{}, fmt.Errorf("%w: %w", ErrInvalidBranch, err)
+	}
+	if branch == session.Branch {
+		return session, nil
+	}
+	if branchname.IsProtected(branch) {
+		return state.Session{}, fmt.Errorf("%w: %s is a protected branch", ErrInvalidBranch, branch)
+	}
+	if pattern := r.branchNamePattern(); pattern != nil && !pattern.MatchString(branch) {
+		return state.Session{}, fmt.Errorf("%w: branch name %q must match %s", ErrInvalidBranch, branch, pattern)
+	}
+	if g.BranchExists(branch) {
+		return state.Session{}, fmt.Errorf("%w: %s", ErrBranchExists, branch)
+	}
+	if err := g.RenameBranch(session.Branch, branch); err != nil {
+		return state.Session{}, fmt.Errorf("rename branch %s to %s: %w", session.Branch, branch, err)
+	}
+	if err := r.runs.SetSessionBranch(session.ID, branch); err != nil {
+		// Put git back so the session still names a branch that exists.
+		_ = g.RenameBranch(branch, session.Branch)
+		return state.Session{}, err
+	}
+	session.Branch = branch
+	return session, nil
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// renameFixture is session-1 on branch fog/test, checked out in a real
+// worktree, with a taken branch fog/taken alongside.
+func renameFixture(t *testing.T) (*Runner, *fakeRunStore, string) {
+	t.Helper()
+	wt := initTestWorktree(t)
+	for _, args := range [][]string{{"branch", "fog/taken"}, {"checkout", "-q", "-b", "fog/test"}} {
+		if out, err := exec.Command("git", append([]string{"-C", wt}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	session := testSession(wt)
+	session.Busy = false
+	*store.sessions["session-1"] = session
+	r := newTestRunner(store, &fakeTool{}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+	return r, store, wt
+}
+
+func currentBranch(t *testing.T, wt string) string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", wt, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("rev-parse: %v", err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestRenameSessionBranch(t *testing.T) {
+	r, store, wt := renameFixture(t)
+
+	session, err := r.RenameSessionBranch("session-1", " fog/fixed-typo ")
+	if err != nil {
+		t.Fatalf("RenameSessionBranch: %v", err)
+	}
+	if session.Branch != "fog/fixed-typo" || store.sessions["session-1"].Branch != "fog/fixed-typo" {
+		t.Fatalf("branch = %q, stored %q", session.Branch, store.sessions["session-1"].Branch)
+	}
+	if got := currentBranch(t, wt); got != "fog/fixed-typo" {
+		t.Fatalf("worktree is on %q", got)
+	}
+	if store.sessions["session-1"].Busy || len(store.busyWrites) != 2 {
+		t.Errorf("busy writes = %v, want the session claimed and released", store.busyWrites)
+	}
+}
+
+func TestRenameSessionBranchRefusals(t *testing.T) {
+	cases := []struct {
+		name   string
+		branch string
+		setup  func(t *testing.T, r *Runner, store *fakeRunStore)
+		want   error
+	}{
+		{"invalid", "fog/bad name", nil, ErrInvalidBranch},
+		{"protected", "main", nil, ErrInvalidBranch},
+		{"taken", "fog/taken", nil, ErrBranchExists},
+		{"regex", "fog/new", func(_ *testing.T, r *Runner, _ *fakeRunStore) {
+			r.settings = fakeSettings{SettingBranchNameRegex: `^team/`}
+		}, ErrInvalidBranch},
+		{"pr open", "fog/new", func(_ *testing.T, _ *Runner, store *fakeRunStore) {
+			store.sessions["session-1"].PRURL = "https://github.com/acme/api/pull/1"
+		}, ErrBranchPublished},
+		{"pushed", "fog/new", func(t *testing.T, _ *Runner, store *fakeRunStore) {
+			// A push whose PR could not be opened leaves an upstream and no PR URL.
+			gitOutput(t, store.sessions["session-1"].WorktreePath, "config", "branch.fog/test.remote", "origin")
+		}, ErrBranchPublished},
+		{"busy", "fog/new", func(_ *testing.T, _ *Runner, store *fakeRunStore) {
+			store.sessions["session-1"].Busy = true
+		}, ErrSessionBusy},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, store, wt := renameFixture(t)
+			if tc.setup != nil {
+				tc.setup(t, r, store)
+			}
+			if _, err := r.RenameSessionBranch("session-1", tc.branch); !errors.Is(err, tc.want) {
+				t.Fatalf("error = %v, want %v", err, tc.want)
+			}
+			if got := currentBranch(t, wt); got != "fog/test" {
+				t.Fatalf("a refused rename moved the worktree to %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// ErrRunNotRetryable is returned by RetryRunAsync for a run that is not the
+// session's latest, or did not fail.
+var ErrRunNotRetryable = errors.New("run cannot be retried")
+
+// RetryRunAsync re-executes a session's failed latest run in the background:
+// a new run in the same worktree, with the same prompt, attachments and
+// context text, resuming the same tool conversation the failed run started
+// from. Setup is not run again. The
+// new run carries a retry event whose Data is the failed run's ID.
+//
+// Like ContinueSessionAsync it returns ErrQueueFull when the queue is
+// saturated.
+func (r *Runner) RetryRunAsync(sessionID, runID string) (state.Run, error) {
+	release, err := r.reserveQueueSlot()
+	if err != nil {
+		return state.Run{}, err
+	}
+	session, run, execOpts, err := r.prepareRetryRun(sessionID, runID)
+	if err != nil {
+		release()
+		return state.Run{}, err
+	}
+	go func(s state.Session, ru state.Run, eo sessionRunOptions) {
+		defer release()
+		_ = r.executeSessionRun(s, ru, eo)
+	}(session, run, execOpts)
+	return run, nil
+}
+
+func (r *Runner) prepareRetryRun(sessionID, runID string) (state.Session, state.Run, sessionRunOptions, error) {
+	if r.runs == nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, errors.New("state store not configured")
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	runID = strings.TrimSpace(runID)
+
+	session, found, err := r.runs.GetSession(sessionID)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	if !found {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("session %q: %w", sessionID, state.ErrNotFound)
+	}
+	failed, found, err := r.runs.GetRun(runID)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	if !found || failed.SessionID != session.ID {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("run %q in session %q: %w", runID, sessionID, state.ErrNotFound)
+	}
+	switch {
+	case session.Paused:
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: resume session %q to retry runs", ErrSessionPaused, sessionID)
+	case session.Busy:
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: session %q has a run in progress", ErrSessionBusy, sessionID)
+	case session.Ephemeral:
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: session %q is a scratch session and its worktree is gone", ErrRunNotRetryable, sessionID)
+	case failed.State != "FAILED":
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: run %s is %s, only failed runs can be retried", ErrRunNotRetryable, runID, failed.State)
+	}
+	latest, found, err := r.runs.GetLatestRun(session.ID)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	if !found || latest.ID != failed.ID {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, fmt.Errorf("%w: run %s is not the session's latest run", ErrRunNotRetryable, runID)
+	}
+
+	// Looked up before the new run exists, skipping the failed run: a run
+	// that failed after its AI phase recorded a conversation of its own, and
+	// resuming that would ask for the same work twice.
+	conversationID := r.lookupConversationID(session.ID, failed.ID)
+
+	run, opts, err := r.createFollowUpRun(session, failed.Prompt, failed.Attachments, failed.ContextText)
+	if err != nil {
+		return state.Session{}, state.Run{}, sessionRunOptions{}, err
+	}
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   run.ID,
+		Type:    "retry",
+		Message: "Retry of run " + failed.ID,
+		Data:    failed.ID,
+	})
+	opts.ConversationID = conversationID
+	opts.HasConversationID = true
+	return session, run, opts, nil
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// seedFailedRun leaves run-1 as session-1's failed latest run in wt, with an
+// earlier run-0 whose conversation a retry should resume.
+func seedFailedRun(store *fakeRunStore, wt string) {
+	store.seed("session-1", "run-1")
+	session := testSession(wt)
+	session.Busy = false
+	*store.sessions["session-1"] = session
+
+	now := time.Now().UTC()
+	store.runs["run-0"] = &state.Run{ID: "run-0", SessionID: "session-1", State: "COMPLETED", CreatedAt: now.Add(-2 * time.Minute)}
+	failed := testRun(wt)
+	failed.State = "FAILED"
+	failed.CreatedAt = now.Add(-time.Minute)
+	*store.runs["run-1"] = failed
+	store.events = append(store.events,
+		state.RunEvent{RunID: "run-0", Type: "ai_session", Data: "conv-before"},
+		state.RunEvent{RunID: "run-1", Type: "ai_session", Data: "conv-failed"},
+	)
+}
+
+func TestPrepareRetryRunReusesPromptAndConversation(t *testing.T) {
+	wt := initTestWorktree(t)
+	store := newFakeRunStore()
+	seedFailedRun(store, wt)
+	r := newTestRunner(store, &fakeTool{}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+	store.runs["run-1"].Attachments = []string{"docs/spec.md"}
+	store.runs["run-1"].ContextText = "the spec"
+
+	_, run, opts, err := r.prepareRetryRun("session-1", "run-1")
+	if err != nil {
+		t.Fatalf("prepareRetryRun: %v", err)
+	}
+	stored := store.runs[run.ID]
+	if !slices.Equal(opts.Attachments, []string{"docs/spec.md"}) || opts.ContextText != "the spec" ||
+		!slices.Equal(stored.Attachments, opts.Attachments) || stored.ContextText != opts.ContextText {
+		t.Errorf("retry inputs = %q / %q (stored %q / %q), want the failed run's", opts.Attachments, opts.ContextText, stored.Attachments, stored.ContextText)
+	}
+	if run.ID == "run-1" || run.Prompt != "add a feature" || run.WorktreePath != wt {
+		t.Errorf("retry run = %+v, want a new run with the failed run's prompt in %s", run, wt)
+	}
+	if !opts.HasConversationID || opts.ConversationID != "conv-before" {
+		t.Errorf("conversation = %q (pinned %v), want conv-before", opts.ConversationID, opts.HasConversationID)
+	}
+	event, found := store.eventOfType("retry")
+	if !found || event.RunID != run.ID || event.Data != "run-1" {
+		t.Errorf("retry event = %+v (found %v), want one on %s naming run-1", event, found, run.ID)
+	}
+	if len(store.busyWrites) != 1 || !store.busyWrites[0] {
+		t.Errorf("busy writes = %v, want the session marked busy once", store.busyWrites)
+	}
+}
+
+func TestPrepareRetryRunRejectsUnretryableRuns(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(store *fakeRunStore)
+		want  error
+	}{
+		{"not failed", func(store *fakeRunStore) { store.runs["run-1"].State = "COMPLETED" }, ErrRunNotRetryable},
+		{"not latest", func(store *fakeRunStore) {
+			store.runs["run-2"] = &state.Run{ID: "run-2", SessionID: "session-1", State: "COMPLETED"}
+			store.latestRunID = "run-2"
+		}, ErrRunNotRetryable},
+		{"busy", func(store *fakeRunStore) { store.sessions["session-1"].Busy = true }, ErrSessionBusy},
+		{"paused", func(store *fakeRunStore) { store.sessions["session-1"].Paused = true }, ErrSessionPaused},
+		{"other session", func(store *fakeRunStore) { store.runs["run-1"].SessionID = "session-2" }, state.ErrNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newFakeRunStore()
+			seedFailedRun(store, t.TempDir())
+			tc.setup(store)
+			r := newTestRunner(store, &fakeTool{}, nil)
+			r.repos = fakeRepos{}
+
+			if _, _, _, err := r.prepareRetryRun("session-1", "run-1"); !errors.Is(err, tc.want) {
+				t.Fatalf("error = %v, want %v", err, tc.want)
+			}
+			if _, found := store.eventOfType("retry"); found {
+				t.Error("a refused retry recorded a retry event")
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// scratchBranchLabel stands in for the branch of an ephemeral session. It is
+// never created in git; the worktree runs on a detached HEAD.
+const scratchBranchLabel = "(scratch)"
+
+// removeScratchWorktree deletes an ephemeral run's worktree. Removal is forced
+// because the whole point of a scratch run is that its changes are discarded.
+// A failure is recorded on the run rather than returned: the run's own outcome
+// has already been stored.
+func (r *Runner) removeScratchWorktree(run state.Run, repoPath string) {
+	wt := strings.TrimSpace(run.WorktreePath)
+	if wt == "" || strings.TrimSpace(repoPath) == "" {
+		return
+	}
+	message := "Removed scratch worktree"
+	if err := git.New(repoPath).RemoveWorktree(wt, true); err != nil {
+		message = "Remove scratch worktree: " + err.Error()
+	}
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   run.ID,
+		Type:    "cleanup",
+		Message: message,
+	})
+}
@@ -0,0 +1,134 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darkLord19/foglet/internal/git"
+)
+
+func TestExecuteSessionRunEphemeralDiscardsWorktree(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+
+	repo := initTestWorktree(t)
+	wt := filepath.Join(t.TempDir(), "scratch")
+	if err := git.New(repo).AddWorktreeDetached(wt, "HEAD"); err != nil {
+		t.Fatalf("add detached worktree: %v", err)
+	}
+
+	tool := &fakeTool{
+		name:      "claude",
+		available: true,
+		output:    "tried something",
+		block: func(context.Context) error {
+			writeFile(t, wt, "experiment.txt", "scratch\n")
+			return nil
+		},
+	}
+	pub := &fakePublisher{}
+	r := newTestRunnerWithPublisher(store, tool, nil, pub)
+
+	session := testSession(wt)
+	session.Branch = scratchBranchLabel
+	session.Ephemeral = true
+	if err := r.executeSessionRun(session, testRun(wt), sessionRunOptions{
+		Prompt:     "try an idea",
+		BaseBranch: "main",
+		Ephemeral:  true,
+		RepoPath:   repo,
+	}); err != nil {
+		t.Fatalf("executeSessionRun: %v", err)
+	}
+
+	if got := lastString(store.runStates); got != "COMPLETED" {
+		t.Errorf("terminal run state = %q, want COMPLETED", got)
+	}
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Errorf("scratch worktree still present: %v", err)
+	}
+	if e, found := store.eventOfType("cleanup"); !found || e.Message != "Removed scratch worktree" {
+		t.Errorf("unexpected cleanup event: %+v found=%v", e, found)
+	}
+	if _, found := store.eventOfType("commit"); found {
+		t.Error("scratch run reached the commit phase")
+	}
+	out, err := exec.Command("git", "-C", repo, "rev-list", "--count", "--all").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git rev-list: %v\n%s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "1" {
+		t.Errorf("commit count = %s, want 1", got)
+	}
+	if pub.calls != 0 {
+		t.Errorf("scratch run opened %d pull requests", pub.calls)
+	}
+	if !store.busyCleared() {
+		t.Error("session left busy after scratch run")
+	}
+}
+
+func TestPrepareFollowUpRunRejectsScratchSession(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"].Ephemeral = true
+	store.sessions["session-1"].WorktreePath = "/tmp/scratch"
+	r := newTestRunner(store, &fakeTool{}, nil)
+
+	_, _, _, err := r.prepareFollowUpRun("session-1", "more")
+	if err == nil || !strings.Contains(err.Error(), "scratch session") {
+		t.Fatalf("expected scratch session rejection, got %v", err)
+	}
+}
+
+func TestResolveLaunchEphemeral(t *testing.T) {
+	r := newLaunchRunner(launchRepos(), fakeSettings{})
+
+	req := validRequest()
+	req.Ephemeral = true
+	req.BranchName = "ignored"
+	opts, err := r.resolveLaunch(req)
+	if err != nil {
+		t.Fatalf("resolveLaunch: %v", err)
+	}
+	if !opts.Ephemeral || opts.Branch != "" {
+		t.Fatalf("unexpected options: ephemeral=%v branch=%q", opts.Ephemeral, opts.Branch)
+	}
+
+	req.AutoPR = true
+	if _, err := r.resolveLaunch(req); !errors.Is(err, ErrInvalidLaunch) {
+		t.Fatalf("error = %v, want ErrInvalidLaunch", err)
+	}
+}
+
+func TestScratchDirUsesConfiguredDirectory(t *testing.T) {
+	parent := filepath.Join(t.TempDir(), "nested", "scratch")
+	r := newTestRunner(newFakeRunStore(), &fakeTool{}, fakeSettings{SettingScratchDir: parent})
+
+	dir, err := r.scratchDir("fog-test-*")
+	if err != nil {
+		t.Fatalf("scratchDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if filepath.Dir(dir) != parent {
+		t.Fatalf("scratch dir %q not under %q", dir, parent)
+	}
+}
+
+func TestScratchDirDefaultsToSystemTemp(t *testing.T) {
+	r := newTestRunner(newFakeRunStore(), &fakeTool{}, nil)
+
+	dir, err := r.scratchDir("fog-test-*")
+	if err != nil {
+		t.Fatalf("scratchDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if filepath.Dir(dir) != filepath.Clean(os.TempDir()) {
+		t.Fatalf("scratch dir %q not under system temp %q", dir, os.TempDir())
+	}
+}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// ErrNoSetupCmd is returned by RerunSetup when neither the request nor the
+// repo's RepoConfigFile names a setup command.
+var ErrNoSetupCmd = errors.New("no setup command")
+
+// ErrWorktreeMissing is returned when a session's worktree is not on disk.
+var ErrWorktreeMissing = errors.New("session worktree is missing")
+
+// SetupResult is how a setup command re-run by RerunSetup ended. A non-zero
+// ExitCode is a result, not an error. Output is the combined output, cut to
+// the size a setup_output event keeps.
+type SetupResult struct {
+	Command  string
+	ExitCode int
+	Output   string
+}
+
+// RerunSetup runs a setup command again in a session's worktree without an
+// AI run, e.g. after a lockfile change. An empty setupCmd falls back to the
+// setup_cmd in the RepoConfigFile of the repo's base worktree, as a launch
+// reads it: the session worktree is the AI's to edit, so its copy of the file
+// is never trusted. The command is checked like a launch's:
+// ValidateShellCommand and the command allowlist.
+//
+// The session is marked busy while the command runs, so it refuses while a
+// run is in flight and no follow-up starts underneath it. A setup event and
+// a setup_output event, carrying the output and exit code, are recorded on
+// the session's latest run.
+func (r *Runner) RerunSetup(sessionID, setupCmd string) (SetupResult, error) {
+	if r.runs == nil {
+		return SetupResult{}, errors.New("state store not configured")
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	session, found, err := r.runs.GetSession(sessionID)
+	if err != nil {
+		return SetupResult{}, err
+	}
+	if !found {
+		return SetupResult{}, fmt.Errorf("session %q: %w", sessionID, state.ErrNotFound)
+	}
+	if session.WorktreePrunedAt != nil {
+		return SetupResult{}, fmt.Errorf("%w: session %q", ErrWorktreePruned, sessionID)
+	}
+	worktreePath := strings.TrimSpace(session.WorktreePath)
+	if worktreePath == "" {
+		return SetupResult{}, fmt.Errorf("%w: session %q has no worktree path", ErrWorktreeMissing, sessionID)
+	}
+	if info, err := os.Stat(worktreePath); err != nil || !info.IsDir() {
+		return SetupResult{}, fmt.Errorf("%w: %s", ErrWorktreeMissing, worktreePath)
+	}
+
+	setupCmd = strings.TrimSpace(setupCmd)
+	if setupCmd == "" {
+		if r.repos == nil {
+			return SetupResult{}, errors.New("state store not configured")
+		}
+		repo, found, err := r.repos.GetRepoByName(session.RepoName)
+		if err != nil {
+			return SetupResult{}, err
+		}
+		if !found {
+			return SetupResult{}, fmt.Errorf("%w: %s", ErrUnknownRepo, session.RepoName)
+		}
+		cfg, err := LoadRepoConfig(repo.BaseWorktreePath)
+		if err != nil {
+			return SetupResult{}, err
+		}
+		setupCmd = cfg.SetupCmd
+	}
+	if setupCmd == "" {
+		return SetupResult{}, fmt.Errorf("%w: pass setup_cmd or set it in %s", ErrNoSetupCmd, RepoConfigFile)
+	}
+	if err := ValidateShellCommand(setupCmd); err != nil {
+		return SetupResult{}, fmt.Errorf("%w: setup_cmd %w", ErrInvalidLaunch, err)
+	}
+	if err := r.checkSessionCommands(setupCmd, false, ""); err != nil {
+		return SetupResult{}, err
+	}
+
+	release, err := r.claimIdleSession(session.ID)
+	if err != nil {
+		return SetupResult{}, err
+	}
+	defer release()
+
+	var runID string
+	if latest, found, err := r.runs.GetLatestRun(session.ID); err == nil && found {
+		runID = latest.ID
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   runID,
+			Type:    "setup",
+			Message: "Re-running setup command",
+			Data:    setupCmd,
+		})
+	}
+
+	// Bounded like a run's setup phase, so a hung command cannot hold the
+	// session busy forever.
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := r.RunTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(r.baseCtx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(r.baseCtx)
+	}
+	defer cancel()
+	code, output, err := r.runShellAllowing(ctx, worktreePath, setupCmd, envEntries(session.Env), nil)
+	if err != nil {
+		exitCode, exited := proc.ExitCode(err)
+		if !exited {
+			return SetupResult{}, fmt.Errorf("setup: %w", err)
+		}
+		code = exitCode
+	}
+	out := truncate(string(output), 8000)
+	if runID != "" {
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   runID,
+			Type:    "setup_output",
+			Message: out,
+			Data:    strconv.Itoa(code),
+		})
+	}
+	return SetupResult{Command: setupCmd, ExitCode: code, Output: out}, nil
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRerunSetup(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	wt := initTestWorktree(t)
+	store.sessions["session-1"].WorktreePath = wt
+	store.sessions["session-1"].RepoName = "acme/api"
+	base := t.TempDir()
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", BaseWorktreePath: base}}
+
+	res, err := r.RerunSetup("session-1", "echo installed")
+	if err != nil {
+		t.Fatalf("RerunSetup: %v", err)
+	}
+	if res.ExitCode != 0 || strings.TrimSpace(res.Output) != "installed" {
+		t.Fatalf("result = %+v, want exit 0 and the output", res)
+	}
+	if got, want := store.eventTypes(), []string{"setup", "setup_output"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if ev, _ := store.eventOfType("setup_output"); ev.RunID != "run-1" || ev.Data != "0" {
+		t.Fatalf("setup_output event = %+v", ev)
+	}
+	if !reflect.DeepEqual(store.busyWrites, []bool{true, false}) {
+		t.Fatalf("busy writes = %v, want the session held busy for the command", store.busyWrites)
+	}
+
+	// A failing command is a result, not an error.
+	res, err = r.RerunSetup("session-1", "false")
+	if err != nil || res.ExitCode != 1 {
+		t.Fatalf("RerunSetup(false) = %+v, %v; want exit 1", res, err)
+	}
+
+	// Without a command, the base worktree's .fog.yaml supplies it. The
+	// session worktree's copy is the AI's to edit and is ignored.
+	writeFile(t, wt, RepoConfigFile, "setup_cmd: echo from-ai\n")
+	if _, err := r.RerunSetup("session-1", ""); !errors.Is(err, ErrNoSetupCmd) {
+		t.Fatalf("RerunSetup without a command = %v, want ErrNoSetupCmd", err)
+	}
+	writeFile(t, base, RepoConfigFile, "setup_cmd: echo from-config\n")
+	if res, err := r.RerunSetup("session-1", ""); err != nil || res.Command != "echo from-config" {
+		t.Fatalf("RerunSetup from .fog.yaml = %+v, %v", res, err)
+	}
+
+	if _, err := r.RerunSetup("session-1", "npm ci && rm -rf /"); !errors.Is(err, ErrInvalidLaunch) {
+		t.Fatalf("RerunSetup with a chained command = %v, want ErrInvalidLaunch", err)
+	}
+	store.sessions["session-1"].Busy = true
+	if _, err := r.RerunSetup("session-1", "echo again"); !errors.Is(err, ErrSessionBusy) {
+		t.Fatalf("RerunSetup on a busy session = %v, want ErrSessionBusy", err)
+	}
+	store.sessions["session-1"].Busy = false
+	store.sessions["session-1"].WorktreePath = filepath.Join(wt, "gone")
+	if _, err := r.RerunSetup("session-1", "echo again"); !errors.Is(err, ErrWorktreeMissing) {
+		t.Fatalf("RerunSetup without a worktree = %v, want ErrWorktreeMissing", err)
+	}
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// ErrNothingToSquash is returned by SquashSession for a session with no
+// commits over its base branch, or one that has no branch at all.
+var ErrNothingToSquash = errors.New("nothing to squash")
+
+// SessionSquash describes the commit SquashSession left on the branch.
+type SessionSquash struct {
+	SHA     string
+	Message string
+	// Squashed is how many commits were folded into SHA.
+	Squashed int
+}
+
+// SquashSession folds every commit on a session's branch since its merge-base
+// with the base branch into one, with git reset --soft and a single commit.
+// An empty message is generated from the session's first prompt the way a
+// run's commit message is, falling back to a fixed one.
+//
+// Only an unpublished branch is squashed: it refuses once the branch was
+// pushed or a PR exists, since that would rewrite pushed history
+// (commit_strategy squash_force does that deliberately), while a run is in
+// flight, and with uncommitted changes in the worktree. The session is
+// claimed busy for the whole squash so no follow-up starts underneath it.
+func (r *Runner) SquashSession(sessionID, message string) (SessionSquash, error) {
+	session, worktreePath, baseBranch, err := r.sessionBranchContext(strings.TrimSpace(sessionID))
+	if errors.Is(err, errScratchNoBranch) {
+		return SessionSquash{}, fmt.Errorf("%w: %w", ErrNothingToSquash, err)
+	}
+	if err != nil {
+		return SessionSquash{}, err
+	}
+	g := git.New(worktreePath).WithContext(r.baseCtx)
+	if err := checkBranchUnpublished(session, g); err != nil {
+		return SessionSquash{}, err
+	}
+	// Claimed before the branch is inspected, so no run can commit between
+	// the count and the reset.
+	release, err := r.claimIdleSession(session.ID)
+	if err != nil {
+		return SessionSquash{}, err
+	}
+	defer release()
+
+	dirty, err := g.IsDirty()
+	if err != nil {
+		return SessionSquash{}, fmt.Errorf("git status failed: %w", err)
+	}
+	if dirty {
+		return SessionSquash{}, ErrDirtyWorktree
+	}
+	head, err := g.HeadSHA()
+	if err != nil {
+		return SessionSquash{}, fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	onto, err := g.MergeBase(baseBranch, head)
+	if err != nil {
+		return SessionSquash{}, fmt.Errorf("git merge-base failed: %w", err)
+	}
+	count, err := g.CountCommits(onto + ".." + head)
+	if err != nil {
+		return SessionSquash{}, fmt.Errorf("git rev-list failed: %w", err)
+	}
+	if count == 0 {
+		return SessionSquash{}, fmt.Errorf("%w: session %q has no commits over %s", ErrNothingToSquash, session.ID, baseBranch)
+	}
+
+	if err := g.ResetSoft(onto); err != nil {
+		return SessionSquash{}, fmt.Errorf("git reset failed: %w", err)
+	}
+	msg := strings.TrimSpace(message)
+	if msg == "" {
+		msg = r.squashCommitMessage(session, worktreePath)
+	}
+	msg = withIssueTrailer(msg, session.IssueRef)
+	sha, err := r.commit(r.baseCtx, g, msg)
+	if err != nil {
+		// Put the branch back rather than leave the session's work staged
+		// on the merge-base.
+		_ = g.ResetSoft(head)
+		return SessionSquash{}, err
+	}
+
+	if latest, found, err := r.runs.GetLatestRun(session.ID); err == nil && found {
+		_ = r.runs.AppendRunEvent(state.RunEvent{
+			RunID:   latest.ID,
+			Type:    "commit",
+			Message: fmt.Sprintf("Squashed %d commit(s) into one", count),
+		})
+	}
+	return SessionSquash{SHA: sha, Message: msg, Squashed: count}, nil
+}
+
+// squashCommitMessage generates a message for the staged squash from the
+// session's first prompt, which states the task the later runs refined.
+func (r *Runner) squashCommitMessage(session state.Session, worktreePath string) string {
+	var prompt string
+	if runs, err := r.runs.ListRuns(session.ID); err == nil && len(runs) > 0 {
+		prompt = runs[len(runs)-1].Prompt // newest first
+	}
+	generated, err := r.generateCommitMessage(r.baseCtx, session.Tool, worktreePath, prompt)
+	if err != nil || strings.TrimSpace(generated) == "" {
+		return fallbackCommitMessage(prompt)
+	}
+	return generated
+}
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// squashFixture is session-1 on fog/test with two commits over main.
+func squashFixture(t *testing.T, tool *fakeTool) (*Runner, *fakeRunStore, string) {
+	t.Helper()
+	wt := initTestWorktree(t)
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", wt}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("checkout", "-q", "-B", "main")
+	git("checkout", "-q", "-b", "fog/test")
+	writeFile(t, wt, "a.txt", "a\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "first run")
+	writeFile(t, wt, "b.txt", "b\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "second run")
+
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	session := testSession(wt)
+	session.Busy = false
+	*store.sessions["session-1"] = session
+	r := newTestRunner(store, tool, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", DefaultBranch: "main"}}
+	return r, store, wt
+}
+
+func commitSubjects(t *testing.T, wt string) []string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", wt, "log", "--format=%s", "main..HEAD").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
+
+func TestSquashSessionWithMessage(t *testing.T) {
+	r, store, wt := squashFixture(t, &fakeTool{name: "claude", available: true})
+
+	squash, err := r.SquashSession("session-1", "feat: add a and b")
+	if err != nil {
+		t.Fatalf("SquashSession: %v", err)
+	}
+	if squash.Squashed != 2 || squash.SHA == "" {
+		t.Fatalf("squash = %+v, want 2 commits folded", squash)
+	}
+	if got := commitSubjects(t, wt); len(got) != 1 || got[0] != "feat: add a and b" {
+		t.Fatalf("commits over main = %v, want the one squashed commit", got)
+	}
+	if _, ok := store.eventOfType("commit"); !ok || !store.busyCleared() {
+		t.Fatal("want a commit event and the session released")
+	}
+}
+
+func TestSquashSessionGeneratesMessage(t *testing.T) {
+	tool := &fakeTool{name: "claude", available: true, output: "feat: generated message"}
+	r, store, wt := squashFixture(t, tool)
+	store.runs["run-1"].Prompt = "add a feature"
+
+	squash, err := r.SquashSession("session-1", "")
+	if err != nil {
+		t.Fatalf("SquashSession: %v", err)
+	}
+	if squash.Message != "feat: generated message" {
+		t.Fatalf("message = %q, want the generated one", squash.Message)
+	}
+	if !strings.Contains(tool.request().Prompt, "add a feature") {
+		t.Fatalf("commit message prompt did not carry the session prompt: %q", tool.request().Prompt)
+	}
+	if got := commitSubjects(t, wt); len(got) != 1 {
+		t.Fatalf("commits over main = %v, want one", got)
+	}
+}
+
+func TestSquashSessionRefusals(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup func(t *testing.T, r *Runner, store *fakeRunStore, wt string)
+		want  error
+	}{
+		{"busy", func(t *testing.T, r *Runner, store *fakeRunStore, wt string) {
+			store.sessions["session-1"].Busy = true
+		}, ErrSessionBusy},
+		{"published", func(t *testing.T, r *Runner, store *fakeRunStore, wt string) {
+			store.sessions["session-1"].PRURL = "https://github.com/acme/api/pull/1"
+		}, ErrBranchPublished},
+		{"pushed", func(t *testing.T, r *Runner, store *fakeRunStore, wt string) {
+			gitOutput(t, wt, "config", "branch."+store.sessions["session-1"].Branch+".remote", "origin")
+		}, ErrBranchPublished},
+		{"dirty", func(t *testing.T, r *Runner, store *fakeRunStore, wt string) {
+			writeFile(t, wt, "c.txt", "c\n")
+		}, ErrDirtyWorktree},
+		{"no commits", func(t *testing.T, r *Runner, store *fakeRunStore, wt string) {
+			if out, err := exec.Command("git", "-C", wt, "reset", "-q", "--hard", "main").CombinedOutput(); err != nil {
+				t.Fatalf("reset: %v\n%s", err, out)
+			}
+		}, ErrNothingToSquash},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, store, wt := squashFixture(t, &fakeTool{name: "claude", available: true})
+			tc.setup(t, r, store, wt)
+			busy := store.sessions["session-1"].Busy
+			if _, err := r.SquashSession("session-1", "feat: squashed"); !errors.Is(err, tc.want) {
+				t.Fatalf("SquashSession = %v, want %v", err, tc.want)
+			}
+			if store.sessions["session-1"].Busy != busy {
+				t.Errorf("busy = %v after a refused squash, want %v", store.sessions["session-1"].Busy, busy)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"io"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +15,8 @@ type runStreamWriter struct {
 	runID     string
 	buffer    strings.Builder
 	lastFlush time.Time
+	// log, when set, gets every chunk in full; the events are truncated.
+	log io.WriteCloser
 }
 
 func newRunStreamWriter(store state.RunEventSink, runID string) *runStreamWriter {
@@ -30,6 +33,9 @@ func (w *runStreamWriter) Append(chunk string) {
 	}
 
 	w.mu.Lock()
+	if w.log != nil {
+		_, _ = io.WriteString(w.log, chunk)
+	}
 	w.buffer.WriteString(chunk)
 	shouldFlush := w.buffer.Len() >= 1000 || time.Since(w.lastFlush) >= 600*time.Millisecond
 	w.mu.Unlock()
@@ -60,3 +66,14 @@ func (w *runStreamWriter) Flush() {
 		Data:  truncate(payload, 8000),
 	})
 }
+
+// Close flushes what is buffered and closes the log file, if any.
+func (w *runStreamWriter) Close() {
+	w.Flush()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.log != nil {
+		_ = w.log.Close()
+		w.log = nil
+	}
+}
@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -87,6 +88,49 @@ func TestContinueSessionRejectsBusySession(t *testing.T) {
 	}
 }
 
+func TestPausedSessionRejectsFollowUpsAndForks(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	store.sessions["session-1"].Paused = true
+	r := newTestRunner(store, &fakeTool{}, nil)
+
+	if _, _, _, err := r.prepareFollowUpRun("session-1", "carry on"); !errors.Is(err, ErrSessionPaused) {
+		t.Fatalf("follow-up error = %v, want ErrSessionPaused", err)
+	}
+	if store.sessions["session-1"].Busy {
+		t.Error("a refused follow-up marked the session busy")
+	}
+	_, _, err := r.prepareForkSession("session-1", ForkSessionOptions{Branch: "fog/fork", Prompt: "try another way"})
+	if !errors.Is(err, ErrSessionPaused) {
+		t.Fatalf("fork error = %v, want ErrSessionPaused", err)
+	}
+}
+
+func TestForkRecordsParentSession(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	wt := initTestWorktree(t)
+	source := store.sessions["session-1"]
+	source.RepoName, source.Tool, source.Branch, source.WorktreePath = "acme/api", "claude", "fog/source", wt
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", BaseWorktreePath: wt, DefaultBranch: "master"}}
+
+	opts, _, err := r.prepareForkSession("session-1", ForkSessionOptions{Branch: "fog/fork", Prompt: "try another way", FullTranscriptContext: true})
+	if err != nil {
+		t.Fatalf("prepareForkSession: %v", err)
+	}
+	if opts.ParentSessionID != "session-1" {
+		t.Fatalf("ParentSessionID = %q, want session-1", opts.ParentSessionID)
+	}
+	session, _, _, err := r.prepareSession(opts)
+	if err != nil {
+		t.Fatalf("prepareSession: %v", err)
+	}
+	if stored := store.sessions[session.ID]; stored == nil || stored.ParentSessionID != "session-1" {
+		t.Fatalf("stored fork = %+v, want parent session-1", stored)
+	}
+}
+
 func TestPrepareFollowUpRunReusesSessionWorktree(t *testing.T) {
 	st, err := state.NewStore(t.TempDir())
 	if err != nil {
@@ -239,6 +283,25 @@ func TestCancelSessionLatestRunCancelsActiveLatestRun(t *testing.T) {
 	}
 }
 
+func TestCancelRunIfActive(t *testing.T) {
+	store := newFakeRunStore()
+	store.seed("session-1", "run-2")
+	r := newTestRunner(store, &fakeTool{}, nil)
+
+	called := false
+	r.active["session-1"] = &activeRun{sessionID: "session-1", runID: "run-2", cancel: func() { called = true }}
+
+	if r.CancelRunIfActive("session-1", "run-1") || called {
+		t.Fatal("expected a run that is not executing to be left alone")
+	}
+	if !r.CancelRunIfActive("session-1", "run-2") || !called {
+		t.Fatal("expected the executing run to be cancelled")
+	}
+	if _, ok := store.eventOfType("cancel_requested"); !ok {
+		t.Fatal("expected a cancel_requested event")
+	}
+}
+
 func TestLookupConversationIDFindsLatestPriorRunSessionID(t *testing.T) {
 	st, err := state.NewStore(t.TempDir())
 	if err != nil {
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+// SettingToolArgs holds extra CLI arguments per AI tool, as a JSON object
+// such as {"claude": ["--max-turns", "20"]}. Each tool invocation gets its
+// tool's arguments after the flags Fog sets. Unset passes none.
+const SettingToolArgs = "tool_args"
+
+// ParseToolArgs parses and checks a tool_args value, keying it by each tool's
+// canonical name. Unknown tools and arguments ai.ValidateExtraArgs refuses
+// are errors; a tool with no arguments is dropped. Empty parses to nil.
+func ParseToolArgs(raw string) (map[string][]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var parsed map[string][]string
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of tool name to argument list: %w", err)
+	}
+	return NormalizeToolArgs(parsed)
+}
+
+// NormalizeToolArgs checks args as ParseToolArgs does and keys them by each
+// tool's canonical name.
+func NormalizeToolArgs(args map[string][]string) (map[string][]string, error) {
+	out := make(map[string][]string, len(args))
+	for name, toolArgs := range args {
+		tool, err := ai.GetTool(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := ai.ValidateExtraArgs(toolArgs); err != nil {
+			return nil, fmt.Errorf("%s: %w", tool.Name(), err)
+		}
+		if len(toolArgs) > 0 {
+			out[tool.Name()] = toolArgs
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// ToolArgs reads tool_args. Like tool_exec_wrapper a malformed value is an
+// error rather than ignored, so a run does not silently go without flags its
+// user relies on.
+func (r *Runner) ToolArgs() (map[string][]string, error) {
+	if r.settings == nil {
+		return nil, nil
+	}
+	raw, found, err := r.settings.GetSetting(SettingToolArgs)
+	if err != nil || !found {
+		return nil, err
+	}
+	args, err := ParseToolArgs(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", SettingToolArgs, err)
+	}
+	return args, nil
+}
@@ -0,0 +1,31 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/darkLord19/foglet/internal/ai"
+)
+
+// SettingToolExecWrapper is a template the AI tool's command runs inside,
+// such as "nice -n 10 {cmd}" or "ssh buildbox {cmd}". {cmd} stands for the
+// tool's argv. Unset runs the tool directly.
+const SettingToolExecWrapper = "tool_exec_wrapper"
+
+// ToolExecWrapper reads tool_exec_wrapper as an argv. Unlike most settings a
+// malformed value is an error rather than a fallback: a wrapper may exist to
+// send the tool elsewhere or inject credentials, and silently running the
+// tool unwrapped would do neither.
+func (r *Runner) ToolExecWrapper() ([]string, error) {
+	if r.settings == nil {
+		return nil, nil
+	}
+	raw, found, err := r.settings.GetSetting(SettingToolExecWrapper)
+	if err != nil || !found {
+		return nil, err
+	}
+	wrapper, err := ai.ParseExecWrapper(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", SettingToolExecWrapper, err)
+	}
+	return wrapper, nil
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunToolPassesExecWrapper(t *testing.T) {
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(newFakeRunStore(), tool, fakeSettings{SettingToolExecWrapper: "nice -n 10 {cmd}"})
+
+	if _, _, _, err := r.runToolWithOptions(context.Background(), "", "claude", t.TempDir(), "fix it", "", "", nil, nil); err != nil {
+		t.Fatalf("runToolWithOptions: %v", err)
+	}
+	if got := tool.request().Wrapper; !slices.Equal(got, []string{"nice", "-n", "10", "{cmd}"}) {
+		t.Fatalf("wrapper = %q", got)
+	}
+}
+
+func TestRunToolRefusesMalformedExecWrapper(t *testing.T) {
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(newFakeRunStore(), tool, fakeSettings{SettingToolExecWrapper: "sh -c '{cmd}'"})
+
+	_, _, _, err := r.runToolWithOptions(context.Background(), "", "claude", t.TempDir(), "fix it", "", "", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), SettingToolExecWrapper) {
+		t.Fatalf("error = %v, want a tool_exec_wrapper error", err)
+	}
+	if tool.calls != 0 {
+		t.Error("the tool ran despite a malformed wrapper")
+	}
+}
+
+func TestRunToolPassesToolArgs(t *testing.T) {
+	tool := &fakeTool{name: "claude", available: true, output: "done"}
+	r := newTestRunner(newFakeRunStore(), tool, fakeSettings{
+		SettingToolArgs: `{"claude-code": ["--max-turns", "20"], "cursor": ["--verbose"]}`,
+	})
+
+	if _, _, _, err := r.runToolWithOptions(context.Background(), "", "claude", t.TempDir(), "fix it", "", "", nil, nil); err != nil {
+		t.Fatalf("runToolWithOptions: %v", err)
+	}
+	if got := tool.request().ExtraArgs; !slices.Equal(got, []string{"--max-turns", "20"}) {
+		t.Fatalf("extra args = %q, want only claude's", got)
+	}
+}
+
+func TestParseToolArgsRefusesUnknownToolsAndShellSyntax(t *testing.T) {
+	for _, raw := range []string{
+		`["--max-turns"]`,
+		`{"vim": ["--max-turns", "20"]}`,
+		`{"claude": ["--max-turns", "$(reboot)"]}`,
+	} {
+		if _, err := ParseToolArgs(raw); err == nil {
+			t.Errorf("ParseToolArgs(%s) accepted", raw)
+		}
+	}
+}
@@ -55,7 +55,8 @@ func (r *Runner) RemoveSessionArtifacts(sessionID string) error {
 			errs = append(errs, fmt.Errorf("remove worktree %s: %w", wt, err))
 		}
 	}
-	if branch := strings.TrimSpace(session.Branch); branch != "" {
+	// A scratch session's branch is only a label; there is nothing to delete.
+	if branch := strings.TrimSpace(session.Branch); branch != "" && !session.Ephemeral {
 		if err := g.DeleteBranch(branch, true); err != nil {
 			errs = append(errs, fmt.Errorf("delete branch %s: %w", branch, err))
 		}
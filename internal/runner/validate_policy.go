@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/proc"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SettingValidateFailPolicy selects what happens to the AI's uncommitted
+// changes when validation fails. Validation always gates the commit: a run
+// that fails it never commits, pushes or opens a PR, whatever the policy.
+const SettingValidateFailPolicy = "validate_fail_policy"
+
+// Validation failure policies.
+const (
+	// ValidateFailKeep leaves the changes in the worktree for inspection. It
+	// is the default.
+	ValidateFailKeep = "keep"
+	// ValidateFailDiscard resets the worktree to the last commit, so the next
+	// follow-up starts from the session's committed state.
+	ValidateFailDiscard = "discard"
+)
+
+// ValidValidateFailPolicy reports whether policy is a known
+// validate_fail_policy. Empty is valid and means keep.
+func ValidValidateFailPolicy(policy string) bool {
+	switch strings.TrimSpace(policy) {
+	case "", ValidateFailKeep, ValidateFailDiscard:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Runner) validateFailPolicy() string {
+	if r.settings == nil {
+		return ValidateFailKeep
+	}
+	raw, found, err := r.settings.GetSetting(SettingValidateFailPolicy)
+	if err != nil || !found || strings.TrimSpace(raw) != ValidateFailDiscard {
+		return ValidateFailKeep
+	}
+	return ValidateFailDiscard
+}
+
+// discardAfterFailedValidation applies ValidateFailDiscard to a worktree whose
+// validation just failed. It returns the run event message describing what it
+// did, or "" under the keep policy.
+func (r *Runner) discardAfterFailedValidation(worktreePath string) (string, error) {
+	if r.validateFailPolicy() != ValidateFailDiscard {
+		return "", nil
+	}
+	if err := git.New(worktreePath).DiscardChanges(); err != nil {
+		return "", fmt.Errorf("git reset failed: %w", err)
+	}
+	return "Discarded uncommitted changes after failed validation", nil
+}
+
+// recordValidateOutput records the validation command's output as a
+// validate_output event, with its exit code as the event data, so a failing
+// test run can be read back before deciding on a follow-up. code and err are
+// runShellAllowing's results. A command that never ran to exit, because it
+// could not start or was canceled, records nothing.
+func (r *Runner) recordValidateOutput(runID string, code int, output []byte, err error) {
+	if err != nil {
+		exitCode, exited := proc.ExitCode(err)
+		if !exited {
+			return
+		}
+		code = exitCode
+	}
+	_ = r.runs.AppendRunEvent(state.RunEvent{
+		RunID:   runID,
+		Type:    "validate_output",
+		Message: truncate(string(output), 8000),
+		Data:    strconv.Itoa(code),
+	})
+}
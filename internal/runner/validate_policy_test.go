@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failValidation runs a session whose validation fails after the AI left an
+// uncommitted file, with validate_fail_policy set to policy.
+func failValidation(t *testing.T, policy string) (*fakeRunStore, string) {
+	t.Helper()
+	store := newFakeRunStore()
+	store.seed("session-1", "run-1")
+	settings := fakeSettings{}
+	if policy != "" {
+		settings[SettingValidateFailPolicy] = policy
+	}
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true, output: "done"}, settings)
+
+	wt := initTestWorktree(t)
+	writeFile(t, wt, "feature.txt", "work")
+
+	err := r.executeSessionRun(testSession(wt), testRun(wt), sessionRunOptions{
+		Prompt:      "add a feature",
+		BaseBranch:  "main",
+		Validate:    true,
+		ValidateCmd: "exit 3",
+		CommitMsg:   "feat: add a feature",
+	})
+	if err == nil {
+		t.Fatal("expected validation failure")
+	}
+	if got := store.runs["run-1"].State; got != "FAILED" {
+		t.Errorf("run state = %q, want FAILED", got)
+	}
+	if got := store.runs["run-1"].CommitSHA; got != "" {
+		t.Errorf("failed validation must not commit, got %q", got)
+	}
+	return store, wt
+}
+
+func TestValidateFailKeepLeavesChanges(t *testing.T) {
+	store, wt := failValidation(t, "")
+	if _, err := os.Stat(filepath.Join(wt, "feature.txt")); err != nil {
+		t.Fatalf("expected AI changes kept for inspection: %v", err)
+	}
+	if _, ok := store.eventOfType("cleanup"); ok {
+		t.Error("keep policy must not record a cleanup event")
+	}
+}
+
+func TestValidateFailDiscardResetsWorktree(t *testing.T) {
+	store, wt := failValidation(t, ValidateFailDiscard)
+	if _, err := os.Stat(filepath.Join(wt, "feature.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected AI changes discarded, stat err = %v", err)
+	}
+	if _, ok := store.eventOfType("cleanup"); !ok {
+		t.Error("expected a cleanup event")
+	}
+}
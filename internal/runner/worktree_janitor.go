@@ -0,0 +1,197 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/git"
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SettingWorktreeTTLDays is the settings key for how many days a finished
+// session keeps its worktree before the worktree janitor removes it. Unset or
+// 0 keeps worktrees until the session is deleted.
+const SettingWorktreeTTLDays = "worktree_ttl_days"
+
+// worktreeJanitorInterval is how often the daemon prunes stale worktrees.
+const worktreeJanitorInterval = 1 * time.Hour
+
+// ErrWorktreePruned is returned for a follow-up or retry in a session whose
+// worktree the janitor removed. Its branch is still there to check out.
+var ErrWorktreePruned = errors.New("session worktree was pruned")
+
+// WorktreeTTL reads worktree_ttl_days. An unset, malformed or negative value
+// is 0, which disables pruning.
+func (r *Runner) WorktreeTTL() time.Duration {
+	if r.settings == nil {
+		return 0
+	}
+	raw, found, err := r.settings.GetSetting(SettingWorktreeTTLDays)
+	if err != nil || !found {
+		return 0
+	}
+	days, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || days < 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// StartWorktreeJanitor prunes stale worktrees now and then on an interval
+// until ctx is cancelled.
+func (r *Runner) StartWorktreeJanitor(ctx context.Context) {
+	if n, err := r.PruneStaleWorktrees(); err != nil {
+		slog.Error("worktree janitor: initial sweep failed", "err", err)
+	} else if n > 0 {
+		slog.Info("worktree janitor: pruned worktrees", "count", n)
+	}
+
+	go func() {
+		ticker := time.NewTicker(worktreeJanitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n, err := r.PruneStaleWorktrees(); err != nil {
+					slog.Error("worktree janitor: sweep failed", "err", err)
+				} else if n > 0 {
+					slog.Info("worktree janitor: pruned worktrees", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+// PruneStaleWorktrees removes the worktree of every finished session last
+// updated more than worktree_ttl_days ago and marks the session pruned. The
+// branch is kept. A session is skipped while a run is live or it is paused,
+// when its worktree has uncommitted changes, and when it has no PR and its branch holds commits
+// that are neither pushed nor on the base branch. Like the trash purge it is
+// best-effort per session; it returns how many worktrees it removed.
+func (r *Runner) PruneStaleWorktrees() (int, error) {
+	ttl := r.WorktreeTTL()
+	if ttl <= 0 || r.runs == nil || r.repos == nil {
+		return 0, nil
+	}
+	sessions, err := r.runs.ListSessions()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-ttl)
+	pruned := 0
+	for _, sess := range sessions {
+		if !worktreePrunable(sess, cutoff) {
+			continue
+		}
+		ok, err := r.pruneSessionWorktree(sess)
+		if err != nil {
+			slog.Warn("worktree janitor: prune worktree failed", "session_id", sess.ID, "err", err)
+			continue
+		}
+		if ok {
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// worktreePrunable reports whether a session is old and finished enough for
+// the janitor to look at its worktree.
+func worktreePrunable(sess state.Session, cutoff time.Time) bool {
+	if sess.Busy || sess.Paused || sess.WorktreePrunedAt != nil || !sess.UpdatedAt.Before(cutoff) {
+		return false
+	}
+	switch sess.Status {
+	case "COMPLETED", "FAILED", "CANCELLED":
+		return true
+	}
+	return false
+}
+
+// pruneSessionWorktree removes one session's worktree, reporting false
+// without an error when the session must keep it. A worktree already gone
+// from disk is only marked.
+//
+// The session is claimed busy for the whole check and removal, so a
+// follow-up, rename or squash that starts meanwhile is refused rather than
+// finding its worktree deleted under it. A session someone else holds is
+// skipped until the next sweep.
+func (r *Runner) pruneSessionWorktree(sess state.Session) (pruned bool, retErr error) {
+	r.mu.Lock()
+	_, active := r.active[sess.ID]
+	r.mu.Unlock()
+	if active {
+		return false, nil
+	}
+	claimed, err := r.runs.ClaimSessionBusy(sess.ID)
+	if err != nil || !claimed {
+		return false, err
+	}
+	defer func() {
+		if err := r.runs.SetSessionBusy(sess.ID, false); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+	// The listed copy may predate a run that finished, or a pause that
+	// landed, before the claim.
+	sess, found, err := r.runs.GetSession(sess.ID)
+	if err != nil || !found || sess.Paused || sess.WorktreePrunedAt != nil {
+		return false, err
+	}
+
+	wt := strings.TrimSpace(sess.WorktreePath)
+	if wt == "" {
+		return false, nil
+	}
+	if _, err := os.Stat(wt); errors.Is(err, os.ErrNotExist) {
+		return true, r.runs.MarkSessionWorktreePruned(sess.ID)
+	}
+
+	repo, found, err := r.repos.GetRepoByName(sess.RepoName)
+	if err != nil {
+		return false, err
+	}
+	if !found || strings.TrimSpace(repo.BaseWorktreePath) == "" {
+		return false, fmt.Errorf("%w: %s", ErrUnknownRepo, sess.RepoName)
+	}
+	if strings.TrimSpace(sess.PRURL) == "" && !branchPreserved(wt, sess.Branch, r.ResolveBaseBranch("", repo)) {
+		return false, nil
+	}
+	// Not forced: git refuses a worktree with uncommitted or untracked
+	// changes, which is work the branch does not hold.
+	if err := git.New(repo.BaseWorktreePath).RemoveWorktree(wt, false); err != nil {
+		return false, fmt.Errorf("remove worktree %s: %w", wt, err)
+	}
+	return true, r.runs.MarkSessionWorktreePruned(sess.ID)
+}
+
+// branchPreserved reports whether every commit checked out in worktreePath
+// survives the worktree: it is on the base branch, or origin's copy of branch
+// has it. Anything it cannot check counts as not preserved.
+func branchPreserved(worktreePath, branch, baseBranch string) bool {
+	g := git.New(worktreePath)
+	base := "origin/" + baseBranch
+	if !g.RefExists(base) {
+		base = baseBranch
+	}
+	if g.RefExists(base) && g.IsAncestor("HEAD", base) {
+		return true
+	}
+	branch = strings.TrimSpace(branch)
+	if branch == "" {
+		return false
+	}
+	sha, err := g.RemoteBranchSHA(branch)
+	if err != nil || sha == "" {
+		return false
+	}
+	return g.IsAncestor("HEAD", sha)
+}
@@ -0,0 +1,181 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// initTestSessionWorktree makes a base repo with main checked out and a
+// worktree on a new branch, fog/<name>, beside it.
+func initTestSessionWorktree(t *testing.T, name string) (base, wt string) {
+	t.Helper()
+	base = initTestWorktree(t)
+	wt = filepath.Join(t.TempDir(), name)
+	for _, args := range [][]string{
+		{"branch", "-M", "main"},
+		{"worktree", "add", "-b", "fog/" + name, wt},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = base
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	return base, wt
+}
+
+func commitIn(t *testing.T, dir, name string) {
+	t.Helper()
+	writeFile(t, dir, name, "work")
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "work"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+}
+
+func TestPruneStaleWorktrees(t *testing.T) {
+	store := newFakeRunStore()
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, fakeSettings{SettingWorktreeTTLDays: "7"})
+	base, _ := initTestSessionWorktree(t, "setup")
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", BaseWorktreePath: base, DefaultBranch: "main"}}
+
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	add := func(id, status string, updated time.Time, edit func(wt string, s *state.Session)) string {
+		wt := filepath.Join(t.TempDir(), id)
+		cmd := exec.Command("git", "worktree", "add", "-b", "fog/"+id, wt)
+		cmd.Dir = base
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git worktree add: %v\n%s", err, out)
+		}
+		s := &state.Session{ID: id, RepoName: "acme/api", Branch: "fog/" + id, WorktreePath: wt, Status: status, UpdatedAt: updated}
+		if edit != nil {
+			edit(wt, s)
+		}
+		store.sessions[id] = s
+		return wt
+	}
+	remote := t.TempDir()
+	for _, cmd := range []*exec.Cmd{exec.Command("git", "init", "--bare", remote), exec.Command("git", "-C", base, "remote", "add", "origin", remote)} {
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s: %v\n%s", cmd, err, out)
+		}
+	}
+
+	// Prunable: nothing beyond main, pushed, or the unpushed work is in a PR.
+	clean := add("clean", "COMPLETED", old, nil)
+	pushed := add("pushed", "CANCELLED", old, func(wt string, s *state.Session) {
+		commitIn(t, wt, "pushed.txt")
+		cmd := exec.Command("git", "push", "origin", s.Branch)
+		cmd.Dir = wt
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git push: %v\n%s", err, out)
+		}
+	})
+	withPR := add("with-pr", "FAILED", old, func(wt string, s *state.Session) {
+		commitIn(t, wt, "pr.txt")
+		s.PRURL = "https://github.com/acme/api/pull/1"
+	})
+	// Kept.
+	recent := add("recent", "COMPLETED", time.Now(), nil)
+	running := add("running", "AI_RUNNING", old, nil)
+	busy := add("busy", "COMPLETED", old, func(_ string, s *state.Session) { s.Busy = true })
+	unpushed := add("unpushed", "COMPLETED", old, func(wt string, _ *state.Session) { commitIn(t, wt, "local.txt") })
+	dirty := add("dirty", "COMPLETED", old, func(wt string, _ *state.Session) { writeFile(t, wt, "scratch.txt", "wip") })
+	live := add("live", "COMPLETED", old, nil)
+	r.active["live"] = &activeRun{}
+	paused := add("paused", "COMPLETED", old, func(_ string, s *state.Session) { s.Paused = true })
+
+	n, err := r.PruneStaleWorktrees()
+	if err != nil {
+		t.Fatalf("PruneStaleWorktrees: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("pruned %d worktrees, want 3", n)
+	}
+	for id, wt := range map[string]string{"clean": clean, "pushed": pushed, "with-pr": withPR} {
+		if _, err := os.Stat(wt); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s worktree still on disk: %v", id, err)
+		}
+		if store.sessions[id].WorktreePrunedAt == nil {
+			t.Errorf("%s not marked pruned", id)
+		}
+		if store.sessions[id].Busy {
+			t.Errorf("%s left busy after pruning", id)
+		}
+		cmd := exec.Command("git", "rev-parse", "--verify", "fog/"+id)
+		cmd.Dir = base
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s branch was deleted: %v\n%s", id, err, out)
+		}
+	}
+	for id, wt := range map[string]string{"recent": recent, "running": running, "busy": busy, "unpushed": unpushed, "dirty": dirty, "live": live, "paused": paused} {
+		if _, err := os.Stat(wt); err != nil {
+			t.Errorf("%s worktree was removed: %v", id, err)
+		}
+		if store.sessions[id].WorktreePrunedAt != nil {
+			t.Errorf("%s marked pruned", id)
+		}
+	}
+
+	// A pruned session takes no follow-ups, forks or branch reads.
+	if _, err := r.ContinueSession("clean", "more work"); !errors.Is(err, ErrWorktreePruned) {
+		t.Fatalf("ContinueSession on a pruned session = %v, want ErrWorktreePruned", err)
+	}
+	if _, _, err := r.ForkSession("clean", ForkSessionOptions{Branch: "fog/clean-fork", Prompt: "try again"}); !errors.Is(err, ErrWorktreePruned) {
+		t.Fatalf("ForkSession on a pruned session = %v, want ErrWorktreePruned", err)
+	}
+	if _, _, err := r.SessionDiff("clean"); !errors.Is(err, ErrWorktreePruned) {
+		t.Fatalf("SessionDiff on a pruned session = %v, want ErrWorktreePruned", err)
+	}
+}
+
+func TestPruneSessionWorktreeSkipsSessionClaimedAfterListing(t *testing.T) {
+	store := newFakeRunStore()
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, fakeSettings{SettingWorktreeTTLDays: "7"})
+	base, wt := initTestSessionWorktree(t, "old")
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", BaseWorktreePath: base, DefaultBranch: "main"}}
+	listed := state.Session{ID: "old", RepoName: "acme/api", Branch: "fog/old", WorktreePath: wt, Status: "COMPLETED", UpdatedAt: time.Now().Add(-30 * 24 * time.Hour)}
+	store.sessions["old"] = &listed
+
+	// A follow-up claimed the session after the janitor listed it.
+	if claimed, err := store.ClaimSessionBusy("old"); err != nil || !claimed {
+		t.Fatalf("ClaimSessionBusy = %v, %v", claimed, err)
+	}
+	snapshot := listed
+	snapshot.Busy = false
+	pruned, err := r.pruneSessionWorktree(snapshot)
+	if err != nil || pruned {
+		t.Fatalf("pruneSessionWorktree = %v, %v; want false, nil", pruned, err)
+	}
+	if _, err := os.Stat(wt); err != nil {
+		t.Fatalf("worktree removed under a busy session: %v", err)
+	}
+	if !store.sessions["old"].Busy {
+		t.Fatal("the janitor released a claim it did not hold")
+	}
+}
+
+func TestPruneStaleWorktreesDisabled(t *testing.T) {
+	store := newFakeRunStore()
+	r := newTestRunner(store, &fakeTool{name: "claude", available: true}, nil)
+	base, wt := initTestSessionWorktree(t, "old")
+	r.repos = fakeRepos{"acme/api": {Name: "acme/api", BaseWorktreePath: base, DefaultBranch: "main"}}
+	store.sessions["old"] = &state.Session{ID: "old", RepoName: "acme/api", Branch: "fog/old", WorktreePath: wt, Status: "COMPLETED", UpdatedAt: time.Now().Add(-365 * 24 * time.Hour)}
+
+	if n, err := r.PruneStaleWorktrees(); err != nil || n != 0 {
+		t.Fatalf("PruneStaleWorktrees without a TTL = %d, %v; want 0, nil", n, err)
+	}
+	if _, err := os.Stat(wt); err != nil {
+		t.Fatalf("worktree removed without a TTL: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// SettingDefaultWorktreeRoot is the settings key for a directory session
+// worktrees are created under, as <root>/<repo-name>/<worktree-name>, instead
+// of next to the repo. A repo's own worktree_root overrides it.
+const SettingDefaultWorktreeRoot = "default_worktree_root"
+
+// ValidateWorktreeRoot checks a worktree root before it is stored. Empty is
+// valid and means no override.
+func ValidateWorktreeRoot(root string) error {
+	root = strings.TrimSpace(root)
+	if root != "" && !filepath.IsAbs(root) {
+		return fmt.Errorf("worktree root %q must be an absolute path", root)
+	}
+	return nil
+}
+
+// WorktreeDir returns the directory new session worktrees for repo go in: its
+// worktree_root, else default_worktree_root, joined with the repo name. It is
+// empty when neither is set, leaving placement to the wtx config.
+func (r *Runner) WorktreeDir(repo state.Repo) string {
+	root := strings.TrimSpace(repo.WorktreeRoot)
+	if root == "" && r.settings != nil {
+		if stored, found, err := r.settings.GetSetting(SettingDefaultWorktreeRoot); err == nil && found {
+			root = strings.TrimSpace(stored)
+		}
+	}
+	if root == "" {
+		return ""
+	}
+	return filepath.Join(root, filepath.FromSlash(repo.Name))
+}
+
+// sessionWorktreeDir resolves WorktreeDir for a session on repoName and
+// checks that worktrees can be created there.
+func (r *Runner) sessionWorktreeDir(repoName string) (string, error) {
+	repo := state.Repo{Name: repoName}
+	if r.repos != nil {
+		found, ok, err := r.repos.GetRepoByName(repoName)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			repo = found
+		}
+	}
+	dir := r.WorktreeDir(repo)
+	if dir == "" {
+		return "", nil
+	}
+	if err := ensureWritableDir(dir); err != nil {
+		return "", fmt.Errorf("worktree root is not writable: %w", err)
+	}
+	return dir, nil
+}
+
+// ensureWritableDir creates dir if needed and checks a file can be created in
+// it, so a read-only or full root fails before any git work starts.
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".fog-write-check-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	return errors.Join(f.Close(), os.Remove(name))
+}
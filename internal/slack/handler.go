@@ -3,6 +3,7 @@ package slack
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -80,9 +81,11 @@ func (h *Handler) HandleCommand(w http.ResponseWriter, r *http.Request) {
 
 	session, run, err := h.runner.Launch(opts)
 	if err != nil {
+		slog.Warn("slack command: launch failed", "repo", opts.RepoName, "channel_id", cmd.ChannelID, "err", err)
 		h.sendErrorResponse(w, err.Error())
 		return
 	}
+	slog.Info("slack command: session started", "session_id", session.ID, "run_id", run.ID, "repo", opts.RepoName, "channel_id", cmd.ChannelID)
 
 	// Store Slack metadata as a run event for thread context lookup
 	if h.stateStore != nil {
@@ -0,0 +1,58 @@
+package slack
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+// notifySessionThreads posts a completion message for every finished run whose
+// session was linked to a Slack thread through the API. Sessions started from
+// Slack are answered by the goroutine that launched them; this covers the rest.
+func (s *SocketMode) notifySessionThreads(ctx context.Context, events <-chan state.RunEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if state.IsFinishEvent(event.Type) {
+				go s.notifySessionThread(ctx, event.RunID)
+			}
+		}
+	}
+}
+
+func (s *SocketMode) notifySessionThread(ctx context.Context, runID string) {
+	store := s.handler.stateStore
+	// A failed run records its error event just before its final state, so
+	// give the state a moment to land.
+	for attempt := 0; ; attempt++ {
+		run, found, err := store.GetRun(runID)
+		if err != nil || !found {
+			return
+		}
+		if isTerminalRunState(run.State) {
+			session, found, err := store.GetSession(run.SessionID)
+			if err != nil || !found || session.SlackChannelID == "" {
+				return
+			}
+			if _, err := s.postMessage(session.SlackChannelID, session.SlackThreadTS, completionTextFromSession(&session, &run)); err != nil {
+				slog.Warn("slack notify failed", "session_id", session.ID, "run_id", run.ID, "err", err)
+			}
+			return
+		}
+		if attempt >= 10 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
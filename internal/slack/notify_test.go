@@ -0,0 +1,163 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkLord19/foglet/internal/state"
+)
+
+func TestNotifySessionThreadsPostsToLinkedThread(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if _, err := store.UpsertRepo(state.Repo{
+		Name: "acme/api", URL: "https://github.com/acme/api.git",
+		Host: "github.com", Owner: "acme", Repo: "api",
+		BarePath: "/tmp/acme/repo.git", BaseWorktreePath: "/tmp/acme/base",
+		DefaultBranch: "main",
+	}); err != nil {
+		t.Fatalf("upsert repo: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, id := range []string{"linked", "unlinked"} {
+		if err := store.CreateSession(state.Session{
+			ID: id, RepoName: "acme/api", Branch: "fog/" + id, WorktreePath: "/tmp/acme/" + id,
+			Tool: "claude", Status: "CREATED", CreatedAt: now, UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		if err := store.CreateRun(state.Run{
+			ID: "run-" + id, SessionID: id, Prompt: "do work", WorktreePath: "/tmp/acme/" + id,
+			State: "AI_RUNNING", CreatedAt: now, UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("create run: %v", err)
+		}
+	}
+	if err := store.SetSessionSlackThread("linked", "C123", "111.222"); err != nil {
+		t.Fatalf("set slack thread: %v", err)
+	}
+
+	chatCh := make(chan map[string]string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		chatCh <- payload
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "ts": "333.444"})
+	}))
+	defer srv.Close()
+
+	sm := NewSocketMode(nil, store, "xapp-test", "xoxb-test")
+	sm.postMessageURL = srv.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, unsubscribe := store.SubscribeRunEvents(8)
+	defer unsubscribe()
+	go sm.notifySessionThreads(ctx, events)
+
+	for _, id := range []string{"unlinked", "linked"} {
+		if err := store.CompleteRun("run-"+id, "COMPLETED", "", "", ""); err != nil {
+			t.Fatalf("complete run: %v", err)
+		}
+		if err := store.AppendRunEvent(state.RunEvent{RunID: "run-" + id, Type: "complete"}); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+
+	select {
+	case payload := <-chatCh:
+		if payload["channel"] != "C123" || payload["thread_ts"] != "111.222" {
+			t.Fatalf("unexpected routing: %+v", payload)
+		}
+		if !strings.Contains(payload["text"], "fog/linked") {
+			t.Fatalf("unexpected message: %q", payload["text"])
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for the completion message")
+	}
+	select {
+	case payload := <-chatCh:
+		t.Fatalf("unlinked session should not be posted, got %+v", payload)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestNotifySessionThreadsPostsTimedOutRun(t *testing.T) {
+	store, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new state store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if _, err := store.UpsertRepo(state.Repo{
+		Name: "acme/api", URL: "https://github.com/acme/api.git",
+		Host: "github.com", Owner: "acme", Repo: "api",
+		BarePath: "/tmp/acme/repo.git", BaseWorktreePath: "/tmp/acme/base",
+		DefaultBranch: "main",
+	}); err != nil {
+		t.Fatalf("upsert repo: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := store.CreateSession(state.Session{
+		ID: "slow", RepoName: "acme/api", Branch: "fog/slow", WorktreePath: "/tmp/acme/slow",
+		Tool: "claude", Status: "CREATED", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := store.CreateRun(state.Run{
+		ID: "run-slow", SessionID: "slow", Prompt: "do work", WorktreePath: "/tmp/acme/slow",
+		State: "AI_RUNNING", CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	if err := store.SetSessionSlackThread("slow", "C123", "111.222"); err != nil {
+		t.Fatalf("set slack thread: %v", err)
+	}
+
+	chatCh := make(chan map[string]string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		chatCh <- payload
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "ts": "333.444"})
+	}))
+	defer srv.Close()
+
+	sm := NewSocketMode(nil, store, "xapp-test", "xoxb-test")
+	sm.postMessageURL = srv.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, unsubscribe := store.SubscribeRunEvents(8)
+	defer unsubscribe()
+	go sm.notifySessionThreads(ctx, events)
+
+	// The order the runner writes them in: the timeout event, then the state.
+	if err := store.AppendRunEvent(state.RunEvent{RunID: "run-slow", Type: "timeout", Message: "ai: timed out after 30m0s"}); err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+	if err := store.CompleteRun("run-slow", "FAILED", "", "", "timed out after 30m0s: signal: killed"); err != nil {
+		t.Fatalf("complete run: %v", err)
+	}
+
+	select {
+	case payload := <-chatCh:
+		if payload["channel"] != "C123" || payload["thread_ts"] != "111.222" {
+			t.Fatalf("unexpected routing: %+v", payload)
+		}
+		if !strings.Contains(payload["text"], "FAILED") || !strings.Contains(payload["text"], "timed out") {
+			t.Fatalf("unexpected message: %q", payload["text"])
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for the timeout message")
+	}
+}
@@ -6,13 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/darkLord19/foglet/internal/runner"
+	"github.com/darkLord19/foglet/internal/slackapi"
 	"github.com/darkLord19/foglet/internal/state"
 	"github.com/gorilla/websocket"
 )
@@ -57,6 +58,12 @@ func (s *SocketMode) Run(ctx context.Context) error {
 		return fmt.Errorf("slack bot token is required")
 	}
 
+	if s.handler.stateStore != nil {
+		events, unsubscribe := s.handler.stateStore.SubscribeRunEvents(64)
+		defer unsubscribe()
+		go s.notifySessionThreads(ctx, events)
+	}
+
 	backoff := time.Second
 	for {
 		if ctx.Err() != nil {
@@ -67,7 +74,7 @@ func (s *SocketMode) Run(ctx context.Context) error {
 		if err == nil {
 			backoff = time.Second
 		} else {
-			log.Printf("slack socket mode disconnected: %v", err)
+			slog.Warn("slack socket mode disconnected", "err", err, "retry_in", backoff)
 			select {
 			case <-ctx.Done():
 				return nil
@@ -177,9 +184,11 @@ func (s *SocketMode) handleSlashEnvelope(raw json.RawMessage) {
 
 	session, run, err := s.handler.runner.Launch(opts)
 	if err != nil {
+		slog.Warn("slack slash command: launch failed", "repo", opts.RepoName, "channel_id", payload.ChannelID, "err", err)
 		s.sendWebhookError(payload.ResponseURL, err.Error())
 		return
 	}
+	slog.Info("slack slash command: session started", "session_id", session.ID, "run_id", run.ID, "repo", opts.RepoName, "channel_id", payload.ChannelID)
 
 	// Store Slack metadata as a run event for thread context lookup
 	if s.handler.stateStore != nil {
@@ -258,9 +267,11 @@ func (s *SocketMode) handleEventsEnvelope(raw json.RawMessage) {
 
 	session, run, err := s.handler.runner.Launch(opts)
 	if err != nil {
+		slog.Warn("slack mention: launch failed", "repo", opts.RepoName, "channel_id", evt.Channel, "err", err)
 		_, _ = s.postMessage(evt.Channel, rootTS, fmt.Sprintf("❌ %s", err.Error()))
 		return
 	}
+	slog.Info("slack mention: session started", "session_id", session.ID, "run_id", run.ID, "repo", opts.RepoName, "channel_id", evt.Channel)
 
 	// Store Slack metadata for thread context lookup
 	if s.handler.stateStore != nil {
@@ -294,9 +305,11 @@ func (s *SocketMode) handleThreadFollowUp(channelID, rootTS, rawPrompt string) {
 	// Continue the existing session with the follow-up prompt
 	run, err := s.handler.runner.ContinueSessionAsync(sessionID, prompt)
 	if err != nil {
+		slog.Warn("slack follow-up failed", "session_id", sessionID, "channel_id", channelID, "err", err)
 		_, _ = s.postMessage(channelID, rootTS, fmt.Sprintf("❌ %s", err.Error()))
 		return
 	}
+	slog.Info("slack follow-up started", "session_id", sessionID, "run_id", run.ID, "channel_id", channelID)
 
 	start := fmt.Sprintf("🚀 Continuing session with: %s", prompt)
 	_, _ = s.postMessage(channelID, rootTS, start)
@@ -396,23 +409,9 @@ func (s *SocketMode) postMessage(channelID, threadTS, text string) (string, erro
 		return "", err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, s.postMessageURL, bytes.NewReader(body))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.botToken)
-	req.Header.Set("User-Agent", "fogd")
-
-	resp, err := s.httpClient.Do(req)
+	data, err := slackapi.PostJSON(s.httpClient, s.postMessageURL, s.botToken, "fogd", body)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode/100 != 2 {
-		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return "", fmt.Errorf("chat.postMessage failed: status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		return "", fmt.Errorf("chat.postMessage failed: %w", err)
 	}
 
 	var result struct {
@@ -420,7 +419,7 @@ func (s *SocketMode) postMessage(channelID, threadTS, text string) (string, erro
 		TS    string `json:"ts"`
 		Error string `json:"error"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(data, &result); err != nil {
 		return "", err
 	}
 	if !result.OK {
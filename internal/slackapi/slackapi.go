@@ -0,0 +1,123 @@
+// Package slackapi sends Slack Web API calls, retrying the ones Slack asks
+// to have retried.
+//
+// Busy workspaces get rate limited (HTTP 429 with Retry-After) and Slack
+// occasionally answers 5xx; both are retried a few times so completion
+// messages are not lost. A total deadline bounds every call, so a wedged Slack
+// cannot block its caller for long.
+package slackapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxRetries is how many times a call is retried after its first
+	// attempt.
+	maxRetries = 3
+	// maxResponseBytes bounds the response body read from Slack.
+	maxResponseBytes = 1 << 20
+)
+
+var (
+	// callDeadline bounds a call across all its attempts and waits. A wait
+	// that would overrun it, as a long Retry-After, ends the call instead.
+	callDeadline = 15 * time.Second
+	// retryBackoff is the wait before the first retry when Slack sends no
+	// Retry-After; it doubles for each later one, with jitter. Tests
+	// shorten it.
+	retryBackoff = 500 * time.Millisecond
+)
+
+// PostJSON posts body to a Slack Web API method at url with token and returns
+// the response body. 429 and 5xx responses are retried up to three times,
+// waiting as long as Retry-After says or a jittered backoff. Other failures,
+// including transport errors that may have reached Slack, are not retried.
+// The final failure is logged as well as returned.
+func PostJSON(client *http.Client, url, token, userAgent string, body []byte) ([]byte, error) {
+	deadline := time.Now().Add(callDeadline)
+	method := url[strings.LastIndex(url, "/")+1:]
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		data, status, header, err := post(client, deadline, url, token, userAgent, body)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+		if !retryable(status) || attempt == maxRetries {
+			break
+		}
+		wait, ok := retryAfter(header)
+		if !ok {
+			wait = backoff(attempt)
+		}
+		if time.Until(deadline) < wait {
+			break
+		}
+		slog.Debug("slack api call retrying", "method", method, "status", status, "attempt", attempt+1, "retry_in", wait)
+		time.Sleep(wait)
+	}
+	slog.Warn("slack api call failed", "method", method, "err", lastErr)
+	return nil, lastErr
+}
+
+// post makes one attempt, reporting the status it got, or 0 when it got
+// none.
+func post(client *http.Client, deadline time.Time, url, token, userAgent string, body []byte) ([]byte, int, http.Header, error) {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, resp.StatusCode, resp.Header, err
+	}
+	if resp.StatusCode/100 != 2 {
+		snippet := strings.TrimSpace(string(data))
+		if len(snippet) > 256 {
+			snippet = snippet[:256]
+		}
+		return nil, resp.StatusCode, resp.Header, fmt.Errorf("slack api status=%d body=%s", resp.StatusCode, snippet)
+	}
+	return data, resp.StatusCode, resp.Header, nil
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status/100 == 5
+}
+
+// retryAfter reads a Retry-After header given in seconds, as Slack sends it.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	secs, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After")))
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+// backoff is the wait before retry attempt+1: retryBackoff doubled attempt
+// times, plus up to half as much again of jitter.
+func backoff(attempt int) time.Duration {
+	wait := retryBackoff << attempt
+	return wait + rand.N(wait/2+1)
+}
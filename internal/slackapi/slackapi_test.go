@@ -0,0 +1,85 @@
+package slackapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slackServer answers each call with the next of statuses, then 200.
+func slackServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("missing bot token: %q", r.Header.Get("Authorization"))
+		}
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, "slow down", statuses[n-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func shortenBackoff(t *testing.T) {
+	t.Helper()
+	prev := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = prev })
+}
+
+func TestPostJSONRetriesRateLimitsAndServerErrors(t *testing.T) {
+	shortenBackoff(t)
+	srv, calls := slackServer(t, "0", http.StatusTooManyRequests, http.StatusBadGateway)
+
+	body, err := PostJSON(srv.Client(), srv.URL+"/chat.postMessage", "xoxb-test", "fogd", []byte(`{}`))
+	if err != nil || string(body) != `{"ok":true}` {
+		t.Fatalf("PostJSON = %q, %v; want the third attempt's body", body, err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("calls = %d, want 3", got)
+	}
+}
+
+func TestPostJSONGivesUpAfterThreeRetries(t *testing.T) {
+	shortenBackoff(t)
+	srv, calls := slackServer(t, "", 500, 500, 500, 500, 500)
+
+	if _, err := PostJSON(srv.Client(), srv.URL+"/chat.postMessage", "xoxb-test", "fogd", []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "status=500") {
+		t.Fatalf("err = %v, want the last 500", err)
+	}
+	if got := calls.Load(); got != 4 {
+		t.Fatalf("calls = %d, want 4", got)
+	}
+}
+
+func TestPostJSONDoesNotRetryClientErrorsOrOverrunTheDeadline(t *testing.T) {
+	shortenBackoff(t)
+	srv, calls := slackServer(t, "", http.StatusBadRequest)
+	if _, err := PostJSON(srv.Client(), srv.URL+"/chat.postMessage", "xoxb-test", "fogd", []byte(`{}`)); err == nil {
+		t.Fatal("expected a 400 to fail")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls after a 400 = %d, want 1", got)
+	}
+
+	// A Retry-After past the deadline ends the call rather than waiting.
+	srv, calls = slackServer(t, "60", http.StatusTooManyRequests)
+	start := time.Now()
+	if _, err := PostJSON(srv.Client(), srv.URL+"/chat.postMessage", "xoxb-test", "fogd", []byte(`{}`)); err == nil {
+		t.Fatal("expected a long rate limit to fail")
+	}
+	if got, took := calls.Load(), time.Since(start); got != 1 || took > 5*time.Second {
+		t.Fatalf("calls = %d after %s; want 1 without waiting", got, took)
+	}
+}
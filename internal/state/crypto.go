@@ -31,6 +31,9 @@ func loadOrCreateMasterKey(path string) ([]byte, error) {
 
 	raw, err := os.ReadFile(path)
 	if err == nil {
+		if err := restrictKeyFile(path); err != nil {
+			return nil, err
+		}
 		return parseKeyFile(raw)
 	}
 	if !errors.Is(err, os.ErrNotExist) {
@@ -66,6 +69,22 @@ func loadOrCreateMasterKey(path string) ([]byte, error) {
 	return key, nil
 }
 
+// restrictKeyFile resets an existing key file to 0600, in case it was copied or
+// restored with a looser mode.
+func restrictKeyFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat key file: %w", err)
+	}
+	if info.Mode().Perm() == 0o600 {
+		return nil
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("set key file permissions: %w", err)
+	}
+	return nil
+}
+
 const keyFileVersionV1 = byte(0x01)
 
 // parseKeyFile handles both legacy (32-byte) and versioned (33-byte) key files.
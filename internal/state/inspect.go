@@ -0,0 +1,51 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// DBInspection is what InspectDB found.
+type DBInspection struct {
+	// Path is the database file.
+	Path string
+	// Exists is false when no Fog process has created the database yet.
+	Exists bool
+	// HasGitHubToken reports whether a GitHub token is stored.
+	HasGitHubToken bool
+}
+
+// InspectDB checks the database in fogHome without changing anything, for
+// diagnostics such as fog doctor. Unlike NewStore it opens the file
+// read-only, and never creates, migrates, locks or recovers it, so it is
+// safe to run while fogd has the database open. A damaged database is
+// reported as an error; a missing one is not.
+func InspectDB(fogHome string) (DBInspection, error) {
+	info := DBInspection{Path: filepath.Join(fogHome, defaultDBName)}
+	if _, err := os.Stat(info.Path); errors.Is(err, os.ErrNotExist) {
+		return info, nil
+	} else if err != nil {
+		return info, fmt.Errorf("stat database: %w", err)
+	}
+	info.Exists = true
+
+	dsn := (&url.URL{Scheme: "file", OmitHost: true, Path: info.Path, RawQuery: "mode=ro"}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return info, fmt.Errorf("open sqlite: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := quickCheck(db); err != nil {
+		return info, err
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(1) FROM secrets WHERE key = ?`, githubPATKey).Scan(&count); err != nil {
+		return info, fmt.Errorf("check github token: %w", err)
+	}
+	info.HasGitHubToken = count > 0
+	return info, nil
+}
@@ -0,0 +1,229 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// SQLite primary result codes that mean the file itself is damaged rather
+// than busy or unreadable for some transient reason.
+const (
+	sqliteCorrupt = 11
+	sqliteNotADB  = 26
+)
+
+var errCorruptDB = errors.New("database is corrupt")
+
+// checkDBIntegrity runs PRAGMA quick_check against the database at path.
+// It returns an error wrapping errCorruptDB when SQLite reports damage, and
+// any other error unchanged so a locked or unreadable file is never mistaken
+// for a corrupt one.
+func checkDBIntegrity(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("open sqlite: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+	return quickCheck(db)
+}
+
+// quickCheck is checkDBIntegrity on an open database.
+func quickCheck(db *sql.DB) error {
+	if _, err := db.Exec(`PRAGMA busy_timeout = 5000;`); err != nil {
+		return corruptOr(err, "set busy timeout")
+	}
+
+	rows, err := db.Query(`PRAGMA quick_check;`)
+	if err != nil {
+		return corruptOr(err, "integrity check")
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return corruptOr(err, "integrity check")
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return corruptOr(err, "integrity check")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", errCorruptDB, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// corruptOr wraps err in errCorruptDB when SQLite blamed the file, and with
+// op otherwise. The driver's result code is used when it exposes one; SQLite's
+// fixed messages for the same codes cover drivers that don't.
+func corruptOr(err error, op string) error {
+	if isCorruptErr(err) {
+		return fmt.Errorf("%w: %v", errCorruptDB, err)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+func isCorruptErr(err error) bool {
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		switch coded.Code() & 0xff {
+		case sqliteCorrupt, sqliteNotADB:
+			return true
+		}
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database disk image is malformed") ||
+		strings.Contains(msg, "file is not a database")
+}
+
+// moveCorruptDB renames the database and its WAL files aside so a fresh
+// database can be created at path, and returns the new database path. The
+// WAL travels with it: left behind, SQLite would replay it into the new file.
+func moveCorruptDB(path string) (string, error) {
+	aside := path + ".corrupt-" + time.Now().UTC().Format("20060102T150405Z")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(path+suffix, aside+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("move corrupt database aside: %w", err)
+		}
+	}
+	return aside, nil
+}
+
+// checkDB checks the database at path and leaves a shared lock on lock, the
+// file every open Store holds, until the Store is closed. A corrupt database
+// is an error unless recover is set. Only fogd sets it, at startup: the
+// database and its WAL files are then moved aside, provided no other Fog
+// process has them open. It returns the aside path, or "" when the database
+// was healthy.
+func checkDB(path string, lock *os.File, recover bool) (string, error) {
+	err := checkDBIntegrity(path)
+	if err == nil {
+		return "", lockShared(lock)
+	}
+	if !errors.Is(err, errCorruptDB) {
+		return "", err
+	}
+	if !recover {
+		return "", fmt.Errorf("%s: %w; start fogd to move it aside (add --db-recover to salvage its readable rows)", path, err)
+	}
+	free, lockErr := tryLockExclusive(lock)
+	if lockErr != nil {
+		return "", fmt.Errorf("lock database: %w", lockErr)
+	}
+	if !free {
+		return "", fmt.Errorf("%s: %w; another Fog process has it open, so it was left in place: stop that process and start fogd again", path, err)
+	}
+	aside, moveErr := moveCorruptDB(path)
+	if moveErr != nil {
+		return "", fmt.Errorf("%v (%w)", err, moveErr)
+	}
+	slog.Warn("state: database failed its integrity check; moved it aside and starting with a fresh database", "path", path, "moved_to", aside, "err", err)
+	// Downgrade, so CLI commands can open the fresh database alongside.
+	return aside, lockShared(lock)
+}
+
+// salvageRows copies every row it can still read from the database at src
+// into s, table by table in creation order so parents land before children.
+// Tables or rows the damage makes unreadable, and rows the fresh schema
+// rejects, are skipped. It returns the number of rows copied.
+func (s *Store) salvageRows(src string) (int, error) {
+	old, err := sql.Open("sqlite", src)
+	if err != nil {
+		return 0, fmt.Errorf("open corrupt database: %w", err)
+	}
+	defer func() { _ = old.Close() }()
+
+	tables, err := queryStrings(s.db, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY rowid`)
+	if err != nil {
+		return 0, fmt.Errorf("list tables: %w", err)
+	}
+
+	copied := 0
+	for _, table := range tables {
+		columns, err := queryStrings(s.db, `SELECT name FROM pragma_table_info(?)`, table)
+		if err != nil {
+			return copied, fmt.Errorf("list %s columns: %w", table, err)
+		}
+		copied += s.salvageTable(old, table, columns)
+	}
+	return copied, nil
+}
+
+// salvageTable copies the readable rows of table from old into s, keeping
+// only the columns the fresh schema has.
+func (s *Store) salvageTable(old *sql.DB, table string, columns []string) int {
+	rows, err := old.Query(`SELECT * FROM "` + table + `"`)
+	if err != nil {
+		return 0
+	}
+	defer rows.Close()
+
+	oldColumns, err := rows.Columns()
+	if err != nil {
+		return 0
+	}
+	known := make(map[string]bool, len(columns))
+	for _, name := range columns {
+		known[name] = true
+	}
+	var keep []int
+	var names, marks []string
+	for i, name := range oldColumns {
+		if known[name] {
+			keep = append(keep, i)
+			names = append(names, `"`+name+`"`)
+			marks = append(marks, "?")
+		}
+	}
+	if len(keep) == 0 {
+		return 0
+	}
+	insert := `INSERT OR IGNORE INTO "` + table + `" (` + strings.Join(names, ", ") + `) VALUES (` + strings.Join(marks, ", ") + `)`
+
+	copied := 0
+	values := make([]any, len(oldColumns))
+	dest := make([]any, len(oldColumns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	args := make([]any, len(keep))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			continue
+		}
+		for i, idx := range keep {
+			args[i] = values[idx]
+		}
+		if _, err := s.db.Exec(insert, args...); err == nil {
+			copied++
+		}
+	}
+	return copied
+}
+
+func queryStrings(db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
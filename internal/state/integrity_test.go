@@ -0,0 +1,205 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewStoreMovesCorruptDatabaseAside(t *testing.T) {
+	tmp := t.TempDir()
+	dbPath := filepath.Join(tmp, defaultDBName)
+	garbage := []byte(strings.Repeat("not a sqlite database\n", 512))
+	if err := os.WriteFile(dbPath, garbage, 0o600); err != nil {
+		t.Fatalf("write corrupt db: %v", err)
+	}
+	var logs bytes.Buffer
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	store, err := NewStoreWithOptions(tmp, StoreOptions{RecoverCorrupt: true})
+	if err != nil {
+		t.Fatalf("new store on corrupt db: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if err := store.SetSetting("default_tool", "claude"); err != nil {
+		t.Fatalf("fresh store unusable: %v", err)
+	}
+	aside, err := filepath.Glob(dbPath + ".corrupt-*")
+	if err != nil || len(aside) != 1 {
+		t.Fatalf("expected one corrupt copy, got %v (err=%v)", aside, err)
+	}
+	kept, err := os.ReadFile(aside[0])
+	if err != nil {
+		t.Fatalf("read corrupt copy: %v", err)
+	}
+	if string(kept) != string(garbage) {
+		t.Fatal("corrupt copy does not match the original file")
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("decode log record: %v\n%s", err, logs.String())
+	}
+	if record["level"] != "WARN" || record["path"] != dbPath || record["moved_to"] != aside[0] {
+		t.Fatalf("log record = %v, want a WARN with path and moved_to", record)
+	}
+}
+
+func TestNewStoreRefusesCorruptDatabaseWithoutRecover(t *testing.T) {
+	tmp := t.TempDir()
+	dbPath := filepath.Join(tmp, defaultDBName)
+	garbage := []byte(strings.Repeat("not a sqlite database\n", 512))
+	if err := os.WriteFile(dbPath, garbage, 0o600); err != nil {
+		t.Fatalf("write corrupt db: %v", err)
+	}
+
+	if _, err := NewStore(tmp); !errors.Is(err, errCorruptDB) || !strings.Contains(err.Error(), "start fogd") {
+		t.Fatalf("NewStore on corrupt db = %v, want a corrupt error pointing at fogd", err)
+	}
+	assertCorruptDBInPlace(t, dbPath, garbage)
+}
+
+func TestRecoverLeavesDatabaseOpenElsewhereInPlace(t *testing.T) {
+	tmp := t.TempDir()
+	dbPath := filepath.Join(tmp, defaultDBName)
+	garbage := []byte(strings.Repeat("not a sqlite database\n", 512))
+	if err := os.WriteFile(dbPath, garbage, 0o600); err != nil {
+		t.Fatalf("write corrupt db: %v", err)
+	}
+	// Another process's Store holds the lock shared for as long as it is open.
+	other, err := os.OpenFile(dbPath+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = other.Close() }()
+	if err := lockShared(other); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewStoreWithOptions(tmp, StoreOptions{RecoverCorrupt: true})
+	if !errors.Is(err, errCorruptDB) || !strings.Contains(err.Error(), "another Fog process") {
+		t.Fatalf("recover with the database open elsewhere = %v, want a refusal", err)
+	}
+	assertCorruptDBInPlace(t, dbPath, garbage)
+}
+
+func assertCorruptDBInPlace(t *testing.T, dbPath string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(dbPath)
+	if err != nil || string(got) != string(want) {
+		t.Fatalf("corrupt database changed (err %v)", err)
+	}
+	if aside, _ := filepath.Glob(dbPath + ".corrupt-*"); len(aside) != 0 {
+		t.Fatalf("database moved aside: %v", aside)
+	}
+}
+
+func TestNewStoreLeavesHealthyDatabaseInPlace(t *testing.T) {
+	tmp := t.TempDir()
+	store, err := NewStore(tmp)
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	if err := store.SetSetting("branch_prefix", "fog"); err != nil {
+		t.Fatalf("set setting: %v", err)
+	}
+	_ = store.Close()
+
+	store, err = NewStore(tmp)
+	if err != nil {
+		t.Fatalf("reopen store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if got, found, _ := store.GetSetting("branch_prefix"); !found || got != "fog" {
+		t.Fatalf("setting lost on reopen: got=%q found=%v", got, found)
+	}
+	if aside, _ := filepath.Glob(filepath.Join(tmp, defaultDBName+".corrupt-*")); len(aside) != 0 {
+		t.Fatalf("healthy database moved aside: %v", aside)
+	}
+}
+
+func TestSalvageRowsCopiesReadableRows(t *testing.T) {
+	oldHome := t.TempDir()
+	old, err := NewStore(oldHome)
+	if err != nil {
+		t.Fatalf("new old store: %v", err)
+	}
+	if err := old.SetSetting("branch_prefix", "fog"); err != nil {
+		t.Fatalf("set setting: %v", err)
+	}
+	if _, err := old.UpsertRepo(Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+	}); err != nil {
+		t.Fatalf("upsert repo: %v", err)
+	}
+	_ = old.Close()
+
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	copied, err := store.salvageRows(filepath.Join(oldHome, defaultDBName))
+	if err != nil {
+		t.Fatalf("salvage rows: %v", err)
+	}
+	if copied < 2 {
+		t.Fatalf("expected at least 2 rows copied, got %d", copied)
+	}
+	if got, found, _ := store.GetSetting("branch_prefix"); !found || got != "fog" {
+		t.Fatalf("setting not salvaged: got=%q found=%v", got, found)
+	}
+	if _, found, err := store.GetRepoByName("acme/api"); err != nil || !found {
+		t.Fatalf("repo not salvaged: found=%v err=%v", found, err)
+	}
+}
+
+func TestInspectDBChangesNothing(t *testing.T) {
+	tmp := t.TempDir()
+	if info, err := InspectDB(tmp); err != nil || info.Exists {
+		t.Fatalf("InspectDB on an empty home = %+v, %v; want not existing", info, err)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Fatalf("InspectDB created files in an empty home: %v", entries)
+	}
+
+	store, err := NewStore(tmp)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if err := store.SaveGitHubToken("ghp_test"); err != nil {
+		t.Fatalf("save token: %v", err)
+	}
+	_ = store.Close()
+	dbPath := filepath.Join(tmp, defaultDBName)
+	before, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := InspectDB(tmp)
+	if err != nil || !info.Exists || !info.HasGitHubToken {
+		t.Fatalf("InspectDB = %+v, %v; want an existing database with a token", info, err)
+	}
+	if after, _ := os.ReadFile(dbPath); !bytes.Equal(before, after) {
+		t.Fatal("InspectDB changed the database")
+	}
+
+	garbage := []byte(strings.Repeat("not a sqlite database\n", 512))
+	if err := os.WriteFile(dbPath, garbage, 0o600); err != nil {
+		t.Fatalf("write corrupt db: %v", err)
+	}
+	if _, err := InspectDB(tmp); !errors.Is(err, errCorruptDB) {
+		t.Fatalf("InspectDB on a corrupt db = %v, want errCorruptDB", err)
+	}
+	assertCorruptDBInPlace(t, dbPath, garbage)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package state
+
+import "os"
+
+// lockShared is a no-op where flock is unavailable.
+func lockShared(*os.File) error { return nil }
+
+// tryLockExclusive always succeeds where flock is unavailable. Renaming a
+// database another process has open fails there anyway.
+func tryLockExclusive(*os.File) (bool, error) { return true, nil }
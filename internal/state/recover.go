@@ -0,0 +1,103 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+)
+
+// interruptedRunError is the error recorded on a run failed by
+// RecoverOrphanedRuns.
+const interruptedRunError = "interrupted: the process running it exited before it finished"
+
+// RecoverOrphanedRuns releases sessions left busy by a process that exited
+// mid-run. For every busy session isLive reports false for, the latest run is
+// marked FAILED with an interrupted event when it has not reached COMPLETED,
+// FAILED or CANCELLED, the session status follows it, and the busy flag is
+// cleared. It returns the runs it failed.
+//
+// Each update is guarded by the state it read, so a run that finishes between
+// the read and the write is left alone.
+func (s *Store) RecoverOrphanedRuns(isLive func(sessionID string) bool) ([]Run, error) {
+	rows, err := s.db.Query(`SELECT id FROM sessions WHERE busy = 1`)
+	if err != nil {
+		return nil, fmt.Errorf("list busy sessions: %w", err)
+	}
+	var busy []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("list busy sessions: %w", err)
+		}
+		busy = append(busy, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("list busy sessions: %w", err)
+	}
+
+	var failed []Run
+	for _, sessionID := range busy {
+		if isLive != nil && isLive(sessionID) {
+			continue
+		}
+		run, found, err := s.GetLatestRun(sessionID)
+		if err != nil {
+			return failed, err
+		}
+		interrupted := found && !slices.Contains(terminalRunStates, run.State)
+		released, err := s.releaseOrphanedSession(sessionID, run, interrupted)
+		if err != nil {
+			return failed, err
+		}
+		if !released || !interrupted {
+			continue
+		}
+		_ = s.AppendRunEvent(RunEvent{
+			RunID:   run.ID,
+			Type:    "interrupted",
+			Message: fmt.Sprintf("Run was %s when the process running it exited; marked FAILED", run.State),
+		})
+		run.State = "FAILED"
+		run.Error = interruptedRunError
+		failed = append(failed, run)
+	}
+	return failed, nil
+}
+
+// releaseOrphanedSession clears the session's busy flag, failing run first
+// when interrupted. It reports false, changing nothing, when run is no longer
+// in the state it was read in.
+func (s *Store) releaseOrphanedSession(sessionID string, run Run, interrupted bool) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("recover session %q: %w", sessionID, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := nowRFC3339Nano()
+	if interrupted {
+		res, err := tx.Exec(
+			`UPDATE runs
+			    SET state = 'FAILED', error = ?, updated_at = ?, completed_at = ?
+			  WHERE id = ? AND state = ?`,
+			interruptedRunError, now, now, run.ID, run.State,
+		)
+		if err != nil {
+			return false, fmt.Errorf("fail interrupted run %q: %w", run.ID, err)
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			// The run moved on since it was read, so its owner is alive.
+			return false, err
+		}
+		if _, err := tx.Exec(`UPDATE sessions SET status = 'FAILED', updated_at = ? WHERE id = ?`, now, sessionID); err != nil {
+			return false, fmt.Errorf("recover session %q: %w", sessionID, err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE sessions SET busy = 0, updated_at = ? WHERE id = ? AND busy = 1`, now, sessionID); err != nil {
+		return false, fmt.Errorf("recover session %q: %w", sessionID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("recover session %q: %w", sessionID, err)
+	}
+	return true, nil
+}
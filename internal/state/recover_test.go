@@ -0,0 +1,66 @@
+package state
+
+import "testing"
+
+func TestRecoverOrphanedRunsFailsInFlightRuns(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	seedSessionRun(t, store, "orphaned", "run-orphaned")
+	seedSessionRun(t, store, "finished", "run-finished")
+	seedSessionRun(t, store, "live", "run-live")
+	for _, id := range []string{"orphaned", "finished", "live"} {
+		if err := store.SetSessionBusy(id, true); err != nil {
+			t.Fatalf("set busy %s: %v", id, err)
+		}
+	}
+	if err := store.SetRunState("run-orphaned", "AI_RUNNING"); err != nil {
+		t.Fatalf("set run state: %v", err)
+	}
+	if err := store.SetRunState("run-live", "AI_RUNNING"); err != nil {
+		t.Fatalf("set run state: %v", err)
+	}
+	if err := store.CompleteRun("run-finished", "COMPLETED", "abc123", "done", ""); err != nil {
+		t.Fatalf("complete run: %v", err)
+	}
+
+	failed, err := store.RecoverOrphanedRuns(func(sessionID string) bool { return sessionID == "live" })
+	if err != nil {
+		t.Fatalf("RecoverOrphanedRuns: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ID != "run-orphaned" || failed[0].State != "FAILED" {
+		t.Fatalf("failed = %+v, want only run-orphaned", failed)
+	}
+
+	run, _, err := store.GetRun("run-orphaned")
+	if err != nil || run.State != "FAILED" || run.Error != interruptedRunError {
+		t.Fatalf("orphaned run = %+v (%v), want FAILED with the interrupted error", run, err)
+	}
+	events, err := store.ListRunEvents("run-orphaned", 0)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) == 0 || events[len(events)-1].Type != "interrupted" {
+		t.Fatalf("events = %+v, want a trailing interrupted event", events)
+	}
+
+	for id, wantBusy := range map[string]bool{"orphaned": false, "finished": false, "live": true} {
+		sess, _, err := store.GetSession(id)
+		if err != nil {
+			t.Fatalf("get session %s: %v", id, err)
+		}
+		if sess.Busy != wantBusy {
+			t.Fatalf("session %s busy = %v, want %v", id, sess.Busy, wantBusy)
+		}
+	}
+	if run, _, _ := store.GetRun("run-finished"); run.State != "COMPLETED" {
+		t.Fatalf("finished run state = %q, want COMPLETED untouched", run.State)
+	}
+	if run, _, _ := store.GetRun("run-live"); run.State != "AI_RUNNING" {
+		t.Fatalf("live run state = %q, want AI_RUNNING untouched", run.State)
+	}
+
+	if again, err := store.RecoverOrphanedRuns(nil); err != nil || len(again) != 1 || again[0].ID != "run-live" {
+		t.Fatalf("second pass without a live check = %+v (%v), want run-live", again, err)
+	}
+}
@@ -2,11 +2,12 @@ package state
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
 
-// Column lists and row scanners for sessions and runs.
+// Column lists and row scanners for repos, sessions and runs.
 //
 // These previously did not exist: the 11-column run scan-and-parse block was
 // written out in full in GetRun, ListRuns and GetLatestRun, and the session
@@ -18,19 +19,60 @@ import (
 // rowScanner is declared in tasks.go and satisfied by both *sql.Row and
 // *sql.Rows, so one scanner serves the single-row and multi-row queries.
 
+const repoColumns = `id, name, url, host, owner, repo, bare_path,
+	base_worktree_path, default_branch, default_tool, default_model, worktree_root, created_at`
+
 const sessionColumns = `id, repo_name, branch, worktree_path, tool, model,
-	autopr, pr_url, status, busy, created_at, updated_at`
+	autopr, pr_url, status, busy, ephemeral, origin, commit_strategy, issue_ref, close_issue,
+	env, paused, accepted_run_id, slack_channel_id, slack_thread_ts, archived_at, worktree_pruned_at,
+	parent_session_id, created_at, updated_at`
 
 const runColumns = `id, session_id, prompt, worktree_path, state,
-	commit_sha, commit_msg, error, created_at, updated_at, completed_at`
+	commit_sha, commit_msg, error, attachments, context_text, created_at, updated_at, completed_at,
+	COALESCE(u.input_tokens, 0), COALESCE(u.output_tokens, 0), COALESCE(u.cost_usd, 0)`
+
+// runsFrom is the FROM clause for runColumns: usage lives in its own table
+// and is absent for runs whose tool reported none.
+const runsFrom = `runs LEFT JOIN run_usage u ON u.run_id = runs.id`
+
+// scanRepo reads one repo row. The column order must match repoColumns.
+func scanRepo(sc rowScanner) (Repo, error) {
+	var (
+		repo         Repo
+		createdAtRaw string
+	)
+	if err := sc.Scan(
+		&repo.ID,
+		&repo.Name,
+		&repo.URL,
+		&repo.Host,
+		&repo.Owner,
+		&repo.Repo,
+		&repo.BarePath,
+		&repo.BaseWorktreePath,
+		&repo.DefaultBranch,
+		&repo.DefaultTool,
+		&repo.DefaultModel,
+		&repo.WorktreeRoot,
+		&createdAtRaw,
+	); err != nil {
+		return Repo{}, err
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, createdAtRaw); err == nil {
+		repo.CreatedAt = ts
+	}
+	return repo, nil
+}
 
 // scanSession reads one session row. The column order must match sessionColumns.
 func scanSession(sc rowScanner) (Session, error) {
 	var (
-		session      Session
-		autoPR, busy int
-		createdAtRaw string
-		updatedAtRaw string
+		session                                     Session
+		autoPR, busy, ephemeral, closeIssue, paused int
+		archivedAtRaw, prunedAtRaw, parentRaw       sql.NullString
+		envRaw                                      string
+		createdAtRaw                                string
+		updatedAtRaw                                string
 	)
 	if err := sc.Scan(
 		&session.ID,
@@ -43,6 +85,19 @@ func scanSession(sc rowScanner) (Session, error) {
 		&session.PRURL,
 		&session.Status,
 		&busy,
+		&ephemeral,
+		&session.Origin,
+		&session.CommitStrategy,
+		&session.IssueRef,
+		&closeIssue,
+		&envRaw,
+		&paused,
+		&session.AcceptedRunID,
+		&session.SlackChannelID,
+		&session.SlackThreadTS,
+		&archivedAtRaw,
+		&prunedAtRaw,
+		&parentRaw,
 		&createdAtRaw,
 		&updatedAtRaw,
 	); err != nil {
@@ -51,6 +106,15 @@ func scanSession(sc rowScanner) (Session, error) {
 
 	session.AutoPR = autoPR == 1
 	session.Busy = busy == 1
+	session.Ephemeral = ephemeral == 1
+	session.CloseIssue = closeIssue == 1
+	session.Paused = paused == 1
+	session.ParentSessionID = parentRaw.String
+	if envRaw != "" {
+		if err := json.Unmarshal([]byte(envRaw), &session.Env); err != nil {
+			return Session{}, fmt.Errorf("parse session env %q: %w", session.ID, err)
+		}
+	}
 
 	var err error
 	if session.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw); err != nil {
@@ -59,6 +123,20 @@ func scanSession(sc rowScanner) (Session, error) {
 	if session.UpdatedAt, err = time.Parse(time.RFC3339Nano, updatedAtRaw); err != nil {
 		return Session{}, fmt.Errorf("parse session updated_at %q: %w", session.ID, err)
 	}
+	if archivedAtRaw.Valid && archivedAtRaw.String != "" {
+		archivedAt, err := time.Parse(time.RFC3339Nano, archivedAtRaw.String)
+		if err != nil {
+			return Session{}, fmt.Errorf("parse session archived_at %q: %w", session.ID, err)
+		}
+		session.ArchivedAt = &archivedAt
+	}
+	if prunedAtRaw.Valid && prunedAtRaw.String != "" {
+		prunedAt, err := time.Parse(time.RFC3339Nano, prunedAtRaw.String)
+		if err != nil {
+			return Session{}, fmt.Errorf("parse session worktree_pruned_at %q: %w", session.ID, err)
+		}
+		session.WorktreePrunedAt = &prunedAt
+	}
 	return session, nil
 }
 
@@ -66,6 +144,7 @@ func scanSession(sc rowScanner) (Session, error) {
 func scanRun(sc rowScanner) (Run, error) {
 	var (
 		run            Run
+		attachmentsRaw string
 		createdAtRaw   string
 		updatedAtRaw   string
 		completedAtRaw sql.NullString
@@ -79,12 +158,22 @@ func scanRun(sc rowScanner) (Run, error) {
 		&run.CommitSHA,
 		&run.CommitMsg,
 		&run.Error,
+		&attachmentsRaw,
+		&run.ContextText,
 		&createdAtRaw,
 		&updatedAtRaw,
 		&completedAtRaw,
+		&run.InputTokens,
+		&run.OutputTokens,
+		&run.CostUSD,
 	); err != nil {
 		return Run{}, err
 	}
+	if attachmentsRaw != "" {
+		if err := json.Unmarshal([]byte(attachmentsRaw), &run.Attachments); err != nil {
+			return Run{}, fmt.Errorf("parse run attachments %q: %w", run.ID, err)
+		}
+	}
 
 	var err error
 	if run.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAtRaw); err != nil {
@@ -26,7 +26,8 @@ func seedSessionAndRun(t *testing.T, s *Store) {
 		ID: "session-1", RepoName: "acme/api", Branch: "fog/test",
 		WorktreePath: "/tmp/acme/wt", Tool: "claude", Model: "sonnet",
 		AutoPR: true, PRURL: "https://example.invalid/pr/1",
-		Status: "CREATED", Busy: true,
+		Status: "CREATED", Busy: true, Origin: "desktop",
+		IssueRef: "#123", CloseIssue: true, Env: map[string]string{"NODE_ENV": "test"},
 		CreatedAt: now, UpdatedAt: now,
 	}); err != nil {
 		t.Fatalf("create session: %v", err)
@@ -60,6 +61,7 @@ func TestGetSessionAndListSessionsAgree(t *testing.T) {
 	if single.ID != all[0].ID || single.AutoPR != all[0].AutoPR ||
 		single.Busy != all[0].Busy || single.PRURL != all[0].PRURL ||
 		single.Tool != all[0].Tool || single.Model != all[0].Model ||
+		single.Origin != all[0].Origin || single.Origin != "desktop" ||
 		!single.CreatedAt.Equal(all[0].CreatedAt) {
 		t.Errorf("single-row and multi-row scans disagree:\n got %+v\nwant %+v", all[0], single)
 	}
@@ -79,6 +81,12 @@ func TestSessionBooleanColumnsRoundTrip(t *testing.T) {
 	if !session.Busy {
 		t.Error("Busy did not survive the round trip")
 	}
+	if session.Env["NODE_ENV"] != "test" {
+		t.Errorf("env did not survive the round trip: %v", session.Env)
+	}
+	if session.IssueRef != "#123" || !session.CloseIssue {
+		t.Errorf("issue reference did not survive the round trip: %q close=%v", session.IssueRef, session.CloseIssue)
+	}
 
 	if err := s.SetSessionBusy("session-1", false); err != nil {
 		t.Fatalf("SetSessionBusy: %v", err)
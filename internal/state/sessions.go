@@ -2,9 +2,11 @@ package state
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,18 +19,31 @@ type RunEventSink interface {
 
 // Session represents one long-lived branch/worktree conversation.
 type Session struct {
-	ID           string    `json:"id"`
-	RepoName     string    `json:"repo_name"`
-	Branch       string    `json:"branch"`
-	WorktreePath string    `json:"worktree_path"`
-	Tool         string    `json:"tool"`
-	Model        string    `json:"model,omitempty"`
-	AutoPR       bool      `json:"autopr"`
-	PRURL        string    `json:"pr_url,omitempty"`
-	Status       string    `json:"status"`
-	Busy         bool      `json:"busy"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               string            `json:"id"`
+	RepoName         string            `json:"repo_name"`
+	Branch           string            `json:"branch"`
+	WorktreePath     string            `json:"worktree_path"`
+	Tool             string            `json:"tool"`
+	Model            string            `json:"model,omitempty"`
+	AutoPR           bool              `json:"autopr"`
+	PRURL            string            `json:"pr_url,omitempty"`
+	Status           string            `json:"status"`
+	Busy             bool              `json:"busy"`
+	Ephemeral        bool              `json:"ephemeral,omitempty"`          // scratch session: detached, never pushed, worktree removed after its run
+	Origin           string            `json:"origin,omitempty"`             // interface that created it: cli, api, desktop, slack, cloud; empty for older sessions
+	CommitStrategy   string            `json:"commit_strategy,omitempty"`    // per_run (empty), squash or squash_force; applied when a run pushes
+	IssueRef         string            `json:"issue_ref,omitempty"`          // tracking issue cited in every commit trailer and the PR body, e.g. #123
+	CloseIssue       bool              `json:"close_issue,omitempty"`        // PR body says "Closes" instead of "Refs" so merging closes IssueRef
+	Env              map[string]string `json:"env,omitempty"`                // extra environment for the setup command, validation and AI tool of every run
+	Paused           bool              `json:"paused,omitempty"`             // follow-ups and forks are refused until resumed; a run in flight is not affected
+	AcceptedRunID    string            `json:"accepted_run_id,omitempty"`    // run the user marked as the session's result; empty until one is accepted
+	SlackChannelID   string            `json:"slack_channel_id,omitempty"`   // channel that receives run completion messages; set via the API
+	SlackThreadTS    string            `json:"slack_thread_ts,omitempty"`    // thread within SlackChannelID; empty posts to the channel
+	ArchivedAt       *time.Time        `json:"archived_at,omitempty"`        // set when the session was archived, by hand or by retention; hidden from the default session list
+	WorktreePrunedAt *time.Time        `json:"worktree_pruned_at,omitempty"` // set when the worktree janitor removed the worktree; the branch is kept
+	ParentSessionID  string            `json:"parent_session_id,omitempty"`  // session this one was forked from; empty for sessions started fresh or forked before it was recorded
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
 }
 
 // Run is one execution step inside a session.
@@ -44,6 +59,14 @@ type Run struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	// Usage summed over the run's AI calls; zero when the tool reported none.
+	InputTokens  int64   `json:"input_tokens,omitempty"`
+	OutputTokens int64   `json:"output_tokens,omitempty"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+	// Attachments and ContextText are what was put ahead of the run's tool
+	// prompt, kept so a retry of the run is given them again.
+	Attachments []string `json:"attachments,omitempty"`
+	ContextText string   `json:"context_text,omitempty"`
 }
 
 // RunEvent captures one timeline event for a run.
@@ -90,9 +113,18 @@ func (s *Store) CreateSession(session Session) error {
 		updatedAt = createdAt
 	}
 
+	envJSON := ""
+	if len(session.Env) > 0 {
+		data, err := json.Marshal(session.Env)
+		if err != nil {
+			return fmt.Errorf("create session %q: encode env: %w", session.ID, err)
+		}
+		envJSON = string(data)
+	}
+
 	_, err := s.db.Exec(
-		`INSERT INTO sessions(id, repo_name, branch, worktree_path, tool, model, autopr, pr_url, status, busy, created_at, updated_at)
-		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO sessions(id, repo_name, branch, worktree_path, tool, model, autopr, pr_url, status, busy, ephemeral, origin, commit_strategy, issue_ref, close_issue, env, parent_session_id, created_at, updated_at)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		session.ID,
 		session.RepoName,
 		session.Branch,
@@ -103,6 +135,13 @@ func (s *Store) CreateSession(session Session) error {
 		strings.TrimSpace(session.PRURL),
 		session.Status,
 		boolToInt(session.Busy),
+		boolToInt(session.Ephemeral),
+		strings.TrimSpace(session.Origin),
+		strings.TrimSpace(session.CommitStrategy),
+		strings.TrimSpace(session.IssueRef),
+		boolToInt(session.CloseIssue),
+		envJSON,
+		nullIfEmpty(session.ParentSessionID),
 		createdAt.Format(time.RFC3339Nano),
 		updatedAt.Format(time.RFC3339Nano),
 	)
@@ -160,6 +199,144 @@ func (s *Store) ListSessions() ([]Session, error) {
 	return sessions, nil
 }
 
+// SessionFilter narrows ListSessionsFiltered. Zero values match everything,
+// except that archived sessions are left out unless IncludeArchived is set.
+type SessionFilter struct {
+	RepoName        string
+	Status          string
+	IncludeArchived bool
+	// Limit caps the page; 0 means no limit. Offset skips that many
+	// matching sessions first.
+	Limit  int
+	Offset int
+}
+
+// ListSessionsFiltered returns one page of the sessions matching f, most
+// recently updated first, and how many sessions match in total.
+func (s *Store) ListSessionsFiltered(f SessionFilter) ([]Session, int, error) {
+	if f.Limit < 0 || f.Offset < 0 {
+		return nil, 0, errors.New("limit and offset cannot be negative")
+	}
+	var where []string
+	var args []any
+	if repo := strings.TrimSpace(f.RepoName); repo != "" {
+		where = append(where, "repo_name = ?")
+		args = append(args, repo)
+	}
+	if status := strings.TrimSpace(f.Status); status != "" {
+		where = append(where, "status = ?")
+		args = append(args, status)
+	}
+	if !f.IncludeArchived {
+		where = append(where, "archived_at IS NULL")
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sessions`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count sessions: %w", err)
+	}
+
+	// SQLite needs a LIMIT before an OFFSET; -1 is its "no limit".
+	limit := f.Limit
+	if limit == 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(
+		`SELECT `+sessionColumns+`
+		   FROM sessions`+clause+`
+		  ORDER BY updated_at DESC
+		  LIMIT ? OFFSET ?`,
+		append(args, limit, f.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterate sessions: %w", err)
+	}
+	return sessions, total, nil
+}
+
+// ListChildSessions returns the sessions forked from parentID, oldest first.
+func (s *Store) ListChildSessions(parentID string) ([]Session, error) {
+	parentID = strings.TrimSpace(parentID)
+	if parentID == "" {
+		return nil, errors.New("session id cannot be empty")
+	}
+
+	rows, err := s.db.Query(
+		`SELECT `+sessionColumns+`
+		   FROM sessions
+		  WHERE parent_session_id = ?
+		  ORDER BY created_at ASC`,
+		parentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list child sessions of %q: %w", parentID, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// ListSessionsByRepo returns one repo's sessions, most recently updated first.
+func (s *Store) ListSessionsByRepo(repoName string) ([]Session, error) {
+	repoName = strings.TrimSpace(repoName)
+	if repoName == "" {
+		return nil, errors.New("repo name cannot be empty")
+	}
+
+	rows, err := s.db.Query(
+		`SELECT `+sessionColumns+`
+		   FROM sessions
+		  WHERE repo_name = ?
+		  ORDER BY updated_at DESC`,
+		repoName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions for repo %q: %w", repoName, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sessions: %w", err)
+	}
+	return sessions, nil
+}
+
 // SetSessionBusy toggles the busy flag for a session.
 func (s *Store) SetSessionBusy(id string, busy bool) error {
 	id = strings.TrimSpace(id)
@@ -184,6 +361,41 @@ func (s *Store) SetSessionBusy(id string, busy bool) error {
 	return nil
 }
 
+// ClaimSessionBusy marks a session busy unless it already is, reporting
+// whether this call claimed it. Check and set are one statement, so of two
+// callers racing for the same session only one gets true; the winner clears
+// the flag with SetSessionBusy when done.
+func (s *Store) ClaimSessionBusy(id string) (bool, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return false, errors.New("session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions
+		    SET busy = 1, updated_at = ?
+		  WHERE id = ? AND busy = 0`,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim session busy %q: %w", id, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected for session %s: %w", id, err)
+	}
+	if rows > 0 {
+		return true, nil
+	}
+	if _, found, err := s.GetSession(id); err != nil {
+		return false, err
+	} else if !found {
+		return false, fmt.Errorf("%w: session %s", ErrNotFound, id)
+	}
+	return false, nil
+}
+
 // UpdateSessionStatus updates the session status value.
 func (s *Store) UpdateSessionStatus(id, status string) error {
 	id = strings.TrimSpace(id)
@@ -236,6 +448,212 @@ func (s *Store) SetSessionPRURL(id, prURL string) error {
 	return nil
 }
 
+// SetSessionBranch records a session's renamed branch.
+func (s *Store) SetSessionBranch(id, branch string) error {
+	id = strings.TrimSpace(id)
+	branch = strings.TrimSpace(branch)
+	if id == "" || branch == "" {
+		return errors.New("session id and branch cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions
+		    SET branch = ?, updated_at = ?
+		  WHERE id = ?`,
+		branch,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set session branch %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetSessionAcceptedRun marks runID as the session's accepted result. An
+// empty runID clears the mark. The caller checks that the run belongs to the
+// session.
+func (s *Store) SetSessionAcceptedRun(id, runID string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions
+		    SET accepted_run_id = ?, updated_at = ?
+		  WHERE id = ?`,
+		strings.TrimSpace(runID),
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set session accepted_run_id %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetSessionSlackThread routes the session's run completion messages to a
+// Slack channel, optionally inside the thread rooted at threadTS. An empty
+// channelID stops them.
+func (s *Store) SetSessionSlackThread(id, channelID, threadTS string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+	channelID = strings.TrimSpace(channelID)
+	threadTS = strings.TrimSpace(threadTS)
+	if channelID == "" {
+		threadTS = ""
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions
+		    SET slack_channel_id = ?, slack_thread_ts = ?, updated_at = ?
+		  WHERE id = ?`,
+		channelID,
+		threadTS,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set session slack thread %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetSessionPaused sets or clears a session's paused flag. Like archiving it
+// leaves updated_at alone: pausing is coordination, not activity.
+func (s *Store) SetSessionPaused(id string, paused bool) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions SET paused = ? WHERE id = ?`,
+		boolToInt(paused),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("set session paused %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ArchiveSession marks a session archived. It leaves updated_at alone so the
+// session keeps its place in activity order.
+func (s *Store) ArchiveSession(id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions SET archived_at = ? WHERE id = ? AND archived_at IS NULL`,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("archive session %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarkSessionWorktreePruned records that a session's worktree was removed
+// while its branch was kept. Like ArchiveSession it leaves updated_at alone.
+func (s *Store) MarkSessionWorktreePruned(id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE sessions SET worktree_pruned_at = ? WHERE id = ?`,
+		nowRFC3339Nano(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("mark session %q worktree pruned: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnarchiveSession clears a session's archived mark, returning it to the
+// default session list. Unarchiving a session that is not archived is a no-op.
+func (s *Store) UnarchiveSession(id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	res, err := s.db.Exec(`UPDATE sessions SET archived_at = NULL WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("unarchive session %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteSession removes a session with its runs and their events, and
+// unlinks any task that owned it and any session forked from it. The worktree and branch on disk are the
+// caller's to remove.
+func (s *Store) DeleteSession(id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("session id cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("delete session %q: %w", id, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		`DELETE FROM run_events WHERE run_id IN (SELECT id FROM runs WHERE session_id = ?)`,
+		`DELETE FROM run_usage WHERE run_id IN (SELECT id FROM runs WHERE session_id = ?)`,
+		`DELETE FROM runs WHERE session_id = ?`,
+		`UPDATE tasks SET session_id = NULL WHERE session_id = ?`,
+		`UPDATE sessions SET parent_session_id = NULL WHERE parent_session_id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return fmt.Errorf("delete session %q: %w", id, err)
+		}
+	}
+	res, err := tx.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete session %q: %w", id, err)
+	}
+	if err := ensureRowsAffected(res, "session "+id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete session %q: %w", id, err)
+	}
+	return nil
+}
+
 // SetSessionWorktreePath updates the session's latest run worktree path.
 func (s *Store) SetSessionWorktreePath(id, worktreePath string) error {
 	id = strings.TrimSpace(id)
@@ -274,6 +692,7 @@ func (s *Store) CreateRun(run Run) error {
 	run.CommitSHA = strings.TrimSpace(run.CommitSHA)
 	run.CommitMsg = strings.TrimSpace(run.CommitMsg)
 	run.Error = strings.TrimSpace(run.Error)
+	run.ContextText = strings.TrimSpace(run.ContextText)
 
 	switch {
 	case run.ID == "":
@@ -302,9 +721,18 @@ func (s *Store) CreateRun(run Run) error {
 		completedAtRaw = run.CompletedAt.UTC().Format(time.RFC3339Nano)
 	}
 
+	attachmentsJSON := ""
+	if len(run.Attachments) > 0 {
+		data, err := json.Marshal(run.Attachments)
+		if err != nil {
+			return fmt.Errorf("create run %q: encode attachments: %w", run.ID, err)
+		}
+		attachmentsJSON = string(data)
+	}
+
 	_, err := s.db.Exec(
-		`INSERT INTO runs(id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, created_at, updated_at, completed_at)
-		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO runs(id, session_id, prompt, worktree_path, state, commit_sha, commit_msg, error, attachments, context_text, created_at, updated_at, completed_at)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		run.ID,
 		run.SessionID,
 		run.Prompt,
@@ -313,6 +741,8 @@ func (s *Store) CreateRun(run Run) error {
 		run.CommitSHA,
 		run.CommitMsg,
 		run.Error,
+		attachmentsJSON,
+		run.ContextText,
 		createdAt.Format(time.RFC3339Nano),
 		updatedAt.Format(time.RFC3339Nano),
 		nullIfEmpty(completedAtRaw),
@@ -332,7 +762,7 @@ func (s *Store) GetRun(id string) (Run, bool, error) {
 
 	run, err := scanRun(s.db.QueryRow(
 		`SELECT `+runColumns+`
-		   FROM runs
+		   FROM `+runsFrom+`
 		  WHERE id = ?`,
 		id,
 	))
@@ -354,7 +784,7 @@ func (s *Store) ListRuns(sessionID string) ([]Run, error) {
 
 	rows, err := s.db.Query(
 		`SELECT `+runColumns+`
-		   FROM runs
+		   FROM `+runsFrom+`
 		  WHERE session_id = ?
 		  ORDER BY created_at DESC`,
 		sessionID,
@@ -387,7 +817,7 @@ func (s *Store) GetLatestRun(sessionID string) (Run, bool, error) {
 
 	run, err := scanRun(s.db.QueryRow(
 		`SELECT `+runColumns+`
-		   FROM runs
+		   FROM `+runsFrom+`
 		  WHERE session_id = ?
 		  ORDER BY created_at DESC
 		  LIMIT 1`,
@@ -402,6 +832,68 @@ func (s *Store) GetLatestRun(sessionID string) (Run, bool, error) {
 	return run, true, nil
 }
 
+// ErrRunNotDeletable is returned by DeleteRun for a run that is still in
+// progress or is the only run of its session.
+var ErrRunNotDeletable = errors.New("run cannot be deleted")
+
+// DeleteRun removes a finished run and its events. A session keeps at least
+// one run, so its only run cannot be deleted; neither can a run that has not
+// reached COMPLETED, FAILED or CANCELLED. Deleting the session's accepted run
+// clears the mark.
+func (s *Store) DeleteRun(id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return errors.New("run id cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("delete run %q: %w", id, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var sessionID, runState string
+	err = tx.QueryRow(`SELECT session_id, state FROM runs WHERE id = ?`, id).Scan(&sessionID, &runState)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("run %q: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("delete run %q: %w", id, err)
+	}
+	switch runState {
+	case "COMPLETED", "FAILED", "CANCELLED":
+	default:
+		return fmt.Errorf("%w: run %s is %s", ErrRunNotDeletable, id, runState)
+	}
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM runs WHERE session_id = ?`, sessionID).Scan(&count); err != nil {
+		return fmt.Errorf("delete run %q: %w", id, err)
+	}
+	if count < 2 {
+		return fmt.Errorf("%w: run %s is the only run of its session", ErrRunNotDeletable, id)
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM run_events WHERE run_id = ?`,
+		`DELETE FROM run_usage WHERE run_id = ?`,
+		`DELETE FROM runs WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return fmt.Errorf("delete run %q: %w", id, err)
+		}
+	}
+	if _, err := tx.Exec(
+		`UPDATE sessions SET accepted_run_id = '' WHERE id = ? AND accepted_run_id = ?`,
+		sessionID, id,
+	); err != nil {
+		return fmt.Errorf("delete run %q: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete run %q: %w", id, err)
+	}
+	return nil
+}
+
 // SetRunState updates only the state and updated timestamp.
 func (s *Store) SetRunState(id, state string) error {
 	id = strings.TrimSpace(id)
@@ -481,7 +973,7 @@ func (s *Store) AppendRunEvent(event RunEvent) error {
 		ts = time.Now().UTC()
 	}
 
-	_, err := s.db.Exec(
+	res, err := s.db.Exec(
 		`INSERT INTO run_events(run_id, ts, type, message, data)
 		 VALUES(?, ?, ?, ?, ?)`,
 		event.RunID,
@@ -493,9 +985,131 @@ func (s *Store) AppendRunEvent(event RunEvent) error {
 	if err != nil {
 		return fmt.Errorf("append run event for %q: %w", event.RunID, err)
 	}
+
+	event.TS = ts
+	if id, err := res.LastInsertId(); err == nil {
+		event.ID = id
+	}
+
+	s.observerMu.RLock()
+	observe := s.onRunEvent
+	for sub := range s.subscribers {
+		sub.deliver(event)
+	}
+	s.observerMu.RUnlock()
+	if observe != nil {
+		observe(event)
+	}
 	return nil
 }
 
+// SetRunEventObserver registers fn to be called with every run event after it
+// is persisted, so a deployment can mirror run activity elsewhere (fogd's
+// --log-events writes it to stdout) without the runner knowing. fn runs on the
+// appending goroutine and must not block. A nil fn removes the observer.
+func (s *Store) SetRunEventObserver(fn func(RunEvent)) {
+	s.observerMu.Lock()
+	defer s.observerMu.Unlock()
+	s.onRunEvent = fn
+}
+
+// IsFinishEvent reports whether eventType is one a run writes as it ends:
+// "complete", or "error", "cancelled" or "timeout" when it fails.
+func IsFinishEvent(eventType string) bool {
+	switch eventType {
+	case "complete", "error", "cancelled", "timeout":
+		return true
+	}
+	return false
+}
+
+// SubscribeRunEvents returns a channel receiving every run event appended
+// from now on, across all runs, and a func that ends the subscription and
+// closes the channel. Delivery never blocks the appender: when the channel's
+// buffer is full the subscriber misses events rather than stalling a run,
+// except the events a run finishes with, which are held and delivered in
+// order once it catches up, so a client waiting for a run to end never hangs.
+func (s *Store) SubscribeRunEvents(buffer int) (<-chan RunEvent, func()) {
+	sub := &runEventSub{ch: make(chan RunEvent, max(buffer, 1)), done: make(chan struct{})}
+	s.observerMu.Lock()
+	if s.subscribers == nil {
+		s.subscribers = make(map[*runEventSub]struct{})
+	}
+	s.subscribers[sub] = struct{}{}
+	s.observerMu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			s.observerMu.Lock()
+			delete(s.subscribers, sub)
+			s.observerMu.Unlock()
+			close(sub.done)
+			sub.flushing.Wait()
+			close(sub.ch)
+		})
+	}
+}
+
+// runEventSub is one SubscribeRunEvents subscription.
+type runEventSub struct {
+	ch   chan RunEvent
+	done chan struct{}
+
+	mu sync.Mutex
+	// held are finish events that found ch full, oldest first. While any
+	// wait, later events queue behind them or are dropped, keeping order.
+	held     []RunEvent
+	flushing sync.WaitGroup
+}
+
+// deliver hands event to the subscriber without blocking. An event that finds
+// the buffer full is dropped, unless it is a finish event.
+func (sub *runEventSub) deliver(event RunEvent) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if len(sub.held) == 0 {
+		select {
+		case sub.ch <- event:
+			return
+		default:
+		}
+	}
+	if !IsFinishEvent(event.Type) {
+		return // the subscriber is behind; it misses this event
+	}
+	sub.held = append(sub.held, event)
+	if len(sub.held) == 1 {
+		sub.flushing.Add(1)
+		go sub.flush()
+	}
+}
+
+// flush sends the held events as the subscriber makes room for them, until
+// none are left or the subscription ends.
+func (sub *runEventSub) flush() {
+	defer sub.flushing.Done()
+	for {
+		sub.mu.Lock()
+		if len(sub.held) == 0 {
+			sub.mu.Unlock()
+			return
+		}
+		event := sub.held[0]
+		sub.mu.Unlock()
+
+		select {
+		case sub.ch <- event:
+		case <-sub.done:
+			return
+		}
+
+		sub.mu.Lock()
+		sub.held = sub.held[1:]
+		sub.mu.Unlock()
+	}
+}
+
 // ListRunEvents returns run events in chronological order.
 func (s *Store) ListRunEvents(runID string, limit int) ([]RunEvent, error) {
 	runID = strings.TrimSpace(runID)
@@ -521,6 +1135,76 @@ func (s *Store) ListRunEvents(runID string, limit int) ([]RunEvent, error) {
 	if err != nil {
 		return nil, fmt.Errorf("list run events for %q: %w", runID, err)
 	}
+	return scanRunEvents(rows, runID)
+}
+
+// ListRunEventsByType returns a run's events of the given types in
+// chronological order, capped like ListRunEvents. The cap applies after the
+// filter, so asking for a few low-volume types is not crowded out by a long
+// stream. With no types it behaves as ListRunEvents.
+func (s *Store) ListRunEventsByType(runID string, limit int, types ...string) ([]RunEvent, error) {
+	if len(types) == 0 {
+		return s.ListRunEvents(runID, limit)
+	}
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return nil, errors.New("run id cannot be empty")
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+	if limit > 2000 {
+		limit = 2000
+	}
+
+	query := `SELECT id, run_id, ts, type, message, data
+		   FROM run_events
+		  WHERE run_id = ?
+		    AND type IN (?` + strings.Repeat(`, ?`, len(types)-1) + `)
+		  ORDER BY id ASC
+		  LIMIT ?`
+	args := []any{runID}
+	for _, t := range types {
+		args = append(args, t)
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list run events for %q: %w", runID, err)
+	}
+	return scanRunEvents(rows, runID)
+}
+
+// ListRunEventsExcept returns a run's events in chronological order, leaving
+// out the given types. Unlike ListRunEvents it is not capped, so dropping
+// high-volume types such as streamed output yields the complete timeline.
+func (s *Store) ListRunEventsExcept(runID string, skipTypes ...string) ([]RunEvent, error) {
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return nil, errors.New("run id cannot be empty")
+	}
+
+	query := `SELECT id, run_id, ts, type, message, data
+		   FROM run_events
+		  WHERE run_id = ?`
+	args := []any{runID}
+	if len(skipTypes) > 0 {
+		query += ` AND type NOT IN (?` + strings.Repeat(`, ?`, len(skipTypes)-1) + `)`
+		for _, t := range skipTypes {
+			args = append(args, t)
+		}
+	}
+	query += ` ORDER BY id ASC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list run events for %q: %w", runID, err)
+	}
+	return scanRunEvents(rows, runID)
+}
+
+func scanRunEvents(rows *sql.Rows, runID string) ([]RunEvent, error) {
 	defer rows.Close()
 
 	events := make([]RunEvent, 0)
@@ -539,10 +1223,11 @@ func (s *Store) ListRunEvents(runID string, limit int) ([]RunEvent, error) {
 		); err != nil {
 			return nil, fmt.Errorf("scan run event: %w", err)
 		}
-		event.TS, err = time.Parse(time.RFC3339Nano, tsRaw)
+		ts, err := time.Parse(time.RFC3339Nano, tsRaw)
 		if err != nil {
 			return nil, fmt.Errorf("parse run event ts for %q: %w", runID, err)
 		}
+		event.TS = ts
 		event.Message = message.String
 		event.Data = data.String
 		events = append(events, event)
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -220,3 +221,564 @@ func TestSessionRunUpdateMissingRows(t *testing.T) {
 		t.Fatal("expected missing run error")
 	}
 }
+
+func TestRunEventObserverSeesPersistedEvents(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	var got []RunEvent
+	store.SetRunEventObserver(func(e RunEvent) { got = append(got, e) })
+
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: " setup ", Message: "Running setup"}); err != nil {
+		t.Fatalf("append run event failed: %v", err)
+	}
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1"}); err == nil {
+		t.Fatal("expected invalid event to be rejected")
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("unexpected observed event count: got %d want 1", len(got))
+	}
+	if got[0].Type != "setup" || got[0].ID == 0 || got[0].TS.IsZero() {
+		t.Fatalf("unexpected observed event: %+v", got[0])
+	}
+
+	store.SetRunEventObserver(nil)
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: "complete"}); err != nil {
+		t.Fatalf("append run event failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected removed observer not to be called, got %d events", len(got))
+	}
+}
+
+func TestSubscribeRunEventsFansOut(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	first, cancelFirst := store.SubscribeRunEvents(4)
+	second, cancelSecond := store.SubscribeRunEvents(1)
+	defer cancelSecond()
+
+	for _, typ := range []string{"setup", "ai_start"} {
+		if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append run event failed: %v", err)
+		}
+	}
+
+	for _, want := range []string{"setup", "ai_start"} {
+		if got := <-first; got.Type != want || got.ID == 0 {
+			t.Fatalf("first subscriber got %+v, want %s", got, want)
+		}
+	}
+	// The full buffer dropped the second event instead of blocking the append.
+	if got := <-second; got.Type != "setup" {
+		t.Fatalf("second subscriber got %+v, want setup", got)
+	}
+	select {
+	case got := <-second:
+		t.Fatalf("expected the overflowing event to be dropped, got %+v", got)
+	default:
+	}
+
+	cancelFirst()
+	cancelFirst()
+	if _, ok := <-first; ok {
+		t.Fatal("expected the cancelled subscription's channel to be closed")
+	}
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: "complete"}); err != nil {
+		t.Fatalf("append after unsubscribe failed: %v", err)
+	}
+}
+
+func TestSubscribeRunEventsKeepsFinishEvents(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	events, unsubscribe := store.SubscribeRunEvents(2)
+	defer unsubscribe()
+
+	// A burst of stream chunks fills the buffer before the run finishes.
+	for _, typ := range []string{"ai_stream", "ai_stream", "ai_stream", "complete", "ai_stream"} {
+		if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append run event failed: %v", err)
+		}
+	}
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case event := <-events:
+			got = append(got, event.Type)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %v, want the complete event delivered", got)
+		}
+	}
+	if want := []string{"ai_stream", "ai_stream", "complete"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("expected the chunks behind a full buffer to be dropped, got %+v", event)
+	default:
+	}
+}
+
+func TestUnsubscribeRunEventsWithHeldFinishEvent(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	events, unsubscribe := store.SubscribeRunEvents(1)
+	for _, typ := range []string{"ai_stream", "error"} {
+		if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append run event failed: %v", err)
+		}
+	}
+	// Ending the subscription stops the held error's delivery and closes
+	// the channel rather than sending on it afterwards.
+	unsubscribe()
+	for range events {
+	}
+}
+
+func TestRunKeepsAttachmentsAndContextText(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	now := time.Now().UTC()
+	if err := store.CreateRun(Run{
+		ID: "run-2", SessionID: "sess-1", Prompt: "again",
+		WorktreePath: "/tmp/acme-api/sess-1", State: "CREATED",
+		Attachments: []string{"docs/spec.md", "go.mod"}, ContextText: "  the spec  ",
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	got, found, err := store.GetRun("run-2")
+	if err != nil || !found {
+		t.Fatalf("get run: found=%v err=%v", found, err)
+	}
+	if strings.Join(got.Attachments, ",") != "docs/spec.md,go.mod" || got.ContextText != "the spec" {
+		t.Errorf("run inputs = %q / %q, want the attachments and trimmed context", got.Attachments, got.ContextText)
+	}
+	plain, _, err := store.GetRun("run-1")
+	if err != nil {
+		t.Fatalf("get run-1: %v", err)
+	}
+	if plain.Attachments != nil || plain.ContextText != "" {
+		t.Errorf("run-1 inputs = %q / %q, want none", plain.Attachments, plain.ContextText)
+	}
+}
+
+func TestDeleteRunGuards(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	if err := store.CompleteRun("run-1", "COMPLETED", "", "", ""); err != nil {
+		t.Fatalf("complete run failed: %v", err)
+	}
+	if err := store.DeleteRun("run-1"); !errors.Is(err, ErrRunNotDeletable) {
+		t.Fatalf("deleting the only run: got %v, want ErrRunNotDeletable", err)
+	}
+
+	now := time.Now().UTC()
+	if err := store.CreateRun(Run{
+		ID: "run-2", SessionID: "sess-1", Prompt: "again",
+		WorktreePath: "/tmp/acme-api/sess-1", State: "AI_RUNNING",
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.DeleteRun("run-2"); !errors.Is(err, ErrRunNotDeletable) {
+		t.Fatalf("deleting a running run: got %v, want ErrRunNotDeletable", err)
+	}
+
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: "ai_output"}); err != nil {
+		t.Fatalf("append run event failed: %v", err)
+	}
+	if err := store.SetSessionAcceptedRun("sess-1", "run-1"); err != nil {
+		t.Fatalf("accept run failed: %v", err)
+	}
+	if err := store.DeleteRun("run-1"); err != nil {
+		t.Fatalf("DeleteRun failed: %v", err)
+	}
+	if _, found, _ := store.GetRun("run-1"); found {
+		t.Fatal("expected run-1 to be deleted")
+	}
+	if events, _ := store.ListRunEvents("run-1", 10); len(events) != 0 {
+		t.Fatalf("expected run-1 events to be deleted, got %d", len(events))
+	}
+	if session, _, _ := store.GetSession("sess-1"); session.AcceptedRunID != "" {
+		t.Fatalf("expected accepted run to be cleared, got %q", session.AcceptedRunID)
+	}
+
+	if err := store.DeleteRun("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleting a missing run: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSetSessionPaused(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	before, _, _ := store.GetSession("sess-1")
+	if before.Paused {
+		t.Fatal("new session is paused")
+	}
+	if err := store.SetSessionPaused("sess-1", true); err != nil {
+		t.Fatalf("SetSessionPaused failed: %v", err)
+	}
+	after, _, _ := store.GetSession("sess-1")
+	if !after.Paused {
+		t.Fatal("expected session to be paused")
+	}
+	if !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("pausing moved updated_at from %v to %v", before.UpdatedAt, after.UpdatedAt)
+	}
+	// Pausing twice is not an error.
+	if err := store.SetSessionPaused("sess-1", true); err != nil {
+		t.Fatalf("pausing twice: %v", err)
+	}
+	if err := store.SetSessionPaused("sess-1", false); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if session, _, _ := store.GetSession("sess-1"); session.Paused {
+		t.Fatal("expected session to be resumed")
+	}
+	if err := store.SetSessionPaused("missing", true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("pausing a missing session: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMarkSessionWorktreePruned(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	before, _, _ := store.GetSession("sess-1")
+	if before.WorktreePrunedAt != nil {
+		t.Fatalf("new session has worktree_pruned_at %v", before.WorktreePrunedAt)
+	}
+	if err := store.MarkSessionWorktreePruned("sess-1"); err != nil {
+		t.Fatalf("MarkSessionWorktreePruned failed: %v", err)
+	}
+	after, _, _ := store.GetSession("sess-1")
+	if after.WorktreePrunedAt == nil {
+		t.Fatal("expected worktree_pruned_at to be set")
+	}
+	if !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("pruning moved updated_at from %v to %v", before.UpdatedAt, after.UpdatedAt)
+	}
+	if err := store.MarkSessionWorktreePruned("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("pruning an unknown session: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestListChildSessions(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "parent", "run-1")
+
+	base := time.Now().UTC()
+	for i, id := range []string{"child-a", "child-b"} {
+		if err := store.CreateSession(Session{
+			ID:              id,
+			RepoName:        "acme/api",
+			Branch:          "fog/" + id,
+			WorktreePath:    "/tmp/acme-api/" + id,
+			Tool:            "claude",
+			Status:          "CREATED",
+			ParentSessionID: "parent",
+			CreatedAt:       base.Add(time.Duration(i) * time.Second),
+		}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+
+	children, err := store.ListChildSessions("parent")
+	if err != nil {
+		t.Fatalf("ListChildSessions: %v", err)
+	}
+	if len(children) != 2 || children[0].ID != "child-a" || children[1].ID != "child-b" || children[0].ParentSessionID != "parent" {
+		t.Fatalf("children = %+v, want child-a then child-b", children)
+	}
+	if parent, _, _ := store.GetSession("parent"); parent.ParentSessionID != "" {
+		t.Fatalf("parent has parent_session_id %q", parent.ParentSessionID)
+	}
+
+	// Deleting the parent unlinks its forks rather than leaving them dangling.
+	if err := store.DeleteSession("parent"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if child, _, _ := store.GetSession("child-a"); child.ParentSessionID != "" {
+		t.Fatalf("child still names deleted parent %q", child.ParentSessionID)
+	}
+}
+
+func TestArchiveAndDeleteSession(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+	if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: "ai_output"}); err != nil {
+		t.Fatalf("append run event failed: %v", err)
+	}
+
+	before, _, _ := store.GetSession("sess-1")
+	if err := store.ArchiveSession("sess-1"); err != nil {
+		t.Fatalf("ArchiveSession failed: %v", err)
+	}
+	after, _, _ := store.GetSession("sess-1")
+	if after.ArchivedAt == nil {
+		t.Fatal("expected archived_at to be set")
+	}
+	if !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("archiving moved updated_at from %v to %v", before.UpdatedAt, after.UpdatedAt)
+	}
+	if err := store.ArchiveSession("sess-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("archiving twice: got %v, want ErrNotFound", err)
+	}
+
+	if err := store.UnarchiveSession("sess-1"); err != nil {
+		t.Fatalf("UnarchiveSession failed: %v", err)
+	}
+	if restored, _, _ := store.GetSession("sess-1"); restored.ArchivedAt != nil || !restored.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("unarchived session = archived_at %v, updated_at %v", restored.ArchivedAt, restored.UpdatedAt)
+	}
+	if err := store.UnarchiveSession("sess-1"); err != nil {
+		t.Fatalf("unarchiving twice: %v", err)
+	}
+	if err := store.UnarchiveSession("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unarchiving an unknown session: got %v, want ErrNotFound", err)
+	}
+
+	if err := store.DeleteSession("sess-1"); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if _, found, _ := store.GetSession("sess-1"); found {
+		t.Fatal("expected sess-1 to be deleted")
+	}
+	if _, found, _ := store.GetRun("run-1"); found {
+		t.Fatal("expected run-1 to be deleted with its session")
+	}
+	if events, _ := store.ListRunEvents("run-1", 10); len(events) != 0 {
+		t.Fatalf("expected run-1 events to be deleted, got %d", len(events))
+	}
+	if err := store.DeleteSession("sess-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleting a missing session: got %v, want ErrNotFound", err)
+	}
+}
+
+// seedSessionRun creates a repo, a session and one run so run-scoped rows have
+// something to reference.
+func seedSessionRun(t *testing.T, store *Store, sessionID, runID string) {
+	t.Helper()
+
+	if _, err := store.UpsertRepo(Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		Owner:            "acme",
+		Repo:             "api",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+		DefaultBranch:    "main",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(Session{
+		ID:           sessionID,
+		RepoName:     "acme/api",
+		Branch:       "fog/" + sessionID,
+		WorktreePath: "/tmp/acme-api/" + sessionID,
+		Tool:         "claude",
+		Status:       "CREATED",
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := store.CreateRun(Run{
+		ID:           runID,
+		SessionID:    sessionID,
+		Prompt:       "do it",
+		WorktreePath: "/tmp/acme-api/" + sessionID,
+		State:        "CREATED",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+}
+
+func TestListRunEventsExceptSkipsTypes(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	if _, err := store.UpsertRepo(Repo{
+		Name:             "acme/api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		Owner:            "acme",
+		Repo:             "api",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+		DefaultBranch:    "main",
+	}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/x", WorktreePath: "/tmp/x", Tool: "claude", Status: "CREATED"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	if err := store.CreateRun(Run{ID: "run-1", SessionID: "sess-1", Prompt: "p", WorktreePath: "/tmp/x", State: "CREATED"}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	for _, typ := range []string{"ai_start", "ai_stream", "ai_stream", "ai_output", "complete"} {
+		if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append event failed: %v", err)
+		}
+	}
+
+	events, err := store.ListRunEventsExcept("run-1", "ai_stream")
+	if err != nil {
+		t.Fatalf("list events failed: %v", err)
+	}
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	if got := strings.Join(types, ","); got != "ai_start,ai_output,complete" {
+		t.Fatalf("event types = %s", got)
+	}
+
+	sessions, err := store.ListSessionsByRepo("acme/api")
+	if err != nil || len(sessions) != 1 || sessions[0].ID != "sess-1" {
+		t.Fatalf("ListSessionsByRepo = %+v, %v", sessions, err)
+	}
+	sessions, err = store.ListSessionsByRepo("acme/other")
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("ListSessionsByRepo(other) = %+v, %v", sessions, err)
+	}
+}
+
+func TestListRunEventsByTypeFiltersBeforeLimit(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+
+	for _, typ := range []string{"ai_start", "ai_stream", "ai_stream", "ai_stream", "ai_output", "commit", "complete"} {
+		if err := store.AppendRunEvent(RunEvent{RunID: "run-1", Type: typ}); err != nil {
+			t.Fatalf("append event failed: %v", err)
+		}
+	}
+
+	events, err := store.ListRunEventsByType("run-1", 2, "ai_output", "commit", "complete")
+	if err != nil {
+		t.Fatalf("list events failed: %v", err)
+	}
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	if got := strings.Join(types, ","); got != "ai_output,commit" {
+		t.Fatalf("event types = %s", got)
+	}
+
+	all, err := store.ListRunEventsByType("run-1", 0)
+	if err != nil || len(all) != 7 {
+		t.Fatalf("unfiltered list = %d events, %v", len(all), err)
+	}
+}
+
+func TestListSessionsFilteredPagesAndCounts(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	for _, name := range []string{"acme/api", "acme/web"} {
+		if _, err := store.UpsertRepo(Repo{Name: name, URL: "https://github.com/" + name + ".git", Host: "github.com", BarePath: "/tmp/" + name, BaseWorktreePath: "/tmp/" + name + "/base", DefaultBranch: "main"}); err != nil {
+			t.Fatalf("upsert repo failed: %v", err)
+		}
+	}
+	now := time.Now().UTC()
+	for i, s := range []struct{ id, repo, status string }{
+		{"s1", "acme/api", "COMPLETED"},
+		{"s2", "acme/api", "FAILED"},
+		{"s3", "acme/web", "COMPLETED"},
+		{"s4", "acme/api", "COMPLETED"},
+		{"s5", "acme/api", "COMPLETED"},
+	} {
+		ts := now.Add(time.Duration(i) * time.Minute)
+		if err := store.CreateSession(Session{ID: s.id, RepoName: s.repo, Branch: "fog/" + s.id, WorktreePath: "/tmp/" + s.id, Tool: "claude", Status: s.status, CreatedAt: ts, UpdatedAt: ts}); err != nil {
+			t.Fatalf("create session failed: %v", err)
+		}
+	}
+	if err := store.ArchiveSession("s5"); err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+
+	ids := func(sessions []Session) string {
+		var out []string
+		for _, s := range sessions {
+			out = append(out, s.ID)
+		}
+		return strings.Join(out, ",")
+	}
+	cases := []struct {
+		filter    SessionFilter
+		want      string
+		wantTotal int
+	}{
+		{SessionFilter{}, "s4,s3,s2,s1", 4},
+		{SessionFilter{IncludeArchived: true}, "s5,s4,s3,s2,s1", 5},
+		{SessionFilter{RepoName: "acme/api"}, "s4,s2,s1", 3},
+		{SessionFilter{RepoName: "acme/api", Status: "COMPLETED"}, "s4,s1", 2},
+		{SessionFilter{Limit: 2}, "s4,s3", 4},
+		{SessionFilter{Limit: 2, Offset: 2}, "s2,s1", 4},
+		{SessionFilter{Offset: 3}, "s1", 4},
+		{SessionFilter{Offset: 10}, "", 4},
+	}
+	for _, tc := range cases {
+		sessions, total, err := store.ListSessionsFiltered(tc.filter)
+		if err != nil {
+			t.Fatalf("ListSessionsFiltered(%+v): %v", tc.filter, err)
+		}
+		if got := ids(sessions); got != tc.want || total != tc.wantTotal {
+			t.Errorf("ListSessionsFiltered(%+v) = %s (total %d), want %s (total %d)", tc.filter, got, total, tc.want, tc.wantTotal)
+		}
+	}
+	if _, _, err := store.ListSessionsFiltered(SessionFilter{Limit: -1}); err == nil {
+		t.Error("expected an error for a negative limit")
+	}
+}
+
+func TestClaimSessionBusyIsExclusive(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	if _, err := store.UpsertRepo(Repo{Name: "acme/api", URL: "https://github.com/acme/api.git", Host: "github.com", Owner: "acme", Repo: "api", BarePath: "/tmp/acme-api/repo.git", BaseWorktreePath: "/tmp/acme-api/base", DefaultBranch: "main"}); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.CreateSession(Session{ID: "sess-1", RepoName: "acme/api", Branch: "fog/a", WorktreePath: "/tmp/acme-api/a", Tool: "claude", Status: "COMPLETED"}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+
+	if claimed, err := store.ClaimSessionBusy("sess-1"); err != nil || !claimed {
+		t.Fatalf("first claim = %v, %v; want true", claimed, err)
+	}
+	if claimed, err := store.ClaimSessionBusy("sess-1"); err != nil || claimed {
+		t.Fatalf("second claim = %v, %v; want false", claimed, err)
+	}
+	if err := store.SetSessionBusy("sess-1", false); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if claimed, err := store.ClaimSessionBusy("sess-1"); err != nil || !claimed {
+		t.Fatalf("claim after release = %v, %v; want true", claimed, err)
+	}
+	if _, err := store.ClaimSessionBusy("ghost"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("claim unknown session err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,78 @@
+package state
+
+import "fmt"
+
+// Stats is an aggregate snapshot of sessions, runs and repos.
+type Stats struct {
+	Sessions         int            `json:"sessions"`
+	BusySessions     int            `json:"busy_sessions"`
+	SessionsByStatus map[string]int `json:"sessions_by_status"`
+	SessionsWithPR   int            `json:"sessions_with_pr"`
+	Runs             int            `json:"runs"`
+	// RunsByState counts finished runs only; in-flight runs are visible through
+	// BusySessions and SessionsByStatus.
+	RunsByState map[string]int `json:"runs_by_state"`
+	Repos       int            `json:"repos"`
+}
+
+// terminalRunStates are the states a run cannot leave.
+var terminalRunStates = []string{"COMPLETED", "FAILED", "CANCELLED"}
+
+// Stats computes aggregate counts with grouped queries, so the cost does not
+// grow with the size of each row.
+func (s *Store) Stats() (Stats, error) {
+	out := Stats{
+		SessionsByStatus: map[string]int{},
+		RunsByState:      map[string]int{},
+	}
+
+	if err := s.db.QueryRow(
+		`SELECT COUNT(*),
+		        COALESCE(SUM(CASE WHEN busy != 0 THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN COALESCE(pr_url, '') != '' THEN 1 ELSE 0 END), 0)
+		   FROM sessions`,
+	).Scan(&out.Sessions, &out.BusySessions, &out.SessionsWithPR); err != nil {
+		return Stats{}, fmt.Errorf("count sessions: %w", err)
+	}
+
+	if err := s.countGrouped(`SELECT status, COUNT(*) FROM sessions GROUP BY status`, out.SessionsByStatus); err != nil {
+		return Stats{}, fmt.Errorf("count sessions by status: %w", err)
+	}
+
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM runs`).Scan(&out.Runs); err != nil {
+		return Stats{}, fmt.Errorf("count runs: %w", err)
+	}
+
+	if err := s.countGrouped(
+		`SELECT state, COUNT(*) FROM runs WHERE state IN (?, ?, ?) GROUP BY state`,
+		out.RunsByState,
+		terminalRunStates[0], terminalRunStates[1], terminalRunStates[2],
+	); err != nil {
+		return Stats{}, fmt.Errorf("count runs by state: %w", err)
+	}
+
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM repos`).Scan(&out.Repos); err != nil {
+		return Stats{}, fmt.Errorf("count repos: %w", err)
+	}
+
+	return out, nil
+}
+
+// countGrouped runs a two-column (key, count) query into dst.
+func (s *Store) countGrouped(query string, dst map[string]int, args ...any) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var n int
+		if err := rows.Scan(&key, &n); err != nil {
+			return err
+		}
+		dst[key] = n
+	}
+	return rows.Err()
+}
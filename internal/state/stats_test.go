@@ -0,0 +1,68 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatsCountsSessionsRunsAndRepos(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	seedSessionRun(t, store, "sess-1", "run-1")
+	if err := store.CreateSession(Session{
+		ID:           "sess-2",
+		RepoName:     "acme/api",
+		Branch:       "fog/sess-2",
+		WorktreePath: "/tmp/acme-api/sess-2",
+		Tool:         "claude",
+		Status:       "AI_RUNNING",
+		Busy:         true,
+	}); err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := store.CreateRun(Run{ID: "run-2", SessionID: "sess-2", Prompt: "p", WorktreePath: "/tmp/acme-api/sess-2", State: "AI_RUNNING", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+	if err := store.CompleteRun("run-1", "COMPLETED", "abc", "feat: x", ""); err != nil {
+		t.Fatalf("complete run failed: %v", err)
+	}
+	if err := store.UpdateSessionStatus("sess-1", "COMPLETED"); err != nil {
+		t.Fatalf("update session status failed: %v", err)
+	}
+	if err := store.SetSessionPRURL("sess-1", "https://github.com/acme/api/pull/1"); err != nil {
+		t.Fatalf("set pr url failed: %v", err)
+	}
+
+	got, err := store.Stats()
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	want := Stats{
+		Sessions:         2,
+		BusySessions:     1,
+		SessionsByStatus: map[string]int{"COMPLETED": 1, "AI_RUNNING": 1},
+		SessionsWithPR:   1,
+		Runs:             2,
+		RunsByState:      map[string]int{"COMPLETED": 1},
+		Repos:            1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected stats:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestStatsOnEmptyStore(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	got, err := store.Stats()
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if got.Sessions != 0 || got.Runs != 0 || got.Repos != 0 || len(got.SessionsByStatus) != 0 {
+		t.Fatalf("unexpected stats on empty store: %+v", got)
+	}
+}
@@ -1,12 +1,15 @@
 package state
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -30,6 +33,13 @@ type SecretReader interface {
 type Store struct {
 	db  *sql.DB
 	key []byte
+	// lock is held shared for the Store's lifetime, so fogd can tell whether
+	// another process has the database open before moving it aside.
+	lock *os.File
+
+	observerMu  sync.RWMutex
+	onRunEvent  func(RunEvent)
+	subscribers map[*runEventSub]struct{}
 }
 
 // Repo holds Fog's managed repository metadata.
@@ -43,14 +53,61 @@ type Repo struct {
 	BarePath         string    `json:"bare_path,omitempty"`
 	BaseWorktreePath string    `json:"base_worktree_path"`
 	DefaultBranch    string    `json:"default_branch,omitempty"`
+	DefaultTool      string    `json:"default_tool,omitempty"`  // overrides the global default_tool for sessions on this repo
+	DefaultModel     string    `json:"default_model,omitempty"` // model used with DefaultTool when a request names none
+	WorktreeRoot     string    `json:"worktree_root,omitempty"` // overrides the global default_worktree_root for this repo's session worktrees
 	CreatedAt        time.Time `json:"created_at"`
 }
 
-// NewStore opens or creates the Fog SQLite database in fogHome.
+// Default permissions for Fog's state. fogHome holds the database and the
+// master key that decrypts its secrets, so neither is readable by other users.
+const (
+	defaultDirMode  os.FileMode = 0o700
+	defaultFileMode os.FileMode = 0o600
+)
+
+// StoreOptions overrides the permissions NewStoreWithOptions applies. Zero
+// values take the defaults. The master key is always 0600 regardless.
+type StoreOptions struct {
+	// DirMode is applied to fogHome, including one that already exists.
+	DirMode os.FileMode
+	// DBMode is applied to the database file. SQLite gives its -wal and -shm
+	// files the same mode.
+	DBMode os.FileMode
+	// RecoverCorrupt moves a database that fails its integrity check aside,
+	// with its WAL files, and starts a fresh one. Without it a corrupt
+	// database is an error. Only fogd sets it, at startup, and the move is
+	// refused while another Fog process has the database open.
+	RecoverCorrupt bool
+	// SalvageCorrupt copies the rows still readable from a database
+	// RecoverCorrupt moved aside into the fresh one that replaces it.
+	// Without it the corrupt file is only moved aside.
+	SalvageCorrupt bool
+}
+
+// NewStore opens or creates the Fog SQLite database in fogHome with the
+// default permissions.
 func NewStore(fogHome string) (*Store, error) {
-	if err := os.MkdirAll(fogHome, 0o755); err != nil {
+	return NewStoreWithOptions(fogHome, StoreOptions{})
+}
+
+// NewStoreWithOptions is NewStore with explicit directory and database file
+// permissions.
+func NewStoreWithOptions(fogHome string, opts StoreOptions) (*Store, error) {
+	if opts.DirMode == 0 {
+		opts.DirMode = defaultDirMode
+	}
+	if opts.DBMode == 0 {
+		opts.DBMode = defaultFileMode
+	}
+	if err := os.MkdirAll(fogHome, opts.DirMode); err != nil {
 		return nil, fmt.Errorf("create fog home: %w", err)
 	}
+	// MkdirAll leaves an existing directory alone, and homes created before
+	// this default were 0755.
+	if err := os.Chmod(fogHome, opts.DirMode); err != nil {
+		return nil, fmt.Errorf("set fog home permissions: %w", err)
+	}
 
 	keyPath := filepath.Join(fogHome, defaultKeyName)
 	key, err := loadOrCreateMasterKey(keyPath)
@@ -59,20 +116,66 @@ func NewStore(fogHome string) (*Store, error) {
 	}
 
 	dbPath := filepath.Join(fogHome, defaultDBName)
+	if err := prepareDBFile(dbPath, opts.DBMode); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(dbPath+".lock", os.O_RDWR|os.O_CREATE, opts.DBMode)
+	if err != nil {
+		return nil, fmt.Errorf("open database lock: %w", err)
+	}
+	corruptPath, err := checkDB(dbPath, lock, opts.RecoverCorrupt)
+	if err != nil {
+		_ = lock.Close()
+		return nil, err
+	}
+	if corruptPath != "" {
+		if err := prepareDBFile(dbPath, opts.DBMode); err != nil {
+			_ = lock.Close()
+			return nil, err
+		}
+	}
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
+		_ = lock.Close()
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
 
-	store := &Store{db: db, key: key}
+	store := &Store{db: db, key: key, lock: lock}
 	if err := store.init(); err != nil {
-		_ = db.Close()
+		_ = store.Close()
 		return nil, err
 	}
 
+	if corruptPath != "" && opts.SalvageCorrupt {
+		copied, err := store.salvageRows(corruptPath)
+		if err != nil {
+			slog.Warn("state: salvage stopped early", "path", corruptPath, "rows", copied, "err", err)
+		} else {
+			slog.Info("state: salvaged rows from corrupt database", "path", corruptPath, "rows", copied)
+		}
+	}
+
 	return store, nil
 }
 
+// prepareDBFile creates the database file with mode before SQLite opens it, and
+// tightens a database and WAL files left by an older, more permissive default.
+func prepareDBFile(path string, mode os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, mode)
+	if err != nil {
+		return fmt.Errorf("create database file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close database file: %w", err)
+	}
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Chmod(p, mode); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("set database permissions: %w", err)
+		}
+	}
+	return nil
+}
+
 func (s *Store) init() error {
 	if _, err := s.db.Exec(`PRAGMA foreign_keys = ON;`); err != nil {
 		return fmt.Errorf("enable foreign keys: %w", err)
@@ -105,6 +208,9 @@ func (s *Store) init() error {
 			bare_path TEXT NOT NULL,
 			base_worktree_path TEXT NOT NULL,
 			default_branch TEXT,
+			default_tool TEXT NOT NULL DEFAULT '',
+			default_model TEXT NOT NULL DEFAULT '',
+			worktree_root TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL
 		);`,
 		`CREATE TABLE IF NOT EXISTS sessions (
@@ -118,6 +224,19 @@ func (s *Store) init() error {
 			pr_url TEXT,
 			status TEXT NOT NULL,
 			busy INTEGER NOT NULL DEFAULT 0,
+			ephemeral INTEGER NOT NULL DEFAULT 0,
+			origin TEXT NOT NULL DEFAULT '',
+			commit_strategy TEXT NOT NULL DEFAULT '',
+			issue_ref TEXT NOT NULL DEFAULT '',
+			close_issue INTEGER NOT NULL DEFAULT 0,
+			env TEXT NOT NULL DEFAULT '',
+			paused INTEGER NOT NULL DEFAULT 0,
+			accepted_run_id TEXT NOT NULL DEFAULT '',
+			slack_channel_id TEXT NOT NULL DEFAULT '',
+			slack_thread_ts TEXT NOT NULL DEFAULT '',
+			archived_at TEXT,
+			worktree_pruned_at TEXT,
+			parent_session_id TEXT,
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
 			FOREIGN KEY(repo_name) REFERENCES repos(name)
@@ -131,6 +250,8 @@ func (s *Store) init() error {
 			commit_sha TEXT,
 			commit_msg TEXT,
 			error TEXT,
+			attachments TEXT NOT NULL DEFAULT '',
+			context_text TEXT NOT NULL DEFAULT '',
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
 			completed_at TEXT,
@@ -145,6 +266,15 @@ func (s *Store) init() error {
 			data TEXT,
 			FOREIGN KEY(run_id) REFERENCES runs(id) ON DELETE CASCADE
 		);`,
+		// One row per run whose tool reported usage, summed across the
+		// run's AI calls. Runs without usage have no row.
+		`CREATE TABLE IF NOT EXISTS run_usage (
+			run_id TEXT PRIMARY KEY,
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			cost_usd REAL NOT NULL DEFAULT 0,
+			FOREIGN KEY(run_id) REFERENCES runs(id) ON DELETE CASCADE
+		);`,
 
 		// Tasks sit above sessions: a task exists from the moment someone
 		// writes it down, and owns at most one session once work starts.
@@ -200,6 +330,12 @@ func (s *Store) init() error {
 			return fmt.Errorf("init schema: %w", err)
 		}
 	}
+	if err := s.ensureReposSchema(); err != nil {
+		return err
+	}
+	if err := s.ensureSessionsSchema(); err != nil {
+		return err
+	}
 	if err := s.ensureRunsSchema(); err != nil {
 		return err
 	}
@@ -215,7 +351,37 @@ func (s *Store) Close() error {
 	if s == nil || s.db == nil {
 		return nil
 	}
-	return s.db.Close()
+	err := s.db.Close()
+	if s.lock != nil {
+		_ = s.lock.Close()
+	}
+	return err
+}
+
+// Ping checks that the database is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return errors.New("state store not configured")
+	}
+	return s.db.PingContext(ctx)
+}
+
+// CheckWritable checks that the database accepts writes, by writing a row
+// inside a transaction that is then rolled back.
+func (s *Store) CheckWritable(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return errors.New("state store not configured")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	_, err = tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO settings(key, value, updated_at) VALUES('fog_write_probe', '', ?)`,
+		nowRFC3339Nano(),
+	)
+	return err
 }
 
 // SetSetting stores a Fog setting.
@@ -402,7 +568,7 @@ func (s *Store) UpsertRepo(repo Repo) (int64, error) {
 // ListRepos returns all managed repositories ordered by name.
 func (s *Store) ListRepos() ([]Repo, error) {
 	rows, err := s.db.Query(
-		`SELECT id, name, url, host, owner, repo, bare_path, base_worktree_path, default_branch, created_at
+		`SELECT ` + repoColumns + `
 		   FROM repos
 		  ORDER BY name ASC`,
 	)
@@ -413,25 +579,10 @@ func (s *Store) ListRepos() ([]Repo, error) {
 
 	repos := make([]Repo, 0)
 	for rows.Next() {
-		var repo Repo
-		var createdAt string
-		if err := rows.Scan(
-			&repo.ID,
-			&repo.Name,
-			&repo.URL,
-			&repo.Host,
-			&repo.Owner,
-			&repo.Repo,
-			&repo.BarePath,
-			&repo.BaseWorktreePath,
-			&repo.DefaultBranch,
-			&createdAt,
-		); err != nil {
+		repo, err := scanRepo(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan repo: %w", err)
 		}
-		if ts, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
-			repo.CreatedAt = ts
-		}
 		repos = append(repos, repo)
 	}
 	if err := rows.Err(); err != nil {
@@ -442,36 +593,134 @@ func (s *Store) ListRepos() ([]Repo, error) {
 
 // GetRepoByName returns one managed repo by alias.
 func (s *Store) GetRepoByName(name string) (Repo, bool, error) {
-	var repo Repo
-	var createdAt string
-	err := s.db.QueryRow(
-		`SELECT id, name, url, host, owner, repo, bare_path, base_worktree_path, default_branch, created_at
+	repo, err := scanRepo(s.db.QueryRow(
+		`SELECT `+repoColumns+`
 		   FROM repos
 		  WHERE name = ?`,
 		name,
-	).Scan(
-		&repo.ID,
-		&repo.Name,
-		&repo.URL,
-		&repo.Host,
-		&repo.Owner,
-		&repo.Repo,
-		&repo.BarePath,
-		&repo.BaseWorktreePath,
-		&repo.DefaultBranch,
-		&createdAt,
-	)
+	))
 	if errors.Is(err, sql.ErrNoRows) {
 		return Repo{}, false, nil
 	}
 	if err != nil {
 		return Repo{}, false, fmt.Errorf("get repo %q: %w", name, err)
 	}
+	return repo, true, nil
+}
 
-	if ts, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
-		repo.CreatedAt = ts
+// SetRepoDefaults stores the tool and model sessions on a repo use when a
+// request names neither. Empty values clear them, deferring to the global
+// defaults. UpsertRepo leaves them alone, so re-importing keeps them.
+func (s *Store) SetRepoDefaults(name, tool, model string) error {
+	res, err := s.db.Exec(
+		`UPDATE repos SET default_tool = ?, default_model = ? WHERE name = ?`,
+		strings.TrimSpace(tool),
+		strings.TrimSpace(model),
+		name,
+	)
+	if err != nil {
+		return fmt.Errorf("set repo defaults %q: %w", name, err)
 	}
-	return repo, true, nil
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("repo %q: %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+// SetRepoWorktreeRoot stores the directory session worktrees for a repo are
+// created under. Empty clears it, deferring to default_worktree_root.
+// UpsertRepo leaves it alone, so re-importing keeps it.
+func (s *Store) SetRepoWorktreeRoot(name, root string) error {
+	res, err := s.db.Exec(`UPDATE repos SET worktree_root = ? WHERE name = ?`, strings.TrimSpace(root), name)
+	if err != nil {
+		return fmt.Errorf("set repo worktree root %q: %w", name, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("repo %q: %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+// RepoInUseError is returned by DeleteRepo while the repo still has sessions.
+type RepoInUseError struct {
+	Repo string
+	// SessionIDs are the sessions blocking the delete.
+	SessionIDs []string
+}
+
+func (e *RepoInUseError) Error() string {
+	return fmt.Sprintf("repo %q has %d session(s); delete them first: %s", e.Repo, len(e.SessionIDs), strings.Join(e.SessionIDs, ", "))
+}
+
+// DeleteRepo stops managing a repo. It refuses with a *RepoInUseError while
+// the repo has any session, running or finished: a session is deleted on its
+// own, which also removes its worktree and run logs wherever they live, so
+// dropping its history is always an explicit step. The repo's tasks keep
+// their place on the board without a repo. Nothing on disk is touched.
+func (s *Store) DeleteRepo(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("repo name cannot be empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("delete repo %q: %w", name, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := repoInUse(tx, name); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE tasks SET repo_name = NULL WHERE repo_name = ?`, name); err != nil {
+		return fmt.Errorf("delete repo %q: %w", name, err)
+	}
+	res, err := tx.Exec(`DELETE FROM repos WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete repo %q: %w", name, err)
+	}
+	if err := ensureRowsAffected(res, "repo "+name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete repo %q: %w", name, err)
+	}
+	return nil
+}
+
+// CheckRepoUnused returns the *RepoInUseError DeleteRepo would refuse with,
+// or nil, so a caller can check before doing work of its own.
+func (s *Store) CheckRepoUnused(name string) error {
+	return repoInUse(s.db, strings.TrimSpace(name))
+}
+
+func repoInUse(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, name string) error {
+	rows, err := q.Query(
+		`SELECT id FROM sessions WHERE repo_name = ? ORDER BY created_at ASC`,
+		name,
+	)
+	if err != nil {
+		return fmt.Errorf("list sessions of repo %q: %w", name, err)
+	}
+	var blocking []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("list sessions of repo %q: %w", name, err)
+		}
+		blocking = append(blocking, id)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("list sessions of repo %q: %w", name, err)
+	}
+	if len(blocking) > 0 {
+		return &RepoInUseError{Repo: name, SessionIDs: blocking}
+	}
+	return nil
 }
 
 func nowRFC3339Nano() string {
@@ -523,13 +772,88 @@ func (s *Store) tableExists(tableName string) (bool, error) {
 	return true, nil
 }
 
+// ensureReposSchema backfills the per-repo default tool, default model and
+// worktree root columns.
+func (s *Store) ensureReposSchema() error {
+	const table = "repos"
+	for _, column := range []string{"default_tool", "default_model", "worktree_root"} {
+		has, err := s.tableColumnExists(table, column)
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		if _, err := s.db.Exec(`ALTER TABLE repos ADD COLUMN ` + column + ` TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("add repos.%s column: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// sessionsBackfill lists the sessions columns added after the table was first
+// created, with the type each is added as. Sessions forked before
+// parent_session_id existed keep a NULL parent; the fork run event still
+// names their source.
+var sessionsBackfill = []struct {
+	column, decl string
+}{
+	{"ephemeral", "INTEGER NOT NULL DEFAULT 0"},
+	{"origin", "TEXT NOT NULL DEFAULT ''"},
+	{"commit_strategy", "TEXT NOT NULL DEFAULT ''"},
+	{"issue_ref", "TEXT NOT NULL DEFAULT ''"},
+	{"close_issue", "INTEGER NOT NULL DEFAULT 0"},
+	{"env", "TEXT NOT NULL DEFAULT ''"},
+	{"paused", "INTEGER NOT NULL DEFAULT 0"},
+	{"accepted_run_id", "TEXT NOT NULL DEFAULT ''"},
+	{"slack_channel_id", "TEXT NOT NULL DEFAULT ''"},
+	{"slack_thread_ts", "TEXT NOT NULL DEFAULT ''"},
+	{"archived_at", "TEXT"},
+	{"worktree_pruned_at", "TEXT"},
+	{"parent_session_id", "TEXT"},
+}
+
+// ensureSessionsSchema backfills the sessionsBackfill columns on databases
+// created before those features existed.
+func (s *Store) ensureSessionsSchema() error {
+	const table = "sessions"
+	for _, col := range sessionsBackfill {
+		has, err := s.tableColumnExists(table, col.column)
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		if _, err := s.db.Exec(`ALTER TABLE sessions ADD COLUMN ` + col.column + ` ` + col.decl); err != nil {
+			return fmt.Errorf("add sessions.%s column: %w", col.column, err)
+		}
+	}
+	return nil
+}
+
+// runsBackfill lists the runs columns added after the table was first
+// created, like sessionsBackfill.
+var runsBackfill = []struct {
+	column, decl string
+}{
+	{"worktree_path", "TEXT"},
+	{"attachments", "TEXT NOT NULL DEFAULT ''"},
+	{"context_text", "TEXT NOT NULL DEFAULT ''"},
+}
+
 func (s *Store) ensureRunsSchema() error {
 	const table = "runs"
-	if hasWorktree, err := s.tableColumnExists(table, "worktree_path"); err != nil {
-		return err
-	} else if !hasWorktree {
-		if _, err := s.db.Exec(`ALTER TABLE runs ADD COLUMN worktree_path TEXT`); err != nil {
-			return fmt.Errorf("add runs.worktree_path column: %w", err)
+	for _, col := range runsBackfill {
+		has, err := s.tableColumnExists(table, col.column)
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		if _, err := s.db.Exec(`ALTER TABLE runs ADD COLUMN ` + col.column + ` ` + col.decl); err != nil {
+			return fmt.Errorf("add runs.%s column: %w", col.column, err)
 		}
 	}
 	return nil
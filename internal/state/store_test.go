@@ -1,8 +1,10 @@
 package state
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -238,6 +240,57 @@ func TestGetRepoByName(t *testing.T) {
 	}
 }
 
+func TestSetRepoDefaults(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	repo := Repo{
+		Name:             "acme-api",
+		URL:              "https://github.com/acme/api.git",
+		Host:             "github.com",
+		Owner:            "acme",
+		Repo:             "api",
+		BarePath:         "/tmp/acme-api/repo.git",
+		BaseWorktreePath: "/tmp/acme-api/base",
+		DefaultBranch:    "main",
+	}
+	if _, err := store.UpsertRepo(repo); err != nil {
+		t.Fatalf("upsert repo failed: %v", err)
+	}
+	if err := store.SetRepoDefaults("acme-api", " codex ", "o3"); err != nil {
+		t.Fatalf("SetRepoDefaults failed: %v", err)
+	}
+	// Re-importing the repo must not clear its defaults.
+	if _, err := store.UpsertRepo(repo); err != nil {
+		t.Fatalf("re-upsert repo failed: %v", err)
+	}
+
+	got, _, err := store.GetRepoByName("acme-api")
+	if err != nil {
+		t.Fatalf("GetRepoByName failed: %v", err)
+	}
+	if got.DefaultTool != "codex" || got.DefaultModel != "o3" {
+		t.Fatalf("unexpected defaults: tool=%q model=%q", got.DefaultTool, got.DefaultModel)
+	}
+
+	if err := store.SetRepoDefaults("missing", "codex", ""); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing repo, got %v", err)
+	}
+
+	if err := store.SetRepoWorktreeRoot("acme-api", "/mnt/scratch"); err != nil {
+		t.Fatalf("SetRepoWorktreeRoot failed: %v", err)
+	}
+	if _, err := store.UpsertRepo(repo); err != nil {
+		t.Fatalf("re-upsert repo failed: %v", err)
+	}
+	if got, _, _ = store.GetRepoByName("acme-api"); got.WorktreeRoot != "/mnt/scratch" {
+		t.Fatalf("worktree root after re-import = %q", got.WorktreeRoot)
+	}
+	if err := store.SetRepoWorktreeRoot("missing", "/mnt/scratch"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing repo, got %v", err)
+	}
+}
+
 func newTestStore(t *testing.T) *Store {
 	t.Helper()
 	store, err := NewStore(t.TempDir())
@@ -247,6 +300,31 @@ func newTestStore(t *testing.T) *Store {
 	return store
 }
 
+func TestEnsureSessionsSchemaBackfillsMissingColumns(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	dropped := []string{"ephemeral", "paused", "archived_at", "parent_session_id"}
+	for _, column := range dropped {
+		if _, err := store.db.Exec(`ALTER TABLE sessions DROP COLUMN ` + column); err != nil {
+			t.Fatalf("drop sessions.%s: %v", column, err)
+		}
+	}
+	if err := store.ensureSessionsSchema(); err != nil {
+		t.Fatalf("ensureSessionsSchema: %v", err)
+	}
+	for _, col := range sessionsBackfill {
+		has, err := store.tableColumnExists("sessions", col.column)
+		if err != nil || !has {
+			t.Errorf("sessions.%s present = %v (%v), want backfilled", col.column, has, err)
+		}
+	}
+	// Running again over a complete table is a no-op.
+	if err := store.ensureSessionsSchema(); err != nil {
+		t.Fatalf("second ensureSessionsSchema: %v", err)
+	}
+}
+
 // TestStoreInitMigratesLegacyTasksTable reproduces the panic seen when an
 // on-disk database still holds the pre-Kanban `tasks` table: init() must drop
 // the incompatible table and recreate it rather than failing to build the
@@ -293,3 +371,119 @@ func TestStoreInitMigratesLegacyTasksTable(t *testing.T) {
 		t.Fatal("expected tasks table to be recreated with a status column")
 	}
 }
+
+func TestNewStoreRestrictsPermissions(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "fog")
+	// A home and key left behind by the old 0755 default.
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := loadOrCreateMasterKey(filepath.Join(home, defaultKeyName)); err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(home, defaultKeyName), 0o644); err != nil {
+		t.Fatalf("loosen key: %v", err)
+	}
+
+	store, err := NewStore(home)
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	for path, want := range map[string]os.FileMode{
+		home:                                0o700,
+		filepath.Join(home, defaultDBName):  0o600,
+		filepath.Join(home, defaultKeyName): 0o600,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat %s: %v", path, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s: got %o want %o", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestNewStoreWithOptionsAppliesModes(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "fog")
+	store, err := NewStoreWithOptions(home, StoreOptions{DirMode: 0o750, DBMode: 0o640})
+	if err != nil {
+		t.Fatalf("new store failed: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	dirInfo, err := os.Stat(home)
+	if err != nil {
+		t.Fatalf("stat home: %v", err)
+	}
+	if got := dirInfo.Mode().Perm(); got != 0o750 {
+		t.Errorf("home: got %o want 750", got)
+	}
+	dbInfo, err := os.Stat(filepath.Join(home, defaultDBName))
+	if err != nil {
+		t.Fatalf("stat db: %v", err)
+	}
+	if got := dbInfo.Mode().Perm(); got != 0o640 {
+		t.Errorf("db: got %o want 640", got)
+	}
+	keyInfo, err := os.Stat(filepath.Join(home, defaultKeyName))
+	if err != nil {
+		t.Fatalf("stat key: %v", err)
+	}
+	if got := keyInfo.Mode().Perm(); got != 0o600 {
+		t.Errorf("key: got %o want 600", got)
+	}
+}
+
+func TestDeleteRepo(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+
+	seedSessionRun(t, store, "session-1", "run-1")
+	seedSessionRun(t, store, "session-2", "run-2")
+	if _, err := store.CreateTask(Task{ID: "task-1", Title: "Fix login", Status: "todo", RepoName: "acme/api", SessionID: "session-1"}); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := store.UpdateSessionStatus("session-1", "COMPLETED"); err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+
+	// Finished or not, a session's history is never dropped with its repo.
+	var inUse *RepoInUseError
+	if err := store.DeleteRepo("acme/api"); !errors.As(err, &inUse) || !slices.Equal(inUse.SessionIDs, []string{"session-1", "session-2"}) {
+		t.Fatalf("DeleteRepo with sessions = %v, want RepoInUseError naming both", err)
+	}
+	if _, found, _ := store.GetRepoByName("acme/api"); !found {
+		t.Fatal("a refused delete removed the repo")
+	}
+	if _, found, _ := store.GetRun("run-1"); !found {
+		t.Fatal("a refused delete removed a finished session's run")
+	}
+
+	if err := store.DeleteSession("session-2"); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	if err := store.DeleteRepo("acme/api"); !errors.As(err, &inUse) || !slices.Equal(inUse.SessionIDs, []string{"session-1"}) {
+		t.Fatalf("DeleteRepo with a finished session = %v, want RepoInUseError naming session-1", err)
+	}
+	if err := store.DeleteSession("session-1"); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+
+	if err := store.DeleteRepo("acme/api"); err != nil {
+		t.Fatalf("DeleteRepo: %v", err)
+	}
+	if _, found, _ := store.GetRepoByName("acme/api"); found {
+		t.Fatal("repo still present after delete")
+	}
+	task, err := store.GetTask("task-1")
+	if err != nil || task.RepoName != "" {
+		t.Fatalf("task after delete = %+v (%v), want it kept without a repo", task, err)
+	}
+
+	if err := store.DeleteRepo("acme/api"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleting a missing repo = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RunUsage is AI token and cost accounting, for one run or summed over a
+// session. Fields are zero, and omitted from JSON, when no tool reported them.
+type RunUsage struct {
+	InputTokens  int64   `json:"input_tokens,omitempty"`
+	OutputTokens int64   `json:"output_tokens,omitempty"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+}
+
+// AddRunUsage adds usage to a run's totals. A run makes several AI calls when
+// it retries or self-reviews, and each reports its own usage.
+func (s *Store) AddRunUsage(runID string, usage RunUsage) error {
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return errors.New("run id cannot be empty")
+	}
+
+	if _, err := s.db.Exec(
+		`INSERT INTO run_usage(run_id, input_tokens, output_tokens, cost_usd)
+		 VALUES(?, ?, ?, ?)
+		 ON CONFLICT(run_id) DO UPDATE SET
+		        input_tokens = input_tokens + excluded.input_tokens,
+		        output_tokens = output_tokens + excluded.output_tokens,
+		        cost_usd = cost_usd + excluded.cost_usd`,
+		runID,
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.CostUSD,
+	); err != nil {
+		return fmt.Errorf("add run usage %q: %w", runID, err)
+	}
+	return nil
+}
+
+// SessionUsage sums usage over every run in a session, and counts the runs
+// that reported any.
+func (s *Store) SessionUsage(sessionID string) (RunUsage, int, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return RunUsage{}, 0, errors.New("session id cannot be empty")
+	}
+
+	var (
+		usage RunUsage
+		runs  int
+	)
+	if err := s.db.QueryRow(
+		`SELECT COALESCE(SUM(u.input_tokens), 0),
+		        COALESCE(SUM(u.output_tokens), 0),
+		        COALESCE(SUM(u.cost_usd), 0),
+		        COUNT(u.run_id)
+		   FROM run_usage u
+		   JOIN runs r ON r.id = u.run_id
+		  WHERE r.session_id = ?`,
+		sessionID,
+	).Scan(&usage.InputTokens, &usage.OutputTokens, &usage.CostUSD, &runs); err != nil {
+		return RunUsage{}, 0, fmt.Errorf("session usage %q: %w", sessionID, err)
+	}
+	return usage, runs, nil
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunUsageAccumulatesAndSums(t *testing.T) {
+	store := newTestStore(t)
+	defer func() { _ = store.Close() }()
+	seedSessionRun(t, store, "sess-1", "run-1")
+	now := time.Now().UTC()
+	if err := store.CreateRun(Run{
+		ID: "run-2", SessionID: "sess-1", Prompt: "again",
+		WorktreePath: "/tmp/acme-api/sess-1", State: "CREATED",
+		CreatedAt: now, UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("create run failed: %v", err)
+	}
+
+	if usage, runs, err := store.SessionUsage("sess-1"); err != nil || runs != 0 || usage != (RunUsage{}) {
+		t.Fatalf("usage before any run reported = %+v over %d runs, err %v", usage, runs, err)
+	}
+
+	for _, add := range []struct {
+		runID string
+		usage RunUsage
+	}{
+		{"run-1", RunUsage{InputTokens: 100, OutputTokens: 10, CostUSD: 0.5}},
+		{"run-1", RunUsage{InputTokens: 50, OutputTokens: 5, CostUSD: 0.25}},
+		{"run-2", RunUsage{InputTokens: 1, OutputTokens: 2}},
+	} {
+		if err := store.AddRunUsage(add.runID, add.usage); err != nil {
+			t.Fatalf("AddRunUsage(%s) failed: %v", add.runID, err)
+		}
+	}
+
+	run, _, err := store.GetRun("run-1")
+	if err != nil {
+		t.Fatalf("GetRun failed: %v", err)
+	}
+	if run.InputTokens != 150 || run.OutputTokens != 15 || run.CostUSD != 0.75 {
+		t.Fatalf("run-1 usage = %d/%d/%v", run.InputTokens, run.OutputTokens, run.CostUSD)
+	}
+	runs, err := store.ListRuns("sess-1")
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("ListRuns = %d runs, err %v", len(runs), err)
+	}
+
+	usage, count, err := store.SessionUsage("sess-1")
+	if err != nil {
+		t.Fatalf("SessionUsage failed: %v", err)
+	}
+	want := RunUsage{InputTokens: 151, OutputTokens: 17, CostUSD: 0.75}
+	if usage != want || count != 2 {
+		t.Fatalf("session usage = %+v over %d runs, want %+v over 2", usage, count, want)
+	}
+
+	if err := store.DeleteSession("sess-1"); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if _, count, _ := store.SessionUsage("sess-1"); count != 0 {
+		t.Fatalf("usage rows survived their session: %d", count)
+	}
+}
@@ -31,3 +31,27 @@ func ResolveTool(requested string, store DefaultToolReader, entrypoint string) (
 	}
 	return tool, nil
 }
+
+// ResolveRepoTool is ResolveTool with a repo-level default consulted between
+// the request and the global default_tool. The precedence is: requested tool,
+// then repoDefault, then default_tool.
+func ResolveRepoTool(requested, repoDefault string, store DefaultToolReader, entrypoint string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		requested = repoDefault
+	}
+	return ResolveTool(requested, store, entrypoint)
+}
+
+// ResolveRepoModel returns the model to use with tool: the requested model,
+// else the repo's default model when tool is the repo's default tool (or the
+// repo names no tool). A model belongs to one tool, so a request that picks a
+// different tool does not inherit it. Empty leaves the choice to the tool.
+func ResolveRepoModel(requested, tool, repoTool, repoModel string) string {
+	if model := strings.TrimSpace(requested); model != "" {
+		return model
+	}
+	if repoTool = strings.TrimSpace(repoTool); repoTool != "" && repoTool != strings.TrimSpace(tool) {
+		return ""
+	}
+	return strings.TrimSpace(repoModel)
+}
@@ -48,3 +48,36 @@ func TestResolveToolStoreError(t *testing.T) {
 		t.Fatal("expected error when store fails")
 	}
 }
+
+func TestResolveRepoToolPrecedence(t *testing.T) {
+	global := fakeStore{tool: "claude", found: true}
+	cases := []struct {
+		requested, repoDefault, want string
+	}{
+		{"cursor", "antigravity", "cursor"},
+		{"", "antigravity", "antigravity"},
+		{"", "", "claude"},
+	}
+	for _, tc := range cases {
+		got, err := ResolveRepoTool(tc.requested, tc.repoDefault, global, "api")
+		if err != nil || got != tc.want {
+			t.Errorf("ResolveRepoTool(%q, %q) = %q, %v; want %q", tc.requested, tc.repoDefault, got, err, tc.want)
+		}
+	}
+}
+
+func TestResolveRepoModel(t *testing.T) {
+	cases := []struct {
+		requested, tool, repoTool, repoModel, want string
+	}{
+		{"opus", "claude", "claude", "sonnet", "opus"},
+		{"", "claude", "claude", "sonnet", "sonnet"},
+		{"", "claude", "", "sonnet", "sonnet"},
+		{"", "cursor", "claude", "sonnet", ""},
+	}
+	for _, tc := range cases {
+		if got := ResolveRepoModel(tc.requested, tc.tool, tc.repoTool, tc.repoModel); got != tc.want {
+			t.Errorf("ResolveRepoModel(%q, %q, %q, %q) = %q, want %q", tc.requested, tc.tool, tc.repoTool, tc.repoModel, got, tc.want)
+		}
+	}
+}